@@ -29,9 +29,10 @@ import (
 // AccountAddress represents an Sero account located at a specific location defined
 // by the optional URL field.
 type Account struct {
-	Address common.AccountAddress `json:"address"` // Sero account address derived from the key
-	Tk      common.AccountAddress `json:"tk"`      // Sero account tk derived from the key
-	URL     URL                   `json:"url"`     // Optional resource locator within a backend
+	Address   common.AccountAddress `json:"address"`             // Sero account address derived from the key
+	Tk        common.AccountAddress `json:"tk"`                  // Sero account tk derived from the key
+	URL       URL                   `json:"url"`                 // Optional resource locator within a backend
+	WatchOnly bool                  `json:"watchOnly,omitempty"` // Whether only the tk is held, not the spending key
 }
 
 // Wallet represents a software or hardware wallet that might contain one or more
@@ -96,10 +97,18 @@ type Wallet interface {
 
 	EncryptTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, txt *tx.T, state *state.StateDB) (*types.Transaction, error)
 
+	// EncryptTxWithSponsor encrypts the given transaction like EncryptTx, paying
+	// its fee from the outs of the sponsor account, which must be unlocked too.
+	EncryptTxWithSponsor(account Account, sponsor Account, tx *types.Transaction, txt *tx.T, state *state.StateDB) (*types.Transaction, error)
+
 	// IsMine return whether an once address is mine or not
 	IsMine(onceAddress common.Address) bool
 
 	AddressUnlocked(account Account) (bool, error)
+
+	// SendAllowed returns ErrSignOnly if the transactions encrypted for the
+	// account are to be handed to the caller only, not sent by the node.
+	SendAllowed(account Account) error
 }
 
 // Backend is a "wallet provider" that may contain a batch of accounts they can
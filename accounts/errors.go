@@ -33,6 +33,14 @@ var ErrUnknownWallet = errors.New("unknown wallet")
 // backend that it does not support.
 var ErrNotSupported = errors.New("not supported")
 
+// ErrWatchOnly is returned when a watch-only account, of which only the tk is
+// held, is requested to unlock or to sign.
+var ErrWatchOnly = errors.New("watch-only account, can not sign without its spending key")
+
+// ErrSignOnly is returned when the transactions of an account unlocked for
+// signing only are requested to be sent by the node.
+var ErrSignOnly = errors.New("account is unlocked for signing only")
+
 // ErrInvalidPassphrase is returned when a decryption operation receives a bad
 // passphrase.
 var ErrInvalidPassphrase = errors.New("invalid passphrase")
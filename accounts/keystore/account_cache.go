@@ -276,8 +276,9 @@ func (ac *accountCache) scanAccounts() error {
 	var (
 		buf = new(bufio.Reader)
 		key struct {
-			Address string `json:"address"`
-			Tk      string `json:"tk"`
+			Address   string `json:"address"`
+			Tk        string `json:"tk"`
+			WatchOnly bool   `json:"watchonly"`
 		}
 	)
 	readAccount := func(path string) *accounts.Account {
@@ -289,7 +290,7 @@ func (ac *accountCache) scanAccounts() error {
 		defer fd.Close()
 		buf.Reset(fd)
 		// Parse the address.
-		key.Address = ""
+		key.Address, key.Tk, key.WatchOnly = "", "", false
 		err = json.NewDecoder(buf).Decode(&key)
 		addr := common.Base58ToAccount(key.Address)
 		tk := common.Base58ToAccount(key.Tk)
@@ -299,7 +300,7 @@ func (ac *accountCache) scanAccounts() error {
 		case (addr == common.AccountAddress{}):
 			log.Debug("Failed to decode keystore key", "path", path, "err", "missing or zero address")
 		default:
-			return &accounts.Account{Address: addr, Tk: tk, URL: accounts.URL{Scheme: KeyStoreScheme, Path: path}}
+			return &accounts.Account{Address: addr, Tk: tk, URL: accounts.URL{Scheme: KeyStoreScheme, Path: path}, WatchOnly: key.WatchOnly}
 		}
 		return nil
 	}
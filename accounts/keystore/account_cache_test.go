@@ -37,18 +37,18 @@ var (
 	cachetestDir, _   = filepath.Abs(filepath.Join("testdata", "keystore"))
 	cachetestAccounts = []accounts.Account{
 		{
-			Address: common.Base58ToAddress("64t1MPxFp4yzxNJ64zp1NmrTXWsrLuw9DMiMZeujbD2HVAKhjR3zpKnuFVjjAXAp86G2PzSVSsdiMdwp5JPoqxtP"),
-			Tk:      common.Base58ToAddress("48rGJTGEeQKiFcCi82rbZdvZeyhoJHnVqeDrV627nT4vKTUtYUKJGYmt4dMnRX94RDAtXJV4SEXKyFPH9TdhFxiB"),
+			Address: common.Base58ToAccount("64t1MPxFp4yzxNJ64zp1NmrTXWsrLuw9DMiMZeujbD2HVAKhjR3zpKnuFVjjAXAp86G2PzSVSsdiMdwp5JPoqxtP"),
+			Tk:      common.Base58ToAccount("48rGJTGEeQKiFcCi82rbZdvZeyhoJHnVqeDrV627nT4vKTUtYUKJGYmt4dMnRX94RDAtXJV4SEXKyFPH9TdhFxiB"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(cachetestDir, "UTC--2018-08-11T10-19-38.165083119Z--64t1MPxFp4yzxNJ64zp1NmrTXWsrLuw9DMiMZeujbD2HVAKhjR3zpKnuFVjjAXAp86G2PzSVSsdiMdwp5JPoqxtP")},
 		},
 		{
-			Address: common.Base58ToAddress("4raP8fYEznZDD9WXc8pvS2tMg992iZiWXssvwhCrXTFEhafcRt8urTeDyANfTrtXpJjnfz65cbYvr7g5WauAJgdc"),
-			Tk:      common.Base58ToAddress("5W5KsFo2di2kzrP2xEjT1iYpx66BoryPJccDRXz4BH5J2MWxKnnWZtmKm7a7BqjheBfi8rKJCqKFPME7hDLuiEJA"),
+			Address: common.Base58ToAccount("4raP8fYEznZDD9WXc8pvS2tMg992iZiWXssvwhCrXTFEhafcRt8urTeDyANfTrtXpJjnfz65cbYvr7g5WauAJgdc"),
+			Tk:      common.Base58ToAccount("5W5KsFo2di2kzrP2xEjT1iYpx66BoryPJccDRXz4BH5J2MWxKnnWZtmKm7a7BqjheBfi8rKJCqKFPME7hDLuiEJA"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(cachetestDir, "aaa")},
 		},
 		{
-			Address: common.Base58ToAddress("3Fov1AdSTVSTEWTEGfbknRrmHxBCoZ6AktyJA4jGFytHu7xDWEYysnR9YkwkKj5Knzttc6tNw4ENY4JZiirrksYw"),
-			Tk:      common.Base58ToAddress("fLFiBSN8JojjcECipDA4yNafv19BvcFEoP91BVsxRsd1qda9QkBXJM3Car9Y6V9VfYpZULx8dcPUnb2iNFnk4JX"),
+			Address: common.Base58ToAccount("3Fov1AdSTVSTEWTEGfbknRrmHxBCoZ6AktyJA4jGFytHu7xDWEYysnR9YkwkKj5Knzttc6tNw4ENY4JZiirrksYw"),
+			Tk:      common.Base58ToAccount("fLFiBSN8JojjcECipDA4yNafv19BvcFEoP91BVsxRsd1qda9QkBXJM3Car9Y6V9VfYpZULx8dcPUnb2iNFnk4JX"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(cachetestDir, "zzz")},
 		},
 	}
@@ -150,31 +150,31 @@ func TestCacheAddDeleteOrder(t *testing.T) {
 
 	accs := []accounts.Account{
 		{
-			Address: common.Base58ToAddress("oJBdJSCpFRyp5wQeJxwE4AUUQWAqh12Jn3Fo8RvUd1XZuZmyyHGhYVCsTGgLmuXKc2hoZWfj5MkNaf8hTvG8Hec"),
+			Address: common.Base58ToAccount("oJBdJSCpFRyp5wQeJxwE4AUUQWAqh12Jn3Fo8RvUd1XZuZmyyHGhYVCsTGgLmuXKc2hoZWfj5MkNaf8hTvG8Hec"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "-309830980"},
 		},
 		{
-			Address: common.Base58ToAddress("29uJ8gWjfgDdF389Y35FDoMbRWXDuTwGEKSEE17MP9xVMCuBMGVgWuofeHqjhGCqxQm3EijZPLdb1vMfSpP8MnNa"),
+			Address: common.Base58ToAccount("29uJ8gWjfgDdF389Y35FDoMbRWXDuTwGEKSEE17MP9xVMCuBMGVgWuofeHqjhGCqxQm3EijZPLdb1vMfSpP8MnNa"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "ggg"},
 		},
 		{
-			Address: common.Base58ToAddress("5BmSf3Cynp2bcw8TFgUTWQBaD3F8bqqJvuCAu83SM1E1nSFUHCdxgSCnBtqv744DFoLsR61PnhSWWarwK3uF6LJv"),
+			Address: common.Base58ToAccount("5BmSf3Cynp2bcw8TFgUTWQBaD3F8bqqJvuCAu83SM1E1nSFUHCdxgSCnBtqv744DFoLsR61PnhSWWarwK3uF6LJv"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "zzzzzz-the-very-last-one.keyXXX"},
 		},
 		{
-			Address: common.Base58ToAddress("5BkUvZ9ifZBhGnJdmSKfs7jn1h3EJzCHVjZWbLQgdTJ1i363CcbShy2SHHKWNqHWjKuX19XmjMg9vJLQ7mLQWWmN"),
+			Address: common.Base58ToAccount("5BkUvZ9ifZBhGnJdmSKfs7jn1h3EJzCHVjZWbLQgdTJ1i363CcbShy2SHHKWNqHWjKuX19XmjMg9vJLQ7mLQWWmN"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "SOMETHING.key"},
 		},
 		{
-			Address: common.Base58ToAddress("64t1MPxFp4yzxNJ64zp1NmrTXWsrLuw9DMiMZeujbD2HVAKhjR3zpKnuFVjjAXAp86G2PzSVSsdiMdwp5JPoqxtP"),
+			Address: common.Base58ToAccount("64t1MPxFp4yzxNJ64zp1NmrTXWsrLuw9DMiMZeujbD2HVAKhjR3zpKnuFVjjAXAp86G2PzSVSsdiMdwp5JPoqxtP"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "UTC--2018-08-11T10-19-38.165083119Z--64t1MPxFp4yzxNJ64zp1NmrTXWsrLuw9DMiMZeujbD2HVAKhjR3zpKnuFVjjAXAp86G2PzSVSsdiMdwp5JPoqxtP"},
 		},
 		{
-			Address: common.Base58ToAddress("4raP8fYEznZDD9WXc8pvS2tMg992iZiWXssvwhCrXTFEhafcRt8urTeDyANfTrtXpJjnfz65cbYvr7g5WauAJgdc"),
+			Address: common.Base58ToAccount("4raP8fYEznZDD9WXc8pvS2tMg992iZiWXssvwhCrXTFEhafcRt8urTeDyANfTrtXpJjnfz65cbYvr7g5WauAJgdc"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "aaa"},
 		},
 		{
-			Address: common.Base58ToAddress("3Fov1AdSTVSTEWTEGfbknRrmHxBCoZ6AktyJA4jGFytHu7xDWEYysnR9YkwkKj5Knzttc6tNw4ENY4JZiirrksYw"),
+			Address: common.Base58ToAccount("3Fov1AdSTVSTEWTEGfbknRrmHxBCoZ6AktyJA4jGFytHu7xDWEYysnR9YkwkKj5Knzttc6tNw4ENY4JZiirrksYw"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: "zzz"},
 		},
 	}
@@ -204,15 +204,15 @@ func TestCacheAddDeleteOrder(t *testing.T) {
 			t.Errorf("expected hasAccount(%x) to return true", a.Address)
 		}
 	}
-	if cache.hasAddress(common.Base58ToAddress("3kawu8SZ6vzMBde3tP2zuS4XkfTeyjQg2yryDopayXPHVhncz3appEeE8BGp3XBYcfByxBnzoTSp5F8MFVhzxeEB")) {
-		t.Errorf("expected hasAccount(%x) to return false", common.Base58ToAddress("fd9bd350f08ee3c0c19b85a8e16114a11a60aa4e"))
+	if cache.hasAddress(common.Base58ToAccount("3kawu8SZ6vzMBde3tP2zuS4XkfTeyjQg2yryDopayXPHVhncz3appEeE8BGp3XBYcfByxBnzoTSp5F8MFVhzxeEB")) {
+		t.Errorf("expected hasAccount(%x) to return false", common.Base58ToAccount("fd9bd350f08ee3c0c19b85a8e16114a11a60aa4e"))
 	}
 
 	// Delete a few keys from the cache.
 	for i := 0; i < len(accs); i += 2 {
 		cache.delete(wantAccounts[i])
 	}
-	cache.delete(accounts.Account{Address: common.Base58ToAddress("3kawu8SZ6vzMBde3tP2zuS4XkfTeyjQg2yryDopayXPHVhncz3appEeE8BGp3XBYcfByxBnzoTSp5F8MFVhzxeEB"), URL: accounts.URL{Scheme: KeyStoreScheme, Path: "something"}})
+	cache.delete(accounts.Account{Address: common.Base58ToAccount("3kawu8SZ6vzMBde3tP2zuS4XkfTeyjQg2yryDopayXPHVhncz3appEeE8BGp3XBYcfByxBnzoTSp5F8MFVhzxeEB"), URL: accounts.URL{Scheme: KeyStoreScheme, Path: "something"}})
 
 	// Check content again after deletion.
 	wantAccountsAfterDelete := []accounts.Account{
@@ -241,19 +241,19 @@ func TestCacheFind(t *testing.T) {
 
 	accs := []accounts.Account{
 		{
-			Address: common.Base58ToAddress("36hSFHR4P242YkF2CDJayM8nxqZyH9iTdQLjMgAytyxLWiatqYwHRtXq5pPJ6XM9i1GCBgPVjhW3AHojoY25B6Ks"),
+			Address: common.Base58ToAccount("36hSFHR4P242YkF2CDJayM8nxqZyH9iTdQLjMgAytyxLWiatqYwHRtXq5pPJ6XM9i1GCBgPVjhW3AHojoY25B6Ks"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "a.key")},
 		},
 		{
-			Address: common.Base58ToAddress("zwyLoRgtaj5XnpwRGqX6jizWf7yqSL7s8Yiaa2w3nThTjALReKn9orwP83xgoBhfwYH2gdapSokUodiJjHbuUsE"),
+			Address: common.Base58ToAccount("zwyLoRgtaj5XnpwRGqX6jizWf7yqSL7s8Yiaa2w3nThTjALReKn9orwP83xgoBhfwYH2gdapSokUodiJjHbuUsE"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "b.key")},
 		},
 		{
-			Address: common.Base58ToAddress("3RG6NiD2ewzo6aAu4sTRTafx92QeoesoS6yEzTsDCShrHvCQ5y4nQJ2zJ5c4kC3HsoJgCG79aJJBLn4EJfVT1yh9"),
+			Address: common.Base58ToAccount("3RG6NiD2ewzo6aAu4sTRTafx92QeoesoS6yEzTsDCShrHvCQ5y4nQJ2zJ5c4kC3HsoJgCG79aJJBLn4EJfVT1yh9"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "c.key")},
 		},
 		{
-			Address: common.Base58ToAddress("5FzgDB5GGc6tKPaif531nD61YJ2JaC7kKzAusDPtJCRWGuH97fPojma16qMr2Dpxn7daDaPnJFCXdB4iUUAFV7Cq"),
+			Address: common.Base58ToAccount("3RG6NiD2ewzo6aAu4sTRTafx92QeoesoS6yEzTsDCShrHvCQ5y4nQJ2zJ5c4kC3HsoJgCG79aJJBLn4EJfVT1yh9"),
 			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "c2.key")},
 		},
 	}
@@ -262,7 +262,7 @@ func TestCacheFind(t *testing.T) {
 	}
 
 	nomatchAccount := accounts.Account{
-		Address: common.Base58ToAddress("bKHV56EP5eJzxPXHunSumEJM8ebQNXpbGgnX3UWSaVsTVx6MMZkGX7pTUmuQXwb4JYsFnvdbZJZkgT6FdEYR3Xh"),
+		Address: common.Base58ToAccount("bKHV56EP5eJzxPXHunSumEJM8ebQNXpbGgnX3UWSaVsTVx6MMZkGX7pTUmuQXwb4JYsFnvdbZJZkgT6FdEYR3Xh"),
 		URL:     accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "something")},
 	}
 	tests := []struct {
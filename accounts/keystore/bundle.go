@@ -0,0 +1,84 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sero-cash/go-sero/accounts"
+)
+
+const bundleVersion = 1
+
+// encryptedBundleJSON is an account exported along with data of the node
+// about it, such as its scanned outs, both encrypted with the same passphrase.
+type encryptedBundleJSON struct {
+	Key     json.RawMessage `json:"key"`
+	Data    cryptoJSON      `json:"data"`
+	Version int             `json:"version"`
+}
+
+// ExportBundle exports an account as a JSON bundle of its key and the given
+// data, encrypted with newPassphrase.
+func (ks *KeyStore) ExportBundle(a accounts.Account, passphrase, newPassphrase string, data []byte) ([]byte, error) {
+	keyJSON, err := ks.Export(a, passphrase, newPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	var N, P int
+	if store, ok := ks.storage.(*keyStorePassphrase); ok {
+		N, P = store.scryptN, store.scryptP
+	} else {
+		N, P = StandardScryptN, StandardScryptP
+	}
+	cryptoStruct, err := encryptData(data, newPassphrase, N, P)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedBundleJSON{keyJSON, cryptoStruct, bundleVersion})
+}
+
+// ImportBundle stores the key of a bundle exported by ExportBundle into the
+// key directory and returns the data of the bundle. An account the keystore
+// holds already is kept as it is, the bundle is only checked to decrypt.
+func (ks *KeyStore) ImportBundle(bundleJSON []byte, passphrase, newPassphrase string) (accounts.Account, []byte, error) {
+	bundle := new(encryptedBundleJSON)
+	if err := json.Unmarshal(bundleJSON, bundle); err != nil {
+		return accounts.Account{}, nil, err
+	}
+	if bundle.Version != bundleVersion {
+		return accounts.Account{}, nil, fmt.Errorf("Version not supported: %v", bundle.Version)
+	}
+	key, err := DecryptKey(bundle.Key, passphrase)
+	if key != nil && key.PrivateKey != nil {
+		defer zeroKey(key.PrivateKey)
+	}
+	if err != nil {
+		return accounts.Account{}, nil, err
+	}
+	data, err := decryptData(bundle.Data, passphrase)
+	if err != nil {
+		return accounts.Account{}, nil, err
+	}
+	if ks.HasAddress(key.Address) {
+		a, err := ks.Find(accounts.Account{Address: key.Address})
+		return a, data, err
+	}
+	a, err := ks.importKey(key, newPassphrase)
+	return a, data, err
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/event"
+)
+
+// AccountEventType represents the lifecycle events of keystore accounts.
+type AccountEventType int
+
+const (
+	// AccountCreated is fired when a key is generated or imported.
+	AccountCreated AccountEventType = iota
+
+	// AccountUnlocked is fired when the key of an account is unlocked.
+	AccountUnlocked
+
+	// AccountLocked is fired when an unlocked key is locked or its unlock expires.
+	AccountLocked
+
+	// AccountUnlockFailed is fired when a passphrase fails to decrypt a key.
+	AccountUnlockFailed
+
+	// AccountAdded is fired when a key file appears in the keystore directory,
+	// including the files of created accounts.
+	AccountAdded
+
+	// AccountRemoved is fired when a key file disappears from the keystore directory.
+	AccountRemoved
+)
+
+var accountEventNames = [...]string{
+	AccountCreated:      "created",
+	AccountUnlocked:     "unlocked",
+	AccountLocked:       "locked",
+	AccountUnlockFailed: "unlockFailed",
+	AccountAdded:        "added",
+	AccountRemoved:      "removed",
+}
+
+func (t AccountEventType) String() string {
+	if t < 0 || int(t) >= len(accountEventNames) {
+		return "unknown"
+	}
+	return accountEventNames[t]
+}
+
+// AccountEvent is an event fired by the keystore on the lifecycle of its
+// accounts, allowing to monitor unlocks as they happen.
+type AccountEvent struct {
+	Account accounts.Account
+	Kind    AccountEventType
+	Timeout time.Duration // Duration of an unlock, 0 if indefinite
+	Time    time.Time
+}
+
+// SubscribeAccountEvents creates an async subscription to receive the
+// lifecycle events of the keystore accounts.
+func (ks *KeyStore) SubscribeAccountEvents(sink chan<- AccountEvent) event.Subscription {
+	return ks.accountScope.Track(ks.accountFeed.Subscribe(sink))
+}
+
+// sendAccountEvent notifies the subscribers of an account event. It must not
+// be called with the keystore lock held.
+func (ks *KeyStore) sendAccountEvent(a accounts.Account, kind AccountEventType, timeout time.Duration) {
+	ks.accountFeed.Send(AccountEvent{Account: a, Kind: kind, Timeout: timeout, Time: time.Now()})
+}
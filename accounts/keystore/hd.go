@@ -0,0 +1,124 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common/math"
+	"github.com/sero-cash/go-sero/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+var errInvalidChild = errors.New("invalid child key, derive the next index")
+
+// MnemonicToSeed returns the BIP-39 seed of a mnemonic sentence and its
+// optional passphrase. The words are not checked against a wordlist.
+func MnemonicToSeed(mnemonic, passphrase string) []byte {
+	password := norm.NFKD.String(strings.Join(strings.Fields(mnemonic), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(password), []byte(salt), 2048, 64, sha512.New)
+}
+
+// DeriveECDSA derives the BIP-32 private key at path from a master seed. The
+// 32 bytes of the key are the seed of the SERO account.
+func DeriveECDSA(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chain := sum[:32], sum[32:]
+
+	n := crypto.S256().Params().N
+	for _, index := range path {
+		mac = hmac.New(sha512.New, chain)
+		if index >= 0x80000000 {
+			mac.Write([]byte{0})
+			mac.Write(key)
+		} else {
+			parent, err := crypto.ToECDSA(key)
+			if err != nil {
+				return nil, err
+			}
+			mac.Write(crypto.CompressPubkey(&parent.PublicKey))
+		}
+		var i [4]byte
+		binary.BigEndian.PutUint32(i[:], index)
+		mac.Write(i[:])
+		sum = mac.Sum(nil)
+
+		child := new(big.Int).SetBytes(sum[:32])
+		if child.Cmp(n) >= 0 {
+			return nil, errInvalidChild
+		}
+		child.Add(child, new(big.Int).SetBytes(key))
+		child.Mod(child, n)
+		if child.Sign() == 0 {
+			return nil, errInvalidChild
+		}
+		key, chain = math.PaddedBigBytes(child, 32), sum[32:]
+	}
+	return crypto.ToECDSA(key)
+}
+
+// DeriveAccount stores the key derived at path from the master seed,
+// encrypting it with the passphrase. Deriving a stored account again returns
+// it unchanged.
+func (ks *KeyStore) DeriveAccount(seed []byte, path accounts.DerivationPath, passphrase string) (accounts.Account, error) {
+	priv, err := DeriveECDSA(seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer zeroKey(priv)
+
+	key := newKeyFromECDSA(priv)
+	if ks.cache.hasAddress(key.Address) {
+		return ks.Find(accounts.Account{Address: key.Address})
+	}
+	return ks.importKey(key, passphrase)
+}
+
+// ExportDerived returns the account of the key derived at path from the master
+// seed and the key as a JSON key encrypted with the passphrase, without storing
+// it.
+func (ks *KeyStore) ExportDerived(seed []byte, path accounts.DerivationPath, passphrase string) (accounts.Account, []byte, error) {
+	priv, err := DeriveECDSA(seed, path)
+	if err != nil {
+		return accounts.Account{}, nil, err
+	}
+	defer zeroKey(priv)
+
+	var N, P int
+	if store, ok := ks.storage.(*keyStorePassphrase); ok {
+		N, P = store.scryptN, store.scryptP
+	} else {
+		N, P = StandardScryptN, StandardScryptP
+	}
+	key := newKeyFromECDSA(priv)
+	keyJSON, err := EncryptKey(key, passphrase, N, P)
+	if err != nil {
+		return accounts.Account{}, nil, err
+	}
+	return accounts.Account{Address: key.Address}, keyJSON, nil
+}
@@ -23,12 +23,14 @@ package keystore
 import (
 	"crypto/ecdsa"
 	crand "crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -47,6 +49,7 @@ var (
 	ErrNoMatch     = errors.New("no key for given address or file")
 	ErrDecrypt     = errors.New("could not decrypt key with given passphrase")
 	ErrNoStoreType = errors.New("could not support kestore type")
+	ErrScopeName   = errors.New("invalid key store scope name")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -70,12 +73,19 @@ type KeyStore struct {
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
 
+	accountFeed  event.Feed              // Event feed to notify account lifecycle events
+	accountScope event.SubscriptionScope // Subscription scope tracking account event listeners
+
+	scopes map[string]*KeyStore // Key stores of the account groups, by name
+
 	mu sync.RWMutex
 }
 
 type unlocked struct {
 	*Key
 	abort chan struct{}
+	scope *UnlockScope // limits of the use of the key, nil for none
+	used  uint64       // number of transactions counted against scope.MaxTxs
 }
 
 // NewKeyStore creates a keystore for the given directory.
@@ -174,6 +184,13 @@ func (ks *KeyStore) refreshWallets() {
 	for _, event := range events {
 		ks.updateFeed.Send(event)
 	}
+	for _, event := range events {
+		kind := AccountAdded
+		if event.Kind == accounts.WalletDropped {
+			kind = AccountRemoved
+		}
+		ks.sendAccountEvent(event.Wallet.Accounts()[0], kind, 0)
+	}
 }
 
 // Subscribe implements accounts.Backend, creating an async subscription to
@@ -220,6 +237,35 @@ func (ks *KeyStore) updater() {
 	}
 }
 
+// Scope returns the key store of the account group name, kept in the tenants
+// subdirectory of the key directory with the same encryption parameters. The
+// key files of a scope are not seen by the parent key store nor by the other
+// scopes. Repeated calls return the same key store.
+func (ks *KeyStore) Scope(name string) (*KeyStore, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, ErrScopeName
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if scope, ok := ks.scopes[name]; ok {
+		return scope, nil
+	}
+	storage, ok := ks.storage.(*keyStorePassphrase)
+	if !ok {
+		return nil, ErrNoStoreType
+	}
+	keydir := filepath.Join(storage.JoinPath("tenants"), name)
+	if err := os.MkdirAll(keydir, 0700); err != nil {
+		return nil, err
+	}
+	scope := NewKeyStore(keydir, storage.scryptN, storage.scryptP)
+	if ks.scopes == nil {
+		ks.scopes = make(map[string]*KeyStore)
+	}
+	ks.scopes[name] = scope
+	return scope, nil
+}
+
 // HasAddress reports whether a key with the given address is present.
 func (ks *KeyStore) HasAddress(addr common.AccountAddress) bool {
 	return ks.cache.hasAddress(addr)
@@ -279,18 +325,32 @@ func (ks *KeyStore) Lock(addr common.AccountAddress) error {
 // shortens the active unlock timeout. If the address was previously unlocked
 // indefinitely the timeout is not altered.
 func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout time.Duration) error {
+	return ks.TimedUnlockScoped(a, passphrase, timeout, nil)
+}
+
+// TimedUnlockScoped unlocks the given account like TimedUnlock, limiting its
+// use to scope until it is locked again. A nil scope imposes no limits.
+//
+// Unlocking an account already unlocked replaces the scope of its unlock. If
+// the address was previously unlocked indefinitely, only a non-nil scope
+// replaces it, so that unlocking never lifts the limits of an indefinite unlock.
+func (ks *KeyStore) TimedUnlockScoped(a accounts.Account, passphrase string, timeout time.Duration, scope *UnlockScope) error {
 	a, key, err := ks.getDecryptedKey(a, passphrase)
 	if err != nil {
+		ks.sendAccountEvent(a, AccountUnlockFailed, timeout)
 		return err
 	}
 
 	ks.mu.Lock()
-	defer ks.mu.Unlock()
 	u, found := ks.unlocked[a.Address]
 	if found {
 		if u.abort == nil {
 			// The address was unlocked indefinitely, so unlocking
 			// it with a timeout would be confusing.
+			if scope != nil {
+				u.scope, u.used = scope, 0
+			}
+			ks.mu.Unlock()
 			zeroKey(key.PrivateKey)
 			return nil
 		}
@@ -298,12 +358,15 @@ func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout t
 		close(u.abort)
 	}
 	if timeout > 0 {
-		u = &unlocked{Key: key, abort: make(chan struct{})}
+		u = &unlocked{Key: key, abort: make(chan struct{}), scope: scope}
 		go ks.expire(a.Address, u, timeout)
 	} else {
-		u = &unlocked{Key: key}
+		u = &unlocked{Key: key, scope: scope}
 	}
 	ks.unlocked[a.Address] = u
+	ks.mu.Unlock()
+
+	ks.sendAccountEvent(a, AccountUnlocked, timeout)
 	return nil
 }
 
@@ -321,6 +384,9 @@ func (ks *KeyStore) getDecryptedKey(a accounts.Account, auth string) (accounts.A
 	if err != nil {
 		return a, nil, err
 	}
+	if a.WatchOnly {
+		return a, nil, accounts.ErrWatchOnly
+	}
 	key, err := ks.storage.GetKey(a.Address, a.URL.Path, auth)
 	return a, key, err
 }
@@ -337,11 +403,20 @@ func (ks *KeyStore) expire(addr common.AccountAddress, u *unlocked, timeout time
 		// was launched with. we can check that using pointer equality
 		// because the map stores a new pointer every time the key is
 		// unlocked.
-		if ks.unlocked[addr] == u {
+		locked := ks.unlocked[addr] == u
+		if locked {
 			zeroKey(u.PrivateKey)
 			delete(ks.unlocked, addr)
 		}
 		ks.mu.Unlock()
+
+		if locked {
+			a, err := ks.Find(accounts.Account{Address: addr})
+			if err != nil {
+				a = accounts.Account{Address: addr}
+			}
+			ks.sendAccountEvent(a, AccountLocked, 0)
+		}
 	}
 }
 
@@ -356,6 +431,7 @@ func (ks *KeyStore) NewAccount(passphrase string) (accounts.Account, error) {
 	// than waiting for file system notifications to pick it up.
 	ks.cache.add(account, false)
 	ks.refreshWallets()
+	ks.sendAccountEvent(account, AccountCreated, 0)
 	return account, nil
 }
 
@@ -402,6 +478,33 @@ func (ks *KeyStore) importKey(key *Key, passphrase string) (accounts.Account, er
 	}
 	ks.cache.add(a, true)
 	ks.refreshWallets()
+	ks.sendAccountEvent(a, AccountCreated, 0)
+	return a, nil
+}
+
+// AddWatchOnly stores a key file holding only the address and the tk of an
+// account, marked as watch-only. Its outs are scanned like the ones of the
+// other accounts, but it can not be unlocked nor sign for it.
+func (ks *KeyStore) AddWatchOnly(address common.AccountAddress, tk common.AccountAddress) (accounts.Account, error) {
+	if ks.cache.hasAddress(address) {
+		return accounts.Account{}, fmt.Errorf("account already exists")
+	}
+	keyJSON, err := json.Marshal(struct {
+		Address   string `json:"address"`
+		Tk        string `json:"tk"`
+		WatchOnly bool   `json:"watchonly"`
+		Version   int    `json:"version"`
+	}{address.Base58(), tk.Base58(), true, version})
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	a := accounts.Account{Address: address, Tk: tk, URL: accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(address))}, WatchOnly: true}
+	if err := writeKeyFile(a.URL.Path, keyJSON); err != nil {
+		return accounts.Account{}, err
+	}
+	ks.cache.add(a, false)
+	ks.refreshWallets()
+	ks.sendAccountEvent(a, AccountCreated, 0)
 	return a, nil
 }
 
@@ -423,6 +526,7 @@ func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (account
 	}
 	ks.cache.add(a, true)
 	ks.refreshWallets()
+	ks.sendAccountEvent(a, AccountCreated, 0)
 	return a, nil
 }
 
@@ -442,6 +546,9 @@ func (ks *KeyStore) GetSeed(a accounts.Account) (*common.Seed, error) {
 
 	unlockedKey, found := ks.unlocked[a.Address]
 	if !found {
+		if a, err := ks.Find(a); err == nil && a.WatchOnly {
+			return nil, accounts.ErrWatchOnly
+		}
 		return nil, ErrLocked
 	}
 	priKey := crypto.FromECDSA(unlockedKey.PrivateKey)
@@ -452,8 +559,9 @@ func (ks *KeyStore) GetSeed(a accounts.Account) (*common.Seed, error) {
 }
 
 func (ks *KeyStore) GetSeedWithPassphrase(account accounts.Account, passphrase string) (*common.Seed, error) {
-	_, key, err := ks.getDecryptedKey(account, passphrase)
+	a, key, err := ks.getDecryptedKey(account, passphrase)
 	if err != nil {
+		ks.sendAccountEvent(a, AccountUnlockFailed, 0)
 		return nil, err
 	}
 	defer zeroKey(key.PrivateKey)
@@ -26,9 +26,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sero-cash/go-czero-import/keys"
 	"github.com/sero-cash/go-sero/accounts"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/zero/txs/tx"
 )
 
 func TestKeyStore(t *testing.T) {
@@ -143,6 +145,92 @@ func TestOverrideUnlock(t *testing.T) {
 
 // Tests that the wallet notifier loop starts and stops correctly based on the
 // addition and removal of wallet event subscriptions.
+func TestUnlockScope(t *testing.T) {
+	dir, ks := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	pass := "foo"
+	a1, err := ks.NewAccount(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := ks.NewAccount(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := &UnlockScope{SignOnly: true, MaxTxs: 2, Recipients: []common.AccountAddress{a2.Address}}
+	if err = ks.TimedUnlockScoped(a1, pass, 5*time.Minute, scope); err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.SendAllowed(a1); err != accounts.ErrSignOnly {
+		t.Fatal("Sending should've failed with ErrSignOnly, got ", err)
+	}
+
+	// Only outs to the allowed recipients pass
+	var allowed, other keys.PKr
+	allowed[0], other[0] = 1, 2
+	txt := &tx.T{Outs: []tx.Out{{Addr: allowed}}}
+	txt.AddRecipient(allowed, a2.Address.ToUint512())
+	if last, err := ks.useUnlocked(a1, txt); err != nil || last {
+		t.Fatalf("first transaction: last %v, err %v", last, err)
+	}
+	txt.Outs = append(txt.Outs, tx.Out{Addr: other})
+	if _, err := ks.useUnlocked(a1, txt); err != ErrRecipientNotAllowed {
+		t.Fatal("Unknown recipient should've failed with ErrRecipientNotAllowed, got ", err)
+	}
+
+	// The second transaction uses up the scope
+	if last, err := ks.useUnlocked(a1, nil); err != nil || !last {
+		t.Fatalf("second transaction: last %v, err %v", last, err)
+	}
+	if _, err := ks.useUnlocked(a1, nil); err != ErrLocked {
+		t.Fatal("Third transaction should've failed with ErrLocked, got ", err)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	dir, ks := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+	dir2, ks2 := tmpKeyStore(t)
+	defer os.RemoveAll(dir2)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := ks.ExportBundle(a, "foo", "bar", []byte("outs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ks2.ImportBundle(bundle, "foo", "baz"); err != ErrDecrypt {
+		t.Fatal("Import with the old passphrase should've failed with ErrDecrypt, got ", err)
+	}
+	imported, data, err := ks2.ImportBundle(bundle, "bar", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Address != a.Address || string(data) != "outs" {
+		t.Fatalf("imported %x with data %q, want %x with data %q", imported.Address, data, a.Address, "outs")
+	}
+	if err := ks2.Unlock(imported, "baz"); err != nil {
+		t.Fatal(err)
+	}
+	// Importing an account held already keeps its key file and passphrase
+	again, data, err := ks2.ImportBundle(bundle, "bar", "qux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != imported || string(data) != "outs" {
+		t.Fatalf("reimported %v with data %q, want %v with data %q", again, data, imported, "outs")
+	}
+	if accs := ks2.Accounts(); len(accs) != 1 {
+		t.Fatalf("reimport changed the accounts: have %d, want 1", len(accs))
+	}
+	if err := ks2.Unlock(imported, "qux"); err != ErrDecrypt {
+		t.Fatal("Unlock with the reimport passphrase should've failed with ErrDecrypt, got ", err)
+	}
+}
+
 func TestWalletNotifierLifecycle(t *testing.T) {
 	// Create a temporary kesytore to test with
 	dir, ks := tmpKeyStore(t)
@@ -230,7 +318,7 @@ func TestWalletNotifications(t *testing.T) {
 
 	// Randomly add and remove accounts.
 	var (
-		live       = make(map[common.Address]accounts.Account)
+		live       = make(map[common.AccountAddress]accounts.Account)
 		wantEvents []walletEvent
 	)
 	for i := 0; i < 1024; i++ {
@@ -265,7 +353,7 @@ func TestWalletNotifications(t *testing.T) {
 }
 
 // checkAccounts checks that all known live accounts are present in the wallet list.
-func checkAccounts(t *testing.T, live map[common.Address]accounts.Account, wallets []accounts.Wallet) {
+func checkAccounts(t *testing.T, live map[common.AccountAddress]accounts.Account, wallets []accounts.Wallet) {
 	if len(live) != len(wallets) {
 		t.Errorf("wallet list doesn't match required accounts: have %d, want %d", len(wallets), len(live))
 		return
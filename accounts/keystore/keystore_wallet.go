@@ -34,6 +34,7 @@ import (
 	"github.com/sero-cash/go-sero/zero/txs/lstate"
 	"github.com/sero-cash/go-sero/zero/txs/pkg"
 	"github.com/sero-cash/go-sero/zero/txs/tx"
+	"github.com/sero-cash/go-sero/zero/utils"
 )
 
 // keystoreWallet implements the accounts.Wallet interface for the original
@@ -51,6 +52,9 @@ func (w *keystoreWallet) URL() accounts.URL {
 // Status implements accounts.Wallet, returning whether the account held by the
 // keystore wallet is unlocked or not.
 func (w *keystoreWallet) Status() (string, error) {
+	if w.account.WatchOnly {
+		return "Watch-only", nil
+	}
 	w.keystore.mu.RLock()
 	defer w.keystore.mu.RUnlock()
 
@@ -102,6 +106,13 @@ func (w *keystoreWallet) EncryptTx(account accounts.Account, tx *types.Transacti
 	if err != nil {
 		return nil, err
 	}
+	last, err := w.keystore.useUnlocked(account, txt)
+	if err != nil {
+		return nil, err
+	}
+	if last {
+		defer w.keystore.Lock(account.Address)
+	}
 	return w.EncryptTxWithSeed(*seed, tx, txt, state)
 
 }
@@ -120,53 +131,95 @@ func (w *keystoreWallet) AddressUnlocked(account accounts.Account) (bool, error)
 	return true, nil
 }
 
+// SendAllowed implements accounts.Wallet, returning accounts.ErrSignOnly if
+// the account is unlocked for signing only.
+func (w *keystoreWallet) SendAllowed(account accounts.Account) error {
+	if account.Address != w.account.Address {
+		return accounts.ErrUnknownAccount
+	}
+	return w.keystore.SendAllowed(account)
+}
+
+// EncryptTxWithSponsor implements accounts.Wallet, encrypting a transaction
+// whose fee is paid by the sponsor account of the same keystore.
+func (w *keystoreWallet) EncryptTxWithSponsor(account accounts.Account, sponsor accounts.Account, tx *types.Transaction, txt *tx.T, state *state.StateDB) (*types.Transaction, error) {
+	// Make sure the requested account is contained within
+	if account.Address != w.account.Address {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if account.URL != (accounts.URL{}) && account.URL != w.account.URL {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if sponsor.Address == account.Address {
+		return nil, errors.New("sponsor must differ from the sender")
+	}
+	seed, err := w.keystore.GetSeed(account)
+	if err != nil {
+		return nil, err
+	}
+	sponsorSeed, err := w.keystore.GetSeed(sponsor)
+	if err != nil {
+		return nil, err
+	}
+	last, err := w.keystore.useUnlocked(account, txt)
+	if err != nil {
+		return nil, err
+	}
+	if last {
+		defer w.keystore.Lock(account.Address)
+	}
+	if last, err = w.keystore.useUnlocked(sponsor, nil); err != nil {
+		return nil, err
+	}
+	if last {
+		defer w.keystore.Lock(sponsor.Address)
+	}
+	return w.encryptTx(*seed, sponsorSeed, tx, txt, state)
+}
+
 func (w *keystoreWallet) EncryptTxWithSeed(seed common.Seed, btx *types.Transaction, txt *tx.T, state *state.StateDB) (*types.Transaction, error) {
+	return w.encryptTx(seed, nil, btx, txt, state)
+}
+
+// encryptTx selects the inputs of the transaction and encrypts it. With a
+// sponsor the fee is paid from the outs of the sponsor, which also receives
+// their change.
+func (w *keystoreWallet) encryptTx(seed common.Seed, sponsor *common.Seed, btx *types.Transaction, txt *tx.T, state *state.StateDB) (*types.Transaction, error) {
 	w.keystore.mu.Lock()
 	defer w.keystore.mu.Unlock()
 	ins := []tx.In{}
 	costTkn := txt.TokenCost()
 	costTkt := txt.TikectCost()
+	var sponsorSeed *keys.Uint256
+	if sponsor != nil {
+		sponsorSeed = sponsor.SeedToUint256()
+		cost := costTkn[txt.Fee.Currency]
+		cost.SubU(&txt.Fee.Value)
+		if cost.Cmp(&utils.U256_0) > 0 {
+			costTkn[txt.Fee.Currency] = cost
+		} else {
+			delete(costTkn, txt.Fee.Currency)
+		}
+		fee := map[keys.Uint256]utils.U256{txt.Fee.Currency: txt.Fee.Value}
+		sponsorTk := keys.Seed2Tk(sponsorSeed)
+		outs, tknMap, tktMap, err := txs.GetRoots(&sponsorTk, fee, nil, txt.MaxInNum, txt.Excluded, txt.Selection)
+		if err != nil {
+			return nil, err
+		}
+		for _, out := range outs {
+			ins = append(ins, tx.In{Root: out})
+		}
+		txt.Outs = append(txt.Outs, changeOuts(*sponsor, tknMap, tktMap)...)
+	}
 	tk := keys.Seed2Tk(seed.SeedToUint256())
-	outs, tknMap, tktMap, err := txs.GetRoots(&tk, costTkn, costTkt)
+	outs, tknMap, tktMap, err := txs.GetRoots(&tk, costTkn, costTkt, txt.MaxInNum, txt.Excluded, txt.Selection)
 	if err != nil {
 		return nil, err
 	}
 	for _, out := range outs {
 		ins = append(ins, tx.In{Root: out})
 	}
-	for cy, value := range tknMap {
-		token := &assets.Token{
-			Currency: cy,
-			Value:    value,
-		}
-		asset := assets.Asset{
-			Tkn: token,
-		}
-		selfOut := tx.Out{
-			Addr:  keys.Addr2PKr(keys.Seed2Addr(seed.SeedToUint256()).NewRef(), keys.RandUint256().NewRef()),
-			Asset: asset,
-			IsZ:   true,
-		}
-		txt.Outs = append(txt.Outs, selfOut)
-	}
-	for catg, value := range tktMap {
-		for _, v := range value {
-			ticket := &assets.Ticket{
-				Category: catg,
-				Value:    v,
-			}
-			asset := assets.Asset{
-				Tkt: ticket,
-			}
-			selfOut := tx.Out{
-				Addr:  keys.Addr2PKr(keys.Seed2Addr(seed.SeedToUint256()).NewRef(), keys.RandUint256().NewRef()),
-				Asset: asset,
-				IsZ:   true,
-			}
-			txt.Outs = append(txt.Outs, selfOut)
-		}
-
-	}
+	txt.Outs = append(txt.Outs, changeOuts(seed, tknMap, tktMap)...)
 
 	if txt.PkgClose != nil {
 		zpkg := lstate.CurrentState1().State.Pkgs.GetPkg(&txt.PkgClose.Id)
@@ -177,8 +230,12 @@ func (w *keystoreWallet) EncryptTxWithSeed(seed common.Seed, btx *types.Transact
 		if err != nil {
 			return nil, err
 		}
+		addr := zpkg.Pack.PKr
+		if txt.PkgClose.Refund {
+			addr = zpkg.From
+		}
 		selfOut := tx.Out{
-			Addr:  zpkg.Pack.PKr,
+			Addr:  addr,
 			Asset: pkg_o.Asset,
 			IsZ:   true,
 		}
@@ -196,7 +253,7 @@ func (w *keystoreWallet) EncryptTxWithSeed(seed common.Seed, btx *types.Transact
 		log.Info("    ctx_out : ", "index", i, "to", hexutil.Encode(out.Addr[:]))
 	}
 
-	stx, err := generate.Gen(seed.SeedToUint256(), txt)
+	stx, err := generate.GenWithSponsor(seed.SeedToUint256(), sponsorSeed, txt)
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +266,45 @@ func (w *keystoreWallet) EncryptTxWithSeed(seed common.Seed, btx *types.Transact
 
 }
 
+// changeOuts returns the outs giving the change of the selected inputs back
+// to the account of seed.
+func changeOuts(seed common.Seed, tknMap map[keys.Uint256]utils.U256, tktMap map[keys.Uint256][]keys.Uint256) (outs []tx.Out) {
+	for cy, value := range tknMap {
+		token := &assets.Token{
+			Currency: cy,
+			Value:    value,
+		}
+		asset := assets.Asset{
+			Tkn: token,
+		}
+		selfOut := tx.Out{
+			Addr:  keys.Addr2PKr(keys.Seed2Addr(seed.SeedToUint256()).NewRef(), keys.RandUint256().NewRef()),
+			Asset: asset,
+			IsZ:   true,
+		}
+		outs = append(outs, selfOut)
+	}
+	for catg, value := range tktMap {
+		for _, v := range value {
+			ticket := &assets.Ticket{
+				Category: catg,
+				Value:    v,
+			}
+			asset := assets.Asset{
+				Tkt: ticket,
+			}
+			selfOut := tx.Out{
+				Addr:  keys.Addr2PKr(keys.Seed2Addr(seed.SeedToUint256()).NewRef(), keys.RandUint256().NewRef()),
+				Asset: asset,
+				IsZ:   true,
+			}
+			outs = append(outs, selfOut)
+		}
+
+	}
+	return
+}
+
 func (w *keystoreWallet) EncryptTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, txt *tx.T, state *state.StateDB) (*types.Transaction, error) {
 	// Make sure the requested account is contained within
 	if account.Address != w.account.Address {
@@ -0,0 +1,124 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"errors"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/txs/tx"
+)
+
+// ErrRecipientNotAllowed is returned for transactions paying to an address
+// outside of the recipients the unlock of the sender is limited to.
+var ErrRecipientNotAllowed = errors.New("recipient not allowed by the unlock scope")
+
+// UnlockScope limits the use of an unlocked account until it is locked again,
+// so that a leaked RPC credential of a node holding unlocked accounts can not
+// spend them freely.
+type UnlockScope struct {
+	// SignOnly lets the node encrypt the transactions of the account for the
+	// caller, but not send them.
+	SignOnly bool
+	// MaxTxs is the number of transactions after which the account is locked,
+	// zero for no limit. Every transaction requested counts, even if its
+	// encryption fails.
+	MaxTxs uint64
+	// Recipients are the only addresses transactions may pay to or transfer
+	// packages to, besides the account itself. Nil allows any.
+	Recipients []common.AccountAddress
+}
+
+// allows checks the recipients of txt, sent by self.
+func (scope *UnlockScope) allows(self common.AccountAddress, txt *tx.T) error {
+	if scope.Recipients == nil {
+		return nil
+	}
+	check := func(pkr *keys.PKr) error {
+		to, ok := txt.Recipients[*pkr]
+		if !ok {
+			return ErrRecipientNotAllowed
+		}
+		if to == *self.ToUint512() {
+			return nil
+		}
+		for _, addr := range scope.Recipients {
+			if to == *addr.ToUint512() {
+				return nil
+			}
+		}
+		return ErrRecipientNotAllowed
+	}
+	for i := range txt.Outs {
+		if err := check(&txt.Outs[i].Addr); err != nil {
+			return err
+		}
+	}
+	if txt.PkgCreate != nil {
+		if err := check(&txt.PkgCreate.PKr); err != nil {
+			return err
+		}
+	}
+	if txt.PkgTransfer != nil {
+		if err := check(&txt.PkgTransfer.PKr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// useUnlocked checks a transaction of the account against the scope of its
+// unlock and counts it, txt being nil for the sponsor of the transaction, which
+// only pays its fee. It reports whether the transaction uses up the ones the
+// scope allows, the account is then to be locked once it is encrypted.
+func (ks *KeyStore) useUnlocked(a accounts.Account, txt *tx.T) (last bool, err error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	u, found := ks.unlocked[a.Address]
+	if !found || u.scope == nil {
+		// Locked accounts fail on their seed
+		return false, nil
+	}
+	if txt != nil {
+		if err := u.scope.allows(a.Address, txt); err != nil {
+			return false, err
+		}
+	}
+	if u.scope.MaxTxs == 0 {
+		return false, nil
+	}
+	if u.used >= u.scope.MaxTxs {
+		return false, ErrLocked
+	}
+	u.used++
+	return u.used == u.scope.MaxTxs, nil
+}
+
+// SendAllowed returns accounts.ErrSignOnly if the account is unlocked for
+// signing only.
+func (ks *KeyStore) SendAllowed(a accounts.Account) error {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if u, found := ks.unlocked[a.Address]; found && u.scope != nil && u.scope.SignOnly {
+		return accounts.ErrSignOnly
+	}
+	return nil
+}
@@ -114,8 +114,24 @@ func (am *Manager) update() {
 	}
 }
 
+// AddBackend starts tracking an additional backend for wallet updates, in the
+// same way as the ones the manager was created with.
+func (am *Manager) AddBackend(backend Backend) {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+
+	am.wallets = merge(am.wallets, backend.Wallets()...)
+	am.updaters = append(am.updaters, backend.Subscribe(am.updates))
+
+	kind := reflect.TypeOf(backend)
+	am.backends[kind] = append(am.backends[kind], backend)
+}
+
 // Backends retrieves the backend(s) with the given type from the account manager.
 func (am *Manager) Backends(kind reflect.Type) []Backend {
+	am.lock.RLock()
+	defer am.lock.RUnlock()
+
 	return am.backends[kind]
 }
 
@@ -161,6 +177,20 @@ func (am *Manager) Find(account Account) (Wallet, error) {
 	return nil, ErrUnknownAccount
 }
 
+// IsWatchOnly returns whether the account is known to a backend as watch-only.
+func (am *Manager) IsWatchOnly(account Account) bool {
+	wallet, err := am.Find(account)
+	if err != nil {
+		return false
+	}
+	for _, a := range wallet.Accounts() {
+		if a.Address == account.Address {
+			return a.WatchOnly
+		}
+	}
+	return false
+}
+
 // Subscribe creates an async subscription to receive notifications when the
 // manager detects the arrival or departure of a wallet from any of its backends.
 func (am *Manager) Subscribe(sink chan<- WalletEvent) event.Subscription {
@@ -73,6 +73,7 @@ var (
 		"COPYING",
 		executablePath("bootnode"),
 		executablePath("gero"),
+		executablePath("prover"),
 	}
 
 	// Files that end up in the swarm*.zip archive.
@@ -91,6 +92,10 @@ var (
 			BinaryName:  "gero",
 			Description: "Sero CLI client.",
 		},
+		{
+			BinaryName:  "prover",
+			Description: "Sero proof generation sidecar.",
+		},
 	}
 
 	// A debian package is created for all executables listed here.
@@ -0,0 +1,447 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package cdc implements a change data capture stream, continuously exporting
+// blocks, transactions, receipts and the asset movements of local accounts into
+// an external database.
+package cdc
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/sero"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// recentHashes is the number of exported block hashes remembered to find
+	// the common ancestor on a reorg.
+	recentHashes = 256
+)
+
+// Kinds of the exported records, they name the tables of the database sinks.
+const (
+	KindBlock    = "block"
+	KindTx       = "tx"
+	KindReceipt  = "receipt"
+	KindMovement = "movement"
+	KindReorg    = "reorg"
+)
+
+var errStopped = errors.New("cdc stopped")
+
+// Record is a single exported row.
+type Record struct {
+	Kind string      `json:"kind"`
+	Data interface{} `json:"data"`
+}
+
+// BlockRecord is the exported row of a block.
+type BlockRecord struct {
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	ParentHash common.Hash `json:"parentHash"`
+	Time       uint64      `json:"timestamp"`
+	Miner      string      `json:"miner"`
+	GasLimit   uint64      `json:"gasLimit"`
+	GasUsed    uint64      `json:"gasUsed"`
+	TxCount    int         `json:"txCount"`
+}
+
+// TxRecord is the exported row of a transaction.
+type TxRecord struct {
+	Number    uint64      `json:"number"`
+	BlockHash common.Hash `json:"blockHash"`
+	Index     int         `json:"index"`
+	Hash      common.Hash `json:"hash"`
+	From      string      `json:"from"`
+	To        string      `json:"to"`
+	Gas       uint64      `json:"gas"`
+	GasPrice  string      `json:"gasPrice"`
+}
+
+// ReceiptRecord is the exported row of a receipt.
+type ReceiptRecord struct {
+	Number          uint64      `json:"number"`
+	BlockHash       common.Hash `json:"blockHash"`
+	Index           int         `json:"index"`
+	TxHash          common.Hash `json:"txHash"`
+	Status          uint64      `json:"status"`
+	GasUsed         uint64      `json:"gasUsed"`
+	ContractAddress string      `json:"contractAddress"`
+	Logs            int         `json:"logs"`
+}
+
+// MovementRecord is the exported row of an out received or spent by a local
+// account. The key identifies the out: the hash of the out_o or the commitment
+// of the out_z received, the root of the out spent.
+type MovementRecord struct {
+	Number    uint64      `json:"number"`
+	BlockHash common.Hash `json:"blockHash"`
+	Account   string      `json:"account"`
+	Spent     bool        `json:"spent"`
+	Key       string      `json:"key"`
+	Currency  string      `json:"currency"`
+	Value     string      `json:"value"`
+	Category  string      `json:"category"`
+	Ticket    string      `json:"ticket"`
+}
+
+// ReorgRecord marks that all rows above Number were dropped from the canonical
+// chain and are going to be exported again.
+type ReorgRecord struct {
+	Number    uint64      `json:"number"`
+	BlockHash common.Hash `json:"blockHash"`
+	OldNumber uint64      `json:"oldNumber"`
+	OldHash   common.Hash `json:"oldHash"`
+}
+
+// cursor is the persisted export position.
+type cursor struct {
+	Next   uint64                 `json:"next"`
+	Hashes map[uint64]common.Hash `json:"hashes"`
+}
+
+// Service is the change data capture stream of a full node.
+type Service struct {
+	config   *Config
+	sero     *sero.Sero
+	mineMode bool
+	sink     Sink
+	path     string // file the cursor is persisted in
+
+	cursor cursor
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// New creates a change data capture stream, persisting its position in datadir.
+func New(config *Config, seroServ *sero.Sero, mineMode bool, datadir string) (*Service, error) {
+	if config.Interval <= 0 {
+		log.Warn("Sanitizing invalid cdc interval", "provided", config.Interval, "updated", DefaultConfig.Interval)
+		conf := *config
+		conf.Interval = DefaultConfig.Interval
+		config = &conf
+	}
+	sink, err := NewSink(config.Sink, config.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		config:   config,
+		sero:     seroServ,
+		mineMode: mineMode,
+		sink:     sink,
+		path:     datadir,
+		cursor:   cursor{Hashes: make(map[uint64]common.Hash)},
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if data, err := ioutil.ReadFile(s.path); err == nil {
+		if err := json.Unmarshal(data, &s.cursor); err != nil {
+			return nil, err
+		}
+		if s.cursor.Hashes == nil {
+			s.cursor.Hashes = make(map[uint64]common.Hash)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the cdc service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// cdc service (nil as it doesn't provide any user callable APIs).
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, starting the export loop.
+func (s *Service) Start(server *p2p.Server) error {
+	go s.loop()
+
+	log.Info("Change data capture started", "sink", s.config.Sink, "next", s.cursor.Next)
+	return nil
+}
+
+// Stop implements node.Service, terminating the export loop.
+func (s *Service) Stop() error {
+	close(s.quit)
+	<-s.done
+
+	log.Info("Change data capture stopped")
+	return s.sink.Close()
+}
+
+func (s *Service) loop() {
+	defer close(s.done)
+
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	headSub := s.sero.BlockChain().SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.export(); err == errStopped {
+			return
+		} else if err != nil {
+			log.Warn("Failed to export chain data", "next", s.cursor.Next, "err", err)
+		}
+		select {
+		case <-headCh:
+		case <-ticker.C:
+		case <-headSub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// target returns the number of the newest block that can be exported. Unless
+// the node runs in mine mode, blocks are held back until the local outs were
+// scanned for them.
+func (s *Service) target() (uint64, bool) {
+	head := s.sero.BlockChain().CurrentBlock().NumberU64()
+	if s.mineMode {
+		return head, true
+	}
+	scanned, ok := lstate.ScannedNumber()
+	if !ok {
+		return 0, false
+	}
+	if scanned < head {
+		return scanned, true
+	}
+	return head, true
+}
+
+func (s *Service) export() error {
+	chain := s.sero.BlockChain()
+
+	if err := s.rewind(); err != nil {
+		return err
+	}
+	target, ok := s.target()
+	if !ok || s.cursor.Next > target {
+		return nil
+	}
+	movements := s.movements(s.cursor.Next, target)
+
+	for number := s.cursor.Next; number <= target; number++ {
+		select {
+		case <-s.quit:
+			return errStopped
+		default:
+		}
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil
+		}
+		if err := s.exportBlock(block, chain.GetReceiptsByHash(block.Hash()), movements[number]); err != nil {
+			return err
+		}
+		if err := s.sink.Flush(); err != nil {
+			return err
+		}
+		s.cursor.Next = number + 1
+		s.cursor.Hashes[number] = block.Hash()
+		delete(s.cursor.Hashes, number-recentHashes)
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewind checks that the last exported block is still canonical, emitting a
+// reorg marker and moving the cursor back to the common ancestor if not.
+func (s *Service) rewind() error {
+	if s.cursor.Next == 0 {
+		return nil
+	}
+	chain := s.sero.BlockChain()
+
+	last := s.cursor.Next - 1
+	if header := chain.GetHeaderByNumber(last); header != nil && header.Hash() == s.cursor.Hashes[last] {
+		return nil
+	}
+	ancestor := uint64(0)
+	numbers := make([]uint64, 0, len(s.cursor.Hashes))
+	for number := range s.cursor.Hashes {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+	for _, number := range numbers {
+		if header := chain.GetHeaderByNumber(number); header != nil && header.Hash() == s.cursor.Hashes[number] {
+			ancestor = number
+			break
+		}
+	}
+	reorg := &ReorgRecord{
+		Number:    ancestor,
+		OldNumber: last,
+		OldHash:   s.cursor.Hashes[last],
+	}
+	if header := chain.GetHeaderByNumber(ancestor); header != nil {
+		reorg.BlockHash = header.Hash()
+	}
+	log.Info("Exporting chain reorg", "ancestor", ancestor, "dropped", last-ancestor)
+	if err := s.sink.Write(&Record{KindReorg, reorg}); err != nil {
+		return err
+	}
+	if err := s.sink.Flush(); err != nil {
+		return err
+	}
+	for number := range s.cursor.Hashes {
+		if number > ancestor {
+			delete(s.cursor.Hashes, number)
+		}
+	}
+	s.cursor.Next = ancestor + 1
+	return s.save()
+}
+
+func (s *Service) exportBlock(block *types.Block, receipts types.Receipts, movements []*MovementRecord) error {
+	number, hash := block.NumberU64(), block.Hash()
+	records := []*Record{{KindBlock, &BlockRecord{
+		Number:     number,
+		Hash:       hash,
+		ParentHash: block.ParentHash(),
+		Time:       block.Time().Uint64(),
+		Miner:      block.Coinbase().String(),
+		GasLimit:   block.GasLimit(),
+		GasUsed:    block.GasUsed(),
+		TxCount:    len(block.Transactions()),
+	}}}
+	for i, tx := range block.Transactions() {
+		rec := &TxRecord{
+			Number:    number,
+			BlockHash: hash,
+			Index:     i,
+			Hash:      tx.Hash(),
+			From:      tx.From().String(),
+			Gas:       tx.Gas(),
+			GasPrice:  tx.GasPrice().String(),
+		}
+		if to := tx.To(); to != nil {
+			rec.To = to.String()
+		}
+		records = append(records, &Record{KindTx, rec})
+	}
+	for i, receipt := range receipts {
+		rec := &ReceiptRecord{
+			Number:    number,
+			BlockHash: hash,
+			Index:     i,
+			TxHash:    receipt.TxHash,
+			Status:    receipt.Status,
+			GasUsed:   receipt.GasUsed,
+			Logs:      len(receipt.Logs),
+		}
+		if receipt.ContractAddress != (common.Address{}) {
+			rec.ContractAddress = receipt.ContractAddress.String()
+		}
+		records = append(records, &Record{KindReceipt, rec})
+	}
+	for _, movement := range movements {
+		movement.BlockHash = hash
+		records = append(records, &Record{KindMovement, movement})
+	}
+	for _, rec := range records {
+		if err := s.sink.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// movements collects the outs received and spent by local accounts in [from,
+// to], grouped by block number.
+func (s *Service) movements(from, to uint64) map[uint64][]*MovementRecord {
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return nil
+	}
+	movements := make(map[uint64][]*MovementRecord)
+	for _, wallet := range s.sero.AccountManager().Wallets() {
+		account := wallet.Accounts()[0]
+		for _, rec := range movementsOf(account.Address.String(), st1.GetHistory(account.Tk.ToUint512(), from, to)) {
+			movements[rec.Number] = append(movements[rec.Number], rec)
+		}
+	}
+	return movements
+}
+
+// movementsOf returns the rows of the outs an account received and spent in
+// the blocks of its balance changes.
+func movementsOf(account string, changes []*lstate.BalanceChange) (records []*MovementRecord) {
+	for _, change := range changes {
+		for _, event := range change.Events {
+			rec := &MovementRecord{
+				Number:  change.Num,
+				Account: account,
+				Spent:   event.Spent,
+			}
+			if len(event.Keys) > 0 {
+				rec.Key = hexutil.Encode(event.Keys[0][:])
+			}
+			if tkn := event.Tkn; tkn != nil {
+				rec.Currency = strings.Trim(string(tkn.Currency[:]), string([]byte{0}))
+				rec.Value = tkn.Value.ToIntRef().String()
+			}
+			if tkt := event.Tkt; tkt != nil {
+				rec.Category = strings.Trim(string(tkt.Category[:]), string([]byte{0}))
+				rec.Ticket = hexutil.Encode(tkt.Value[:])
+			}
+			records = append(records, rec)
+		}
+	}
+	return
+}
+
+func (s *Service) save() error {
+	data, err := json.Marshal(&s.cursor)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
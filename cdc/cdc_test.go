@@ -0,0 +1,81 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package cdc
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func TestMovements(t *testing.T) {
+	sero := keys.Uint256{'S', 'E', 'R', 'O'}
+	changes := []*lstate.BalanceChange{
+		{Num: 5, Events: []lstate.OutEvent{
+			{Keys: []keys.Uint256{{1}}, Tkn: &assets.Token{Currency: sero, Value: utils.U256(*big.NewInt(7))}},
+		}},
+		{Num: 9, Events: []lstate.OutEvent{
+			{Keys: []keys.Uint256{{2}, {3}}, Spent: true, Tkn: &assets.Token{Currency: sero, Value: utils.U256(*big.NewInt(7))}},
+			{Keys: []keys.Uint256{{4}}, Tkt: &assets.Ticket{Category: keys.Uint256{'T'}, Value: keys.Uint256{5}}},
+		}},
+	}
+	want := []*MovementRecord{
+		{Number: 5, Account: "a", Key: "0x01" + zeros(31), Currency: "SERO", Value: "7"},
+		{Number: 9, Account: "a", Spent: true, Key: "0x02" + zeros(31), Currency: "SERO", Value: "7"},
+		{Number: 9, Account: "a", Key: "0x04" + zeros(31), Category: "T", Ticket: "0x05" + zeros(31)},
+	}
+	if have := movementsOf("a", changes); !reflect.DeepEqual(have, want) {
+		t.Errorf("movements mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+}
+
+// zeros returns the hex encoding of n zero bytes.
+func zeros(n int) (ret string) {
+	for i := 0; i < n; i++ {
+		ret += "00"
+	}
+	return
+}
+
+func TestIntervalDefault(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cdc-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{Sink: filepath.Join(dir, "out.json")}
+	s, err := New(config, nil, false, filepath.Join(dir, "cursor"))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer s.sink.Close()
+	if s.config.Interval != DefaultConfig.Interval {
+		t.Errorf("interval mismatch: have %v, want %v", s.config.Interval, DefaultConfig.Interval)
+	}
+	if config.Interval != 0 {
+		t.Errorf("caller config modified")
+	}
+}
@@ -0,0 +1,41 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package cdc
+
+import "time"
+
+// DefaultConfig contains default settings for the change data capture stream.
+var DefaultConfig = Config{
+	Prefix:   "sero",
+	Interval: 3 * time.Second,
+}
+
+// Config contains the configuration parameters of the change data capture stream.
+type Config struct {
+	// Sink is the destination of the exported records. Supported are a path of
+	// a NDJSON file ("-" for stdout), "postgres:<path>" writing SQL statements
+	// for psql into a file or named pipe and "clickhouse:<url>" inserting over
+	// the ClickHouse HTTP interface. If this field is empty, nothing is exported.
+	Sink string `toml:",omitempty"`
+
+	// Prefix is prepended to the table names of the database sinks.
+	Prefix string `toml:",omitempty"`
+
+	// Interval is how often the stream polls for new blocks besides reacting
+	// to chain head events. Zero or negative values fall back to the default.
+	Interval time.Duration `toml:",omitempty"`
+}
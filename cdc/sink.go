@@ -0,0 +1,279 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Sink is the destination of the exported records.
+type Sink interface {
+	// Write queues a record for export.
+	Write(rec *Record) error
+
+	// Flush exports all queued records, it is called after every block.
+	Flush() error
+
+	// Close flushes and releases the sink.
+	Close() error
+}
+
+// NewSink creates the sink described by the configured sink url.
+func NewSink(sink string, prefix string) (Sink, error) {
+	switch {
+	case strings.HasPrefix(sink, "postgres:"):
+		w, err := openStream(strings.TrimPrefix(sink, "postgres:"))
+		if err != nil {
+			return nil, err
+		}
+		return &sqlSink{w: w, out: bufio.NewWriter(w), prefix: prefix}, nil
+	case strings.HasPrefix(sink, "clickhouse:"):
+		u, err := url.Parse(strings.TrimPrefix(sink, "clickhouse:"))
+		if err != nil {
+			return nil, err
+		}
+		return &clickhouseSink{url: u, prefix: prefix, rows: make(map[string]*bytes.Buffer)}, nil
+	case sink == "":
+		return nil, fmt.Errorf("empty cdc sink")
+	}
+	w, err := openStream(sink)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonSink{w: w, out: bufio.NewWriter(w)}, nil
+}
+
+func openStream(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// jsonSink writes every record as a line of JSON.
+type jsonSink struct {
+	w   io.WriteCloser
+	out *bufio.Writer
+}
+
+func (s *jsonSink) Write(rec *Record) error {
+	enc, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.out.Write(enc)
+	return s.out.WriteByte('\n')
+}
+
+func (s *jsonSink) Flush() error {
+	return s.out.Flush()
+}
+
+func (s *jsonSink) Close() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	return s.w.Close()
+}
+
+// columns flattens a record payload into its column names and values.
+func columns(data interface{}) ([]string, map[string]interface{}, error) {
+	enc, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(enc))
+	dec.UseNumber()
+
+	values := make(map[string]interface{})
+	if err := dec.Decode(&values); err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, values, nil
+}
+
+// sqlSink writes the records as SQL statements to be piped into psql. Reorg
+// markers delete the rows of all dropped blocks before they are inserted.
+type sqlSink struct {
+	w      io.WriteCloser
+	out    *bufio.Writer
+	prefix string
+	open   bool
+}
+
+func (s *sqlSink) table(kind string) string {
+	return fmt.Sprintf("%s_%s", s.prefix, kind)
+}
+
+func (s *sqlSink) Write(rec *Record) error {
+	if !s.open {
+		s.out.WriteString("BEGIN;\n")
+		s.open = true
+	}
+	if reorg, ok := rec.Data.(*ReorgRecord); ok {
+		for _, kind := range []string{KindBlock, KindTx, KindReceipt, KindMovement} {
+			fmt.Fprintf(s.out, "DELETE FROM %s WHERE \"number\" > %d;\n", s.table(kind), reorg.Number)
+		}
+	}
+	names, values, err := columns(rec.Data)
+	if err != nil {
+		return err
+	}
+	quoted := make([]string, len(names))
+	literals := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+		literals[i] = sqlLiteral(values[name])
+	}
+	_, err = fmt.Fprintf(s.out, "INSERT INTO %s (%s) VALUES (%s);\n", s.table(rec.Kind), strings.Join(quoted, ", "), strings.Join(literals, ", "))
+	return err
+}
+
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	default:
+		enc, _ := json.Marshal(v)
+		return "'" + strings.Replace(string(enc), "'", "''", -1) + "'"
+	}
+}
+
+func (s *sqlSink) Flush() error {
+	if s.open {
+		s.out.WriteString("COMMIT;\n")
+		s.open = false
+	}
+	return s.out.Flush()
+}
+
+func (s *sqlSink) Close() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	return s.w.Close()
+}
+
+// clickhouseSink inserts the records over the ClickHouse HTTP interface, one
+// batch per table and block. Reorg markers delete the rows of all dropped
+// blocks before the rows following them are inserted.
+type clickhouseSink struct {
+	url     *url.URL
+	prefix  string
+	rows    map[string]*bytes.Buffer
+	deletes []string // statements run before the rows are inserted
+}
+
+func (s *clickhouseSink) table(kind string) string {
+	return fmt.Sprintf("%s_%s", s.prefix, kind)
+}
+
+func (s *clickhouseSink) Write(rec *Record) error {
+	if reorg, ok := rec.Data.(*ReorgRecord); ok {
+		// The rows queued before belong to the dropped blocks or precede them
+		if err := s.Flush(); err != nil {
+			return err
+		}
+		for _, kind := range []string{KindBlock, KindTx, KindReceipt, KindMovement} {
+			s.deletes = append(s.deletes, fmt.Sprintf("ALTER TABLE %s DELETE WHERE number > %d", s.table(kind), reorg.Number))
+		}
+	}
+	enc, err := json.Marshal(rec.Data)
+	if err != nil {
+		return err
+	}
+	table := s.table(rec.Kind)
+	buf, ok := s.rows[table]
+	if !ok {
+		buf = new(bytes.Buffer)
+		s.rows[table] = buf
+	}
+	buf.Write(enc)
+	return buf.WriteByte('\n')
+}
+
+func (s *clickhouseSink) Flush() error {
+	for len(s.deletes) > 0 {
+		if err := s.exec(s.deletes[0], nil); err != nil {
+			return err
+		}
+		s.deletes = s.deletes[1:]
+	}
+	for table, buf := range s.rows {
+		if buf.Len() == 0 {
+			continue
+		}
+		if err := s.exec(fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table), buf.Bytes()); err != nil {
+			return err
+		}
+		buf.Reset()
+	}
+	return nil
+}
+
+// exec runs a query with the given data, waiting for the mutations it starts
+// to complete.
+func (s *clickhouseSink) exec(query string, data []byte) error {
+	u := *s.url
+	params := u.Query()
+	params.Set("query", query)
+	params.Set("mutations_sync", "1")
+	u.RawQuery = params.Encode()
+
+	resp, err := http.Post(u.String(), "application/x-ndjson", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("clickhouse query %q failed: %s: %s", query, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *clickhouseSink) Close() error {
+	return s.Flush()
+}
@@ -0,0 +1,123 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bufCloser collects the output of a stream sink.
+type bufCloser struct{ bytes.Buffer }
+
+func (*bufCloser) Close() error { return nil }
+
+func TestJSONSink(t *testing.T) {
+	w := new(bufCloser)
+	sink := &jsonSink{w: w, out: bufio.NewWriter(w)}
+	sink.Write(&Record{KindBlock, &BlockRecord{Number: 1}})
+	sink.Write(&Record{KindMovement, &MovementRecord{Number: 1, Spent: true}})
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("line count mismatch: have %d, want 2", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], `{"kind":"block","data":{"number":1,`) {
+		t.Errorf("block line mismatch: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"spent":true`) {
+		t.Errorf("movement line mismatch: %s", lines[1])
+	}
+}
+
+func TestSQLSinkReorg(t *testing.T) {
+	w := new(bufCloser)
+	sink := &sqlSink{w: w, out: bufio.NewWriter(w), prefix: "sero"}
+	sink.Write(&Record{KindReorg, &ReorgRecord{Number: 7, OldNumber: 9}})
+	sink.Write(&Record{KindBlock, &BlockRecord{Number: 8, Miner: "it's"}})
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	want := []string{
+		"BEGIN;",
+		`DELETE FROM sero_block WHERE "number" > 7;`,
+		`DELETE FROM sero_tx WHERE "number" > 7;`,
+		`DELETE FROM sero_receipt WHERE "number" > 7;`,
+		`DELETE FROM sero_movement WHERE "number" > 7;`,
+		`INSERT INTO sero_reorg ("blockHash", "number", "oldHash", "oldNumber") VALUES ('0x0000000000000000000000000000000000000000000000000000000000000000', 7, '0x0000000000000000000000000000000000000000000000000000000000000000', 9);`,
+		`INSERT INTO sero_block ("gasLimit", "gasUsed", "hash", "miner", "number", "parentHash", "timestamp", "txCount") VALUES (0, 0, '0x0000000000000000000000000000000000000000000000000000000000000000', 'it''s', 8, '0x0000000000000000000000000000000000000000000000000000000000000000', 0, 0);`,
+		"COMMIT;",
+	}
+	if have := strings.Split(strings.TrimSpace(w.String()), "\n"); strings.Join(have, "\n") != strings.Join(want, "\n") {
+		t.Errorf("statements mismatch:\nhave %s\nwant %s", strings.Join(have, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestClickHouseSinkReorg(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		queries = append(queries, r.URL.Query().Get("query")+"|"+strings.TrimSpace(string(body)))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink("clickhouse:"+server.URL, "sero")
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	// Rows queued before a reorg are inserted before the deletes
+	sink.Write(&Record{KindTx, &TxRecord{Number: 9}})
+	sink.Write(&Record{KindReorg, &ReorgRecord{Number: 7, OldNumber: 9}})
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	want := []string{
+		"INSERT INTO sero_tx FORMAT JSONEachRow|",
+		"ALTER TABLE sero_block DELETE WHERE number > 7|",
+		"ALTER TABLE sero_tx DELETE WHERE number > 7|",
+		"ALTER TABLE sero_receipt DELETE WHERE number > 7|",
+		"ALTER TABLE sero_movement DELETE WHERE number > 7|",
+		"INSERT INTO sero_reorg FORMAT JSONEachRow|",
+	}
+	if len(queries) != len(want) {
+		t.Fatalf("query count mismatch: have %d, want %d: %v", len(queries), len(want), queries)
+	}
+	for i := range want {
+		if !strings.HasPrefix(queries[i], want[i]) {
+			t.Errorf("query %d mismatch: have %s, want %s", i, queries[i], want[i])
+		}
+	}
+	// A failing delete is retried on the next flush
+	queries = nil
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	sink.Write(&Record{KindReorg, &ReorgRecord{Number: 3}})
+	if err := sink.Flush(); err == nil {
+		t.Fatalf("failed delete not reported")
+	}
+	if pending := len(sink.(*clickhouseSink).deletes); pending != 4 {
+		t.Errorf("pending delete count mismatch: have %d, want 4", pending)
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"runtime"
 	"strconv"
@@ -28,6 +29,7 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/console"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/era"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/log"
 	"github.com/sero-cash/go-sero/sero/downloader"
@@ -74,6 +76,78 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	exportEraCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportEra),
+		Name:      "export-era",
+		Usage:     "Export finalized history into era archives",
+		ArgsUsage: "<dir> [<epochFirst> <epochLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.AlphanetFlag,
+			utils.DeveloperFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Writes one immutable archive per epoch of 8192 blocks into the given
+directory. Every archive contains the headers, bodies, receipts and zstate
+block records of its epoch and is named after its content root. Optional
+second and third arguments limit the range of exported epochs. Only epochs
+buried under at least 1024 blocks are exported.`,
+	}
+	importEraCommand = cli.Command{
+		Action:    utils.MigrateFlags(importEra),
+		Name:      "import-era",
+		Usage:     "Import history from era archives",
+		ArgsUsage: "<file|dir> ( <file|dir> ... ) ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Every archive is fully verified against its content address before its
+blocks are imported. The zstate block records produced by the import are
+compared against the archived ones. Directories are imported in epoch order.
+The canonical blocks already known without their bodies or receipts, such as
+the blocks below an imported state snapshot, get them restored instead.`,
+	}
+	exportStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportState),
+		Name:      "export-state",
+		Usage:     "Export a trusted state snapshot of a block",
+		ArgsUsage: "<filename> [<blockNum>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.AlphanetFlag,
+			utils.DeveloperFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Writes the header chain, the body, the zstate block records and the full state
+of the given block, or of the head block if none is given, into a snapshot file.
+If the file ends with .gz, the output will be gzipped.`,
+	}
+	importStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(importState),
+		Name:      "import-state",
+		Usage:     "Initialize an empty node from a trusted state snapshot",
+		ArgsUsage: "<filename> <blockHash>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.AlphanetFlag,
+			utils.DeveloperFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The hash of the snapshot block has to be obtained from a trusted source. The
+header chain is verified from the local genesis up to that hash, the state
+against the root of its header and the zstate block records against the state.
+The chain is only initialized once everything was verified. The database must
+not contain any block beyond genesis.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -223,7 +297,7 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*serodb.LDBDatabase)
+	db := hotDatabase(chainDb)
 
 	stats, err := db.LDB().GetProperty("leveldb.stats")
 	if err != nil {
@@ -308,13 +382,126 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func eraNetwork(ctx *cli.Context) string {
+	switch {
+	case ctx.GlobalBool(utils.AlphanetFlag.Name):
+		return "alpha"
+	case ctx.GlobalBool(utils.DeveloperFlag.Name):
+		return "dev"
+	}
+	return "sero"
+}
+
+func exportEra(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+	start := time.Now()
+
+	first, last := uint64(0), uint64(math.MaxUint64)
+	if len(ctx.Args()) >= 3 {
+		var ferr, lerr error
+		first, ferr = strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		last, lerr = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: epoch not a positive integer\n")
+		}
+	}
+	if err := utils.ExportEra(chain, ctx.Args().First(), eraNetwork(ctx), first, last); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importEra(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	var files []string
+	for _, arg := range ctx.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			utils.Fatalf("Import error: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := era.Files(arg)
+		if err != nil {
+			utils.Fatalf("Import error: %v", err)
+		}
+		files = append(files, matches...)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	for _, fn := range files {
+		if err := utils.ImportEra(chain, chainDb, fn); err != nil {
+			chain.Stop()
+			utils.Fatalf("Import error: %s: %v", fn, err)
+		}
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+func exportState(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+	start := time.Now()
+
+	number := chain.CurrentBlock().NumberU64()
+	if len(ctx.Args()) >= 2 {
+		var err error
+		if number, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+		}
+	}
+	if err := utils.ExportState(chain, ctx.Args().First(), eraNetwork(ctx), number); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importState(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires two arguments.")
+	}
+	var trusted common.Hash
+	if err := trusted.UnmarshalText([]byte(ctx.Args().Get(1))); err != nil {
+		utils.Fatalf("Import error: invalid block hash: %v", err)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	chain.Stop()
+	defer chainDb.Close()
+
+	start := time.Now()
+	if err := utils.ImportState(chainDb, ctx.Args().First(), eraNetwork(ctx), trusted); err != nil {
+		utils.Fatalf("Import error: %v", err)
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*serodb.LDBDatabase)
+	diskdb := hotDatabase(utils.MakeChainDatabase(ctx, stack))
 
 	start := time.Now()
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -330,7 +517,7 @@ func exportPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*serodb.LDBDatabase)
+	diskdb := hotDatabase(utils.MakeChainDatabase(ctx, stack))
 
 	start := time.Now()
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -380,7 +567,7 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*serodb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = hotDatabase(chainDb).LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -449,3 +636,12 @@ func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
 	return err != nil
 }
+
+// hotDatabase returns the LevelDB database of the chain data, the hot one if
+// the chain database is split.
+func hotDatabase(db serodb.Database) *serodb.LDBDatabase {
+	if split, ok := db.(*serodb.SplitDatabase); ok {
+		return split.Hot()
+	}
+	return db.(*serodb.LDBDatabase)
+}
@@ -28,6 +28,7 @@ import (
 	"gopkg.in/urfave/cli.v1"
 
 	"github.com/naoina/toml"
+	"github.com/sero-cash/go-sero/cdc"
 	"github.com/sero-cash/go-sero/cmd/utils"
 	"github.com/sero-cash/go-sero/dashboard"
 	"github.com/sero-cash/go-sero/node"
@@ -50,6 +51,10 @@ var (
 		Name:  "config",
 		Usage: "TOML configuration file",
 	}
+	grpcAddrFlag = cli.StringFlag{
+		Name:  "grpc.addr",
+		Usage: "Listening address of the gRPC gateway serving the main wallet RPCs (e.g. 127.0.0.1:9545), disabled if empty; needs a build with the grpc tag",
+	}
 )
 
 // These settings ensure that TOML keys use the same names as Go struct fields.
@@ -73,11 +78,17 @@ type serostatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+type grpcConfig struct {
+	Addr string `toml:",omitempty"`
+}
+
 type seroConfig struct {
 	Sero      sero.Config
 	Node      node.Config
 	Serostats serostatsConfig
 	Dashboard dashboard.Config
+	CDC       cdc.Config
+	GRPC      grpcConfig
 }
 
 func loadConfig(file string, cfg *seroConfig) error {
@@ -111,6 +122,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, seroConfig) {
 		Sero:      sero.DefaultConfig,
 		Node:      defaultNodeConfig(),
 		Dashboard: dashboard.DefaultConfig,
+		CDC:       cdc.DefaultConfig,
 	}
 
 	// Load config file.
@@ -130,8 +142,12 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, seroConfig) {
 	if ctx.GlobalIsSet(utils.SeroStatsURLFlag.Name) {
 		cfg.Serostats.URL = ctx.GlobalString(utils.SeroStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(grpcAddrFlag.Name) {
+		cfg.GRPC.Addr = ctx.GlobalString(grpcAddrFlag.Name)
+	}
 
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
+	utils.SetCDCConfig(ctx, &cfg.CDC)
 
 	return stack, cfg
 }
@@ -144,6 +160,12 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
 		utils.RegisterDashboardService(stack, &cfg.Dashboard, gitCommit)
 	}
+	if cfg.CDC.Sink != "" {
+		utils.RegisterCDCService(stack, &cfg.CDC, cfg.Sero.MineMode)
+	}
+	if cfg.GRPC.Addr != "" {
+		registerGRPCService(stack, &cfg.GRPC)
+	}
 
 	return stack
 }
@@ -0,0 +1,59 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build grpc
+// +build grpc
+
+package main
+
+import (
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/node"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/sero"
+	"github.com/sero-cash/go-sero/sero/grpcapi"
+)
+
+// grpcService runs the gRPC gateway as a service of the node, next to the
+// Sero service it serves.
+type grpcService struct {
+	srv *grpcapi.Server
+}
+
+func (s *grpcService) Protocols() []p2p.Protocol { return nil }
+func (s *grpcService) APIs() []rpc.API           { return nil }
+
+func (s *grpcService) Start(*p2p.Server) error { return s.srv.Start() }
+
+func (s *grpcService) Stop() error {
+	s.srv.Stop()
+	return nil
+}
+
+// registerGRPCService adds the gRPC gateway to the stack.
+func registerGRPCService(stack *node.Node, cfg *grpcConfig) {
+	err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var seroServ *sero.Sero
+		if err := ctx.Service(&seroServ); err != nil {
+			return nil, err
+		}
+		return &grpcService{grpcapi.New(cfg.Addr, seroServ.APIBackend, seroServ.TxPoolAPI())}, nil
+	})
+	if err != nil {
+		utils.Fatalf("Failed to register the gRPC gateway: %v", err)
+	}
+}
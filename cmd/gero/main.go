@@ -47,6 +47,7 @@ import (
 	"github.com/sero-cash/go-sero/node"
 	"github.com/sero-cash/go-sero/sero"
 	"github.com/sero-cash/go-sero/seroclient"
+	"github.com/sero-cash/go-sero/zero/txs/generate"
 )
 
 const (
@@ -68,11 +69,20 @@ var (
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
+		utils.ColdDataDirFlag,
+		utils.ColdTablesFlag,
+		utils.HotBlocksFlag,
+		utils.EraDirFlag,
+		utils.ZKParamsDirFlag,
+		utils.ProverFlag,
+		utils.ProverRemoteFlag,
 		utils.NoUSBFlag,
 		utils.DashboardEnabledFlag,
 		utils.DashboardAddrFlag,
 		utils.DashboardPortFlag,
 		utils.DashboardRefreshFlag,
+		utils.CDCSinkFlag,
+		utils.CDCPrefixFlag,
 		utils.EthashCacheDirFlag,
 		utils.EthashCachesInMemoryFlag,
 		utils.EthashCachesOnDiskFlag,
@@ -81,14 +91,21 @@ var (
 		utils.EthashDatasetsOnDiskFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolPriceLimitFlag,
+		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolLocalSlotsFlag,
+		utils.TxPoolEvictionFlag,
 		utils.SyncModeFlag,
+		utils.LightServFlag,
+		utils.LightPeersFlag,
 		utils.MiningModeFlag,
 		utils.GCModeFlag,
+		utils.ReceiptRetentionFlag,
+		utils.ZStateRetentionFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
@@ -97,6 +114,7 @@ var (
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.SerobaseFlag,
+		utils.MinerPayoutsFlag,
 		utils.GasPriceFlag,
 		utils.VThreadsFlag,
 		utils.PThreadsFlag,
@@ -124,7 +142,19 @@ var (
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMaxPriceFlag,
+		utils.LstateScanWorkersFlag,
+		utils.ReplicaListenFlag,
+		utils.ReplicaPrimaryFlag,
+		utils.ReplicaCertFlag,
+		utils.ReplicaKeyFlag,
+		utils.ReplicaCAFlag,
+		utils.ReplicaAccountsFlag,
+		utils.ReplicaIntervalFlag,
 		utils.ExtraDataFlag,
+		utils.StratumAddrFlag,
+		utils.StratumDifficultyFlag,
+		utils.StratumShareTimeFlag,
 		configFileFlag,
 	}
 
@@ -136,6 +166,34 @@ var (
 		utils.RPCReadTimeoutFlag,
 		utils.RPCWriteTimeoutFlag,
 		utils.RPCIdleTimeoutFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCBatchResponseSizeFlag,
+		utils.RPCExecTimeLimitFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCConcurrentCallsFlag,
+		utils.RPCConnectionCallsFlag,
+		utils.RPCMethodTimesFlag,
+		utils.RPCMethodsFlag,
+		utils.WSMethodsFlag,
+		utils.IPCMethodsFlag,
+		utils.RPCAuthModulesFlag,
+		utils.RPCAuthTokensFlag,
+		utils.RPCAuditFlag,
+		utils.RPCAuditMaxSizeFlag,
+		utils.RPCGasCapFlag,
+		utils.RPCEstimateGasCapFlag,
+		utils.RPCCallCacheFlag,
+		utils.TxDefaultGasFlag,
+		utils.TxDefaultGasCyFlag,
+		utils.TxMaxMemoFlag,
+		utils.TxMinConfFlag,
+		utils.TxSelectionFlag,
+		utils.TxRelayFlag,
+		grpcAddrFlag,
+		utils.DailyStatsFlag,
+		utils.MemoIndexFlag,
+		utils.BlockHooksFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
@@ -152,6 +210,7 @@ var (
 		utils.MetricsInfluxDBUsernameFlag,
 		utils.MetricsInfluxDBPasswordFlag,
 		utils.MetricsInfluxDBHostTagFlag,
+		utils.MetricsPrometheusAddrFlag,
 	}
 )
 
@@ -164,6 +223,10 @@ func init() {
 		// See chaincmd.go:
 		importCommand,
 		exportCommand,
+		importEraCommand,
+		exportEraCommand,
+		importStateCommand,
+		exportStateCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		copydbCommand,
@@ -211,7 +274,11 @@ func init() {
 			case ctx.GlobalBool(utils.DeveloperFlag.Name):
 				netType = cpt.NET_Dev
 			}
-			cpt.ZeroInit(getKeyStore(ctx), netType)
+			keystore := getKeyStore(ctx)
+			generate.CheckProver(cpt.ZeroInit(keystore, netType))
+			generate.SetParams(ctx.GlobalString(utils.ZKParamsDirFlag.Name), func() error {
+				return cpt.ZeroInit(keystore, netType)
+			})
 
 		}
 
@@ -240,6 +307,8 @@ func init() {
 		log.Debug("Sanitizing Go's GC trigger", "percent", int(gogc))
 		godebug.SetGCPercent(int(gogc))
 
+		utils.SetupProver(ctx)
+
 		// Start metrics export if enabled
 		utils.SetupMetrics(ctx)
 
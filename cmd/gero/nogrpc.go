@@ -0,0 +1,31 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !grpc
+// +build !grpc
+
+package main
+
+import (
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/node"
+)
+
+// registerGRPCService refuses to start a node asking for the gRPC gateway
+// without it being built in.
+func registerGRPCService(stack *node.Node, cfg *grpcConfig) {
+	utils.Fatalf("Option %q: gero was built without the gRPC gateway, rebuild it with -tags grpc", grpcAddrFlag.Name)
+}
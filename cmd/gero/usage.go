@@ -70,10 +70,19 @@ var AppHelpFlagGroups = []flagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
+			utils.ColdDataDirFlag,
+			utils.ColdTablesFlag,
+			utils.HotBlocksFlag,
+			utils.EraDirFlag,
+			utils.ZKParamsDirFlag,
+			utils.ProverFlag,
+			utils.ProverRemoteFlag,
 			utils.NetworkIdFlag,
 			utils.AlphanetFlag,
 			utils.DeveloperFlag,
 			utils.SyncModeFlag,
+			utils.LightServFlag,
+			utils.LightPeersFlag,
 			utils.SeroStatsURLFlag,
 			utils.IdentityFlag,
 		},
@@ -100,11 +109,14 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolPriceLimitFlag,
+			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolLocalSlotsFlag,
+			utils.TxPoolEvictionFlag,
 		},
 	},
 	{
@@ -133,6 +145,34 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCReadTimeoutFlag,
 			utils.RPCWriteTimeoutFlag,
 			utils.RPCIdleTimeoutFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCBatchResponseSizeFlag,
+			utils.RPCExecTimeLimitFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCConcurrentCallsFlag,
+			utils.RPCConnectionCallsFlag,
+			utils.RPCMethodTimesFlag,
+			utils.RPCMethodsFlag,
+			utils.WSMethodsFlag,
+			utils.IPCMethodsFlag,
+			utils.RPCAuthModulesFlag,
+			utils.RPCAuthTokensFlag,
+			utils.RPCAuditFlag,
+			utils.RPCAuditMaxSizeFlag,
+			utils.RPCGasCapFlag,
+			utils.RPCEstimateGasCapFlag,
+			utils.RPCCallCacheFlag,
+			utils.TxDefaultGasFlag,
+			utils.TxDefaultGasCyFlag,
+			utils.TxMaxMemoFlag,
+			utils.TxMinConfFlag,
+			utils.TxSelectionFlag,
+			utils.TxRelayFlag,
+			grpcAddrFlag,
+			utils.DailyStatsFlag,
+			utils.MemoIndexFlag,
+			utils.BlockHooksFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
@@ -170,9 +210,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MiningEnabledFlag,
 			utils.MinerThreadsFlag,
 			utils.SerobaseFlag,
+			utils.MinerPayoutsFlag,
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.StratumAddrFlag,
+			utils.StratumDifficultyFlag,
+			utils.StratumShareTimeFlag,
 		},
 	},
 	{
@@ -180,6 +224,25 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMaxPriceFlag,
+		},
+	},
+	{
+		Name: "LSTATE",
+		Flags: []cli.Flag{
+			utils.LstateScanWorkersFlag,
+		},
+	},
+	{
+		Name: "LSTATE REPLICATION",
+		Flags: []cli.Flag{
+			utils.ReplicaListenFlag,
+			utils.ReplicaPrimaryFlag,
+			utils.ReplicaCertFlag,
+			utils.ReplicaKeyFlag,
+			utils.ReplicaCAFlag,
+			utils.ReplicaAccountsFlag,
+			utils.ReplicaIntervalFlag,
 		},
 	},
 	{
@@ -205,6 +268,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MetricsInfluxDBUsernameFlag,
 			utils.MetricsInfluxDBPasswordFlag,
 			utils.MetricsInfluxDBHostTagFlag,
+			utils.MetricsPrometheusAddrFlag,
 		},
 	},
 	{
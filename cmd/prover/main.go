@@ -0,0 +1,122 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// prover runs the proof generation of transactions for nodes started with
+// --prover, in a process of its own.
+//
+// The nodes send the spending seeds of their accounts with the descriptions
+// to prove, so the endpoints must only be reachable by them: the HTTP endpoint
+// is refused on other than loopback addresses unless -remote is set, and has no
+// authentication of its own.
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/prover"
+	"github.com/sero-cash/go-sero/zero/txs/generate"
+)
+
+func main() {
+	var (
+		ipcPath   = flag.String("ipcpath", "prover.ipc", "path of the IPC endpoint, empty to disable")
+		httpAddr  = flag.String("http", "", "listen address of the HTTP endpoint (e.g. 127.0.0.1:8546), empty to disable")
+		remote    = flag.Bool("remote", false, "allow the HTTP endpoint on other than loopback addresses; its callers get the spending seeds of the nodes' accounts")
+		vhosts    = flag.String("vhosts", "localhost", "comma separated host names the HTTP endpoint accepts requests for (* for any)")
+		network   = flag.String("net", "beta", "network of the proving parameters (beta|alpha|dev)")
+		keystore  = flag.String("keystore", "", "directory passed to the initialization of the czero library")
+		zkparams  = flag.String("zkparams", "", "directory of the zero-knowledge parameters, verified against its "+generate.ParamsManifest+" file")
+		threads   = flag.Int("threads", runtime.NumCPU(), "number of proofs generated at the same time")
+		verbosity = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+		vmodule   = flag.String("vmodule", "", "log verbosity pattern")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	glogger.Vmodule(*vmodule)
+	log.Root().SetHandler(glogger)
+
+	if *ipcPath == "" && *httpAddr == "" {
+		utils.Fatalf("Use -ipcpath or -http to specify an endpoint")
+	}
+	netType := cpt.NET_Beta
+	switch *network {
+	case "beta":
+	case "alpha":
+		netType = cpt.NET_Alpha
+	case "dev":
+		netType = cpt.NET_Dev
+	default:
+		utils.Fatalf("-net: unknown network %q", *network)
+	}
+	if err := generate.CheckProver(cpt.ZeroInit(*keystore, netType)); err != nil {
+		utils.Fatalf("czero: %v", err)
+	}
+	if *zkparams != "" {
+		if err := generate.SetParams(*zkparams, func() error { return cpt.ZeroInit(*keystore, netType) }); err != nil {
+			utils.Fatalf("-zkparams: %v", err)
+		}
+	}
+
+	apis := []rpc.API{{
+		Namespace: prover.Namespace,
+		Version:   "1.0",
+		Service:   prover.NewAPI(*threads),
+		Public:    true,
+	}}
+	if *ipcPath != "" {
+		listener, _, err := rpc.StartIPCEndpoint(*ipcPath, apis, rpc.Access{}, nil)
+		if err != nil {
+			utils.Fatalf("-ipcpath: %v", err)
+		}
+		defer listener.Close()
+		log.Info("IPC endpoint opened", "url", *ipcPath)
+	}
+	if *httpAddr != "" {
+		host, _, err := net.SplitHostPort(*httpAddr)
+		if err != nil {
+			utils.Fatalf("-http: %v", err)
+		}
+		if !prover.IsLoopback(host) {
+			if !*remote {
+				utils.Fatalf("-http: %s is not a loopback address, the callers would get the spending seeds of the nodes' accounts; use -remote to allow it", *httpAddr)
+			}
+			log.Warn("HTTP endpoint reachable from other hosts, its callers get the spending seeds of the nodes' accounts", "addr", *httpAddr)
+		}
+		listener, _, err := rpc.StartHTTPEndpoint(*httpAddr, apis, []string{prover.Namespace}, nil, strings.Split(*vhosts, ","), rpc.DefaultHTTPTimeouts, rpc.Limits{}, rpc.Access{}, nil)
+		if err != nil {
+			utils.Fatalf("-http: %v", err)
+		}
+		defer listener.Close()
+		log.Info("HTTP endpoint opened", "url", "http://"+*httpAddr)
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Got interrupt, shutting down...")
+}
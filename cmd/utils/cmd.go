@@ -23,13 +23,17 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
 
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/era"
 	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/snapshot"
+	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/crypto"
 	"github.com/sero-cash/go-sero/internal/debug"
@@ -310,3 +314,171 @@ func ExportPreimages(db *serodb.LDBDatabase, fn string) error {
 	log.Info("Exported preimages", "file", fn)
 	return nil
 }
+
+// ExportEra writes every finalized epoch in [first, last] as a content addressed
+// history archive into dir.
+func ExportEra(blockchain *core.BlockChain, dir string, network string, first uint64, last uint64) error {
+	head := blockchain.CurrentBlock().NumberU64()
+	if head < era.FinalityDepth+era.EpochSize-1 {
+		return fmt.Errorf("no finalized epoch available at head #%d", head)
+	}
+	if final := (head-era.FinalityDepth+1)/era.EpochSize - 1; last > final {
+		log.Warn("Limiting era export to finalized epochs", "requested", last, "final", final)
+		last = final
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	statedb, err := blockchain.State()
+	if err != nil {
+		return err
+	}
+	for epoch := first; epoch <= last; epoch++ {
+		tmp := filepath.Join(dir, fmt.Sprintf(".era-%05d.tmp", epoch))
+		fh, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		root, err := writeEraEpoch(blockchain, statedb, fh, network, epoch)
+		fh.Close()
+		if err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("epoch %d: %v", epoch, err)
+		}
+		fn := filepath.Join(dir, era.Filename(network, epoch, root))
+		if err := os.Rename(tmp, fn); err != nil {
+			return err
+		}
+		log.Info("Exported era archive", "epoch", epoch, "file", fn, "root", root)
+	}
+	return nil
+}
+
+func writeEraEpoch(blockchain *core.BlockChain, statedb *state.StateDB, w io.Writer, network string, epoch uint64) (common.Hash, error) {
+	writer, err := era.NewWriter(w, network, epoch)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	for number := epoch * era.EpochSize; number < (epoch+1)*era.EpochSize; number++ {
+		block := blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return common.Hash{}, fmt.Errorf("block #%d not found", number)
+		}
+		receipts := blockchain.GetReceiptsByHash(block.Hash())
+		if len(receipts) != len(block.Transactions()) {
+			return common.Hash{}, fmt.Errorf("receipts of block #%d not available", number)
+		}
+		zblock, err := statedb.GetZState().GetBlock(number, block.Hash().HashToUint256()).Serial()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if err := writer.Add(era.NewEntry(block, receipts, zblock)); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return writer.Finalize()
+}
+
+// ImportEra verifies a history archive and imports its blocks, restoring the
+// bodies and receipts of the canonical blocks missing them.
+func ImportEra(chain *core.BlockChain, db serodb.Database, fn string) error {
+	return era.Import(chain, db, fn, nil)
+}
+
+// ExportState writes a trusted state snapshot of the given canonical block,
+// holding the header chain, the block body, the zstate block records and the
+// full state of the block.
+func ExportState(blockchain *core.BlockChain, fn string, network string, number uint64) error {
+	log.Info("Exporting state snapshot", "file", fn, "number", number)
+
+	block := blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	if number == 0 {
+		return fmt.Errorf("cannot snapshot the genesis block")
+	}
+	if tail := blockchain.ZStateTail(); tail > 1 {
+		return fmt.Errorf("cannot snapshot the zstate records, pruned below %d", tail)
+	}
+	statedb, err := blockchain.StateAt(block.Root(), number)
+	if err != nil {
+		return err
+	}
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		gz := gzip.NewWriter(writer)
+		defer gz.Close()
+		writer = gz
+	}
+	snap, err := snapshot.NewWriter(writer, network, number, block.Hash(), blockchain.Genesis().Hash())
+	if err != nil {
+		return err
+	}
+	for n := uint64(1); n <= number; n++ {
+		header := blockchain.GetHeaderByNumber(n)
+		if header == nil {
+			return fmt.Errorf("header #%d not found", n)
+		}
+		zblock, err := statedb.GetZState().GetBlock(n, header.Hash().HashToUint256()).Serial()
+		if err != nil {
+			return err
+		}
+		if err := snap.AddHeader(&snapshot.Entry{Header: header, ZBlock: zblock}); err != nil {
+			return err
+		}
+	}
+	if err := snap.AddBody(block.Body()); err != nil {
+		return err
+	}
+	triedb := blockchain.StateCache().TrieDB()
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		blob, err := triedb.Node(it.Hash)
+		if err != nil {
+			return err
+		}
+		if err := snap.AddNode(it.Hash, blob); err != nil {
+			return err
+		}
+	}
+	if it.Error != nil {
+		return it.Error
+	}
+	log.Info("Exported state snapshot", "file", fn)
+	return nil
+}
+
+// ImportState initializes an empty chain database from a state snapshot,
+// verifying it against the trusted hash of the snapshot block.
+func ImportState(db serodb.Database, fn string, network string, trusted common.Hash) error {
+	log.Info("Importing state snapshot", "file", fn, "trusted", trusted)
+
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	head, err := snapshot.Import(db, reader, network, trusted)
+	if err != nil {
+		return err
+	}
+	log.Info("Imported state snapshot", "number", head.Number, "hash", trusted, "root", head.Root)
+	return nil
+}
@@ -18,9 +18,11 @@
 package utils
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -28,6 +30,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sero-cash/go-sero/zero/prover"
+	"github.com/sero-cash/go-sero/zero/txs"
 	"github.com/sero-cash/go-sero/zero/txs/generate"
 	"github.com/sero-cash/go-sero/zero/txs/verify"
 
@@ -35,6 +39,7 @@ import (
 
 	"github.com/sero-cash/go-sero/accounts"
 	"github.com/sero-cash/go-sero/accounts/keystore"
+	"github.com/sero-cash/go-sero/cdc"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/fdlimit"
 	"github.com/sero-cash/go-sero/consensus"
@@ -45,9 +50,12 @@ import (
 	"github.com/sero-cash/go-sero/core/vm"
 	"github.com/sero-cash/go-sero/crypto"
 	"github.com/sero-cash/go-sero/dashboard"
+	"github.com/sero-cash/go-sero/les"
 	"github.com/sero-cash/go-sero/log"
 	"github.com/sero-cash/go-sero/metrics"
 	"github.com/sero-cash/go-sero/metrics/influxdb"
+	"github.com/sero-cash/go-sero/metrics/prometheus"
+	"github.com/sero-cash/go-sero/miner"
 	"github.com/sero-cash/go-sero/node"
 	"github.com/sero-cash/go-sero/p2p"
 	"github.com/sero-cash/go-sero/p2p/discover"
@@ -58,6 +66,8 @@ import (
 	"github.com/sero-cash/go-sero/sero"
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/gasprice"
+	"github.com/sero-cash/go-sero/sero/replica"
+	"github.com/sero-cash/go-sero/sero/stratum"
 	"github.com/sero-cash/go-sero/serodb"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -125,6 +135,36 @@ var (
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
 	}
+	ColdDataDirFlag = DirectoryFlag{
+		Name:  "datadir.cold",
+		Usage: "Data directory of the cold chain database keeping the tables of old blocks, e.g. on a cheaper volume (default = all chain data inside the datadir)",
+	}
+	ColdTablesFlag = cli.StringFlag{
+		Name:  "datadir.cold.tables",
+		Usage: `Comma separated tables kept in the cold chain database ("headers", "bodies", "receipts"), moved online when changed`,
+		Value: strings.Join(sero.DefaultConfig.ColdTables, ","),
+	}
+	HotBlocksFlag = cli.Uint64Flag{
+		Name:  "datadir.cold.hotblocks",
+		Usage: "Number of recent blocks whose tables stay in the datadir when a cold chain database is used",
+		Value: sero.DefaultConfig.HotBlocks,
+	}
+	EraDirFlag = DirectoryFlag{
+		Name:  "era.dir",
+		Usage: "Directory of era history archives imported while syncing, restoring the bodies and receipts missing below a state snapshot",
+	}
+	ZKParamsDirFlag = DirectoryFlag{
+		Name:  "zkparams",
+		Usage: "Directory of the zero-knowledge parameters, verified against the hashes of its " + generate.ParamsManifest + " file (not verified if empty)",
+	}
+	ProverFlag = cli.StringFlag{
+		Name:  "prover",
+		Usage: "IPC path or loopback URL of a prover process generating the proofs of transactions instead of the node",
+	}
+	ProverRemoteFlag = cli.BoolFlag{
+		Name:  "prover.remote",
+		Usage: "Allow a prover on another host; it sees the spending seeds of the accounts",
+	}
 	NoUSBFlag = cli.BoolFlag{
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
@@ -163,9 +203,19 @@ var (
 	defaultSyncMode = sero.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "snap")`,
 		Value: &defaultSyncMode,
 	}
+	LightServFlag = cli.IntFlag{
+		Name:  "lightserv",
+		Usage: "Maximum percentage of the CPUs serving light clients (0 disables the light server)",
+		Value: 0,
+	}
+	LightPeersFlag = cli.IntFlag{
+		Name:  "lightpeers",
+		Usage: "Maximum number of light clients served",
+		Value: sero.DefaultConfig.LightPeers,
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
@@ -173,9 +223,17 @@ var (
 	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
-		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Usage: `Blockchain garbage collection mode ("full", "archive", "wallet")`,
 		Value: "full",
 	}
+	ReceiptRetentionFlag = cli.Uint64Flag{
+		Name:  "gcmode.receipts",
+		Usage: "Number of recent blocks to keep receipts and logs for in full gc mode (0 = keep all)",
+	}
+	ZStateRetentionFlag = cli.Uint64Flag{
+		Name:  "gcmode.zstate",
+		Usage: "Number of recent blocks to keep zstate block records for in full gc mode, records with unspent local outs are kept (0 = keep all)",
+	}
 	DashboardAddrFlag = cli.StringFlag{
 		Name:  "dashboard.addr",
 		Usage: "Dashboard listening interface",
@@ -191,6 +249,16 @@ var (
 		Usage: "Dashboard metrics collection refresh rate",
 		Value: dashboard.DefaultConfig.Refresh,
 	}
+	// Change data capture settings
+	CDCSinkFlag = cli.StringFlag{
+		Name:  "cdc.sink",
+		Usage: `Change data capture sink (NDJSON file path, "-", "postgres:<path>" or "clickhouse:<url>")`,
+	}
+	CDCPrefixFlag = cli.StringFlag{
+		Name:  "cdc.prefix",
+		Usage: "Table name prefix of the change data capture database sinks",
+		Value: cdc.DefaultConfig.Prefix,
+	}
 	// Ethash settings
 	EthashCacheDirFlag = DirectoryFlag{
 		Name:  "ethash.cachedir",
@@ -231,6 +299,11 @@ var (
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
 		Value: sero.DefaultConfig.TxPool.PriceLimit,
 	}
+	TxPoolPriceBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.pricebump",
+		Usage: "Price bump percentage to replace a pooled transaction spending the same outs",
+		Value: sero.DefaultConfig.TxPool.PriceBump,
+	}
 	TxPoolAccountSlotsFlag = cli.Uint64Flag{
 		Name:  "txpool.accountslots",
 		Usage: "Minimum number of executable transaction slots guaranteed per account",
@@ -256,6 +329,16 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: sero.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolLocalSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.localslots",
+		Usage: "Transaction slots reserved for locally submitted transactions",
+		Value: sero.DefaultConfig.TxPool.LocalSlots,
+	}
+	TxPoolEvictionFlag = cli.StringFlag{
+		Name:  "txpool.eviction",
+		Usage: `Remote transaction evicted from a full pool ("price" for the cheapest, "age" for the oldest)`,
+		Value: sero.DefaultConfig.TxPool.Eviction,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -313,6 +396,10 @@ var (
 		Usage: "Public address for block mining rewards (default = first account created)",
 		Value: "0",
 	}
+	MinerPayoutsFlag = cli.StringFlag{
+		Name:  "minerpayouts",
+		Usage: "Comma separated account:percent shares of the block rewards paid besides the serobase",
+	}
 	GasPriceFlag = BigFlag{
 		Name:  "gasprice",
 		Usage: "Minimal gas price to accept for mining a transactions",
@@ -322,6 +409,20 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	StratumAddrFlag = cli.StringFlag{
+		Name:  "stratum.addr",
+		Usage: "Serve the mining work to pool software over stratum on the given address",
+	}
+	StratumDifficultyFlag = cli.Uint64Flag{
+		Name:  "stratum.difficulty",
+		Usage: "Share difficulty the stratum workers start with",
+		Value: sero.DefaultConfig.Stratum.Difficulty,
+	}
+	StratumShareTimeFlag = cli.DurationFlag{
+		Name:  "stratum.sharetime",
+		Usage: "Time between two shares of a stratum worker the share difficulty is retargeted to",
+		Value: sero.DefaultConfig.Stratum.ShareTime,
+	}
 	// AccountAddress settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -398,6 +499,122 @@ var (
         If IdleTimeout is zero, the value of ReadTimeout is used. If both are zero, ReadHeaderTimeout is used.`,
 		Value: 120,
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpcbatchlimit",
+		Usage: "Maximum number of requests in a JSON-RPC batch over HTTP and websocket (0 = unlimited)",
+	}
+	RPCBatchResponseSizeFlag = cli.IntFlag{
+		Name:  "rpcbatchresponsesize",
+		Usage: "Maximum size in bytes of the responses of a JSON-RPC batch over HTTP and websocket, the remaining requests fail (0 = unlimited)",
+	}
+	RPCExecTimeLimitFlag = cli.DurationFlag{
+		Name:  "rpcexectimelimit",
+		Usage: "Maximum execution time of an RPC request or of all the requests of a batch (0 = unlimited)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Requests per second each client may send over HTTP and websocket (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Requests each client may send at once over HTTP and websocket when rate limited",
+		Value: 100,
+	}
+	RPCConcurrentCallsFlag = cli.IntFlag{
+		Name:  "rpcconcurrentcalls",
+		Usage: "Maximum number of HTTP and websocket calls executing at once, further ones are rejected (0 = unlimited)",
+	}
+	RPCConnectionCallsFlag = cli.IntFlag{
+		Name:  "rpcconnectioncalls",
+		Usage: "Maximum number of calls of a websocket connection executing at once (0 = unlimited)",
+	}
+	RPCMethodTimesFlag = cli.StringFlag{
+		Name:  "rpcmethodtimes",
+		Usage: "Comma separated time budgets of single calls over HTTP and websocket, e.g. sero_call=5s,sero_estimateGas=10s",
+	}
+	RPCMethodsFlag = cli.StringFlag{
+		Name:  "rpcmethods",
+		Usage: "Comma separated methods served over the HTTP-RPC interface, module_* for a whole module (empty = all of the offered API's)",
+	}
+	WSMethodsFlag = cli.StringFlag{
+		Name:  "wsmethods",
+		Usage: "Comma separated methods served over the WS-RPC interface, module_* for a whole module (empty = all of the offered API's)",
+	}
+	IPCMethodsFlag = cli.StringFlag{
+		Name:  "ipcmethods",
+		Usage: "Comma separated methods served over the IPC interface, module_* for a whole module (empty = all methods)",
+	}
+	RPCAuthModulesFlag = cli.StringFlag{
+		Name:  "rpcauthmodules",
+		Usage: "API's served over the HTTP-RPC and WS-RPC interfaces only with an auth token, e.g. personal,debug",
+	}
+	RPCAuthTokensFlag = cli.StringFlag{
+		Name:  "rpcauthtokens",
+		Usage: "File holding the auth tokens accepted as \"Authorization: Bearer\" header, one per line",
+	}
+	RPCAuditFlag = cli.StringFlag{
+		Name:  "rpcaudit",
+		Usage: "File recording the state changing RPC calls, relative to the data directory (empty = no auditing)",
+	}
+	RPCAuditMaxSizeFlag = cli.IntFlag{
+		Name:  "rpcauditmaxsize",
+		Usage: "Size in megabytes the RPC audit log is rotated at",
+		Value: 100,
+	}
+	RPCGasCapFlag = cli.Uint64Flag{
+		Name:  "rpcgascap",
+		Usage: "Maximum gas sero_call and sero_estimateGas may use (0 = unlimited)",
+	}
+	RPCEstimateGasCapFlag = cli.Uint64Flag{
+		Name:  "rpcestimategascap",
+		Usage: "Maximum gas sero_estimateGas may use, below the RPC gas cap (0 = the RPC gas cap)",
+	}
+	RPCCallCacheFlag = cli.IntFlag{
+		Name:  "rpccallcache",
+		Usage: "Number of sero_call results of contracts cached until the next block (0 = disabled)",
+		Value: sero.DefaultConfig.RPCCallCache,
+	}
+	TxDefaultGasFlag = cli.Uint64Flag{
+		Name:  "txdefaultgas",
+		Usage: "Gas of RPC sent transactions not specifying it, contract calls are estimated instead",
+		Value: sero.DefaultConfig.TxDefaults.Gas,
+	}
+	TxDefaultGasCyFlag = cli.StringFlag{
+		Name:  "txdefaultgascy",
+		Usage: "Currency paying the gas of RPC sent contract calls not specifying it (AUTO picks a held currency accepted by the contract)",
+		Value: sero.DefaultConfig.TxDefaults.GasCurrency,
+	}
+	TxMinConfFlag = cli.Uint64Flag{
+		Name:  "txminconf",
+		Usage: "Minimum confirmations of the outs spent by RPC sent transactions",
+		Value: sero.DefaultConfig.TxDefaults.MinConf,
+	}
+	TxSelectionFlag = cli.StringFlag{
+		Name:  "txselection",
+		Usage: "Coin selection of the outs spent by RPC sent transactions not specifying it (largest, smallest, exact, consolidate; index order if empty)",
+		Value: sero.DefaultConfig.TxDefaults.Selection,
+	}
+	TxMaxMemoFlag = cli.IntFlag{
+		Name:  "txmaxmemo",
+		Usage: "Maximum memo length in bytes of RPC sent transactions, memos over 64 bytes are carried encrypted in the transfer data (at most 4096)",
+		Value: sero.DefaultConfig.TxDefaults.MaxMemo,
+	}
+	DailyStatsFlag = cli.BoolFlag{
+		Name:  "dailystats",
+		Usage: "Aggregate daily chain statistics served by sero_getDailyStats",
+	}
+	MemoIndexFlag = cli.BoolFlag{
+		Name:  "memoindex",
+		Usage: "Keep an encrypted index of the memos received by the local accounts, searched by memoindex_search",
+	}
+	TxRelayFlag = cli.StringFlag{
+		Name:  "txrelay",
+		Usage: "Comma separated RPC endpoints of gateway nodes local transactions are forwarded to for broadcast",
+	}
+	BlockHooksFlag = cli.StringFlag{
+		Name:  "blockhooks",
+		Usage: "Comma separated Go plugins (.so) called for every imported block and reorg",
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
@@ -519,6 +736,46 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: sero.DefaultConfig.GPO.Percentile,
 	}
+	GpoMaxPriceFlag = BigFlag{
+		Name:  "gpomaxprice",
+		Usage: "Maximum gas price suggested by the oracle",
+		Value: gasprice.DefaultMaxPrice,
+	}
+	// Lstate settings
+	LstateScanWorkersFlag = cli.IntFlag{
+		Name:  "lstate.scanworkers",
+		Usage: "Number of workers matching the outs of the blocks against the local accounts (0 = one per CPU)",
+	}
+	// Lstate replication settings
+	ReplicaListenFlag = cli.StringFlag{
+		Name:  "replica.listen",
+		Usage: "Serve the lstate to hot standbys on the given address (primary)",
+	}
+	ReplicaPrimaryFlag = cli.StringFlag{
+		Name:  "replica.primary",
+		Usage: "Keep the lstate in sync with the primary at the given address (standby)",
+	}
+	ReplicaCertFlag = cli.StringFlag{
+		Name:  "replica.cert",
+		Usage: "TLS certificate of this node for lstate replication",
+	}
+	ReplicaKeyFlag = cli.StringFlag{
+		Name:  "replica.key",
+		Usage: "Private key of the lstate replication certificate",
+	}
+	ReplicaCAFlag = cli.StringFlag{
+		Name:  "replica.ca",
+		Usage: "CA issuing the lstate replication certificates of both nodes",
+	}
+	ReplicaAccountsFlag = cli.BoolFlag{
+		Name:  "replica.accounts",
+		Usage: "Replicate the accounts of the primary as watch-only keys",
+	}
+	ReplicaIntervalFlag = cli.DurationFlag{
+		Name:  "replica.interval",
+		Usage: "Time between two lstate syncs of a standby",
+		Value: sero.DefaultConfig.Replica.Interval,
+	}
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:  metrics.MetricsEnabledFlag,
@@ -557,6 +814,10 @@ var (
 		Usage: "InfluxDB `host` tag attached to all measurements",
 		Value: "localhost",
 	}
+	MetricsPrometheusAddrFlag = cli.StringFlag{
+		Name:  "metrics.prometheus",
+		Usage: "Listen address of the Prometheus metrics endpoint /metrics (e.g. 127.0.0.1:6061), disabled if empty",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -728,6 +989,91 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits creates the request limits of the HTTP and websocket RPC
+// interfaces from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseSizeFlag.Name) {
+		cfg.RPCLimits.BatchResponseSize = ctx.GlobalInt(RPCBatchResponseSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCExecTimeLimitFlag.Name) {
+		cfg.RPCLimits.ExecutionTime = ctx.GlobalDuration(RPCExecTimeLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.RequestRate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+		cfg.RPCLimits.RequestBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCConcurrentCallsFlag.Name) {
+		cfg.RPCLimits.ConcurrentCalls = ctx.GlobalInt(RPCConcurrentCallsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCConnectionCallsFlag.Name) {
+		cfg.RPCLimits.ConnectionCalls = ctx.GlobalInt(RPCConnectionCallsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodTimesFlag.Name) {
+		cfg.RPCLimits.MethodTimes = make(map[string]time.Duration)
+		for _, budget := range splitAndTrim(ctx.GlobalString(RPCMethodTimesFlag.Name)) {
+			parts := strings.SplitN(budget, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid RPC method time budget %q, want method=duration", budget)
+			}
+			limit, err := time.ParseDuration(parts[1])
+			if err != nil {
+				Fatalf("Invalid RPC method time budget %q: %v", budget, err)
+			}
+			cfg.RPCLimits.MethodTimes[parts[0]] = limit
+		}
+	}
+}
+
+// setRPCAccess restricts the methods served by the RPC interfaces and the
+// callers of the modules requiring auth from the set command line flags.
+func setRPCAccess(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCMethodsFlag.Name) {
+		cfg.HTTPAccess.Methods = splitAndTrim(ctx.GlobalString(RPCMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(WSMethodsFlag.Name) {
+		cfg.WSAccess.Methods = splitAndTrim(ctx.GlobalString(WSMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(IPCMethodsFlag.Name) {
+		cfg.IPCAccess.Methods = splitAndTrim(ctx.GlobalString(IPCMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAuthModulesFlag.Name) {
+		modules := splitAndTrim(ctx.GlobalString(RPCAuthModulesFlag.Name))
+		cfg.HTTPAccess.AuthModules = modules
+		cfg.WSAccess.AuthModules = modules
+	}
+	if ctx.GlobalIsSet(RPCAuthTokensFlag.Name) {
+		file := ctx.GlobalString(RPCAuthTokensFlag.Name)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			Fatalf("Failed to read auth tokens %s: %v", file, err)
+		}
+		var tokens []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if token := strings.TrimSpace(line); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+		cfg.HTTPAccess.AuthTokens = tokens
+		cfg.WSAccess.AuthTokens = tokens
+	}
+	if len(cfg.HTTPAccess.AuthModules) > 0 && len(cfg.HTTPAccess.AuthTokens) == 0 {
+		log.Warn("RPC modules require auth but no tokens are configured", "modules", strings.Join(cfg.HTTPAccess.AuthModules, ","))
+	}
+}
+
+// setRPCAudit configures the audit log of the state changing RPC calls.
+func setRPCAudit(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCAuditFlag.Name) {
+		cfg.RPCAudit = ctx.GlobalString(RPCAuditFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuditMaxSizeFlag.Name) {
+		cfg.RPCAuditMaxSize = ctx.GlobalInt(RPCAuditMaxSizeFlag.Name)
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -816,6 +1162,30 @@ func setSerobase(ctx *cli.Context, ks *keystore.KeyStore, cfg *sero.Config) {
 	}
 }
 
+// setMinerPayouts creates the split of the block rewards from the account:percent
+// pairs of the command line flags, accounts being addresses or keystore indexes.
+func setMinerPayouts(ctx *cli.Context, ks *keystore.KeyStore, cfg *sero.Config) {
+	if !ctx.GlobalIsSet(MinerPayoutsFlag.Name) {
+		return
+	}
+	cfg.MinerPayouts = nil
+	for _, share := range strings.Split(ctx.GlobalString(MinerPayoutsFlag.Name), ",") {
+		parts := strings.Split(strings.TrimSpace(share), ":")
+		if len(parts) != 2 {
+			Fatalf("Option %q: invalid share %q, want account:percent", MinerPayoutsFlag.Name, share)
+		}
+		account, err := MakeAddress(ks, parts[0])
+		if err != nil {
+			Fatalf("Option %q: %v", MinerPayoutsFlag.Name, err)
+		}
+		percent, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			Fatalf("Option %q: invalid percent %q", MinerPayoutsFlag.Name, parts[1])
+		}
+		cfg.MinerPayouts = append(cfg.MinerPayouts, miner.Payout{Address: account.Address, Percent: percent})
+	}
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -916,6 +1286,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCAccess(ctx, cfg)
+	setRPCAudit(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -939,6 +1312,45 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMaxPriceFlag.Name) {
+		cfg.MaxPrice = GlobalBig(ctx, GpoMaxPriceFlag.Name)
+	}
+}
+
+func setStratum(ctx *cli.Context, cfg *stratum.Config) {
+	if ctx.GlobalIsSet(StratumAddrFlag.Name) {
+		cfg.Addr = ctx.GlobalString(StratumAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(StratumDifficultyFlag.Name) {
+		cfg.Difficulty = ctx.GlobalUint64(StratumDifficultyFlag.Name)
+	}
+	if ctx.GlobalIsSet(StratumShareTimeFlag.Name) {
+		cfg.ShareTime = ctx.GlobalDuration(StratumShareTimeFlag.Name)
+	}
+}
+
+func setReplica(ctx *cli.Context, cfg *replica.Config) {
+	if ctx.GlobalIsSet(ReplicaListenFlag.Name) {
+		cfg.Listen = ctx.GlobalString(ReplicaListenFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaPrimaryFlag.Name) {
+		cfg.Primary = ctx.GlobalString(ReplicaPrimaryFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaCertFlag.Name) {
+		cfg.Cert = ctx.GlobalString(ReplicaCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaKeyFlag.Name) {
+		cfg.Key = ctx.GlobalString(ReplicaKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaCAFlag.Name) {
+		cfg.CA = ctx.GlobalString(ReplicaCAFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaAccountsFlag.Name) {
+		cfg.Accounts = ctx.GlobalBool(ReplicaAccountsFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaIntervalFlag.Name) {
+		cfg.Interval = ctx.GlobalDuration(ReplicaIntervalFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -948,6 +1360,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolAccountSlotsFlag.Name) {
 		cfg.AccountSlots = ctx.GlobalUint64(TxPoolAccountSlotsFlag.Name)
 	}
@@ -963,6 +1378,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolLocalSlotsFlag.Name) {
+		cfg.LocalSlots = ctx.GlobalUint64(TxPoolLocalSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolEvictionFlag.Name) {
+		cfg.Eviction = ctx.GlobalString(TxPoolEvictionFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *sero.Config) {
@@ -1032,11 +1453,20 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setSerobase(ctx, ks, cfg)
+	setMinerPayouts(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setReplica(ctx, &cfg.Replica)
+	setStratum(ctx, &cfg.Stratum)
 	setEthash(ctx, cfg)
 
 	cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
+	if ctx.GlobalIsSet(LightServFlag.Name) {
+		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightPeersFlag.Name) {
+		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1046,10 +1476,39 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" && gcmode != "wallet" {
+		Fatalf("--%s must be either 'full', 'archive' or 'wallet'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	cfg.BodyPruning = ctx.GlobalString(GCModeFlag.Name) == "wallet"
+	if ctx.GlobalIsSet(LstateScanWorkersFlag.Name) {
+		cfg.LstateScanWorkers = ctx.GlobalInt(LstateScanWorkersFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReceiptRetentionFlag.Name) {
+		if cfg.NoPruning {
+			Fatalf("--%s can't be used in archive gc mode", ReceiptRetentionFlag.Name)
+		}
+		cfg.ReceiptRetention = ctx.GlobalUint64(ReceiptRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(ZStateRetentionFlag.Name) {
+		if cfg.NoPruning {
+			Fatalf("--%s can't be used in archive gc mode", ZStateRetentionFlag.Name)
+		}
+		cfg.ZStateRetention = ctx.GlobalUint64(ZStateRetentionFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(ColdDataDirFlag.Name) {
+		cfg.ColdDataDir = ctx.GlobalString(ColdDataDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(ColdTablesFlag.Name) {
+		cfg.ColdTables = splitColdTables(ctx.GlobalString(ColdTablesFlag.Name))
+	}
+	if ctx.GlobalIsSet(HotBlocksFlag.Name) {
+		cfg.HotBlocks = ctx.GlobalUint64(HotBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(EraDirFlag.Name) {
+		cfg.EraDir = ctx.GlobalString(EraDirFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
@@ -1074,6 +1533,49 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCEstimateGasCapFlag.Name) {
+		cfg.RPCEstimateGasCap = ctx.GlobalUint64(RPCEstimateGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallCacheFlag.Name) {
+		cfg.RPCCallCache = ctx.GlobalInt(RPCCallCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxDefaultGasFlag.Name) {
+		cfg.TxDefaults.Gas = ctx.GlobalUint64(TxDefaultGasFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxDefaultGasCyFlag.Name) {
+		cfg.TxDefaults.GasCurrency = strings.ToUpper(ctx.GlobalString(TxDefaultGasCyFlag.Name))
+	}
+	if ctx.GlobalIsSet(TxMaxMemoFlag.Name) {
+		if memo := ctx.GlobalInt(TxMaxMemoFlag.Name); memo <= 0 || memo > 4096 {
+			Fatalf("--%s must be between 1 and 4096", TxMaxMemoFlag.Name)
+		}
+		cfg.TxDefaults.MaxMemo = ctx.GlobalInt(TxMaxMemoFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxMinConfFlag.Name) {
+		cfg.TxDefaults.MinConf = ctx.GlobalUint64(TxMinConfFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxSelectionFlag.Name) {
+		selection := strings.ToLower(ctx.GlobalString(TxSelectionFlag.Name))
+		if !txs.ValidSelection(selection) {
+			Fatalf("--%s: unknown coin selection %q", TxSelectionFlag.Name, selection)
+		}
+		cfg.TxDefaults.Selection = selection
+	}
+	if ctx.GlobalIsSet(DailyStatsFlag.Name) {
+		cfg.DailyStats = ctx.GlobalBool(DailyStatsFlag.Name)
+	}
+	if ctx.GlobalIsSet(MemoIndexFlag.Name) {
+		cfg.MemoIndex = ctx.GlobalBool(MemoIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxRelayFlag.Name) {
+		cfg.RelayTargets = splitAndTrim(ctx.GlobalString(TxRelayFlag.Name))
+	}
+	if ctx.GlobalIsSet(BlockHooksFlag.Name) {
+		cfg.BlockHooks = splitAndTrim(ctx.GlobalString(BlockHooksFlag.Name))
+	}
 
 	if ctx.GlobalIsSet(MiningModeFlag.Name) {
 		cfg.MineMode = true
@@ -1099,6 +1601,16 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 	}
 }
 
+// SetCDCConfig applies change data capture related command line flags to the config.
+func SetCDCConfig(ctx *cli.Context, cfg *cdc.Config) {
+	if ctx.GlobalIsSet(CDCSinkFlag.Name) {
+		cfg.Sink = ctx.GlobalString(CDCSinkFlag.Name)
+	}
+	if ctx.GlobalIsSet(CDCPrefixFlag.Name) {
+		cfg.Prefix = ctx.GlobalString(CDCPrefixFlag.Name)
+	}
+}
+
 // SetDashboardConfig applies dashboard related command line flags to the config.
 func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 	cfg.Host = ctx.GlobalString(DashboardAddrFlag.Name)
@@ -1108,10 +1620,24 @@ func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 
 // RegisterEthService adds an Sero client to the stack.
 func RegisterEthService(stack *node.Node, cfg *sero.Config) {
-	err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		fullNode, err := sero.New(ctx, cfg)
-		return fullNode, err
-	})
+	var err error
+	if cfg.SyncMode == downloader.LightSync {
+		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			return les.New(ctx, cfg)
+		})
+	} else {
+		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			fullNode, err := sero.New(ctx, cfg)
+			if fullNode != nil && cfg.LightServ > 0 {
+				ls, err := les.NewLesServer(fullNode, cfg)
+				if err != nil {
+					return nil, err
+				}
+				fullNode.AddLesServer(ls)
+			}
+			return fullNode, err
+		})
+	}
 	if err != nil {
 		Fatalf("Failed to register the Sero service: %v", err)
 	}
@@ -1124,12 +1650,54 @@ func RegisterDashboardService(stack *node.Node, cfg *dashboard.Config, commit st
 	})
 }
 
+// RegisterCDCService adds a change data capture stream to the stack.
+func RegisterCDCService(stack *node.Node, cfg *cdc.Config, mineMode bool) {
+	err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var seroServ *sero.Sero
+		if err := ctx.Service(&seroServ); err != nil {
+			return nil, err
+		}
+		return cdc.New(cfg, seroServ, mineMode, ctx.ResolvePath("cdc.cursor"))
+	})
+	if err != nil {
+		Fatalf("Failed to register the change data capture service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
 	params.TargetGasLimit = ctx.GlobalUint64(TargetGasLimitFlag.Name)
 }
 
+// SetupProver hands the proof generation of transactions to the prover
+// process set by the flags, if any.
+func SetupProver(ctx *cli.Context) {
+	endpoint := ctx.GlobalString(ProverFlag.Name)
+	if endpoint == "" {
+		return
+	}
+	remote := ctx.GlobalBool(ProverRemoteFlag.Name)
+	client, err := prover.NewClient(endpoint, remote)
+	if err != nil {
+		Fatalf("Option %q: %v, use --%s to allow it", ProverFlag.Name, err, ProverRemoteFlag.Name)
+	}
+	if !prover.IsLocal(endpoint) {
+		log.Warn("Prover is on another host, it sees the spending seeds of the accounts", "endpoint", endpoint)
+	}
+	generate.SetProver(client)
+
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if status, err := client.Status(timeout); err != nil {
+		log.Warn("Prover not reachable, transactions fail until it is", "endpoint", endpoint, "err", err)
+	} else if !status.Compatible {
+		log.Warn("Prover is not able to generate transactions", "endpoint", endpoint, "reason", status.Reason)
+	} else {
+		log.Info("Generating proofs with prover", "endpoint", endpoint)
+	}
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -1148,6 +1716,16 @@ func SetupMetrics(ctx *cli.Context) {
 				"host": hosttag,
 			})
 		}
+		if addr := ctx.GlobalString(MetricsPrometheusAddrFlag.Name); addr != "" {
+			log.Info("Enabling Prometheus metrics endpoint", "addr", addr)
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", prometheus.Handler(metrics.DefaultRegistry))
+			go func() {
+				if err := http.ListenAndServe(addr, mux); err != nil {
+					log.Error("Prometheus metrics endpoint failed", "err", err)
+				}
+			}()
+		}
 	}
 }
 
@@ -1162,9 +1740,30 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) serodb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	if dir := ctx.GlobalString(ColdDataDirFlag.Name); dir != "" {
+		hot, ok := chainDb.(*serodb.LDBDatabase)
+		if !ok {
+			return chainDb
+		}
+		tables := splitColdTables(ctx.GlobalString(ColdTablesFlag.Name))
+		if chainDb, err = sero.OpenColdDatabase(hot, stack.ResolvePath(filepath.Join(dir, name)), cache, handles, tables); err != nil {
+			Fatalf("Could not open cold database: %v", err)
+		}
+	}
 	return chainDb
 }
 
+// splitColdTables splits the comma separated names of the cold tables.
+func splitColdTables(list string) []string {
+	tables := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
 func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	var genesis *core.Genesis
 	switch {
@@ -1198,13 +1797,14 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 			DatasetsOnDisk: sero.DefaultConfig.Ethash.DatasetsOnDisk,
 		})
 	}
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" && gcmode != "wallet" {
+		Fatalf("--%s must be either 'full', 'archive' or 'wallet'", GCModeFlag.Name)
 	}
 	cache := &core.CacheConfig{
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: sero.DefaultConfig.TrieCache,
 		TrieTimeLimit: sero.DefaultConfig.TrieTimeout,
+		BodyPruning:   ctx.GlobalString(GCModeFlag.Name) == "wallet",
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"os"
 	"reflect"
+	"runtime"
 	"sync"
 	"testing"
 
@@ -676,22 +677,18 @@ func TestHashimoto(t *testing.T) {
 	hash := hexutil.MustDecode("0xc9149cc0386e689d789a1c2f3d5d169a61a6218ed30e74414dc736e442ef3d1f")
 	nonce := uint64(0)
 
-	wantDigest := hexutil.MustDecode("0xe4073cffaef931d37117cefd9afd27ea0f1cad6a981dd2605c4a1ac97c519800")
-	wantResult := hexutil.MustDecode("0xd3539235ee2e6f8db665c0a72169f55b7f6c605712330b778ec3944f0eb5a557")
+	// The miner hashes depend on the block number, so check the light lookup
+	// against the full one instead of fixed vectors
+	for _, number := range []uint64{0, V2Number} {
+		wantDigest, wantResult := hashimotoFull(dataset, hash, nonce, number)
 
-	digest, result := hashimotoLight(32*1024, cache, hash, nonce)
-	if !bytes.Equal(digest, wantDigest) {
-		t.Errorf("light hashimoto digest mismatch: have %x, want %x", digest, wantDigest)
-	}
-	if !bytes.Equal(result, wantResult) {
-		t.Errorf("light hashimoto result mismatch: have %x, want %x", result, wantResult)
-	}
-	digest, result = hashimotoFull(dataset, hash, nonce)
-	if !bytes.Equal(digest, wantDigest) {
-		t.Errorf("full hashimoto digest mismatch: have %x, want %x", digest, wantDigest)
-	}
-	if !bytes.Equal(result, wantResult) {
-		t.Errorf("full hashimoto result mismatch: have %x, want %x", result, wantResult)
+		digest, result := hashimotoLight(32*1024, cache, hash, nonce, number)
+		if !bytes.Equal(digest, wantDigest) {
+			t.Errorf("block %d: light hashimoto digest mismatch: have %x, want %x", number, digest, wantDigest)
+		}
+		if !bytes.Equal(result, wantResult) {
+			t.Errorf("block %d: light hashimoto result mismatch: have %x, want %x", number, result, wantResult)
+		}
 	}
 }
 
@@ -704,22 +701,23 @@ func TestConcurrentDiskCacheGeneration(t *testing.T) {
 	}
 	defer os.RemoveAll(cachedir)
 
-	// Define a heavy enough block, one from mainnet should do
-	block := types.NewBlockWithHeader(&types.Header{
-		Number:      big.NewInt(3311058),
-		ParentHash:  common.HexToHash("0xd783efa4d392943503f28438ad5830b2d5964696ffc285f338585e9fe0a37a05"),
-		Coinbase:    common.Base58ToAddress("3sxsj1HaqtdMXoKAe6VW6UuSSvdvM59NiTs23Y3Cavzoi2G41QSsyhhM1fhaGvSaAe9aVXdMMWrwhcEgdbR6oupe"),
-		Root:        common.HexToHash("0x77d14e10470b5850332524f8cd6f69ad21f070ce92dca33ab2858300242ef2f1"),
-		TxHash:      common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"),
-		ReceiptHash: common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"),
-		Difficulty:  big.NewInt(167925187834220),
-		GasLimit:    4015682,
-		GasUsed:     0,
-		Time:        big.NewInt(1488928920),
-		Extra:       []byte("www.bw.com"),
-		MixDigest:   common.HexToHash("0x3e140b0784516af5e5ec6730f2fb20cca22f32be399b9e4ad77d32541f798cd0"),
-		Nonce:       types.EncodeNonce(0xf400cd0006070c49),
-	})
+	// Define a heavy enough block
+	header := &types.Header{
+		Number:     big.NewInt(3311058),
+		ParentHash: common.HexToHash("0xd783efa4d392943503f28438ad5830b2d5964696ffc285f338585e9fe0a37a05"),
+		Difficulty: big.NewInt(167925187834220),
+		GasLimit:   4015682,
+		Time:       big.NewInt(1488928920),
+		Nonce:      types.EncodeNonce(0xf400cd0006070c49),
+	}
+	number, hash := header.Number.Uint64(), header.HashNoNonce().Bytes()
+
+	// The seals depend on the miner hashes, so check the digests of the caches
+	// generated on disk against the one of a cache generated in memory
+	cache := make([]uint32, cacheSize(number)/4)
+	generateCache(cache, number/epochLength, seedHash(number))
+	want, _ := hashimotoLight(datasetSize(number), cache, hash, header.Nonce.Uint64(), number)
+
 	// Simulate multiple processes sharing the same datadir
 	var pend sync.WaitGroup
 
@@ -729,8 +727,12 @@ func TestConcurrentDiskCacheGeneration(t *testing.T) {
 		go func(idx int) {
 			defer pend.Done()
 			ethash := New(Config{cachedir, 0, 1, "", 0, 0, ModeNormal})
-			if err := ethash.VerifySeal(nil, block.Header()); err != nil {
-				t.Errorf("proc %d: block verification failed: %v", idx, err)
+			cache := ethash.cache(number)
+			digest, _ := hashimotoLight(datasetSize(number), cache.cache, hash, header.Nonce.Uint64(), number)
+			runtime.KeepAlive(cache)
+
+			if !bytes.Equal(digest, want) {
+				t.Errorf("proc %d: digest mismatch: have %x, want %x", idx, digest, want)
 			}
 		}(i)
 	}
@@ -766,7 +768,7 @@ func BenchmarkHashimotoLight(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		hashimotoLight(datasetSize(1), cache, hash, 0)
+		hashimotoLight(datasetSize(1), cache, hash, 0, 0)
 	}
 }
 
@@ -782,6 +784,6 @@ func BenchmarkHashimotoFullSmall(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		hashimotoFull(dataset, hash, 0)
+		hashimotoFull(dataset, hash, 0, 0)
 	}
 }
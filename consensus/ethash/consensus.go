@@ -54,6 +54,7 @@ var (
 	errInvalidDifficulty = errors.New("non-positive difficulty")
 	errInvalidMixDigest  = errors.New("invalid mix digest")
 	errInvalidPoW        = errors.New("invalid proof-of-work")
+	errEarlyPayouts      = errors.New("payouts before the CoinbaseSplit fork")
 )
 
 // Author implements consensus.Engine, returning the header's coinbase as the
@@ -178,6 +179,9 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainReader, header, parent *
 	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
 	}
+	if err := verifyPayouts(chain.Config(), header); err != nil {
+		return err
+	}
 	// Verify the header's timestamp
 	if header.Time.Cmp(big.NewInt(time.Now().Add(allowedFutureBlockTime).Unix())) > 0 {
 		return consensus.ErrFutureBlock
@@ -251,6 +255,36 @@ var (
 	bigMinus99    = big.NewInt(-99)
 )
 
+// verifyPayouts checks the split of the block reward: at most
+// MaxCoinbasePayouts valid PKrs with a positive share each, which together
+// don't exceed the whole reward.
+func verifyPayouts(config *params.ChainConfig, header *types.Header) error {
+	if len(header.Payouts) == 0 {
+		return nil
+	}
+	if !config.IsCoinbaseSplit(header.Number) {
+		return errEarlyPayouts
+	}
+	if uint64(len(header.Payouts)) > params.MaxCoinbasePayouts {
+		return fmt.Errorf("too many payouts: %d > %d", len(header.Payouts), params.MaxCoinbasePayouts)
+	}
+	total := uint64(0)
+	for i := range header.Payouts {
+		payout := &header.Payouts[i]
+		if !keys.PKrValid(payout.PKr.ToPKr()) {
+			return fmt.Errorf("invalid payout pkr %v", payout.PKr)
+		}
+		if payout.Percent == 0 || payout.Percent > 100 {
+			return fmt.Errorf("invalid payout percent %d", payout.Percent)
+		}
+		total += payout.Percent
+	}
+	if total > 100 {
+		return fmt.Errorf("payouts exceed the reward: %d%%", total)
+	}
+	return nil
+}
+
 // calcDifficultyAutumnTwilight is the difficulty adjustment algorithm. It returns
 // the difficulty that a new block should have when created at time given the
 // parent block's time and difficulty. The calculation uses the AutumnTwilight rules.
@@ -362,6 +396,39 @@ func (ethash *Ethash) VerifySeal(chain consensus.ChainReader, header *types.Head
 	return nil
 }
 
+// VerifyShare checks the seal of a header against the share difficulty of a
+// pool worker, reporting whether it also seals the block.
+func (ethash *Ethash) VerifyShare(header *types.Header, difficulty *big.Int) (bool, error) {
+	// If we're running a fake PoW, every share seals the block
+	if ethash.config.PowMode == ModeFake || ethash.config.PowMode == ModeFullFake {
+		return true, nil
+	}
+	if ethash.shared != nil {
+		return ethash.shared.VerifyShare(header, difficulty)
+	}
+	if difficulty.Sign() <= 0 {
+		return false, errInvalidDifficulty
+	}
+	number := header.Number.Uint64()
+
+	cache := ethash.cache(number)
+	size := datasetSize(number)
+	if ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
+	}
+	digest, result := hashimotoLight(size, cache.cache, header.HashNoNonce().Bytes(), header.Nonce.Uint64(), number)
+	runtime.KeepAlive(cache)
+
+	if !bytes.Equal(header.MixDigest[:], digest) {
+		return false, errInvalidMixDigest
+	}
+	value := new(big.Int).SetBytes(result)
+	if value.Cmp(new(big.Int).Div(maxUint256, difficulty)) > 0 {
+		return false, errInvalidPoW
+	}
+	return value.Cmp(new(big.Int).Div(maxUint256, header.ActualDifficulty())) <= 0, nil
+}
+
 // Prepare implements consensus.Engine, initializing the difficulty field of a
 // header to conform to the ethash protocol. The changes are done inline.
 func (ethash *Ethash) Prepare(chain consensus.ChainReader, header *types.Header) error {
@@ -411,7 +478,8 @@ var (
 )
 
 // AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward .
+// reward. The total reward consists of the static block reward . The payouts
+// of the header take their shares of it, the coinbase the rest.
 func accumulateRewards(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, gasReward uint64) {
 
 	var reward *big.Int
@@ -423,12 +491,35 @@ func accumulateRewards(config *params.ChainConfig, statedb *state.StateDB, heade
 
 	//log.Info(fmt.Sprintf("BlockNumber = %v, gasLimie = %v, gasUsed = %v, reward = %v", header.Number.Uint64(), header.GasLimit, header.GasUsed, reward))
 	reward.Add(reward, new(big.Int).SetUint64(gasReward))
+	shares, rest := splitReward(reward, header.Payouts)
+	for i, payout := range header.Payouts {
+		if shares[i].Sign() > 0 {
+			addReward(statedb, payout.PKr, shares[i])
+		}
+	}
+	addReward(statedb, header.Coinbase, rest)
+}
+
+// splitReward returns the shares of the payouts in reward, rounded down, and
+// the rest left to the coinbase, which gets the remainders of the rounding.
+func splitReward(reward *big.Int, payouts []types.Payout) ([]*big.Int, *big.Int) {
+	shares := make([]*big.Int, len(payouts))
+	rest := new(big.Int).Set(reward)
+	for i, payout := range payouts {
+		shares[i] = new(big.Int).Div(new(big.Int).Mul(reward, new(big.Int).SetUint64(payout.Percent)), big100)
+		rest.Sub(rest, shares[i])
+	}
+	return shares, rest
+}
+
+// addReward pays a SERO out of the block reward to pkr.
+func addReward(statedb *state.StateDB, pkr common.Address, value *big.Int) {
 	asset := assets.Asset{Tkn: &assets.Token{
 		Currency: *common.BytesToHash(common.LeftPadBytes([]byte("SERO"), 32)).HashToUint256(),
-		Value:    utils.U256(*reward),
+		Value:    utils.U256(*value),
 	},
 	}
-	statedb.GetZState().AddTxOut(header.Coinbase, asset)
+	statedb.GetZState().AddTxOut(pkr, asset)
 }
 
 func accumulateRewardsV1(config *params.ChainConfig, statedb *state.StateDB, header *types.Header) *big.Int {
@@ -24,6 +24,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/math"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/params"
@@ -176,3 +178,74 @@ func print(number int64, difficulty int64) {
 	fmt.Println(new(big.Float).Quo(new(big.Float).SetInt(v2), big.NewFloat(1e+18)))
 	fmt.Println("-------------------------")
 }
+
+// testPayout returns a payout of percent to a PKr of the seed.
+func testPayout(seed byte, percent uint64) types.Payout {
+	addr := keys.Seed2Addr(&keys.Uint256{seed})
+	pkr := keys.Addr2PKr(&addr, &keys.Uint256{seed})
+	return types.Payout{PKr: common.BytesToAddress(pkr[:]), Percent: percent}
+}
+
+func TestVerifyPayouts(t *testing.T) {
+	config := &params.ChainConfig{CoinbaseSplitBlock: big.NewInt(10)}
+
+	tests := []struct {
+		number  int64
+		payouts []types.Payout
+		valid   bool
+	}{
+		{5, nil, true},
+		{10, nil, true},
+		{10, []types.Payout{testPayout(1, 30), testPayout(2, 70)}, true},
+		{10, []types.Payout{testPayout(1, 100)}, true},
+		{9, []types.Payout{testPayout(1, 30)}, false},                     // before the fork
+		{10, []types.Payout{testPayout(1, 0)}, false},                     // no share
+		{10, []types.Payout{testPayout(1, 101)}, false},                   // more than the reward
+		{10, []types.Payout{testPayout(1, 60), testPayout(2, 41)}, false}, // together more than the reward
+	}
+	for i, tt := range tests {
+		header := &types.Header{Number: big.NewInt(tt.number), Payouts: tt.payouts}
+		if err := verifyPayouts(config, header); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+	// Payouts before a fork that is not scheduled are refused as well
+	header := &types.Header{Number: big.NewInt(10), Payouts: []types.Payout{testPayout(1, 30)}}
+	if err := verifyPayouts(&params.ChainConfig{}, header); err != errEarlyPayouts {
+		t.Errorf("unscheduled fork error mismatch: have %v, want %v", err, errEarlyPayouts)
+	}
+}
+
+func TestSplitReward(t *testing.T) {
+	tests := []struct {
+		reward   int64
+		percents []uint64
+		shares   []int64
+		rest     int64
+	}{
+		{1000, nil, nil, 1000},
+		{1000, []uint64{30, 70}, []int64{300, 700}, 0},
+		{999, []uint64{33, 33, 33}, []int64{329, 329, 329}, 12}, // the rounding goes to the coinbase
+		{1, []uint64{50}, []int64{0}, 1},
+		{0, []uint64{50}, []int64{0}, 0},
+	}
+	for i, tt := range tests {
+		payouts := make([]types.Payout, len(tt.percents))
+		for j, percent := range tt.percents {
+			payouts[j] = testPayout(byte(j+1), percent)
+		}
+		reward := big.NewInt(tt.reward)
+		shares, rest := splitReward(reward, payouts)
+		for j, share := range shares {
+			if share.Int64() != tt.shares[j] {
+				t.Errorf("test %d: share %d mismatch: have %v, want %d", i, j, share, tt.shares[j])
+			}
+		}
+		if rest.Int64() != tt.rest {
+			t.Errorf("test %d: rest mismatch: have %v, want %d", i, rest, tt.rest)
+		}
+		if reward.Int64() != tt.reward {
+			t.Errorf("test %d: reward modified: have %v, want %d", i, reward, tt.reward)
+		}
+	}
+}
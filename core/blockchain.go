@@ -37,6 +37,7 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/mclock"
 	"github.com/sero-cash/go-sero/consensus"
+	"github.com/sero-cash/go-sero/core/hooks"
 	"github.com/sero-cash/go-sero/core/rawdb"
 	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/core/types"
@@ -71,6 +72,19 @@ const (
 	badBlockLimit       = 10
 	triesInMemory       = 128
 
+	// receiptPruneBatch is the maximum number of blocks whose receipts are dropped
+	// during a single block import, so enabling retention on an old database
+	// catches up gradually instead of stalling the import.
+	receiptPruneBatch = 1024
+
+	// bodyPruneBatch is the maximum number of blocks whose bodies are checked
+	// for pruning during a single block import.
+	bodyPruneBatch = 1024
+
+	// zstatePruneBatch is the maximum number of blocks whose zstate block
+	// records are checked for pruning during a single block import.
+	zstatePruneBatch = 1024
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
@@ -81,6 +95,9 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	ReceiptLimit  uint64        // Number of recent blocks to retain receipts and logs for (0 = keep all)
+	BodyPruning   bool          // Whether to drop scanned block bodies unrelated to local accounts (wallet node)
+	ZStateLimit   uint64        // Number of recent blocks to retain zstate block records for (0 = keep all)
 }
 
 type Downloader interface {
@@ -283,6 +300,9 @@ func (self *State1BlockChain) GetCurrenHeader() *types.Header {
 func (self *State1BlockChain) GetHeader(hash *common.Hash) *types.Header {
 	return self.bc.GetHeaderByHash(*hash)
 }
+func (self *State1BlockChain) GetHeaderByNumber(num uint64) *types.Header {
+	return self.bc.GetHeaderByNumber(num)
+}
 func (self *State1BlockChain) NewState(hash *common.Hash) *zstate.ZState {
 	header := self.bc.GetHeaderByHash(*hash)
 	num := header.Number.Uint64()
@@ -299,8 +319,16 @@ func (self *State1BlockChain) NewState(hash *common.Hash) *zstate.ZState {
 	return st.GetZState()
 }
 func (self *State1BlockChain) GetTks() []keys.Uint512 {
+	return self.bc.localTks()
+}
+func (self *State1BlockChain) ZStateTail() uint64 {
+	return self.bc.ZStateTail()
+}
+
+// localTks returns the trace keys of all local accounts.
+func (bc *BlockChain) localTks() []keys.Uint512 {
 	tks := []keys.Uint512{}
-	for _, w := range self.bc.accountManager.Wallets() {
+	for _, w := range bc.accountManager.Wallets() {
 		tk := w.Accounts()[0].Tk
 		tks = append(tks, *tk.ToUint512())
 	}
@@ -414,6 +442,9 @@ func (bc *BlockChain) SetHead(head uint64, delFn DeleteCallback) error {
 	rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
 	rawdb.WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash())
 
+	// Roll the local outs back with the chain
+	lstate.Rewind(currentBlock.NumberU64())
+
 	return bc.loadLastState()
 }
 
@@ -493,6 +524,11 @@ func (bc *BlockChain) StateAt(root common.Hash, number uint64) (*state.StateDB,
 	return state.New(root, bc.stateCache, number)
 }
 
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -993,6 +1029,10 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	batch := bc.db.NewBatch()
 	rawdb.WriteBlock(batch, block)
 	state.GetZState().RecordBlock(block.Header().Hash().HashToUint256())
+	var zblock *zstate.Block
+	if hooks.Enabled() {
+		zblock = state.GetZState().GetBlock(block.NumberU64(), block.Hash().HashToUint256())
+	}
 	//rawdb.WriteHash(bc.db, block.Number().Uint64(), block.Hash())
 
 	root, err := state.Commit(true)
@@ -1081,7 +1121,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
-			if err := bc.reorg(currentBlock, block); err != nil {
+			if err := bc.reorg(currentBlock, block, state.GetZState()); err != nil {
 				return NonStatTy, err
 			}
 		}
@@ -1089,6 +1129,15 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		rawdb.WriteTxLookupEntries(batch, block)
 		rawdb.WritePreimages(batch, block.NumberU64(), state.Preimages())
 
+		if bc.cacheConfig.ReceiptLimit > 0 && !bc.cacheConfig.Disabled {
+			bc.pruneReceipts(batch, block.NumberU64())
+		}
+		if bc.cacheConfig.BodyPruning && !bc.cacheConfig.Disabled {
+			bc.pruneBodies(batch, block.NumberU64())
+		}
+		if bc.cacheConfig.ZStateLimit > 0 && !bc.cacheConfig.Disabled {
+			bc.pruneZState(batch, block.NumberU64())
+		}
 		status = CanonStatTy
 	} else {
 		status = SideStatTy
@@ -1101,11 +1150,217 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		if hooks.Enabled() {
+			hooks.BlockImported(block, receipts, zblock)
+		}
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
 }
 
+// pruneReceipts deletes the receipts of canonical blocks that fell behind the
+// configured retention horizon and advances the receipt tail accordingly.
+func (bc *BlockChain) pruneReceipts(batch serodb.Batch, head uint64) {
+	// Reorgs need the receipts of the dropped blocks to emit removed logs, so
+	// never prune inside the window of recent in-memory tries.
+	limit := bc.cacheConfig.ReceiptLimit
+	if limit < triesInMemory {
+		limit = triesInMemory
+	}
+	if head < limit {
+		return
+	}
+	horizon := head - limit + 1
+	tail := rawdb.ReadReceiptTail(bc.db)
+	if tail >= horizon {
+		return
+	}
+	if horizon-tail > receiptPruneBatch {
+		horizon = tail + receiptPruneBatch
+	}
+	for number := tail; number < horizon; number++ {
+		if hash := rawdb.ReadCanonicalHash(bc.db, number); hash != (common.Hash{}) {
+			rawdb.DeleteReceipts(batch, hash, number)
+		}
+	}
+	rawdb.WriteReceiptTail(batch, horizon)
+}
+
+// pruneBodies deletes the bodies and receipts of canonical blocks that were
+// scanned for local outs and carry no data of local accounts, advancing the
+// body tail accordingly. Headers and state are kept for verification. The
+// blocks the scan found receiving, spending or changing the packages of the
+// outs of local accounts are kept whatever their descriptors.
+func (bc *BlockChain) pruneBodies(batch serodb.Batch, head uint64) {
+	if head < triesInMemory {
+		return
+	}
+	horizon := head - triesInMemory + 1
+	scanned, ok := lstate.ScannedNumber()
+	if !ok {
+		return
+	}
+	if scanned+1 < horizon {
+		horizon = scanned + 1
+	}
+	tail := rawdb.ReadBodyTail(bc.db)
+	if tail >= horizon {
+		return
+	}
+	if horizon-tail > bodyPruneBatch {
+		horizon = tail + bodyPruneBatch
+	}
+	wallet := lstate.CurrentState1()
+	if wallet == nil {
+		return
+	}
+	tks := bc.localTks()
+	for number := tail; number < horizon; number++ {
+		hash := rawdb.ReadCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		block := rawdb.ReadBlock(bc.db, hash, number)
+		if block == nil || isLocalBlock(block, tks) || wallet.Relevant(number, hash.HashToUint256()) {
+			continue
+		}
+		rawdb.DeleteBody(batch, hash, number)
+		rawdb.DeleteReceipts(batch, hash, number)
+	}
+	rawdb.WriteBodyTail(batch, horizon)
+}
+
+// pruneZState deletes the zstate block records of canonical blocks that fell
+// behind the configured retention horizon and were scanned for local outs,
+// advancing the zstate tail accordingly. The records list the outs created and
+// spent by their block and are only read again when scanning it, so the
+// records creating outs still owned by local accounts are kept for rescans.
+// The outs, nils and out tree in the state are never touched.
+func (bc *BlockChain) pruneZState(batch serodb.Batch, head uint64) {
+	// Reorgs and the hooks need the records of the recent blocks
+	limit := bc.cacheConfig.ZStateLimit
+	if limit < triesInMemory {
+		limit = triesInMemory
+	}
+	if head < limit {
+		return
+	}
+	horizon := head - limit + 1
+	scanned, ok := lstate.ScannedNumber()
+	if !ok {
+		return
+	}
+	if scanned+1 < horizon {
+		horizon = scanned + 1
+	}
+	wallet := lstate.CurrentState1()
+	if wallet == nil {
+		return
+	}
+	tail := rawdb.ReadZStateTail(bc.db)
+	if tail >= horizon {
+		return
+	}
+	if horizon-tail > zstatePruneBatch {
+		horizon = tail + zstatePruneBatch
+	}
+	for number := tail; number < horizon; number++ {
+		hash := rawdb.ReadCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		key := zstate.BlockKey(number, hash.HashToUint256())
+		data, err := bc.db.Get(key)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		get := zstate.BlockGet{}
+		if err := get.Unserial(data); err != nil || get.Out == nil {
+			continue
+		}
+		if ownsUnspent(wallet, get.Out.Roots) {
+			continue
+		}
+		batch.Delete(key)
+	}
+	rawdb.WriteZStateTail(batch, horizon)
+}
+
+// BodyPruned reports whether the block of hash fell behind the body tail of a
+// wallet node. The bodies and receipts of such blocks are not served to peers,
+// even those kept for the local accounts, so they can't tell which are local.
+func (bc *BlockChain) BodyPruned(hash common.Hash) bool {
+	if !bc.cacheConfig.BodyPruning || bc.cacheConfig.Disabled {
+		return false
+	}
+	number := bc.hc.GetBlockNumber(hash)
+	return number != nil && *number < rawdb.ReadBodyTail(bc.db)
+}
+
+// ZStateTail returns the number of the oldest block whose zstate block record
+// was not pruned, the records of the canonical blocks below it may be gone.
+func (bc *BlockChain) ZStateTail() uint64 {
+	return rawdb.ReadZStateTail(bc.db)
+}
+
+// ownsUnspent reports whether a local account owns one of the outs and did not
+// spend it yet.
+func ownsUnspent(wallet *lstate.State, roots []keys.Uint256) bool {
+	for i := range roots {
+		if out, err := wallet.GetOut(&roots[i]); err != nil || out != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalBlock reports whether the block was mined by, or contains transactions
+// sent by, paying to or handing packages to one of the given local accounts.
+func isLocalBlock(block *types.Block, tks []keys.Uint512) bool {
+	isMine := func(pkr *keys.PKr) bool {
+		for i := range tks {
+			if keys.IsMyPKr(&tks[i], pkr) {
+				return true
+			}
+		}
+		return false
+	}
+	if coinbase := block.Coinbase(); isMine(coinbase.ToPKr()) {
+		return true
+	}
+	for _, payout := range block.Header().Payouts {
+		if isMine(payout.PKr.ToPKr()) {
+			return true
+		}
+	}
+	for _, tx := range block.Transactions() {
+		stxt := tx.Stxt()
+		if stxt == nil {
+			continue
+		}
+		if isMine(&stxt.From) {
+			return true
+		}
+		for i := range stxt.Desc_O.Outs {
+			if isMine(&stxt.Desc_O.Outs[i].Addr) {
+				return true
+			}
+		}
+		for i := range stxt.Desc_Z.Outs {
+			if isMine(&stxt.Desc_Z.Outs[i].PKr) {
+				return true
+			}
+		}
+		if create := stxt.Desc_Pkg.Create; create != nil && isMine(&create.PKr) {
+			return true
+		}
+		if transfer := stxt.Desc_Pkg.Transfer; transfer != nil && isMine(&transfer.PKr) {
+			return true
+		}
+	}
+	return false
+}
+
 // InsertChain attempts to insert the given batch of blocks in to the canonical
 // chain or, otherwise, create a fork. If an error is returned it will return
 // the index number of the failing block as well an error describing what went
@@ -1267,9 +1522,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks, local bool) (int, []interf
 			return i, events, coalescedLogs, err
 		}
 
-		for _, tx := range block.Transactions() {
-			err := verify.Verify(tx.GetZZSTX(), state.GetZState())
-			if err != nil {
+		for j, tx := range block.Transactions() {
+			if err := verify.Verify(tx.GetZZSTX(), state.GetZState()); err != nil {
+				err = &InvalidTxError{Index: j, Hash: tx.Hash(), Err: err}
+				bc.reportBlock(block, nil, err)
 				return i, events, coalescedLogs, err
 			}
 		}
@@ -1379,7 +1635,9 @@ func countTransactions(chain []*types.Block) (c int) {
 // reorgs takes two blocks, an old chain and a new chain and will reconstruct the blocks and inserts them
 // to be part of the new canonical chain and accumulates potential missing transactions and post an
 // event about them
-func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
+// reorg takes the zstate of newBlock, which holds the zstate records of the
+// blocks of the new chain.
+func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block, zs *zstate.ZState) error {
 	var (
 		newChain    types.Blocks
 		oldChain    types.Blocks
@@ -1477,6 +1735,20 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	}
 	batch.Write()
 
+	// Roll the local outs of the dropped blocks back, the new branch is
+	// rescanned from the common ancestor
+	if len(oldChain) > 0 {
+		lstate.Rewind(commonBlock.NumberU64())
+	}
+	if hooks.Enabled() {
+		hooks.ChainReorged(commonBlock, oldChain)
+		// newChain[0] is newBlock, announced by the caller once written
+		for i := len(newChain) - 1; i > 0; i-- {
+			block := newChain[i]
+			receipts := rawdb.ReadReceipts(bc.db, block.Hash(), block.NumberU64())
+			hooks.BlockImported(block, receipts, zs.GetBlock(block.NumberU64(), block.Hash().HashToUint256()))
+		}
+	}
 	if len(deletedLogs) > 0 {
 		go bc.rmLogsFeed.Send(RemovedLogsEvent{deletedLogs})
 	}
@@ -1526,26 +1798,42 @@ func (bc *BlockChain) update() {
 	}
 }
 
+// BadBlock is a block rejected on import, with the reason it was rejected.
+type BadBlock struct {
+	Block *types.Block
+	Err   error     // *InvalidTxError if a transaction of the block was rejected
+	Time  time.Time // time the block was rejected
+}
+
 // BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
 func (bc *BlockChain) BadBlocks() []*types.Block {
 	blocks := make([]*types.Block, 0, bc.badBlocks.Len())
+	for _, bad := range bc.BadBlockReports() {
+		blocks = append(blocks, bad.Block)
+	}
+	return blocks
+}
+
+// BadBlockReports returns the last 'bad blocks' that the client has seen on the
+// network, with the errors they were rejected for.
+func (bc *BlockChain) BadBlockReports() []*BadBlock {
+	reports := make([]*BadBlock, 0, bc.badBlocks.Len())
 	for _, hash := range bc.badBlocks.Keys() {
-		if blk, exist := bc.badBlocks.Peek(hash); exist {
-			block := blk.(*types.Block)
-			blocks = append(blocks, block)
+		if bad, exist := bc.badBlocks.Peek(hash); exist {
+			reports = append(reports, bad.(*BadBlock))
 		}
 	}
-	return blocks
+	return reports
 }
 
 // addBadBlock adds a bad block to the bad-block LRU cache
-func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Hash(), block)
+func (bc *BlockChain) addBadBlock(block *types.Block, err error) {
+	bc.badBlocks.Add(block.Hash(), &BadBlock{Block: block, Err: err, Time: time.Now()})
 }
 
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	bc.addBadBlock(block)
+	bc.addBadBlock(block, err)
 
 	var receiptString string
 	for _, receipt := range receipts {
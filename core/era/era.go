@@ -0,0 +1,315 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements immutable, content addressed history archives. Every
+// archive holds the headers, bodies, receipts and zstate block records of one
+// epoch of finalized blocks, so history can be distributed outside of p2p.
+package era
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto/sha3"
+	"github.com/sero-cash/go-sero/rlp"
+)
+
+const (
+	// Version is the archive format version written into every file.
+	Version = 1
+
+	// EpochSize is the number of blocks stored in a single archive.
+	EpochSize = 8192
+
+	// FinalityDepth is the number of confirmations an epoch needs on top of
+	// its last block before it is considered final enough to be archived.
+	FinalityDepth = 1024
+)
+
+var (
+	errBadVersion   = errors.New("unsupported era archive version")
+	errRootMismatch = errors.New("era archive content root mismatch")
+
+	fileNameRe = regexp.MustCompile(`^([a-z0-9]+)-(\d{5})-([0-9a-f]{8})\.era$`)
+)
+
+// Meta is the leading record of an archive.
+type Meta struct {
+	Version uint64
+	Network string
+	Epoch   uint64
+	Start   uint64
+	Count   uint64
+}
+
+// Entry is the archived data of a single block.
+type Entry struct {
+	Header   *types.Header
+	Body     *types.Body
+	Receipts rawdb.StateRecepipts
+	ZBlock   []byte // RLP encoded zstate block record, empty if none was recorded
+}
+
+// NewEntry assembles the archive entry of a block.
+func NewEntry(block *types.Block, receipts types.Receipts, zblock []byte) *Entry {
+	entry := &Entry{
+		Header: block.Header(),
+		Body:   block.Body(),
+		ZBlock: zblock,
+	}
+	entry.Receipts.Receipts = make([]*types.ReceiptForStorage, len(receipts))
+	entry.Receipts.States = make([]byte, len(receipts))
+	for i, receipt := range receipts {
+		entry.Receipts.Receipts[i] = (*types.ReceiptForStorage)(receipt)
+		entry.Receipts.States[i] = byte(receipt.Status)
+	}
+	return entry
+}
+
+// Block assembles the archived header and body into a block.
+func (e *Entry) Block() *types.Block {
+	return types.NewBlockWithHeader(e.Header).WithBody(e.Body.Transactions)
+}
+
+// ReceiptList converts the archived receipts into their internal representation.
+func (e *Entry) ReceiptList() types.Receipts {
+	receipts := make(types.Receipts, len(e.Receipts.Receipts))
+	for i, receipt := range e.Receipts.Receipts {
+		receipts[i] = (*types.Receipt)(receipt)
+		receipts[i].Status = uint64(e.Receipts.States[i])
+	}
+	return receipts
+}
+
+// verify checks that the body and receipts of the entry match the roots
+// committed to by its header.
+func (e *Entry) verify() error {
+	number := e.Header.Number.Uint64()
+	if e.Body == nil {
+		return fmt.Errorf("block %d: missing body", number)
+	}
+	if hash := types.DeriveSha(types.Transactions(e.Body.Transactions)); hash != e.Header.TxHash {
+		return fmt.Errorf("block %d: transaction root mismatch (have %x, want %x)", number, hash, e.Header.TxHash)
+	}
+	if len(e.Receipts.Receipts) != len(e.Receipts.States) {
+		return fmt.Errorf("block %d: receipt status count mismatch", number)
+	}
+	if hash := types.DeriveSha(e.ReceiptList()); hash != e.Header.ReceiptHash {
+		return fmt.Errorf("block %d: receipt root mismatch (have %x, want %x)", number, hash, e.Header.ReceiptHash)
+	}
+	return nil
+}
+
+// Filename returns the content addressed name of an archive.
+func Filename(network string, epoch uint64, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%x.era", network, epoch, root[:4])
+}
+
+// ParseFilename extracts the network, epoch and short content root from the
+// name of an archive file.
+func ParseFilename(name string) (network string, epoch uint64, short string, err error) {
+	m := fileNameRe.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return "", 0, "", fmt.Errorf("invalid era archive name %q", name)
+	}
+	if epoch, err = strconv.ParseUint(m[2], 10, 64); err != nil {
+		return "", 0, "", err
+	}
+	return m[1], epoch, m[3], nil
+}
+
+// accumulator folds the hashes of the archived records into the content root.
+type accumulator struct {
+	hashes []byte
+}
+
+func (a *accumulator) add(v interface{}) error {
+	enc, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		return err
+	}
+	d := sha3.NewKeccak256()
+	d.Write(enc)
+	a.hashes = d.Sum(a.hashes)
+	return nil
+}
+
+func (a *accumulator) root() (ret common.Hash) {
+	d := sha3.NewKeccak256()
+	d.Write(a.hashes)
+	copy(ret[:], d.Sum(nil))
+	return
+}
+
+// Writer streams the records of one epoch into an archive.
+type Writer struct {
+	w     io.Writer
+	meta  Meta
+	acc   accumulator
+	count uint64
+	last  common.Hash
+}
+
+// NewWriter writes the archive meta record and returns a writer for its entries.
+func NewWriter(w io.Writer, network string, epoch uint64) (*Writer, error) {
+	meta := Meta{
+		Version: Version,
+		Network: network,
+		Epoch:   epoch,
+		Start:   epoch * EpochSize,
+		Count:   EpochSize,
+	}
+	writer := &Writer{w: w, meta: meta}
+	if err := writer.acc.add(&meta); err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(w, &meta); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Add appends the next block of the epoch to the archive.
+func (w *Writer) Add(entry *Entry) error {
+	if want := w.meta.Start + w.count; entry.Header.Number.Uint64() != want {
+		return fmt.Errorf("non contiguous era entry: have #%d, want #%d", entry.Header.Number.Uint64(), want)
+	}
+	if w.count > 0 && entry.Header.ParentHash != w.last {
+		return fmt.Errorf("era entry #%d does not extend #%d", entry.Header.Number.Uint64(), entry.Header.Number.Uint64()-1)
+	}
+	if err := w.acc.add(entry); err != nil {
+		return err
+	}
+	if err := rlp.Encode(w.w, entry); err != nil {
+		return err
+	}
+	w.count++
+	w.last = entry.Header.Hash()
+	return nil
+}
+
+// Finalize writes the content root trailer and returns it.
+func (w *Writer) Finalize() (common.Hash, error) {
+	if w.count != w.meta.Count {
+		return common.Hash{}, fmt.Errorf("incomplete era epoch: have %d entries, want %d", w.count, w.meta.Count)
+	}
+	root := w.acc.root()
+	if err := rlp.Encode(w.w, root); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+// Reader streams the entries of an archive, verifying them on the go.
+type Reader struct {
+	stream *rlp.Stream
+	meta   Meta
+	acc    accumulator
+	count  uint64
+	last   common.Hash
+}
+
+// NewReader reads and validates the meta record of an archive.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{stream: rlp.NewStream(r, 0)}
+	if err := reader.stream.Decode(&reader.meta); err != nil {
+		return nil, err
+	}
+	if reader.meta.Version != Version {
+		return nil, errBadVersion
+	}
+	if reader.meta.Start != reader.meta.Epoch*EpochSize || reader.meta.Count != EpochSize {
+		return nil, fmt.Errorf("invalid era epoch bounds: start %d, count %d", reader.meta.Start, reader.meta.Count)
+	}
+	if err := reader.acc.add(&reader.meta); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// Meta returns the meta record of the archive.
+func (r *Reader) Meta() Meta {
+	return r.meta
+}
+
+// Next returns the next verified entry of the archive, or io.EOF once all
+// entries were read and the content root trailer matched.
+func (r *Reader) Next() (*Entry, error) {
+	if r.count == r.meta.Count {
+		var root common.Hash
+		if err := r.stream.Decode(&root); err != nil {
+			return nil, err
+		}
+		if root != r.acc.root() {
+			return nil, errRootMismatch
+		}
+		return nil, io.EOF
+	}
+	entry := new(Entry)
+	if err := r.stream.Decode(entry); err != nil {
+		return nil, err
+	}
+	if want := r.meta.Start + r.count; entry.Header.Number.Uint64() != want {
+		return nil, fmt.Errorf("non contiguous era entry: have #%d, want #%d", entry.Header.Number.Uint64(), want)
+	}
+	if r.count > 0 && entry.Header.ParentHash != r.last {
+		return nil, fmt.Errorf("era entry #%d does not extend #%d", entry.Header.Number.Uint64(), entry.Header.Number.Uint64()-1)
+	}
+	if err := entry.verify(); err != nil {
+		return nil, err
+	}
+	if err := r.acc.add(entry); err != nil {
+		return nil, err
+	}
+	r.count++
+	r.last = entry.Header.Hash()
+	return entry, nil
+}
+
+// Verify reads a complete archive file and checks its entries, its content
+// root and that the root matches the content address in the file name.
+func Verify(path string) (Meta, common.Hash, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return Meta{}, common.Hash{}, err
+	}
+	defer fh.Close()
+
+	reader, err := NewReader(fh)
+	if err != nil {
+		return Meta{}, common.Hash{}, err
+	}
+	for {
+		if _, err := reader.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return reader.meta, common.Hash{}, err
+		}
+	}
+	root := reader.acc.root()
+	if name := Filename(reader.meta.Network, reader.meta.Epoch, root); name != filepath.Base(path) {
+		return reader.meta, root, fmt.Errorf("era archive %s does not match its content address %s", filepath.Base(path), name)
+	}
+	return reader.meta, root, nil
+}
@@ -0,0 +1,143 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func writeTestEpoch(t *testing.T, w io.Writer, epoch uint64) common.Hash {
+	writer, err := NewWriter(w, "dev", epoch)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	var parent common.Hash
+	for n := epoch * EpochSize; n < (epoch+1)*EpochSize; n++ {
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(n), ParentHash: parent}, nil, nil)
+		if err := writer.Add(NewEntry(block, nil, []byte{byte(n)})); err != nil {
+			t.Fatalf("failed to add block #%d: %v", n, err)
+		}
+		parent = block.Hash()
+	}
+	root, err := writer.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	return root
+}
+
+func TestEraRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	buf := new(bytes.Buffer)
+	root := writeTestEpoch(t, buf, 1)
+
+	path := filepath.Join(dir, Filename("dev", 1, root))
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta, have, err := Verify(path)
+	if err != nil {
+		t.Fatalf("failed to verify archive: %v", err)
+	}
+	if have != root || meta.Epoch != 1 || meta.Network != "dev" {
+		t.Fatalf("meta mismatch: have %+v %x, want epoch 1 %x", meta, have, root)
+	}
+	// A renamed archive must not pass verification
+	renamed := filepath.Join(dir, Filename("dev", 1, common.Hash{}))
+	if err := os.Rename(path, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Verify(renamed); err == nil {
+		t.Fatalf("verified archive with wrong content address")
+	}
+	// A corrupted archive must be rejected
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+	reader, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err = reader.Next(); err != nil {
+			break
+		}
+	}
+	if err != errRootMismatch {
+		t.Fatalf("corruption error mismatch: have %v, want %v", err, errRootMismatch)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	db := serodb.NewMemDatabase()
+
+	// A chain snapshotted at block 3, only the bodies of the genesis and
+	// snapshot blocks are known
+	var (
+		entries []*Entry
+		parent  common.Hash
+	)
+	for n := uint64(0); n <= 3; n++ {
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(n), ParentHash: parent}, nil, nil)
+		rawdb.WriteHeader(db, block.Header())
+		rawdb.WriteCanonicalHash(db, block.Hash(), n)
+		entries = append(entries, NewEntry(block, nil, nil))
+		parent = block.Hash()
+	}
+	rawdb.WriteBody(db, entries[0].Header.Hash(), 0, entries[0].Body)
+	rawdb.WriteReceipts(db, entries[0].Header.Hash(), 0, nil)
+	rawdb.WriteBody(db, entries[3].Header.Hash(), 3, entries[3].Body)
+	rawdb.WriteBodyTail(db, 3)
+	rawdb.WriteReceiptTail(db, 4)
+
+	// Restoring a block not joined to the tails keeps them
+	if !restore(db, entries[1]) {
+		t.Fatalf("block #1 not restored")
+	}
+	lowerTails(db)
+	if body, receipt := rawdb.ReadBodyTail(db), rawdb.ReadReceiptTail(db); body != 3 || receipt != 4 {
+		t.Errorf("tails mismatch: have %d, %d, want 3, 4", body, receipt)
+	}
+	// Filling the gap lowers them down to the genesis block
+	for _, entry := range entries[2:] {
+		if !restore(db, entry) {
+			t.Fatalf("block #%d not restored", entry.Header.Number)
+		}
+	}
+	lowerTails(db)
+	if body, receipt := rawdb.ReadBodyTail(db), rawdb.ReadReceiptTail(db); body != 0 || receipt != 0 {
+		t.Errorf("tails mismatch: have %d, %d, want 0, 0", body, receipt)
+	}
+	if restore(db, entries[2]) {
+		t.Errorf("complete block #2 restored again")
+	}
+}
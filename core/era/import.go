@@ -0,0 +1,194 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// importBatchSize is the number of blocks inserted into the chain at once.
+const importBatchSize = 2500
+
+// ErrAborted is returned when an import is aborted.
+var ErrAborted = errors.New("era import aborted")
+
+// Files returns the archives in dir, ordered by epoch.
+func Files(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.era"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		_, ei, _, _ := ParseFilename(matches[i])
+		_, ej, _, _ := ParseFilename(matches[j])
+		return ei < ej
+	})
+	return matches, nil
+}
+
+// Import verifies an archive and imports its blocks. Blocks missing from the
+// chain are inserted and executed, their zstate block records checked against
+// the archived ones. The canonical blocks below the head whose bodies or
+// receipts are missing, as the blocks below a state snapshot, get them
+// restored from the archive, lowering the body and receipt tails once the
+// restored history joins them. Closing abort stops the import between blocks.
+func Import(chain *core.BlockChain, db serodb.Database, path string, abort <-chan struct{}) error {
+	log.Info("Importing era archive", "file", path)
+
+	meta, root, err := Verify(path)
+	if err != nil {
+		return err
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	reader, err := NewReader(fh)
+	if err != nil {
+		return err
+	}
+	var (
+		blocks   = make(types.Blocks, 0, importBatchSize)
+		entries  = make([]*Entry, 0, importBatchSize)
+		restored int
+	)
+	flush := func() error {
+		if len(blocks) == 0 {
+			return nil
+		}
+		if _, err := chain.InsertChain(blocks); err != nil {
+			return err
+		}
+		statedb, err := chain.State()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if len(entry.ZBlock) == 0 {
+				continue
+			}
+			number, hash := entry.Header.Number.Uint64(), entry.Header.Hash()
+			zblock, err := statedb.GetZState().GetBlock(number, hash.HashToUint256()).Serial()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(zblock, entry.ZBlock) {
+				return fmt.Errorf("zstate block record mismatch at #%d", number)
+			}
+		}
+		blocks, entries = blocks[:0], entries[:0]
+		return nil
+	}
+	for {
+		select {
+		case <-abort:
+			return ErrAborted
+		default:
+		}
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		// don't import first block
+		number := entry.Header.Number.Uint64()
+		if number == 0 {
+			continue
+		}
+		hash := entry.Header.Hash()
+		if number <= chain.CurrentBlock().NumberU64() && rawdb.ReadCanonicalHash(db, number) == hash {
+			if restore(db, entry) {
+				restored++
+			}
+			continue
+		}
+		if chain.HasBlockAndState(hash, number) {
+			continue
+		}
+		blocks = append(blocks, entry.Block())
+		entries = append(entries, entry)
+		if len(blocks) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if restored > 0 {
+		lowerTails(db)
+	}
+	log.Info("Imported era archive", "epoch", meta.Epoch, "root", root, "restored", restored)
+	return nil
+}
+
+// restore writes the body, receipts and transaction lookup entries of a
+// canonical block missing them, reporting whether anything was written.
+func restore(db serodb.Database, entry *Entry) bool {
+	number, hash := entry.Header.Number.Uint64(), entry.Header.Hash()
+
+	batch := db.NewBatch()
+	if !rawdb.HasBody(db, hash, number) {
+		rawdb.WriteBody(batch, hash, number, entry.Body)
+		rawdb.WriteTxLookupEntries(batch, entry.Block())
+	}
+	if rawdb.ReadReceipts(db, hash, number) == nil {
+		rawdb.WriteReceipts(batch, hash, number, entry.ReceiptList())
+	}
+	if batch.ValueSize() == 0 {
+		return false
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to restore era block", "number", number, "err", err)
+	}
+	return true
+}
+
+// lowerTails moves the body and receipt tails down to the oldest canonical
+// block of the history they are joined to without gaps.
+func lowerTails(db serodb.Database) {
+	tail := rawdb.ReadBodyTail(db)
+	for tail > 0 && rawdb.HasBody(db, rawdb.ReadCanonicalHash(db, tail-1), tail-1) {
+		tail--
+	}
+	if tail < rawdb.ReadBodyTail(db) {
+		rawdb.WriteBodyTail(db, tail)
+	}
+	tail = rawdb.ReadReceiptTail(db)
+	for tail > 0 && rawdb.ReadReceipts(db, rawdb.ReadCanonicalHash(db, tail-1), tail-1) != nil {
+		tail--
+	}
+	if tail < rawdb.ReadReceiptTail(db) {
+		rawdb.WriteReceiptTail(db, tail)
+	}
+}
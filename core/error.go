@@ -16,7 +16,12 @@
 
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-sero/common"
+)
 
 var (
 	// ErrKnownBlock is returned when a block to import is already known locally.
@@ -32,4 +37,32 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrTxExpired is returned if a transaction is included in or submitted for
+	// a block after its valid until block.
+	ErrTxExpired = errors.New("transaction expired")
+
+	// ErrExpiryNotActive is returned if a transaction carries a valid until
+	// block before the Expiry fork.
+	ErrExpiryNotActive = errors.New("transaction expiry not active yet")
+
+	// ErrEhashMismatch is returned if the fields of a transaction outside its
+	// stx, such as its expiry, are not the ones its sender signed.
+	ErrEhashMismatch = errors.New("transaction does not match its signed ehash")
+
+	// ErrPkgTermsNotActive is returned if a transaction creates a package with
+	// closing terms or closes one with a preimage before the PkgTerms fork.
+	ErrPkgTermsNotActive = errors.New("package terms not active yet")
 )
+
+// InvalidTxError is returned when a block to import carries a transaction
+// failing the verification of its proofs and balance, or failing to apply.
+type InvalidTxError struct {
+	Index int         // position of the transaction in the block
+	Hash  common.Hash // hash of the transaction
+	Err   error       // reason the transaction was rejected
+}
+
+func (e *InvalidTxError) Error() string {
+	return fmt.Sprintf("invalid transaction %d (%x): %v", e.Index, e.Hash, e.Err)
+}
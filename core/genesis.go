@@ -228,7 +228,7 @@ func (g *Genesis) ToBlock(db serodb.Database) *types.Block {
 	}
 	statedb, _ := state.NewGenesis(common.Hash{}, state.NewDatabase(db))
 	statedb.RegisterToken(state.EmptyAddress, "SERO")
-	statedb.AddBalance(state.EmptyAddress, "SERO", new(big.Int).Mul(big.NewInt(250000000), big.NewInt(1e+18)))
+	statedb.AddBalance(state.EmptyAddress, "SERO", new(big.Int).Set(GenesisRewardPool))
 
 	sero := common.BytesToHash(common.LeftPadBytes([]byte("SERO"), 32))
 	var keys common.Addresses
@@ -321,6 +321,34 @@ func (g *Genesis) MustCommit(db serodb.Database) *types.Block {
 	return block
 }
 
+// GenesisRewardPool is the SERO balance of the reward pool every genesis block
+// starts with. The first block rewards are paid out of it.
+var GenesisRewardPool = new(big.Int).Mul(big.NewInt(250000000), big.NewInt(1e+18))
+
+// Supply returns the amount of SERO in existence after the genesis block, the
+// reward pool included.
+func (g *Genesis) Supply() *big.Int {
+	supply := new(big.Int).Set(GenesisRewardPool)
+	for addr, account := range g.Alloc {
+		if addr != state.EmptyAddress && account.Balance != nil {
+			supply.Add(supply, account.Balance)
+		}
+	}
+	return supply
+}
+
+// GenesisByHash returns the specification of the known genesis block with the
+// given hash, or nil if the hash belongs to a custom genesis block.
+func GenesisByHash(hash common.Hash) *Genesis {
+	switch hash {
+	case params.MainnetGenesisHash:
+		return DefaultGenesisBlock()
+	case params.AlphanetGenesisHash:
+		return DefaultAlphanetGenesisBlock()
+	}
+	return nil
+}
+
 // DefaultGenesisBlock returns the Ethereum main net genesis block.
 func DefaultGenesisBlock() *Genesis {
 	return &Genesis{
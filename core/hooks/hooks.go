@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hooks runs custom code, such as indexers and alerting, inside the
+// node for every block becoming canonical and every reorg, without polling.
+//
+// Hooks are registered in-process with Register, or loaded from Go plugins
+// with Load. A plugin is a shared object built with -buildmode=plugin against
+// the same sources as the node, exporting
+//
+//	func NewBlockHook() (hooks.BlockHook, error)
+package hooks
+
+import (
+	"fmt"
+	"plugin"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+)
+
+// queueSize is the number of calls waiting for the hooks before block
+// import waits for them.
+const queueSize = 1024
+
+// slowHook is the duration after which a call of a hook is logged as slow.
+const slowHook = time.Second
+
+// PluginSymbol is the constructor a plugin exports.
+const PluginSymbol = "NewBlockHook"
+
+// BlockHook is called by the blockchain in the order of the changes of the
+// canonical chain. Calls are made from a single goroutine, after the blocks
+// are written and off the import path, so the chain may be ahead of the block
+// a hook is called with. A hook blocking for long stalls block import once
+// the queue of pending calls is full.
+type BlockHook interface {
+	// BlockImported is called for every block becoming canonical, in ascending
+	// order, with its receipts and the zstate record of the outs it created
+	// and spent and the packages it changed.
+	BlockImported(block *types.Block, receipts types.Receipts, zblock *zstate.Block)
+
+	// ChainReorged is called when the blocks above ancestor are dropped from
+	// the canonical chain, dropped being ordered from the highest down. The
+	// blocks of the new branch follow with BlockImported.
+	ChainReorged(ancestor *types.Block, dropped []*types.Block)
+}
+
+var registry struct {
+	mu    sync.RWMutex
+	hooks []BlockHook
+	queue chan func(BlockHook)
+}
+
+// Register adds a hook called for all changes of the canonical chain from now
+// on.
+func Register(hook BlockHook) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if registry.queue == nil {
+		registry.queue = make(chan func(BlockHook), queueSize)
+		go loop(registry.queue)
+	}
+	registry.hooks = append(registry.hooks, hook)
+}
+
+// Load opens a plugin and registers the hook it constructs.
+func Load(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("block hook %s: %v", path, err)
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return fmt.Errorf("block hook %s: %v", path, err)
+	}
+	newHook, ok := sym.(func() (BlockHook, error))
+	if !ok {
+		return fmt.Errorf("block hook %s: %s is %T, not func() (hooks.BlockHook, error)", path, PluginSymbol, sym)
+	}
+	hook, err := newHook()
+	if err != nil {
+		return fmt.Errorf("block hook %s: %v", path, err)
+	}
+	if hook == nil {
+		return fmt.Errorf("block hook %s: no hook constructed", path)
+	}
+	Register(hook)
+	log.Info("Loaded block hook", "path", path)
+	return nil
+}
+
+// Enabled reports whether any hook is registered, so the blockchain can skip
+// collecting what the hooks are called with.
+func Enabled() bool {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return len(registry.hooks) > 0
+}
+
+// BlockImported queues the call of the hooks for a block becoming canonical.
+func BlockImported(block *types.Block, receipts types.Receipts, zblock *zstate.Block) {
+	dispatch(func(hook BlockHook) {
+		hook.BlockImported(block, receipts, zblock)
+	})
+}
+
+// ChainReorged queues the call of the hooks for the blocks dropped by a reorg.
+func ChainReorged(ancestor *types.Block, dropped []*types.Block) {
+	dispatch(func(hook BlockHook) {
+		hook.ChainReorged(ancestor, dropped)
+	})
+}
+
+func dispatch(call func(BlockHook)) {
+	registry.mu.RLock()
+	queue := registry.queue
+	registry.mu.RUnlock()
+
+	if queue != nil {
+		queue <- call
+	}
+}
+
+func loop(queue chan func(BlockHook)) {
+	for call := range queue {
+		registry.mu.RLock()
+		hooks := registry.hooks
+		registry.mu.RUnlock()
+
+		for _, hook := range hooks {
+			run(hook, call)
+		}
+	}
+}
+
+// run calls a hook, isolating the node from its panics.
+func run(hook BlockHook, call func(BlockHook)) {
+	start := time.Now()
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("Block hook panicked", "hook", fmt.Sprintf("%T", hook), "err", err)
+		}
+		if elapsed := time.Since(start); elapsed > slowHook {
+			log.Warn("Block hook is slow", "hook", fmt.Sprintf("%T", hook), "elapsed", elapsed)
+		}
+	}()
+	call(hook)
+}
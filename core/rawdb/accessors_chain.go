@@ -126,6 +126,88 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// ReadReceiptTail retrieves the number of the oldest block whose receipts are
+// still retained. Receipts of all canonical blocks below it have been pruned.
+func ReadReceiptTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(receiptTailKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WriteReceiptTail stores the number of the oldest block whose receipts are
+// still retained.
+func WriteReceiptTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(receiptTailKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		log.Crit("Failed to store receipt tail", "err", err)
+	}
+}
+
+// ReadBodyTail retrieves the number of the oldest block whose body was not yet
+// checked for pruning. Bodies of canonical blocks below it were dropped unless
+// they carried data of local accounts.
+func ReadBodyTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(bodyTailKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WriteBodyTail stores the number of the oldest block whose body was not yet
+// checked for pruning.
+func WriteBodyTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(bodyTailKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		log.Crit("Failed to store body tail", "err", err)
+	}
+}
+
+// ReadZStateTail retrieves the number of the oldest block whose zstate block
+// record was not yet checked for pruning. Records of canonical blocks below it
+// were dropped unless they created outs still owned by local accounts.
+func ReadZStateTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(zstateTailKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WriteZStateTail stores the number of the oldest block whose zstate block
+// record was not yet checked for pruning.
+func WriteZStateTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(zstateTailKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		log.Crit("Failed to store zstate tail", "err", err)
+	}
+}
+
+// ReadArchiveTail retrieves the number of the oldest block whose state is
+// retained, the node having run in archive mode since. It reports false if the
+// node does not run in archive mode.
+func ReadArchiveTail(db DatabaseReader) (uint64, bool) {
+	data, _ := db.Get(archiveTailKey)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// WriteArchiveTail stores the number of the oldest block whose state is
+// retained by the archive node.
+func WriteArchiveTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(archiveTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store archive tail", "err", err)
+	}
+}
+
+// DeleteArchiveTail removes the archive tail, the node pruning the state again.
+func DeleteArchiveTail(db DatabaseDeleter) {
+	if err := db.Delete(archiveTailKey); err != nil {
+		log.Crit("Failed to delete archive tail", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
@@ -262,6 +262,19 @@ func TestHeadStorage(t *testing.T) {
 	}
 }
 
+// Tests that the receipt retention tail can be stored and retrieved.
+func TestReceiptTailStorage(t *testing.T) {
+	db := serodb.NewMemDatabase()
+
+	if tail := ReadReceiptTail(db); tail != 0 {
+		t.Fatalf("Non zero receipt tail returned: %d", tail)
+	}
+	WriteReceiptTail(db, 4096)
+	if tail := ReadReceiptTail(db); tail != 4096 {
+		t.Fatalf("Receipt tail mismatch: have %d, want %d", tail, 4096)
+	}
+}
+
 // Tests that receipts associated with a single block can be stored and retrieved.
 func TestBlockReceiptStorage(t *testing.T) {
 	db := serodb.NewMemDatabase()
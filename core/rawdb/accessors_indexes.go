@@ -17,6 +17,9 @@
 package rawdb
 
 import (
+	"encoding/binary"
+	"math/big"
+
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/log"
@@ -62,6 +65,250 @@ func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash) {
 	db.Delete(txLookupKey(hash))
 }
 
+// ClientRequest is the transaction sent for a client request id, which is
+// forgotten once it expires.
+type ClientRequest struct {
+	Hash    common.Hash
+	Expires uint64 // unix time
+}
+
+// ReadClientRequest retrieves the transaction sent for a client request id.
+func ReadClientRequest(db DatabaseReader, id common.Hash) *ClientRequest {
+	data, _ := db.Get(clientRequestKey(id))
+	if len(data) == 0 {
+		return nil
+	}
+	request := new(ClientRequest)
+	if err := rlp.DecodeBytes(data, request); err != nil {
+		log.Error("Invalid client request RLP", "id", id, "err", err)
+		return nil
+	}
+	return request
+}
+
+// WriteClientRequest stores the transaction sent for a client request id.
+func WriteClientRequest(db DatabaseWriter, id common.Hash, request *ClientRequest) {
+	data, err := rlp.EncodeToBytes(request)
+	if err != nil {
+		log.Crit("Failed to encode client request", "err", err)
+	}
+	if err := db.Put(clientRequestKey(id), data); err != nil {
+		log.Crit("Failed to store client request", "err", err)
+	}
+}
+
+// DeleteClientRequest removes the transaction of a client request id.
+func DeleteClientRequest(db DatabaseDeleter, id common.Hash) {
+	db.Delete(clientRequestKey(id))
+}
+
+// SpendRecord is a value sent by a local account through the RPC interface.
+type SpendRecord struct {
+	Time     uint64 // unix time of the send
+	Currency string
+	Amount   *big.Int
+}
+
+// ReadSpendLog retrieves the recent sends of a local account.
+func ReadSpendLog(db DatabaseReader, account common.AccountAddress) []SpendRecord {
+	data, _ := db.Get(spendLogKey(account))
+	if len(data) == 0 {
+		return nil
+	}
+	var records []SpendRecord
+	if err := rlp.DecodeBytes(data, &records); err != nil {
+		log.Error("Invalid spend log RLP", "account", account, "err", err)
+		return nil
+	}
+	return records
+}
+
+// WriteSpendLog stores the recent sends of a local account.
+func WriteSpendLog(db DatabaseWriter, account common.AccountAddress, records []SpendRecord) {
+	data, err := rlp.EncodeToBytes(records)
+	if err != nil {
+		log.Crit("Failed to encode spend log", "err", err)
+	}
+	if err := db.Put(spendLogKey(account), data); err != nil {
+		log.Crit("Failed to store spend log", "err", err)
+	}
+}
+
+// SendTemplate is a named send of a local account, invoked through the RPC
+// interface with the amount to send.
+type SendTemplate struct {
+	Name        string
+	Tenant      string // tenant the template belongs to, empty for the node
+	From        common.AccountAddress
+	To          common.AccountAddress
+	Currency    string
+	Memo        string   // pattern of the memo
+	Gas         uint64   // 0 for the estimate
+	GasPrice    *big.Int `rlp:"nil"`
+	GasCurrency string
+	Sent        uint64 // number of sends from the template
+}
+
+// ReadSendTemplates retrieves the send templates.
+func ReadSendTemplates(db DatabaseReader) []SendTemplate {
+	data, _ := db.Get(sendTemplatesKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var templates []SendTemplate
+	if err := rlp.DecodeBytes(data, &templates); err != nil {
+		log.Error("Invalid send templates RLP", "err", err)
+		return nil
+	}
+	return templates
+}
+
+// WriteSendTemplates stores the send templates.
+func WriteSendTemplates(db DatabaseWriter, templates []SendTemplate) {
+	data, err := rlp.EncodeToBytes(templates)
+	if err != nil {
+		log.Crit("Failed to encode send templates", "err", err)
+	}
+	if err := db.Put(sendTemplatesKey, data); err != nil {
+		log.Crit("Failed to store send templates", "err", err)
+	}
+}
+
+// PaymentSchedule is a payment of a local account repeated by the node at the
+// times of a cron specification.
+type PaymentSchedule struct {
+	Name       string
+	Tenant     string // tenant the schedule belongs to, empty for the node
+	Spec       string // minute, hour, day of month, month and day of week
+	From       common.AccountAddress
+	To         common.AccountAddress
+	Currency   string
+	Amount     *big.Int
+	Memo       string // pattern of the memo
+	MaxRetries uint64 // retries of a failed run
+	RetryDelay uint64 // seconds between the retries
+	Paused     bool
+	Next       uint64 // unix time of the next attempt
+	Attempt    uint64 // retries of the current run
+	Sent       uint64 // number of successful runs
+	Runs       []ScheduledRun
+}
+
+// ScheduledRun is an attempt of a payment schedule.
+type ScheduledRun struct {
+	Time    uint64
+	Attempt uint64
+	Hash    common.Hash // zero if the attempt failed
+	Error   string
+}
+
+// ReadPaymentSchedules retrieves the payment schedules.
+func ReadPaymentSchedules(db DatabaseReader) []PaymentSchedule {
+	data, _ := db.Get(paymentSchedulesKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var schedules []PaymentSchedule
+	if err := rlp.DecodeBytes(data, &schedules); err != nil {
+		log.Error("Invalid payment schedules RLP", "err", err)
+		return nil
+	}
+	return schedules
+}
+
+// WritePaymentSchedules stores the payment schedules.
+func WritePaymentSchedules(db DatabaseWriter, schedules []PaymentSchedule) {
+	data, err := rlp.EncodeToBytes(schedules)
+	if err != nil {
+		log.Crit("Failed to encode payment schedules", "err", err)
+	}
+	if err := db.Put(paymentSchedulesKey, data); err != nil {
+		log.Crit("Failed to store payment schedules", "err", err)
+	}
+}
+
+// DailyStats are the statistics of the blocks of a day.
+type DailyStats struct {
+	Day        uint64 // days since the unix epoch, in UTC
+	FirstBlock uint64
+	LastBlock  uint64
+	Blocks     uint64
+	Txs        []TxTypeCount
+	ActivePKrs uint64   // distinct PKrs sending or receiving
+	NewTokens  []string // currencies registered by contracts
+	Fees       []CurrencyAmount
+	Incomplete uint64 // blocks whose token issuances could not be replayed
+}
+
+// TxTypeCount is the number of the transactions of a type.
+type TxTypeCount struct {
+	Type  string
+	Count uint64
+}
+
+// CurrencyAmount is an amount of a currency.
+type CurrencyAmount struct {
+	Currency string
+	Amount   *big.Int
+}
+
+// ReadDailyStats retrieves the statistics of a day, nil if none were
+// aggregated.
+func ReadDailyStats(db DatabaseReader, day uint64) *DailyStats {
+	data, _ := db.Get(dailyStatsKey(day))
+	if len(data) == 0 {
+		return nil
+	}
+	stats := new(DailyStats)
+	if err := rlp.DecodeBytes(data, stats); err != nil {
+		log.Error("Invalid daily stats RLP", "day", day, "err", err)
+		return nil
+	}
+	return stats
+}
+
+// WriteDailyStats stores the statistics of a day.
+func WriteDailyStats(db DatabaseWriter, stats *DailyStats) {
+	data, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		log.Crit("Failed to encode daily stats", "err", err)
+	}
+	if err := db.Put(dailyStatsKey(stats.Day), data); err != nil {
+		log.Crit("Failed to store daily stats", "err", err)
+	}
+}
+
+// HasDailyPKr reports whether a PKr was active on a day.
+func HasDailyPKr(db DatabaseReader, day uint64, pkr common.Hash) bool {
+	has, _ := db.Has(dailyPKrKey(day, pkr))
+	return has
+}
+
+// WriteDailyPKr marks a PKr active on a day.
+func WriteDailyPKr(db DatabaseWriter, day uint64, pkr common.Hash) {
+	if err := db.Put(dailyPKrKey(day, pkr), nil); err != nil {
+		log.Crit("Failed to store daily PKr", "err", err)
+	}
+}
+
+// ReadDailyStatsHead retrieves the number of the next block to aggregate into
+// the daily statistics.
+func ReadDailyStatsHead(db DatabaseReader) uint64 {
+	data, _ := db.Get(dailyStatsHeadKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteDailyStatsHead stores the number of the next block to aggregate into
+// the daily statistics.
+func WriteDailyStatsHead(db DatabaseWriter, number uint64) {
+	if err := db.Put(dailyStatsHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store daily stats head", "err", err)
+	}
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db DatabaseReader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -22,6 +22,7 @@ import (
 
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/metrics"
+	"github.com/sero-cash/go-sero/serodb"
 )
 
 // The fields below define the low level database schema prefixing.
@@ -41,6 +42,18 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// receiptTailKey tracks the oldest block number whose receipts are still retained.
+	receiptTailKey = []byte("ReceiptTail")
+
+	// bodyTailKey tracks the oldest block number whose body was not yet checked for pruning.
+	bodyTailKey = []byte("BodyTail")
+
+	// zstateTailKey tracks the oldest block number whose zstate block record was not yet checked for pruning.
+	zstateTailKey = []byte("ZStateTail")
+
+	// archiveTailKey tracks the oldest block number whose state is retained by an archive node.
+	archiveTailKey = []byte("ArchiveTail")
+
 	indexPrefix = []byte("indexB")
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
@@ -57,6 +70,15 @@ var (
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
+	clientRequestPrefix = []byte("client-request-")   // clientRequestPrefix + request id hash -> transaction hash and expiry
+	spendLogPrefix      = []byte("spend-log-")        // spendLogPrefix + account address -> spend records
+	sendTemplatesKey    = []byte("send-templates")    // sendTemplatesKey -> send templates
+	paymentSchedulesKey = []byte("payment-schedules") // paymentSchedulesKey -> payment schedules
+
+	dailyStatsPrefix  = []byte("daily-stats-")   // dailyStatsPrefix + day (uint64 big endian) -> daily statistics
+	dailyPKrPrefix    = []byte("daily-pkr-")     // dailyPKrPrefix + day (uint64 big endian) + PKr hash -> nothing
+	dailyStatsHeadKey = []byte("DailyStatsHead") // dailyStatsHeadKey -> number of the next block to aggregate
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 
@@ -137,3 +159,51 @@ func preimageKey(hash common.Hash) []byte {
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
 }
+
+// clientRequestKey = clientRequestPrefix + id
+func clientRequestKey(id common.Hash) []byte {
+	return append(clientRequestPrefix, id.Bytes()...)
+}
+
+// spendLogKey = spendLogPrefix + account address
+func spendLogKey(account common.AccountAddress) []byte {
+	return append(spendLogPrefix, account.Bytes()...)
+}
+
+// dailyStatsKey = dailyStatsPrefix + day (uint64 big endian)
+func dailyStatsKey(day uint64) []byte {
+	return append(dailyStatsPrefix, encodeBlockNumber(day)...)
+}
+
+// dailyPKrKey = dailyPKrPrefix + day (uint64 big endian) + PKr hash
+func dailyPKrKey(day uint64, pkr common.Hash) []byte {
+	return append(append(dailyPKrPrefix, encodeBlockNumber(day)...), pkr.Bytes()...)
+}
+
+// ColdTables are the tables of block data a split chain database can keep on
+// the cold volume.
+var ColdTables = []serodb.ColdTable{
+	{
+		Name:   "headers",
+		Prefix: headerPrefix,
+		KeyLengths: []int{
+			len(headerKey(0, common.Hash{})),
+			len(headerTDKey(0, common.Hash{})),
+			len(headerHashKey(0)),
+		},
+	},
+	{
+		Name:       "bodies",
+		Prefix:     blockBodyPrefix,
+		KeyLengths: []int{len(blockBodyKey(0, common.Hash{}))},
+	},
+	{
+		Name:       "receipts",
+		Prefix:     blockReceiptsPrefix,
+		KeyLengths: []int{len(blockReceiptsKey(0, common.Hash{}))},
+	},
+}
+
+// DefaultColdTables are the tables kept on the cold volume unless configured
+// otherwise.
+var DefaultColdTables = []string{"bodies", "receipts"}
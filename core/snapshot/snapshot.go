@@ -0,0 +1,307 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements trusted state snapshots. A snapshot carries the
+// header chain up to one block, the body of that block, the zstate block
+// records and every node of its state, so a node can be initialized from it
+// without replaying the chain. Everything in a snapshot is verified against
+// the hash of its block, which the operator obtains out of band.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+)
+
+// Version is the snapshot format version written into every file.
+const Version = 1
+
+var (
+	errBadVersion = errors.New("unsupported state snapshot version")
+	errNoGenesis  = errors.New("database has no genesis block")
+	errNotEmpty   = errors.New("database already contains blocks beyond genesis")
+)
+
+// Meta is the leading record of a snapshot.
+type Meta struct {
+	Version uint64
+	Network string
+	Number  uint64
+	Hash    common.Hash
+}
+
+// Entry is a header of the snapshotted chain with its zstate block record.
+type Entry struct {
+	Header *types.Header
+	ZBlock []byte // RLP encoded zstate block record, empty if none was recorded
+}
+
+// Node is a state trie node or a contract code, keyed by its hash.
+type Node struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// Writer streams the records of a snapshot. Headers are added in order from
+// block 1, followed by the body of the snapshot block and then the nodes.
+type Writer struct {
+	w     io.Writer
+	meta  Meta
+	count uint64
+	last  common.Hash
+	body  bool
+}
+
+// NewWriter writes the snapshot meta record and returns a writer for its
+// records. The header chain is linked to the given genesis hash.
+func NewWriter(w io.Writer, network string, number uint64, hash common.Hash, genesis common.Hash) (*Writer, error) {
+	meta := Meta{
+		Version: Version,
+		Network: network,
+		Number:  number,
+		Hash:    hash,
+	}
+	if err := rlp.Encode(w, &meta); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, meta: meta, last: genesis}, nil
+}
+
+// AddHeader appends the next header of the chain to the snapshot.
+func (w *Writer) AddHeader(entry *Entry) error {
+	if want := w.count + 1; entry.Header.Number.Uint64() != want {
+		return fmt.Errorf("non contiguous snapshot header: have #%d, want #%d", entry.Header.Number.Uint64(), want)
+	}
+	if entry.Header.ParentHash != w.last {
+		return fmt.Errorf("snapshot header #%d does not extend #%d", w.count+1, w.count)
+	}
+	if err := rlp.Encode(w.w, entry); err != nil {
+		return err
+	}
+	w.count++
+	w.last = entry.Header.Hash()
+	return nil
+}
+
+// AddBody appends the body of the snapshot block, once all headers were added.
+func (w *Writer) AddBody(body *types.Body) error {
+	if w.count != w.meta.Number || w.last != w.meta.Hash {
+		return fmt.Errorf("incomplete snapshot header chain: have %d headers, want %d", w.count, w.meta.Number)
+	}
+	if err := rlp.Encode(w.w, body); err != nil {
+		return err
+	}
+	w.body = true
+	return nil
+}
+
+// AddNode appends a state node or contract code to the snapshot.
+func (w *Writer) AddNode(hash common.Hash, blob []byte) error {
+	if !w.body {
+		return errors.New("snapshot nodes added before the block body")
+	}
+	return rlp.Encode(w.w, &Node{Hash: hash, Blob: blob})
+}
+
+// Import verifies a snapshot against the trusted hash of its block and
+// initializes the database from it. The database must not contain any block
+// beyond its genesis, to which the snapshotted header chain has to link.
+//
+// Headers, zstate block records and nodes are content addressed and written as
+// they are verified, but the chain is only made canonical once the header
+// chain matched the trusted hash, the state is complete and every out root
+// recorded in the zstate block records is present in the state.
+func Import(db serodb.Database, r io.Reader, network string, trusted common.Hash) (*types.Header, error) {
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errNoGenesis
+	}
+	if head := rawdb.ReadHeadHeaderHash(db); head != genesis {
+		return nil, errNotEmpty
+	}
+	stream := rlp.NewStream(r, 0)
+
+	var meta Meta
+	if err := stream.Decode(&meta); err != nil {
+		return nil, err
+	}
+	if meta.Version != Version {
+		return nil, errBadVersion
+	}
+	if meta.Network != network {
+		return nil, fmt.Errorf("snapshot of network %q, want %q", meta.Network, network)
+	}
+	if meta.Hash != trusted {
+		return nil, fmt.Errorf("snapshot of block %x, want trusted %x", meta.Hash, trusted)
+	}
+	if meta.Number == 0 {
+		return nil, errors.New("snapshot of the genesis block")
+	}
+	td := rawdb.ReadTd(db, genesis, 0)
+	if td == nil {
+		return nil, errNoGenesis
+	}
+	var (
+		batch  = db.NewBatch()
+		hashes = make([]common.Hash, 0, meta.Number)
+		zroots = make(map[uint64]common.Hash)
+		parent = genesis
+		head   *types.Header
+	)
+	flush := func(force bool) error {
+		if force || batch.ValueSize() >= serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}
+	// Verify the header chain from the local genesis up to the trusted block
+	for number := uint64(1); number <= meta.Number; number++ {
+		var entry Entry
+		if err := stream.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("header #%d: %v", number, err)
+		}
+		header := entry.Header
+		if header == nil || header.Number == nil || header.Number.Uint64() != number {
+			return nil, fmt.Errorf("header #%d: unexpected number", number)
+		}
+		if header.ParentHash != parent {
+			return nil, fmt.Errorf("header #%d: does not extend #%d", number, number-1)
+		}
+		hash := header.Hash()
+		td = new(big.Int).Add(td, header.Difficulty)
+
+		rawdb.WriteHeader(batch, header)
+		rawdb.WriteTd(batch, hash, number, td)
+		if len(entry.ZBlock) > 0 {
+			if err := batch.Put(zstate.BlockKey(number, hash.HashToUint256()), entry.ZBlock); err != nil {
+				return nil, err
+			}
+			zroots[number] = hash
+		}
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+		parent, head = hash, header
+	}
+	if parent != trusted {
+		return nil, fmt.Errorf("header chain leads to %x, want trusted %x", parent, trusted)
+	}
+	// Verify the body of the trusted block. Sero blocks have no uncles, the
+	// transaction root commits to the whole body and decoding rejects bodies
+	// carrying any other field.
+	body := new(types.Body)
+	if err := stream.Decode(body); err != nil {
+		return nil, fmt.Errorf("body: %v", err)
+	}
+	if hash := types.DeriveSha(types.Transactions(body.Transactions)); hash != head.TxHash {
+		return nil, fmt.Errorf("transaction root mismatch (have %x, want %x)", hash, head.TxHash)
+	}
+	rawdb.WriteBody(batch, trusted, meta.Number, body)
+
+	// Store every node matching its hash, then check the state for completeness
+	nodes := 0
+	for {
+		var node Node
+		if err := stream.Decode(&node); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("node %d: %v", nodes, err)
+		}
+		if hash := crypto.Keccak256Hash(node.Blob); hash != node.Hash {
+			return nil, fmt.Errorf("node %x: content hash mismatch", node.Hash)
+		}
+		if err := batch.Put(node.Hash[:], node.Blob); err != nil {
+			return nil, err
+		}
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+		nodes++
+	}
+	if err := flush(true); err != nil {
+		return nil, err
+	}
+	statedb, err := state.New(head.Root, state.NewDatabase(db), meta.Number)
+	if err != nil {
+		return nil, fmt.Errorf("state %x: %v", head.Root, err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		return nil, fmt.Errorf("incomplete state %x: %v", head.Root, it.Error)
+	}
+	if err := verifyZState(statedb, zroots); err != nil {
+		return nil, err
+	}
+	log.Info("Verified state snapshot", "number", meta.Number, "hash", trusted, "nodes", nodes)
+
+	// Everything checks out, make the snapshotted chain canonical
+	for i, hash := range hashes {
+		rawdb.WriteCanonicalHash(batch, hash, uint64(i+1))
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+	}
+	rawdb.WriteBodyTail(batch, meta.Number)
+	rawdb.WriteReceiptTail(batch, meta.Number+1)
+	rawdb.WriteHeadHeaderHash(batch, trusted)
+	rawdb.WriteHeadFastBlockHash(batch, trusted)
+	rawdb.WriteHeadBlockHash(batch, trusted)
+	if err := flush(true); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// verifyZState checks that every out root recorded by the zstate block
+// records of the chain is present in the snapshotted state.
+func verifyZState(statedb *state.StateDB, records map[uint64]common.Hash) error {
+	zs := statedb.GetZState()
+	for number, hash := range records {
+		block := zs.GetBlock(number, hash.HashToUint256())
+		if block == nil {
+			return fmt.Errorf("zstate record of block #%d: undecodable", number)
+		}
+		for i := range block.Roots {
+			out, err := zs.State.GetOut(&block.Roots[i])
+			if err != nil {
+				return err
+			}
+			if out == nil {
+				return fmt.Errorf("zstate record of block #%d: out %x missing from state", number, block.Roots[i][:])
+			}
+		}
+	}
+	return nil
+}
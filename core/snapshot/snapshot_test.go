@@ -0,0 +1,165 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// newTestDatabase creates a chain database holding only a genesis block.
+func newTestDatabase() (serodb.Database, *types.Header) {
+	db := serodb.NewMemDatabase()
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1)}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteTd(db, genesis.Hash(), 0, genesis.Difficulty)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	rawdb.WriteHeadHeaderHash(db, genesis.Hash())
+	rawdb.WriteHeadBlockHash(db, genesis.Hash())
+	return db, genesis
+}
+
+// makeTestSnapshot writes a snapshot of a short chain on top of the genesis,
+// whose head commits to a small state. If skip is set, the node at that index
+// is left out of the snapshot.
+func makeTestSnapshot(t *testing.T, genesis *types.Header, skip int) ([]byte, *types.Header) {
+	sdb := state.NewDatabase(serodb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, sdb, 0)
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.AddBalance(addr, "SERO", big.NewInt(int64(i)+1))
+		if i%4 == 0 {
+			statedb.SetCode(addr, []byte{i, i, i})
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	headers := []*types.Header{genesis}
+	for n := int64(1); n <= 3; n++ {
+		headers = append(headers, &types.Header{
+			Number:     big.NewInt(n),
+			ParentHash: headers[n-1].Hash(),
+			Difficulty: big.NewInt(2),
+			TxHash:     types.EmptyRootHash,
+		})
+	}
+	head := headers[3]
+	head.Root = root
+
+	buf := new(bytes.Buffer)
+	writer, err := NewWriter(buf, "dev", 3, head.Hash(), genesis.Hash())
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	for _, header := range headers[1:] {
+		if err := writer.AddHeader(&Entry{Header: header}); err != nil {
+			t.Fatalf("failed to add header #%d: %v", header.Number, err)
+		}
+	}
+	if err := writer.AddBody(&types.Body{}); err != nil {
+		t.Fatalf("failed to add body: %v", err)
+	}
+	statedb, _ = state.New(root, sdb, 3)
+	it := state.NewNodeIterator(statedb)
+	for i := 0; it.Next(); {
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		if i != skip {
+			blob, err := sdb.TrieDB().Node(it.Hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node %x: %v", it.Hash, err)
+			}
+			if err := writer.AddNode(it.Hash, blob); err != nil {
+				t.Fatalf("failed to add node: %v", err)
+			}
+		}
+		i++
+	}
+	if it.Error != nil {
+		t.Fatalf("failed to iterate state: %v", it.Error)
+	}
+	return buf.Bytes(), head
+}
+
+func TestSnapshotImport(t *testing.T) {
+	db, genesis := newTestDatabase()
+	snap, head := makeTestSnapshot(t, genesis, -1)
+
+	if _, err := Import(db, bytes.NewReader(snap), "dev", head.Hash()); err != nil {
+		t.Fatalf("failed to import snapshot: %v", err)
+	}
+	if hash := rawdb.ReadHeadBlockHash(db); hash != head.Hash() {
+		t.Fatalf("head block mismatch: have %x, want %x", hash, head.Hash())
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 2); hash != head.ParentHash {
+		t.Fatalf("canonical hash #2 mismatch: have %x, want %x", hash, head.ParentHash)
+	}
+	if td := rawdb.ReadTd(db, head.Hash(), 3); td == nil || td.Int64() != 7 {
+		t.Fatalf("total difficulty mismatch: have %v, want 7", td)
+	}
+	statedb, err := state.New(head.Root, state.NewDatabase(db), 3)
+	if err != nil {
+		t.Fatalf("failed to open imported state: %v", err)
+	}
+	if balance := statedb.GetBalance(common.BytesToAddress([]byte{5}), "SERO"); balance.Int64() != 6 {
+		t.Fatalf("balance mismatch: have %v, want 6", balance)
+	}
+	// A second import must be refused
+	if _, err := Import(db, bytes.NewReader(snap), "dev", head.Hash()); err != errNotEmpty {
+		t.Fatalf("reimport error mismatch: have %v, want %v", err, errNotEmpty)
+	}
+}
+
+func TestSnapshotUntrusted(t *testing.T) {
+	db, genesis := newTestDatabase()
+	snap, _ := makeTestSnapshot(t, genesis, -1)
+
+	if _, err := Import(db, bytes.NewReader(snap), "dev", common.Hash{1}); err == nil {
+		t.Fatalf("snapshot of untrusted block imported")
+	}
+	if hash := rawdb.ReadHeadBlockHash(db); hash != genesis.Hash() {
+		t.Fatalf("head block changed by failed import")
+	}
+}
+
+func TestSnapshotIncompleteState(t *testing.T) {
+	db, genesis := newTestDatabase()
+	snap, head := makeTestSnapshot(t, genesis, 3)
+
+	if _, err := Import(db, bytes.NewReader(snap), "dev", head.Hash()); err == nil {
+		t.Fatalf("snapshot with incomplete state imported")
+	}
+	if hash := rawdb.ReadHeadBlockHash(db); hash != genesis.Hash() {
+		t.Fatalf("head block changed by failed import")
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 3); hash != (common.Hash{}) {
+		t.Fatalf("canonical hash written by failed import")
+	}
+}
@@ -126,6 +126,9 @@ type (
 	addLogChange struct {
 		txhash common.Hash
 	}
+	addTokenTransferChange struct {
+		txhash common.Hash
+	}
 	addPreimageChange struct {
 		hash common.Hash
 	}
@@ -230,6 +233,19 @@ func (ch addLogChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch addTokenTransferChange) revert(s *StateDB) {
+	transfers := s.tokenTransfers[ch.txhash]
+	if len(transfers) == 1 {
+		delete(s.tokenTransfers, ch.txhash)
+	} else {
+		s.tokenTransfers[ch.txhash] = transfers[:len(transfers)-1]
+	}
+}
+
+func (ch addTokenTransferChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch addPreimageChange) revert(s *StateDB) {
 	delete(s.preimages, ch.hash)
 }
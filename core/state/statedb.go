@@ -18,6 +18,7 @@
 package state
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/big"
 	"sort"
@@ -86,6 +87,8 @@ type StateDB struct {
 	logs         map[common.Hash][]*types.Log
 	logSize      uint
 
+	tokenTransfers map[common.Hash][]*types.TokenTransfer
+
 	preimages map[common.Hash][]byte
 
 	// Journal of state modifications. This is the backbone of
@@ -180,6 +183,7 @@ func NewGenesis(root common.Hash, db Database) (*StateDB, error) {
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
+		tokenTransfers:    make(map[common.Hash][]*types.TokenTransfer),
 		preimages:         make(map[common.Hash][]byte),
 		journal:           newJournal(),
 	}, nil
@@ -200,6 +204,7 @@ func New(root common.Hash, db Database, number uint64) (*StateDB, error) {
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
+		tokenTransfers:    make(map[common.Hash][]*types.TokenTransfer),
 		preimages:         make(map[common.Hash][]byte),
 		journal:           newJournal(),
 		number:            number + 1,
@@ -313,6 +318,124 @@ func (self *StateDB) SetTokenRate(contractAddr common.Address, coinName string,
 	return false
 }
 
+func tokenInfoKey(coinName string, index int) common.Hash {
+	bytes, _ := rlp.EncodeToBytes([]interface{}{"TokenInfo", strings.ToUpper(coinName), uint64(index)})
+	return crypto.Keccak256Hash(bytes)
+}
+
+// setChunks stores data in 32 byte chunks at the slots following first and
+// clears the chunks left over from a previous value of length oldLen.
+func (self *StateDB) setChunks(stateObject *stateObject, key func(int) common.Hash, first int, data []byte, oldLen int) {
+	chunks := (len(data) + 31) / 32
+	for i := 0; i < chunks; i++ {
+		var chunk common.Hash
+		copy(chunk[:], data[i*32:])
+		stateObject.SetState(self.db, key(first+i), chunk)
+	}
+	for i := chunks; i < (oldLen+31)/32; i++ {
+		stateObject.SetState(self.db, key(first+i), common.Hash{})
+	}
+}
+
+// getChunks loads length bytes stored by setChunks.
+func (self *StateDB) getChunks(stateObject *stateObject, key func(int) common.Hash, first int, length int) []byte {
+	data := make([]byte, 0, length+31)
+	for i := 0; len(data) < length; i++ {
+		chunk := stateObject.GetState(self.db, key(first+i))
+		data = append(data, chunk[:]...)
+	}
+	return data[:length]
+}
+
+// SetTokenInfo records the decimals and description of a token, only the
+// contract which registered the token is allowed to do so. The first slot
+// holds a set marker, the description length and the decimals, the following
+// slots hold the description in 32 byte chunks.
+func (self *StateDB) SetTokenInfo(contractAddr common.Address, coinName string, decimals uint8, description string) bool {
+	if self.GetContrctAddressByToken(coinName) != contractAddr {
+		return false
+	}
+	stateObject := self.GetOrNewStateObject(EmptyAddress)
+	if stateObject == nil {
+		return false
+	}
+	key := func(i int) common.Hash { return tokenInfoKey(coinName, i) }
+	head := stateObject.GetState(self.db, key(0))
+	oldLen := int(binary.BigEndian.Uint16(head[28:30]))
+
+	desc := []byte(description)
+	head = common.Hash{}
+	head[27] = 1
+	binary.BigEndian.PutUint16(head[28:30], uint16(len(desc)))
+	head[31] = decimals
+	stateObject.SetState(self.db, key(0), head)
+	self.setChunks(stateObject, key, 1, desc, oldLen)
+	return true
+}
+
+// GetTokenInfo returns the decimals and description recorded for a token.
+func (self *StateDB) GetTokenInfo(coinName string) (decimals uint8, description string, ok bool) {
+	stateObject := self.getStateObject(EmptyAddress)
+	if stateObject == nil {
+		return
+	}
+	key := func(i int) common.Hash { return tokenInfoKey(coinName, i) }
+	head := stateObject.GetState(self.db, key(0))
+	if head[27] == 0 {
+		return
+	}
+	length := int(binary.BigEndian.Uint16(head[28:30]))
+	return head[31], string(self.getChunks(stateObject, key, 1, length)), true
+}
+
+func ticketMetaKey(categoryName string, value common.Hash, index int) common.Hash {
+	bytes, _ := rlp.EncodeToBytes([]interface{}{"TicketMeta", strings.ToUpper(categoryName), value, uint64(index)})
+	return crypto.Keccak256Hash(bytes)
+}
+
+// SetTicketMetadata binds a metadata URI and content hash to a ticket, a
+// zero value binds the default of the whole category. Only the contract
+// which registered the category is allowed to do so. The first slot holds a
+// set marker and the URI length, the second the content hash and the
+// following slots the URI in 32 byte chunks.
+func (self *StateDB) SetTicketMetadata(contractAddr common.Address, categoryName string, value common.Hash, uri string, contentHash common.Hash) bool {
+	if self.GetContrctAddressByTicket(categoryName) != contractAddr {
+		return false
+	}
+	stateObject := self.GetOrNewStateObject(EmptyAddress)
+	if stateObject == nil {
+		return false
+	}
+	key := func(i int) common.Hash { return ticketMetaKey(categoryName, value, i) }
+	head := stateObject.GetState(self.db, key(0))
+	oldLen := int(binary.BigEndian.Uint16(head[30:32]))
+
+	data := []byte(uri)
+	head = common.Hash{}
+	head[29] = 1
+	binary.BigEndian.PutUint16(head[30:32], uint16(len(data)))
+	stateObject.SetState(self.db, key(0), head)
+	stateObject.SetState(self.db, key(1), contentHash)
+	self.setChunks(stateObject, key, 2, data, oldLen)
+	return true
+}
+
+// GetTicketMetadata returns the metadata URI and content hash bound to a
+// ticket, a zero value returns the default of the category.
+func (self *StateDB) GetTicketMetadata(categoryName string, value common.Hash) (uri string, contentHash common.Hash, ok bool) {
+	stateObject := self.getStateObject(EmptyAddress)
+	if stateObject == nil {
+		return
+	}
+	key := func(i int) common.Hash { return ticketMetaKey(categoryName, value, i) }
+	head := stateObject.GetState(self.db, key(0))
+	if head[29] == 0 {
+		return
+	}
+	length := int(binary.BigEndian.Uint16(head[30:32]))
+	return string(self.getChunks(stateObject, key, 2, length)), stateObject.GetState(self.db, key(1)), true
+}
+
 //register
 func (self *StateDB) RegisterToken(contractAddr common.Address, coinName string) bool {
 	return self.registerAddressByState("Token", contractAddr, strings.ToUpper(coinName))
@@ -394,6 +517,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.txIndex = 0
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
+	self.tokenTransfers = make(map[common.Hash][]*types.TokenTransfer)
 	self.preimages = make(map[common.Hash][]byte)
 	self.clearJournalAndRefund()
 	return nil
@@ -414,6 +538,19 @@ func (self *StateDB) GetLogs(hash common.Hash) []*types.Log {
 	return self.logs[hash]
 }
 
+// AddTokenTransfer records a token issuance or send of a contract in the
+// current transaction, dropped again if the call is reverted.
+func (self *StateDB) AddTokenTransfer(transfer *types.TokenTransfer) {
+	self.journal.append(addTokenTransferChange{txhash: self.thash})
+	self.tokenTransfers[self.thash] = append(self.tokenTransfers[self.thash], transfer)
+}
+
+// GetTokenTransfers returns the token issuances and sends of the contracts
+// called by a transaction.
+func (self *StateDB) GetTokenTransfers(hash common.Hash) []*types.TokenTransfer {
+	return self.tokenTransfers[hash]
+}
+
 func (self *StateDB) Logs() []*types.Log {
 	var logs []*types.Log
 	for _, lgs := range self.logs {
@@ -779,6 +916,7 @@ func (self *StateDB) Copy() *StateDB {
 		refund:            self.refund,
 		logs:              make(map[common.Hash][]*types.Log, len(self.logs)),
 		logSize:           self.logSize,
+		tokenTransfers:    make(map[common.Hash][]*types.TokenTransfer, len(self.tokenTransfers)),
 		preimages:         make(map[common.Hash][]byte),
 		journal:           newJournal(),
 	}
@@ -807,6 +945,10 @@ func (self *StateDB) Copy() *StateDB {
 		state.logs[hash] = make([]*types.Log, len(logs))
 		copy(state.logs[hash], logs)
 	}
+	for hash, transfers := range self.tokenTransfers {
+		state.tokenTransfers[hash] = make([]*types.TokenTransfer, len(transfers))
+		copy(state.tokenTransfers[hash], transfers)
+	}
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
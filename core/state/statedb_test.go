@@ -428,3 +428,78 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// TestTokenInfo tests that token metadata can only be recorded by the issuer
+// and that shorter descriptions replace longer ones.
+func TestTokenInfo(t *testing.T) {
+	sdb, _ := New(common.Hash{}, NewDatabase(serodb.NewMemDatabase()), 0)
+	issuer := common.BytesToAddress([]byte{1})
+	other := common.BytesToAddress([]byte{2})
+	if !sdb.RegisterToken(issuer, "TEST") {
+		t.Fatal("failed to register token")
+	}
+	if _, _, ok := sdb.GetTokenInfo("TEST"); ok {
+		t.Fatal("token info present before being recorded")
+	}
+	if sdb.SetTokenInfo(other, "TEST", 8, "stolen") {
+		t.Fatal("token info recorded by other contract")
+	}
+	long := strings.Repeat("long description ", 5)
+	if !sdb.SetTokenInfo(issuer, "test", 8, long) {
+		t.Fatal("failed to record token info")
+	}
+	if decimals, desc, ok := sdb.GetTokenInfo("TEST"); !ok || decimals != 8 || desc != long {
+		t.Fatalf("token info mismatch: have (%d, %q, %v), want (8, %q, true)", decimals, desc, ok, long)
+	}
+	if !sdb.SetTokenInfo(issuer, "TEST", 0, "short") {
+		t.Fatal("failed to update token info")
+	}
+	if decimals, desc, ok := sdb.GetTokenInfo("TEST"); !ok || decimals != 0 || desc != "short" {
+		t.Fatalf("token info mismatch: have (%d, %q, %v), want (0, \"short\", true)", decimals, desc, ok)
+	}
+}
+
+func TestTicketMetadata(t *testing.T) {
+	sdb, _ := New(common.Hash{}, NewDatabase(serodb.NewMemDatabase()), 0)
+	issuer := common.BytesToAddress([]byte{1})
+	other := common.BytesToAddress([]byte{2})
+	value := common.BytesToHash([]byte{3})
+	hash := common.BytesToHash([]byte{4})
+	if !sdb.RegisterTicket(issuer, "ART") {
+		t.Fatal("failed to register ticket")
+	}
+	if sdb.SetTicketMetadata(other, "ART", value, "ipfs://stolen", hash) {
+		t.Fatal("ticket metadata bound by other contract")
+	}
+	uri := "ipfs://" + strings.Repeat("Qm", 40)
+	if !sdb.SetTicketMetadata(issuer, "art", value, uri, hash) {
+		t.Fatal("failed to bind ticket metadata")
+	}
+	if have, haveHash, ok := sdb.GetTicketMetadata("ART", value); !ok || have != uri || haveHash != hash {
+		t.Fatalf("ticket metadata mismatch: have (%q, %x, %v), want (%q, %x, true)", have, haveHash, ok, uri, hash)
+	}
+	if _, _, ok := sdb.GetTicketMetadata("ART", common.Hash{}); ok {
+		t.Fatal("category default present before being bound")
+	}
+}
+
+// TestTokenTransferRevert tests that the token transfers of reverted calls are
+// dropped from the transaction.
+func TestTokenTransferRevert(t *testing.T) {
+	sdb, _ := New(common.Hash{}, NewDatabase(serodb.NewMemDatabase()), 0)
+	thash := common.BytesToHash([]byte{1})
+	sdb.Prepare(thash, common.Hash{}, 0)
+
+	issued := &types.TokenTransfer{Contract: common.BytesToAddress([]byte{2}), Currency: "TEST", Value: big.NewInt(1)}
+	sdb.AddTokenTransfer(issued)
+	snapshot := sdb.Snapshot()
+	sdb.AddTokenTransfer(&types.TokenTransfer{Contract: common.BytesToAddress([]byte{2}), Currency: "TEST", Value: big.NewInt(2)})
+	sdb.RevertToSnapshot(snapshot)
+
+	if transfers := sdb.GetTokenTransfers(thash); len(transfers) != 1 || transfers[0] != issued {
+		t.Fatalf("token transfers mismatch: have %v, want [%v]", transfers, issued)
+	}
+	if transfers := sdb.Copy().GetTokenTransfers(thash); len(transfers) != 1 {
+		t.Fatalf("copied token transfers mismatch: have %d, want 1", len(transfers))
+	}
+}
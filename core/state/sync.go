@@ -22,14 +22,20 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/trie"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
 )
 
 // NewStateSync create a new state trie download scheduler.
 func NewStateSync(root common.Hash, database trie.DatabaseReader) *trie.Sync {
 	var syncer *trie.Sync
 	callback := func(leaf []byte, parent common.Hash) error {
+		// The zstate keeps its outs, nils, packages and out tree directly
+		// in the account trie, those leaves have no subtries to fetch
 		var obj Account
 		if err := rlp.Decode(bytes.NewReader(leaf), &obj); err != nil {
+			if zstate.IsTrieRecord(leaf) {
+				return nil
+			}
 			return err
 		}
 		syncer.AddSubTrie(obj.Root, 64, parent, nil)
@@ -69,7 +69,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
 		receipt, gas, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
 		if err != nil {
-			return nil, nil, 0, err
+			return nil, nil, 0, &InvalidTxError{Index: i, Hash: tx.Hash(), Err: err}
 		}
 		gasReward += new(big.Int).Mul(new(big.Int).SetUint64(gas), tx.GasPrice()).Uint64()
 		receipts = append(receipts, receipt)
@@ -86,6 +86,19 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error) {
+	if config.IsExpiry(header.Number) {
+		if tx.Ehash() != tx.GetZZSTX().Ehash {
+			return nil, 0, ErrEhashMismatch
+		}
+		if tx.Expired(header.Number.Uint64()) {
+			return nil, 0, ErrTxExpired
+		}
+	} else if tx.HasExpiry() {
+		return nil, 0, ErrExpiryNotActive
+	}
+	if !config.IsPkgTerms(header.Number) && tx.GetZZSTX().Desc_Pkg.HasTerms() {
+		return nil, 0, ErrPkgTermsNotActive
+	}
 	msg, err := tx.AsMessage()
 	if err != nil {
 		return nil, 0, err
@@ -127,5 +140,6 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Set the receipt logs and create a bloom for filtering
 	receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.TokenTransfers = statedb.GetTokenTransfers(tx.Hash())
 	return receipt, gas, err
 }
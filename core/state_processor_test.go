@@ -0,0 +1,50 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+)
+
+// Tests that a block may not include a transaction the expiry of which was
+// changed after the sender signed it.
+func TestApplyTamperedExpiry(t *testing.T) {
+	signed := types.NewTransaction(big.NewInt(1), 21000, nil).WithValidUntil(100)
+	signed, err := signed.WithEncrypt(&stx.T{Ehash: signed.Ehash()})
+	if err != nil {
+		t.Fatalf("failed to encrypt transaction: %v", err)
+	}
+	tampered := signed.WithValidUntil(200)
+
+	header := &types.Header{Number: big.NewInt(150), GasLimit: 1000000}
+	gp := new(GasPool).AddGas(header.GasLimit)
+	_, _, err = ApplyTransaction(params.TestChainConfig, nil, nil, gp, nil, header, tampered, new(uint64), vm.Config{})
+	if err != ErrEhashMismatch {
+		t.Fatalf("tampered expiry error mismatch: have %v, want %v", err, ErrEhashMismatch)
+	}
+	// The signed expiry still applies
+	_, _, err = ApplyTransaction(params.TestChainConfig, nil, nil, gp, nil, header, signed, new(uint64), vm.Config{})
+	if err != ErrTxExpired {
+		t.Fatalf("signed expiry error mismatch: have %v, want %v", err, ErrTxExpired)
+	}
+}
@@ -45,6 +45,7 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+	refund     uint64 // gas refunded after the execution
 }
 
 // Message represents a message sent to a contract.
@@ -120,6 +121,15 @@ func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, bool,
 	return NewStateTransition(evm, msg, gp).TransitionDb()
 }
 
+// ApplyMessageRefund applies the message like ApplyMessage, also returning the
+// gas refunded, so the gas the execution consumed before the refund is the gas
+// used plus the refund.
+func ApplyMessageRefund(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, uint64, bool, error) {
+	st := NewStateTransition(evm, msg, gp)
+	ret, used, failed, err := st.TransitionDb()
+	return ret, used, st.refund, failed, err
+}
+
 // to returns the recipient of the message.
 func (st *StateTransition) to() common.Address {
 	if st.msg == nil || st.msg.To() == nil /* contract creation */ {
@@ -234,6 +244,7 @@ func (st *StateTransition) refundGas() {
 		refund = st.state.GetRefund()
 	}
 	st.gas += refund
+	st.refund = refund
 
 	// Return SERO for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
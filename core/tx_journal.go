@@ -71,7 +71,15 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 	journal.writer = new(devNull)
 	defer func() { journal.writer = nil }()
 
-	// Inject all transactions from the journal into the pool
+	total, dropped, err := loadTxs(input, add)
+	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+
+	return err
+}
+
+// loadTxs parses an RLP stream of transactions and adds them in batches,
+// returning the numbers of the parsed and the rejected transactions.
+func loadTxs(input io.Reader, add func([]*types.Transaction) []error) (int, int, error) {
 	stream := rlp.NewStream(input, 0)
 	total, dropped := 0, 0
 
@@ -93,7 +101,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 	for {
 		// Parse the next transaction and terminate on error
 		tx := new(types.Transaction)
-		if err = stream.Decode(tx); err != nil {
+		if err := stream.Decode(tx); err != nil {
 			if err != io.EOF {
 				failure = err
 			}
@@ -110,9 +118,28 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+	return total, dropped, failure
+}
+
+// Dump writes the pending and queued transactions of the pool to w as an RLP
+// stream, returning their number.
+func (pool *TxPool) Dump(w io.Writer) (int, error) {
+	pending, queued := pool.Content()
+	for _, tx := range append(pending, queued...) {
+		if err := rlp.Encode(w, tx); err != nil {
+			return 0, err
+		}
+	}
+	return len(pending) + len(queued), nil
+}
 
-	return failure
+// Restore adds the transactions of a stream written by Dump to the pool as
+// local ones, returning the numbers of the restored and of the dropped
+// transactions, such as the ones included in blocks since.
+func (pool *TxPool) Restore(r io.Reader) (int, int, error) {
+	total, dropped, err := loadTxs(r, pool.AddLocals)
+	log.Info("Restored transaction pool", "transactions", total, "dropped", dropped)
+	return total - dropped, dropped, err
 }
 
 // insert adds the specified transaction to the local disk journal.
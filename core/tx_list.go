@@ -22,7 +22,6 @@ import (
 
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core/types"
-	"github.com/sero-cash/go-sero/log"
 )
 
 // priceHeap is a heap.Interface implementation over transactions for retrieving
@@ -139,30 +138,32 @@ func (l *txPricedList) Remove(tx *types.Transaction) bool {
 	return false
 }
 
-// Underpriced checks whether a transaction is cheaper than (or as cheap as) the
-// lowest priced transaction currently being tracked.
-func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) bool {
-	// Local transactions cannot be underpriced
-	if local.containsTx(tx) {
-		return false
-	}
-	// Discard stale priced points if found at the heap start
+// Cheapest returns the cheapest transaction evictable allows, the first one
+// older reports for transactions of the same price, nil if there is none.
+// The transactions evictable refuses are left in the list.
+func (l *txPricedList) Cheapest(evictable func(tx *types.Transaction) bool, older func(a, b *types.Transaction) bool) *types.Transaction {
+	var cheapest *types.Transaction
+	save := make(types.Transactions, 0, 64)
 	for len(*l.items) > 0 {
 		head := []*types.Transaction(*l.items)[0]
+		if cheapest != nil && head.GasPrice().Cmp(cheapest.GasPrice()) > 0 {
+			break
+		}
+		heap.Pop(l.items)
+		// Discard stale priced points if found at the heap start
 		if l.all.Get(head.Hash()) == nil {
 			l.stales--
-			heap.Pop(l.items)
 			continue
 		}
-		break
+		save = append(save, head)
+		if evictable(head) && (cheapest == nil || older(head, cheapest)) {
+			cheapest = head
+		}
 	}
-	// Check if the transaction is underpriced or not
-	if len(*l.items) == 0 {
-		log.Error("Pricing query for empty pool") // This cannot happen, print to catch programming errors
-		return false
+	for _, tx := range save {
+		heap.Push(l.items, tx)
 	}
-	cheapest := []*types.Transaction(*l.items)[0]
-	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+	return cheapest
 }
 
 // Discard finds a number of most underpriced transactions, removes them from the
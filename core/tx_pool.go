@@ -17,6 +17,7 @@
 package core
 
 import (
+	"container/list"
 	"errors"
 	"fmt"
 	"math"
@@ -25,6 +26,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/sero-cash/go-czero-import/keys"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/core/types"
@@ -47,6 +49,10 @@ var (
 	// configured for the transaction pool.
 	ErrUnderpriced = errors.New("transaction underpriced")
 
+	// ErrReplaceUnderpriced is returned if a transaction spends outs spent by
+	// pooled transactions without raising the gas price enough to replace them.
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
+
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
@@ -61,6 +67,10 @@ var (
 	ErrOversizedData = errors.New("oversized data")
 
 	ErrCurrencyError = errors.New("currency error")
+
+	// ErrTxPoolFull is returned if a local transaction finds the pool full of
+	// local transactions, none of which may be evicted.
+	ErrTxPoolFull = errors.New("transaction pool is full of local transactions")
 )
 
 var (
@@ -72,6 +82,10 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	replacedTxCounter    = metrics.NewRegisteredCounter("txpool/replaced", nil)
+	evictedTxCounter     = metrics.NewRegisteredCounter("txpool/evicted", nil)
+	pendingGauge         = metrics.NewRegisteredGauge("txpool/pending", nil)
+	queuedGauge          = metrics.NewRegisteredGauge("txpool/queued", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -83,6 +97,13 @@ const (
 	TxStatusPending
 )
 
+// Eviction policies choosing the remote transaction dropped to make room for
+// a new one once the pool is full.
+const (
+	EvictByPrice = "price" // the cheapest transaction, the oldest of the same price
+	EvictByAge   = "age"   // the transaction pooled first
+)
+
 // blockChain provides the state of blockchain and current gas limit to do
 // some pre checks in tx pool and event subscribers.
 type blockChain interface {
@@ -98,6 +119,7 @@ type TxPoolConfig struct {
 	NoLocals bool // Whether local transaction handling should be disabled
 
 	PriceLimit uint64 // Minimum gas priced to enforce for acceptance into the pool
+	PriceBump  uint64 // Minimum price bump percentage to replace a pooled transaction spending the same outs
 
 	AccountSlots uint64 // Number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
@@ -105,6 +127,9 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	LocalSlots uint64 // Slots of the pool only local transactions may take
+	Eviction   string // Policy choosing the remote transaction evicted from a full pool
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -112,12 +137,16 @@ type TxPoolConfig struct {
 var DefaultTxPoolConfig = TxPoolConfig{
 
 	PriceLimit:   1,
+	PriceBump:    10,
 	AccountSlots: 16,
 	GlobalSlots:  4096,
 	AccountQueue: 64,
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	LocalSlots: 512,
+	Eviction:   EvictByPrice,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -129,6 +158,18 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool priced limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
 	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
+		conf.PriceBump = DefaultTxPoolConfig.PriceBump
+	}
+	if capacity := conf.GlobalSlots + conf.GlobalQueue; conf.LocalSlots > capacity {
+		log.Warn("Sanitizing invalid txpool local slots", "provided", conf.LocalSlots, "updated", capacity)
+		conf.LocalSlots = capacity
+	}
+	if conf.Eviction != EvictByPrice && conf.Eviction != EvictByAge {
+		log.Warn("Sanitizing invalid txpool eviction policy", "provided", conf.Eviction, "updated", DefaultTxPoolConfig.Eviction)
+		conf.Eviction = DefaultTxPoolConfig.Eviction
+	}
 	return conf
 }
 
@@ -155,7 +196,11 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals *accountSet // Set of local transaction to exempt from eviction rules
+	locals   map[common.Hash]struct{}      // Local transactions, exempt from eviction by remote ones
+	remotes  *list.List                    // Arrivals of the remote transactions in the order they were pooled
+	arrivals map[common.Hash]*list.Element // Elements of the remote transactions in remotes
+	arrived  uint64                        // Number of remote transactions pooled so far
+	spent    map[keys.Uint256]common.Hash  // Pooled transactions by the roots and traces of the outs they spend
 	//journal *txJournal  // Journal of local transaction to back up to disk
 
 	all        *txLookup     // All transactions to allow lookups
@@ -185,7 +230,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
-	pool.locals = newAccountSet()
+	pool.locals = make(map[common.Hash]struct{})
+	pool.remotes = list.New()
+	pool.arrivals = make(map[common.Hash]*list.Element)
+	pool.spent = make(map[keys.Uint256]common.Hash)
 	pool.priced = newTxPricedList(pool.all)
 	pool.newQueue = newTxPricedList(newTxLookup())
 	pool.newPending = newTxPricedList(newTxLookup())
@@ -238,6 +286,9 @@ func (pool *TxPool) loop() {
 
 			// Handle stats reporting ticks
 		case <-report.C:
+			pending, queued := pool.Stats()
+			pendingGauge.Update(int64(pending))
+			queuedGauge.Update(int64(queued))
 			log.Debug("Transaction pool status report", "queued", pool.all.Count())
 		case <-evict.C:
 			pool.mu.Lock()
@@ -250,6 +301,7 @@ func (pool *TxPool) loop() {
 			for _, tx := range drop {
 				pool.removeTx(tx.Hash())
 			}
+			pool.forgetStale()
 			pool.mu.Unlock()
 		}
 	}
@@ -346,6 +398,14 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		pool.removeTx(tx.Hash())
 		log.Debug("confirm removeTx tx", "hash", tx.Hash())
 	}
+	// Drop the transactions that can not be included in the next block anymore
+	next := newHead.Number.Uint64() + 1
+	for _, tx := range append(pool.newPending.Flatten(), pool.newQueue.Flatten()...) {
+		if tx.Expired(next) {
+			pool.removeTx(tx.Hash())
+			log.Debug("Dropped expired transaction", "hash", tx.Hash(), "validUntil", tx.ValidUntil())
+		}
+	}
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	if len(reinject) > 0 {
@@ -385,6 +445,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.priced.Discard(pool.gasPrice, 0)
 	pool.newQueue.Discard(pool.gasPrice, 0)
 	pool.newPending.Discard(pool.gasPrice, 0)
+	pool.forgetStale()
 
 	log.Info("Transaction pool priced threshold updated", "priced", pool.gasPrice)
 }
@@ -463,6 +524,22 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (e error) {
 		return ErrVerifyError
 	}
 
+	// Ensure the expiry was not altered and has not passed yet
+	if tx.Ehash() != tx.GetZZSTX().Ehash {
+		return ErrEhashMismatch
+	}
+	next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), big.NewInt(1))
+	if !pool.chainconfig.IsExpiry(next) {
+		if tx.HasExpiry() {
+			return ErrExpiryNotActive
+		}
+	} else if tx.Expired(next.Uint64()) {
+		return ErrTxExpired
+	}
+	if !pool.chainconfig.IsPkgTerms(next) && tx.GetZZSTX().Desc_Pkg.HasTerms() {
+		return ErrPkgTermsNotActive
+	}
+
 	// Drop non-local transactions under our own minimal accepted gas priced
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
@@ -499,19 +576,27 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
-	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
-		// If the new transaction is underpriced, don't accept it
-		if !local && pool.newQueue.Underpriced(tx, pool.locals) {
-			log.Trace("Discarding underpriced transaction", "hash", hash, "priced", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			return false, ErrUnderpriced
+	return pool.insert(tx, local)
+}
+
+// insert pools a valid transaction, replacing the pooled ones spending the
+// same outs and evicting a remote transaction if the pool is full.
+func (pool *TxPool) insert(tx *types.Transaction, local bool) (bool, error) {
+	hash := tx.Hash()
+	// If the transaction spends the outs of pooled ones, it must outbid them all
+	conflicts := pool.conflicts(tx)
+	for _, old := range conflicts {
+		threshold := new(big.Int).Mul(old.GasPrice(), big.NewInt(int64(100+pool.config.PriceBump)))
+		threshold.Div(threshold, big.NewInt(100))
+		if tx.GasPrice().Cmp(threshold) < 0 {
+			log.Trace("Discarding underpriced replacement transaction", "hash", hash, "replaced", old.Hash(), "priced", tx.GasPrice())
+			return false, ErrReplaceUnderpriced
 		}
-		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(pool.gasPrice, pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1))
-		for _, tx := range drop {
-			pool.removeTx(tx.Hash())
-			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "priced", tx.GasPrice())
+	}
+	// If the transaction pool is full, make room evicting a remote transaction
+	if pool.all.Get(hash) == nil {
+		if err := pool.makeRoom(tx, local, conflicts); err != nil {
+			return false, err
 		}
 	}
 
@@ -519,10 +604,163 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if pool.all.Get(hash) == nil {
+		return flag, nil
+	}
+	// The replaced transactions leave once the replacement is pooled
+	for _, old := range conflicts {
+		pool.removeTx(old.Hash())
+		log.Trace("Replaced pooled transaction", "hash", old.Hash(), "replacement", hash)
+		replacedTxCounter.Inc(1)
+	}
+	for key := range SpentKeys(tx) {
+		pool.spent[key] = hash
+	}
+	if local {
+		pool.locals[hash] = struct{}{}
+		pool.forgetArrival(hash)
+	} else if _, ok := pool.locals[hash]; !ok {
+		if _, ok := pool.arrivals[hash]; !ok {
+			pool.arrived++
+			pool.arrivals[hash] = pool.remotes.PushBack(&arrival{hash: hash, seq: pool.arrived})
+		}
+	}
 	log.Trace("Pooled new future transaction", "hash", hash, "from", tx.From(), "to", tx.To())
 	return flag, nil
 }
 
+// makeRoom evicts a remote transaction if the pool has no free slot for tx.
+// Local transactions may take any slot and evict any remote transaction, while
+// remote ones are kept out of the slots reserved for the local ones and only
+// evict the remote transaction chosen by the eviction policy. The slots of the
+// transactions tx replaces are free.
+func (pool *TxPool) makeRoom(tx *types.Transaction, local bool, replaced types.Transactions) error {
+	capacity := pool.config.GlobalSlots + pool.config.GlobalQueue
+	total, locals := uint64(pool.all.Count()), uint64(len(pool.locals))
+	for _, old := range replaced {
+		total--
+		if _, ok := pool.locals[old.Hash()]; ok {
+			locals--
+		}
+	}
+	if local {
+		if total < capacity {
+			return nil
+		}
+		if locals >= total {
+			return ErrTxPoolFull
+		}
+	} else if total < capacity && total-locals < capacity-pool.config.LocalSlots {
+		return nil
+	}
+	victim := pool.evictable()
+	if victim == nil {
+		log.Trace("Discarding remote transaction, no slot left", "hash", tx.Hash())
+		underpricedTxCounter.Inc(1)
+		return ErrUnderpriced
+	}
+	// If the new remote transaction is underpriced, don't accept it
+	if !local && pool.config.Eviction == EvictByPrice && victim.GasPrice().Cmp(tx.GasPrice()) >= 0 {
+		log.Trace("Discarding underpriced transaction", "hash", tx.Hash(), "priced", tx.GasPrice())
+		underpricedTxCounter.Inc(1)
+		return ErrUnderpriced
+	}
+	pool.removeTx(victim.Hash())
+	log.Trace("Evicted remote transaction", "hash", victim.Hash(), "priced", victim.GasPrice(), "policy", pool.config.Eviction)
+	evictedTxCounter.Inc(1)
+	return nil
+}
+
+// evictable returns the remote transaction evicted first under the eviction
+// policy, nil if all the pooled transactions are local.
+func (pool *TxPool) evictable() *types.Transaction {
+	if pool.config.Eviction == EvictByAge {
+		for elem := pool.remotes.Front(); elem != nil; elem = pool.remotes.Front() {
+			hash := elem.Value.(*arrival).hash
+			if tx := pool.all.Get(hash); tx != nil {
+				return tx
+			}
+			pool.forgetArrival(hash)
+		}
+		return nil
+	}
+	return pool.priced.Cheapest(func(tx *types.Transaction) bool {
+		_, ok := pool.arrivals[tx.Hash()]
+		return ok
+	}, func(a, b *types.Transaction) bool {
+		return pool.arrivals[a.Hash()].Value.(*arrival).seq < pool.arrivals[b.Hash()].Value.(*arrival).seq
+	})
+}
+
+// arrival is the rank of a remote transaction in the order they were pooled.
+type arrival struct {
+	hash common.Hash
+	seq  uint64
+}
+
+// forgetArrival drops a transaction from the arrival order of the remote ones.
+func (pool *TxPool) forgetArrival(hash common.Hash) {
+	if elem, ok := pool.arrivals[hash]; ok {
+		pool.remotes.Remove(elem)
+		delete(pool.arrivals, hash)
+	}
+}
+
+// forgetStale drops the local marks, arrival times and spent outs of the
+// transactions which left the pool without being removed by removeTx.
+func (pool *TxPool) forgetStale() {
+	for hash := range pool.arrivals {
+		if pool.all.Get(hash) == nil {
+			pool.forgetArrival(hash)
+		}
+	}
+	for hash := range pool.locals {
+		if pool.all.Get(hash) == nil {
+			delete(pool.locals, hash)
+		}
+	}
+	for key, hash := range pool.spent {
+		if pool.all.Get(hash) == nil {
+			delete(pool.spent, key)
+		}
+	}
+}
+
+// conflicts returns the pooled transactions other than tx spending any of the
+// outs it spends, by root for open inputs and by trace for private ones.
+func (pool *TxPool) conflicts(tx *types.Transaction) types.Transactions {
+	var conflicts types.Transactions
+	seen := make(map[common.Hash]bool)
+	for key := range SpentKeys(tx) {
+		hash, ok := pool.spent[key]
+		if !ok || hash == tx.Hash() || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		if pooled := pool.all.Get(hash); pooled != nil {
+			conflicts = append(conflicts, pooled)
+		}
+	}
+	return conflicts
+}
+
+// SpentKeys returns the roots of the open inputs and the traces of the private
+// inputs of a transaction.
+func SpentKeys(tx *types.Transaction) map[keys.Uint256]bool {
+	stxt := tx.GetZZSTX()
+	if stxt == nil {
+		return nil
+	}
+	spent := make(map[keys.Uint256]bool)
+	for _, in := range stxt.Desc_O.Ins {
+		spent[in.Root] = true
+	}
+	for _, in := range stxt.Desc_Z.Ins {
+		spent[in.Trace] = true
+	}
+	return spent
+}
+
 // Note, this method assumes the pool lock is held!
 func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, error) {
 	// Try to insert the transaction into the future queue
@@ -624,6 +862,12 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	return status
 }
 
+// PriceBump returns the minimum gas price increase in percent for a transaction
+// to replace the pooled ones spending the same outs.
+func (pool *TxPool) PriceBump() uint64 {
+	return pool.config.PriceBump
+}
+
 // Get returns a transaction if it is contained in the pool
 // and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
@@ -640,6 +884,13 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	}
 
 	pool.priced.Remove(tx)
+	delete(pool.locals, hash)
+	pool.forgetArrival(hash)
+	for key := range SpentKeys(tx) {
+		if pool.spent[key] == hash {
+			delete(pool.spent, key)
+		}
+	}
 	//Remove it from the list of known transactions
 	if pool.newQueue.Remove(tx) {
 		return
@@ -687,39 +938,6 @@ func (pool *TxPool) promoteExecutables() {
 	}
 }
 
-// accountSet is simply a set of addresses to check for existence, and a abi
-// capable of deriving addresses from transactions.
-type accountSet struct {
-	accounts map[common.Address]struct{}
-	//abi   types.Signer
-}
-
-// newAccountSet creates a new address set with an associated abi for sender
-// derivations.
-func newAccountSet() *accountSet {
-	return &accountSet{
-		accounts: make(map[common.Address]struct{}),
-		//abi:   abi,
-	}
-}
-
-// contains checks if a given address is contained within the set.
-func (as *accountSet) contains(addr common.Address) bool {
-	_, exist := as.accounts[addr]
-	return exist
-}
-
-// containsTx checks if the sender of a given tx is within the set. If the sender
-// cannot be derived, this method returns false.
-func (as *accountSet) containsTx(tx *types.Transaction) bool {
-	return as.contains(tx.From())
-}
-
-// add inserts a new address into the set to track.
-func (as *accountSet) add(addr common.Address) {
-	as.accounts[addr] = struct{}{}
-}
-
 // txLookup is used internally by TxPool to track transactions while allowing lookup without
 // mutex contention.
 //
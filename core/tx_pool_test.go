@@ -0,0 +1,160 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"container/list"
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+)
+
+// newEvictionPool creates a pool of slots transactions, reserved of which
+// for the local ones, without a chain to validate them against.
+func newEvictionPool(slots uint64, reserved uint64, eviction string) *TxPool {
+	pool := &TxPool{
+		config:   TxPoolConfig{PriceBump: 10, GlobalSlots: slots, LocalSlots: reserved, Eviction: eviction},
+		gasPrice: big.NewInt(1),
+		all:      newTxLookup(),
+		locals:   make(map[common.Hash]struct{}),
+		remotes:  list.New(),
+		arrivals: make(map[common.Hash]*list.Element),
+		spent:    make(map[keys.Uint256]common.Hash),
+	}
+	pool.priced = newTxPricedList(pool.all)
+	pool.newQueue = newTxPricedList(newTxLookup())
+	pool.newPending = newTxPricedList(newTxLookup())
+	return pool
+}
+
+// pricedTransaction creates the transaction id of price spending the open out
+// of root in.
+func pricedTransaction(id byte, price int64, in byte) *types.Transaction {
+	stxt := &stx.T{Ehash: keys.Uint256{id}}
+	stxt.Desc_O.Ins = []stx.In_S{{Root: keys.Uint256{in}}}
+	tx, _ := types.NewTransaction(big.NewInt(price), 21000, nil).WithEncrypt(stxt)
+	return tx
+}
+
+// insertAll pools the transactions, failing the test on the first error.
+func insertAll(t *testing.T, pool *TxPool, local bool, txs ...*types.Transaction) {
+	for _, tx := range txs {
+		if _, err := pool.insert(tx, local); err != nil {
+			t.Fatalf("failed to pool transaction %x: %v", tx.Hash(), err)
+		}
+	}
+}
+
+// checkPooled checks the pool holds exactly the given transactions.
+func checkPooled(t *testing.T, pool *TxPool, txs ...*types.Transaction) {
+	if count := pool.all.Count(); count != len(txs) {
+		t.Errorf("pooled transaction count mismatch: have %d, want %d", count, len(txs))
+	}
+	for _, tx := range txs {
+		if pool.all.Get(tx.Hash()) == nil {
+			t.Errorf("transaction %x not pooled", tx.Hash())
+		}
+	}
+}
+
+func TestEvictByPrice(t *testing.T) {
+	pool := newEvictionPool(3, 0, EvictByPrice)
+	a, b, c := pricedTransaction(1, 2, 1), pricedTransaction(2, 1, 2), pricedTransaction(3, 1, 3)
+	insertAll(t, pool, false, a, b, c)
+
+	// The oldest of the cheapest transactions goes first
+	d := pricedTransaction(4, 3, 4)
+	insertAll(t, pool, false, d)
+	checkPooled(t, pool, a, c, d)
+
+	// A remote transaction not outbidding the cheapest one is refused
+	if _, err := pool.insert(pricedTransaction(5, 1, 5), false); err != ErrUnderpriced {
+		t.Errorf("underpriced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	checkPooled(t, pool, a, c, d)
+}
+
+func TestEvictByAge(t *testing.T) {
+	pool := newEvictionPool(3, 0, EvictByAge)
+	a, b, c := pricedTransaction(1, 1, 1), pricedTransaction(2, 3, 2), pricedTransaction(3, 2, 3)
+	insertAll(t, pool, false, b, a, c)
+
+	// The transaction pooled first goes first, whatever its price
+	d := pricedTransaction(4, 1, 4)
+	insertAll(t, pool, false, d)
+	checkPooled(t, pool, a, c, d)
+
+	e := pricedTransaction(5, 1, 5)
+	insertAll(t, pool, false, e)
+	checkPooled(t, pool, c, d, e)
+}
+
+func TestLocalSlots(t *testing.T) {
+	pool := newEvictionPool(3, 1, EvictByPrice)
+	a, b := pricedTransaction(1, 1, 1), pricedTransaction(2, 2, 2)
+	insertAll(t, pool, false, a, b)
+
+	// Remote transactions are kept out of the reserved slot
+	c := pricedTransaction(3, 3, 3)
+	insertAll(t, pool, false, c)
+	checkPooled(t, pool, b, c)
+
+	// Local ones take it, then evict the remote ones
+	l1, l2 := pricedTransaction(4, 1, 4), pricedTransaction(5, 1, 5)
+	insertAll(t, pool, true, l1, l2)
+	checkPooled(t, pool, c, l1, l2)
+
+	// Remote transactions only evict the remote ones
+	d := pricedTransaction(6, 9, 6)
+	insertAll(t, pool, false, d)
+	checkPooled(t, pool, d, l1, l2)
+
+	l3 := pricedTransaction(7, 1, 7)
+	insertAll(t, pool, true, l3)
+	checkPooled(t, pool, l1, l2, l3)
+	if _, err := pool.insert(pricedTransaction(8, 9, 8), false); err != ErrUnderpriced {
+		t.Errorf("remote transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if _, err := pool.insert(pricedTransaction(9, 9, 9), true); err != ErrTxPoolFull {
+		t.Errorf("local transaction error mismatch: have %v, want %v", err, ErrTxPoolFull)
+	}
+	if len(pool.locals) != 3 {
+		t.Errorf("pooled local count mismatch: have %d, want 3", len(pool.locals))
+	}
+}
+
+func TestReplaceConflicting(t *testing.T) {
+	pool := newEvictionPool(2, 0, EvictByPrice)
+	a, b := pricedTransaction(1, 10, 1), pricedTransaction(2, 10, 2)
+	insertAll(t, pool, false, a, b)
+
+	// A replacement must outbid the transaction spending the same out
+	if _, err := pool.insert(pricedTransaction(3, 10, 1), false); err != ErrReplaceUnderpriced {
+		t.Errorf("replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	// and takes its slot in a full pool without evicting another one
+	c := pricedTransaction(4, 11, 1)
+	insertAll(t, pool, false, c)
+	checkPooled(t, pool, b, c)
+	if pool.spent[keys.Uint256{1}] != c.Hash() {
+		t.Errorf("spent out not indexed to the replacement")
+	}
+}
@@ -84,6 +84,14 @@ type Header struct {
 	Extra       []byte         `json:"extraData"        gencodec:"required"`
 	MixDigest   common.Hash    `json:"mixHash"          gencodec:"required"`
 	Nonce       BlockNonce     `json:"nonce"            gencodec:"required"`
+	Payouts     []Payout       `json:"payouts,omitempty" rlp:"tail"`
+}
+
+// Payout is a share of the block reward paid to a PKr other than the coinbase,
+// which receives what the payouts leave.
+type Payout struct {
+	PKr     common.Address `json:"pkr"`
+	Percent uint64         `json:"percent"`
 }
 
 // field type overrides for gencodec
@@ -112,8 +120,10 @@ func (h *Header) Hash() common.Hash {
 }
 
 // HashNoNonce returns the hash which is used as input for the proof-of-work search.
+// The payouts are only hashed if there are any, keeping the hashes of the
+// headers from before the CoinbaseSplit fork.
 func (h *Header) HashNoNonce() common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		h.ParentHash,
 		h.Coinbase,
 		h.Root,
@@ -126,7 +136,11 @@ func (h *Header) HashNoNonce() common.Hash {
 		h.GasUsed,
 		h.Time,
 		h.Extra,
-	})
+	}
+	if len(h.Payouts) > 0 {
+		fields = append(fields, h.Payouts)
+	}
+	return rlpHash(fields)
 }
 
 func (h *Header) ActualDifficulty() *big.Int {
@@ -145,7 +159,7 @@ func (h *Header) ActualDifficulty() *big.Int {
 // Size returns the approximate memory used by all internal contents. It is used
 // to approximate and limit the memory consumption of various caches.
 func (h *Header) Size() common.StorageSize {
-	return common.StorageSize(unsafe.Sizeof(*h)) + common.StorageSize(len(h.Extra)+(h.Difficulty.BitLen()+h.Number.BitLen()+h.Time.BitLen())/8)
+	return common.StorageSize(unsafe.Sizeof(*h)) + common.StorageSize(len(h.Extra)+len(h.Payouts)*int(unsafe.Sizeof(Payout{}))+(h.Difficulty.BitLen()+h.Number.BitLen()+h.Time.BitLen())/8)
 }
 
 func rlpHash(x interface{}) (h common.Hash) {
@@ -259,6 +273,10 @@ func CopyHeader(h *Header) *Header {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
 	}
+	if len(h.Payouts) > 0 {
+		cpy.Payouts = make([]Payout, len(h.Payouts))
+		copy(cpy.Payouts, h.Payouts)
+	}
 	return &cpy
 }
 
@@ -16,26 +16,34 @@ var _ = (*txdataMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (t txdata) MarshalJSON() ([]byte, error) {
 	type txdata struct {
-		Price    *hexutil.Big   `json:"gasPrice" gencodec:"required"`
-		GasLimit hexutil.Uint64 `json:"gas"      gencodec:"required"`
-		Payload  hexutil.Bytes  `json:"input"    gencodec:"required"`
-		Stxt     *stx.T         `json:"stxt"    gencodec:"required"`
+		Price    *hexutil.Big     `json:"gasPrice" gencodec:"required"`
+		GasLimit hexutil.Uint64   `json:"gas"      gencodec:"required"`
+		Payload  hexutil.Bytes    `json:"input"    gencodec:"required"`
+		Stxt     *stx.T           `json:"stxt"    gencodec:"required"`
+		Expiry   []hexutil.Uint64 `json:"expiry,omitempty" rlp:"tail"`
 	}
 	var enc txdata
 	enc.Price = (*hexutil.Big)(t.Price)
 	enc.GasLimit = hexutil.Uint64(t.GasLimit)
 	enc.Payload = t.Payload
 	enc.Stxt = t.Stxt
+	if t.Expiry != nil {
+		enc.Expiry = make([]hexutil.Uint64, len(t.Expiry))
+		for k, v := range t.Expiry {
+			enc.Expiry[k] = hexutil.Uint64(v)
+		}
+	}
 	return json.Marshal(&enc)
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (t *txdata) UnmarshalJSON(input []byte) error {
 	type txdata struct {
-		Price    *hexutil.Big    `json:"gasPrice" gencodec:"required"`
-		GasLimit *hexutil.Uint64 `json:"gas"      gencodec:"required"`
-		Payload  *hexutil.Bytes  `json:"input"    gencodec:"required"`
-		Stxt     *stx.T          `json:"stxt"    gencodec:"required"`
+		Price    *hexutil.Big     `json:"gasPrice" gencodec:"required"`
+		GasLimit *hexutil.Uint64  `json:"gas"      gencodec:"required"`
+		Payload  *hexutil.Bytes   `json:"input"    gencodec:"required"`
+		Stxt     *stx.T           `json:"stxt"    gencodec:"required"`
+		Expiry   []hexutil.Uint64 `json:"expiry,omitempty" rlp:"tail"`
 	}
 	var dec txdata
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -57,5 +65,11 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'stxt' for txdata")
 	}
 	t.Stxt = dec.Stxt
+	if dec.Expiry != nil {
+		t.Expiry = make([]uint64, len(dec.Expiry))
+		for k, v := range dec.Expiry {
+			t.Expiry[k] = uint64(v)
+		}
+	}
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/big"
 	"unsafe"
 
 	"github.com/sero-cash/go-sero/common"
@@ -55,6 +56,31 @@ type Receipt struct {
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         uint64         `json:"gasUsed" gencodec:"required"`
+
+	// TokenTransfers are the tokens issued and sent by contracts, recorded
+	// when the block was processed. Receipts stored before lack them.
+	TokenTransfers []*TokenTransfer `json:"-"`
+}
+
+// TokenTransfer is a token issuance or a send of a contract. They are handled
+// by the VM itself and leave no log behind.
+type TokenTransfer struct {
+	Contract common.Address          // contract issuing or sending
+	To       *common.ContractAddress // short address sent to, nil for issuances
+	Currency string
+	Value    *big.Int // nil for sends of a ticket only
+	Category string
+	Ticket   common.Hash
+}
+
+// tokenTransferRLP is the storage encoding of a token transfer.
+type tokenTransferRLP struct {
+	Contract common.Address
+	To       []byte
+	Currency string
+	Value    *big.Int
+	Category string
+	Ticket   common.Hash
 }
 
 type receiptMarshaling struct {
@@ -80,6 +106,7 @@ type receiptStorageRLP struct {
 	ContractAddress   common.Address
 	Logs              []*LogForStorage
 	GasUsed           uint64
+	TokenTransfers    []*tokenTransferRLP `rlp:"tail"`
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -168,6 +195,22 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
 	}
+	for _, transfer := range r.TokenTransfers {
+		stored := &tokenTransferRLP{
+			Contract: transfer.Contract,
+			Currency: transfer.Currency,
+			Value:    transfer.Value,
+			Category: transfer.Category,
+			Ticket:   transfer.Ticket,
+		}
+		if transfer.To != nil {
+			stored.To = transfer.To[:]
+		}
+		if stored.Value == nil {
+			stored.Value = new(big.Int)
+		}
+		enc.TokenTransfers = append(enc.TokenTransfers, stored)
+	}
 	return rlp.Encode(w, enc)
 }
 
@@ -189,6 +232,21 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed = dec.TxHash, dec.ContractAddress, dec.GasUsed
+	for _, stored := range dec.TokenTransfers {
+		transfer := &TokenTransfer{
+			Contract: stored.Contract,
+			Category: stored.Category,
+			Ticket:   stored.Ticket,
+		}
+		if len(stored.To) > 0 {
+			to := common.BytesToContractAddress(stored.To)
+			transfer.To = &to
+		}
+		if stored.Currency != "" {
+			transfer.Currency, transfer.Value = stored.Currency, stored.Value
+		}
+		r.TokenTransfers = append(r.TokenTransfers, transfer)
+	}
 	return nil
 }
 
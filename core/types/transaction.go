@@ -53,6 +53,10 @@ type txdata struct {
 	GasLimit uint64   `json:"gas"      gencodec:"required"`
 	Payload  []byte   `json:"input"    gencodec:"required"`
 	Stxt     *zstx.T  `json:"stxt"    gencodec:"required"`
+
+	// Expiry holds the last block number the transaction may be included in,
+	// transactions without it never expire.
+	Expiry []uint64 `json:"expiry,omitempty" rlp:"tail"`
 }
 
 type txdataMarshaling struct {
@@ -60,6 +64,7 @@ type txdataMarshaling struct {
 	GasLimit hexutil.Uint64
 	Payload  hexutil.Bytes
 	Stxt     *zstx.T
+	Expiry   []hexutil.Uint64
 }
 
 func NewTransaction(gasPrice *big.Int, gasLimit uint64, data []byte) *Transaction {
@@ -81,11 +86,15 @@ func NewTransaction(gasPrice *big.Int, gasLimit uint64, data []byte) *Transactio
 }
 
 func (tx Transaction) Ehash() keys.Uint256 {
-	h := rlpHash([]interface{}{
+	fields := []interface{}{
 		&tx.data.Price,
 		tx.data.GasLimit,
 		tx.data.Payload,
-	})
+	}
+	if len(tx.data.Expiry) > 0 {
+		fields = append(fields, tx.data.Expiry)
+	}
+	h := rlpHash(fields)
 	r := keys.Uint256{}
 	copy(r[:], h[:])
 	return r
@@ -150,7 +159,11 @@ func stringToUint512(str string) keys.Uint512 {
 	return ret
 }
 
-func NewCreatePkg(Pkr keys.PKr, currency string, value *big.Int, catg string, tkt *common.Hash, memo string) *ztx.PkgCreate {
+// NewCreatePkg returns the description of a package for Pkr, which its creator
+// may refund expiry blocks after its creation if it is not closed by then. An
+// expiry of zero creates a package which never expires. A package with a
+// hashlock is closed by revealing its SHA-256 preimage.
+func NewCreatePkg(Pkr keys.PKr, currency string, value *big.Int, catg string, tkt *common.Hash, memo string, expiry uint64, hashlock *keys.Uint256) *ztx.PkgCreate {
 	var token *assets.Token
 	var ticket *assets.Ticket
 	if value != nil {
@@ -176,11 +189,16 @@ func NewCreatePkg(Pkr keys.PKr, currency string, value *big.Int, catg string, tk
 		Memo:  stringToUint512(memo),
 	}
 
-	return &ztx.PkgCreate{
-		Id:  keys.RandUint256(),
-		PKr: Pkr,
-		Pkg: pkg,
+	create := &ztx.PkgCreate{
+		Id:     keys.RandUint256(),
+		PKr:    Pkr,
+		Pkg:    pkg,
+		Expiry: expiry,
+	}
+	if hashlock != nil {
+		create.Hashlock = *hashlock
 	}
+	return create
 
 }
 
@@ -231,6 +249,37 @@ func (tx *Transaction) Gas() uint64 {
 }
 func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.Price) }
 
+// ValidUntil returns the last block number the transaction may be included
+// in, 0 if it does not expire.
+func (tx *Transaction) ValidUntil() uint64 {
+	if len(tx.data.Expiry) == 0 {
+		return 0
+	}
+	return tx.data.Expiry[0]
+}
+
+// HasExpiry returns whether the transaction carries a valid until block, which
+// is only accepted from the Expiry fork on.
+func (tx *Transaction) HasExpiry() bool {
+	return len(tx.data.Expiry) > 0
+}
+
+// Expired returns whether the transaction may not be included in the block
+// of the given number anymore.
+func (tx *Transaction) Expired(number uint64) bool {
+	validUntil := tx.ValidUntil()
+	return validUntil != 0 && number > validUntil
+}
+
+// WithValidUntil returns a copy of the transaction expiring after the block
+// of the given number. It changes the ehash, so it must be set before the
+// transaction is encrypted.
+func (tx *Transaction) WithValidUntil(number uint64) *Transaction {
+	cpy := &Transaction{data: tx.data}
+	cpy.data.Expiry = []uint64{number}
+	return cpy
+}
+
 func (tx *Transaction) GetZZSTX() *zstx.T {
 	return tx.data.Stxt
 }
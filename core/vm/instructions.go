@@ -62,8 +62,16 @@ var (
 	topic_setTokenRate  = common.HexToHash("0x6800e94e36131c049eaeb631e4530829b0d3d20d5b637c8015a8dc9cedd70aed")
 	topic_closePkg      = common.HexToHash("0xbbf1aa2159b035802d0a4d44611849d5d4ada0329c81580477d5ec3e82f4f0a6")
 	topic_transferPkg   = common.HexToHash("0xa8b83585a613dcf6c905ad7e0ce34cd07d1283cc72906d1fe78037d49adae455")
+	topic_setTokenInfo  = common.HexToHash("0x46fd4a53c0b580277a17fac60303dfd11ed05687851d0c799db667195516560a")
+	topic_setTicketMeta = common.HexToHash("0x6c9499a2bcfe67dcbf3648e55ec21ad09edb8b1f2789b4fbb768b79fdbba583d")
 )
 
+// maxTokenDescriptionLen is the maximum length of a token description in bytes.
+const maxTokenDescriptionLen = 256
+
+// maxTicketURILen is the maximum length of a ticket metadata URI in bytes.
+const maxTicketURILen = 256
+
 func opAdd(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	x, y := stack.pop(), stack.peek()
 	math.U256(y.Add(x, y))
@@ -996,6 +1004,7 @@ func handleIssueToken(d []byte, evm *EVM, contract *Contract, mem []byte) (bool,
 
 	total := new(big.Int).SetBytes(d[32:64])
 	evm.StateDB.AddBalance(contract.Address(), coinName, total)
+	evm.StateDB.AddTokenTransfer(&types.TokenTransfer{Contract: contract.Address(), Currency: coinName, Value: total})
 	return true, nil
 }
 
@@ -1050,7 +1059,81 @@ func handleSend(d []byte, evm *EVM, contract *Contract, mem []byte) ([]byte, uin
 
 	asset := assets.Asset{Tkn: token, Tkt: ticket}
 	gas := evm.callGasTemp + params.CallStipend
-	return evm.Call(contract, toAddr, nil, gas, &asset)
+	ret, returnGas, err := evm.Call(contract, toAddr, nil, gas, &asset)
+	if err == nil {
+		transfer := &types.TokenTransfer{Contract: contract.Address(), To: &addr, Category: category, Ticket: ticketHash}
+		if token != nil {
+			transfer.Currency, transfer.Value = currency, amount
+		}
+		evm.StateDB.AddTokenTransfer(transfer)
+	}
+	return ret, returnGas, err
+}
+
+// memString reads the length prefixed string at offset of the memory.
+func memString(mem []byte, offset uint64, max uint64) (string, error) {
+	if offset+32 < offset || offset+32 > uint64(len(mem)) {
+		return "", errReturnDataOutOfBounds
+	}
+	length := new(big.Int).SetBytes(mem[offset : offset+32])
+	if !length.IsUint64() || length.Uint64() > max {
+		return "", fmt.Errorf("string of length %v exceeds %d bytes", length, max)
+	}
+	end := offset + 32 + length.Uint64()
+	if end > uint64(len(mem)) {
+		return "", errReturnDataOutOfBounds
+	}
+	return string(mem[offset+32 : end]), nil
+}
+
+func handleSetTokenInfo(d []byte, evm *EVM, contract *Contract, mem []byte) error {
+	if len(d) < 128 {
+		return fmt.Errorf("setTokenInfo error , contract : %s, error : %s", contract.Address(), "data too short")
+	}
+	coinName, err := memString(mem, new(big.Int).SetBytes(d[0:32]).Uint64(), 32)
+	if err != nil {
+		return fmt.Errorf("setTokenInfo error , contract : %s, error : %s", contract.Address(), err)
+	}
+	match, err := regexp.Match("^[A-Z][A-Z0-9_]{0,31}$", []byte(coinName))
+	if err != nil || !match {
+		return fmt.Errorf("setTokenInfo error , contract : %s, error : %s", contract.Address(), "illegal coinName")
+	}
+	decimals := new(big.Int).SetBytes(d[32:64])
+	if decimals.Cmp(big.NewInt(math.MaxUint8)) > 0 {
+		return fmt.Errorf("setTokenInfo error , contract : %s, error : %s", contract.Address(), "illegal decimals")
+	}
+	description, err := memString(mem, new(big.Int).SetBytes(d[64:96]).Uint64(), maxTokenDescriptionLen)
+	if err != nil {
+		return fmt.Errorf("setTokenInfo error , contract : %s, error : %s", contract.Address(), err)
+	}
+	if !evm.StateDB.SetTokenInfo(contract.Address(), coinName, uint8(decimals.Uint64()), description) {
+		return fmt.Errorf("setTokenInfo error , contract : %s, error : %s", contract.Address(), "coinName not registered by contract")
+	}
+	return nil
+}
+
+func handleSetTicketMetadata(d []byte, evm *EVM, contract *Contract, mem []byte) error {
+	if len(d) < 160 {
+		return fmt.Errorf("setTicketMetadata error , contract : %s, error : %s", contract.Address(), "data too short")
+	}
+	categoryName, err := memString(mem, new(big.Int).SetBytes(d[0:32]).Uint64(), 32)
+	if err != nil {
+		return fmt.Errorf("setTicketMetadata error , contract : %s, error : %s", contract.Address(), err)
+	}
+	match, err := regexp.Match("^[A-Z][A-Z0-9_]{0,31}$", []byte(categoryName))
+	if err != nil || !match {
+		return fmt.Errorf("setTicketMetadata error , contract : %s, error : %s", contract.Address(), "illegal categoryName")
+	}
+	value := common.BytesToHash(d[32:64])
+	uri, err := memString(mem, new(big.Int).SetBytes(d[64:96]).Uint64(), maxTicketURILen)
+	if err != nil {
+		return fmt.Errorf("setTicketMetadata error , contract : %s, error : %s", contract.Address(), err)
+	}
+	contentHash := common.BytesToHash(d[96:128])
+	if !evm.StateDB.SetTicketMetadata(contract.Address(), categoryName, value, uri, contentHash) {
+		return fmt.Errorf("setTicketMetadata error , contract : %s, error : %s", contract.Address(), "categoryName not registered by contract")
+	}
+	return nil
 }
 
 func makeLog(size int) executionFunc {
@@ -1146,6 +1229,26 @@ func makeLog(size int) executionFunc {
 				memory.Set(mStart.Uint64()+length-32, 32, hashFalse)
 			}
 			contract.Gas += interpreter.evm.callGasTemp
+		} else if topics[0] == topic_setTokenInfo && interpreter.evm.chainRules.IsTokenInfo {
+			result := hashTrue
+			if err := handleSetTokenInfo(d, interpreter.evm, contract, data); err != nil {
+				log.Trace("setTokenInfo error ", "contract", contract.Address(), "error", err)
+				result = hashFalse
+			}
+			if length >= 32 {
+				memory.Set(mStart.Uint64()+length-32, 32, result)
+			}
+			contract.Gas += interpreter.evm.callGasTemp
+		} else if topics[0] == topic_setTicketMeta && interpreter.evm.chainRules.IsTicketMeta {
+			result := hashTrue
+			if err := handleSetTicketMetadata(d, interpreter.evm, contract, data); err != nil {
+				log.Trace("setTicketMetadata error ", "contract", contract.Address(), "error", err)
+				result = hashFalse
+			}
+			if length >= 32 {
+				memory.Set(mStart.Uint64()+length-32, 32, result)
+			}
+			contract.Gas += interpreter.evm.callGasTemp
 		} else if topics[0] == topic_closePkg {
 			id := keys.Uint256{}
 			copy(id[:], d[0:32])
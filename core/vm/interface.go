@@ -43,6 +43,10 @@ type StateDB interface {
 	GetTokenRate(common.Address, string) (*big.Int, *big.Int)
 	RegisterToken(common.Address, string) bool
 	GetContrctAddressByToken(key string) common.Address
+	SetTokenInfo(common.Address, string, uint8, string) bool
+	GetTokenInfo(string) (uint8, string, bool)
+	SetTicketMetadata(common.Address, string, common.Hash, string, common.Hash) bool
+	GetTicketMetadata(string, common.Hash) (string, common.Hash, bool)
 
 	SetTicketNonce(common.Address, uint64)
 	GetTicketNonce(common.Address) uint64
@@ -79,6 +83,7 @@ type StateDB interface {
 	Snapshot() int
 
 	AddLog(*types.Log)
+	AddTokenTransfer(*types.TokenTransfer)
 	AddPreimage(common.Hash, []byte)
 
 	ForEachStorage(common.Address, func(common.Hash, common.Hash) bool)
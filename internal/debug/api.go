@@ -35,6 +35,7 @@ import (
 	"time"
 
 	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
 )
 
 // Handler is the global debugging handler.
@@ -51,6 +52,25 @@ type HandlerT struct {
 	traceFile string
 }
 
+// RpcStats are the statistics of the RPC methods called.
+type RpcStats struct {
+	Since   int64                      `json:"since"` // unix time the statistics count from
+	Methods map[string]rpc.MethodStats `json:"methods"`
+}
+
+// RpcStats returns the call and error counts and the latency percentiles, in
+// milliseconds over the latest calls, of the RPC methods called since the
+// node started or the statistics were reset.
+func (*HandlerT) RpcStats() *RpcStats {
+	methods, since := rpc.CallStats()
+	return &RpcStats{Since: since.Unix(), Methods: methods}
+}
+
+// ResetRpcStats drops the statistics of the RPC methods collected so far.
+func (*HandlerT) ResetRpcStats() {
+	rpc.ResetCallStats()
+}
+
 // Verbosity sets the log verbosity ceiling. The verbosity of individual packages
 // and source files can be raised using Vmodule.
 func (*HandlerT) Verbosity(level int) {
@@ -17,6 +17,7 @@
 package ethapi
 
 import (
+	"context"
 	"sync"
 
 	"github.com/sero-cash/go-sero/common"
@@ -25,6 +26,21 @@ import (
 type AddrLocker struct {
 	mu    sync.Mutex
 	locks map[common.Address]*sync.Mutex
+	queue proofQueue // orders the sends waiting for mu
+}
+
+// lockSends locks mu for a send once it is next in the proof queue. The
+// returned function unlocks it.
+func (l *AddrLocker) lockSends(ctx context.Context, config ProofQueue) (func(), error) {
+	release, err := l.queue.acquire(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		release()
+	}, nil
 }
 
 // lock returns the lock of the given address.
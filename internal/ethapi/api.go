@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -82,6 +83,59 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+// FeeHistory are the gas price statistics of a range of blocks starting with
+// OldestBlock, per block and requested percentile.
+type FeeHistory struct {
+	OldestBlock  *big.Int
+	GasPrice     [][]*big.Int
+	Fees         []map[string][]*big.Int // fee per gas by the currency paying it
+	GasUsedRatio []float64
+}
+
+// FeeHistoryResult are the gas price statistics of a range of blocks.
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big                `json:"oldestBlock"`
+	GasPrice     [][]*hexutil.Big            `json:"gasPrice,omitempty"`
+	Fees         []map[string][]*hexutil.Big `json:"fees,omitempty"`
+	GasUsedRatio []float64                   `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas prices paid at the given percentiles of the gas
+// used in each of up to blockCount blocks ending with lastBlock, along with how
+// full the blocks were, so clients can choose their gas price under load. The
+// fees per gas are reported by the currency paying them too, as contracts may
+// accept fees in their own tokens.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, percentiles []float64) (*FeeHistoryResult, error) {
+	history, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(history.OldestBlock),
+		GasUsedRatio: history.GasUsedRatio,
+	}
+	if history.GasPrice != nil {
+		result.GasPrice = make([][]*hexutil.Big, len(history.GasPrice))
+		result.Fees = make([]map[string][]*hexutil.Big, len(history.Fees))
+		for i, blockPrices := range history.GasPrice {
+			result.GasPrice[i] = toHexBigs(blockPrices)
+			result.Fees[i] = make(map[string][]*hexutil.Big, len(history.Fees[i]))
+			for currency, fees := range history.Fees[i] {
+				result.Fees[i][currency] = toHexBigs(fees)
+			}
+		}
+	}
+	return result, nil
+}
+
+func toHexBigs(values []*big.Int) []*hexutil.Big {
+	result := make([]*hexutil.Big, len(values))
+	for i, value := range values {
+		result[i] = (*hexutil.Big)(value)
+	}
+	return result
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -121,9 +175,54 @@ func NewPublicTxPoolAPI(b Backend) *PublicTxPoolAPI {
 	return &PublicTxPoolAPI{b}
 }
 
-// Content returns the transactions contained within the transaction pool.
+// TxPoolFilter selects the pooled transactions by their sender. Account
+// selects the ones sent from any PKr of a local account, PKr and Short the
+// ones sent from a PKr or from the PKr of a short address, Local the ones
+// sent by any local account. The transactions must match all set fields.
+type TxPoolFilter struct {
+	Account *common.AccountAddress  `json:"account"`
+	PKr     *common.Address         `json:"pkr"`
+	Short   *common.ContractAddress `json:"short"`
+	Local   bool                    `json:"local"`
+}
+
+// matcher returns the function telling the transactions the filter selects.
+func (f *TxPoolFilter) matcher(ctx context.Context, b Backend) (func(tx *types.Transaction) bool, error) {
+	if f == nil {
+		return func(*types.Transaction) bool { return true }, nil
+	}
+	var owner accounts.Wallet
+	if f.Account != nil {
+		wallet, err := scopeOf(ctx, b).find(accounts.Account{Address: *f.Account})
+		if err != nil {
+			return nil, err
+		}
+		owner = wallet
+	}
+	wallets := scopeOf(ctx, b).wallets()
+	return func(tx *types.Transaction) bool {
+		from := tx.From()
+		switch {
+		case owner != nil && !owner.IsMine(from):
+			return false
+		case f.PKr != nil && *f.PKr != from:
+			return false
+		case f.Short != nil && *f.Short != shortAddress(&tx.Stxt().From):
+			return false
+		case f.Local && getAddressByPkr(wallets, from) == nil:
+			return false
+		}
+		return true
+	}, nil
+}
 
-func (s *PublicTxPoolAPI) Content() map[string]map[string]*RPCTransaction {
+// Content returns the transactions contained within the transaction pool,
+// the ones selected by the filter if given.
+func (s *PublicTxPoolAPI) Content(ctx context.Context, filter *TxPoolFilter) (map[string]map[string]*RPCTransaction, error) {
+	match, err := filter.matcher(ctx, s.b)
+	if err != nil {
+		return nil, err
+	}
 	content := map[string]map[string]*RPCTransaction{
 		"pending": make(map[string]*RPCTransaction),
 		"queued":  make(map[string]*RPCTransaction),
@@ -131,22 +230,18 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]*RPCTransaction {
 	pending, queue := s.b.TxPoolContent()
 
 	// Flatten the pending transactions
-
-	dump := make(map[string]*RPCTransaction)
 	for _, tx := range pending {
-		dump[tx.Hash().Hex()] = newRPCPendingTransaction(tx)
+		if match(tx) {
+			content["pending"][tx.Hash().Hex()] = newRPCPendingTransaction(tx)
+		}
 	}
-	content["pending"] = dump
-
 	// Flatten the queued transactions
-
-	qdump := make(map[string]*RPCTransaction)
 	for _, tx := range queue {
-		qdump[tx.Hash().Hex()] = newRPCPendingTransaction(tx)
+		if match(tx) {
+			content["queued"][tx.Hash().Hex()] = newRPCPendingTransaction(tx)
+		}
 	}
-	content["queued"] = qdump
-
-	return content
+	return content, nil
 }
 
 // Status returns the number of pending and queued transaction in the pool.
@@ -158,55 +253,68 @@ func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	}
 }
 
-// Inspect retrieves the content of the transaction pool and flattens it into an
-// easily inspectable list.
-
-func (s *PublicTxPoolAPI) Inspect() map[string]map[string]string {
-	content := map[string]map[string]string{
-		"pending": make(map[string]string),
-		"queued":  make(map[string]string),
+// Inspect retrieves the content of the transaction pool, the transactions
+// selected by the filter if given, and flattens it into an easily inspectable
+// list keyed by the short address of the senders and the transaction hashes.
+func (s *PublicTxPoolAPI) Inspect(ctx context.Context, filter *TxPoolFilter) (map[string]map[string]map[string]string, error) {
+	match, err := filter.matcher(ctx, s.b)
+	if err != nil {
+		return nil, err
 	}
-	/*pending, queue := s.b.TxPoolContent()
+	content := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string),
+		"queued":  make(map[string]map[string]string),
+	}
+	pending, queue := s.b.TxPoolContent()
 
 	// Define a formatter to flatten a transaction into a string
+	var short = func(pkr *keys.PKr) string {
+		addr := shortAddress(pkr)
+		return hexutil.Encode(addr[:])
+	}
 	var format = func(tx *types.Transaction) string {
+		stxt := tx.Stxt()
+		fee := fmt.Sprintf("%v %s fee, %d outs", stxt.Fee.Value.ToIntRef(), strings.Trim(string(stxt.Fee.Currency[:]), zerobyte), len(stxt.Desc_O.Outs)+len(stxt.Desc_Z.Outs))
 		if to := tx.To(); to != nil {
-			return fmt.Sprintf("%s:  %v gas × %v wei", tx.To().Base58(), tx.Gas(), tx.GasPrice())
+			return fmt.Sprintf("%s: %s", short(to.ToPKr()), fee)
 		}
-		return fmt.Sprintf("contract creation: %v gas × %v wei", tx.Gas(), tx.GasPrice())
-	}
-	// Flatten the pending transactions
-
-	dump := make(map[string]string)
-	for _, tx := range pending {
-		dump[fmt.Sprintf("%s", tx.Hash())] = format(tx)
+		if len(stxt.Desc_Z.Outs) > 0 {
+			return fmt.Sprintf("%s: %s", short(&stxt.Desc_Z.Outs[0].PKr), fee)
+		}
+		return fmt.Sprintf("contract creation: %s", fee)
 	}
-	content["pending"] = dump
-
-	// Flatten the queued transactions
-	qdump := make(map[string]string)
-	for _, tx := range queue {
-		qdump[fmt.Sprintf("%s", tx.Hash())] = format(tx)
+	flatten := func(dump map[string]map[string]string, txs types.Transactions) {
+		for _, tx := range txs {
+			if !match(tx) {
+				continue
+			}
+			sender := short(&tx.Stxt().From)
+			if dump[sender] == nil {
+				dump[sender] = make(map[string]string)
+			}
+			dump[sender][tx.Hash().Hex()] = format(tx)
+		}
 	}
-	content["queued"] = qdump*/
-	return content
+	flatten(content["pending"], pending)
+	flatten(content["queued"], queue)
+	return content, nil
 }
 
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
-	am *accounts.Manager
+	b Backend
 }
 
 // NewPublicAccountAPI creates a new PublicAccountAPI.
-func NewPublicAccountAPI(am *accounts.Manager) *PublicAccountAPI {
-	return &PublicAccountAPI{am: am}
+func NewPublicAccountAPI(b Backend) *PublicAccountAPI {
+	return &PublicAccountAPI{b: b}
 }
 
 // Accounts returns the collection of accounts this node manages
-func (s *PublicAccountAPI) Accounts() []common.AccountAddress {
+func (s *PublicAccountAPI) Accounts(ctx context.Context) []common.AccountAddress {
 	addresses := make([]common.AccountAddress, 0) // return [] instead of nil if empty
-	for _, wallet := range s.am.Wallets() {
+	for _, wallet := range scopeOf(ctx, s.b).wallets() {
 		for _, account := range wallet.Accounts() {
 			addresses = append(addresses, account.Address)
 		}
@@ -214,8 +322,8 @@ func (s *PublicAccountAPI) Accounts() []common.AccountAddress {
 	return addresses
 }
 
-func (s *PublicAccountAPI) IsMinePKr(pkr common.Address) *common.AccountAddress {
-	wallets := s.am.Wallets()
+func (s *PublicAccountAPI) IsMinePKr(ctx context.Context, pkr common.Address) *common.AccountAddress {
+	wallets := scopeOf(ctx, s.b).wallets()
 	return getAddressByPkr(wallets, pkr)
 }
 
@@ -238,21 +346,23 @@ type PrivateAccountAPI struct {
 	am        *accounts.Manager
 	nonceLock *AddrLocker
 	b         Backend
+	spend     *spendGuard
 }
 
 // NewPrivateAccountAPI create a new PrivateAccountAPI.
-func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
+func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker, spend *spendGuard) *PrivateAccountAPI {
 	return &PrivateAccountAPI{
 		am:        b.AccountManager(),
 		nonceLock: nonceLock,
 		b:         b,
+		spend:     spend,
 	}
 }
 
 // ListAccounts will return a list of addresses for accounts this node manages.
-func (s *PrivateAccountAPI) ListAccounts() []common.AccountAddress {
+func (s *PrivateAccountAPI) ListAccounts(ctx context.Context) []common.AccountAddress {
 	addresses := make([]common.AccountAddress, 0) // return [] instead of nil if empty
-	for _, wallet := range s.am.Wallets() {
+	for _, wallet := range scopeOf(ctx, s.b).wallets() {
 		for _, account := range wallet.Accounts() {
 			addresses = append(addresses, account.Address)
 		}
@@ -270,9 +380,9 @@ type rawWallet struct {
 }
 
 // ListWallets will return a list of wallets this node manages.
-func (s *PrivateAccountAPI) ListWallets() []rawWallet {
+func (s *PrivateAccountAPI) ListWallets(ctx context.Context) []rawWallet {
 	wallets := make([]rawWallet, 0) // return [] instead of nil if empty
-	for _, wallet := range s.am.Wallets() {
+	for _, wallet := range scopeOf(ctx, s.b).wallets() {
 		status, failure := wallet.Status()
 
 		raw := rawWallet{
@@ -292,8 +402,8 @@ func (s *PrivateAccountAPI) ListWallets() []rawWallet {
 // connection and attempting to authenticate via the provided passphrase. Note,
 // the method may return an extra challenge requiring a second open (e.g. the
 // Trezor PIN matrix challenge).
-func (s *PrivateAccountAPI) OpenWallet(url string, passphrase *string) error {
-	wallet, err := s.am.Wallet(url)
+func (s *PrivateAccountAPI) OpenWallet(ctx context.Context, url string, passphrase *string) error {
+	wallet, err := scopeOf(ctx, s.b).wallet(url)
 	if err != nil {
 		return err
 	}
@@ -306,8 +416,8 @@ func (s *PrivateAccountAPI) OpenWallet(url string, passphrase *string) error {
 
 // DeriveAccount requests a HD wallet to derive a new account, optionally pinning
 // it for later reuse.
-func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (accounts.Account, error) {
-	wallet, err := s.am.Wallet(url)
+func (s *PrivateAccountAPI) DeriveAccount(ctx context.Context, url string, path string, pin *bool) (accounts.Account, error) {
+	wallet, err := scopeOf(ctx, s.b).wallet(url)
 	if err != nil {
 		return accounts.Account{}, err
 	}
@@ -321,15 +431,57 @@ func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (a
 	return wallet.Derive(derivPath, *pin)
 }
 
+// DeriveSeroAccount derives the account at path from the seed of the BIP-39
+// mnemonic and stores its key encrypted with password. Relative paths are
+// appended to the default root path, "0" derives m/44'/60'/0'/0/0.
+func (s *PrivateAccountAPI) DeriveSeroAccount(ctx context.Context, mnemonic string, path string, password string) (accounts.Account, error) {
+	derivPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	return ks.DeriveAccount(keystore.MnemonicToSeed(mnemonic, ""), derivPath, password)
+}
+
+// ExportDerivedKey returns the key at path derived from the seed of the
+// BIP-39 mnemonic as a JSON key encrypted with password. The derived account
+// must be one of the accounts of the caller.
+func (s *PrivateAccountAPI) ExportDerivedKey(ctx context.Context, mnemonic string, path string, password string) (string, error) {
+	derivPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return "", err
+	}
+	scope := scopeOf(ctx, s.b)
+	ks, err := scope.keystore()
+	if err != nil {
+		return "", err
+	}
+	account, keyJSON, err := ks.ExportDerived(keystore.MnemonicToSeed(mnemonic, ""), derivPath, password)
+	if err != nil {
+		return "", err
+	}
+	if !scope.contains(account) {
+		return "", accounts.ErrUnknownAccount
+	}
+	return string(keyJSON), nil
+}
+
 // NewAccount will create a new account and returns the address for the new account.
-func (s *PrivateAccountAPI) NewAccount(password string) (common.AccountAddress, error) {
-	acc, err := fetchKeystore(s.am).NewAccount(password)
+func (s *PrivateAccountAPI) NewAccount(ctx context.Context, password string) (common.AccountAddress, error) {
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return common.AccountAddress{}, err
+	}
+	acc, err := ks.NewAccount(password)
 	if err != nil {
 		return common.AccountAddress{}, err
 	}
 
 	if zconfig.Is_Dev() {
-		fetchKeystore(s.am).TimedUnlock(acc, password, 0)
+		ks.TimedUnlock(acc, password, 0)
 	}
 	return acc.Address, nil
 }
@@ -341,19 +493,89 @@ func fetchKeystore(am *accounts.Manager) *keystore.KeyStore {
 
 // ImportRawKey stores the given hex encoded ECDSA key into the key directory,
 // encrypting it with the passphrase.
-func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (common.AccountAddress, error) {
+func (s *PrivateAccountAPI) ImportRawKey(ctx context.Context, privkey string, password string) (common.AccountAddress, error) {
 	key, err := crypto.HexToECDSA(privkey)
 	if err != nil {
 		return common.AccountAddress{}, err
 	}
-	acc, err := fetchKeystore(s.am).ImportECDSA(key, password)
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return common.AccountAddress{}, err
+	}
+	acc, err := ks.ImportECDSA(key, password)
+	return acc.Address, err
+}
+
+// ImportTk adds a watch-only account from its address and tk. Its balances,
+// pkgs and history are tracked like the ones of the other accounts, but it
+// can not send as its spending key is not held.
+func (s *PrivateAccountAPI) ImportTk(ctx context.Context, addr common.AccountAddress, tk common.AccountAddress) (common.AccountAddress, error) {
+	if !keys.IsPKValid(addr.ToUint512()) {
+		return common.AccountAddress{}, errors.New("invalid address")
+	}
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return common.AccountAddress{}, err
+	}
+	acc, err := ks.AddWatchOnly(addr, tk)
 	return acc.Address, err
 }
 
+// ExportBundle returns an account as a JSON bundle of its key and its scanned
+// outs, spent nullifiers and history, encrypted with newPassword. Importing
+// the bundle on another node spares rescanning the chain for the account.
+func (s *PrivateAccountAPI) ExportBundle(ctx context.Context, addr common.AccountAddress, password string, newPassword string) (string, error) {
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return "", err
+	}
+	account, err := ks.Find(accounts.Account{Address: addr})
+	if err != nil {
+		return "", err
+	}
+	outs, err := lstate.ExportAccount(*account.Tk.ToUint512())
+	if err != nil {
+		return "", err
+	}
+	bundle, err := ks.ExportBundle(account, password, newPassword, outs)
+	if err != nil {
+		return "", err
+	}
+	return string(bundle), nil
+}
+
+// ImportBundle stores the key of a bundle returned by ExportBundle, encrypting
+// it with newPassword, and adds its outs. The blocks following the export are
+// scanned for the account in the background, progress is reported by
+// lstate_rescanStatus.
+func (s *PrivateAccountAPI) ImportBundle(ctx context.Context, bundle string, password string, newPassword string) (common.AccountAddress, error) {
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return common.AccountAddress{}, err
+	}
+	account, outs, err := ks.ImportBundle([]byte(bundle), password, newPassword)
+	if err != nil {
+		return common.AccountAddress{}, err
+	}
+	if err := lstate.ImportAccount(*account.Tk.ToUint512(), outs); err != nil {
+		return account.Address, fmt.Errorf("key imported, outs not: %v", err)
+	}
+	return account.Address, nil
+}
+
+// UnlockScopeArgs limits the use of an account unlocked by UnlockAccount.
+type UnlockScopeArgs struct {
+	SignOnly   bool                    `json:"signOnly"`   // encrypt transactions for the caller without sending them
+	MaxTxs     *hexutil.Uint64         `json:"maxTxs"`     // transactions after which the account is locked again
+	Recipients []common.AccountAddress `json:"recipients"` // the only addresses the account may pay to
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
-func (s *PrivateAccountAPI) UnlockAccount(addr common.AccountAddress, password string, duration *uint64) (bool, error) {
+//
+// The optional scope limits the use of the account until it is locked again.
+func (s *PrivateAccountAPI) UnlockAccount(ctx context.Context, addr common.AccountAddress, password string, duration *uint64, scope *UnlockScopeArgs) (bool, error) {
 	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
 	var d time.Duration
 	if duration == nil {
@@ -366,13 +588,79 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.AccountAddress, password s
 	if zconfig.Is_Dev() {
 		d = 0
 	}
-	err := fetchKeystore(s.am).TimedUnlock(accounts.Account{Address: addr}, password, d)
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return false, err
+	}
+	var limits *keystore.UnlockScope
+	if scope != nil {
+		limits = &keystore.UnlockScope{SignOnly: scope.SignOnly, Recipients: scope.Recipients}
+		if scope.MaxTxs != nil {
+			if *scope.MaxTxs == 0 {
+				return false, errors.New("maxTxs must be positive")
+			}
+			limits.MaxTxs = uint64(*scope.MaxTxs)
+		}
+	}
+	err = ks.TimedUnlockScoped(accounts.Account{Address: addr}, password, d, limits)
 	return err == nil, err
 }
 
 // LockAccount will lock the account associated with the given address when it's unlocked.
-func (s *PrivateAccountAPI) LockAccount(addr common.AccountAddress) bool {
-	return fetchKeystore(s.am).Lock(addr) == nil
+func (s *PrivateAccountAPI) LockAccount(ctx context.Context, addr common.AccountAddress) bool {
+	ks, err := scopeOf(ctx, s.b).keystore()
+	return err == nil && ks.Lock(addr) == nil
+}
+
+// AccountEvent is the notification of personal_subscribe("accountEvents").
+type AccountEvent struct {
+	Type    string                `json:"type"`
+	Address common.AccountAddress `json:"address"`
+	URL     string                `json:"url"`
+	Timeout hexutil.Uint64        `json:"timeout"` // seconds an unlock lasts, 0 if indefinite
+	Time    hexutil.Uint64        `json:"time"`
+}
+
+// AccountEvents creates a subscription fired when keystore accounts are
+// created, unlocked, locked, fail to unlock, or their key files are added to
+// or removed from the keystore directory.
+func (s *PrivateAccountAPI) AccountEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan keystore.AccountEvent, 16)
+		eventsSub := ks.SubscribeAccountEvents(events)
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, &AccountEvent{
+					Type:    ev.Kind.String(),
+					Address: ev.Account.Address,
+					URL:     ev.Account.URL.String(),
+					Timeout: hexutil.Uint64(ev.Timeout / time.Second),
+					Time:    hexutil.Uint64(ev.Time.Unix()),
+				})
+			case <-rpcSub.Err():
+				eventsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				eventsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
 }
 
 type threaded interface {
@@ -384,11 +672,14 @@ type threaded interface {
 // NOTE: the caller needs to ensure that the nonceLock is held, if applicable,
 // and release it after the transaction has been submitted to the tx pool
 func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs, passwd string) (*types.Transaction, error) {
-	s.nonceLock.mu.Lock()
-	defer s.nonceLock.mu.Unlock()
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	// Look up the wallet containing the requested abi
 	account := accounts.Account{Address: args.From}
-	wallet, err := s.am.Find(account)
+	wallet, err := scopeOf(ctx, s.b).find(account)
 	if err != nil {
 		return nil, err
 	}
@@ -396,6 +687,9 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
+	if err := s.spend.check(&args); err != nil {
+		return nil, err
+	}
 
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
 
@@ -421,7 +715,15 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs
 			defer th.SetThreads(threads)
 		}
 	}
-	return wallet.EncryptTxWithPassphrase(account, passwd, tx, txt, state)
+	if args.Sponsor != nil {
+		return nil, errors.New("sponsored transactions need unlocked accounts")
+	}
+	encrypted, err := wallet.EncryptTxWithPassphrase(account, passwd, tx, txt, state)
+	if err != nil {
+		return nil, err
+	}
+	s.spend.record(&args)
+	return encrypted, nil
 }
 
 // SendTransaction will create a transaction from the given arguments and
@@ -450,12 +752,13 @@ func (s *PrivateAccountAPI) SignAndSendTransaction(ctx context.Context, args Sen
 // PublicBlockChainAPI provides an API to access the Ethereum blockchain.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicBlockChainAPI struct {
-	b Backend
+	b     Backend
+	calls *callCache // nil if disabled
 }
 
 // NewPublicBlockChainAPI creates a new Ethereum blockchain API.
 func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
-	return &PublicBlockChainAPI{b}
+	return &PublicBlockChainAPI{b, newCallCache(b.RPCCallCache())}
 }
 
 // BlockNumber returns the block number of the chain head.
@@ -485,6 +788,43 @@ func (s *PublicBlockChainAPI) CurrencyToContractAddress(ctx context.Context, cy
 	return &contractAddr, nil
 }
 
+// TokenInfo is the display metadata of a currency.
+type TokenInfo struct {
+	Currency    string                 `json:"currency"`
+	Issuer      *common.AccountAddress `json:"issuer"`
+	Decimals    hexutil.Uint           `json:"decimals"`
+	Description string                 `json:"description"`
+	Recorded    bool                   `json:"recorded"` // whether the issuer recorded the metadata on chain
+}
+
+// GetTokenInfo returns the metadata recorded by the issuer of a currency. SERO
+// itself has a fixed number of 18 decimals.
+func (s *PublicBlockChainAPI) GetTokenInfo(ctx context.Context, cy string) (*TokenInfo, error) {
+	if cy == "" {
+		return nil, errors.New("cy can not be empty!")
+	}
+	cy = strings.ToUpper(cy)
+	if cy == params.DefaultCurrency {
+		return &TokenInfo{Currency: cy, Decimals: 18, Description: "SERO", Recorded: true}, nil
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	contractAddress := state.GetContrctAddressByToken(cy)
+	if contractAddress == (common.Address{}) {
+		return nil, fmt.Errorf("currency %s not exists", cy)
+	}
+	issuer := common.BytesToAccount(contractAddress[:64])
+	info := &TokenInfo{Currency: cy, Issuer: &issuer}
+	if decimals, description, ok := state.GetTokenInfo(cy); ok {
+		info.Decimals = hexutil.Uint(decimals)
+		info.Description = description
+		info.Recorded = true
+	}
+	return info, nil
+}
+
 type ConvertAddress struct {
 	Addr      map[common.AccountAddress]common.Address  `json:"addr"`
 	ShortAddr map[common.Address]common.ContractAddress `json:"shortAddr"`
@@ -536,7 +876,7 @@ func (s *PublicBlockChainAPI) GetFullAddress(ctx context.Context, shortAddresses
 	for _, short := range shortAddresses {
 		full := state.GetNonceAddress(short[:])
 
-		wallets := s.b.AccountManager().Wallets()
+		wallets := scopeOf(ctx, s.b).wallets()
 
 		if len(wallets) > 0 {
 			for _, wallet := range wallets {
@@ -555,12 +895,24 @@ func (s *PublicBlockChainAPI) GetFullAddress(ctx context.Context, shortAddresses
 type Balance struct {
 	Tkn map[string]*hexutil.Big   `json:"tkn"`
 	Tkt map[string][]*common.Hash `json:"tkt"`
+
+	// Breakdown of the tokens of local accounts
+	Locked      map[string]*hexutil.Big `json:"locked,omitempty"`      // spent by pooled transactions or locked by sero_lockOuts
+	Immature    map[string]*hexutil.Big `json:"immature,omitempty"`    // block rewards below the maturity depth
+	Unconfirmed map[string]*hexutil.Big `json:"unconfirmed,omitempty"` // outs with fewer than the minimum confirmations
+	Spendable   map[string]*hexutil.Big `json:"spendable,omitempty"`   // available to new transactions
+	Incoming    map[string]*hexutil.Big `json:"incoming,omitempty"`    // paid by pooled transactions, pending block only
 }
 
 // GetBalance returns the amount of wei for the given address in the state of the
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
-// block numbers are also allowed.
-func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.AccountAddress, blockNr rpc.BlockNumber) (Balance, error) {
+// block numbers are also allowed. For local accounts the outs with fewer than
+// minConf confirmations, defaulting to the node policy, are not spendable.
+// At the pending block the tokens paid to them by pooled transactions, change
+// included, are reported as incoming apart from the confirmed balance.
+// Their balance at a past block is rebuilt from the local out index, without
+// the breakdown.
+func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.AccountAddress, blockNr rpc.BlockNumber, minConf *hexutil.Uint64) (Balance, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 
 	if state == nil || err != nil {
@@ -590,21 +942,42 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Acc
 		// Look up the wallet containing the requested abi
 		account := accounts.Account{Address: address}
 
-		wallet, err := s.b.AccountManager().Find(account)
+		wallet, err := scopeOf(ctx, s.b).find(account)
 		if err != nil {
 			return Balance{}, err
 		}
 
 		seed := wallet.Accounts()[0].Tk
 
+		if blockNr >= 0 && uint64(blockNr) < s.b.CurrentBlock().NumberU64() {
+			return historicalBalance(seed.ToUint512(), uint64(blockNr))
+		}
+
 		outs, err := txs.GetOuts(seed.ToUint512())
+		if balance, err := txs.GetBalance(seed.ToUint512()); err == nil {
+			for cy, value := range balance {
+				tkn[strings.Trim(string(cy[:]), zerobyte)] = (*hexutil.Big)(value)
+			}
+		}
+		pooled := pooledInputs(s.b)
+		rewards := newRewardChecker(ctx, s.b)
+		maxNum, confErr := maxInNum(s.b, minConf)
+		locked := map[string]*hexutil.Big{}
+		immature := map[string]*hexutil.Big{}
+		unconfirmed := map[string]*hexutil.Big{}
+		spendable := map[string]*hexutil.Big{}
 		for _, out := range outs {
 			if out.Out_O.Asset.Tkn != nil {
 				cy := strings.Trim(string(out.Out_O.Asset.Tkn.Currency[:]), zerobyte)
-				if tkn[cy] == nil {
-					tkn[cy] = (*hexutil.Big)(out.Out_O.Asset.Tkn.Value.ToIntRef())
-				} else {
-					tkn[cy] = (*hexutil.Big)(new(big.Int).Add((*big.Int)(tkn[cy]), (out.Out_O.Asset.Tkn.Value.ToIntRef())))
+				switch {
+				case pooled[out.Root] || pooled[out.Trace] || outLocked(out.Root):
+					addBalance(locked, cy, out.Out_O.Asset.Tkn.Value.ToIntRef())
+				case rewards.immature(out):
+					addBalance(immature, cy, out.Out_O.Asset.Tkn.Value.ToIntRef())
+				case confErr != nil || (maxNum > 0 && out.Num > maxNum):
+					addBalance(unconfirmed, cy, out.Out_O.Asset.Tkn.Value.ToIntRef())
+				default:
+					addBalance(spendable, cy, out.Out_O.Asset.Tkn.Value.ToIntRef())
 				}
 			}
 			if out.Out_O.Asset.Tkt != nil {
@@ -620,11 +993,169 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Acc
 		if len(tkt) > 0 {
 			result.Tkt = tkt
 		}
+		if len(tkn) > 0 {
+			result.Locked, result.Immature, result.Unconfirmed, result.Spendable = locked, immature, unconfirmed, spendable
+		}
+		if blockNr == rpc.PendingBlockNumber {
+			result.Incoming = pooledIncoming(s.b, wallet)
+		}
 		return result, state.Error()
 	}
 
 }
 
+// historicalBalance returns the balance of a local account at the end of a
+// past block, rebuilt from the account index of the local outs.
+func historicalBalance(tk *keys.Uint512, num uint64) (Balance, error) {
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return Balance{}, errors.New("local outs are not scanned yet")
+	}
+	tkns, tkts, err := st1.GetBalanceAt(tk, num)
+	if err != nil {
+		return Balance{}, err
+	}
+	result := Balance{}
+	if len(tkns) > 0 {
+		result.Tkn = map[string]*hexutil.Big{}
+		for cy, value := range tkns {
+			result.Tkn[strings.Trim(string(cy[:]), zerobyte)] = (*hexutil.Big)(value)
+		}
+	}
+	if len(tkts) > 0 {
+		result.Tkt = map[string][]*common.Hash{}
+		for _, tkt := range tkts {
+			catg := strings.Trim(string(tkt.Category[:]), zerobyte)
+			t := common.BytesToHash(tkt.Value[:])
+			result.Tkt[catg] = append(result.Tkt[catg], &t)
+		}
+	}
+	return result, nil
+}
+
+// maxInNum returns the newest block the outs spent with minConf
+// confirmations may be created in, zero for no limit. The node policy
+// applies if minConf is nil.
+func maxInNum(b Backend, minConf *hexutil.Uint64) (uint64, error) {
+	conf := b.TxDefaults().MinConf
+	if minConf != nil {
+		conf = uint64(*minConf)
+	}
+	if conf <= 1 {
+		return 0, nil
+	}
+	head := b.CurrentBlock().NumberU64()
+	if head < conf {
+		return 0, fmt.Errorf("no outs have %d confirmations yet", conf)
+	}
+	return head - conf + 1, nil
+}
+
+func addBalance(balances map[string]*hexutil.Big, cy string, value *big.Int) {
+	if balances[cy] == nil {
+		balances[cy] = (*hexutil.Big)(new(big.Int))
+	}
+	(*big.Int)(balances[cy]).Add((*big.Int)(balances[cy]), value)
+}
+
+// pooledInputs returns the roots and traces of the outs spent by the
+// transactions in the pool.
+func pooledInputs(b Backend) map[keys.Uint256]bool {
+	inputs := make(map[keys.Uint256]bool)
+	pending, queued := b.TxPoolContent()
+	for _, tx := range append(pending, queued...) {
+		for key := range core.SpentKeys(tx) {
+			inputs[key] = true
+		}
+	}
+	return inputs
+}
+
+// pooledIncoming returns the tokens paid to the account of a wallet by the
+// transactions in the pool.
+func pooledIncoming(b Backend, wallet accounts.Wallet) map[string]*hexutil.Big {
+	incoming := map[string]*hexutil.Big{}
+	add := func(tkn *assets.Token) {
+		if tkn != nil && tkn.Value.ToIntRef().Sign() > 0 {
+			addBalance(incoming, strings.Trim(string(tkn.Currency[:]), zerobyte), tkn.Value.ToIntRef())
+		}
+	}
+	tk := wallet.Accounts()[0].Tk.ToUint512()
+	pending, queued := b.TxPoolContent()
+	for _, tx := range append(pending, queued...) {
+		stxt := tx.GetZZSTX()
+		if stxt == nil {
+			continue
+		}
+		for i := range stxt.Desc_O.Outs {
+			if out := &stxt.Desc_O.Outs[i]; wallet.IsMine(common.BytesToAddress(out.Addr[:])) {
+				add(out.Asset.Tkn)
+			}
+		}
+		for i := range stxt.Desc_Z.Outs {
+			out := &stxt.Desc_Z.Outs[i]
+			if !wallet.IsMine(common.BytesToAddress(out.PKr[:])) {
+				continue
+			}
+			if out_o, err := stx.DecOut_Z(tk, out); err == nil {
+				add(out_o.Asset.Tkn)
+			}
+		}
+	}
+	if len(incoming) == 0 {
+		return nil
+	}
+	return incoming
+}
+
+// rewardChecker tells the block rewards below the coinbase maturity depth of
+// the chain apart from the other outs, caching the looked up headers. They
+// are neither reported as spendable nor selected as inputs, as they may still
+// be reorganised away.
+type rewardChecker struct {
+	ctx      context.Context
+	b        Backend
+	head     uint64
+	maturity uint64
+	headers  map[uint64]*types.Header
+}
+
+func newRewardChecker(ctx context.Context, b Backend) *rewardChecker {
+	return &rewardChecker{
+		ctx:      ctx,
+		b:        b,
+		head:     b.CurrentBlock().NumberU64(),
+		maturity: b.ChainConfig().GetCoinbaseMaturity(),
+		headers:  make(map[uint64]*types.Header),
+	}
+}
+
+// immature reports whether out is a block reward paid less than the maturity
+// depth ago. Rewards are paid in the clear to the coinbase and the payouts of
+// the block.
+func (c *rewardChecker) immature(out *lstate.OutState) bool {
+	if out.Z || out.Num+c.maturity <= c.head {
+		return false
+	}
+	header, ok := c.headers[out.Num]
+	if !ok {
+		header, _ = c.b.HeaderByNumber(c.ctx, rpc.BlockNumber(out.Num))
+		c.headers[out.Num] = header
+	}
+	if header == nil {
+		return false
+	}
+	if *header.Coinbase.ToPKr() == out.Out_O.Addr {
+		return true
+	}
+	for i := range header.Payouts {
+		if *header.Payouts[i].PKr.ToPKr() == out.Out_O.Addr {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *PublicBlockChainAPI) GetPkg(ctx context.Context, accountAdress common.AccountAddress, packed bool, id *keys.Uint256) (interface{}, error) {
 
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
@@ -637,8 +1168,9 @@ func (s *PublicBlockChainAPI) GetPkg(ctx context.Context, accountAdress common.A
 
 	// Look up the wallet containing the requested abi
 	account := accounts.Account{Address: accountAdress}
-	wallets := s.b.AccountManager().Wallets()
-	wallet, err := s.b.AccountManager().Find(account)
+	scope := scopeOf(ctx, s.b)
+	wallets := scope.wallets()
+	wallet, err := scope.find(account)
 	if err != nil {
 		return nil, err
 	}
@@ -651,6 +1183,13 @@ func (s *PublicBlockChainAPI) GetPkg(ctx context.Context, accountAdress common.A
 
 			pkg["id"] = p.Pkg.Z.Pack.Id
 			pkg["packed"] = packed
+			terms := p.Pkg.Z.Pack.GetTerms()
+			if terms.Expiry != 0 {
+				pkg["refundableAt"] = hexutil.Uint64(p.Pkg.Z.High + terms.Expiry)
+			}
+			if terms.Hashlock != keys.Empty_Uint256 {
+				pkg["hashlock"] = terms.Hashlock
+			}
 			to := getAddressByPkr(wallets, common.BytesToAddress(p.Pkg.Z.Pack.PKr[:]))
 			if to != nil {
 				pkg["to_addr"] = to
@@ -696,7 +1235,7 @@ func (s *PublicBlockChainAPI) WatchPkg(ctx context.Context, id keys.Uint256, key
 	if err != nil {
 		return nil, err
 	}
-	wallets := s.b.AccountManager().Wallets()
+	wallets := scopeOf(ctx, s.b).wallets()
 	pkg := map[string]interface{}{}
 	pkg["id"] = id
 	pkg["key"] = key
@@ -737,6 +1276,9 @@ func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.
 		}
 		return response, err
 	}
+	if err == nil && blockNr >= 0 {
+		err = s.prunedBody(uint64(blockNr))
+	}
 	return nil, err
 }
 
@@ -758,13 +1300,25 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash comm
 	if block != nil {
 		return s.rpcOutputBlock(block, true, fullTx)
 	}
+	if number := rawdb.ReadHeaderNumber(s.b.ChainDb(), blockHash); err == nil && number != nil {
+		err = s.prunedBody(*number)
+	}
 	return nil, err
 }
 
+// prunedBody returns an error if the body of the known block of number may
+// have been pruned by a wallet node.
+func (s *PublicBlockChainAPI) prunedBody(number uint64) error {
+	if tail := rawdb.ReadBodyTail(s.b.ChainDb()); number < tail {
+		return fmt.Errorf("body of block %d has been pruned, bodies are only retained from block %d", number, tail)
+	}
+	return nil
+}
+
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
+	state, err := s.stateAt(ctx, blockNr)
+	if err != nil {
 		return nil, err
 	}
 	code := state.GetCode(address)
@@ -775,14 +1329,27 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
 func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
+	state, err := s.stateAt(ctx, blockNr)
+	if err != nil {
 		return nil, err
 	}
 	res := state.GetState(address, common.HexToHash(key))
 	return res[:], state.Error()
 }
 
+// stateAt returns the state at a block, failing for blocks the node does not
+// know and blocks whose state was pruned.
+func (s *PublicBlockChainAPI) stateAt(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, error) {
+	statedb, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, fmt.Errorf("block %d not found", blockNr)
+	}
+	return statedb, nil
+}
+
 type Smbol string
 
 // MarshalText implements encoding.TextMarshaler.
@@ -812,6 +1379,13 @@ func (s *Smbol) IsNotSero() bool {
 	return !s.IsSero()
 }
 
+// autoGasCurrency lets the node pick the currency paying the gas.
+const autoGasCurrency = "AUTO"
+
+func (s *Smbol) IsAuto() bool {
+	return (strings.ToUpper(strings.TrimSpace(string(*s))) == autoGasCurrency)
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From        common.AccountAddress  `json:"from"`
@@ -827,17 +1401,19 @@ type CallArgs struct {
 	Tkt         *common.Hash           `json:"tkt"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+// doCall executes the call, returning its result, the gas used, the gas
+// refunded after the execution and whether it failed.
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, uint64, bool, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, 0, false, err
+		return nil, 0, 0, false, err
 	}
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.AccountAddress{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if wallets := scopeOf(ctx, s.b).wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				addr = accounts[0].Address
 			}
@@ -848,6 +1424,10 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gas == 0 {
 		gas = math.MaxUint64 / 2
 	}
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && gas > gasCap {
+		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", gasCap)
+		gas = gasCap
+	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
@@ -901,7 +1481,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if args.To != nil && state.IsContract(common.BytesToAddress(args.To[:])) && args.GasCurrency.IsNotSero() {
 		m, d := state.GetTokenRate(common.BytesToAddress(args.To[:]), string(args.GasCurrency))
 		if m.Sign() == 0 || d.Sign() == 0 {
-			return nil, 0, false, errors.New("gasCurrency must be SERO or nil")
+			return nil, 0, 0, false, errors.New("gasCurrency must be SERO or nil")
 		}
 		state.AddBalance(common.BytesToAddress(args.To[:]), "SERO", fee)
 		fee = new(big.Int).Div(fee.Mul(fee, m), d)
@@ -929,7 +1509,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, 0, 0, false, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -941,29 +1521,52 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	res, gas, refund, failed, err := core.ApplyMessageRefund(evm, msg, gp)
 
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, 0, 0, false, err
+	}
+	// The call was cancelled by its time budget rather than failing
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, 0, 0, false, fmt.Errorf("execution aborted (timeout)")
 	}
 
-	return res, gas, failed, err
+	return res, gas, refund, failed, err
 
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+	if s.calls != nil {
+		return s.cachedCall(ctx, args, blockNr)
+	}
+	return s.call(ctx, args, blockNr)
+}
+
+func (s *PublicBlockChainAPI) call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	result, _, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
 	return (hexutil.Bytes)(result), err
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
+// given transaction against the current pending block. The estimate is read
+// from an execution at the gas ceiling and confirmed by a second one, only
+// transactions depending on the gas left are searched further.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
-	// Binary search the gas requirement, as it may be higher than the amount used
+	// Transfers to accounts run no code, their shielded ins and outs are not
+	// charged gas either, so they cost the intrinsic gas only
+	if args.To != nil && len(args.Data) == 0 {
+		state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.PendingBlockNumber)
+		if err != nil {
+			return 0, err
+		}
+		if !state.IsContract(common.BytesToAddress(args.To[:])) {
+			gas, err := core.IntrinsicGas(nil, false)
+			return hexutil.Uint64(gas), err
+		}
+	}
 	var (
-		lo  uint64 = params.TxGas - 1
 		hi  uint64
 		cap uint64
 	)
@@ -977,19 +1580,50 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 		}
 		hi = block.GasLimit()
 	}
-	cap = hi
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && hi > gasCap {
+		log.Warn("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
+		hi = gasCap
+	}
+	if gasCap := s.b.RPCEstimateGasCap(); gasCap != 0 && hi > gasCap {
+		log.Warn("Caller gas above estimation allowance, capping", "requested", hi, "cap", gasCap)
+		hi = gasCap
+	}
+	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
+		_, _, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
 		if err != nil || failed {
 			return false
 		}
 		return true
 	}
-	// Execute the binary search and hone in on an executable gas limit
+	// Execute once at the cap. The gas consumed before the refund, raised by
+	// the 1/64 of the available gas calls retain, is what the transaction
+	// needs unless its path depends on the gas left
+	args.Gas = hexutil.Uint64(cap)
+	_, used, refund, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
+	if err != nil {
+		return 0, err
+	}
+	if failed {
+		return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
+	}
+	estimate := used + refund
+	estimate += estimate / 63
+	if estimate < params.TxGas {
+		estimate = params.TxGas
+	}
+	if estimate >= cap || executable(estimate) {
+		if estimate > cap {
+			estimate = cap
+		}
+		return hexutil.Uint64(estimate), nil
+	}
+	// The transaction checks the gas left, search the gap to the cap
+	lo := estimate
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
 		if !executable(mid) {
@@ -998,11 +1632,8 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 			hi = mid
 		}
 	}
-	// Reject the transaction as invalid if it still fails at the highest allowance
-	if hi == cap {
-		if !executable(hi) {
-			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
-		}
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("gas estimation aborted: %v", err)
 	}
 	return hexutil.Uint64(hi), nil
 }
@@ -1092,6 +1723,20 @@ func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]inter
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+	if len(head.Payouts) > 0 {
+		fields["payouts"] = head.Payouts
+	}
+
+	// Aggregate the transaction sizes for fee per byte analysis
+	var txsSize, proofSize uint64
+	for _, tx := range b.Transactions() {
+		txsSize += uint64(tx.Size())
+		if stxt := tx.Stxt(); stxt != nil {
+			proofSize += uint64(stxt.ProofSize())
+		}
+	}
+	fields["transactionsSize"] = hexutil.Uint64(txsSize)
+	fields["proofSize"] = hexutil.Uint64(proofSize)
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -1145,6 +1790,9 @@ type RPCTransaction struct {
 	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
 	Value            *hexutil.Big    `json:"value"`
 	Stx              *stx.T          `json:"stx"`
+	Size             hexutil.Uint64  `json:"size"`      // RLP encoded size in bytes
+	ProofSize        hexutil.Uint64  `json:"proofSize"` // size of the zero knowledge proofs in bytes
+	ValidUntilBlock  *hexutil.Uint64 `json:"validUntilBlock,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1167,6 +1815,13 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		Input:    hexutil.Bytes(tx.Data()),
 		To:       to,
 		Stx:      tx.Stxt(),
+		Size:     hexutil.Uint64(tx.Size()),
+	}
+	if stxt := tx.Stxt(); stxt != nil {
+		result.ProofSize = hexutil.Uint64(stxt.ProofSize())
+	}
+	if validUntil := tx.ValidUntil(); validUntil != 0 {
+		result.ValidUntilBlock = (*hexutil.Uint64)(&validUntil)
 	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
@@ -1214,18 +1869,26 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	spend     *spendGuard
+}
+
+// NewSharedTransactionPoolAPI creates the transaction pool API of a node,
+// shared by the RPC clients and the senders of the node itself so they use
+// the same nonce locks and spend policy.
+func NewSharedTransactionPoolAPI(b Backend) *PublicTransactionPoolAPI {
+	return NewPublicTransactionPoolAPI(b, new(AddrLocker), newSpendGuard(b))
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
-func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI {
-	return &PublicTransactionPoolAPI{b, nonceLock}
+func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker, spend *spendGuard) *PublicTransactionPoolAPI {
+	return &PublicTransactionPoolAPI{b, nonceLock, spend}
 }
 
-func (s *PublicTransactionPoolAPI) AddressUnlocked(addr common.AccountAddress) (bool, error) {
+func (s *PublicTransactionPoolAPI) AddressUnlocked(ctx context.Context, addr common.AccountAddress) (bool, error) {
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: addr}
 
-	wallet, err := s.b.AccountManager().Find(account)
+	wallet, err := scopeOf(ctx, s.b).find(account)
 	if err != nil {
 		return false, err
 	}
@@ -1324,24 +1987,82 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
-func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+//
+// With the hash of the block the transaction was seen in before, canonical
+// reports whether that block is still on the canonical chain. If it was
+// reorged out, replacedBy is the canonical block at its height and the
+// receipt, if any, is the one of the block including the transaction now.
+func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash, expectedBlock *common.Hash) (map[string]interface{}, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
 	if tx == nil {
-		return nil, nil
+		if expectedBlock == nil {
+			return nil, nil
+		}
+		// Reorged out and not included again
+		replacedBy, err := s.replacingBlock(*expectedBlock)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"transactionHash": hash,
+			"blockHash":       nil,
+			"canonical":       false,
+			"replacedBy":      replacedBy,
+		}, nil
 	}
 	receipts, err := s.b.GetReceipts(ctx, blockHash)
 	if err != nil {
 		return nil, err
 	}
+	if len(receipts) == 0 {
+		if tail := rawdb.ReadReceiptTail(s.b.ChainDb()); blockNumber < tail {
+			return nil, fmt.Errorf("receipt of block %d has been pruned, receipts are only retained from block %d", blockNumber, tail)
+		}
+	}
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
 	receipt := receipts[index]
+	fields := receiptFields(tx, receipt, blockHash, blockNumber, index)
+	fields["canonical"] = true
+	if expectedBlock != nil && *expectedBlock != blockHash {
+		replacedBy, err := s.replacingBlock(*expectedBlock)
+		if err != nil {
+			return nil, err
+		}
+		fields["canonical"] = false
+		fields["replacedBy"] = replacedBy
+	}
+	return fields, nil
+}
 
-	//var abi types.Signer = types.FrontierSigner{}
-	//
-	//from, _ := types.Sender(abi, tx)
+// GetBlockReceiptsByNumber returns the receipts of all transactions of a
+// block, read at once.
+func (s *PublicTransactionPoolAPI) GetBlockReceiptsByNumber(ctx context.Context, blockNr rpc.BlockNumber) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(txs) {
+		if tail := rawdb.ReadReceiptTail(s.b.ChainDb()); block.NumberU64() < tail {
+			return nil, fmt.Errorf("receipts of block %d have been pruned, receipts are only retained from block %d", block.NumberU64(), tail)
+		}
+		return nil, fmt.Errorf("receipts of block %d not found", block.NumberU64())
+	}
+	result := make([]map[string]interface{}, len(txs))
+	for i, tx := range txs {
+		result[i] = receiptFields(tx, receipts[i], block.Hash(), block.NumberU64(), uint64(i))
+	}
+	return result, nil
+}
 
+// receiptFields returns the RPC representation of the receipt of tx.
+func receiptFields(tx *types.Transaction, receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, index uint64) map[string]interface{} {
 	to := tx.To()
 
 	if to != nil && bytes.Equal(to[:], (&common.Address{})[:]) {
@@ -1351,7 +2072,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              tx.From(),
 		"to":                to,
@@ -1374,7 +2095,54 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = common.BytesToAccount(receipt.ContractAddress[:64])
 	}
-	return fields, nil
+	// Receipts stored before the transfers were recorded lack them
+	if len(receipt.TokenTransfers) > 0 {
+		fields["tokenTransfers"] = newRPCTokenTransfers(receipt.TokenTransfers)
+	}
+	return fields
+}
+
+// RPCTokenTransfer is a token issuance or a send of a contract, which the VM
+// executes without logging them.
+type RPCTokenTransfer struct {
+	Contract common.AccountAddress   `json:"contract"`
+	To       *common.ContractAddress `json:"to"` // nil for issuances
+	Currency string                  `json:"currency,omitempty"`
+	Value    *hexutil.Big            `json:"value,omitempty"`
+	Category string                  `json:"category,omitempty"`
+	Ticket   *common.Hash            `json:"ticket,omitempty"`
+}
+
+func newRPCTokenTransfers(transfers []*types.TokenTransfer) []RPCTokenTransfer {
+	result := make([]RPCTokenTransfer, len(transfers))
+	for i, transfer := range transfers {
+		result[i] = RPCTokenTransfer{
+			Contract: common.BytesToAccount(transfer.Contract[:]),
+			To:       transfer.To,
+			Currency: transfer.Currency,
+			Value:    (*hexutil.Big)(transfer.Value),
+			Category: transfer.Category,
+		}
+		if transfer.Ticket != (common.Hash{}) {
+			ticket := transfer.Ticket
+			result[i].Ticket = &ticket
+		}
+	}
+	return result
+}
+
+// replacingBlock returns the canonical block at the height of the given
+// block, nil if the chain is shorter now.
+func (s *PublicTransactionPoolAPI) replacingBlock(hash common.Hash) (*common.Hash, error) {
+	db := s.b.ChainDb()
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		return nil, fmt.Errorf("unknown block %x", hash)
+	}
+	if canonical := rawdb.ReadCanonicalHash(db, *number); canonical != (common.Hash{}) {
+		return &canonical, nil
+	}
+	return nil, nil
 }
 
 // SendTxArgs represents the arguments to sumbit a new transaction into the transaction pool.
@@ -1382,7 +2150,7 @@ type SendTxArgs struct {
 	From        common.AccountAddress  `json:"from"`
 	To          *common.AccountAddress `json:"to"`
 	Gas         *hexutil.Uint64        `json:"gas"`
-	GasCurrency Smbol                  `json:"gasCy"` //default SERO
+	GasCurrency Smbol                  `json:"gasCy"` //default SERO, AUTO picks a held currency accepted by the contract
 	GasPrice    *hexutil.Big           `json:"gasPrice"`
 	Value       *hexutil.Big           `json:"value"`
 	Data        *hexutil.Bytes         `json:"data"`
@@ -1391,31 +2159,152 @@ type SendTxArgs struct {
 	Category    Smbol                  `json:"catg"`
 	Tkt         *common.Hash           `json:"tkt"`
 	Memo        string                 `json:"Memo"`
+	MinConf     *hexutil.Uint64        `json:"minConf"` // default node policy
+	Sponsor     *common.AccountAddress `json:"sponsor"` // local account paying the fee, default from
+
+	// ValidUntilBlock is the last block the transaction may be included in,
+	// after which it can be sent again without the risk of paying twice
+	ValidUntilBlock *hexutil.Uint64 `json:"validUntilBlock"`
+
+	// Selection is the coin selection strategy of the inputs: largest,
+	// smallest, exact or consolidate, default node policy
+	Selection *string `json:"selection"`
+
+	// Receptions pay several recipients with a single transaction, in place
+	// of To and the payment fields
+	Receptions []Reception `json:"receptions"`
+
+	// Assets are further tokens and tickets paid to To by the transaction,
+	// each one in an output of its own
+	Assets []Asset `json:"assets"`
+
+	// PkgExpiry is the number of blocks after which the creator of a package
+	// may refund it with sero_refundPkg, if it is not closed by then
+	PkgExpiry *hexutil.Uint64 `json:"pkgExpiry"`
+
+	// Hashlock is the SHA-256 hash of the preimage the owner of a package
+	// reveals to close it
+	Hashlock *keys.Uint256 `json:"hashlock"`
+
+	maxInNum  uint64
+	exclude   []keys.Uint256 // outs the caller leaves out of coin selection
+	excluded  []keys.Uint256
+	selection string
+	approved  bool // exempt from the spend limits
+}
+
+// maxMemoLength is the size of the memo field of outputs in bytes.
+const maxMemoLength = len(keys.Uint512{})
+
+// TxDefaults are the node side policies filling in unspecified fields of the
+// transactions sent through the RPC interface. Zero values select the defaults.
+type TxDefaults struct {
+	Gas         uint64 `toml:",omitempty"` // Gas of transactions not executing contract code
+	GasCurrency string `toml:",omitempty"` // Currency paying the gas of contract calls, AUTO to pick one
+	MaxMemo     int    `toml:",omitempty"` // Maximum memo length in bytes, longer than an output memo for transfers only
+	MinConf     uint64 `toml:",omitempty"` // Confirmations of the outs spent, zero or one for any
+	Selection   string `toml:",omitempty"` // Coin selection strategy, index order if empty
+}
+
+// DefaultTxDefaults contains the default transaction policies.
+var DefaultTxDefaults = TxDefaults{
+	Gas:         90000,
+	GasCurrency: params.DefaultCurrency,
+	MaxMemo:     1024,
+}
+
+// sanitize replaces the unset and invalid fields with their defaults.
+func (d TxDefaults) sanitize() TxDefaults {
+	if d.Gas == 0 {
+		d.Gas = DefaultTxDefaults.Gas
+	}
+	if d.GasCurrency == "" {
+		d.GasCurrency = DefaultTxDefaults.GasCurrency
+	}
+	if d.MaxMemo <= 0 || d.MaxMemo > maxExtendedMemoLength {
+		d.MaxMemo = DefaultTxDefaults.MaxMemo
+	}
+	if !txs.ValidSelection(d.Selection) {
+		d.Selection = DefaultTxDefaults.Selection
+	}
+	return d
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
 func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
-	if args.Gas == nil {
-		args.Gas = new(hexutil.Uint64)
-		*(*uint64)(args.Gas) = 90000
-	}
+	defaults := b.TxDefaults().sanitize()
 
-	if args.GasCurrency.IsEmpty() {
-		args.GasCurrency = Smbol(params.DefaultCurrency)
+	var err error
+	if args.maxInNum, err = maxInNum(b, args.MinConf); err != nil {
+		return err
 	}
 
-	if strings.Trim(args.Memo, "") != "" {
-		b := []byte(args.Memo)
-		if len(b) > 64 {
-			return errors.New("args memo is too long,it's limited 64 bytes")
+	if len(args.Memo) > defaults.MaxMemo {
+		return fmt.Errorf("args memo is too long,it's limited %d bytes", defaults.MaxMemo)
+	}
+	args.selection = defaults.Selection
+	if args.Selection != nil {
+		if !txs.ValidSelection(*args.Selection) {
+			return fmt.Errorf("unknown coin selection %q", *args.Selection)
 		}
+		args.selection = *args.Selection
 	}
+	if args.Sponsor != nil {
+		if *args.Sponsor == args.From {
+			return errors.New("sponsor must differ from the sender")
+		}
+		if _, err := scopeOf(ctx, b).find(accounts.Account{Address: *args.Sponsor}); err != nil {
+			return fmt.Errorf("sponsor: %v", err)
+		}
+		if args.excluded, err = excludedRoots(ctx, b, args.From, *args.Sponsor); err != nil {
+			return err
+		}
+	} else if args.excluded, err = excludedRoots(ctx, b, args.From); err != nil {
+		return err
+	}
+	args.excluded = append(args.excluded, args.exclude...)
 
-	state, _, err := b.StateAndHeaderByNumber(ctx, -1)
+	state, header, err := b.StateAndHeaderByNumber(ctx, -1)
 	if err != nil {
 		return err
 	}
-	if args.To == nil || !state.IsContract(common.BytesToAddress(args.To[:])) {
+	if args.ValidUntilBlock != nil && uint64(*args.ValidUntilBlock) <= header.Number.Uint64() {
+		return fmt.Errorf("validUntilBlock %d has passed, the current block is %d", uint64(*args.ValidUntilBlock), header.Number.Uint64())
+	}
+	if len(args.Assets) > 0 {
+		if err := args.expandAssets(state); err != nil {
+			return err
+		}
+	}
+	multi := len(args.Receptions) > 0
+	if multi {
+		if err := args.checkReceptions(state); err != nil {
+			return err
+		}
+	}
+	isContract := args.To != nil && state.IsContract(common.BytesToAddress(args.To[:]))
+	extendedMemo := len(args.Memo) > maxMemoLength
+	if extendedMemo {
+		if args.To == nil || isContract {
+			return fmt.Errorf("memos longer than %d bytes are only supported by transfers", maxMemoLength)
+		}
+		if args.Data != nil && len(*args.Data) > 0 {
+			return fmt.Errorf("memos longer than %d bytes can not be sent with data", maxMemoLength)
+		}
+	}
+	if args.GasCurrency.IsEmpty() {
+		if isContract {
+			args.GasCurrency = Smbol(defaults.GasCurrency)
+		} else {
+			args.GasCurrency = Smbol(params.DefaultCurrency)
+		}
+	}
+	autoGas := args.GasCurrency.IsAuto()
+	if autoGas {
+		// Picked once the gas is known
+		args.GasCurrency = Smbol(params.DefaultCurrency)
+	}
+	if !isContract {
 		if args.GasCurrency.IsNotEmpty() && args.GasCurrency.IsNotSero() {
 			return errors.New(`GasCurrency must be null or SERO`)
 		}
@@ -1456,7 +2345,10 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			return errors.New(fmt.Sprintf("tx without %s tkt", args.Category))
 		}
 	}
-	if args.To == nil {
+	if err := args.checkTickets(ctx, b); err != nil {
+		return err
+	}
+	if args.To == nil && !multi {
 		// Contract creation
 		var input []byte
 		if args.Data != nil {
@@ -1467,17 +2359,139 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			return errors.New(`contract creation without any data provided`)
 		}
 	}
+	if args.Gas == nil {
+		gas := hexutil.Uint64(defaults.Gas)
+		if (args.To == nil && !multi) || isContract {
+			// Contract code is executed, estimate instead of guessing
+			if gas, err = NewPublicBlockChainAPI(b).EstimateGas(ctx, args.toCallArgs()); err != nil {
+				return err
+			}
+		}
+		if extendedMemo {
+			// The memo payload is charged as transaction data
+			gas += hexutil.Uint64(uint64(extendedMemoPayloadSize(len(args.Memo))) * params.TxDataNonZeroGas)
+		}
+		args.Gas = &gas
+	}
+	if autoGas && isContract {
+		if args.GasCurrency, err = args.selectGasCurrency(ctx, b, state); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// selectGasCurrency picks the currency paying the gas of a contract call. SERO
+// is preferred if the paying account can afford it, otherwise the first held token
+// accepted by the contract is used, falling back to SERO.
+func (args *SendTxArgs) selectGasCurrency(ctx context.Context, b Backend, state *state.StateDB) (Smbol, error) {
+	payer := args.From
+	if args.Sponsor != nil {
+		payer = *args.Sponsor
+	}
+	held, err := heldTokens(ctx, b, payer)
+	if err != nil {
+		return "", err
+	}
+	fee := new(big.Int).Mul(args.GasPrice.ToInt(), new(big.Int).SetUint64(uint64(*args.Gas)))
+	affords := func(cy string, fee *big.Int) bool {
+		need := fee
+		if cy == string(args.Currency) && payer == args.From {
+			need = new(big.Int).Add(fee, args.Value.ToInt())
+		}
+		return held[cy] != nil && held[cy].Cmp(need) >= 0
+	}
+	if affords(params.DefaultCurrency, fee) {
+		return Smbol(params.DefaultCurrency), nil
+	}
+	currencies := make([]string, 0, len(held))
+	for cy := range held {
+		if cy != params.DefaultCurrency {
+			currencies = append(currencies, cy)
+		}
+	}
+	sort.Strings(currencies)
+
+	contract := common.BytesToAddress(args.To[:])
+	for _, cy := range currencies {
+		m, d := state.GetTokenRate(contract, cy)
+		if m.Sign() == 0 || d.Sign() == 0 {
+			continue
+		}
+		if affords(cy, new(big.Int).Div(new(big.Int).Mul(fee, m), d)) {
+			return Smbol(cy), nil
+		}
+	}
+	return Smbol(params.DefaultCurrency), nil
+}
+
+// heldTokens sums the token outputs of a local account of the scope of the
+// request of ctx by currency.
+func heldTokens(ctx context.Context, b Backend, address common.AccountAddress) (map[string]*big.Int, error) {
+	wallet, err := scopeOf(ctx, b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	tk := wallet.Accounts()[0].Tk
+	outs, err := txs.GetOuts(tk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[string]*big.Int)
+	for _, out := range outs {
+		if out.Out_O.Asset.Tkn == nil {
+			continue
+		}
+		cy := strings.Trim(string(out.Out_O.Asset.Tkn.Currency[:]), zerobyte)
+		if held[cy] == nil {
+			held[cy] = new(big.Int)
+		}
+		held[cy].Add(held[cy], out.Out_O.Asset.Tkn.Value.ToIntRef())
+	}
+	return held, nil
+}
+
+// encrypt encrypts the transaction with the wallet of the sender, the fee
+// being paid by the sponsor if set.
+func (args *SendTxArgs) encrypt(wallet accounts.Wallet, tx *types.Transaction, txt *ztx.T, state *state.StateDB) (*types.Transaction, error) {
+	account := accounts.Account{Address: args.From}
+	if args.Sponsor != nil {
+		return wallet.EncryptTxWithSponsor(account, accounts.Account{Address: *args.Sponsor}, tx, txt, state)
+	}
+	return wallet.EncryptTx(account, tx, txt, state)
+}
+
+// toCallArgs converts the transaction into the arguments of a message call.
+func (args *SendTxArgs) toCallArgs() CallArgs {
+	call := CallArgs{
+		From:        args.From,
+		To:          args.To,
+		GasCurrency: args.GasCurrency,
+		GasPrice:    *args.GasPrice,
+		Value:       *args.Value,
+		Currency:    args.Currency,
+		Dynamic:     args.Dynamic,
+		Category:    args.Category,
+		Tkt:         args.Tkt,
+	}
+	if args.Data != nil {
+		call.Data = *args.Data
+	}
+	return call
+}
+
 func (args *SendTxArgs) toTransaction(state *state.StateDB) (*types.Transaction, *ztx.T, error) {
 	var input []byte
 	var Pkr keys.PKr
 	var isZ bool
 	to := args.To
+	memo := args.Memo
 	fromRand := keys.Uint256{}
 
 	feevalue := new(big.Int).Mul(((*big.Int)(args.GasPrice)), new(big.Int).SetUint64(uint64(*args.Gas)))
+	if len(args.Receptions) > 0 {
+		return args.toMultiTransaction(feevalue)
+	}
 	if to == nil {
 		copy(fromRand[:16], (*args.Data)[:16])
 		isZ = false
@@ -1499,34 +2513,85 @@ func (args *SendTxArgs) toTransaction(state *state.StateDB) (*types.Transaction,
 	if args.Data != nil {
 		input = *args.Data
 	}
-	tx := types.NewTransaction((*big.Int)(args.GasPrice), uint64(*args.Gas), input)
+	if len(memo) > maxMemoLength {
+		if !isZ || len(input) > 0 {
+			return nil, nil, fmt.Errorf("memos longer than %d bytes are only supported by transfers", maxMemoLength)
+		}
+		var err error
+		if memo, input, err = sealExtendedMemo([]byte(memo)); err != nil {
+			return nil, nil, err
+		}
+	}
+	tx := args.newTransaction(input)
 	ehash := tx.Ehash()
 	fee := assets.Token{
 		utils.StringToUint256(string(args.GasCurrency)),
 		utils.U256(*feevalue),
 	}
-	outData := types.NewTxtOut(Pkr, string(args.Currency), (*big.Int)(args.Value), string(args.Category), args.Tkt, args.Memo, isZ)
+	outData := types.NewTxtOut(Pkr, string(args.Currency), (*big.Int)(args.Value), string(args.Category), args.Tkt, memo, isZ)
 	txt := types.NewTxt(fromRand.NewRef(), ehash, fee, outData, nil, nil, nil)
+	if to != nil {
+		txt.AddRecipient(Pkr, to.ToUint512())
+	}
+	txt.MaxInNum = args.maxInNum
+	txt.Excluded = args.excluded
+	txt.Selection = args.selection
 	return tx, txt, nil
 }
 
+// toMultiTransaction returns the transaction of args paying its receptions.
+func (args *SendTxArgs) toMultiTransaction(feevalue *big.Int) (*types.Transaction, *ztx.T, error) {
+	tx := args.newTransaction(nil)
+	fee := assets.Token{
+		utils.StringToUint256(string(args.GasCurrency)),
+		utils.U256(*feevalue),
+	}
+	txt := types.NewTxt(keys.RandUint256().NewRef(), tx.Ehash(), fee, nil, nil, nil, nil)
+	txt.Outs = args.receptionOuts(txt)
+	txt.MaxInNum = args.maxInNum
+	txt.Excluded = args.excluded
+	txt.Selection = args.selection
+	return tx, txt, nil
+}
+
+// newTransaction returns the transaction of args carrying input, which
+// expires after ValidUntilBlock if set.
+func (args *SendTxArgs) newTransaction(input []byte) *types.Transaction {
+	tx := types.NewTransaction((*big.Int)(args.GasPrice), uint64(*args.Gas), input)
+	if args.ValidUntilBlock != nil {
+		tx = tx.WithValidUntil(uint64(*args.ValidUntilBlock))
+	}
+	return tx
+}
+
 func (args *SendTxArgs) toPkg(state *state.StateDB) (*types.Transaction, *ztx.T, error) {
+	if len(args.Memo) > maxMemoLength {
+		return nil, nil, fmt.Errorf("args memo is too long,it's limited %d bytes", maxMemoLength)
+	}
 	var Pkr keys.PKr
 	if state.IsContract(common.BytesToAddress(args.To[:])) {
 		Pkr = *(args.To.ToPKr())
 	} else {
 		Pkr = keys.Addr2PKr(args.To.ToUint512(), keys.RandUint256().NewRef())
 	}
-	tx := types.NewTransaction((*big.Int)(args.GasPrice), uint64(*args.Gas), nil)
+	tx := args.newTransaction(nil)
 	fromRand := keys.RandUint256().NewRef()
 	ehash := tx.Ehash()
 	fee := assets.Token{
 		utils.StringToUint256(string(args.GasCurrency)),
 		utils.U256(*new(big.Int).Mul(((*big.Int)(args.GasPrice)), new(big.Int).SetUint64(uint64(*args.Gas)))),
 	}
-	pkgCreate := types.NewCreatePkg(Pkr, string(args.Currency), (*big.Int)(args.Value), string(args.Category), args.Tkt, args.Memo)
+	var expiry uint64
+	if args.PkgExpiry != nil {
+		expiry = uint64(*args.PkgExpiry)
+	}
+	pkgCreate := types.NewCreatePkg(Pkr, string(args.Currency), (*big.Int)(args.Value), string(args.Category), args.Tkt, args.Memo, expiry, args.Hashlock)
 	txt := types.NewTxt(fromRand, ehash, fee, nil, pkgCreate, nil, nil)
 	txt.FromRnd = keys.RandUint256().NewRef()
+	txt.AddRecipient(Pkr, args.To.ToUint512())
+	txt.MaxInNum = args.maxInNum
+	txt.Excluded = args.excluded
+	txt.Selection = args.selection
 	return tx, txt, nil
 }
 
@@ -1535,6 +2600,7 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, to
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
+	relayTransaction(b, tx)
 	log.Info("Submitted transaction", "fullhash", tx.Hash().Hex(), "recipient", to)
 	return tx.Hash(), nil
 }
@@ -1542,51 +2608,220 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, to
 // SendTransaction creates a transaction for the given argument, sign it and submit it to the
 // transaction pool.
 func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
-	s.nonceLock.mu.Lock()
-	defer s.nonceLock.mu.Unlock()
-	// Look up the wallet containing the requested abi
-	account := accounts.Account{Address: args.From}
-
-	wallet, err := s.b.AccountManager().Find(account)
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
 	if err != nil {
 		return common.Hash{}, err
 	}
+	defer unlock()
 
-	// Set some sanity defaults and terminate on failure
-	if err := args.setDefaults(ctx, s.b); err != nil {
+	encrypted, err := s.assembleTransaction(ctx, args)
+	if err != nil {
 		return common.Hash{}, err
 	}
+	return submitTransaction(ctx, s.b, encrypted, args.To)
+}
 
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+// clientRequestTTL is the time a client request id is remembered for after
+// the transaction of the request was sent.
+const clientRequestTTL = 24 * time.Hour
+
+// SendTransactionIdempotent works like SendTransaction, except that retries
+// with the clientRequestId of a previous call of the same sender return the
+// hash of the transaction sent by that call instead of sending another one.
+// The id is forgotten after a day, or once the transaction is neither pooled
+// nor included in the chain any more, a retry sending it again then.
+func (s *PublicTransactionPoolAPI) SendTransactionIdempotent(ctx context.Context, args SendTxArgs, clientRequestId string) (common.Hash, error) {
+	return s.sendIdempotent(ctx, args, clientRequestId, uint64(time.Now().Add(clientRequestTTL).Unix()))
+}
 
+// sendIdempotent sends the transaction of a client request id remembered
+// until expires, unless one sent for the id is still pending or included.
+func (s *PublicTransactionPoolAPI) sendIdempotent(ctx context.Context, args SendTxArgs, clientRequestId string, expires uint64) (common.Hash, error) {
+	if clientRequestId == "" {
+		return common.Hash{}, errors.New("clientRequestId can not be empty")
+	}
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
 	if err != nil {
 		return common.Hash{}, err
 	}
+	defer unlock()
 
-	// Assemble the transaction and sign with the wallet
-	tx, txt, err := args.toTransaction(state)
+	db := s.b.ChainDb()
+	id := crypto.Keccak256Hash(args.From[:], []byte(clientRequestId))
+	if request := s.clientRequest(id); request != nil {
+		log.Info("Transaction already sent for client request", "id", clientRequestId, "fullhash", request.Hash.Hex())
+		return request.Hash, nil
+	}
+	encrypted, err := s.assembleTransaction(ctx, args)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	if th, ok := s.b.GetEngin().(threaded); ok {
-		miner := s.b.GetMiner()
-		if miner.CanStart() {
-			miner.SetCanStart(0)
-			defer miner.SetCanStart(1)
+	// Record the request before submitting, a crash in between must not
+	// lead to a second transaction
+	rawdb.WriteClientRequest(db, id, &rawdb.ClientRequest{Hash: encrypted.Hash(), Expires: expires})
+	hash, err := submitTransaction(ctx, s.b, encrypted, args.To)
+	if err != nil {
+		rawdb.DeleteClientRequest(db, id)
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+// clientRequest returns the transaction sent for a client request id, nil if
+// the id expired or the transaction is neither pooled nor included in the
+// chain.
+func (s *PublicTransactionPoolAPI) clientRequest(id common.Hash) *rawdb.ClientRequest {
+	db := s.b.ChainDb()
+	request := rawdb.ReadClientRequest(db, id)
+	if request == nil {
+		return nil
+	}
+	if uint64(time.Now().Unix()) >= request.Expires {
+		rawdb.DeleteClientRequest(db, id)
+		return nil
+	}
+	if s.b.GetPoolTransaction(request.Hash) != nil {
+		return request
+	}
+	if blockHash, _, _ := rawdb.ReadTxLookupEntry(db, request.Hash); blockHash != (common.Hash{}) {
+		return request
+	}
+	log.Info("Transaction of client request dropped", "id", id, "fullhash", request.Hash.Hex())
+	return nil
+}
+
+// SendTxResult is the outcome of one of the transactions of SendTransactions.
+type SendTxResult struct {
+	Hash  *common.Hash `json:"hash,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// SendTransactions creates, signs and submits a transaction for each of the
+// given arguments. A failing item does not stop the others, the results
+// report the hash or the error of every item in order.
+func (s *PublicTransactionPoolAPI) SendTransactions(ctx context.Context, args []SendTxArgs) ([]SendTxResult, error) {
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer s.pauseMining()()
+
+	results := make([]SendTxResult, len(args))
+	for i := range args {
+		encrypted, err := s.encryptTransaction(ctx, args[i], state)
+		if err == nil {
+			var hash common.Hash
+			if hash, err = submitTransaction(ctx, s.b, encrypted, args[i].To); err == nil {
+				results[i].Hash = &hash
+			}
 		}
-		threads := th.Threads()
-		if threads >= 0 {
-			th.SetThreads(-1)
-			defer th.SetThreads(threads)
+		if err != nil {
+			results[i].Error = err.Error()
 		}
 	}
-	encrypted, err := wallet.EncryptTx(account, tx, txt, state)
+	return results, nil
+}
+
+// assembleTransaction creates the transaction for the given argument and
+// encrypts it with the wallet of the sender. The caller holds the nonceLock.
+func (s *PublicTransactionPoolAPI) assembleTransaction(ctx context.Context, args SendTxArgs) (*types.Transaction, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer s.pauseMining()()
+
+	return s.encryptTransaction(ctx, args, state)
+}
+
+// encryptTransaction creates the transaction for the given argument on top of
+// state and encrypts it with the wallet of the sender.
+func (s *PublicTransactionPoolAPI) encryptTransaction(ctx context.Context, args SendTxArgs, state *state.StateDB) (*types.Transaction, error) {
+	// Look up the wallet containing the requested abi
+	account := accounts.Account{Address: args.From}
+
+	wallet, err := scopeOf(ctx, s.b).find(account)
+	if err != nil {
+		return nil, err
+	}
+	if s.b.AccountManager().IsWatchOnly(account) {
+		return nil, accounts.ErrWatchOnly
+	}
+	if err := wallet.SendAllowed(account); err != nil {
+		return nil, err
+	}
+
+	// Set some sanity defaults and terminate on failure
+	if err := args.setDefaults(ctx, s.b); err != nil {
+		return nil, err
+	}
+	if !args.approved {
+		if err := s.spend.check(&args); err != nil {
+			return nil, err
+		}
+	}
+
+	// Assemble the transaction and sign with the wallet
+	tx, txt, err := args.toTransaction(state)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := args.encrypt(wallet, tx, txt, state)
+	if err != nil {
+		return nil, err
+	}
+	s.spend.record(&args)
+	return encrypted, nil
+}
+
+// sendApproved sends a held send approved through the policy API.
+func (s *PublicTransactionPoolAPI) sendApproved(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer unlock()
+
+	args.approved = true
+	encrypted, err := s.assembleTransaction(ctx, args)
 	if err != nil {
 		return common.Hash{}, err
 	}
 	return submitTransaction(ctx, s.b, encrypted, args.To)
 }
 
+// pauseMining keeps the local miner from competing with the proof generation
+// of transactions, the returned function resumes it.
+func (s *PublicTransactionPoolAPI) pauseMining() func() {
+	th, ok := s.b.GetEngin().(threaded)
+	if !ok {
+		return func() {}
+	}
+	miner := s.b.GetMiner()
+	canStart := miner.CanStart()
+	if canStart {
+		miner.SetCanStart(0)
+	}
+	threads := th.Threads()
+	if threads >= 0 {
+		th.SetThreads(-1)
+	}
+	return func() {
+		if threads >= 0 {
+			th.SetThreads(threads)
+		}
+		if canStart {
+			miner.SetCanStart(1)
+		}
+	}
+}
+
 func (s *PublicTransactionPoolAPI) ReSendTransaction(ctx context.Context, txhash common.Hash) (common.Hash, error) {
 
 	pending, err := s.b.GetPoolTransactions()
@@ -1610,25 +2845,115 @@ func (s *PublicTransactionPoolAPI) ReSendTransaction(ctx context.Context, txhash
 	return submitTransaction(ctx, s.b, tx, nil)
 }
 
+// CancelTransaction replaces a pooled transaction of a local account by one
+// paying the SERO outs it spends back to the account, at the gas price of the
+// pooled transaction raised by the price bump of the pool unless a non-zero
+// gasPrice is given. It returns the hash of the replacement.
+//
+// Only the SERO outs are spent again by the replacement, the token and ticket
+// outs of the cancelled transaction stay unspent and are free again once the
+// replacement is mined.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, hash common.Hash, gasPrice *hexutil.Big) (common.Hash, error) {
+	tx := s.b.GetPoolTransaction(hash)
+	if tx == nil {
+		return common.Hash{}, fmt.Errorf("transaction %x is not in the pool", hash)
+	}
+	spent := core.SpentKeys(tx)
+	if len(spent) == 0 {
+		return common.Hash{}, fmt.Errorf("transaction %x spends no outs", hash)
+	}
+
+	// Find the local account holding the SERO outs spent by the transaction,
+	// coin selection may only spend those
+	cy := utils.StringToUint256(params.DefaultCurrency)
+	var (
+		from    *common.AccountAddress
+		exclude []keys.Uint256
+		value   = new(big.Int)
+	)
+	for _, wallet := range scopeOf(ctx, s.b).wallets() {
+		account := wallet.Accounts()[0]
+		outs, err := txs.GetOuts(account.Tk.ToUint512())
+		if err != nil {
+			return common.Hash{}, err
+		}
+		exclude, value = exclude[:0], new(big.Int)
+		for _, out := range outs {
+			tkn := out.Out_O.Asset.Tkn
+			if tkn != nil && tkn.Currency == cy && (spent[out.Root] || spent[out.Trace]) {
+				value.Add(value, tkn.Value.ToIntRef())
+			} else {
+				exclude = append(exclude, out.Root)
+			}
+		}
+		if value.Sign() > 0 {
+			address := account.Address
+			from = &address
+			break
+		}
+	}
+	if from == nil {
+		return common.Hash{}, fmt.Errorf("transaction %x spends no %s outs of a local account", hash, params.DefaultCurrency)
+	}
+
+	price := (*big.Int)(gasPrice)
+	if price == nil || price.Sign() == 0 {
+		price = new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(100+s.b.PriceBump())))
+		price.Add(price, big.NewInt(99))
+		price.Div(price, big.NewInt(100))
+	}
+	gas := hexutil.Uint64(s.b.TxDefaults().sanitize().Gas)
+	value.Sub(value, new(big.Int).Mul(price, new(big.Int).SetUint64(uint64(gas))))
+	if value.Sign() <= 0 {
+		return common.Hash{}, errors.New("outs of the transaction are worth less than the fee of its replacement")
+	}
+	to := *from
+	minConf := hexutil.Uint64(0)
+	args := SendTxArgs{
+		From:        *from,
+		To:          &to,
+		Gas:         &gas,
+		GasCurrency: Smbol(params.DefaultCurrency),
+		GasPrice:    (*hexutil.Big)(price),
+		Value:       (*hexutil.Big)(value),
+		Currency:    Smbol(params.DefaultCurrency),
+		MinConf:     &minConf,
+		exclude:     exclude,
+	}
+	return s.SendTransaction(ctx, args)
+}
+
 func (s *PublicTransactionPoolAPI) CreatePkg(ctx context.Context, args SendTxArgs) (common.Hash, error) {
-	s.nonceLock.mu.Lock()
-	defer s.nonceLock.mu.Unlock()
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer unlock()
 	// Look up the wallet containing the requested abi
 	account := accounts.Account{Address: args.From}
 
-	wallet, err := s.b.AccountManager().Find(account)
+	wallet, err := scopeOf(ctx, s.b).find(account)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if err := wallet.SendAllowed(account); err != nil {
+		return common.Hash{}, err
+	}
 
 	if args.To == nil {
 		return common.Hash{}, errors.New("to can not be nil")
 	}
+	if len(args.Receptions) > 0 || len(args.Assets) > 0 {
+		return common.Hash{}, errors.New("packages can not be created with receptions or assets")
+	}
 
 	// Set some sanity defaults and terminate on failure
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
+	if err := s.spend.check(&args); err != nil {
+		return common.Hash{}, err
+	}
 
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
 
@@ -1653,10 +2978,11 @@ func (s *PublicTransactionPoolAPI) CreatePkg(ctx context.Context, args SendTxArg
 			defer th.SetThreads(threads)
 		}
 	}
-	encrypted, err := wallet.EncryptTx(account, tx, txt, state)
+	encrypted, err := args.encrypt(wallet, tx, txt, state)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	s.spend.record(&args)
 	return submitTransaction(ctx, s.b, encrypted, args.To)
 }
 
@@ -1666,13 +2992,30 @@ type ClosePkgArgs struct {
 	GasPrice *hexutil.Big           `json:"gasPrice"`
 	PkgId    *keys.Uint256          `json:"id"`
 	Key      *keys.Uint256          `json:"key"`
+	MinConf  *hexutil.Uint64        `json:"minConf"`  // default node policy
+	Preimage *keys.Uint256          `json:"preimage"` // opens the hashlock of the package
+
+	maxInNum  uint64
+	excluded  []keys.Uint256
+	selection string
+	refund    bool // close as the creator of an expired package
 }
 
 func (args *ClosePkgArgs) setDefaults(ctx context.Context, b Backend) error {
+	var err error
+	if args.maxInNum, err = maxInNum(b, args.MinConf); err != nil {
+		return err
+	}
+	if args.From != nil {
+		if args.excluded, err = excludedRoots(ctx, b, *args.From); err != nil {
+			return err
+		}
+	}
+	args.selection = b.TxDefaults().sanitize().Selection
 
 	if args.Gas == nil {
 		args.Gas = new(hexutil.Uint64)
-		*(*uint64)(args.Gas) = 90000
+		*(*uint64)(args.Gas) = b.TxDefaults().sanitize().Gas
 	}
 
 	if args.GasPrice == nil {
@@ -1706,23 +3049,32 @@ func (args *ClosePkgArgs) toTransaction(state *state.StateDB) (*types.Transactio
 			utils.StringToUint256(params.DefaultCurrency),
 			utils.U256(*fee),
 		},
-		PkgClose: &ztx.PkgClose{*args.PkgId, *args.Key},
+		PkgClose: &ztx.PkgClose{Id: *args.PkgId, Key: *args.Key, Refund: args.refund, Preimage: args.Preimage},
 	}
 	txt.Ehash = ehash
 	txt.FromRnd = keys.RandUint256().NewRef()
+	txt.MaxInNum = args.maxInNum
+	txt.Excluded = args.excluded
+	txt.Selection = args.selection
 	return tx, txt, nil
 }
 
 func (s *PublicTransactionPoolAPI) ClosePkg(ctx context.Context, args ClosePkgArgs) (common.Hash, error) {
-	s.nonceLock.mu.Lock()
-	defer s.nonceLock.mu.Unlock()
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer unlock()
 	// Look up the wallet containing the requested abi
 	account := accounts.Account{Address: *args.From}
 
-	wallet, err := s.b.AccountManager().Find(account)
+	wallet, err := scopeOf(ctx, s.b).find(account)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if err := wallet.SendAllowed(account); err != nil {
+		return common.Hash{}, err
+	}
 
 	// Set some sanity defaults and terminate on failure
 	if err := args.setDefaults(ctx, s.b); err != nil {
@@ -1765,12 +3117,27 @@ type TransferPkgArgs struct {
 	GasPrice *hexutil.Big           `json:"gasPrice"`
 	PkgId    *keys.Uint256          `json:"id"`
 	To       *common.AccountAddress `json:"To"`
+	MinConf  *hexutil.Uint64        `json:"minConf"` // default node policy
+
+	maxInNum  uint64
+	excluded  []keys.Uint256
+	selection string
 }
 
 func (args *TransferPkgArgs) setDefaults(ctx context.Context, b Backend) error {
+	var err error
+	if args.maxInNum, err = maxInNum(b, args.MinConf); err != nil {
+		return err
+	}
+	if args.From != nil {
+		if args.excluded, err = excludedRoots(ctx, b, *args.From); err != nil {
+			return err
+		}
+	}
+	args.selection = b.TxDefaults().sanitize().Selection
 	if args.Gas == nil {
 		args.Gas = new(hexutil.Uint64)
-		*(*uint64)(args.Gas) = 90000
+		*(*uint64)(args.Gas) = b.TxDefaults().sanitize().Gas
 	}
 
 	if args.GasPrice == nil {
@@ -1814,19 +3181,29 @@ func (args *TransferPkgArgs) toTransaction(state *state.StateDB) (*types.Transac
 	}
 	txt.Ehash = ehash
 	txt.FromRnd = keys.RandUint256().NewRef()
+	txt.AddRecipient(Pkr, args.To.ToUint512())
+	txt.MaxInNum = args.maxInNum
+	txt.Excluded = args.excluded
+	txt.Selection = args.selection
 	return tx, txt, nil
 }
 
 func (s *PublicTransactionPoolAPI) TransferPkg(ctx context.Context, args TransferPkgArgs) (common.Hash, error) {
-	s.nonceLock.mu.Lock()
-	defer s.nonceLock.mu.Unlock()
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer unlock()
 	// Look up the wallet containing the requested abi
 	account := accounts.Account{Address: *args.From}
 
-	wallet, err := s.b.AccountManager().Find(account)
+	wallet, err := scopeOf(ctx, s.b).find(account)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if err := wallet.SendAllowed(account); err != nil {
+		return common.Hash{}, err
+	}
 
 	// Set some sanity defaults and terminate on failure
 	if err := args.setDefaults(ctx, s.b); err != nil {
@@ -1873,8 +3250,11 @@ type EncryptTransactionResult struct {
 // The node needs to have the private key of the account corresponding with
 // the given from address and it needs to be unlocked.
 func (s *PublicTransactionPoolAPI) EncryptTransaction(ctx context.Context, args SendTxArgs) (*EncryptTransactionResult, error) {
-	s.nonceLock.mu.Lock()
-	defer s.nonceLock.mu.Unlock()
+	unlock, err := s.nonceLock.lockSends(ctx, s.b.ProofQueue())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
@@ -1899,7 +3279,7 @@ func (s *PublicTransactionPoolAPI) EncryptTransaction(ctx context.Context, args
 
 	account := accounts.Account{Address: args.From}
 
-	wallet, err := s.b.AccountManager().Find(account)
+	wallet, err := scopeOf(ctx, s.b).find(account)
 	if err != nil {
 		return nil, err
 	}
@@ -1915,7 +3295,7 @@ func (s *PublicTransactionPoolAPI) EncryptTransaction(ctx context.Context, args
 			defer th.SetThreads(threads)
 		}
 	}
-	signed, err := wallet.EncryptTx(account, tx, txt, state)
+	signed, err := args.encrypt(wallet, tx, txt, state)
 	if err != nil {
 		return nil, err
 	}
@@ -1928,14 +3308,15 @@ func (s *PublicTransactionPoolAPI) EncryptTransaction(ctx context.Context, args
 
 // PendingTransactions returns the transactions that are in the transaction pool
 // and have a from address that is one of the accounts this node manages.
-func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, error) {
+func (s *PublicTransactionPoolAPI) PendingTransactions(ctx context.Context) ([]*RPCTransaction, error) {
 	pending, err := s.b.GetPoolTransactions()
 	if err != nil {
 		return nil, err
 	}
+	wallets := scopeOf(ctx, s.b).wallets()
 	transactions := make([]*RPCTransaction, 0, len(pending))
 	for _, tx := range pending {
-		if fromAddr := getAddressByPkr(s.b.AccountManager().Wallets(), tx.From()); fromAddr != nil {
+		if fromAddr := getAddressByPkr(wallets, tx.From()); fromAddr != nil {
 			transactions = append(transactions, newRPCPendingTransaction(tx))
 		}
 	}
@@ -2031,11 +3412,96 @@ func (api *PrivateDebugAPI) ChaindbCompact() error {
 	return nil
 }
 
+// maxDbKeys is the maximum number of keys returned by a single debug_dbKeys call.
+const maxDbKeys = 1024
+
+// DbGet returns the raw value stored under the given key in the chain database.
+func (api *PrivateDebugAPI) DbGet(key hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.b.ChainDb().Get(key)
+}
+
+// DbKeys returns up to limit keys of the chain database starting with the given
+// prefix, in key order.
+func (api *PrivateDebugAPI) DbKeys(prefix hexutil.Bytes, limit int) ([]hexutil.Bytes, error) {
+	ldb, ok := api.b.ChainDb().(interface {
+		LDB() *leveldb.DB
+	})
+	if !ok {
+		return nil, fmt.Errorf("dbKeys does not work for memory databases")
+	}
+	if limit <= 0 || limit > maxDbKeys {
+		limit = maxDbKeys
+	}
+	it := ldb.LDB().NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	result := []hexutil.Bytes{}
+	for len(result) < limit && it.Next() {
+		result = append(result, common.CopyBytes(it.Key()))
+	}
+	return result, it.Error()
+}
+
 // SetHead rewinds the head of the blockchain to a previous block.
 func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
 	api.b.SetHead(uint64(number))
 }
 
+// PrivateLStateAPI provides maintenance methods for the local out database.
+type PrivateLStateAPI struct {
+	b Backend
+}
+
+// NewPrivateLStateAPI creates a new API definition for the local out database
+// maintenance methods.
+func NewPrivateLStateAPI(b Backend) *PrivateLStateAPI {
+	return &PrivateLStateAPI{b: b}
+}
+
+// Stats returns the size of the local out database and the progress of the
+// last repair run.
+func (api *PrivateLStateAPI) Stats() (lstate.Stats, error) {
+	return lstate.GetStats()
+}
+
+// Compact removes the local out state files of the orphaned blocks and
+// of the canonical ones outside the recent window and the checkpoints, and
+// returns the number of removed files.
+func (api *PrivateLStateAPI) Compact() (int, error) {
+	return lstate.Compact()
+}
+
+// Repair starts removing corrupt and orphaned local out state files in the
+// background, they are re-derived from the chain database afterwards.
+// Progress is reported by lstate_stats.
+func (api *PrivateLStateAPI) Repair() error {
+	return lstate.Repair()
+}
+
+// RescanFrom drops the local out state from the given block on and replays the
+// scanning of the chain from there. The state is shared by all local accounts,
+// use RescanAccount to rescan a single one.
+func (api *PrivateLStateAPI) RescanFrom(blockNum hexutil.Uint64) (int, error) {
+	return lstate.RescanFrom(uint64(blockNum))
+}
+
+// RescanAccount rebuilds the outs of a local account from the given block on
+// in the background, e.g. after its key was imported. Unlike RescanFrom the
+// outs of the other accounts are left alone. Progress is reported by
+// lstate_rescanStatus.
+func (api *PrivateLStateAPI) RescanAccount(ctx context.Context, address common.AccountAddress, fromBlock hexutil.Uint64) error {
+	wallet, err := scopeOf(ctx, api.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return fmt.Errorf("account %v: %v", address, err)
+	}
+	return lstate.RescanAccount(*wallet.Accounts()[0].Tk.ToUint512(), uint64(fromBlock))
+}
+
+// RescanStatus returns the progress of the last account rescan.
+func (api *PrivateLStateAPI) RescanStatus() lstate.RescanProgress {
+	return lstate.RescanStatus()
+}
+
 // PublicNetAPI offers network related RPC methods
 type PublicNetAPI struct {
 	net            *p2p.Server
@@ -44,9 +44,21 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*FeeHistory, error)
 	ChainDb() serodb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
+	RPCGasCap() uint64         // global gas cap for sero_call and sero_estimateGas, 0 if unlimited
+	RPCEstimateGasCap() uint64 // gas cap of sero_estimateGas, 0 if the global one applies
+	RPCCallCache() int         // number of sero_call results cached, 0 if disabled
+	GenesisSupply() *big.Int
+	TxDefaults() TxDefaults   // policies filling in unspecified transaction fields
+	SpendPolicy() SpendPolicy // per-account spend limits of locally signed transactions
+	ProofQueue() ProofQueue   // priorities and quotas of the callers sending transactions
+	Tenants() Tenants         // account groups isolated from each other, by name
+	Merge() MergeConfig       // automatic merging of the small outs of local accounts
+	RelayTargets() []string   // RPC endpoints of the gateway nodes broadcasting local transactions
+	SupplyIndex() *SupplyIndex
 
 	// BlockChain API
 	SetHead(number uint64)
@@ -68,6 +80,7 @@ type Backend interface {
 	//GetPoolNonce(ctx context.Context, addr common.Data) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (types.Transactions, types.Transactions)
+	PriceBump() uint64 // gas price increase in percent replacing pooled transactions spending the same outs
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
@@ -76,8 +89,9 @@ type Backend interface {
 	GetMiner() *miner.Miner
 }
 
-func GetAPIs(apiBackend Backend) []rpc.API {
-	nonceLock := new(AddrLocker)
+func GetAPIs(apiBackend Backend, pool *PublicTransactionPoolAPI, scheduler *Scheduler) []rpc.API {
+	nonceLock, spend := pool.nonceLock, pool.spend
+	personal := NewPrivateAccountAPI(apiBackend, nonceLock, spend)
 	return []rpc.API{
 		{
 			Namespace: "sero",
@@ -92,7 +106,12 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "sero",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   pool,
+			Public:    true,
+		}, {
+			Namespace: "sero",
+			Version:   APIVersion,
+			Service:   NewPublicStableAPI(apiBackend, nonceLock, spend),
 			Public:    true,
 		}, {
 			Namespace: "txpool",
@@ -111,12 +130,32 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "sero",
 			Version:   "1.0",
-			Service:   NewPublicAccountAPI(apiBackend.AccountManager()),
+			Service:   NewPublicAccountAPI(apiBackend),
 			Public:    true,
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
-			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
+			Service:   personal,
+			Public:    false,
+		}, {
+			Namespace: "session",
+			Version:   "1.0",
+			Service:   NewPrivateSessionAPI(apiBackend, personal),
+			Public:    false,
+		}, {
+			Namespace: "policy",
+			Version:   "1.0",
+			Service:   NewPrivatePolicyAPI(apiBackend, pool),
+			Public:    false,
+		}, {
+			Namespace: "scheduler",
+			Version:   "1.0",
+			Service:   NewPrivateSchedulerAPI(scheduler),
+			Public:    false,
+		}, {
+			Namespace: "lstate",
+			Version:   "1.0",
+			Service:   NewPrivateLStateAPI(apiBackend),
 			Public:    false,
 		},
 	}
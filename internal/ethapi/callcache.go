@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// callCache holds the results of recent sero_call requests. Entries are keyed
+// by the block they ran against, so they stay valid, but the cache is purged
+// on every new head to keep it filled with the calls of the latest block.
+type callCache struct {
+	lock    sync.Mutex
+	head    common.Hash // chain head when the cached results were added
+	results *lru.Cache
+}
+
+// newCallCache returns a cache of size results, nil if size is not positive.
+func newCallCache(size int) *callCache {
+	if size <= 0 {
+		return nil
+	}
+	results, _ := lru.New(size)
+	return &callCache{results: results}
+}
+
+// sync purges the cache if the chain head moved.
+func (c *callCache) sync(head common.Hash) {
+	if c.head != head {
+		c.results.Purge()
+		c.head = head
+	}
+}
+
+func (c *callCache) get(head, key common.Hash) (hexutil.Bytes, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sync(head)
+	if result, ok := c.results.Get(key); ok {
+		return result.(hexutil.Bytes), true
+	}
+	return nil, false
+}
+
+func (c *callCache) add(head, key common.Hash, result hexutil.Bytes) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sync(head)
+	c.results.Add(key, result)
+}
+
+// callKey identifies a call by the block it runs against and its arguments.
+func callKey(block common.Hash, args CallArgs) common.Hash {
+	enc, _ := json.Marshal(args)
+	return crypto.Keccak256Hash(block[:], enc)
+}
+
+// cachedCall runs the call through the cache. Only calls of contracts with
+// static parameters are cached, the others use random one-time addresses.
+func (s *PublicBlockChainAPI) cachedCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	if blockNr == rpc.PendingBlockNumber || args.To == nil || args.Dynamic {
+		return s.call(ctx, args, blockNr)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if !state.IsContract(common.BytesToAddress(args.To[:])) {
+		return s.call(ctx, args, blockNr)
+	}
+	head, key := s.b.CurrentBlock().Hash(), callKey(header.Hash(), args)
+	if result, ok := s.calls.get(head, key); ok {
+		return result, nil
+	}
+	result, err := s.call(ctx, args, rpc.BlockNumber(header.Number.Int64()))
+	if err == nil {
+		s.calls.add(head, key, result)
+	}
+	return result, err
+}
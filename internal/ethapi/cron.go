@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed cron specification of five fields: minute, hour, day
+// of month, month and day of week. The fields are "*", numbers, ranges
+// ("1-5"), steps ("*/15", "0-30/10") and lists of them ("1,15"). Times are
+// UTC.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64 // bit sets of the allowed values
+	anyDom, anyDow                bool
+}
+
+// cronField is the range of values of a field of a cron specification.
+type cronField struct {
+	name     string
+	min, max uint
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseCron parses a cron specification.
+func parseCron(spec string) (*cronSpec, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron spec %q must have %d fields", spec, len(cronFields))
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &cronSpec{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns the bit set of the values of a field.
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, step := part, uint64(1)
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", bounds.name, field)
+			}
+			expr, step = part[:i], n
+		}
+		low, high := uint64(bounds.min), uint64(bounds.max)
+		if expr != "*" {
+			ends := strings.SplitN(expr, "-", 2)
+			n, err := strconv.ParseUint(ends[0], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", bounds.name, field)
+			}
+			low, high = n, n
+			if len(ends) == 2 {
+				if high, err = strconv.ParseUint(ends[1], 10, 8); err != nil {
+					return 0, fmt.Errorf("invalid range in %s field %q", bounds.name, field)
+				}
+			} else if step > 1 {
+				high = uint64(bounds.max)
+			}
+		}
+		if low < uint64(bounds.min) || high > uint64(bounds.max) || low > high {
+			return 0, fmt.Errorf("%s field %q out of range %d-%d", bounds.name, field, bounds.min, bounds.max)
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// next returns the first time after t matching the specification, the zero
+// time if there is none within five years.
+func (c *cronSpec) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether the day of t is allowed. As in cron, a day matches
+// either of the day of month and day of week fields when both are
+// restricted.
+func (c *cronSpec) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
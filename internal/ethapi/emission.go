@@ -0,0 +1,151 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// maxEmissionSamples is the maximum number of blocks sampled by a single
+// emission schedule request.
+const maxEmissionSamples = 1024
+
+// EmissionEntry is the scheduled emission of a single block.
+type EmissionEntry struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Projected bool           `json:"projected"` // whether the block is past the current head
+	Reward    hexutil.Big    `json:"reward"`
+	Community hexutil.Big    `json:"community"`
+	Team      hexutil.Big    `json:"team"`
+	Issuance  hexutil.Big    `json:"issuance"` // total supply after the block
+}
+
+// rewardSegmentEnd returns the last block sharing the rewards of block n at a
+// constant difficulty and gas usage.
+func rewardSegmentEnd(n uint64) uint64 {
+	halve, epoch := halveNimber.Uint64(), interval.Uint64()
+	switch {
+	case n < ethash.V2Number:
+		return ethash.V2Number - 1
+	case n < halve:
+		return halve - 1
+	default:
+		return halve + ((n-halve)/epoch+1)*epoch - 1
+	}
+}
+
+// projection advances the supply past the current head, assuming every block
+// has the difficulty and gas usage of the head.
+type projection struct {
+	head   *types.Header
+	number uint64
+	total  *big.Int
+	paid   *big.Int
+}
+
+func (p *projection) advance(to uint64) {
+	for p.number < to {
+		n := p.number + 1
+		end := rewardSegmentEnd(n)
+		if end > to {
+			end = to
+		}
+		count := new(big.Int).SetUint64(end - n + 1)
+		rewards := GetBlockReward(new(big.Int).SetUint64(n), p.head.Difficulty, p.head.GasUsed, p.head.GasLimit)
+		if n < ethash.V2Number {
+			p.paid.Add(p.paid, new(big.Int).Mul(rewards[0], count))
+			if p.paid.Cmp(core.GenesisRewardPool) > 0 {
+				p.paid.Set(core.GenesisRewardPool)
+			}
+		} else {
+			issued := new(big.Int).Add(rewards[0], rewards[1])
+			issued.Add(issued, rewards[2])
+			p.total.Add(p.total, issued.Mul(issued, count))
+		}
+		p.number = end
+	}
+}
+
+// EmissionSchedule returns the block rewards and the cumulative issuance of
+// every step-th block in the range. Blocks past the current head are projected
+// with the difficulty and gas usage of the head.
+func (s *PublicBlockChainAPI) EmissionSchedule(ctx context.Context, fromBlock, toBlock, step hexutil.Uint64) ([]*EmissionEntry, error) {
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if to < from {
+		return nil, fmt.Errorf("invalid range: %d > %d", from, to)
+	}
+	if step == 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if samples := (to-from)/uint64(step) + 1; samples > maxEmissionSamples {
+		return nil, fmt.Errorf("range of %d samples exceeds the limit of %d", samples, maxEmissionSamples)
+	}
+	head, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil || err != nil {
+		return nil, err
+	}
+	var (
+		entries []*EmissionEntry
+		proj    *projection
+	)
+	for n := from; n <= to; n += uint64(step) {
+		entry := &EmissionEntry{Number: hexutil.Uint64(n)}
+		if n <= head.Number.Uint64() {
+			header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(n))
+			if header == nil || err != nil {
+				return nil, fmt.Errorf("block %d not found", n)
+			}
+			info, err := s.supplyAt(ctx, rpc.BlockNumber(n))
+			if err != nil {
+				return nil, err
+			}
+			rewards := [3]*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+			if n > 0 {
+				rewards = GetBlockReward(header.Number, header.Difficulty, header.GasUsed, header.GasLimit)
+			}
+			entry.Reward, entry.Community, entry.Team = hexutil.Big(*rewards[0]), hexutil.Big(*rewards[1]), hexutil.Big(*rewards[2])
+			entry.Issuance = hexutil.Big(*info.total)
+		} else {
+			if proj == nil {
+				info, err := s.supplyAt(ctx, rpc.BlockNumber(head.Number.Uint64()))
+				if err != nil {
+					return nil, err
+				}
+				proj = &projection{head: head, number: head.Number.Uint64(), total: info.total, paid: info.paid}
+			}
+			proj.advance(n)
+			rewards := GetBlockReward(new(big.Int).SetUint64(n), head.Difficulty, head.GasUsed, head.GasLimit)
+			entry.Projected = true
+			entry.Reward, entry.Community, entry.Team = hexutil.Big(*rewards[0]), hexutil.Big(*rewards[1]), hexutil.Big(*rewards[2])
+			entry.Issuance = hexutil.Big(*new(big.Int).Set(proj.total))
+		}
+		entries = append(entries, entry)
+		if n+uint64(step) < n {
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/txs"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// FeeEstimate is the cost of a transaction and the descriptors the wallet
+// generates for it. Shielded descriptors are not charged gas, they are
+// reported as they determine the size of the transaction and its proving time.
+type FeeEstimate struct {
+	Gas         hexutil.Uint64 `json:"gas"`
+	GasPrice    *hexutil.Big   `json:"gasPrice"`
+	GasCurrency Smbol          `json:"gasCy"`
+	Fee         *hexutil.Big   `json:"fee"`
+	Ins         hexutil.Uint64 `json:"ins"`   // outs of the sender, and the sponsor, spent as inputs
+	OutsZ       hexutil.Uint64 `json:"outsZ"` // shielded outs, including the change
+	OutsO       hexutil.Uint64 `json:"outsO"` // transparent outs
+}
+
+// EstimateFee returns the fee of sending a transaction and the number of ins
+// and outs the wallet generates for it, selecting the inputs the way sending
+// it would. Token and ticket transfers are covered as well as contract calls.
+func (s *PublicTransactionPoolAPI) EstimateFee(ctx context.Context, args SendTxArgs) (*FeeEstimate, error) {
+	if err := args.setDefaults(ctx, s.b); err != nil {
+		return nil, err
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	_, txt, err := args.toTransaction(state)
+	if err != nil {
+		return nil, err
+	}
+	costTkn := txt.TokenCost()
+	costTkt := txt.TikectCost()
+
+	var ins, changes int
+	if args.Sponsor != nil {
+		cost := costTkn[txt.Fee.Currency]
+		cost.SubU(&txt.Fee.Value)
+		if cost.Cmp(&utils.U256_0) > 0 {
+			costTkn[txt.Fee.Currency] = cost
+		} else {
+			delete(costTkn, txt.Fee.Currency)
+		}
+		fee := map[keys.Uint256]utils.U256{txt.Fee.Currency: txt.Fee.Value}
+		roots, change, err := s.selectInputs(ctx, *args.Sponsor, fee, nil, txt.MaxInNum, txt.Excluded, txt.Selection)
+		if err != nil {
+			return nil, err
+		}
+		ins, changes = ins+roots, changes+change
+	}
+	roots, change, err := s.selectInputs(ctx, args.From, costTkn, costTkt, txt.MaxInNum, txt.Excluded, txt.Selection)
+	if err != nil {
+		return nil, err
+	}
+	ins, changes = ins+roots, changes+change
+
+	estimate := &FeeEstimate{
+		Gas:         *args.Gas,
+		GasPrice:    args.GasPrice,
+		GasCurrency: args.GasCurrency,
+		Fee:         (*hexutil.Big)(new(big.Int).Mul(args.GasPrice.ToInt(), new(big.Int).SetUint64(uint64(*args.Gas)))),
+		Ins:         hexutil.Uint64(ins),
+		OutsZ:       hexutil.Uint64(changes),
+	}
+	for _, out := range txt.Outs {
+		if out.IsZ {
+			estimate.OutsZ++
+		} else {
+			estimate.OutsO++
+		}
+	}
+	return estimate, nil
+}
+
+// selectInputs returns the number of outs of an account selected to pay the
+// costs and the number of change outs paid back to it.
+func (s *PublicTransactionPoolAPI) selectInputs(ctx context.Context, address common.AccountAddress, costTkn map[keys.Uint256]utils.U256, costTkt map[keys.Uint256][]keys.Uint256, maxInNum uint64, excluded []keys.Uint256, selection string) (int, int, error) {
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return 0, 0, err
+	}
+	roots, tknMap, tktMap, err := txs.GetRoots(wallet.Accounts()[0].Tk.ToUint512(), costTkn, costTkt, maxInNum, excluded, selection)
+	if err != nil {
+		return 0, 0, err
+	}
+	changes := len(tknMap)
+	for _, tkts := range tktMap {
+		changes += len(tkts)
+	}
+	return len(roots), changes, nil
+}
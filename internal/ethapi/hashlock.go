@@ -0,0 +1,67 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+)
+
+// CreateHashlockPkg creates a package its owner closes only by revealing the
+// preimage of args.Hashlock, the building block of atomic swaps: the owner
+// learns the key of the package from its creator and, closing it, publishes
+// the preimage the creator unlocks the counterpart with, on this or another
+// chain. Together with a pkgExpiry the creator refunds the package if the
+// swap does not complete.
+func (s *PublicTransactionPoolAPI) CreateHashlockPkg(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	if args.Hashlock == nil || *args.Hashlock == keys.Empty_Uint256 {
+		return common.Hash{}, errors.New("hashlock can not be empty")
+	}
+	return s.CreatePkg(ctx, args)
+}
+
+// CloseWithPreimage closes a hashlocked package revealing the preimage of its
+// hashlock.
+func (s *PublicTransactionPoolAPI) CloseWithPreimage(ctx context.Context, args ClosePkgArgs) (common.Hash, error) {
+	if args.PkgId == nil {
+		return common.Hash{}, errors.New("id can not be nil")
+	}
+	if args.Preimage == nil {
+		return common.Hash{}, errors.New("preimage can not be nil")
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	pg := state.GetZState().Pkgs.GetPkg(args.PkgId)
+	if pg == nil {
+		return common.Hash{}, fmt.Errorf("package %v not found", hexutil.Encode(args.PkgId[:]))
+	}
+	terms := pg.Pack.GetTerms()
+	if terms.Hashlock == keys.Empty_Uint256 {
+		return common.Hash{}, fmt.Errorf("package %v has no hashlock", hexutil.Encode(args.PkgId[:]))
+	}
+	if !terms.Unlocks(args.Preimage) {
+		return common.Hash{}, errors.New("preimage does not open the hashlock")
+	}
+	return s.ClosePkg(ctx, args)
+}
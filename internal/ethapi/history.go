@@ -0,0 +1,304 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+const (
+	// defaultHistoryPageSize is the number of entries returned by a
+	// sero_getTransactionsByAccount call without page size.
+	defaultHistoryPageSize = 100
+
+	// maxHistoryPageSize is the largest page size accepted.
+	maxHistoryPageSize = 1000
+)
+
+// AccountTransaction is the part a local account took in a transaction. Outs
+// not created by a transaction output, like mining rewards, contract payments
+// or closed packages, are grouped per block without transaction hash.
+type AccountTransaction struct {
+	BlockNumber  hexutil.Uint64           `json:"blockNumber"`
+	BlockHash    common.Hash              `json:"blockHash"`
+	TxHash       *common.Hash             `json:"transactionHash"`
+	TxIndex      *hexutil.Uint            `json:"transactionIndex"`
+	ReceivedOuts hexutil.Uint64           `json:"receivedOuts"`
+	SpentOuts    hexutil.Uint64           `json:"spentOuts"`
+	Received     map[string]*hexutil.Big  `json:"received"`
+	Sent         map[string]*hexutil.Big  `json:"sent"`
+	ReceivedTkts map[string][]common.Hash `json:"receivedTkts,omitempty"`
+	SentTkts     map[string][]common.Hash `json:"sentTkts,omitempty"`
+}
+
+// AccountHistory is a page of the transaction history of an account. Next is
+// the cursor of the following page, nil on the last one.
+type AccountHistory struct {
+	Transactions []*AccountTransaction `json:"transactions"`
+	Next         *string               `json:"next"`
+}
+
+// GetTransactionsByAccount returns the transactions of a local account in the
+// given range of blocks, in chain order. The history is indexed as the outs of
+// the account are scanned, so it starts with the first block scanned after
+// the index was introduced. The cursor is the one returned with the previous
+// page.
+func (s *PublicBlockChainAPI) GetTransactionsByAccount(ctx context.Context, address common.AccountAddress, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber, pageSize *hexutil.Uint64, cursor *string) (*AccountHistory, error) {
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return nil, errors.New("local outs are not scanned yet")
+	}
+	size := uint64(defaultHistoryPageSize)
+	if pageSize != nil {
+		size = uint64(*pageSize)
+	}
+	if size == 0 || size > maxHistoryPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d", maxHistoryPageSize)
+	}
+	from, err := s.resolveBlockNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolveBlockNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	var skip uint64
+	if cursor != nil {
+		var num uint64
+		if _, err := fmt.Sscanf(*cursor, "%d:%d", &num, &skip); err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", *cursor)
+		}
+		if num < from {
+			skip = 0
+		} else {
+			from = num
+		}
+	}
+
+	history := &AccountHistory{Transactions: []*AccountTransaction{}}
+	for _, change := range st1.GetHistory(wallet.Accounts()[0].Tk.ToUint512(), from, to) {
+		entries, err := s.accountTransactions(ctx, change)
+		if err != nil {
+			return nil, err
+		}
+		start := uint64(0)
+		if change.Num == from {
+			start = skip
+		}
+		for i := start; i < uint64(len(entries)); i++ {
+			if uint64(len(history.Transactions)) == size {
+				next := fmt.Sprintf("%d:%d", change.Num, i)
+				history.Next = &next
+				return history, nil
+			}
+			history.Transactions = append(history.Transactions, entries[i])
+		}
+	}
+	return history, nil
+}
+
+// resolveBlockNumber returns the number of the given block.
+func (s *PublicBlockChainAPI) resolveBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) (uint64, error) {
+	if blockNr >= 0 {
+		return uint64(blockNr), nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %d not found", blockNr)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// accountTransactions assigns the out events of an account in a block to the
+// transactions of the block they were part of.
+func (s *PublicBlockChainAPI) accountTransactions(ctx context.Context, change *lstate.BalanceChange) ([]*AccountTransaction, error) {
+	block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(change.Num))
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", change.Num)
+	}
+	events := make(map[keys.Uint256][]int)
+	for i, event := range change.Events {
+		for _, key := range event.Keys {
+			events[key] = append(events[key], i)
+		}
+	}
+	assigned := make([]bool, len(change.Events))
+	newEntry := func() *AccountTransaction {
+		return &AccountTransaction{
+			BlockNumber: hexutil.Uint64(change.Num),
+			BlockHash:   block.Hash(),
+			Received:    map[string]*hexutil.Big{},
+			Sent:        map[string]*hexutil.Big{},
+		}
+	}
+	claim := func(entry *AccountTransaction, key keys.Uint256) {
+		for _, i := range events[key] {
+			if !assigned[i] {
+				assigned[i] = true
+				addOutEvent(entry, &change.Events[i])
+				return
+			}
+		}
+	}
+
+	entries := []*AccountTransaction{}
+	for index, tx := range block.Transactions() {
+		stxt := tx.GetZZSTX()
+		if stxt == nil {
+			continue
+		}
+		entry := newEntry()
+		for i := range stxt.Desc_O.Ins {
+			claim(entry, stxt.Desc_O.Ins[i].Root)
+		}
+		for i := range stxt.Desc_Z.Ins {
+			claim(entry, stxt.Desc_Z.Ins[i].Trace)
+		}
+		for i := range stxt.Desc_O.Outs {
+			claim(entry, stxt.Desc_O.Outs[i].ToHash())
+		}
+		for i := range stxt.Desc_Z.Outs {
+			claim(entry, stxt.Desc_Z.Outs[i].OutCM)
+		}
+		if entry.ReceivedOuts+entry.SpentOuts == 0 {
+			continue
+		}
+		hash, txIndex := tx.Hash(), hexutil.Uint(index)
+		entry.TxHash, entry.TxIndex = &hash, &txIndex
+		entries = append(entries, entry)
+	}
+	entry := newEntry()
+	for i := range change.Events {
+		if !assigned[i] {
+			addOutEvent(entry, &change.Events[i])
+		}
+	}
+	if entry.ReceivedOuts+entry.SpentOuts > 0 {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func addOutEvent(entry *AccountTransaction, event *lstate.OutEvent) {
+	tkns, tkts := entry.Received, &entry.ReceivedTkts
+	if event.Spent {
+		entry.SpentOuts++
+		tkns, tkts = entry.Sent, &entry.SentTkts
+	} else {
+		entry.ReceivedOuts++
+	}
+	if event.Tkn != nil {
+		addBalance(tkns, strings.Trim(string(event.Tkn.Currency[:]), zerobyte), event.Tkn.Value.ToIntRef())
+	}
+	if event.Tkt != nil {
+		if *tkts == nil {
+			*tkts = map[string][]common.Hash{}
+		}
+		catg := strings.Trim(string(event.Tkt.Category[:]), zerobyte)
+		(*tkts)[catg] = append((*tkts)[catg], common.BytesToHash(event.Tkt.Value[:]))
+	}
+}
+
+// AccountBlockTransaction is a transaction of a block as seen by a local
+// account: its asset deltas and the outs it received, decrypted with their
+// memos.
+type AccountBlockTransaction struct {
+	*AccountTransaction
+	Outs []*DecryptedOut `json:"outs"`
+}
+
+// AccountBlock is a block reduced to the transactions relevant to a local
+// account.
+type AccountBlock struct {
+	Number       hexutil.Uint64             `json:"number"`
+	Hash         common.Hash                `json:"hash"`
+	ParentHash   common.Hash                `json:"parentHash"`
+	Timestamp    *hexutil.Big               `json:"timestamp"`
+	Account      common.AccountAddress      `json:"account"`
+	Transactions []*AccountBlockTransaction `json:"transactions"`
+}
+
+// GetBlockForAccount returns a block with only the transactions a local
+// account took part in, each with the assets the account received and sent
+// and its decrypted outs. Like sero_getTransactionsByAccount it relies on the
+// history index, blocks not scanned yet have no transactions.
+func (s *PublicBlockChainAPI) GetBlockForAccount(ctx context.Context, blockNr rpc.BlockNumber, address common.AccountAddress) (*AccountBlock, error) {
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return nil, errors.New("local outs are not scanned yet")
+	}
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, nil
+	}
+	result := &AccountBlock{
+		Number:       hexutil.Uint64(block.NumberU64()),
+		Hash:         block.Hash(),
+		ParentHash:   block.ParentHash(),
+		Timestamp:    (*hexutil.Big)(block.Time()),
+		Account:      address,
+		Transactions: []*AccountBlockTransaction{},
+	}
+	wallets := []accounts.Wallet{wallet}
+	txs := block.Transactions()
+	for _, change := range st1.GetHistory(wallet.Accounts()[0].Tk.ToUint512(), block.NumberU64(), block.NumberU64()) {
+		entries, err := s.accountTransactions(ctx, change)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.BlockHash != block.Hash() {
+				continue
+			}
+			btx := &AccountBlockTransaction{AccountTransaction: entry, Outs: []*DecryptedOut{}}
+			if entry.TxIndex != nil {
+				tx := txs[*entry.TxIndex]
+				btx.Outs = decryptOuts(wallets, tx, tx.GetZZSTX())
+			}
+			result.Transactions = append(result.Transactions, btx)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs"
+)
+
+// Memos longer than the memo field of an output are carried as an encrypted
+// payload in the data of the transfer. The memo field then holds
+//
+//	magic (4 bytes) | payload key (32 bytes) | payload hash (28 bytes)
+//
+// and is encrypted for the receiver like any other memo, so only the receiver
+// learns the key opening the payload. The payload itself is
+//
+//	magic (4 bytes) | nonce (12 bytes) | AES-256-GCM sealed memo
+const (
+	// maxExtendedMemoLength is the hard limit of memos carried as payload.
+	maxExtendedMemoLength = 4096
+
+	memoKeyLength   = 32
+	memoNonceLength = 12
+	memoHashLength  = maxMemoLength - len(extendedMemoMagic) - memoKeyLength
+)
+
+var extendedMemoMagic = [4]byte{'S', 'M', 'X', 1}
+
+// extendedMemoPayloadSize returns the size of the payload carrying a memo of
+// the given length.
+func extendedMemoPayloadSize(length int) int {
+	return len(extendedMemoMagic) + memoNonceLength + length + 16
+}
+
+// sealExtendedMemo encrypts memo with a fresh key, returning the memo field
+// of the output and the payload to put into the transaction data.
+func sealExtendedMemo(memo []byte) (field string, payload []byte, err error) {
+	key := make([]byte, memoKeyLength)
+	nonce := make([]byte, memoNonceLength)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return "", nil, err
+	}
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", nil, err
+	}
+	gcm, err := newMemoCipher(key)
+	if err != nil {
+		return "", nil, err
+	}
+	payload = append(append(extendedMemoMagic[:], nonce...), gcm.Seal(nil, nonce, memo, nil)...)
+
+	head := make([]byte, 0, maxMemoLength)
+	head = append(head, extendedMemoMagic[:]...)
+	head = append(head, key...)
+	head = append(head, crypto.Keccak256(payload)[:memoHashLength]...)
+	return string(head), payload, nil
+}
+
+// openExtendedMemo decrypts the payload referenced by the memo field of an
+// output. It fails if the payload does not belong to the field.
+func openExtendedMemo(field keys.Uint512, payload []byte) ([]byte, error) {
+	if !isExtendedMemo(field) {
+		return nil, errors.New("not an extended memo")
+	}
+	if !matchExtendedMemo(field, payload) {
+		return nil, errors.New("memo payload mismatch")
+	}
+	key := field[len(extendedMemoMagic) : len(extendedMemoMagic)+memoKeyLength]
+	gcm, err := newMemoCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := payload[len(extendedMemoMagic) : len(extendedMemoMagic)+memoNonceLength]
+	return gcm.Open(nil, nonce, payload[len(extendedMemoMagic)+memoNonceLength:], nil)
+}
+
+// isExtendedMemo reports whether the memo field of an output references a
+// payload.
+func isExtendedMemo(field keys.Uint512) bool {
+	return bytes.HasPrefix(field[:], extendedMemoMagic[:])
+}
+
+// matchExtendedMemo reports whether payload is the one referenced by field.
+func matchExtendedMemo(field keys.Uint512, payload []byte) bool {
+	if len(payload) < extendedMemoPayloadSize(0) || !bytes.HasPrefix(payload, extendedMemoMagic[:]) {
+		return false
+	}
+	return bytes.Equal(field[maxMemoLength-memoHashLength:], crypto.Keccak256(payload)[:memoHashLength])
+}
+
+func newMemoCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// StructuredMemo is the conventional JSON layout of memos, letting wallets and
+// exchanges attach typed data such as invoice or deposit references.
+type StructuredMemo struct {
+	Type    string          `json:"type"`
+	Ref     string          `json:"ref,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// parseStructuredMemo returns the structured form of memo, or nil if memo
+// does not follow the convention.
+func parseStructuredMemo(memo []byte) *StructuredMemo {
+	if len(memo) == 0 || memo[0] != '{' {
+		return nil
+	}
+	var sm StructuredMemo
+	if err := json.Unmarshal(memo, &sm); err != nil || sm.Type == "" {
+		return nil
+	}
+	return &sm
+}
+
+// DecryptedMemo is the memo of an unspent output of a local account.
+type DecryptedMemo struct {
+	Root       keys.Uint256    `json:"root"`
+	Number     hexutil.Uint64  `json:"blockNumber"`
+	TxHash     *common.Hash    `json:"transactionHash,omitempty"`
+	Extended   bool            `json:"extended"`
+	Memo       hexutil.Bytes   `json:"memo"`
+	Text       string          `json:"text,omitempty"`
+	Structured *StructuredMemo `json:"structured,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// GetMemos returns the decrypted memos of the unspent outputs of a local
+// account. Extended memos are resolved from the payload of the transaction
+// creating the output and structured memos are parsed.
+func (s *PublicBlockChainAPI) GetMemos(ctx context.Context, address common.AccountAddress) ([]*DecryptedMemo, error) {
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	tk := wallet.Accounts()[0].Tk
+	outs, err := txs.GetOuts(tk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	blocks := make(map[uint64]*types.Block)
+	result := []*DecryptedMemo{}
+	for _, out := range outs {
+		field := out.Out_O.Memo
+		if field == (keys.Uint512{}) {
+			continue
+		}
+		dm := &DecryptedMemo{Root: out.Root, Number: hexutil.Uint64(out.Num)}
+		if isExtendedMemo(field) {
+			dm.Extended = true
+			block, ok := blocks[out.Num]
+			if !ok {
+				if block, err = s.b.BlockByNumber(ctx, rpc.BlockNumber(out.Num)); err != nil {
+					return nil, err
+				}
+				blocks[out.Num] = block
+			}
+			if err := resolveExtendedMemo(dm, field, block); err != nil {
+				dm.Error = err.Error()
+			}
+		} else {
+			dm.Memo = bytes.TrimLeft(field[:], zerobyte)
+		}
+		if isPrintable(dm.Memo) {
+			dm.Text = string(dm.Memo)
+		}
+		dm.Structured = parseStructuredMemo(dm.Memo)
+		result = append(result, dm)
+	}
+	return result, nil
+}
+
+// resolveExtendedMemo looks up the payload referenced by field in the
+// transactions of block and stores the decrypted memo in dm.
+func resolveExtendedMemo(dm *DecryptedMemo, field keys.Uint512, block *types.Block) error {
+	if block == nil {
+		return fmt.Errorf("block %d not found", dm.Number)
+	}
+	for _, tx := range block.Transactions() {
+		if !matchExtendedMemo(field, tx.Data()) {
+			continue
+		}
+		hash := tx.Hash()
+		dm.TxHash = &hash
+		memo, err := openExtendedMemo(field, tx.Data())
+		if err != nil {
+			return err
+		}
+		dm.Memo = memo
+		return nil
+	}
+	return errors.New("memo payload not found")
+}
+
+func isPrintable(b []byte) bool {
+	for _, c := range string(b) {
+		if c == 0xfffd || (c < 0x20 && c != '\n' && c != '\t' && c != '\r') {
+			return false
+		}
+	}
+	return true
+}
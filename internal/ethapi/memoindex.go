@@ -0,0 +1,387 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// The memo index keeps the memos received by every local account in buckets
+// of memoBucketBlocks blocks, each sealed with AES-256-GCM under a key derived
+// from the tk of the account. The database reveals which accounts received
+// memos in which buckets, not the memos nor the outs carrying them.
+const (
+	// memoIndexConfirmations is the depth blocks are indexed at, so that the
+	// index need not be rolled back on reorgs.
+	memoIndexConfirmations = 12
+
+	memoBucketBlocks = 1024
+
+	// maxMemoMatches is the number of matches memoindex_search returns at most.
+	maxMemoMatches = 1000
+)
+
+var (
+	memoIndexPrefix    = []byte("memoindex-")
+	memoBucketPrefix   = []byte("memoindex-b") // + account + bucket number -> sealed entries
+	memoProgressPrefix = []byte("memoindex-p") // + account -> next block to index
+)
+
+// indexedMemo is a memo received by an account, as stored in the index.
+type indexedMemo struct {
+	Number   uint64
+	TxHash   common.Hash
+	Index    uint32
+	Z        bool
+	Currency string
+	Value    *big.Int
+	Memo     []byte
+}
+
+// MemoIndex indexes the memos received by the local accounts as blocks get
+// confirmed. Accounts added later are indexed from the genesis block on.
+type MemoIndex struct {
+	b    Backend
+	db   serodb.Database
+	quit chan chan struct{}
+}
+
+// NewMemoIndex creates the memo index stored in db.
+func NewMemoIndex(b Backend, db serodb.Database) *MemoIndex {
+	return &MemoIndex{b: b, db: db, quit: make(chan chan struct{})}
+}
+
+// Start starts indexing the blocks in the background.
+func (m *MemoIndex) Start() {
+	go m.loop()
+}
+
+// Stop stops the indexing.
+func (m *MemoIndex) Stop() {
+	done := make(chan struct{})
+	m.quit <- done
+	<-done
+}
+
+func (m *MemoIndex) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.b.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		if done := m.catchUp(); done != nil {
+			close(done)
+			return
+		}
+		select {
+		case <-heads:
+		case <-sub.Err():
+			done := <-m.quit
+			close(done)
+			return
+		case done := <-m.quit:
+			close(done)
+			return
+		}
+	}
+}
+
+// catchUp indexes the blocks confirmed since the last run. It returns the
+// channel of a stop request received meanwhile.
+func (m *MemoIndex) catchUp() chan struct{} {
+	head := m.b.CurrentBlock().NumberU64()
+	if head < memoIndexConfirmations {
+		return nil
+	}
+	last := head - memoIndexConfirmations
+
+	wallets := m.b.AccountManager().Wallets()
+	progress := make(map[common.AccountAddress]uint64, len(wallets))
+	from := last + 1
+	for _, wallet := range wallets {
+		for _, account := range wallet.Accounts() {
+			next := m.progress(account.Address)
+			progress[account.Address] = next
+			if next < from {
+				from = next
+			}
+		}
+	}
+	for number := from; number <= last; number++ {
+		select {
+		case done := <-m.quit:
+			return done
+		default:
+		}
+		block, err := m.b.BlockByNumber(context.Background(), rpc.BlockNumber(number))
+		if err != nil || block == nil {
+			log.Warn("Memo index block missing", "number", number, "err", err)
+			return nil
+		}
+		var pending []accounts.Wallet
+		for _, wallet := range wallets {
+			if accts := wallet.Accounts(); len(accts) > 0 && progress[accts[0].Address] <= number {
+				pending = append(pending, wallet)
+			}
+		}
+		received := make(map[common.AccountAddress][]*indexedMemo)
+		for _, tx := range block.Transactions() {
+			stxt := tx.Stxt()
+			if stxt == nil {
+				continue
+			}
+			for _, dout := range decryptOuts(pending, tx, stxt) {
+				if len(dout.Memo) == 0 {
+					continue
+				}
+				entry := &indexedMemo{
+					Number:   number,
+					TxHash:   tx.Hash(),
+					Index:    uint32(dout.Index),
+					Z:        dout.Z,
+					Currency: dout.Currency,
+					Value:    (*big.Int)(dout.Value),
+					Memo:     dout.Memo,
+				}
+				received[dout.Account] = append(received[dout.Account], entry)
+			}
+		}
+		batch := m.db.NewBatch()
+		for _, wallet := range pending {
+			account := wallet.Accounts()[0]
+			if entries := received[account.Address]; len(entries) > 0 {
+				if err := m.add(batch, account, number/memoBucketBlocks, entries); err != nil {
+					log.Error("Failed to index memos", "account", account.Address, "number", number, "err", err)
+					return nil
+				}
+			}
+			batch.Put(memoProgressKey(account.Address), encodeBlockNumber(number+1))
+			progress[account.Address] = number + 1
+		}
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to write memo index", "number", number, "err", err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// progress returns the next block to index for an account.
+func (m *MemoIndex) progress(address common.AccountAddress) uint64 {
+	data, _ := m.db.Get(memoProgressKey(address))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// add appends entries to a bucket of an account.
+func (m *MemoIndex) add(batch serodb.Batch, account accounts.Account, bucket uint64, entries []*indexedMemo) error {
+	existing, err := m.bucket(account, bucket)
+	if err != nil {
+		return err
+	}
+	data, err := rlp.EncodeToBytes(append(existing, entries...))
+	if err != nil {
+		return err
+	}
+	gcm, err := newMemoCipher(memoIndexKey(account))
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, memoNonceLength)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	return batch.Put(memoBucketKey(account.Address, bucket), gcm.Seal(nonce, nonce, data, nil))
+}
+
+// bucket returns the entries of a bucket of an account, decrypted.
+func (m *MemoIndex) bucket(account accounts.Account, bucket uint64) ([]*indexedMemo, error) {
+	sealed, err := m.db.Get(memoBucketKey(account.Address, bucket))
+	if err != nil || len(sealed) == 0 {
+		return nil, nil
+	}
+	if len(sealed) < memoNonceLength {
+		return nil, errors.New("memo index bucket truncated")
+	}
+	gcm, err := newMemoCipher(memoIndexKey(account))
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, sealed[:memoNonceLength], sealed[memoNonceLength:], nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*indexedMemo
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// memoIndexKey derives the key sealing the index of an account from its tk.
+func memoIndexKey(account accounts.Account) []byte {
+	return crypto.Keccak256(memoIndexPrefix, account.Tk[:])
+}
+
+func memoBucketKey(address common.AccountAddress, bucket uint64) []byte {
+	key := append(append([]byte{}, memoBucketPrefix...), address[:]...)
+	return append(key, encodeBlockNumber(bucket)...)
+}
+
+func memoProgressKey(address common.AccountAddress) []byte {
+	return append(append([]byte{}, memoProgressPrefix...), address[:]...)
+}
+
+func encodeBlockNumber(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+// MemoRange restricts a memo search to the blocks from FromBlock to ToBlock,
+// both included, and to a single account.
+type MemoRange struct {
+	FromBlock *rpc.BlockNumber       `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber       `json:"toBlock"`
+	Account   *common.AccountAddress `json:"account"`
+}
+
+// MemoMatch is a received out whose memo matches a search.
+type MemoMatch struct {
+	Account     common.AccountAddress `json:"account"`
+	BlockNumber hexutil.Uint64        `json:"blockNumber"`
+	TxHash      common.Hash           `json:"transactionHash"`
+	Index       hexutil.Uint          `json:"index"` // position among the outs of the kind
+	Z           bool                  `json:"z"`
+	Currency    string                `json:"currency,omitempty"`
+	Value       *hexutil.Big          `json:"value,omitempty"`
+	Memo        hexutil.Bytes         `json:"memo"`
+	Text        string                `json:"text,omitempty"`
+	Structured  *StructuredMemo       `json:"structured,omitempty"`
+}
+
+// PrivateMemoIndexAPI searches the memo index.
+type PrivateMemoIndexAPI struct {
+	b     Backend
+	index *MemoIndex
+}
+
+// NewPrivateMemoIndexAPI creates the API searching the memo index.
+func NewPrivateMemoIndexAPI(b Backend, index *MemoIndex) *PrivateMemoIndexAPI {
+	return &PrivateMemoIndexAPI{b: b, index: index}
+}
+
+// Search returns the outs received by the local accounts whose memo
+// contains query, ignoring case, in block order. Only blocks 12 deep in the
+// chain are indexed. At most 1000 matches are returned; narrow the range if
+// there are more.
+func (api *PrivateMemoIndexAPI) Search(ctx context.Context, query string, rng *MemoRange) ([]*MemoMatch, error) {
+	if query == "" {
+		return nil, errors.New("empty query")
+	}
+	from, to := uint64(0), api.b.CurrentBlock().NumberU64()
+	if rng == nil {
+		rng = &MemoRange{}
+	}
+	if rng.FromBlock != nil && *rng.FromBlock >= 0 {
+		from = uint64(*rng.FromBlock)
+	}
+	if rng.ToBlock != nil && *rng.ToBlock >= 0 && uint64(*rng.ToBlock) < to {
+		to = uint64(*rng.ToBlock)
+	}
+	if to < from {
+		return nil, fmt.Errorf("invalid block range %d to %d", from, to)
+	}
+	scope := scopeOf(ctx, api.b)
+	var searched []accounts.Account
+	if rng.Account != nil {
+		wallet, err := scope.find(accounts.Account{Address: *rng.Account})
+		if err != nil {
+			return nil, err
+		}
+		searched = wallet.Accounts()[:1]
+	} else {
+		for _, wallet := range scope.wallets() {
+			searched = append(searched, wallet.Accounts()...)
+		}
+	}
+
+	query = strings.ToLower(query)
+	matches := []*MemoMatch{}
+	for _, account := range searched {
+		for bucket := from / memoBucketBlocks; bucket <= to/memoBucketBlocks; bucket++ {
+			entries, err := api.index.bucket(account, bucket)
+			if err != nil {
+				return nil, fmt.Errorf("memo index of %s: %v", account.Address.String(), err)
+			}
+			for _, entry := range entries {
+				if entry.Number < from || entry.Number > to || !strings.Contains(strings.ToLower(string(entry.Memo)), query) {
+					continue
+				}
+				if len(matches) == maxMemoMatches {
+					return nil, fmt.Errorf("more than %d matches", maxMemoMatches)
+				}
+				matches = append(matches, entry.match(account.Address))
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].BlockNumber < matches[j].BlockNumber
+	})
+	return matches, nil
+}
+
+// match returns an indexed memo as the match of a search.
+func (entry *indexedMemo) match(account common.AccountAddress) *MemoMatch {
+	match := &MemoMatch{
+		Account:     account,
+		BlockNumber: hexutil.Uint64(entry.Number),
+		TxHash:      entry.TxHash,
+		Index:       hexutil.Uint(entry.Index),
+		Z:           entry.Z,
+		Currency:    entry.Currency,
+		Memo:        entry.Memo,
+		Structured:  parseStructuredMemo(entry.Memo),
+	}
+	if entry.Currency != "" {
+		match.Value = (*hexutil.Big)(entry.Value)
+	}
+	if isPrintable(entry.Memo) {
+		match.Text = string(entry.Memo)
+	}
+	return match
+}
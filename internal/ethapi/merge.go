@@ -0,0 +1,270 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/zero/txs"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+var errNothingToMerge = errors.New("fewer than two outs to merge")
+
+// MergeConfig sets up the merging of the small outs of local accounts, such as
+// the block rewards of mining accounts, into larger ones so that later sends
+// need fewer inputs.
+type MergeConfig struct {
+	Interval  time.Duration `toml:",omitempty"` // Period of the automatic merges, 0 to disable them
+	Currency  string        `toml:",omitempty"` // Currency merged automatically
+	Threshold *big.Int      `toml:",omitempty"` // Outs worth less are merged automatically, any if unset
+	MaxIns    int           `toml:",omitempty"` // Outs merged by a transaction
+	Target    int           `toml:",omitempty"` // Outs of the currency an account keeps unmerged
+}
+
+// DefaultMergeConfig contains the default merge settings, without automatic
+// merges.
+var DefaultMergeConfig = MergeConfig{
+	Currency: params.DefaultCurrency,
+	MaxIns:   10,
+	Target:   10,
+}
+
+// sanitize replaces the unset and invalid fields with their defaults.
+func (c MergeConfig) sanitize() MergeConfig {
+	if c.Currency == "" {
+		c.Currency = DefaultMergeConfig.Currency
+	}
+	if c.MaxIns < 2 {
+		c.MaxIns = DefaultMergeConfig.MaxIns
+	}
+	if c.Target < 1 {
+		c.Target = DefaultMergeConfig.Target
+	}
+	return c
+}
+
+// mergeableOuts returns the outs of an account which a merge may spend:
+// confirmed, neither excluded from coin selection nor spent by a pooled
+// transaction, holding no ticket and worth less than threshold if set. They
+// are sorted by increasing value.
+func mergeableOuts(ctx context.Context, b Backend, address common.AccountAddress, currency string, threshold *big.Int) ([]*lstate.OutState, error) {
+	wallet, err := scopeOf(ctx, b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	outs, err := txs.GetOuts(wallet.Accounts()[0].Tk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	maxNum, err := maxInNum(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := excludedRoots(ctx, b, address)
+	if err != nil {
+		return nil, err
+	}
+	skip := pooledInputs(b)
+	for _, root := range excluded {
+		skip[root] = true
+	}
+	cy := *common.BytesToHash(common.LeftPadBytes([]byte(currency), 32)).HashToUint256()
+
+	var mergeable []*lstate.OutState
+	for _, out := range outs {
+		tkn := out.Out_O.Asset.Tkn
+		switch {
+		case tkn == nil || tkn.Currency != cy || out.Out_O.Asset.Tkt != nil:
+		case skip[out.Root] || skip[out.Trace]:
+		case maxNum > 0 && out.Num > maxNum:
+		case threshold != nil && threshold.Sign() > 0 && tkn.Value.ToIntRef().Cmp(threshold) >= 0:
+		default:
+			mergeable = append(mergeable, out)
+		}
+	}
+	sort.SliceStable(mergeable, func(i, j int) bool {
+		return mergeable[i].Out_O.Asset.Tkn.Value.Cmp(&mergeable[j].Out_O.Asset.Tkn.Value) < 0
+	})
+	return mergeable, nil
+}
+
+// merge sends a transaction of an account to itself spending the smallest
+// maxIns of its mergeable outs of a currency, and returns its hash and the
+// number of the outs merged.
+func (s *PublicTransactionPoolAPI) merge(ctx context.Context, address common.AccountAddress, currency string, threshold *big.Int, maxIns int) (common.Hash, int, error) {
+	currency = strings.ToUpper(currency)
+	mergeable, err := mergeableOuts(ctx, s.b, address, currency, threshold)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	if len(mergeable) > maxIns {
+		mergeable = mergeable[:maxIns]
+	}
+	if len(mergeable) < 2 {
+		return common.Hash{}, 0, errNothingToMerge
+	}
+	merged := make(map[keys.Uint256]bool)
+	value := new(big.Int)
+	for _, out := range mergeable {
+		merged[out.Root] = true
+		value.Add(value, out.Out_O.Asset.Tkn.Value.ToIntRef())
+	}
+
+	// Coin selection may only spend the merged outs of the currency
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	outs, err := txs.GetOuts(wallet.Accounts()[0].Tk.ToUint512())
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	cy := mergeable[0].Out_O.Asset.Tkn.Currency
+	var exclude []keys.Uint256
+	for _, out := range outs {
+		if tkn := out.Out_O.Asset.Tkn; tkn != nil && tkn.Currency == cy && !merged[out.Root] {
+			exclude = append(exclude, out.Root)
+		}
+	}
+
+	gas := hexutil.Uint64(s.b.TxDefaults().sanitize().Gas)
+	price, err := s.b.SuggestPrice(ctx)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	if currency == params.DefaultCurrency {
+		// The fee is paid out of the merged outs
+		value.Sub(value, new(big.Int).Mul(price, new(big.Int).SetUint64(uint64(gas))))
+		if value.Sign() <= 0 {
+			return common.Hash{}, 0, errors.New("merged outs are worth less than the fee")
+		}
+	}
+	to := address
+	selection := txs.SelectIndex
+	args := SendTxArgs{
+		From:        address,
+		To:          &to,
+		Gas:         &gas,
+		GasCurrency: Smbol(params.DefaultCurrency),
+		GasPrice:    (*hexutil.Big)(price),
+		Value:       (*hexutil.Big)(value),
+		Currency:    Smbol(currency),
+		Selection:   &selection,
+		exclude:     exclude,
+	}
+	hash, err := s.SendTransaction(ctx, args)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	return hash, len(mergeable), nil
+}
+
+// Merge sends a transaction of a local account to itself merging its smallest
+// outs of a currency worth less than threshold each, any if unset, into one.
+// Up to the configured number of outs are merged by a call, the fee is paid
+// out of the merged outs for the gas currency.
+func (s *PublicTransactionPoolAPI) Merge(ctx context.Context, address common.AccountAddress, currency Smbol, threshold *hexutil.Big) (common.Hash, error) {
+	if currency == "" {
+		currency = Smbol(params.DefaultCurrency)
+	}
+	hash, _, err := s.merge(ctx, address, string(currency), (*big.Int)(threshold), s.b.Merge().sanitize().MaxIns)
+	return hash, err
+}
+
+// Merger merges the small outs of the accounts of the node periodically, as
+// long as they hold more than the target number of outs of the currency. The
+// accounts must stay unlocked to be merged.
+type Merger struct {
+	b    Backend
+	pool *PublicTransactionPoolAPI
+	quit chan chan struct{}
+}
+
+// NewMerger creates the merge service, sending through the transaction pool
+// API served to the clients.
+func NewMerger(b Backend, pool *PublicTransactionPoolAPI) *Merger {
+	return &Merger{b: b, pool: pool, quit: make(chan chan struct{})}
+}
+
+// Start starts the automatic merges if enabled.
+func (m *Merger) Start() {
+	if m.b.Merge().Interval > 0 {
+		go m.loop()
+	}
+}
+
+// Stop stops the automatic merges.
+func (m *Merger) Stop() {
+	if m.b.Merge().Interval > 0 {
+		done := make(chan struct{})
+		m.quit <- done
+		<-done
+	}
+}
+
+func (m *Merger) loop() {
+	ticker := time.NewTicker(m.b.Merge().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.mergeAll()
+		case done := <-m.quit:
+			close(done)
+			return
+		}
+	}
+}
+
+// mergeAll sends a merge for every account of the node holding too many
+// mergeable outs.
+func (m *Merger) mergeAll() {
+	config := m.b.Merge().sanitize()
+	ctx := context.Background()
+	for _, wallet := range scopeOf(ctx, m.b).wallets() {
+		for _, account := range wallet.Accounts() {
+			mergeable, err := mergeableOuts(ctx, m.b, account.Address, config.Currency, config.Threshold)
+			if err != nil || len(mergeable) <= config.Target {
+				continue
+			}
+			// Merging n outs into one leaves n-1 fewer
+			ins := len(mergeable) - config.Target + 1
+			if ins > config.MaxIns {
+				ins = config.MaxIns
+			}
+			hash, merged, err := m.pool.merge(ctx, account.Address, config.Currency, config.Threshold, ins)
+			if err != nil {
+				log.Debug("Out merge failed", "account", account.Address, "err", err)
+				continue
+			}
+			log.Info("Merged outs", "account", account.Address, "outs", merged, "hash", hash)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/generate"
+	"github.com/sero-cash/go-sero/zero/txs/tx"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// OfflineTx is an unsigned transaction assembled by a node tracking the
+// sender, to be signed where its seed is kept.
+type OfflineTx struct {
+	From     common.AccountAddress `json:"from"`
+	GasPrice *hexutil.Big          `json:"gasPrice"`
+	Gas      hexutil.Uint64        `json:"gas"`
+	Data     hexutil.Bytes         `json:"data"`
+	Param    hexutil.Bytes         `json:"param"` // encoded generate.TxParam
+
+	ValidUntilBlock *hexutil.Uint64 `json:"validUntilBlock,omitempty"`
+}
+
+// GenTxParam assembles the transaction of args without signing it: the
+// inputs are selected and returned with their witnesses, so the transaction
+// can be signed by SignTxWithSk on a node without the chain or the keystore
+// holding the seed. The sender only needs to be tracked by this node.
+func (s *PublicTransactionPoolAPI) GenTxParam(ctx context.Context, args SendTxArgs) (*OfflineTx, error) {
+	if args.Sponsor != nil {
+		return nil, errors.New("sponsored transactions can not be signed offline")
+	}
+	if err := args.setDefaults(ctx, s.b); err != nil {
+		return nil, err
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	btx, txt, err := args.toTransaction(state)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: args.From})
+	if err != nil {
+		return nil, err
+	}
+	tk := wallet.Accounts()[0].Tk
+	roots, tknMap, tktMap, err := txs.GetRoots(tk.ToUint512(), txt.TokenCost(), txt.TikectCost(), txt.MaxInNum, txt.Excluded, txt.Selection)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range roots {
+		txt.Ins = append(txt.Ins, tx.In{Root: root})
+	}
+	txt.Outs = append(txt.Outs, changeOuts(args.From, tknMap, tktMap)...)
+
+	param, err := generate.GenTxParam(txt)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := param.Serial()
+	if err != nil {
+		return nil, err
+	}
+	return &OfflineTx{
+		From:     args.From,
+		GasPrice: (*hexutil.Big)(btx.GasPrice()),
+		Gas:      hexutil.Uint64(btx.Gas()),
+		Data:     btx.Data(),
+		Param:    encoded,
+
+		ValidUntilBlock: args.ValidUntilBlock,
+	}, nil
+}
+
+// changeOuts returns the outs giving the change of the selected inputs back
+// to the account at address.
+func changeOuts(address common.AccountAddress, tknMap map[keys.Uint256]utils.U256, tktMap map[keys.Uint256][]keys.Uint256) (outs []tx.Out) {
+	change := func(asset assets.Asset) tx.Out {
+		return tx.Out{
+			Addr:  keys.Addr2PKr(address.ToUint512(), keys.RandUint256().NewRef()),
+			Asset: asset,
+			IsZ:   true,
+		}
+	}
+	for cy, value := range tknMap {
+		outs = append(outs, change(assets.Asset{Tkn: &assets.Token{Currency: cy, Value: value}}))
+	}
+	for catg, values := range tktMap {
+		for _, value := range values {
+			outs = append(outs, change(assets.Asset{Tkt: &assets.Ticket{Category: catg, Value: value}}))
+		}
+	}
+	return
+}
+
+// SignOfflineTx generates and signs the transaction assembled by GenTxParam
+// with the seed of its sender.
+func SignOfflineTx(offline *OfflineTx, seed *common.Seed) (*types.Transaction, error) {
+	if keys.Seed2Addr(seed.SeedToUint256()) != *offline.From.ToUint512() {
+		return nil, fmt.Errorf("the key is not the one of %v", offline.From)
+	}
+	param, err := generate.UnserialTxParam(offline.Param)
+	if err != nil {
+		return nil, fmt.Errorf("invalid param: %v", err)
+	}
+	btx := types.NewTransaction(offline.GasPrice.ToInt(), uint64(offline.Gas), offline.Data)
+	if offline.ValidUntilBlock != nil {
+		btx = btx.WithValidUntil(uint64(*offline.ValidUntilBlock))
+	}
+	if btx.Ehash() != param.Tx.Ehash {
+		return nil, errors.New("param does not match the transaction")
+	}
+	stx, err := generate.SignTxParam(param, seed.SeedToUint256())
+	if err != nil {
+		return nil, err
+	}
+	return btx.WithEncrypt(&stx)
+}
+
+// SignTxWithSk signs a transaction assembled by GenTxParam with the given
+// seed and returns it encoded for SendRawTransaction. It is meant to be
+// called on an air-gapped node, the seed is neither stored nor sent anywhere.
+func (s *PublicTransactionPoolAPI) SignTxWithSk(offline OfflineTx, sk hexutil.Bytes) (hexutil.Bytes, error) {
+	if len(sk) != common.SeedLength {
+		return nil, fmt.Errorf("sk must be %d bytes", common.SeedLength)
+	}
+	var seed common.Seed
+	copy(seed[:], sk)
+	signed, err := SignOfflineTx(&offline, &seed)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(signed)
+}
+
+// SendRawTransaction submits a signed transaction, as returned by
+// SignTxWithSk or EncryptTransaction, to the transaction pool.
+func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, signed); err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, signed, nil)
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/txs"
+)
+
+// lockedOuts are the roots of the outs pinned by sero_lockOuts, left out of
+// the coin selection until they are unlocked or the node restarts.
+var lockedOuts = struct {
+	sync.RWMutex
+	roots map[keys.Uint256]bool
+}{roots: make(map[keys.Uint256]bool)}
+
+// outLocked reports whether an out is pinned by sero_lockOuts.
+func outLocked(root keys.Uint256) bool {
+	lockedOuts.RLock()
+	defer lockedOuts.RUnlock()
+	return lockedOuts.roots[root]
+}
+
+// excludedRoots returns the roots of the outs held by the given local
+// accounts coin selection must leave out: the immature block rewards and the
+// locked outs.
+func excludedRoots(ctx context.Context, b Backend, addresses ...common.AccountAddress) ([]keys.Uint256, error) {
+	rewards := newRewardChecker(ctx, b)
+	roots := []keys.Uint256{}
+	for _, address := range addresses {
+		wallet, err := scopeOf(ctx, b).find(accounts.Account{Address: address})
+		if err != nil {
+			return nil, err
+		}
+		outs, err := txs.GetOuts(wallet.Accounts()[0].Tk.ToUint512())
+		if err != nil {
+			return nil, err
+		}
+		for _, out := range outs {
+			if rewards.immature(out) || outLocked(out.Root) {
+				roots = append(roots, out.Root)
+			}
+		}
+	}
+	return roots, nil
+}
+
+// UnspentOut is an out held by a local account.
+type UnspentOut struct {
+	Root          keys.Uint256   `json:"root"`
+	Currency      string         `json:"cy,omitempty"`
+	Value         *hexutil.Big   `json:"value,omitempty"`
+	Category      string         `json:"catg,omitempty"`
+	Ticket        *common.Hash   `json:"tkt,omitempty"`
+	Number        hexutil.Uint64 `json:"number"`
+	Confirmations hexutil.Uint64 `json:"confirmations"`
+	Locked        bool           `json:"locked"`  // pinned by sero_lockOuts
+	Pending       bool           `json:"pending"` // spent by a pooled transaction
+}
+
+// GetOuts returns the unspent outs of a local account, oldest first.
+func (s *PublicTransactionPoolAPI) GetOuts(ctx context.Context, address common.AccountAddress) ([]*UnspentOut, error) {
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	outs, err := txs.GetOuts(wallet.Accounts()[0].Tk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	head := s.b.CurrentBlock().NumberU64()
+	pooled := pooledInputs(s.b)
+	result := make([]*UnspentOut, 0, len(outs))
+	for _, out := range outs {
+		unspent := &UnspentOut{
+			Root:    out.Root,
+			Number:  hexutil.Uint64(out.Num),
+			Locked:  outLocked(out.Root),
+			Pending: pooled[out.Root] || pooled[out.Trace],
+		}
+		if out.Num <= head {
+			unspent.Confirmations = hexutil.Uint64(head - out.Num + 1)
+		}
+		if tkn := out.Out_O.Asset.Tkn; tkn != nil {
+			unspent.Currency = strings.Trim(string(tkn.Currency[:]), zerobyte)
+			unspent.Value = (*hexutil.Big)(tkn.Value.ToIntRef())
+		}
+		if tkt := out.Out_O.Asset.Tkt; tkt != nil {
+			unspent.Category = strings.Trim(string(tkt.Category[:]), zerobyte)
+			ticket := common.Hash{}
+			copy(ticket[:], tkt.Value[:])
+			unspent.Ticket = &ticket
+		}
+		result = append(result, unspent)
+	}
+	return result, nil
+}
+
+// accountRoots returns the set of the roots of the unspent outs of a local
+// account.
+func accountRoots(ctx context.Context, b Backend, address common.AccountAddress) (map[keys.Uint256]bool, error) {
+	wallet, err := scopeOf(ctx, b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	outs, err := txs.GetOuts(wallet.Accounts()[0].Tk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	roots := make(map[keys.Uint256]bool, len(outs))
+	for _, out := range outs {
+		roots[out.Root] = true
+	}
+	return roots, nil
+}
+
+// LockOuts pins unspent outs of a local account so the transactions sent
+// through the node do not select them as inputs. The locks are kept in
+// memory only.
+func (s *PublicTransactionPoolAPI) LockOuts(ctx context.Context, address common.AccountAddress, roots []keys.Uint256) error {
+	held, err := accountRoots(ctx, s.b, address)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if !held[root] {
+			return fmt.Errorf("out %s is not an unspent out of the account", hexutil.Encode(root[:]))
+		}
+	}
+	lockedOuts.Lock()
+	defer lockedOuts.Unlock()
+	for _, root := range roots {
+		lockedOuts.roots[root] = true
+	}
+	return nil
+}
+
+// UnlockOuts releases outs of a local account pinned by sero_lockOuts, all
+// of them if no root is given. The roots which are not unspent outs of the
+// account are ignored.
+func (s *PublicTransactionPoolAPI) UnlockOuts(ctx context.Context, address common.AccountAddress, roots []keys.Uint256) error {
+	held, err := accountRoots(ctx, s.b, address)
+	if err != nil {
+		return err
+	}
+	lockedOuts.Lock()
+	defer lockedOuts.Unlock()
+	if len(roots) == 0 {
+		for root := range held {
+			delete(lockedOuts.roots, root)
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if held[root] {
+			delete(lockedOuts.roots, root)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rlp"
+)
+
+// defaultPaymentExpiry is the validity of payment requests created without
+// an expiry.
+const defaultPaymentExpiry = time.Hour
+
+var errPaymentRequestExpired = errors.New("payment request expired")
+
+// PaymentRequest asks for a payment to a merchant account. It is signed with
+// the key of the account under a PKr derived from it with Rand, so that the
+// wallet paying it can check both the signature and that the signer is the
+// account paid.
+type PaymentRequest struct {
+	Id        common.Hash           `json:"id"`
+	To        common.AccountAddress `json:"to"`
+	Value     *hexutil.Big          `json:"value"`
+	Currency  Smbol                 `json:"cy"`
+	Memo      string                `json:"memo"`
+	Expires   hexutil.Uint64        `json:"expires"` // unix time after which the request is not paid
+	Rand      keys.Uint256          `json:"rand"`
+	Signer    common.Address        `json:"signer"` // PKr of To derived with Rand
+	Signature hexutil.Bytes         `json:"signature"`
+}
+
+// PaymentRequestArgs are the terms of a payment request to create.
+type PaymentRequestArgs struct {
+	To       common.AccountAddress `json:"to"`
+	Value    *hexutil.Big          `json:"value"`
+	Currency Smbol                 `json:"cy"` // default SERO
+	Memo     string                `json:"memo"`
+	Expiry   *hexutil.Uint64       `json:"expiry"` // seconds the request is valid for, default an hour
+}
+
+// PaymentAck acknowledges the payment of a request with the transaction
+// paying it.
+type PaymentAck struct {
+	Id     common.Hash `json:"id"`
+	Hash   common.Hash `json:"hash"` // hash of the signed request
+	TxHash common.Hash `json:"transactionHash"`
+}
+
+// hash returns the hash the merchant signs, covering every term of the
+// request.
+func (r *PaymentRequest) hash() common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{
+		r.Id,
+		r.To,
+		(*big.Int)(r.Value),
+		strings.ToUpper(string(r.Currency)),
+		r.Memo,
+		uint64(r.Expires),
+		r.Rand,
+		r.Signer,
+	})
+	return crypto.Keccak256Hash([]byte("sero-payment-request"), enc)
+}
+
+// verify checks that the request is signed by the account it pays.
+func (r *PaymentRequest) verify() error {
+	if r.Value == nil || r.Value.ToInt().Sign() <= 0 {
+		return errors.New("payment request without value")
+	}
+	if len(r.Memo) > maxMemoLength {
+		return fmt.Errorf("payment request memo is too long, it's limited %d bytes", maxMemoLength)
+	}
+	if keys.Addr2PKr(r.To.ToUint512(), r.Rand.NewRef()) != *r.Signer.ToPKr() {
+		return errors.New("payment request not signed for the account paid")
+	}
+	if len(r.Signature) != len(keys.Uint512{}) {
+		return errors.New("invalid payment request signature")
+	}
+	var sign keys.Uint512
+	copy(sign[:], r.Signature)
+	if !keys.VerifyPKr(r.hash().HashToUint256(), &sign, r.Signer.ToPKr()) {
+		return errors.New("invalid payment request signature")
+	}
+	return nil
+}
+
+// CreatePaymentRequest creates a payment request to a local account and signs
+// it with the key of the account, which has to be unlocked.
+func (s *PublicTransactionPoolAPI) CreatePaymentRequest(ctx context.Context, args PaymentRequestArgs) (*PaymentRequest, error) {
+	if args.Value == nil || args.Value.ToInt().Sign() <= 0 {
+		return nil, errors.New("payment request without value")
+	}
+	if len(args.Memo) > maxMemoLength {
+		return nil, fmt.Errorf("payment request memo is too long, it's limited %d bytes", maxMemoLength)
+	}
+	ks, err := scopeOf(ctx, s.b).keystore()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := ks.GetSeed(accounts.Account{Address: args.To})
+	if err != nil {
+		return nil, err
+	}
+	expiry := defaultPaymentExpiry
+	if args.Expiry != nil {
+		expiry = time.Duration(*args.Expiry) * time.Second
+	}
+	currency := args.Currency
+	if currency.IsEmpty() {
+		currency = Smbol(params.DefaultCurrency)
+	}
+	request := &PaymentRequest{
+		To:       args.To,
+		Value:    args.Value,
+		Currency: Smbol(strings.ToUpper(string(currency))),
+		Memo:     args.Memo,
+		Expires:  hexutil.Uint64(time.Now().Add(expiry).Unix()),
+		Rand:     keys.RandUint256(),
+	}
+	if _, err := rand.Read(request.Id[:]); err != nil {
+		return nil, err
+	}
+	signer := keys.Addr2PKr(args.To.ToUint512(), request.Rand.NewRef())
+	request.Signer = common.BytesToAddress(signer[:])
+
+	sign, err := keys.SignPKr(seed.SeedToUint256(), request.hash().HashToUint256(), &signer)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = sign[:]
+	return request, nil
+}
+
+// PayRequest verifies a payment request and pays it from a local account. A
+// request is paid once by the node: paying it again returns the
+// acknowledgement of the first payment.
+func (s *PublicTransactionPoolAPI) PayRequest(ctx context.Context, request PaymentRequest, from common.AccountAddress) (*PaymentAck, error) {
+	if err := request.verify(); err != nil {
+		return nil, err
+	}
+	hash := request.hash()
+	clientRequestId := "payment-request:" + hash.Hex()
+
+	// Acknowledge a request paid before even if it expired since
+	id := crypto.Keccak256Hash(from[:], []byte(clientRequestId))
+	if sent := s.clientRequest(id); sent != nil {
+		return &PaymentAck{Id: request.Id, Hash: hash, TxHash: sent.Hash}, nil
+	}
+	if time.Now().Unix() >= int64(request.Expires) {
+		return nil, errPaymentRequestExpired
+	}
+	args := SendTxArgs{
+		From:     from,
+		To:       &request.To,
+		Value:    request.Value,
+		Currency: request.Currency,
+		Memo:     request.Memo,
+	}
+	// The payment is remembered at least until the request expires
+	expires := uint64(time.Now().Add(clientRequestTTL).Unix())
+	if uint64(request.Expires) > expires {
+		expires = uint64(request.Expires)
+	}
+	txHash, err := s.sendIdempotent(ctx, args, clientRequestId, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentAck{Id: request.Id, Hash: hash, TxHash: txHash}, nil
+}
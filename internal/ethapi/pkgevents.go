@@ -0,0 +1,199 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/pkgstate"
+)
+
+// maxPkgEventBlocks is the number of blocks sero_getPkgEvents scans at most.
+const maxPkgEventBlocks = 1024
+
+// Types of the package lifecycle events.
+const (
+	PkgCreated     = "created"
+	PkgTransferred = "transferred"
+	PkgClosed      = "closed"
+)
+
+// PkgEvent notifies a local account of the creation, transfer or close of a
+// package addressed to it. A transfer is notified to both the previous and the
+// new owner, a close to the owner the package was closed by.
+type PkgEvent struct {
+	Type        string                  `json:"type"`
+	Id          keys.Uint256            `json:"id"`
+	Account     common.AccountAddress   `json:"account"` // local account owning the package
+	From        *common.ContractAddress `json:"from"`    // short address of the creator, nil if unknown
+	To          *common.ContractAddress `json:"to"`      // short address of the owner after the event, nil if closed
+	BlockNumber hexutil.Uint64          `json:"blockNumber"`
+	BlockHash   common.Hash             `json:"blockHash"`
+	TxHash      common.Hash             `json:"transactionHash"`
+}
+
+// pkgEvents returns the package events of a block concerning the wallets.
+// The previous owner of transferred and closed packages is read from the
+// state of the parent block, if the node still has it; otherwise the
+// sender of a close is taken as its owner.
+func pkgEvents(ctx context.Context, b Backend, block *types.Block, wallets []accounts.Wallet) []*PkgEvent {
+	var (
+		events []*PkgEvent
+		states = make(map[uint64]*pkgstate.PkgState)
+	)
+	// pkgAt returns the package after the given block, nil if it is unknown
+	pkgAt := func(number uint64, id *keys.Uint256) *pkgstate.ZPkg {
+		pkgs, ok := states[number]
+		if !ok {
+			if state, _, err := b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(number)); err == nil && state != nil {
+				pkgs = &state.GetZState().Pkgs
+			}
+			states[number] = pkgs
+		}
+		if pkgs == nil {
+			return nil
+		}
+		return pkgs.GetPkg(id)
+	}
+	notify := func(kind string, id keys.Uint256, owner *keys.PKr, from *keys.PKr, to *keys.PKr, tx *types.Transaction) {
+		account := getAddressByPkr(wallets, common.BytesToAddress(owner[:]))
+		if account == nil {
+			return
+		}
+		event := &PkgEvent{
+			Type:        kind,
+			Id:          id,
+			Account:     *account,
+			BlockNumber: hexutil.Uint64(block.NumberU64()),
+			BlockHash:   block.Hash(),
+			TxHash:      tx.Hash(),
+		}
+		if from != nil {
+			short := shortAddress(from)
+			event.From = &short
+		}
+		if to != nil {
+			short := shortAddress(to)
+			event.To = &short
+		}
+		events = append(events, event)
+	}
+
+	number := block.NumberU64()
+	for _, tx := range block.Transactions() {
+		stxt := tx.Stxt()
+		switch desc := stxt.Desc_Pkg; {
+		case desc.Create != nil:
+			notify(PkgCreated, desc.Create.Id, &desc.Create.PKr, &stxt.From, &desc.Create.PKr, tx)
+
+		case desc.Transfer != nil:
+			var from *keys.PKr
+			if pkg := pkgAt(number, &desc.Transfer.Id); pkg != nil {
+				from = &pkg.From
+			}
+			notify(PkgTransferred, desc.Transfer.Id, &desc.Transfer.PKr, from, &desc.Transfer.PKr, tx)
+			if number > 0 {
+				if prev := pkgAt(number-1, &desc.Transfer.Id); prev != nil && prev.Pack.PKr != desc.Transfer.PKr {
+					notify(PkgTransferred, desc.Transfer.Id, &prev.Pack.PKr, from, &desc.Transfer.PKr, tx)
+				}
+			}
+
+		case desc.Close != nil:
+			owner, from := &stxt.From, (*keys.PKr)(nil)
+			if number > 0 {
+				if prev := pkgAt(number-1, &desc.Close.Id); prev != nil {
+					owner, from = &prev.Pack.PKr, &prev.From
+				}
+			}
+			notify(PkgClosed, desc.Close.Id, owner, from, nil, tx)
+		}
+	}
+	return events
+}
+
+// GetPkgEvents returns the package events of the local accounts in the blocks
+// from fromBlock to toBlock, both included and at most 1024. Polling clients
+// continue with the block after the last one returned.
+func (s *PublicBlockChainAPI) GetPkgEvents(ctx context.Context, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber) ([]*PkgEvent, error) {
+	head := s.b.CurrentBlock().NumberU64()
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if fromBlock < 0 {
+		from = head
+	}
+	if toBlock < 0 || to > head {
+		to = head
+	}
+	if to < from {
+		return nil, fmt.Errorf("invalid block range %d to %d", from, to)
+	}
+	if to-from >= maxPkgEventBlocks {
+		return nil, fmt.Errorf("at most %d blocks are scanned", maxPkgEventBlocks)
+	}
+	wallets := scopeOf(ctx, s.b).wallets()
+	events := []*PkgEvent{}
+	for number := from; number <= to; number++ {
+		block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		events = append(events, pkgEvents(ctx, s.b, block, wallets)...)
+	}
+	return events, nil
+}
+
+// PkgEvents creates a subscription notifying the package events of the local
+// accounts in the blocks imported from now on.
+func (s *PublicBlockChainAPI) PkgEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	scope := scopeOf(ctx, s.b)
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		chainEvents := make(chan core.ChainEvent, 16)
+		chainSub := s.b.SubscribeChainEvent(chainEvents)
+		defer chainSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-chainEvents:
+				for _, event := range pkgEvents(context.Background(), s.b, ev.Block, scope.wallets()) {
+					notifier.Notify(rpcSub.ID, event)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
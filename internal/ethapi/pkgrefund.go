@@ -0,0 +1,85 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+// RefundPkgArgs are the arguments of sero_refundPkg.
+type RefundPkgArgs struct {
+	From     *common.AccountAddress `json:"from"` // creator of the package
+	Gas      *hexutil.Uint64        `json:"gas"`
+	GasPrice *hexutil.Big           `json:"gasPrice"`
+	PkgId    *keys.Uint256          `json:"id"`
+	MinConf  *hexutil.Uint64        `json:"minConf"` // default node policy
+}
+
+// RefundPkg returns the asset of an expired package to its creator. Packages
+// created with a pkgExpiry may be refunded that many blocks after their
+// creation if their owner did not close them.
+func (s *PublicTransactionPoolAPI) RefundPkg(ctx context.Context, args RefundPkgArgs) (common.Hash, error) {
+	if args.From == nil {
+		return common.Hash{}, errors.New("from can not be nil")
+	}
+	if args.PkgId == nil {
+		return common.Hash{}, errors.New("id can not be nil")
+	}
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: *args.From})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return common.Hash{}, errors.New("local outs are not scanned yet")
+	}
+	var found *lstate.Pkg
+	for _, p := range st1.GetPkgs(wallet.Accounts()[0].Tk.ToUint512(), true) {
+		if p.Pkg.Z.Pack.Id == *args.PkgId {
+			found = p
+			break
+		}
+	}
+	if found == nil || found.Key == (keys.Uint256{}) {
+		return common.Hash{}, fmt.Errorf("package %v was not created by %v", hexutil.Encode(args.PkgId[:]), args.From.String())
+	}
+	expiry := found.Pkg.Z.Pack.ExpiryBlocks()
+	if expiry == 0 {
+		return common.Hash{}, fmt.Errorf("package %v does not expire", hexutil.Encode(args.PkgId[:]))
+	}
+	if next := s.b.CurrentBlock().NumberU64() + 1; !found.Pkg.Z.Refundable(next) {
+		return common.Hash{}, fmt.Errorf("package %v can be refunded from block %d", hexutil.Encode(args.PkgId[:]), found.Pkg.Z.High+expiry)
+	}
+	key := found.Key
+	return s.ClosePkg(ctx, ClosePkgArgs{
+		From:     args.From,
+		Gas:      args.Gas,
+		GasPrice: args.GasPrice,
+		PkgId:    args.PkgId,
+		Key:      &key,
+		MinConf:  args.MinConf,
+		refund:   true,
+	})
+}
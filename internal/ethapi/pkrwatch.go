@@ -0,0 +1,493 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/hooks"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+)
+
+// maxWatchedPKrs is the number of PKrs watched at most.
+const maxWatchedPKrs = 256
+
+var pkrWatchKey = []byte("pkrwatch-list")
+
+// Types of the PKr watch events.
+const (
+	WatchOutReceived = "out"
+	WatchOutSpent    = "spent"
+	WatchPkgReceived = "pkg"
+)
+
+// watchedOut is an out received by a watched PKr. O outs are seen spent by
+// their root, Z outs only once their owner disclosed their nil.
+type watchedOut struct {
+	Root    keys.Uint256
+	Nil     *keys.Uint256 `rlp:"nil"`
+	Number  uint64
+	TxHash  common.Hash
+	SpentAt uint64 // block the out was spent in, 0 if unspent
+	SpentTx common.Hash
+}
+
+// watchedPkg is a package created for or transferred to a watched PKr.
+type watchedPkg struct {
+	Id     keys.Uint256
+	Number uint64
+	TxHash common.Hash
+}
+
+type watchedPKr struct {
+	PKr   keys.PKr
+	Label string
+	Since uint64
+	Outs  []*watchedOut
+	Pkgs  []*watchedPkg
+}
+
+// PKrWatchEvent notifies an out received or spent or a package received by a
+// watched PKr. Events of blocks dropped by a reorg are notified again with
+// removed set.
+type PKrWatchEvent struct {
+	Type        string         `json:"type"`
+	PKr         common.Address `json:"pkr"`
+	Label       string         `json:"label"`
+	Root        *keys.Uint256  `json:"root,omitempty"`
+	Pkg         *keys.Uint256  `json:"pkg,omitempty"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	Removed     bool           `json:"removed"`
+}
+
+// WatchedPKr is the summary of what a watched PKr received and spent since it
+// is watched.
+type WatchedPKr struct {
+	PKr       common.Address `json:"pkr"`
+	Label     string         `json:"label"`
+	Since     hexutil.Uint64 `json:"since"`
+	Outs      hexutil.Uint64 `json:"outs"`
+	Spent     hexutil.Uint64 `json:"spent"`
+	Disclosed hexutil.Uint64 `json:"disclosed"`
+	Pkgs      hexutil.Uint64 `json:"pkgs"`
+}
+
+// PKrWatcher tracks the public, on-chain facts about a list of PKrs: the outs
+// they receive, the spends of these outs that can be linked to them and the
+// packages addressed to them. It needs no key material. The spend of an O out
+// shows its root; the spend of a Z out only shows its nil, which the owner of
+// the PKr discloses with sero_discloseOut.
+//
+// The watcher is a block hook, registered on the first watch so that nodes
+// watching nothing do not collect the hook records of the blocks.
+type PKrWatcher struct {
+	b      Backend
+	db     serodb.Database
+	feed   event.Feed
+	once   sync.Once
+	mu     sync.Mutex
+	pkrs   map[keys.PKr]*watchedPKr
+	loaded bool
+}
+
+// NewPKrWatcher creates the watcher of the PKrs listed in db.
+func NewPKrWatcher(b Backend, db serodb.Database) *PKrWatcher {
+	w := &PKrWatcher{b: b, db: db, pkrs: make(map[keys.PKr]*watchedPKr)}
+	if blob, err := db.Get(pkrWatchKey); err == nil && len(blob) > 0 {
+		var list []*watchedPKr
+		if err := rlp.DecodeBytes(blob, &list); err != nil {
+			log.Error("Invalid PKr watch list", "err", err)
+		}
+		for _, watched := range list {
+			w.pkrs[watched.PKr] = watched
+		}
+	}
+	if len(w.pkrs) > 0 {
+		w.register()
+	}
+	return w
+}
+
+func (w *PKrWatcher) register() {
+	w.once.Do(func() { hooks.Register(w) })
+}
+
+// save writes the watch list, the lock being held.
+func (w *PKrWatcher) save() {
+	list := make([]*watchedPKr, 0, len(w.pkrs))
+	for _, watched := range w.pkrs {
+		list = append(list, watched)
+	}
+	blob, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Error("Failed to encode the PKr watch list", "err", err)
+		return
+	}
+	if err := w.db.Put(pkrWatchKey, blob); err != nil {
+		log.Error("Failed to write the PKr watch list", "err", err)
+	}
+}
+
+// SubscribeEvents subscribes to the events of the watched PKrs.
+func (w *PKrWatcher) SubscribeEvents(ch chan<- *PKrWatchEvent) event.Subscription {
+	return w.feed.Subscribe(ch)
+}
+
+// BlockImported implements hooks.BlockHook, recording the outs created for
+// and spent by the watched PKrs and the packages addressed to them.
+func (w *PKrWatcher) BlockImported(block *types.Block, receipts types.Receipts, zblock *zstate.Block) {
+	for _, event := range w.imported(block, zblock) {
+		w.feed.Send(event)
+	}
+}
+
+// ChainReorged implements hooks.BlockHook, dropping what the watched PKrs
+// received and spent above the ancestor.
+func (w *PKrWatcher) ChainReorged(ancestor *types.Block, dropped []*types.Block) {
+	for _, event := range w.reorged(ancestor, dropped) {
+		w.feed.Send(event)
+	}
+}
+
+func (w *PKrWatcher) imported(block *types.Block, zblock *zstate.Block) []*PKrWatchEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pkrs) == 0 {
+		return nil
+	}
+	number, hash := block.NumberU64(), block.Hash()
+	var events []*PKrWatchEvent
+	notify := func(kind string, watched *watchedPKr, root *keys.Uint256, pkg *keys.Uint256, txHash common.Hash) {
+		events = append(events, &PKrWatchEvent{
+			Type:        kind,
+			PKr:         common.BytesToAddress(watched.PKr[:]),
+			Label:       watched.Label,
+			Root:        root,
+			Pkg:         pkg,
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   hash,
+			TxHash:      txHash,
+		})
+	}
+
+	// The outs created by the block, found by their root in its state
+	if zblock != nil && len(zblock.Roots) > 0 {
+		state, _, err := w.b.StateAndHeaderByNumber(context.Background(), rpc.BlockNumber(number))
+		if err != nil || state == nil {
+			log.Warn("PKr watch skips the outs of a block", "number", number, "err", err)
+		} else {
+			zs := state.GetZState()
+			for i := range zblock.Roots {
+				root := zblock.Roots[i]
+				out, err := zs.State.GetOut(&root)
+				if err != nil || out == nil {
+					continue
+				}
+				watched := w.pkrs[*out.ToPKr()]
+				if watched == nil {
+					continue
+				}
+				txHash := outTx(block, out.ToPKr(), out.Out_Z)
+				watched.Outs = append(watched.Outs, &watchedOut{Root: root, Number: number, TxHash: txHash})
+				notify(WatchOutReceived, watched, &root, nil, txHash)
+			}
+		}
+	}
+
+	// The spends of the linked outs and the packages addressed to the PKrs
+	for _, tx := range block.Transactions() {
+		stxt := tx.Stxt()
+		for _, in := range stxt.Desc_O.Ins {
+			if watched, out := w.findOut(func(out *watchedOut) bool { return out.Root == in.Root }); out != nil {
+				out.SpentAt, out.SpentTx = number, tx.Hash()
+				notify(WatchOutSpent, watched, &out.Root, nil, tx.Hash())
+			}
+		}
+		for _, in := range stxt.Desc_Z.Ins {
+			if watched, out := w.findOut(func(out *watchedOut) bool { return out.Nil != nil && *out.Nil == in.Nil }); out != nil {
+				out.SpentAt, out.SpentTx = number, tx.Hash()
+				notify(WatchOutSpent, watched, &out.Root, nil, tx.Hash())
+			}
+		}
+		var (
+			id  keys.Uint256
+			pkr *keys.PKr
+		)
+		switch desc := stxt.Desc_Pkg; {
+		case desc.Create != nil:
+			id, pkr = desc.Create.Id, &desc.Create.PKr
+		case desc.Transfer != nil:
+			id, pkr = desc.Transfer.Id, &desc.Transfer.PKr
+		}
+		if pkr != nil {
+			if watched := w.pkrs[*pkr]; watched != nil {
+				watched.Pkgs = append(watched.Pkgs, &watchedPkg{Id: id, Number: number, TxHash: tx.Hash()})
+				notify(WatchPkgReceived, watched, nil, &id, tx.Hash())
+			}
+		}
+	}
+	if len(events) > 0 {
+		w.save()
+	}
+	return events
+}
+
+func (w *PKrWatcher) reorged(ancestor *types.Block, dropped []*types.Block) []*PKrWatchEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	top := ancestor.NumberU64()
+	hashes := make(map[uint64]common.Hash, len(dropped))
+	for _, block := range dropped {
+		hashes[block.NumberU64()] = block.Hash()
+	}
+	var events []*PKrWatchEvent
+	notify := func(kind string, watched *watchedPKr, root *keys.Uint256, pkg *keys.Uint256, number uint64, txHash common.Hash) {
+		events = append(events, &PKrWatchEvent{
+			Type:        kind,
+			PKr:         common.BytesToAddress(watched.PKr[:]),
+			Label:       watched.Label,
+			Root:        root,
+			Pkg:         pkg,
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   hashes[number],
+			TxHash:      txHash,
+			Removed:     true,
+		})
+	}
+	for _, watched := range w.pkrs {
+		outs := watched.Outs[:0]
+		for _, out := range watched.Outs {
+			if out.SpentAt > top {
+				notify(WatchOutSpent, watched, &out.Root, nil, out.SpentAt, out.SpentTx)
+				out.SpentAt, out.SpentTx = 0, common.Hash{}
+			}
+			if out.Number > top {
+				notify(WatchOutReceived, watched, &out.Root, nil, out.Number, out.TxHash)
+				continue
+			}
+			outs = append(outs, out)
+		}
+		watched.Outs = outs
+
+		pkgs := watched.Pkgs[:0]
+		for _, pkg := range watched.Pkgs {
+			if pkg.Number > top {
+				notify(WatchPkgReceived, watched, nil, &pkg.Id, pkg.Number, pkg.TxHash)
+				continue
+			}
+			pkgs = append(pkgs, pkg)
+		}
+		watched.Pkgs = pkgs
+	}
+	if len(events) > 0 {
+		w.save()
+	}
+	return events
+}
+
+// findOut returns the unspent out of a watched PKr matching a spend.
+func (w *PKrWatcher) findOut(match func(*watchedOut) bool) (*watchedPKr, *watchedOut) {
+	for _, watched := range w.pkrs {
+		for _, out := range watched.Outs {
+			if out.SpentAt == 0 && match(out) {
+				return watched, out
+			}
+		}
+	}
+	return nil, nil
+}
+
+// outTx returns the hash of the transaction of a block creating an out for a
+// PKr, Z outs being told apart by their commitment. Outs created by contracts
+// have no such transaction and are given the zero hash.
+func outTx(block *types.Block, pkr *keys.PKr, z *stx.Out_Z) common.Hash {
+	for _, tx := range block.Transactions() {
+		stxt := tx.Stxt()
+		if z != nil {
+			for _, out := range stxt.Desc_Z.Outs {
+				if out.OutCM == z.OutCM {
+					return tx.Hash()
+				}
+			}
+		} else {
+			for _, out := range stxt.Desc_O.Outs {
+				if out.Addr == *pkr {
+					return tx.Hash()
+				}
+			}
+		}
+	}
+	return common.Hash{}
+}
+
+// watch adds a PKr to the watch list, or relabels it.
+func (w *PKrWatcher) watch(pkr keys.PKr, label string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if watched := w.pkrs[pkr]; watched != nil {
+		watched.Label = label
+	} else {
+		if len(w.pkrs) >= maxWatchedPKrs {
+			return fmt.Errorf("at most %d PKrs are watched", maxWatchedPKrs)
+		}
+		w.pkrs[pkr] = &watchedPKr{PKr: pkr, Label: label, Since: w.b.CurrentBlock().NumberU64() + 1}
+	}
+	w.save()
+	w.register()
+	return nil
+}
+
+// unwatch removes a PKr from the watch list.
+func (w *PKrWatcher) unwatch(pkr keys.PKr) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pkrs[pkr] == nil {
+		return false
+	}
+	delete(w.pkrs, pkr)
+	w.save()
+	return true
+}
+
+// disclose links the spend of a Z out of a watched PKr by its nil.
+func (w *PKrWatcher) disclose(pkr keys.PKr, root keys.Uint256, nullifier keys.Uint256) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched := w.pkrs[pkr]
+	if watched == nil {
+		return errors.New("PKr is not watched")
+	}
+	for _, out := range watched.Outs {
+		if out.Root == root {
+			out.Nil = &nullifier
+			w.save()
+			return nil
+		}
+	}
+	return errors.New("out not received since the PKr is watched")
+}
+
+// list returns the summaries of the watched PKrs.
+func (w *PKrWatcher) list() []*WatchedPKr {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	list := make([]*WatchedPKr, 0, len(w.pkrs))
+	for _, watched := range w.pkrs {
+		summary := &WatchedPKr{
+			PKr:   common.BytesToAddress(watched.PKr[:]),
+			Label: watched.Label,
+			Since: hexutil.Uint64(watched.Since),
+			Outs:  hexutil.Uint64(len(watched.Outs)),
+			Pkgs:  hexutil.Uint64(len(watched.Pkgs)),
+		}
+		for _, out := range watched.Outs {
+			if out.SpentAt != 0 {
+				summary.Spent++
+			}
+			if out.Nil != nil {
+				summary.Disclosed++
+			}
+		}
+		list = append(list, summary)
+	}
+	return list
+}
+
+// PublicPKrWatchAPI offers the watch list of PKrs.
+type PublicPKrWatchAPI struct {
+	w *PKrWatcher
+}
+
+// NewPublicPKrWatchAPI creates the API of the PKr watcher.
+func NewPublicPKrWatchAPI(w *PKrWatcher) *PublicPKrWatchAPI {
+	return &PublicPKrWatchAPI{w}
+}
+
+// WatchPKr starts tracking the outs received and spent and the packages
+// received by a PKr from the next block on. Watching a watched PKr relabels
+// it.
+func (api *PublicPKrWatchAPI) WatchPKr(pkr common.Address, label string) error {
+	return api.w.watch(*pkr.ToPKr(), label)
+}
+
+// UnwatchPKr stops tracking a PKr and forgets what it received.
+func (api *PublicPKrWatchAPI) UnwatchPKr(pkr common.Address) bool {
+	return api.w.unwatch(*pkr.ToPKr())
+}
+
+// WatchedPKrs returns the watched PKrs with the counts of what they received
+// and spent.
+func (api *PublicPKrWatchAPI) WatchedPKrs() []*WatchedPKr {
+	return api.w.list()
+}
+
+// DiscloseOut links the spend of a Z out received by a watched PKr to it by
+// the nil its owner disclosed. The pair is taken as given: a wrong nil only
+// leaves the spend unnoticed.
+func (api *PublicPKrWatchAPI) DiscloseOut(pkr common.Address, root keys.Uint256, nullifier keys.Uint256) error {
+	return api.w.disclose(*pkr.ToPKr(), root, nullifier)
+}
+
+// PkrWatchEvents creates a subscription notifying the events of the watched
+// PKrs.
+func (api *PublicPKrWatchAPI) PkrWatchEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan *PKrWatchEvent, 64)
+		sub := api.w.SubscribeEvents(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case event := <-events:
+				notifier.Notify(rpcSub.ID, event)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/log"
+)
+
+const (
+	spendDay  = 24 * time.Hour
+	spendWeek = 7 * spendDay
+
+	// maxHeldSends is the number of sends waiting for approval at most.
+	maxHeldSends = 1024
+)
+
+// SpendPolicy limits the value local accounts send through the RPC interface
+// within a day and within a week. Sends exceeding a limit are held until one
+// of the approvers approves them through the policy API. Fees are not counted.
+type SpendPolicy struct {
+	Limits    map[string]SpendLimits `toml:",omitempty"` // by account address, "*" for accounts without own limits
+	Approvers []string               `toml:",omitempty"` // addresses of the accounts approving held sends
+}
+
+// SpendLimits are the amounts per currency an account may send within the
+// last 24 hours and the last 7 days. Currencies without limit are not limited.
+type SpendLimits struct {
+	Daily  map[string]*big.Int `toml:",omitempty"`
+	Weekly map[string]*big.Int `toml:",omitempty"`
+}
+
+// limits returns the limits of an account, nil if it is not limited.
+func (p SpendPolicy) limits(account common.AccountAddress) *SpendLimits {
+	if limits, ok := p.Limits[account.Base58()]; ok {
+		return &limits
+	}
+	if limits, ok := p.Limits["*"]; ok {
+		return &limits
+	}
+	return nil
+}
+
+// isApprover reports whether account may approve held sends.
+func (p SpendPolicy) isApprover(account common.AccountAddress) bool {
+	for _, approver := range p.Approvers {
+		if common.Base58ToAccount(approver) == account {
+			return true
+		}
+	}
+	return false
+}
+
+// HeldSend is a send exceeding the spend limits of its account.
+type HeldSend struct {
+	Id       common.Hash            `json:"id"`
+	From     common.AccountAddress  `json:"from"`
+	To       *common.AccountAddress `json:"to"`
+	Currency string                 `json:"currency"`
+	Value    *hexutil.Big           `json:"value"`
+	Reason   string                 `json:"reason"`
+	Time     hexutil.Uint64         `json:"time"`
+
+	args SendTxArgs
+}
+
+// spendGuard enforces the spend policy on the sends of the RPC interface. The
+// sends of an account are logged in the database, the held sends are kept in
+// memory and lost on restart.
+type spendGuard struct {
+	b    Backend
+	mu   sync.Mutex
+	held map[common.Hash]*HeldSend
+}
+
+func newSpendGuard(b Backend) *spendGuard {
+	return &spendGuard{b: b, held: make(map[common.Hash]*HeldSend)}
+}
+
+// recentSpends returns the sends of an account within the last week.
+func (g *spendGuard) recentSpends(account common.AccountAddress, now time.Time) []rawdb.SpendRecord {
+	horizon := uint64(now.Add(-spendWeek).Unix())
+	records := []rawdb.SpendRecord{}
+	for _, record := range rawdb.ReadSpendLog(g.b.ChainDb(), account) {
+		if record.Time > horizon {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// exceeded returns why sending value of currency from account exceeds its
+// limits, or an empty string if it does not.
+func (g *spendGuard) exceeded(account common.AccountAddress, currency string, value *big.Int, now time.Time) string {
+	policy := g.b.SpendPolicy()
+	limits := policy.limits(account)
+	if limits == nil {
+		return ""
+	}
+	day, week := new(big.Int).Set(value), new(big.Int).Set(value)
+	horizon := uint64(now.Add(-spendDay).Unix())
+	for _, record := range g.recentSpends(account, now) {
+		if record.Currency != currency {
+			continue
+		}
+		week.Add(week, record.Amount)
+		if record.Time > horizon {
+			day.Add(day, record.Amount)
+		}
+	}
+	if limit, ok := limits.Daily[currency]; ok && day.Cmp(limit) > 0 {
+		return fmt.Sprintf("daily %s limit of %v exceeded", currency, limit)
+	}
+	if limit, ok := limits.Weekly[currency]; ok && week.Cmp(limit) > 0 {
+		return fmt.Sprintf("weekly %s limit of %v exceeded", currency, limit)
+	}
+	return ""
+}
+
+// check holds the send if it exceeds the limits of the sender, failing with
+// the id of the held send. The caller holds the nonceLock.
+func (g *spendGuard) check(args *SendTxArgs) error {
+	spends := args.spends()
+	currencies := make([]string, 0, len(spends))
+	for currency := range spends {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	now := time.Now()
+	var currency, reason string
+	for _, currency = range currencies {
+		if reason = g.exceeded(args.From, currency, spends[currency], now); reason != "" {
+			break
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	value := (*hexutil.Big)(spends[currency])
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.held) >= maxHeldSends {
+		return fmt.Errorf("%s, too many sends waiting for approval", reason)
+	}
+	var id common.Hash
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	g.held[id] = &HeldSend{
+		Id:       id,
+		From:     args.From,
+		To:       args.To,
+		Currency: currency,
+		Value:    value,
+		Reason:   reason,
+		Time:     hexutil.Uint64(now.Unix()),
+		args:     *args,
+	}
+	log.Warn("Send held for approval", "id", id, "from", args.From, "currency", currency, "value", value.ToInt(), "reason", reason)
+	return fmt.Errorf("%s, send held for approval with id %x", reason, id)
+}
+
+// record logs a send of the sender, dropping the records older than a week.
+func (g *spendGuard) record(args *SendTxArgs) {
+	spends := args.spends()
+	if len(spends) == 0 || g.b.SpendPolicy().limits(args.From) == nil {
+		return
+	}
+	now := time.Now()
+	records := g.recentSpends(args.From, now)
+	for currency, amount := range spends {
+		records = append(records, rawdb.SpendRecord{
+			Time:     uint64(now.Unix()),
+			Currency: currency,
+			Amount:   amount,
+		})
+	}
+	rawdb.WriteSpendLog(g.b.ChainDb(), args.From, records)
+}
+
+// take removes a held send.
+func (g *spendGuard) take(id common.Hash) (*HeldSend, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	held, ok := g.held[id]
+	if !ok {
+		return nil, fmt.Errorf("no send held with id %x", id)
+	}
+	delete(g.held, id)
+	return held, nil
+}
+
+// restore puts back a held send taken for an unauthorized decision.
+func (g *spendGuard) restore(held *HeldSend) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.held[held.Id] = held
+}
+
+// list returns the held sends.
+func (g *spendGuard) list() []*HeldSend {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sends := make([]*HeldSend, 0, len(g.held))
+	for _, held := range g.held {
+		sends = append(sends, held)
+	}
+	return sends
+}
+
+// PrivatePolicyAPI lets the approvers of the spend policy approve or reject
+// the sends held for exceeding the limits of their accounts.
+type PrivatePolicyAPI struct {
+	b     Backend
+	pool  *PublicTransactionPoolAPI
+	spend *spendGuard
+}
+
+// NewPrivatePolicyAPI creates the policy API around the sends of pool.
+func NewPrivatePolicyAPI(b Backend, pool *PublicTransactionPoolAPI) *PrivatePolicyAPI {
+	return &PrivatePolicyAPI{b: b, pool: pool, spend: pool.spend}
+}
+
+// HeldSends returns the sends of the accounts of the caller waiting for
+// approval.
+func (s *PrivatePolicyAPI) HeldSends(ctx context.Context) []*HeldSend {
+	scope := scopeOf(ctx, s.b)
+	sends := []*HeldSend{}
+	for _, held := range s.spend.list() {
+		if scope.contains(accounts.Account{Address: held.From}) {
+			sends = append(sends, held)
+		}
+	}
+	return sends
+}
+
+// authorize checks that the sender and approver are accounts of the caller,
+// that approver is an approver of the policy other than the sender and that
+// passphrase decrypts its key.
+func (s *PrivatePolicyAPI) authorize(ctx context.Context, held *HeldSend, approver common.AccountAddress, passphrase string) error {
+	scope := scopeOf(ctx, s.b)
+	if !scope.contains(accounts.Account{Address: held.From}) {
+		return fmt.Errorf("no send held with id %x", held.Id)
+	}
+	policy := s.b.SpendPolicy()
+	if !policy.isApprover(approver) {
+		return errors.New("not an approver of the spend policy")
+	}
+	if approver == held.From {
+		return errors.New("sends can not be approved by their sender")
+	}
+	account := accounts.Account{Address: approver}
+	if !scope.contains(account) {
+		return accounts.ErrUnknownAccount
+	}
+	ks, err := scope.keystore()
+	if err != nil {
+		return err
+	}
+	if _, err := ks.GetSeedWithPassphrase(account, passphrase); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ApproveSend sends a held send on behalf of an approver, authenticated by
+// the passphrase of its account.
+func (s *PrivatePolicyAPI) ApproveSend(ctx context.Context, id common.Hash, approver common.AccountAddress, passphrase string) (common.Hash, error) {
+	held, err := s.spend.take(id)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.authorize(ctx, held, approver, passphrase); err != nil {
+		s.spend.restore(held)
+		return common.Hash{}, err
+	}
+	log.Info("Held send approved", "id", id, "approver", approver)
+	return s.pool.sendApproved(ctx, held.args)
+}
+
+// RejectSend drops a held send on behalf of an approver, authenticated by the
+// passphrase of its account.
+func (s *PrivatePolicyAPI) RejectSend(ctx context.Context, id common.Hash, approver common.AccountAddress, passphrase string) error {
+	held, err := s.spend.take(id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, held, approver, passphrase); err != nil {
+		s.spend.restore(held)
+		return err
+	}
+	log.Info("Held send rejected", "id", id, "approver", approver)
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/common/hexutil"
+)
+
+// ProofQueue orders the sends waiting for proof generation, which runs one
+// send at a time, by the weight of their callers. Callers are told apart by
+// the API key sent in the X-Api-Key header of their requests.
+type ProofQueue struct {
+	Callers map[string]ProofCaller `toml:",omitempty"` // by API key
+	Default ProofCaller            // callers without a known API key
+}
+
+// ProofCaller is the priority and the quota of a caller. Sends of heavier
+// callers are served first, sends of the same weight in arrival order.
+type ProofCaller struct {
+	Name      string
+	Weight    int
+	MaxQueued int `toml:",omitempty"` // sends waiting at most, 0 for no limit
+}
+
+// DefaultProofQueue serves all callers in arrival order.
+var DefaultProofQueue = ProofQueue{
+	Default: ProofCaller{Name: "default"},
+}
+
+// caller returns the caller of the request of ctx and its API key.
+func (q ProofQueue) caller(ctx context.Context) (string, ProofCaller) {
+	key, _ := ctx.Value("apikey").(string)
+	if caller, ok := q.Callers[key]; ok && key != "" {
+		if caller.Name == "" {
+			caller.Name = "unnamed"
+		}
+		return key, caller
+	}
+	return "", q.Default
+}
+
+// QueuedSend is a send waiting for or running its proof generation.
+type QueuedSend struct {
+	Id       hexutil.Uint64 `json:"id"`
+	Caller   string         `json:"caller"`
+	Weight   int            `json:"weight"`
+	Position int            `json:"position"` // 0 for the running send
+	Since    hexutil.Uint64 `json:"since"`
+}
+
+type proofTicket struct {
+	id     uint64
+	key    string
+	caller ProofCaller
+	since  time.Time
+	ready  chan struct{}
+}
+
+// proofQueue grants the proof generation to one send at a time.
+type proofQueue struct {
+	mu      sync.Mutex
+	seq     uint64
+	running *proofTicket
+	waiting []*proofTicket
+}
+
+// acquire waits until the send of the request of ctx is next, failing if its
+// caller exceeds its quota or the request is cancelled.
+func (q *proofQueue) acquire(ctx context.Context, config ProofQueue) (func(), error) {
+	key, caller := config.caller(ctx)
+
+	q.mu.Lock()
+	q.seq++
+	t := &proofTicket{id: q.seq, key: key, caller: caller, since: time.Now(), ready: make(chan struct{})}
+	if q.running == nil {
+		q.running = t
+		q.mu.Unlock()
+		return func() { q.release(t) }, nil
+	}
+	if caller.MaxQueued > 0 {
+		queued := 0
+		for _, w := range q.waiting {
+			if w.key == key {
+				queued++
+			}
+		}
+		if queued >= caller.MaxQueued {
+			q.mu.Unlock()
+			return nil, fmt.Errorf("%s has %d sends queued for proof generation already", caller.Name, queued)
+		}
+	}
+	// Queue behind the sends of the same or a higher weight
+	i := len(q.waiting)
+	for i > 0 && q.waiting[i-1].caller.Weight < caller.Weight {
+		i--
+	}
+	q.waiting = append(q.waiting, nil)
+	copy(q.waiting[i+1:], q.waiting[i:])
+	q.waiting[i] = t
+	q.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return func() { q.release(t) }, nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, w := range q.waiting {
+			if w == t {
+				q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// Handed over concurrently, pass it on
+		q.next()
+		return nil, ctx.Err()
+	}
+}
+
+func (q *proofQueue) release(t *proofTicket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running == t {
+		q.next()
+	}
+}
+
+// next hands the proof generation to the first waiting send.
+func (q *proofQueue) next() {
+	if len(q.waiting) == 0 {
+		q.running = nil
+		return
+	}
+	q.running, q.waiting = q.waiting[0], q.waiting[1:]
+	close(q.running.ready)
+}
+
+// status returns the running send followed by the waiting ones in order.
+func (q *proofQueue) status() []QueuedSend {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	sends := []QueuedSend{}
+	add := func(t *proofTicket, position int) {
+		sends = append(sends, QueuedSend{
+			Id:       hexutil.Uint64(t.id),
+			Caller:   t.caller.Name,
+			Weight:   t.caller.Weight,
+			Position: position,
+			Since:    hexutil.Uint64(t.since.Unix()),
+		})
+	}
+	if q.running != nil {
+		add(q.running, 0)
+	}
+	for i, t := range q.waiting {
+		add(t, i+1)
+	}
+	return sends
+}
+
+// ProofQueue returns the send running its proof generation and the sends
+// waiting for it with their positions.
+func (s *PublicTransactionPoolAPI) ProofQueue() []QueuedSend {
+	return s.nonceLock.queue.status()
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/params"
+	ztx "github.com/sero-cash/go-sero/zero/txs/tx"
+)
+
+// maxReceptions bounds the recipients of a transaction, each of them adding an
+// output to prove.
+const maxReceptions = 64
+
+// Reception is the payment of a transaction to one of its recipients.
+type Reception struct {
+	To       common.AccountAddress `json:"to"`
+	Currency Smbol                 `json:"cy"` // default SERO
+	Value    *hexutil.Big          `json:"value"`
+	Category Smbol                 `json:"catg"`
+	Tkt      *common.Hash          `json:"tkt"`
+	Memo     string                `json:"memo"`
+}
+
+// Asset is a token, a ticket or both paid to the recipient of a transaction
+// besides its value.
+type Asset struct {
+	Currency Smbol        `json:"cy"` // default SERO
+	Value    *hexutil.Big `json:"value"`
+	Category Smbol        `json:"catg"`
+	Tkt      *common.Hash `json:"tkt"`
+}
+
+// expandAssets turns a transfer of several assets to To into receptions of
+// the recipient, the first one carrying the payment fields of args and the
+// memo. Contracts are called with a single asset, they can not be paid so.
+func (args *SendTxArgs) expandAssets(state *state.StateDB) error {
+	if len(args.Receptions) > 0 {
+		return errors.New("assets and receptions can not be sent together")
+	}
+	if args.To == nil {
+		return errors.New("assets can not be sent to a contract creation")
+	}
+	if state.IsContract(common.BytesToAddress(args.To[:])) {
+		return errors.New("assets can not be sent to contracts")
+	}
+	receptions := make([]Reception, 0, len(args.Assets)+1)
+	if (args.Value != nil && args.Value.ToInt().Sign() != 0) || args.Tkt != nil {
+		receptions = append(receptions, Reception{
+			To:       *args.To,
+			Currency: args.Currency,
+			Value:    args.Value,
+			Category: args.Category,
+			Tkt:      args.Tkt,
+		})
+	}
+	for _, asset := range args.Assets {
+		receptions = append(receptions, Reception{
+			To:       *args.To,
+			Currency: asset.Currency,
+			Value:    asset.Value,
+			Category: asset.Category,
+			Tkt:      asset.Tkt,
+		})
+	}
+	receptions[0].Memo = args.Memo
+
+	args.To, args.Value, args.Currency, args.Category, args.Tkt, args.Memo = nil, nil, "", "", nil, ""
+	args.Receptions, args.Assets = receptions, nil
+	return nil
+}
+
+// checkReceptions validates the receptions of a transaction paying several
+// recipients and fills in their defaults. Such a transaction only transfers
+// assets, the payment fields of args must be left unset.
+func (args *SendTxArgs) checkReceptions(state *state.StateDB) error {
+	if len(args.Receptions) > maxReceptions {
+		return fmt.Errorf("too many receptions, at most %d are allowed", maxReceptions)
+	}
+	if args.To != nil || (args.Value != nil && args.Value.ToInt().Sign() != 0) || args.Tkt != nil || args.Category.IsNotEmpty() || args.Memo != "" {
+		return errors.New("to, value, catg, tkt and memo must be unset with receptions")
+	}
+	if args.Data != nil && len(*args.Data) > 0 {
+		return errors.New("receptions can not be sent with data")
+	}
+	for i := range args.Receptions {
+		r := &args.Receptions[i]
+		if state.IsContract(common.BytesToAddress(r.To[:])) {
+			return fmt.Errorf("reception %d: contracts can not be paid with receptions", i)
+		}
+		if len(r.Memo) > maxMemoLength {
+			return fmt.Errorf("reception %d: memo is too long, it's limited %d bytes", i, maxMemoLength)
+		}
+		if r.Currency.IsEmpty() {
+			r.Currency = Smbol(params.DefaultCurrency)
+		}
+		r.Currency = Smbol(strings.ToUpper(string(r.Currency)))
+		if r.Value == nil {
+			r.Value = new(hexutil.Big)
+		}
+		if r.Value.ToInt().Sign() < 0 {
+			return fmt.Errorf("reception %d: negative value", i)
+		}
+		if r.Category.IsEmpty() != (r.Tkt == nil) {
+			return fmt.Errorf("reception %d: catg and tkt must be set together", i)
+		}
+		if r.Value.ToInt().Sign() == 0 && r.Tkt == nil {
+			return fmt.Errorf("reception %d pays nothing", i)
+		}
+	}
+	return nil
+}
+
+// receptionOuts returns the shielded outputs paying the receptions, recording
+// their recipients in txt.
+func (args *SendTxArgs) receptionOuts(txt *ztx.T) []ztx.Out {
+	outs := make([]ztx.Out, 0, len(args.Receptions))
+	for _, r := range args.Receptions {
+		pkr := keys.Addr2PKr(r.To.ToUint512(), keys.RandUint256().NewRef())
+		txt.AddRecipient(pkr, r.To.ToUint512())
+		out := types.NewTxtOut(pkr, string(r.Currency), r.Value.ToInt(), string(r.Category), r.Tkt, r.Memo, true)
+		outs = append(outs, *out)
+	}
+	return outs
+}
+
+// spends sums the values a send pays to others by currency.
+func (args *SendTxArgs) spends() map[string]*big.Int {
+	spends := make(map[string]*big.Int)
+	add := func(to *common.AccountAddress, currency Smbol, value *hexutil.Big) {
+		if value == nil || value.ToInt().Sign() == 0 || (to != nil && *to == args.From) {
+			return
+		}
+		cy := strings.ToUpper(string(currency))
+		if spends[cy] == nil {
+			spends[cy] = new(big.Int)
+		}
+		spends[cy].Add(spends[cy], value.ToInt())
+	}
+	add(args.To, args.Currency, args.Value)
+	for i := range args.Receptions {
+		add(&args.Receptions[i].To, args.Receptions[i].Currency, args.Receptions[i].Value)
+	}
+	return spends
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// relayTimeout bounds the forwarding of a transaction to a gateway node.
+const relayTimeout = 30 * time.Second
+
+// forwardTransaction hands an encoded transaction to the node serving the RPC
+// endpoint url for broadcast, returning the hash it reports.
+func forwardTransaction(ctx context.Context, url string, encodedTx hexutil.Bytes) (common.Hash, error) {
+	ctx, cancel := context.WithTimeout(ctx, relayTimeout)
+	defer cancel()
+
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer client.Close()
+
+	var hash common.Hash
+	if err := client.CallContext(ctx, &hash, "sero_sendRawTransaction", encodedTx); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+// relayTransaction forwards a locally submitted transaction to the configured
+// gateway nodes in the background, so that it is broadcast even if the node
+// has no peers.
+func relayTransaction(b Backend, tx *types.Transaction) {
+	targets := b.RelayTargets()
+	if len(targets) == 0 {
+		return
+	}
+	encoded, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		log.Warn("Failed to encode relayed transaction", "hash", tx.Hash(), "err", err)
+		return
+	}
+	for _, url := range targets {
+		go func(url string) {
+			if _, err := forwardTransaction(context.Background(), url, encoded); err != nil {
+				log.Warn("Failed to relay transaction", "hash", tx.Hash(), "target", url, "err", err)
+				return
+			}
+			log.Debug("Relayed transaction", "hash", tx.Hash(), "target", url)
+		}(url)
+	}
+}
+
+// ForwardRawTransaction hands a signed transaction to a gateway node for
+// broadcast without adding it to the local pool. The url must be one of the
+// configured relay targets, an empty one forwards to all of them. It succeeds
+// if any target accepts the transaction.
+func (s *PublicTransactionPoolAPI) ForwardRawTransaction(ctx context.Context, url string, encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	targets := s.b.RelayTargets()
+	if url != "" {
+		known := false
+		for _, target := range targets {
+			known = known || target == url
+		}
+		if !known {
+			return common.Hash{}, fmt.Errorf("%s is not a relay target", url)
+		}
+		targets = []string{url}
+	}
+	if len(targets) == 0 {
+		return common.Hash{}, fmt.Errorf("no relay targets configured")
+	}
+	var lastErr error
+	forwarded := false
+	for _, target := range targets {
+		hash, err := forwardTransaction(ctx, target, encodedTx)
+		if err != nil {
+			log.Warn("Failed to forward transaction", "hash", tx.Hash(), "target", target, "err", err)
+			lastErr = err
+			continue
+		}
+		if hash != tx.Hash() {
+			log.Warn("Relay target reported another transaction hash", "hash", tx.Hash(), "target", target, "reported", hash)
+		}
+		forwarded = true
+	}
+	if !forwarded {
+		return common.Hash{}, lastErr
+	}
+	log.Info("Forwarded transaction", "fullhash", tx.Hash().Hex(), "targets", len(targets))
+	return tx.Hash(), nil
+}
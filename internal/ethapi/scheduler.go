@@ -0,0 +1,387 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/log"
+)
+
+const (
+	schedulerTick   = 10 * time.Second // interval of the checks for due payments
+	maxScheduleRuns = 100              // runs kept in the record of a schedule
+)
+
+// Scheduler sends the recurring payments of the payment schedules. The
+// funding accounts must stay unlocked, a run failing on a locked account is
+// retried as any other failure.
+type Scheduler struct {
+	b    Backend
+	pool *PublicTransactionPoolAPI
+
+	mu   sync.Mutex // serializes the updates of the stored schedules
+	quit chan chan struct{}
+}
+
+// NewScheduler creates the payment scheduler, sending through the transaction
+// pool API served to the clients.
+func NewScheduler(b Backend, pool *PublicTransactionPoolAPI) *Scheduler {
+	return &Scheduler{
+		b:    b,
+		pool: pool,
+		quit: make(chan chan struct{}),
+	}
+}
+
+// Start starts sending the due payments.
+func (s *Scheduler) Start() {
+	go s.loop()
+}
+
+// Stop stops the scheduler, waiting for the run in progress.
+func (s *Scheduler) Stop() {
+	done := make(chan struct{})
+	s.quit <- done
+	<-done
+}
+
+func (s *Scheduler) loop() {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(time.Now())
+		select {
+		case <-ticker.C:
+		case done := <-s.quit:
+			close(done)
+			return
+		}
+	}
+}
+
+// runDue sends the payments due at now. Runs missed while the node was down
+// are collapsed into a single one.
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db := s.b.ChainDb()
+	schedules := rawdb.ReadPaymentSchedules(db)
+	changed := false
+	for i := range schedules {
+		schedule := &schedules[i]
+		if schedule.Paused || schedule.Next == 0 || schedule.Next > uint64(now.Unix()) {
+			continue
+		}
+		s.run(schedule, now)
+		changed = true
+	}
+	if changed {
+		rawdb.WritePaymentSchedules(db, schedules)
+	}
+}
+
+// run sends a payment of a schedule and sets the time of its next attempt.
+func (s *Scheduler) run(schedule *rawdb.PaymentSchedule, now time.Time) {
+	hash, err := s.send(schedule, now)
+
+	record := rawdb.ScheduledRun{Time: uint64(now.Unix()), Attempt: schedule.Attempt, Hash: hash}
+	if err != nil {
+		record.Error = err.Error()
+		log.Warn("Scheduled payment failed", "schedule", schedule.Name, "tenant", schedule.Tenant, "attempt", schedule.Attempt, "err", err)
+	} else {
+		log.Info("Scheduled payment sent", "schedule", schedule.Name, "tenant", schedule.Tenant, "hash", hash)
+	}
+	schedule.Runs = append(schedule.Runs, record)
+	if len(schedule.Runs) > maxScheduleRuns {
+		schedule.Runs = schedule.Runs[len(schedule.Runs)-maxScheduleRuns:]
+	}
+
+	if err != nil && schedule.Attempt < schedule.MaxRetries {
+		schedule.Attempt++
+		schedule.Next = uint64(now.Unix()) + schedule.RetryDelay
+		return
+	}
+	if err == nil {
+		schedule.Sent++
+	}
+	schedule.Attempt = 0
+	schedule.Next = nextRun(schedule.Spec, now)
+}
+
+// send sends a payment of a schedule in the account scope of its tenant.
+func (s *Scheduler) send(schedule *rawdb.PaymentSchedule, now time.Time) (common.Hash, error) {
+	ctx := context.Background()
+	if schedule.Tenant != "" {
+		tenant, ok := s.b.Tenants()[schedule.Tenant]
+		if !ok || len(tenant.APIKeys) == 0 {
+			return common.Hash{}, fmt.Errorf("unknown tenant %s", schedule.Tenant)
+		}
+		ctx = context.WithValue(ctx, "apikey", tenant.APIKeys[0])
+	}
+	to := schedule.To
+	return s.pool.SendTransaction(ctx, SendTxArgs{
+		From:     schedule.From,
+		To:       &to,
+		Value:    (*hexutil.Big)(schedule.Amount),
+		Currency: Smbol(schedule.Currency),
+		Memo:     expandMemo(schedule.Memo, schedule.Amount, schedule.Sent+1, now),
+	})
+}
+
+// nextRun returns the unix time of the run of a cron specification following
+// now, zero if there is none.
+func nextRun(spec string, now time.Time) uint64 {
+	cron, err := parseCron(spec)
+	if err != nil {
+		return 0
+	}
+	next := cron.next(now)
+	if next.IsZero() {
+		return 0
+	}
+	return uint64(next.Unix())
+}
+
+// findSchedule returns the index of the schedule of a tenant with the given
+// name, -1 if there is none.
+func findSchedule(schedules []rawdb.PaymentSchedule, tenant string, name string) int {
+	for i := range schedules {
+		if schedules[i].Tenant == tenant && schedules[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// update applies f to the stored schedule of a tenant with the given name.
+func (s *Scheduler) update(tenant string, name string, f func(schedule *rawdb.PaymentSchedule)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db := s.b.ChainDb()
+	schedules := rawdb.ReadPaymentSchedules(db)
+	i := findSchedule(schedules, tenant, name)
+	if i < 0 {
+		return fmt.Errorf("unknown payment schedule %s", name)
+	}
+	f(&schedules[i])
+	rawdb.WritePaymentSchedules(db, schedules)
+	return nil
+}
+
+// PaymentSchedule is a shielded payment of a local account the node sends at
+// the times of a cron specification (UTC). The memo is a pattern as the one
+// of the send templates. A failed run is retried up to MaxRetries times,
+// RetryDelay seconds apart, before waiting for the next time of the spec.
+type PaymentSchedule struct {
+	Name       string                `json:"name"`
+	Spec       string                `json:"spec"`
+	From       common.AccountAddress `json:"from"`
+	To         common.AccountAddress `json:"to"`
+	Currency   Smbol                 `json:"cy"`
+	Amount     *hexutil.Big          `json:"amount"`
+	Memo       string                `json:"memo"`
+	MaxRetries hexutil.Uint64        `json:"maxRetries"`
+	RetryDelay hexutil.Uint64        `json:"retryDelay"`
+	Paused     bool                  `json:"paused"`
+	Next       hexutil.Uint64        `json:"next"`
+	Sent       hexutil.Uint64        `json:"sent"`
+}
+
+func newPaymentSchedule(stored *rawdb.PaymentSchedule) *PaymentSchedule {
+	return &PaymentSchedule{
+		Name:       stored.Name,
+		Spec:       stored.Spec,
+		From:       stored.From,
+		To:         stored.To,
+		Currency:   Smbol(stored.Currency),
+		Amount:     (*hexutil.Big)(stored.Amount),
+		Memo:       stored.Memo,
+		MaxRetries: hexutil.Uint64(stored.MaxRetries),
+		RetryDelay: hexutil.Uint64(stored.RetryDelay),
+		Paused:     stored.Paused,
+		Next:       hexutil.Uint64(stored.Next),
+		Sent:       hexutil.Uint64(stored.Sent),
+	}
+}
+
+// ScheduledRun is an attempt of a payment schedule, with the hash of the
+// transaction or the error of the attempt.
+type ScheduledRun struct {
+	Time    hexutil.Uint64 `json:"time"`
+	Attempt hexutil.Uint64 `json:"attempt"`
+	Hash    *common.Hash   `json:"hash"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// PrivateSchedulerAPI manages the payment schedules. Schedules are only
+// visible to the tenant of the caller.
+type PrivateSchedulerAPI struct {
+	s *Scheduler
+}
+
+// NewPrivateSchedulerAPI creates the management API of the payment scheduler.
+func NewPrivateSchedulerAPI(s *Scheduler) *PrivateSchedulerAPI {
+	return &PrivateSchedulerAPI{s}
+}
+
+// AddSchedule stores a payment schedule, replacing the one of the same name,
+// and returns the unix time of its first run.
+func (api *PrivateSchedulerAPI) AddSchedule(ctx context.Context, schedule PaymentSchedule) (hexutil.Uint64, error) {
+	if schedule.Name == "" {
+		return 0, errors.New("schedule name can not be empty")
+	}
+	if schedule.To == (common.AccountAddress{}) {
+		return 0, errors.New("schedule recipient can not be empty")
+	}
+	if schedule.Amount == nil || schedule.Amount.ToInt().Sign() <= 0 {
+		return 0, errors.New("schedule amount must be positive")
+	}
+	cron, err := parseCron(schedule.Spec)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	next := cron.next(now)
+	if next.IsZero() {
+		return 0, fmt.Errorf("cron spec %q never runs", schedule.Spec)
+	}
+	b := api.s.b
+	scope := scopeOf(ctx, b)
+	if _, err := scope.find(accounts.Account{Address: schedule.From}); err != nil {
+		return 0, err
+	}
+	maxMemo := b.TxDefaults().sanitize().MaxMemo
+	if len(expandMemo(schedule.Memo, schedule.Amount.ToInt(), math.MaxUint64, now)) > maxMemo {
+		return 0, fmt.Errorf("schedule memo may exceed %d bytes", maxMemo)
+	}
+
+	stored := rawdb.PaymentSchedule{
+		Name:       schedule.Name,
+		Tenant:     scope.tenant,
+		Spec:       schedule.Spec,
+		From:       schedule.From,
+		To:         schedule.To,
+		Currency:   strings.ToUpper(string(schedule.Currency)),
+		Amount:     new(big.Int).Set(schedule.Amount.ToInt()),
+		Memo:       schedule.Memo,
+		MaxRetries: uint64(schedule.MaxRetries),
+		RetryDelay: uint64(schedule.RetryDelay),
+		Paused:     schedule.Paused,
+		Next:       uint64(next.Unix()),
+	}
+	api.s.mu.Lock()
+	defer api.s.mu.Unlock()
+
+	db := b.ChainDb()
+	schedules := rawdb.ReadPaymentSchedules(db)
+	if i := findSchedule(schedules, scope.tenant, schedule.Name); i >= 0 {
+		stored.Sent = schedules[i].Sent
+		stored.Runs = schedules[i].Runs
+		schedules[i] = stored
+	} else {
+		schedules = append(schedules, stored)
+	}
+	rawdb.WritePaymentSchedules(db, schedules)
+	return hexutil.Uint64(stored.Next), nil
+}
+
+// RemoveSchedule removes a payment schedule, returning whether it existed.
+func (api *PrivateSchedulerAPI) RemoveSchedule(ctx context.Context, name string) bool {
+	tenant := scopeOf(ctx, api.s.b).tenant
+
+	api.s.mu.Lock()
+	defer api.s.mu.Unlock()
+
+	db := api.s.b.ChainDb()
+	schedules := rawdb.ReadPaymentSchedules(db)
+	i := findSchedule(schedules, tenant, name)
+	if i < 0 {
+		return false
+	}
+	rawdb.WritePaymentSchedules(db, append(schedules[:i], schedules[i+1:]...))
+	return true
+}
+
+// PauseSchedule stops the runs of a payment schedule until it is resumed.
+func (api *PrivateSchedulerAPI) PauseSchedule(ctx context.Context, name string) error {
+	return api.s.update(scopeOf(ctx, api.s.b).tenant, name, func(schedule *rawdb.PaymentSchedule) {
+		schedule.Paused = true
+	})
+}
+
+// ResumeSchedule resumes a paused payment schedule at the next time of its
+// spec, the runs missed while paused are skipped.
+func (api *PrivateSchedulerAPI) ResumeSchedule(ctx context.Context, name string) error {
+	return api.s.update(scopeOf(ctx, api.s.b).tenant, name, func(schedule *rawdb.PaymentSchedule) {
+		if schedule.Paused {
+			schedule.Paused = false
+			schedule.Attempt = 0
+			schedule.Next = nextRun(schedule.Spec, time.Now())
+		}
+	})
+}
+
+// Schedules returns the payment schedules of the tenant of the caller.
+func (api *PrivateSchedulerAPI) Schedules(ctx context.Context) []*PaymentSchedule {
+	tenant := scopeOf(ctx, api.s.b).tenant
+	schedules := []*PaymentSchedule{}
+	for _, stored := range rawdb.ReadPaymentSchedules(api.s.b.ChainDb()) {
+		if stored.Tenant == tenant {
+			schedules = append(schedules, newPaymentSchedule(&stored))
+		}
+	}
+	return schedules
+}
+
+// Runs returns the recent runs of a payment schedule, oldest first.
+func (api *PrivateSchedulerAPI) Runs(ctx context.Context, name string) ([]*ScheduledRun, error) {
+	tenant := scopeOf(ctx, api.s.b).tenant
+	schedules := rawdb.ReadPaymentSchedules(api.s.b.ChainDb())
+	i := findSchedule(schedules, tenant, name)
+	if i < 0 {
+		return nil, fmt.Errorf("unknown payment schedule %s", name)
+	}
+	runs := make([]*ScheduledRun, 0, len(schedules[i].Runs))
+	for _, stored := range schedules[i].Runs {
+		run := &ScheduledRun{
+			Time:    hexutil.Uint64(stored.Time),
+			Attempt: hexutil.Uint64(stored.Attempt),
+			Error:   stored.Error,
+		}
+		if stored.Hash != (common.Hash{}) {
+			hash := stored.Hash
+			run.Hash = &hash
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,319 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+)
+
+const (
+	// defaultSessionIdle is the idle timeout of the sessions opened without one.
+	defaultSessionIdle = 15 * time.Minute
+
+	// maxSessionIdle is the longest idle timeout of a session.
+	maxSessionIdle = 24 * time.Hour
+
+	// maxSessions is the number of sessions open at once.
+	maxSessions = 256
+)
+
+var (
+	errUnknownSession  = errors.New("unknown or expired session")
+	errTooManySessions = errors.New("too many open sessions")
+	errSessionAccount  = errors.New("account is not unlocked in the session")
+)
+
+// SessionOptions are the settings of a session.
+type SessionOptions struct {
+	IdleTimeout *hexutil.Uint64 `json:"idleTimeout"` // seconds without a request the session lasts, default 15 minutes
+	Gas         *hexutil.Uint64 `json:"gas"`         // gas of the transactions not setting it
+	GasPrice    *hexutil.Big    `json:"gasPrice"`    // gas price of the transactions not setting it
+}
+
+// SessionInfo describes a session. The token is only returned when the session
+// is opened; the id identifies the session to revoke it without the token.
+type SessionInfo struct {
+	Id       string                  `json:"id"`
+	Token    string                  `json:"token,omitempty"`
+	Accounts []common.AccountAddress `json:"accounts"`
+	Opened   hexutil.Uint64          `json:"opened"`
+	LastUsed hexutil.Uint64          `json:"lastUsed"`
+	Expires  hexutil.Uint64          `json:"expires"`
+	Options  SessionOptions          `json:"options"`
+}
+
+// session is an authenticated client with the accounts it unlocked. The
+// passphrases are kept in memory only, for the session to sign with, and are
+// dropped when the session is closed, revoked or expires.
+type session struct {
+	id       string
+	tenant   string
+	keys     map[common.AccountAddress]string // passphrases by account
+	opened   time.Time
+	lastUsed time.Time
+	idle     time.Duration
+	options  SessionOptions
+}
+
+func (s *session) expired(now time.Time) bool {
+	return now.Sub(s.lastUsed) > s.idle
+}
+
+func (s *session) info() *SessionInfo {
+	info := &SessionInfo{
+		Id:       s.id,
+		Accounts: []common.AccountAddress{},
+		Opened:   hexutil.Uint64(s.opened.Unix()),
+		LastUsed: hexutil.Uint64(s.lastUsed.Unix()),
+		Expires:  hexutil.Uint64(s.lastUsed.Add(s.idle).Unix()),
+		Options:  s.options,
+	}
+	for account := range s.keys {
+		info.Accounts = append(info.Accounts, account)
+	}
+	return info
+}
+
+// PrivateSessionAPI lets interactive clients authenticate once and sign
+// with the accounts of a session token, instead of sending the passphrase
+// with every request or unlocking the accounts for all clients of the node.
+type PrivateSessionAPI struct {
+	b        Backend
+	personal *PrivateAccountAPI
+
+	mu       sync.Mutex
+	sessions map[string]*session // by token
+}
+
+// NewPrivateSessionAPI creates the session API signing with personal.
+func NewPrivateSessionAPI(b Backend, personal *PrivateAccountAPI) *PrivateSessionAPI {
+	return &PrivateSessionAPI{
+		b:        b,
+		personal: personal,
+		sessions: make(map[string]*session),
+	}
+}
+
+// expire drops the sessions idle for longer than their timeout.
+func (api *PrivateSessionAPI) expire(now time.Time) {
+	for token, s := range api.sessions {
+		if s.expired(now) {
+			delete(api.sessions, token)
+			log.Debug("Session expired", "id", s.id)
+		}
+	}
+}
+
+// use returns the session of a token for a request of ctx, refreshing its
+// idle timeout. The sessions of other tenants are unknown.
+func (api *PrivateSessionAPI) use(ctx context.Context, token string) (*session, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	now := time.Now()
+	api.expire(now)
+	s, ok := api.sessions[token]
+	if !ok || s.tenant != scopeOf(ctx, api.b).tenant {
+		return nil, errUnknownSession
+	}
+	s.lastUsed = now
+	return s, nil
+}
+
+// authenticate checks the passphrase of an account of the scope of ctx.
+func (api *PrivateSessionAPI) authenticate(ctx context.Context, addr common.AccountAddress, passphrase string) error {
+	ks, err := scopeOf(ctx, api.b).keystore()
+	if err != nil {
+		return err
+	}
+	_, err = ks.GetSeedWithPassphrase(accounts.Account{Address: addr}, passphrase)
+	return err
+}
+
+// Open authenticates with the passphrase of an account and opens a session
+// with it unlocked, returning the token of the session.
+func (api *PrivateSessionAPI) Open(ctx context.Context, addr common.AccountAddress, passphrase string, options *SessionOptions) (*SessionInfo, error) {
+	if err := api.authenticate(ctx, addr, passphrase); err != nil {
+		return nil, err
+	}
+	s := &session{
+		tenant: scopeOf(ctx, api.b).tenant,
+		keys:   map[common.AccountAddress]string{addr: passphrase},
+		idle:   defaultSessionIdle,
+	}
+	if options != nil {
+		s.options = *options
+		if options.IdleTimeout != nil {
+			idle := uint64(*options.IdleTimeout)
+			if idle == 0 || idle > uint64(maxSessionIdle/time.Second) {
+				return nil, errors.New("idle timeout must be between 1 second and 24 hours")
+			}
+			s.idle = time.Duration(idle) * time.Second
+		}
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := hexutil.Encode(secret)
+	s.id = hexutil.Encode(crypto.Keccak256(secret)[:8])
+	s.opened = time.Now()
+	s.lastUsed = s.opened
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.expire(s.opened)
+	if len(api.sessions) >= maxSessions {
+		return nil, errTooManySessions
+	}
+	api.sessions[token] = s
+	log.Info("Session opened", "id", s.id, "account", addr, "idle", s.idle)
+
+	info := s.info()
+	info.Token = token
+	return info, nil
+}
+
+// Unlock authenticates with the passphrase of a further account and unlocks
+// it in the session.
+func (api *PrivateSessionAPI) Unlock(ctx context.Context, token string, addr common.AccountAddress, passphrase string) (*SessionInfo, error) {
+	if _, err := api.use(ctx, token); err != nil {
+		return nil, err
+	}
+	if err := api.authenticate(ctx, addr, passphrase); err != nil {
+		return nil, err
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	s, ok := api.sessions[token]
+	if !ok {
+		return nil, errUnknownSession
+	}
+	s.keys[addr] = passphrase
+	return s.info(), nil
+}
+
+// Lock removes an account from the session.
+func (api *PrivateSessionAPI) Lock(ctx context.Context, token string, addr common.AccountAddress) (*SessionInfo, error) {
+	s, err := api.use(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	delete(s.keys, addr)
+	return s.info(), nil
+}
+
+// Info returns the session of a token.
+func (api *PrivateSessionAPI) Info(ctx context.Context, token string) (*SessionInfo, error) {
+	s, err := api.use(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return s.info(), nil
+}
+
+// Close closes the session of a token.
+func (api *PrivateSessionAPI) Close(ctx context.Context, token string) error {
+	s, err := api.use(ctx, token)
+	if err != nil {
+		return err
+	}
+	api.mu.Lock()
+	delete(api.sessions, token)
+	api.mu.Unlock()
+
+	log.Info("Session closed", "id", s.id)
+	return nil
+}
+
+// List returns the open sessions of the tenant of the request, without their
+// tokens.
+func (api *PrivateSessionAPI) List(ctx context.Context) []*SessionInfo {
+	tenant := scopeOf(ctx, api.b).tenant
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.expire(time.Now())
+	infos := []*SessionInfo{}
+	for _, s := range api.sessions {
+		if s.tenant == tenant {
+			infos = append(infos, s.info())
+		}
+	}
+	return infos
+}
+
+// Revoke closes a session by its id, e.g. when its token was stolen. It
+// reports whether the session was open.
+func (api *PrivateSessionAPI) Revoke(ctx context.Context, id string) bool {
+	tenant := scopeOf(ctx, api.b).tenant
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	for token, s := range api.sessions {
+		if s.id == id && s.tenant == tenant {
+			delete(api.sessions, token)
+			log.Warn("Session revoked", "id", id)
+			return true
+		}
+	}
+	return false
+}
+
+// SendTransaction signs a transaction with an account of the session and
+// submits it. The gas settings of the session apply unless the transaction
+// sets them.
+func (api *PrivateSessionAPI) SendTransaction(ctx context.Context, token string, args SendTxArgs) (common.Hash, error) {
+	s, err := api.use(ctx, token)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	api.mu.Lock()
+	passphrase, ok := s.keys[args.From]
+	options := s.options
+	api.mu.Unlock()
+
+	if !ok {
+		return common.Hash{}, errSessionAccount
+	}
+	if args.Gas == nil {
+		args.Gas = options.Gas
+	}
+	if args.GasPrice == nil {
+		args.GasPrice = options.GasPrice
+	}
+	return api.personal.SendTransaction(ctx, args, passphrase)
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// APIVersion is the version of the stable sero namespace. The major version is
+// bumped on incompatible changes to any of the stableMethods, the minor version
+// when methods are added to them.
+const APIVersion = "1.1"
+
+// stableMethods are the methods of the sero namespace covered by APIVersion.
+// Methods of the namespace missing here may change without notice.
+var stableMethods = []string{
+	"sero_apiVersion",
+	"sero_protocolVersion",
+	"sero_syncing",
+	"sero_gasPrice",
+	"sero_accounts",
+	"sero_blockNumber",
+	"sero_getBalance",
+	"sero_getBlockByNumber",
+	"sero_getBlockByHash",
+	"sero_getBlockReward",
+	"sero_getTokenInfo",
+	"sero_getTransactionByHash",
+	"sero_getTransactionReceipt",
+	"sero_getTransactionCount",
+	"sero_call",
+	"sero_estimateGas",
+	"sero_sendTransaction",
+	"sero_resendTransaction",
+	"sero_getPackage",
+	"sero_watchPackage",
+	"sero_createPackage",
+	"sero_closePackage",
+	"sero_transferPackage",
+}
+
+// APIVersionInfo describes the stable sero namespace.
+type APIVersionInfo struct {
+	Version string   `json:"version"`
+	Methods []string `json:"methods"`
+}
+
+// PublicStableAPI completes the stable sero namespace with consistently named
+// methods for the ones whose original names are kept for compatibility.
+type PublicStableAPI struct {
+	chain *PublicBlockChainAPI
+	pool  *PublicTransactionPoolAPI
+}
+
+// NewPublicStableAPI creates a new stable sero namespace API.
+func NewPublicStableAPI(b Backend, nonceLock *AddrLocker, spend *spendGuard) *PublicStableAPI {
+	return &PublicStableAPI{
+		chain: NewPublicBlockChainAPI(b),
+		pool:  NewPublicTransactionPoolAPI(b, nonceLock, spend),
+	}
+}
+
+// ApiVersion returns the version of the stable sero namespace and its methods.
+func (s *PublicStableAPI) ApiVersion() APIVersionInfo {
+	return APIVersionInfo{
+		Version: APIVersion,
+		Methods: append([]string(nil), stableMethods...),
+	}
+}
+
+// GetBlockReward returns the rewards paid by the given block.
+func (s *PublicStableAPI) GetBlockReward(ctx context.Context, blockNr rpc.BlockNumber) [3]hexutil.Big {
+	return s.chain.GetBlockRewardByNumber(ctx, blockNr)
+}
+
+// ResendTransaction resubmits a transaction of a local account.
+func (s *PublicStableAPI) ResendTransaction(ctx context.Context, txhash common.Hash) (common.Hash, error) {
+	return s.pool.ReSendTransaction(ctx, txhash)
+}
+
+// GetPackage returns the packages sent (packed) or received by the account,
+// or only the one with the given id.
+func (s *PublicStableAPI) GetPackage(ctx context.Context, address common.AccountAddress, packed bool, id *keys.Uint256) (interface{}, error) {
+	return s.chain.GetPkg(ctx, address, packed, id)
+}
+
+// WatchPackage opens the package with the given id and key.
+func (s *PublicStableAPI) WatchPackage(ctx context.Context, id keys.Uint256, key keys.Uint256) (map[string]interface{}, error) {
+	return s.chain.WatchPkg(ctx, id, key)
+}
+
+// CreatePackage sends a transaction creating a package.
+func (s *PublicStableAPI) CreatePackage(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return s.pool.CreatePkg(ctx, args)
+}
+
+// ClosePackage sends a transaction closing a received package.
+func (s *PublicStableAPI) ClosePackage(ctx context.Context, args ClosePkgArgs) (common.Hash, error) {
+	return s.pool.ClosePkg(ctx, args)
+}
+
+// TransferPackage sends a transaction handing a package over to another account.
+func (s *PublicStableAPI) TransferPackage(ctx context.Context, args TransferPkgArgs) (common.Hash, error) {
+	return s.pool.TransferPkg(ctx, args)
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/txs"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+// AccountSummary is an overview of the activity of a local account. The
+// counters and totals cover the activity scanned since the account index was
+// introduced, FirstBlock and LastBlock are nil without such activity.
+type AccountSummary struct {
+	Outs         hexutil.Uint64          `json:"outs"` // unspent outs
+	ReceivedOuts hexutil.Uint64          `json:"receivedOuts"`
+	SpentOuts    hexutil.Uint64          `json:"spentOuts"`
+	FirstBlock   *hexutil.Uint64         `json:"firstBlock"`
+	LastBlock    *hexutil.Uint64         `json:"lastBlock"`
+	Received     map[string]*hexutil.Big `json:"received"` // including the change
+	Sent         map[string]*hexutil.Big `json:"sent"`     // value of the spent outs
+	Pending      PendingActivity         `json:"pending"`
+}
+
+// PendingActivity is the activity of an account in the transaction pool.
+type PendingActivity struct {
+	Transactions hexutil.Uint64          `json:"transactions"` // pooled transactions spending outs of the account
+	Locked       map[string]*hexutil.Big `json:"locked"`       // value of the outs they spend
+}
+
+// GetAccountSummary returns the out counts, the first and last active blocks,
+// the totals received and sent per currency and the pool activity of a local
+// account.
+func (s *PublicBlockChainAPI) GetAccountSummary(ctx context.Context, address common.AccountAddress) (*AccountSummary, error) {
+	wallet, err := scopeOf(ctx, s.b).find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return nil, errors.New("local outs are not scanned yet")
+	}
+	tk := wallet.Accounts()[0].Tk.ToUint512()
+	outs, err := txs.GetOuts(tk)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &AccountSummary{
+		Outs:     hexutil.Uint64(len(outs)),
+		Received: map[string]*hexutil.Big{},
+		Sent:     map[string]*hexutil.Big{},
+		Pending:  PendingActivity{Locked: map[string]*hexutil.Big{}},
+	}
+	if index := st1.GetAccountIndex(tk); index != nil {
+		if index.Received+index.Spent > 0 {
+			first, last := hexutil.Uint64(index.FirstNum), hexutil.Uint64(index.LastNum)
+			summary.FirstBlock, summary.LastBlock = &first, &last
+		}
+		summary.ReceivedOuts = hexutil.Uint64(index.Received)
+		summary.SpentOuts = hexutil.Uint64(index.Spent)
+		addTokens(summary.Received, index.ReceivedTkns)
+		addTokens(summary.Sent, index.SpentTkns)
+	}
+
+	mine := make(map[keys.Uint256]*lstate.OutState)
+	for _, out := range outs {
+		mine[out.Root] = out
+		mine[out.Trace] = out
+	}
+	pending, queued := s.b.TxPoolContent()
+	for _, tx := range append(pending, queued...) {
+		stxt := tx.GetZZSTX()
+		if stxt == nil {
+			continue
+		}
+		spent := []*lstate.OutState{}
+		for _, in := range stxt.Desc_O.Ins {
+			if out, ok := mine[in.Root]; ok {
+				spent = append(spent, out)
+			}
+		}
+		for _, in := range stxt.Desc_Z.Ins {
+			if out, ok := mine[in.Trace]; ok {
+				spent = append(spent, out)
+			}
+		}
+		if len(spent) == 0 {
+			continue
+		}
+		summary.Pending.Transactions++
+		for _, out := range spent {
+			if tkn := out.Out_O.Asset.Tkn; tkn != nil {
+				addBalance(summary.Pending.Locked, strings.Trim(string(tkn.Currency[:]), zerobyte), tkn.Value.ToIntRef())
+			}
+		}
+	}
+	return summary, nil
+}
+
+func addTokens(balances map[string]*hexutil.Big, tkns []assets.Token) {
+	for _, tkn := range tkns {
+		addBalance(balances, strings.Trim(string(tkn.Currency[:]), zerobyte), tkn.Value.ToIntRef())
+	}
+}
@@ -0,0 +1,303 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+const (
+	// supplyCheckpointInterval is the number of blocks between the indexed
+	// emission sums, so supply queries replay the schedule of fewer blocks.
+	supplyCheckpointInterval = 1024
+
+	// rewardPayoutInterval is the number of blocks the team and community
+	// rewards accumulate in their pools before being paid out.
+	rewardPayoutInterval = 5000
+)
+
+var (
+	supplyCheckpointPrefix = []byte("supplyindex-c") // + block number -> supply checkpoint
+	supplyProgressKey      = []byte("supplyindex-p") // -> newest checkpoint block number
+)
+
+// supplyCheckpoint holds the emission sums up to and including a block.
+type supplyCheckpoint struct {
+	Hash    common.Hash
+	Paid    *big.Int // rewards paid out of the genesis reward pool before V2
+	Minted  *big.Int // rewards issued since V2
+	Pending *big.Int // team and community rewards not paid out yet
+}
+
+func newSupplyCheckpoint() *supplyCheckpoint {
+	return &supplyCheckpoint{Paid: new(big.Int), Minted: new(big.Int), Pending: new(big.Int)}
+}
+
+// add adds the scheduled rewards of a block to the sums.
+func (c *supplyCheckpoint) add(header *types.Header) {
+	rewards := GetBlockReward(header.Number, header.Difficulty, header.GasUsed, header.GasLimit)
+	if number := header.Number.Uint64(); number < ethash.V2Number {
+		c.Paid.Add(c.Paid, rewards[0])
+		if c.Paid.Cmp(core.GenesisRewardPool) > 0 {
+			c.Paid.Set(core.GenesisRewardPool)
+		}
+	} else {
+		c.Minted.Add(c.Minted, rewards[0]).Add(c.Minted, rewards[1]).Add(c.Minted, rewards[2])
+		c.Pending.Add(c.Pending, rewards[1]).Add(c.Pending, rewards[2])
+		if number%rewardPayoutInterval == 0 {
+			c.Pending.SetUint64(0)
+		}
+	}
+	c.Hash = header.Hash()
+}
+
+// SupplyIndex keeps the emission sums of every 1024th block of the canonical
+// chain, indexing the new blocks in the background, so the supply at a block
+// is derived from the schedule of at most 1023 blocks.
+type SupplyIndex struct {
+	b    Backend
+	db   serodb.Database
+	quit chan chan struct{}
+
+	lock    sync.RWMutex
+	indexed uint64 // newest checkpoint block
+}
+
+// NewSupplyIndex creates the supply index stored in db.
+func NewSupplyIndex(b Backend, db serodb.Database) *SupplyIndex {
+	x := &SupplyIndex{b: b, db: db, quit: make(chan chan struct{})}
+	if data, _ := db.Get(supplyProgressKey); len(data) == 8 {
+		x.indexed = binary.BigEndian.Uint64(data)
+	}
+	return x
+}
+
+// Start starts indexing the blocks in the background.
+func (x *SupplyIndex) Start() {
+	go x.loop()
+}
+
+// Stop stops the indexing.
+func (x *SupplyIndex) Stop() {
+	done := make(chan struct{})
+	x.quit <- done
+	<-done
+}
+
+func (x *SupplyIndex) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := x.b.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		if done := x.catchUp(); done != nil {
+			close(done)
+			return
+		}
+		select {
+		case <-heads:
+		case <-sub.Err():
+			done := <-x.quit
+			close(done)
+			return
+		case done := <-x.quit:
+			close(done)
+			return
+		}
+	}
+}
+
+// catchUp drops the checkpoints reorged out and indexes the blocks imported
+// since the last run. It returns the channel of a stop request received
+// meanwhile.
+func (x *SupplyIndex) catchUp() chan struct{} {
+	ctx := context.Background()
+	head := x.b.CurrentBlock().NumberU64()
+
+	last := x.progress()
+	sums := newSupplyCheckpoint()
+	for ; last > 0; last -= supplyCheckpointInterval {
+		if checkpoint := x.checkpoint(last); checkpoint != nil {
+			if header, _ := x.b.HeaderByNumber(ctx, rpc.BlockNumber(last)); header != nil && header.Hash() == checkpoint.Hash {
+				sums = checkpoint
+				break
+			}
+		}
+		x.db.Delete(supplyCheckpointKey(last))
+	}
+	x.setProgress(last)
+
+	for number := last + 1; number <= head-head%supplyCheckpointInterval; number++ {
+		select {
+		case done := <-x.quit:
+			return done
+		default:
+		}
+		header, err := x.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil || header == nil {
+			log.Warn("Supply index header missing", "number", number, "err", err)
+			return nil
+		}
+		sums.add(header)
+		if number%supplyCheckpointInterval != 0 {
+			continue
+		}
+		data, err := rlp.EncodeToBytes(sums)
+		if err != nil {
+			log.Error("Failed to encode supply checkpoint", "number", number, "err", err)
+			return nil
+		}
+		batch := x.db.NewBatch()
+		batch.Put(supplyCheckpointKey(number), data)
+		batch.Put(supplyProgressKey, encodeBlockNumber(number))
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to write supply index", "number", number, "err", err)
+			return nil
+		}
+		x.setProgress(number)
+	}
+	return nil
+}
+
+// progress returns the newest checkpoint block.
+func (x *SupplyIndex) progress() uint64 {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	return x.indexed
+}
+
+func (x *SupplyIndex) setProgress(number uint64) {
+	x.lock.Lock()
+	x.indexed = number
+	x.lock.Unlock()
+}
+
+// checkpoint returns the stored sums of a block, nil if missing.
+func (x *SupplyIndex) checkpoint(number uint64) *supplyCheckpoint {
+	data, _ := x.db.Get(supplyCheckpointKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	checkpoint := new(supplyCheckpoint)
+	if err := rlp.DecodeBytes(data, checkpoint); err != nil {
+		log.Error("Invalid supply checkpoint", "number", number, "err", err)
+		return nil
+	}
+	return checkpoint
+}
+
+// sums returns the emission sums up to and including the given canonical
+// block, replaying the schedule since the checkpoint before it.
+func (x *SupplyIndex) sums(ctx context.Context, head *types.Header) (*supplyCheckpoint, error) {
+	number := head.Number.Uint64()
+	start := number - number%supplyCheckpointInterval
+	if indexed := x.progress(); start > indexed {
+		return nil, fmt.Errorf("supply of block %d not indexed yet, indexed up to block %d", number, indexed)
+	}
+	sums := newSupplyCheckpoint()
+	if start > 0 {
+		if sums = x.checkpoint(start); sums == nil {
+			return nil, fmt.Errorf("supply checkpoint of block %d missing", start)
+		}
+	}
+	for n := start + 1; n <= number; n++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := x.b.HeaderByNumber(ctx, rpc.BlockNumber(n))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("block %d not found", n)
+		}
+		sums.add(header)
+	}
+	if sums.Hash != head.Hash() && number > 0 {
+		return nil, fmt.Errorf("block %d reorged while computing its supply", number)
+	}
+	return sums, nil
+}
+
+func supplyCheckpointKey(number uint64) []byte {
+	return append(append([]byte{}, supplyCheckpointPrefix...), encodeBlockNumber(number)...)
+}
+
+// supply is the SERO in existence at a block and the part of it still held by
+// the reward pools.
+type supply struct {
+	total  *big.Int
+	locked *big.Int
+	paid   *big.Int // rewards paid out of the genesis reward pool
+}
+
+// supplyAt derives the supply at the given block from the genesis allocations
+// and the emission schedule. The genesis reward pool funds the rewards before
+// V2, its remainder staying locked at state.EmptyAddress afterwards, when
+// every block issues new coins.
+func (s *PublicBlockChainAPI) supplyAt(ctx context.Context, blockNr rpc.BlockNumber) (*supply, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		blockNr = rpc.LatestBlockNumber
+	}
+	head, err := s.b.HeaderByNumber(ctx, blockNr)
+	if head == nil || err != nil {
+		return nil, fmt.Errorf("block %d not found", blockNr)
+	}
+	sums, err := s.b.SupplyIndex().sums(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	locked := new(big.Int).Sub(core.GenesisRewardPool, sums.Paid)
+	return &supply{
+		total:  new(big.Int).Add(s.b.GenesisSupply(), sums.Minted),
+		locked: locked.Add(locked, sums.Pending),
+		paid:   sums.Paid,
+	}, nil
+}
+
+// TotalSupply returns the amount of SERO in existence at the given block.
+func (s *PublicBlockChainAPI) TotalSupply(ctx context.Context, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
+	info, err := s.supplyAt(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(info.total), nil
+}
+
+// CirculatingSupply returns the amount of SERO in existence at the given block
+// excluding the undistributed balances of the reward pools.
+func (s *PublicBlockChainAPI) CirculatingSupply(ctx context.Context, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
+	info, err := s.supplyAt(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(new(big.Int).Sub(info.total, info.locked)), nil
+}
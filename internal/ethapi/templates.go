@@ -0,0 +1,212 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+)
+
+// sendTemplatesMu serializes the updates of the stored send templates.
+var sendTemplatesMu sync.Mutex
+
+// SendTemplate is a named send of a local account, repeated by scripts with
+// sero_sendFromTemplate and the amount to send. The memo is a pattern where
+// {amount}, {seq} (the number of the send from the template, starting at 1),
+// {date} (UTC, as 2006-01-02) and {time} (unix seconds) are replaced.
+type SendTemplate struct {
+	Name        string                `json:"name"`
+	From        common.AccountAddress `json:"from"`
+	To          common.AccountAddress `json:"to"`
+	Currency    Smbol                 `json:"cy"`
+	Memo        string                `json:"memo"`
+	Gas         *hexutil.Uint64       `json:"gas"`
+	GasPrice    *hexutil.Big          `json:"gasPrice"`
+	GasCurrency Smbol                 `json:"gasCy"`
+	Sent        hexutil.Uint64        `json:"sent"`
+}
+
+func newSendTemplate(stored *rawdb.SendTemplate) *SendTemplate {
+	template := &SendTemplate{
+		Name:        stored.Name,
+		From:        stored.From,
+		To:          stored.To,
+		Currency:    Smbol(stored.Currency),
+		Memo:        stored.Memo,
+		GasPrice:    (*hexutil.Big)(stored.GasPrice),
+		GasCurrency: Smbol(stored.GasCurrency),
+		Sent:        hexutil.Uint64(stored.Sent),
+	}
+	if stored.Gas > 0 {
+		gas := hexutil.Uint64(stored.Gas)
+		template.Gas = &gas
+	}
+	return template
+}
+
+// expandMemo replaces the placeholders of a memo pattern.
+func expandMemo(pattern string, amount *big.Int, seq uint64, now time.Time) string {
+	return strings.NewReplacer(
+		"{amount}", amount.String(),
+		"{seq}", strconv.FormatUint(seq, 10),
+		"{date}", now.UTC().Format("2006-01-02"),
+		"{time}", strconv.FormatInt(now.Unix(), 10),
+	).Replace(pattern)
+}
+
+// sendArgs returns the arguments of the next send of amount from a template.
+func (t *SendTemplate) sendArgs(amount *hexutil.Big, now time.Time) SendTxArgs {
+	to := t.To
+	memo := expandMemo(t.Memo, amount.ToInt(), uint64(t.Sent)+1, now)
+	return SendTxArgs{
+		From:        t.From,
+		To:          &to,
+		Gas:         t.Gas,
+		GasCurrency: t.GasCurrency,
+		GasPrice:    t.GasPrice,
+		Value:       amount,
+		Currency:    t.Currency,
+		Memo:        memo,
+	}
+}
+
+// findSendTemplate returns the index of the template of a tenant with the
+// given name, -1 if there is none.
+func findSendTemplate(templates []rawdb.SendTemplate, tenant string, name string) int {
+	for i := range templates {
+		if templates[i].Tenant == tenant && templates[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// CreateSendTemplate stores a send template, replacing the one of the same
+// name. Templates are only visible to the tenant of the caller.
+func (s *PublicTransactionPoolAPI) CreateSendTemplate(ctx context.Context, template SendTemplate) error {
+	if template.Name == "" {
+		return errors.New("template name can not be empty")
+	}
+	if template.To == (common.AccountAddress{}) {
+		return errors.New("template recipient can not be empty")
+	}
+	scope := scopeOf(ctx, s.b)
+	if _, err := scope.find(accounts.Account{Address: template.From}); err != nil {
+		return err
+	}
+	// The memo is checked with the longest values of the placeholders
+	maxAmount := new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 256), common.Big1)
+	maxMemo := s.b.TxDefaults().sanitize().MaxMemo
+	if len(expandMemo(template.Memo, maxAmount, math.MaxUint64, time.Now())) > maxMemo {
+		return fmt.Errorf("template memo may exceed %d bytes", maxMemo)
+	}
+
+	stored := rawdb.SendTemplate{
+		Name:        template.Name,
+		Tenant:      scope.tenant,
+		From:        template.From,
+		To:          template.To,
+		Currency:    strings.ToUpper(string(template.Currency)),
+		Memo:        template.Memo,
+		GasPrice:    (*big.Int)(template.GasPrice),
+		GasCurrency: strings.ToUpper(string(template.GasCurrency)),
+	}
+	if template.Gas != nil {
+		stored.Gas = uint64(*template.Gas)
+	}
+	sendTemplatesMu.Lock()
+	defer sendTemplatesMu.Unlock()
+
+	db := s.b.ChainDb()
+	templates := rawdb.ReadSendTemplates(db)
+	if i := findSendTemplate(templates, scope.tenant, template.Name); i >= 0 {
+		templates[i] = stored
+	} else {
+		templates = append(templates, stored)
+	}
+	rawdb.WriteSendTemplates(db, templates)
+	return nil
+}
+
+// DeleteSendTemplate removes a send template, returning whether it existed.
+func (s *PublicTransactionPoolAPI) DeleteSendTemplate(ctx context.Context, name string) bool {
+	tenant := scopeOf(ctx, s.b).tenant
+
+	sendTemplatesMu.Lock()
+	defer sendTemplatesMu.Unlock()
+
+	db := s.b.ChainDb()
+	templates := rawdb.ReadSendTemplates(db)
+	i := findSendTemplate(templates, tenant, name)
+	if i < 0 {
+		return false
+	}
+	rawdb.WriteSendTemplates(db, append(templates[:i], templates[i+1:]...))
+	return true
+}
+
+// SendTemplates returns the send templates of the tenant of the caller.
+func (s *PublicTransactionPoolAPI) SendTemplates(ctx context.Context) []*SendTemplate {
+	tenant := scopeOf(ctx, s.b).tenant
+	templates := []*SendTemplate{}
+	for _, stored := range rawdb.ReadSendTemplates(s.b.ChainDb()) {
+		if stored.Tenant == tenant {
+			templates = append(templates, newSendTemplate(&stored))
+		}
+	}
+	return templates
+}
+
+// SendFromTemplate sends amount as set up by a send template and returns the
+// transaction hash.
+func (s *PublicTransactionPoolAPI) SendFromTemplate(ctx context.Context, name string, amount hexutil.Big) (common.Hash, error) {
+	if amount.ToInt().Sign() <= 0 {
+		return common.Hash{}, errors.New("amount must be positive")
+	}
+	tenant := scopeOf(ctx, s.b).tenant
+
+	// The sends of a template are serialized so their sequence numbers are
+	// distinct
+	sendTemplatesMu.Lock()
+	defer sendTemplatesMu.Unlock()
+
+	db := s.b.ChainDb()
+	templates := rawdb.ReadSendTemplates(db)
+	i := findSendTemplate(templates, tenant, name)
+	if i < 0 {
+		return common.Hash{}, fmt.Errorf("unknown send template %s", name)
+	}
+	hash, err := s.SendTransaction(ctx, newSendTemplate(&templates[i]).sendArgs(&amount, time.Now()))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	templates[i].Sent++
+	rawdb.WriteSendTemplates(db, templates)
+	return hash, nil
+}
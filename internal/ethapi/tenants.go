@@ -0,0 +1,142 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/accounts/keystore"
+	"github.com/sero-cash/go-sero/log"
+)
+
+var errNoTenantKeyStore = errors.New("key store of the tenant is not available")
+
+// Tenants are groups of local accounts isolated from each other at the API.
+// The accounts of a tenant are kept in a key store of their own and are only
+// reachable by the requests sending one of its API keys in the X-Api-Key
+// header. The other requests reach the accounts of the node only.
+type Tenants map[string]Tenant // by name
+
+// Tenant is the credentials of a group of accounts.
+type Tenant struct {
+	APIKeys []string
+}
+
+// Validate checks that the API keys of the tenants are set and distinct.
+func (t Tenants) Validate() error {
+	owners := make(map[string]string)
+	for name, tenant := range t {
+		if len(tenant.APIKeys) == 0 {
+			return errors.New("tenant " + name + " has no API key")
+		}
+		for _, key := range tenant.APIKeys {
+			if key == "" {
+				return errors.New("tenant " + name + " has an empty API key")
+			}
+			if owner, ok := owners[key]; ok && owner != name {
+				return errors.New("tenants " + owner + " and " + name + " share an API key")
+			}
+			owners[key] = name
+		}
+	}
+	return nil
+}
+
+// tenant returns the name of the tenant of the request of ctx, "" for the
+// node.
+func (t Tenants) tenant(ctx context.Context) string {
+	key, _ := ctx.Value("apikey").(string)
+	if key == "" {
+		return ""
+	}
+	for name, tenant := range t {
+		for _, k := range tenant.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// accountScope is the part of the local accounts a request may use.
+type accountScope struct {
+	am     *accounts.Manager
+	ks     *keystore.KeyStore // nil if the key store of the tenant failed
+	tenant string
+}
+
+// scopeOf returns the accounts reachable by the request of ctx.
+func scopeOf(ctx context.Context, b Backend) accountScope {
+	am := b.AccountManager()
+	scope := accountScope{am: am, ks: fetchKeystore(am), tenant: b.Tenants().tenant(ctx)}
+	if scope.tenant != "" {
+		ks, err := scope.ks.Scope(scope.tenant)
+		if err != nil {
+			log.Error("Tenant key store unavailable", "tenant", scope.tenant, "err", err)
+		}
+		scope.ks = ks
+	}
+	return scope
+}
+
+// keystore returns the key store holding the accounts of the scope.
+func (s accountScope) keystore() (*keystore.KeyStore, error) {
+	if s.ks == nil {
+		return nil, errNoTenantKeyStore
+	}
+	return s.ks, nil
+}
+
+// wallets returns the wallets of the scope.
+func (s accountScope) wallets() []accounts.Wallet {
+	if s.ks == nil {
+		return nil
+	}
+	return s.ks.Wallets()
+}
+
+// contains reports whether an account belongs to the scope.
+func (s accountScope) contains(account accounts.Account) bool {
+	return s.ks != nil && s.ks.HasAddress(account.Address)
+}
+
+// find returns the wallet of an account of the scope, the accounts of the
+// other scopes are unknown.
+func (s accountScope) find(account accounts.Account) (accounts.Wallet, error) {
+	if !s.contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return s.am.Find(account)
+}
+
+// wallet returns the wallet of the scope with the given URL.
+func (s accountScope) wallet(url string) (accounts.Wallet, error) {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range wallet.Accounts() {
+		if s.contains(account) {
+			return wallet, nil
+		}
+	}
+	return nil, accounts.ErrUnknownWallet
+}
@@ -0,0 +1,287 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// ticketPageSize is the number of tickets returned by a sero_listTickets call.
+const ticketPageSize = 100
+
+// Ticket is a ticket owned by a local account.
+type Ticket struct {
+	Category string         `json:"category"`
+	Value    common.Hash    `json:"value"`
+	Root     keys.Uint256   `json:"root"`
+	Number   hexutil.Uint64 `json:"blockNumber"` // block the ticket was received in
+	Locked   bool           `json:"locked"`      // pinned by sero_lockOuts
+	Pending  bool           `json:"pending"`     // spent by a pooled transaction
+}
+
+// TicketPage is a page of the tickets of an account. Next is the cursor of
+// the following page, nil on the last one.
+type TicketPage struct {
+	Tickets []Ticket        `json:"tickets"`
+	Next    *hexutil.Uint64 `json:"next"`
+}
+
+// ListTickets returns the tickets owned by a local account ordered by
+// category and value, optionally restricted to a category. The cursor is
+// the one returned with the previous page.
+func (s *PublicBlockChainAPI) ListTickets(ctx context.Context, account common.AccountAddress, category Smbol, cursor *hexutil.Uint64) (*TicketPage, error) {
+	tickets, err := accountTickets(ctx, s.b, account, category)
+	if err != nil {
+		return nil, err
+	}
+	page := &TicketPage{Tickets: []Ticket{}}
+	from := uint64(0)
+	if cursor != nil {
+		from = uint64(*cursor)
+	}
+	if from >= uint64(len(tickets)) {
+		return page, nil
+	}
+	to := from + ticketPageSize
+	if to < uint64(len(tickets)) {
+		next := hexutil.Uint64(to)
+		page.Next = &next
+	} else {
+		to = uint64(len(tickets))
+	}
+	page.Tickets = tickets[from:to]
+	return page, nil
+}
+
+// accountTickets returns the unspent tickets of a local account ordered by
+// category and value, optionally restricted to a category.
+func accountTickets(ctx context.Context, b Backend, account common.AccountAddress, category Smbol) ([]Ticket, error) {
+	wallet, err := scopeOf(ctx, b).find(accounts.Account{Address: account})
+	if err != nil {
+		return nil, err
+	}
+	tk := wallet.Accounts()[0].Tk
+	outs, err := txs.GetOuts(tk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	catg := utils.StringToUint256(string(category))
+	pooled := pooledInputs(b)
+
+	tickets := []Ticket{}
+	for _, out := range outs {
+		tkt := out.Out_O.Asset.Tkt
+		if tkt == nil || tkt.Value == keys.Empty_Uint256 {
+			continue
+		}
+		if category.IsNotEmpty() && tkt.Category != catg {
+			continue
+		}
+		tickets = append(tickets, Ticket{
+			Category: strings.Trim(string(tkt.Category[:]), zerobyte),
+			Value:    common.BytesToHash(tkt.Value[:]),
+			Root:     out.Root,
+			Number:   hexutil.Uint64(out.Num),
+			Locked:   outLocked(out.Root),
+			Pending:  pooled[out.Root] || pooled[out.Trace],
+		})
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		if tickets[i].Category != tickets[j].Category {
+			return tickets[i].Category < tickets[j].Category
+		}
+		return bytes.Compare(tickets[i].Value[:], tickets[j].Value[:]) < 0
+	})
+	return tickets, nil
+}
+
+// GetTickets returns all the unspent tickets of a local account by category,
+// ordered by value.
+func (s *PublicBlockChainAPI) GetTickets(ctx context.Context, account common.AccountAddress) (map[string][]Ticket, error) {
+	tickets, err := accountTickets(ctx, s.b, account, "")
+	if err != nil {
+		return nil, err
+	}
+	categories := make(map[string][]Ticket)
+	for _, ticket := range tickets {
+		categories[ticket.Category] = append(categories[ticket.Category], ticket)
+	}
+	return categories, nil
+}
+
+// GetTicket returns a ticket of a local account, nil if the account does not
+// hold it unspent.
+func (s *PublicBlockChainAPI) GetTicket(ctx context.Context, account common.AccountAddress, category Smbol, value common.Hash) (*Ticket, error) {
+	if category.IsEmpty() {
+		return nil, errors.New("catg can not be empty!")
+	}
+	tickets, err := accountTickets(ctx, s.b, account, category)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tickets {
+		if tickets[i].Value == value {
+			return &tickets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// checkTickets verifies that the sender holds the tickets a send transfers,
+// unspent and neither locked nor spent by a pooled transaction, before their
+// proofs are generated.
+func (args *SendTxArgs) checkTickets(ctx context.Context, b Backend) error {
+	type wanted struct {
+		category Smbol
+		value    common.Hash
+	}
+	var tkts []wanted
+	if args.Tkt != nil {
+		tkts = append(tkts, wanted{args.Category, *args.Tkt})
+	}
+	for _, r := range args.Receptions {
+		if r.Tkt != nil {
+			tkts = append(tkts, wanted{r.Category, *r.Tkt})
+		}
+	}
+	if len(tkts) == 0 {
+		return nil
+	}
+	tickets, err := accountTickets(ctx, b, args.From, "")
+	if err != nil {
+		return err
+	}
+	held := make(map[string]*Ticket)
+	for i := range tickets {
+		held[tickets[i].Category+tickets[i].Value.Hex()] = &tickets[i]
+	}
+	seen := make(map[string]bool)
+	for _, tkt := range tkts {
+		category := strings.ToUpper(string(tkt.category))
+		key := category + tkt.value.Hex()
+		ticket := held[key]
+		switch {
+		case ticket == nil:
+			return fmt.Errorf("ticket %s %s is not held by the sender", category, tkt.value.Hex())
+		case seen[key]:
+			return fmt.Errorf("ticket %s %s is sent twice", category, tkt.value.Hex())
+		case ticket.Locked:
+			return fmt.Errorf("ticket %s %s is locked", category, tkt.value.Hex())
+		case ticket.Pending:
+			return fmt.Errorf("ticket %s %s is spent by a pending transaction", category, tkt.value.Hex())
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// TicketEvent is an ownership change of a ticket seen by a local account.
+type TicketEvent struct {
+	Account *common.AccountAddress `json:"account"`
+	Root    keys.Uint256           `json:"root"`
+	Number  hexutil.Uint64         `json:"blockNumber"`
+	Type    string                 `json:"type"` // received or spent
+}
+
+// GetTicketHistory returns the transfers of a ticket from and to the local
+// accounts in block order. Transfers between foreign accounts are shielded
+// and not part of the history.
+func (s *PublicBlockChainAPI) GetTicketHistory(ctx context.Context, category Smbol, value common.Hash) ([]TicketEvent, error) {
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		return nil, errors.New("local outs are not scanned yet")
+	}
+	scope := scopeOf(ctx, s.b)
+	owners := make(map[keys.Uint512]common.AccountAddress)
+	foreign := make(map[keys.Uint512]bool)
+	for _, wallet := range s.b.AccountManager().Wallets() {
+		for _, account := range wallet.Accounts() {
+			if scope.contains(account) {
+				owners[*account.Tk.ToUint512()] = account.Address
+			} else {
+				foreign[*account.Tk.ToUint512()] = true
+			}
+		}
+	}
+	catg := utils.StringToUint256(string(category))
+	events := []TicketEvent{}
+	for _, event := range st1.GetTktEvents(&catg, value.HashToUint256()) {
+		if foreign[event.Tk] {
+			continue
+		}
+		te := TicketEvent{Root: event.Root, Number: hexutil.Uint64(event.Num), Type: "received"}
+		if event.Spent {
+			te.Type = "spent"
+		}
+		if owner, ok := owners[event.Tk]; ok {
+			te.Account = &owner
+		}
+		events = append(events, te)
+	}
+	return events, nil
+}
+
+// TicketMetadata is the metadata bound to a ticket by the issuer of its
+// category.
+type TicketMetadata struct {
+	Category    string                 `json:"category"`
+	Value       common.Hash            `json:"value"`
+	Issuer      *common.AccountAddress `json:"issuer"`
+	URI         string                 `json:"uri"`
+	ContentHash common.Hash            `json:"contentHash"`
+	Default     bool                   `json:"default"`  // whether the category default applies
+	Recorded    bool                   `json:"recorded"` // whether the issuer bound metadata on chain
+}
+
+// GetTicketMetadata returns the metadata URI and content hash bound to a
+// ticket, falling back to the default bound to its category.
+func (s *PublicBlockChainAPI) GetTicketMetadata(ctx context.Context, category Smbol, value common.Hash) (*TicketMetadata, error) {
+	if category.IsEmpty() {
+		return nil, errors.New("catg can not be empty!")
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	catg := string(category)
+	contractAddress := state.GetContrctAddressByTicket(catg)
+	if contractAddress == (common.Address{}) {
+		return nil, fmt.Errorf("category %s not exists", catg)
+	}
+	issuer := common.BytesToAccount(contractAddress[:64])
+	meta := &TicketMetadata{Category: catg, Value: value, Issuer: &issuer}
+	if uri, hash, ok := state.GetTicketMetadata(catg, value); ok {
+		meta.URI, meta.ContentHash, meta.Recorded = uri, hash, true
+	} else if uri, hash, ok := state.GetTicketMetadata(catg, common.Hash{}); ok {
+		meta.URI, meta.ContentHash, meta.Recorded, meta.Default = uri, hash, true, true
+	}
+	return meta, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+)
+
+// TransactionDetail is the content of a transaction as seen by the local
+// accounts taking part in it.
+type TransactionDetail struct {
+	Hash        common.Hash            `json:"hash"`
+	BlockHash   *common.Hash           `json:"blockHash"`
+	BlockNumber *hexutil.Uint64        `json:"blockNumber"`
+	From        common.ContractAddress `json:"from"`        // short address of the sending PKr
+	FromAccount *common.AccountAddress `json:"fromAccount"` // local account sending the transaction, if any
+	FeeCurrency string                 `json:"feeCurrency"`
+	Fee         *hexutil.Big           `json:"fee"`
+	Outs        []*DecryptedOut        `json:"outs"`
+}
+
+// DecryptedOut is an out of a transaction received by a local account.
+type DecryptedOut struct {
+	Index    hexutil.Uint           `json:"index"` // position among the outs of the kind
+	Z        bool                   `json:"z"`
+	To       common.ContractAddress `json:"to"` // short address of the receiving PKr
+	Account  common.AccountAddress  `json:"account"`
+	Currency string                 `json:"currency,omitempty"`
+	Value    *hexutil.Big           `json:"value,omitempty"`
+	Category string                 `json:"category,omitempty"`
+	Ticket   *common.Hash           `json:"ticket,omitempty"`
+	Memo     hexutil.Bytes          `json:"memo,omitempty"`
+	Text     string                 `json:"text,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// GetTransactionDetail returns a transaction with the outs received by local
+// accounts decrypted. It fails if neither the sender nor any receiver of the
+// transaction is a local account.
+func (s *PublicTransactionPoolAPI) GetTransactionDetail(ctx context.Context, hash common.Hash) (*TransactionDetail, error) {
+	detail := &TransactionDetail{Hash: hash}
+	tx, blockHash, blockNumber, _ := rawdb.ReadTransaction(s.b.ChainDb(), hash)
+	if tx != nil {
+		number := hexutil.Uint64(blockNumber)
+		detail.BlockHash, detail.BlockNumber = &blockHash, &number
+	} else if tx = s.b.GetPoolTransaction(hash); tx == nil {
+		return nil, nil
+	}
+	stxt := tx.GetZZSTX()
+	if stxt == nil {
+		return nil, errors.New("transaction carries no zero transaction")
+	}
+	wallets := scopeOf(ctx, s.b).wallets()
+
+	detail.From = shortAddress(&stxt.From)
+	detail.FromAccount = getAddressByPkr(wallets, common.BytesToAddress(stxt.From[:]))
+	detail.FeeCurrency = strings.Trim(string(stxt.Fee.Currency[:]), zerobyte)
+	detail.Fee = (*hexutil.Big)(stxt.Fee.Value.ToIntRef())
+
+	detail.Outs = decryptOuts(wallets, tx, stxt)
+	if detail.FromAccount == nil && len(detail.Outs) == 0 {
+		return nil, errors.New("transaction does not involve local accounts")
+	}
+	return detail, nil
+}
+
+// decryptOuts returns the outs of a transaction received by the wallets,
+// decrypted.
+func decryptOuts(wallets []accounts.Wallet, tx *types.Transaction, stxt *stx.T) []*DecryptedOut {
+	outs := []*DecryptedOut{}
+	for i := range stxt.Desc_O.Outs {
+		out := &stxt.Desc_O.Outs[i]
+		if account := localAccount(wallets, &out.Addr); account != nil {
+			dout := &DecryptedOut{Index: hexutil.Uint(i), Account: account.Address}
+			dout.fill(out, tx)
+			outs = append(outs, dout)
+		}
+	}
+	for i := range stxt.Desc_Z.Outs {
+		out := &stxt.Desc_Z.Outs[i]
+		account := localAccount(wallets, &out.PKr)
+		if account == nil {
+			continue
+		}
+		dout := &DecryptedOut{Index: hexutil.Uint(i), Z: true, Account: account.Address}
+		if out_o, err := stx.DecOut_Z(account.Tk.ToUint512(), out); err != nil {
+			dout.To = shortAddress(&out.PKr)
+			dout.Error = err.Error()
+		} else {
+			dout.fill(&out_o, tx)
+		}
+		outs = append(outs, dout)
+	}
+	return outs
+}
+
+// fill sets the receiver, the asset and the memo of a decrypted out.
+func (dout *DecryptedOut) fill(out *stx.Out_O, tx *types.Transaction) {
+	dout.To = shortAddress(&out.Addr)
+	if tkn := out.Asset.Tkn; tkn != nil {
+		dout.Currency = strings.Trim(string(tkn.Currency[:]), zerobyte)
+		dout.Value = (*hexutil.Big)(tkn.Value.ToIntRef())
+	}
+	if tkt := out.Asset.Tkt; tkt != nil && tkt.Category != (keys.Uint256{}) {
+		dout.Category = strings.Trim(string(tkt.Category[:]), zerobyte)
+		value := common.BytesToHash(tkt.Value[:])
+		dout.Ticket = &value
+	}
+	if out.Memo == (keys.Uint512{}) {
+		return
+	}
+	if isExtendedMemo(out.Memo) {
+		memo, err := openExtendedMemo(out.Memo, tx.Data())
+		if err != nil {
+			dout.Error = err.Error()
+			return
+		}
+		dout.Memo = memo
+	} else {
+		dout.Memo = bytes.TrimLeft(out.Memo[:], zerobyte)
+	}
+	if isPrintable(dout.Memo) {
+		dout.Text = string(dout.Memo)
+	}
+}
+
+// localAccount returns the local account owning pkr, or nil if there is none.
+func localAccount(wallets []accounts.Wallet, pkr *keys.PKr) *accounts.Account {
+	for _, wallet := range wallets {
+		if wallet.IsMine(common.BytesToAddress(pkr[:])) {
+			return &wallet.Accounts()[0]
+		}
+	}
+	return nil
+}
+
+// shortAddress returns the short address of a PKr.
+func shortAddress(pkr *keys.PKr) common.ContractAddress {
+	short := keys.HashPKr(pkr)
+	return common.BytesToContractAddress(short[:])
+}
@@ -22,11 +22,16 @@ var Modules = map[string]string{
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
+	"lstate":     Lstate_JS,
+	"memoindex":  MemoIndex_JS,
 	"ser":        SER_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
+	"policy":     Policy_JS,
 	"rpc":        RPC_JS,
+	"scheduler":  Scheduler_JS,
+	"session":    Session_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
@@ -134,6 +139,21 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'reloadProvingKeys',
+			call: 'admin_reloadProvingKeys',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'dumpTxPool',
+			call: 'admin_dumpTxPool',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'loadTxPool',
+			call: 'admin_loadTxPool',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -242,6 +262,16 @@ web3._extend({
 			params: 0,
 			outputFormatter: console.log
 		}),
+		new web3._extend.Method({
+			name: 'rpcStats',
+			call: 'debug_rpcStats',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'resetRpcStats',
+			call: 'debug_resetRpcStats',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'freeOSMemory',
 			call: 'debug_freeOSMemory',
@@ -357,6 +387,22 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'dbGet',
+			call: 'debug_dbGet',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'dbKeys',
+			call: 'debug_dbKeys',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'trieStats',
+			call: 'debug_trieStats',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -431,8 +477,253 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getTokenInfo',
+			call: 'sero_getTokenInfo',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getMemos',
+			call: 'sero_getMemos',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendTransactionIdempotent',
+			call: 'sero_sendTransactionIdempotent',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'sendTransactions',
+			call: 'sero_sendTransactions',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'listTickets',
+			call: 'sero_listTickets',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getTicketHistory',
+			call: 'sero_getTicketHistory',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getTicketMetadata',
+			call: 'sero_getTicketMetadata',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getAccountSummary',
+			call: 'sero_getAccountSummary',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionDetail',
+			call: 'sero_getTransactionDetail',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'genTxParam',
+			call: 'sero_genTxParam',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'signTxWithSk',
+			call: 'sero_signTxWithSk',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'sendRawTransaction',
+			call: 'sero_sendRawTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'estimateFee',
+			call: 'sero_estimateFee',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'sero_feeHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockReceiptsByNumber',
+			call: 'sero_getBlockReceiptsByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'createSendTemplate',
+			call: 'sero_createSendTemplate',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'deleteSendTemplate',
+			call: 'sero_deleteSendTemplate',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendFromTemplate',
+			call: 'sero_sendFromTemplate',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getTickets',
+			call: 'sero_getTickets',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTicket',
+			call: 'sero_getTicket',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getBlockForAccount',
+			call: 'sero_getBlockForAccount',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getPkgEvents',
+			call: 'sero_getPkgEvents',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getHeaderProofs',
+			call: 'sero_getHeaderProofs',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getReceiptProof',
+			call: 'sero_getReceiptProof',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'attestEvent',
+			call: 'sero_attestEvent',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getDailyStats',
+			call: 'sero_getDailyStats',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'createPaymentRequest',
+			call: 'sero_createPaymentRequest',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'payRequest',
+			call: 'sero_payRequest',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'watchPKr',
+			call: 'sero_watchPKr',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'unwatchPKr',
+			call: 'sero_unwatchPKr',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'watchedPKrs',
+			call: 'sero_watchedPKrs',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'discloseOut',
+			call: 'sero_discloseOut',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'forwardRawTransaction',
+			call: 'sero_forwardRawTransaction',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getOuts',
+			call: 'sero_getOuts',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'lockOuts',
+			call: 'sero_lockOuts',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'unlockOuts',
+			call: 'sero_unlockOuts',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'merge',
+			call: 'sero_merge',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'sero_cancelTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionsByAccount',
+			call: 'sero_getTransactionsByAccount',
+			params: 5,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'totalSupply',
+			call: 'sero_totalSupply',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
+		new web3._extend.Method({
+			name: 'emissionSchedule',
+			call: 'sero_emissionSchedule',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'circulatingSupply',
+			call: 'sero_circulatingSupply',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'apiVersion',
+			getter: 'sero_apiVersion'
+		}),
+		new web3._extend.Property({
+			name: 'nodeInfo',
+			getter: 'sero_nodeInfo'
+		}),
+		new web3._extend.Property({
+			name: 'provingKeyInfo',
+			getter: 'sero_provingKeyInfo'
+		}),
+		new web3._extend.Property({
+			name: 'proofQueue',
+			getter: 'sero_proofQueue'
+		}),
+		new web3._extend.Property({
+			name: 'sendTemplates',
+			getter: 'sero_sendTemplates'
+		}),
 		new web3._extend.Property({
 			name: 'pendingTransactions',
 			getter: 'sero_pendingTransactions',
@@ -449,6 +740,59 @@ web3._extend({
 });
 `
 
+const Lstate_JS = `
+web3._extend({
+	property: 'lstate',
+	methods: [
+		new web3._extend.Method({
+			name: 'compact',
+			call: 'lstate_compact',
+		}),
+		new web3._extend.Method({
+			name: 'repair',
+			call: 'lstate_repair',
+		}),
+		new web3._extend.Method({
+			name: 'rescanFrom',
+			call: 'lstate_rescanFrom',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'rescanAccount',
+			call: 'lstate_rescanAccount',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'stats',
+			getter: 'lstate_stats'
+		}),
+		new web3._extend.Property({
+			name: 'rescanStatus',
+			getter: 'lstate_rescanStatus'
+		}),
+	]
+});
+`
+
+const MemoIndex_JS = `
+web3._extend({
+	property: 'memoindex',
+	methods: [
+		new web3._extend.Method({
+			name: 'search',
+			call: 'memoindex_search',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+	]
+});
+`
+
 const Miner_JS = `
 web3._extend({
 	property: 'miner',
@@ -484,6 +828,10 @@ web3._extend({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
 		}),
+		new web3._extend.Method({
+			name: 'stratumWorkers',
+			call: 'miner_stratumWorkers'
+		}),
 	],
 	properties: []
 });
@@ -511,6 +859,31 @@ web3._extend({
 			call: 'personal_importRawKey',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'importTk',
+			call: 'personal_importTk',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'exportBundle',
+			call: 'personal_exportBundle',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'importBundle',
+			call: 'personal_importBundle',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'deriveSeroAccount',
+			call: 'personal_deriveSeroAccount',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'exportDerivedKey',
+			call: 'personal_exportDerivedKey',
+			params: 3
+		}),
 		new web3._extend.Method({
 			name: 'sign',
 			call: 'personal_sign',
@@ -527,6 +900,12 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'unlockAccountScoped',
+			call: 'personal_unlockAccount',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'personal_signTransaction',
@@ -543,6 +922,122 @@ web3._extend({
 })
 `
 
+const Policy_JS = `
+web3._extend({
+	property: 'policy',
+	methods: [
+		new web3._extend.Method({
+			name: 'approveSend',
+			call: 'policy_approveSend',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'rejectSend',
+			call: 'policy_rejectSend',
+			params: 3
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'heldSends',
+			getter: 'policy_heldSends'
+		}),
+	]
+});
+`
+
+const Scheduler_JS = `
+web3._extend({
+	property: 'scheduler',
+	methods: [
+		new web3._extend.Method({
+			name: 'addSchedule',
+			call: 'scheduler_addSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeSchedule',
+			call: 'scheduler_removeSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'pauseSchedule',
+			call: 'scheduler_pauseSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'resumeSchedule',
+			call: 'scheduler_resumeSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'runs',
+			call: 'scheduler_runs',
+			params: 1
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'schedules',
+			getter: 'scheduler_schedules'
+		}),
+	]
+});
+`
+
+const Session_JS = `
+web3._extend({
+	property: 'session',
+	methods: [
+		new web3._extend.Method({
+			name: 'open',
+			call: 'session_open',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'unlock',
+			call: 'session_unlock',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'lock',
+			call: 'session_lock',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'info',
+			call: 'session_info',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'close',
+			call: 'session_close',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'revoke',
+			call: 'session_revoke',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendTransaction',
+			call: 'session_sendTransaction',
+			params: 2
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'list',
+			getter: 'session_list'
+		}),
+	]
+});
+`
+
 const RPC_JS = `
 web3._extend({
 	property: 'rpc',
@@ -603,13 +1098,29 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'contentOf',
+			call: 'txpool_content',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'inspectOf',
+			call: 'txpool_inspect',
+			params: 1
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
 			name: 'content',
 			getter: 'txpool_content'
 		}),
+		new web3._extend.Property({
+			name: 'inspect',
+			getter: 'txpool_inspect'
+		}),
 		new web3._extend.Property({
 			name: 'status',
 			getter: 'txpool_status',
@@ -0,0 +1,223 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// PublicLightSeroAPI provides the sero methods a light client can serve.
+type PublicLightSeroAPI struct {
+	l *LightSero
+}
+
+// NewPublicLightSeroAPI creates the sero API of a light client.
+func NewPublicLightSeroAPI(l *LightSero) *PublicLightSeroAPI {
+	return &PublicLightSeroAPI{l}
+}
+
+// BlockNumber returns the number of the head header.
+func (api *PublicLightSeroAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.l.hc.CurrentHeader().Number.Uint64())
+}
+
+// GetHeaderByNumber returns the canonical header of a block, nil if unknown.
+func (api *PublicLightSeroAPI) GetHeaderByNumber(number rpc.BlockNumber) *types.Header {
+	if number < 0 {
+		return api.l.hc.CurrentHeader()
+	}
+	return api.l.hc.GetHeaderByNumber(uint64(number))
+}
+
+// SendRawTransaction sends a signed transaction to the pools of the servers.
+func (api *PublicLightSeroAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	errs, err := api.l.retriever.sendTxs(ctx, []*types.Transaction{tx})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if errs[0] != "" {
+		return common.Hash{}, fmt.Errorf("transaction rejected: %s", errs[0])
+	}
+	return tx.Hash(), nil
+}
+
+// PublicLightAPI provides the on-demand retrieval of the light client.
+type PublicLightAPI struct {
+	l *LightSero
+}
+
+// NewPublicLightAPI creates the les API of a light client.
+func NewPublicLightAPI(l *LightSero) *PublicLightAPI {
+	return &PublicLightAPI{l}
+}
+
+// SyncStatus is the progress of the header sync.
+type SyncStatus struct {
+	Head       common.Hash    `json:"head"`
+	Number     hexutil.Uint64 `json:"number"`
+	Syncing    bool           `json:"syncing"`
+	Servers    int            `json:"servers"`
+	BestNumber hexutil.Uint64 `json:"bestNumber"` // head announced by the best server
+}
+
+// SyncStatus returns the progress of the header sync.
+func (api *PublicLightAPI) SyncStatus() SyncStatus {
+	head := api.l.hc.CurrentHeader()
+	status := SyncStatus{
+		Head:    head.Hash(),
+		Number:  hexutil.Uint64(head.Number.Uint64()),
+		Syncing: atomic.LoadInt32(&api.l.syncing) == 1,
+	}
+	servers := api.l.peers.Servers()
+	status.Servers = len(servers)
+	if len(servers) > 0 {
+		_, number, _ := servers[0].Head()
+		status.BestNumber = hexutil.Uint64(number)
+	}
+	return status
+}
+
+// LightOut is an out created in a block.
+type LightOut struct {
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	Root        keys.Uint256     `json:"root"`
+	Out         txstate.OutState `json:"out"`
+}
+
+// LightSpent is the root of an out spent in a block.
+type LightSpent struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	Root        keys.Uint256   `json:"root"`
+}
+
+// LightOuts are the outs created and spent in a range of blocks.
+type LightOuts struct {
+	Outs  []LightOut   `json:"outs"`
+	Spent []LightSpent `json:"spent"`
+}
+
+// GetOuts retrieves the outs created and spent in the canonical blocks from
+// fromBlock to toBlock, both included and at most 128, so that wallets can
+// find theirs by decrypting them. The records are proven against the state
+// root of the head header.
+func (api *PublicLightAPI) GetOuts(ctx context.Context, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber) (*LightOuts, error) {
+	current := api.l.hc.CurrentHeader()
+	head := current.Number.Uint64()
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if fromBlock < 0 {
+		from = head
+	}
+	if toBlock < 0 || to > head {
+		to = head
+	}
+	if from == 0 {
+		from = 1 // the genesis outs are not recorded
+	}
+	if to < from {
+		return nil, fmt.Errorf("invalid block range %d to %d", from, to)
+	}
+	if to-from >= maxZBlockFetch {
+		return nil, fmt.Errorf("at most %d blocks are retrieved", maxZBlockFetch)
+	}
+	var (
+		headers []*types.Header
+		hashes  []common.Hash
+		numbers = make(map[common.Hash]uint64)
+	)
+	for number := from; number <= to; number++ {
+		header := api.l.hc.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("header #%d not synced", number)
+		}
+		hash := header.Hash()
+		headers = append(headers, header)
+		hashes = append(hashes, hash)
+		numbers[hash] = number
+	}
+	blocks, err := api.l.retriever.zblocks(ctx, current, headers)
+	if err != nil {
+		return nil, err
+	}
+	result := &LightOuts{Outs: []LightOut{}, Spent: []LightSpent{}}
+	var (
+		roots     []keys.Uint256
+		rootBlock = make(map[keys.Uint256]uint64)
+	)
+	for _, hash := range hashes {
+		block := blocks[hash]
+		for _, root := range block.Roots {
+			roots = append(roots, root)
+			rootBlock[root] = numbers[hash]
+		}
+		for _, root := range block.Dels {
+			result.Spent = append(result.Spent, LightSpent{BlockNumber: hexutil.Uint64(numbers[hash]), Root: root})
+		}
+	}
+	for start := 0; start < len(roots); start += maxOutFetch {
+		end := start + maxOutFetch
+		if end > len(roots) {
+			end = len(roots)
+		}
+		outs, err := api.l.retriever.outs(ctx, current, roots[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, out := range outs {
+			result.Outs = append(result.Outs, LightOut{BlockNumber: hexutil.Uint64(rootBlock[out.Root]), Root: out.Root, Out: out.Out})
+		}
+	}
+	return result, nil
+}
+
+// Witness is the path of the commitment of an out to the anchor of the
+// commitment tree, as needed to spend the out.
+type Witness struct {
+	Pos    hexutil.Uint64 `json:"pos"`
+	Paths  []keys.Uint256 `json:"paths"`
+	Anchor keys.Uint256   `json:"anchor"`
+}
+
+// GetWitness retrieves the witness of an out, anchored at the head header.
+// The path is checked to lead from the commitment of the out to the anchor,
+// which is proven against the state root of the header.
+func (api *PublicLightAPI) GetWitness(ctx context.Context, root keys.Uint256) (*Witness, error) {
+	head := api.l.hc.CurrentHeader()
+	outs, err := api.l.retriever.outs(ctx, head, []keys.Uint256{root})
+	if err != nil {
+		return nil, err
+	}
+	witnesses, err := api.l.retriever.witnesses(ctx, head, outs)
+	if err != nil {
+		return nil, err
+	}
+	w := witnesses[0]
+	return &Witness{Pos: hexutil.Uint64(w.Pos), Paths: w.Paths, Anchor: w.Anchor}, nil
+}
@@ -0,0 +1,353 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/consensus"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/node"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/sero"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// forceSyncCycle is the interval the client checks for a better server.
+const forceSyncCycle = 10 * time.Second
+
+// LightSero implements the light client service: it syncs the headers from
+// the servers and retrieves the rest on demand.
+type LightSero struct {
+	config      *sero.Config
+	chainConfig *params.ChainConfig
+	chainDb     serodb.Database
+	engine      consensus.Engine
+	hc          *core.HeaderChain
+
+	accountManager *accounts.Manager
+	networkID      uint64
+	netRPCService  *ethapi.PublicNetAPI
+
+	peers     *peerSet
+	retriever *retriever
+	syncCh    chan *peer
+	syncing   int32 // Flag whether headers are being synced (atomic)
+	quitting  int32 // Flag whether the client is shutting down (atomic)
+	quit      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates the light client service.
+func New(ctx *node.ServiceContext, config *sero.Config) (*LightSero, error) {
+	chainDb, err := sero.CreateDB(ctx, config, "lightchaindata")
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, _, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
+	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
+		return nil, genesisErr
+	}
+	log.Info("Initialised chain configuration", "config", chainConfig)
+
+	peers := newPeerSet()
+	l := &LightSero{
+		config:         config,
+		chainConfig:    chainConfig,
+		chainDb:        chainDb,
+		engine:         sero.CreateConsensusEngine(ctx, &config.Ethash, chainConfig, chainDb),
+		accountManager: ctx.AccountManager,
+		networkID:      config.NetworkId,
+		peers:          peers,
+		retriever:      newRetriever(peers),
+		syncCh:         make(chan *peer, 1),
+		quit:           make(chan struct{}),
+	}
+	l.hc, err = core.NewHeaderChain(chainDb, chainConfig, l.engine, func() bool {
+		return atomic.LoadInt32(&l.quitting) == 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Initialising light client protocol", "versions", ProtocolVersions, "network", config.NetworkId)
+	return l, nil
+}
+
+// Protocols implements node.Service, returning the les protocols the client
+// connects to the servers with.
+func (l *LightSero) Protocols() []p2p.Protocol {
+	var protocols []p2p.Protocol
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				l.wg.Add(1)
+				defer l.wg.Done()
+				return l.handle(newPeer(int(version), p, rw))
+			},
+			PeerInfo: func(id discover.NodeID) interface{} {
+				if p := l.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
+					return p.Info()
+				}
+				return nil
+			},
+		})
+	}
+	return protocols
+}
+
+// APIs implements node.Service, returning the RPC services of the client.
+func (l *LightSero) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "sero",
+			Version:   "1.0",
+			Service:   NewPublicLightSeroAPI(l),
+			Public:    true,
+		}, {
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPublicLightAPI(l),
+			Public:    true,
+		}, {
+			Namespace: "net",
+			Version:   "1.0",
+			Service:   l.netRPCService,
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service, starting the header sync.
+func (l *LightSero) Start(srvr *p2p.Server) error {
+	l.netRPCService = ethapi.NewPublicNetAPI(srvr, l.networkID)
+	go l.syncer()
+	return nil
+}
+
+// Stop implements node.Service, disconnecting the servers.
+func (l *LightSero) Stop() error {
+	atomic.StoreInt32(&l.quitting, 1)
+	close(l.quit)
+	l.peers.Close()
+	l.wg.Wait()
+	l.chainDb.Close()
+	log.Info("Light client stopped")
+	return nil
+}
+
+// HeaderChain returns the headers synced by the client.
+func (l *LightSero) HeaderChain() *core.HeaderChain { return l.hc }
+
+// handle is the callback invoked to manage the life cycle of a server.
+func (l *LightSero) handle(p *peer) error {
+	p.Log().Debug("Light server connected", "name", p.Name())
+
+	genesis := l.hc.GetHeaderByNumber(0)
+	head := l.hc.CurrentHeader()
+	hash, number := head.Hash(), head.Number.Uint64()
+	status := &statusData{
+		ProtocolVersion: uint32(p.version),
+		NetworkId:       l.networkID,
+		TD:              l.hc.GetTd(hash, number),
+		Head:            hash,
+		HeadNum:         number,
+		Genesis:         genesis.Hash(),
+	}
+	if err := p.Handshake(l.networkID, status); err != nil {
+		p.Log().Debug("Light server handshake failed", "err", err)
+		return err
+	}
+	if !p.serving {
+		return p2p.DiscUselessPeer
+	}
+	if err := l.peers.Register(p); err != nil {
+		p.Log().Error("Light server registration failed", "err", err)
+		return err
+	}
+	defer l.peers.Unregister(p.id)
+
+	l.requestSync(p)
+	for {
+		if err := l.handleMsg(p); err != nil {
+			p.Log().Debug("Light server message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a server.
+// The remote connection is torn down upon returning any error.
+func (l *LightSero) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	var resp reply
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case AnnounceMsg:
+		var announce announceData
+		if err := msg.Decode(&announce); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if announce.TD == nil {
+			return errResp(ErrDecode, "announcement without total difficulty")
+		}
+		p.SetHead(announce.Hash, announce.Number, announce.TD)
+		l.requestSync(p)
+		return nil
+
+	case BlockHeadersMsg:
+		resp = new(blockHeadersData)
+	case ZBlocksMsg:
+		resp = new(zBlocksData)
+	case OutsMsg:
+		resp = new(outsData)
+	case WitnessesMsg:
+		resp = new(witnessesData)
+	case TxStatusMsg:
+		resp = new(txStatusData)
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	if err := msg.Decode(resp); err != nil {
+		return errResp(ErrDecode, "%v: %v", msg, err)
+	}
+	if !l.retriever.deliver(p, resp) {
+		p.Log().Debug("Unrequested light reply", "code", msg.Code, "reqid", resp.reqID())
+	}
+	return nil
+}
+
+// requestSync asks the syncer to sync with a server, unless it is busy.
+func (l *LightSero) requestSync(p *peer) {
+	select {
+	case l.syncCh <- p:
+	default:
+	}
+}
+
+// syncer syncs the headers with the servers announcing a better chain and
+// periodically with the best server.
+func (l *LightSero) syncer() {
+	ticker := time.NewTicker(forceSyncCycle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case p := <-l.syncCh:
+			l.synchronise(p)
+		case <-ticker.C:
+			if servers := l.peers.Servers(); len(servers) > 0 {
+				l.synchronise(servers[0])
+			}
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// synchronise imports the headers of a server with a higher total difficulty.
+// Headers are requested from the local head on; if the server is on another
+// branch, the requests step back until they connect to the local chain.
+func (l *LightSero) synchronise(p *peer) {
+	head := l.hc.CurrentHeader()
+	_, _, td := p.Head()
+	if td.Cmp(l.hc.GetTd(head.Hash(), head.Number.Uint64())) <= 0 {
+		return
+	}
+	atomic.StoreInt32(&l.syncing, 1)
+	defer atomic.StoreInt32(&l.syncing, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-l.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	from := head.Number.Uint64() + 1
+	for {
+		headers, err := l.retriever.headers(ctx, p, from, maxHeaderFetch)
+		if err != nil {
+			p.Log().Debug("Header retrieval failed", "from", from, "err", err)
+			return
+		}
+		if len(headers) == 0 {
+			return
+		}
+		if l.hc.GetHeader(headers[0].ParentHash, from-1) == nil {
+			// On another branch, step back
+			if from <= 1 {
+				p.Log().Debug("Server chain does not connect to the genesis block")
+				p.Disconnect(p2p.DiscUselessPeer)
+				return
+			}
+			if from > maxHeaderFetch {
+				from -= maxHeaderFetch
+			} else {
+				from = 1
+			}
+			continue
+		}
+		if _, err := l.insertHeaders(headers); err != nil {
+			p.Log().Debug("Invalid headers", "from", from, "err", err)
+			p.Disconnect(p2p.DiscUselessPeer)
+			return
+		}
+		if len(headers) < maxHeaderFetch {
+			return
+		}
+		from += uint64(len(headers))
+	}
+}
+
+// insertHeaders verifies a contiguous batch of headers and writes it.
+func (l *LightSero) insertHeaders(headers []*types.Header) (int, error) {
+	if i, err := l.hc.ValidateHeaderChain(headers, 100); err != nil {
+		return i, err
+	}
+	return l.hc.InsertHeaderChain(headers, func(header *types.Header) error {
+		_, err := l.hc.WriteHeader(header)
+		return err
+	}, time.Now())
+}
@@ -0,0 +1,209 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Flow control parameters announced by servers.
+const (
+	defaultBufLimit = 300000
+	defaultRecharge = 50000
+)
+
+// requestCost is the cost of a request of a kind, in buffer units.
+type requestCost struct {
+	base, perItem uint64
+}
+
+// requestCosts are the costs of the requests, roughly proportional to the
+// work of the server. Witnesses are paths read from the commitment tree,
+// transactions are verified before they enter the pool.
+var requestCosts = map[uint64]requestCost{
+	GetBlockHeadersMsg: {150, 10},
+	GetZBlocksMsg:      {150, 30},
+	GetOutsMsg:         {150, 50},
+	GetWitnessesMsg:    {200, 400},
+	SendTxMsg:          {100, 500},
+}
+
+// cost returns the cost of a request of items.
+func cost(code uint64, items int) uint64 {
+	c := requestCosts[code]
+	return c.base + c.perItem*uint64(items)
+}
+
+// buffer is the flow control buffer of a client: requests spend it, time
+// recharges it up to its limit. Servers track the real buffer of each client,
+// clients an estimate of theirs, corrected by the value of every reply.
+type buffer struct {
+	mu       sync.Mutex
+	limit    uint64
+	recharge uint64 // per second
+	value    uint64
+	updated  time.Time
+}
+
+func newBuffer(limit, recharge uint64) *buffer {
+	return &buffer{limit: limit, recharge: recharge, value: limit, updated: time.Now()}
+}
+
+// update recharges the buffer for the time passed since the last update.
+func (b *buffer) update(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.value += uint64(elapsed/time.Millisecond) * b.recharge / 1000
+		if b.value > b.limit {
+			b.value = b.limit
+		}
+	}
+	b.updated = now
+}
+
+// spend takes cost from the buffer, returning the value left. It fails if the
+// buffer holds less than cost.
+func (b *buffer) spend(cost uint64) (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update(time.Now())
+	if b.value < cost {
+		return b.value, false
+	}
+	b.value -= cost
+	return b.value, true
+}
+
+// set corrects the value of the buffer with the one reported by the server.
+func (b *buffer) set(value uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.updated = time.Now()
+	if value > b.limit {
+		value = b.limit
+	}
+	b.value = value
+}
+
+// wait spends cost from the buffer as soon as it holds enough.
+func (b *buffer) wait(ctx context.Context, cost uint64) error {
+	if cost > b.limit {
+		cost = b.limit
+	}
+	for {
+		b.mu.Lock()
+		b.update(time.Now())
+		if b.value >= cost {
+			b.value -= cost
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((cost - b.value) * uint64(time.Second) / b.recharge)
+		b.mu.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Priorities of the requests served. Spending needs witnesses and sends
+// transactions, so these go before the headers, which go before the bulk
+// downloads of outs.
+const (
+	prioHigh = iota
+	prioNormal
+	prioLow
+)
+
+var requestPriorities = map[uint64]int{
+	GetBlockHeadersMsg: prioNormal,
+	GetZBlocksMsg:      prioLow,
+	GetOutsMsg:         prioLow,
+	GetWitnessesMsg:    prioHigh,
+	SendTxMsg:          prioHigh,
+}
+
+// servingQueue serves the requests of all clients with a limited number of
+// threads, taking the pending requests of the highest priority first.
+type servingQueue struct {
+	queues [3]chan func()
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newServingQueue(threads int) *servingQueue {
+	q := &servingQueue{quit: make(chan struct{})}
+	for i := range q.queues {
+		q.queues[i] = make(chan func())
+	}
+	for i := 0; i < threads; i++ {
+		q.wg.Add(1)
+		go q.loop()
+	}
+	return q
+}
+
+func (q *servingQueue) loop() {
+	defer q.wg.Done()
+
+	high, normal, low := q.queues[prioHigh], q.queues[prioNormal], q.queues[prioLow]
+	for {
+		var task func()
+		select {
+		case task = <-high:
+		default:
+			select {
+			case task = <-high:
+			case task = <-normal:
+			default:
+				select {
+				case task = <-high:
+				case task = <-normal:
+				case task = <-low:
+				case <-q.quit:
+					return
+				}
+			}
+		}
+		task()
+	}
+}
+
+// serve runs task at the given priority and waits for it. It returns false if
+// the queue stopped before the task ran.
+func (q *servingQueue) serve(priority int, task func()) bool {
+	done := make(chan struct{})
+	select {
+	case q.queues[priority] <- func() { task(); close(done) }:
+	case <-q.quit:
+		return false
+	}
+	<-done
+	return true
+}
+
+// stop stops serving, waiting for the requests being served.
+func (q *servingQueue) stop() {
+	close(q.quit)
+	q.wg.Wait()
+}
@@ -0,0 +1,292 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// requestTimeout is the time a server has to reply before the request is
+// retried with another one.
+const requestTimeout = 10 * time.Second
+
+// retriever sends the requests of a light client to the servers and matches
+// their replies.
+type retriever struct {
+	peers *peerSet
+
+	lock    sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan reply
+}
+
+func newRetriever(peers *peerSet) *retriever {
+	return &retriever{peers: peers, pending: make(map[uint64]chan reply)}
+}
+
+// deliver hands a reply to the request waiting for it. It returns false if no
+// request is waiting, such as for replies arriving after their timeout.
+func (r *retriever) deliver(p *peer, msg reply) bool {
+	r.lock.Lock()
+	ch, ok := r.pending[msg.reqID()]
+	delete(r.pending, msg.reqID())
+	r.lock.Unlock()
+
+	if !ok {
+		return false
+	}
+	p.buf.set(msg.bufValue())
+	ch <- msg
+	return true
+}
+
+// request sends a request to a server, waiting for its reply.
+func (r *retriever) request(ctx context.Context, p *peer, code uint64, items int, build func(reqID uint64) interface{}) (reply, error) {
+	if err := p.buf.wait(ctx, cost(code, items)); err != nil {
+		return nil, err
+	}
+	ch := make(chan reply, 1)
+	r.lock.Lock()
+	r.nextID++
+	reqID := r.nextID
+	r.pending[reqID] = ch
+	r.lock.Unlock()
+
+	defer func() {
+		r.lock.Lock()
+		delete(r.pending, reqID)
+		r.lock.Unlock()
+	}()
+	if err := p2p.Send(p.rw, code, build(reqID)); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(requestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-timeout.C:
+		return nil, errRequestTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// retrieve sends a request to the servers in turn, the best first, until one
+// replies with a valid reply.
+func (r *retriever) retrieve(ctx context.Context, code uint64, items int, build func(reqID uint64) interface{}, valid func(reply) error) (reply, error) {
+	err := errNoServer
+	for _, p := range r.peers.Servers() {
+		var msg reply
+		if msg, err = r.request(ctx, p, code, items, build); err == nil {
+			if err = valid(msg); err == nil {
+				return msg, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Debug("Light request failed", "peer", p.id, "code", code, "err", err)
+	}
+	return nil, err
+}
+
+// headers retrieves the canonical headers of a server from origin on.
+func (r *retriever) headers(ctx context.Context, p *peer, origin uint64, amount int) ([]*types.Header, error) {
+	msg, err := r.request(ctx, p, GetBlockHeadersMsg, amount, func(reqID uint64) interface{} {
+		return &getBlockHeadersData{ReqID: reqID, Origin: origin, Amount: uint64(amount)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := msg.(*blockHeadersData).Headers
+	for i, header := range headers {
+		if header.Number.Uint64() != origin+uint64(i) {
+			return nil, errInvalidReply
+		}
+	}
+	return headers, nil
+}
+
+// zblocks retrieves the zstate records of the blocks of headers, proven
+// against the state of head.
+func (r *retriever) zblocks(ctx context.Context, head *types.Header, headers []*types.Header) (map[common.Hash]ZBlock, error) {
+	hashes := make([]common.Hash, len(headers))
+	for i, header := range headers {
+		hashes[i] = header.Hash()
+	}
+	blocks := make(map[common.Hash]ZBlock, len(hashes))
+	_, err := r.retrieve(ctx, GetZBlocksMsg, len(hashes), func(reqID uint64) interface{} {
+		return &getZBlocksData{ReqID: reqID, Head: head.Hash(), Hashes: hashes}
+	}, func(msg reply) error {
+		reply := msg.(*zBlocksData)
+		if len(reply.Blocks) != len(hashes) {
+			return errInvalidReply
+		}
+		proof := reply.Proof.database()
+		for i, block := range reply.Blocks {
+			if block.Hash != hashes[i] {
+				return errInvalidReply
+			}
+			value, err := verifyRecord(head.Root, txstate.BlockKey(headers[i].Number.Uint64()), proof)
+			if err != nil {
+				return err
+			}
+			proven, err := decodeZBlock(block.Hash, value)
+			if err != nil {
+				return err
+			}
+			blocks[block.Hash] = proven
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// outs retrieves outs by root, proven against the state of head.
+func (r *retriever) outs(ctx context.Context, head *types.Header, roots []keys.Uint256) ([]ZOut, error) {
+	var outs []ZOut
+	_, err := r.retrieve(ctx, GetOutsMsg, len(roots), func(reqID uint64) interface{} {
+		return &getOutsData{ReqID: reqID, Head: head.Hash(), Roots: roots}
+	}, func(msg reply) error {
+		reply := msg.(*outsData)
+		if len(reply.Outs) != len(roots) {
+			return errInvalidReply
+		}
+		proof := reply.Proof.database()
+		outs = make([]ZOut, len(roots))
+		for i := range reply.Outs {
+			if reply.Outs[i].Root != roots[i] {
+				return errInvalidReply
+			}
+			value, err := verifyRecord(head.Root, txstate.OutKey(&roots[i]), proof)
+			if err != nil {
+				return err
+			}
+			if len(value) == 0 {
+				return errInvalidReply
+			}
+			outs[i].Root = roots[i]
+			if err := rlp.DecodeBytes(value, &outs[i].Out); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outs, nil
+}
+
+// witnesses retrieves the witnesses of outs by root, anchored at the state
+// of head. The paths are checked to lead from the commitments of the outs to
+// the anchor, which is proven to be the root of their tree.
+func (r *retriever) witnesses(ctx context.Context, head *types.Header, outs []ZOut) ([]ZWitness, error) {
+	roots := make([]keys.Uint256, len(outs))
+	for i := range outs {
+		roots[i] = outs[i].Root
+	}
+	msg, err := r.retrieve(ctx, GetWitnessesMsg, len(roots), func(reqID uint64) interface{} {
+		return &getOutsData{ReqID: reqID, Head: head.Hash(), Roots: roots}
+	}, func(msg reply) error {
+		reply := msg.(*witnessesData)
+		if len(reply.Witnesses) != len(outs) {
+			return errInvalidReply
+		}
+		proof := reply.Proof.database()
+		for i, w := range reply.Witnesses {
+			if w.Root != outs[i].Root || len(w.Paths) != txstate.DEPTH {
+				return errInvalidReply
+			}
+			cm := outs[i].Out.ToRootCM()
+			var paths [txstate.DEPTH]keys.Uint256
+			copy(paths[:], w.Paths)
+			if txstate.CalcRoot(cm, w.Pos, &paths) != w.Anchor {
+				return errInvalidReply
+			}
+			treeIndex, err := verifySlot(head.Root, txstate.TreeIndexKey(*cm), proof)
+			if err != nil {
+				return err
+			}
+			anchor, err := verifySlot(head.Root, txstate.TreeRootKey(keys.Uint256_To_Uint64(&treeIndex)), proof)
+			if err != nil {
+				return err
+			}
+			if anchor != w.Anchor {
+				return errInvalidReply
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*witnessesData).Witnesses, nil
+}
+
+// sendTxs sends transactions to all servers, returning the errors of the
+// first server replying.
+func (r *retriever) sendTxs(ctx context.Context, txs []*types.Transaction) ([]string, error) {
+	servers := r.peers.Servers()
+	if len(servers) == 0 {
+		return nil, errNoServer
+	}
+	type result struct {
+		errs []string
+		err  error
+	}
+	results := make(chan result, len(servers))
+	for _, p := range servers {
+		go func(p *peer) {
+			msg, err := r.request(ctx, p, SendTxMsg, len(txs), func(reqID uint64) interface{} {
+				return &sendTxData{ReqID: reqID, Txs: txs}
+			})
+			if err == nil && len(msg.(*txStatusData).Errors) != len(txs) {
+				err = errInvalidReply
+			}
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			results <- result{errs: msg.(*txStatusData).Errors}
+		}(p)
+	}
+	var err error
+	for range servers {
+		res := <-results
+		if res.err == nil {
+			return res.errs, nil
+		}
+		err = res.err
+	}
+	return nil, err
+}
@@ -0,0 +1,166 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/tri"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// testChain serves the headers and the states of a test chain, the state
+// roots of all headers being replaced by forged if set. The zstate of the
+// blocks below tail is pruned.
+type testChain struct {
+	serverChain
+	db      state.Database
+	headers map[common.Hash]*types.Header
+	forged  *common.Hash
+	tail    uint64
+}
+
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	header := c.headers[hash]
+	if header != nil && c.forged != nil {
+		header = types.CopyHeader(header)
+		header.Root = *c.forged
+	}
+	return header
+}
+
+func (c *testChain) ZStateTail() uint64 {
+	return c.tail
+}
+
+func (c *testChain) StateAt(root common.Hash, number uint64) (*state.StateDB, error) {
+	return state.New(root, c.db, number)
+}
+
+// newTestState commits a state recording block and out, returning its root.
+func newTestState(t *testing.T, db state.Database, block *txstate.StateBlock, root keys.Uint256, out *txstate.OutState) common.Hash {
+	st, err := state.New(common.Hash{}, db, 1)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	zs := st.GetZState()
+	tri.UpdateObj(zs.Tri, txstate.BlockKey(1), block)
+	tri.UpdateObj(zs.Tri, txstate.OutKey(&root), out)
+	hash, err := st.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(hash, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return hash
+}
+
+// newTestPeers connects a light client to a server over a message pipe,
+// returning the client and a function disconnecting them.
+func newTestPeers(server *LesServer) (*LightSero, func()) {
+	client := &LightSero{peers: newPeerSet(), syncCh: make(chan *peer, 1)}
+	client.retriever = newRetriever(client.peers)
+
+	crw, srw := p2p.MsgPipe()
+	sp := newPeer(lpv1, p2p.NewPeer(discover.NodeID{1}, "server", nil), crw)
+	sp.serving, sp.buf = true, newBuffer(defaultBufLimit, defaultRecharge)
+	sp.SetHead(common.Hash{}, 1, big.NewInt(1))
+	client.peers.Register(sp)
+	cp := newPeer(lpv1, p2p.NewPeer(discover.NodeID{2}, "client", nil), srw)
+	cp.buf = newBuffer(defaultBufLimit, defaultRecharge)
+
+	go func() {
+		for server.handleMsg(cp) == nil {
+		}
+	}()
+	go func() {
+		for client.handleMsg(sp) == nil {
+		}
+	}()
+	return client, func() {
+		crw.Close()
+		srw.Close()
+		server.queue.stop()
+	}
+}
+
+func TestZStateRoundTrip(t *testing.T) {
+	db := state.NewDatabase(serodb.NewMemDatabase())
+
+	root := keys.Uint256{1}
+	cm := keys.Uint256{2}
+	block := &txstate.StateBlock{Roots: []keys.Uint256{root}, Dels: []keys.Uint256{{3}}}
+	out := &txstate.OutState{Index: 7, OutCM: &cm, RootCM: &cm}
+
+	genesis := &types.Header{Number: big.NewInt(0)}
+	head := &types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash(), Root: newTestState(t, db, block, root, out)}
+	forged := newTestState(t, db, &txstate.StateBlock{Roots: []keys.Uint256{{4}}}, root, &txstate.OutState{Index: 8, OutCM: &cm, RootCM: &cm})
+
+	chain := &testChain{db: db, headers: map[common.Hash]*types.Header{genesis.Hash(): genesis, head.Hash(): head}}
+	server := &LesServer{chain: chain, peers: newPeerSet(), queue: newServingQueue(1)}
+	client, disconnect := newTestPeers(server)
+	defer disconnect()
+
+	ctx := context.Background()
+	blocks, err := client.retriever.zblocks(ctx, head, []*types.Header{genesis, head})
+	if err != nil {
+		t.Fatalf("failed to retrieve zblocks: %v", err)
+	}
+	if got := blocks[genesis.Hash()]; len(got.Roots) != 0 || len(got.Dels) != 0 {
+		t.Errorf("genesis zblock mismatch: have %v, want empty", got)
+	}
+	if got := blocks[head.Hash()]; !reflect.DeepEqual(got.Roots, block.Roots) || !reflect.DeepEqual(got.Dels, block.Dels) {
+		t.Errorf("head zblock mismatch: have %v, want %v", got, block)
+	}
+	outs, err := client.retriever.outs(ctx, head, []keys.Uint256{root})
+	if err != nil {
+		t.Fatalf("failed to retrieve outs: %v", err)
+	}
+	if len(outs) != 1 || outs[0].Root != root || outs[0].Out.Index != out.Index {
+		t.Errorf("out mismatch: have %v, want %v", outs, out)
+	}
+	if _, err := client.retriever.outs(ctx, head, []keys.Uint256{{5}}); err == nil {
+		t.Errorf("unknown out retrieved")
+	}
+
+	// The zstate below the tail is not served
+	chain.tail = 1
+	if _, err := client.retriever.zblocks(ctx, head, []*types.Header{genesis}); err == nil {
+		t.Errorf("pruned zblock retrieved")
+	}
+	chain.tail = 0
+
+	// A server answering from another state fails the proofs
+	chain.forged = &forged
+	if _, err := client.retriever.zblocks(ctx, head, []*types.Header{head}); err == nil {
+		t.Errorf("forged zblock accepted")
+	}
+	if _, err := client.retriever.outs(ctx, head, []keys.Uint256{root}); err == nil {
+		t.Errorf("forged out accepted")
+	}
+}
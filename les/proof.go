@@ -0,0 +1,123 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/trie"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// NodeList is the list of the trie nodes proving the zstate records of a
+// reply against the state root of the header the client requested them at.
+type NodeList [][]byte
+
+// Put implements serodb.Putter, collecting the nodes of trie proofs.
+func (n *NodeList) Put(key []byte, value []byte) error {
+	*n = append(*n, common.CopyBytes(value))
+	return nil
+}
+
+// database returns the nodes keyed by their hash, as read by trie.VerifyProof.
+func (n NodeList) database() *serodb.MemDatabase {
+	db := serodb.NewMemDatabase()
+	for _, node := range n {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// zstateProver proves the zstate records of the state of a header.
+type zstateProver struct {
+	accounts state.Trie
+	storage  state.Trie // storage of the commitment trees, nil if empty
+	proof    NodeList
+}
+
+func newZStateProver(st *state.StateDB, root common.Hash) (*zstateProver, error) {
+	accounts, err := st.Database().OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &zstateProver{accounts: accounts, storage: st.StorageTrie(state.EmptyAddress)}, nil
+}
+
+// proveRecord adds the proof of the zstate record at key of the account trie.
+// Both tries are secure, their proofs are keyed by the hash of the key.
+func (p *zstateProver) proveRecord(key []byte) error {
+	return p.accounts.Prove(crypto.Keccak256(key), 0, &p.proof)
+}
+
+// proveSlot adds the proof of a slot of the storage of the commitment trees.
+func (p *zstateProver) proveSlot(slot keys.Uint256) error {
+	if err := p.proveRecord(state.EmptyAddress[:]); err != nil {
+		return err
+	}
+	if p.storage == nil {
+		return nil
+	}
+	return p.storage.Prove(crypto.Keccak256(slot[:]), 0, &p.proof)
+}
+
+// decodeZBlock decodes the StateBlock record of the block of hash, empty if
+// the block has none.
+func decodeZBlock(hash common.Hash, value []byte) (ZBlock, error) {
+	block := ZBlock{Hash: hash}
+	if len(value) == 0 {
+		return block, nil
+	}
+	var record txstate.StateBlock
+	if err := rlp.DecodeBytes(value, &record); err != nil {
+		return block, err
+	}
+	block.Roots, block.Dels = record.Roots, record.Dels
+	return block, nil
+}
+
+// verifyRecord returns the zstate record at key proven against root, nil if
+// the proof shows there is none.
+func verifyRecord(root common.Hash, key []byte, proof *serodb.MemDatabase) ([]byte, error) {
+	value, _, err := trie.VerifyProof(root, crypto.Keccak256(key), proof)
+	return value, err
+}
+
+// verifySlot returns the slot of the storage of the commitment trees proven
+// against root.
+func verifySlot(root common.Hash, slot keys.Uint256, proof *serodb.MemDatabase) (ret keys.Uint256, err error) {
+	enc, err := verifyRecord(root, state.EmptyAddress[:], proof)
+	if err != nil || enc == nil {
+		return ret, err
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return ret, err
+	}
+	value, err := verifyRecord(account.Root, slot[:], proof)
+	if err != nil || value == nil {
+		return ret, err
+	}
+	_, content, _, err := rlp.Split(value)
+	if err != nil {
+		return ret, err
+	}
+	return keys.Uint256(common.BytesToHash(content)), nil
+}
@@ -0,0 +1,239 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package les implements the light client protocol of SERO. Light clients
+// download the block headers only and fetch the outs and the commitment tree
+// witnesses they need on demand from serving full nodes, which prove them
+// against the state roots of the headers, rate their requests with flow
+// control and serve them by priority.
+package les
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	lpv1 = 1
+)
+
+// ProtocolName is the official short name of the protocol used during capability negotiation.
+var ProtocolName = "les"
+
+// ProtocolVersions are the supported versions of the les protocol (first is primary).
+var ProtocolVersions = []uint{lpv1}
+
+// ProtocolLengths are the number of implemented message corresponding to different protocol versions.
+var ProtocolLengths = []uint64{12}
+
+const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+
+// les protocol message codes
+const (
+	StatusMsg          = 0x00
+	AnnounceMsg        = 0x01
+	GetBlockHeadersMsg = 0x02
+	BlockHeadersMsg    = 0x03
+	GetZBlocksMsg      = 0x04
+	ZBlocksMsg         = 0x05
+	GetOutsMsg         = 0x06
+	OutsMsg            = 0x07
+	GetWitnessesMsg    = 0x08
+	WitnessesMsg       = 0x09
+	SendTxMsg          = 0x0a
+	TxStatusMsg        = 0x0b
+)
+
+// Maximum number of items requested by a single message
+const (
+	maxHeaderFetch  = 192
+	maxZBlockFetch  = 128
+	maxOutFetch     = 256
+	maxWitnessFetch = 64
+	maxTxSend       = 64
+)
+
+type errCode int
+
+const (
+	ErrMsgTooLarge = iota
+	ErrDecode
+	ErrInvalidMsgCode
+	ErrProtocolVersionMismatch
+	ErrNetworkIdMismatch
+	ErrGenesisBlockMismatch
+	ErrNoStatusMsg
+	ErrExtraStatusMsg
+	ErrRequestRejected
+	ErrUnexpectedResponse
+)
+
+func (e errCode) String() string {
+	return errorToString[int(e)]
+}
+
+// XXX change once legacy code is out
+var errorToString = map[int]string{
+	ErrMsgTooLarge:             "Message too long",
+	ErrDecode:                  "Invalid message",
+	ErrInvalidMsgCode:          "Invalid message code",
+	ErrProtocolVersionMismatch: "Protocol version mismatch",
+	ErrNetworkIdMismatch:       "NetworkId mismatch",
+	ErrGenesisBlockMismatch:    "Genesis block mismatch",
+	ErrNoStatusMsg:             "No status message",
+	ErrExtraStatusMsg:          "Extra status message",
+	ErrRequestRejected:         "Request rejected, buffer exceeded",
+	ErrUnexpectedResponse:      "Unexpected response",
+}
+
+func errResp(code errCode, format string, v ...interface{}) error {
+	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+}
+
+var (
+	errNoServer       = errors.New("no serving peer")
+	errRequestTimeout = errors.New("request timed out")
+	errInvalidReply   = errors.New("invalid reply")
+	errUnknownHead    = errors.New("unknown head")
+)
+
+// statusData is the network packet for the status message. Servers announce
+// the parameters of the flow control their clients are subject to.
+type statusData struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	Head            common.Hash
+	HeadNum         uint64
+	Genesis         common.Hash
+	Serving         bool
+	BufLimit        uint64 // cost units a client may spend at once
+	Recharge        uint64 // cost units recharged per second
+}
+
+// announceData is the network packet announcing a new head to the clients.
+type announceData struct {
+	Hash   common.Hash
+	Number uint64
+	TD     *big.Int
+}
+
+// getBlockHeadersData requests the canonical headers from Origin on.
+type getBlockHeadersData struct {
+	ReqID  uint64
+	Origin uint64
+	Amount uint64
+}
+
+// getZBlocksData requests the zstate records of blocks, proven against the
+// state of the Head header.
+type getZBlocksData struct {
+	ReqID  uint64
+	Head   common.Hash
+	Hashes []common.Hash
+}
+
+// getOutsData requests outs, or their witnesses, by root, proven against the
+// state of the Head header.
+type getOutsData struct {
+	ReqID uint64
+	Head  common.Hash
+	Roots []keys.Uint256
+}
+
+// sendTxData sends transactions to the pool of a server.
+type sendTxData struct {
+	ReqID uint64
+	Txs   []*types.Transaction
+}
+
+// ZBlock is the zstate record of a block: the roots of the outs it created
+// and of the ones it spent.
+type ZBlock struct {
+	Hash  common.Hash
+	Roots []keys.Uint256
+	Dels  []keys.Uint256
+}
+
+// ZOut is an out of the commitment tree.
+type ZOut struct {
+	Root keys.Uint256
+	Out  txstate.OutState
+}
+
+// ZWitness is the path of the commitment of an out to the anchor of the
+// commitment tree.
+type ZWitness struct {
+	Root   keys.Uint256
+	Pos    uint64
+	Paths  []keys.Uint256
+	Anchor keys.Uint256
+}
+
+// Replies carry the buffer value of the client left after serving the
+// request, so the client can keep its estimate in sync. The zstate replies
+// carry the trie nodes proving their records.
+type blockHeadersData struct {
+	ReqID, BV uint64
+	Headers   []*types.Header
+}
+
+type zBlocksData struct {
+	ReqID, BV uint64
+	Blocks    []ZBlock
+	Proof     NodeList
+}
+
+type outsData struct {
+	ReqID, BV uint64
+	Outs      []ZOut
+	Proof     NodeList
+}
+
+type witnessesData struct {
+	ReqID, BV uint64
+	Witnesses []ZWitness
+	Proof     NodeList
+}
+
+type txStatusData struct {
+	ReqID, BV uint64
+	Errors    []string // empty if the transaction was accepted
+}
+
+// reply is implemented by the replies to the requests of a client.
+type reply interface {
+	reqID() uint64
+	bufValue() uint64
+}
+
+func (r *blockHeadersData) reqID() uint64    { return r.ReqID }
+func (r *blockHeadersData) bufValue() uint64 { return r.BV }
+func (r *zBlocksData) reqID() uint64         { return r.ReqID }
+func (r *zBlocksData) bufValue() uint64      { return r.BV }
+func (r *outsData) reqID() uint64            { return r.ReqID }
+func (r *outsData) bufValue() uint64         { return r.BV }
+func (r *witnessesData) reqID() uint64       { return r.ReqID }
+func (r *witnessesData) bufValue() uint64    { return r.BV }
+func (r *txStatusData) reqID() uint64        { return r.ReqID }
+func (r *txStatusData) bufValue() uint64     { return r.BV }
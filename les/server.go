@@ -0,0 +1,442 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/sero"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// serverChain is the chain of the full node the light clients are served
+// from.
+type serverChain interface {
+	CurrentHeader() *types.Header
+	Genesis() *types.Block
+	GetTd(hash common.Hash, number uint64) *big.Int
+	GetHeaderByHash(hash common.Hash) *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	StateAt(root common.Hash, number uint64) (*state.StateDB, error)
+	ZStateTail() uint64
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// serverTxPool is the pool the transactions of the light clients are added to.
+type serverTxPool interface {
+	AddRemotes(txs []*types.Transaction) []error
+}
+
+// LesServer serves the light clients from a full node.
+type LesServer struct {
+	chain     serverChain
+	txPool    serverTxPool
+	networkID uint64
+	maxPeers  int
+	peers     *peerSet
+	queue     *servingQueue
+	quit      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewLesServer creates the light server of a full node. It serves at most
+// config.LightPeers clients, with threads taking config.LightServ percent of
+// the CPUs.
+func NewLesServer(s *sero.Sero, config *sero.Config) (*LesServer, error) {
+	if config.LightPeers <= 0 {
+		return nil, fmt.Errorf("invalid light peer count %d", config.LightPeers)
+	}
+	threads := runtime.NumCPU() * config.LightServ / 100
+	if threads < 1 {
+		threads = 1
+	}
+	return &LesServer{
+		chain:     s.BlockChain(),
+		txPool:    s.TxPool(),
+		networkID: config.NetworkId,
+		maxPeers:  config.LightPeers,
+		peers:     newPeerSet(),
+		queue:     newServingQueue(threads),
+		quit:      make(chan struct{}),
+	}, nil
+}
+
+// Protocols returns the les protocols served to the light clients.
+func (s *LesServer) Protocols() []p2p.Protocol {
+	var protocols []p2p.Protocol
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				s.wg.Add(1)
+				defer s.wg.Done()
+				return s.handle(newPeer(int(version), p, rw))
+			},
+			PeerInfo: func(id discover.NodeID) interface{} {
+				if p := s.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
+					return p.Info()
+				}
+				return nil
+			},
+		})
+	}
+	return protocols
+}
+
+// Start starts announcing the new heads to the clients.
+func (s *LesServer) Start(srvr *p2p.Server) {
+	go s.announceLoop()
+	log.Info("Light server started", "clients", s.maxPeers)
+}
+
+// Stop disconnects the clients and stops serving.
+func (s *LesServer) Stop() {
+	close(s.quit)
+	s.peers.Close()
+	s.queue.stop()
+	s.wg.Wait()
+	log.Info("Light server stopped")
+}
+
+// SetBloomBitsIndexer is a no-op, light clients do not filter logs.
+func (s *LesServer) SetBloomBitsIndexer(bbIndexer *core.ChainIndexer) {}
+
+func (s *LesServer) announceLoop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			hash, number := ev.Block.Hash(), ev.Block.NumberU64()
+			announce := &announceData{Hash: hash, Number: number, TD: s.chain.GetTd(hash, number)}
+			for _, p := range s.peers.All() {
+				if err := p2p.Send(p.rw, AnnounceMsg, announce); err != nil {
+					p.Log().Debug("Head announcement failed", "err", err)
+				}
+			}
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// handle is the callback invoked to manage the life cycle of a light client.
+func (s *LesServer) handle(p *peer) error {
+	if s.peers.Len() >= s.maxPeers && !p.Peer.Info().Network.Trusted {
+		return p2p.DiscTooManyPeers
+	}
+	p.Log().Debug("Light client connected", "name", p.Name())
+
+	chain := s.chain
+	head := chain.CurrentHeader()
+	hash, number := head.Hash(), head.Number.Uint64()
+	status := &statusData{
+		ProtocolVersion: uint32(p.version),
+		NetworkId:       s.networkID,
+		TD:              chain.GetTd(hash, number),
+		Head:            hash,
+		HeadNum:         number,
+		Genesis:         chain.Genesis().Hash(),
+		Serving:         true,
+		BufLimit:        defaultBufLimit,
+		Recharge:        defaultRecharge,
+	}
+	if err := p.Handshake(s.networkID, status); err != nil {
+		p.Log().Debug("Light client handshake failed", "err", err)
+		return err
+	}
+	if err := s.peers.Register(p); err != nil {
+		p.Log().Error("Light client registration failed", "err", err)
+		return err
+	}
+	defer s.peers.Unregister(p.id)
+
+	for {
+		if err := s.handleMsg(p); err != nil {
+			p.Log().Debug("Light client message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a light
+// client. The remote connection is torn down upon returning any error.
+func (s *LesServer) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	var (
+		items int
+		reqID uint64
+		serve func(bv uint64) error
+	)
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case GetBlockHeadersMsg:
+		var req getBlockHeadersData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if req.Amount > maxHeaderFetch {
+			req.Amount = maxHeaderFetch
+		}
+		items, reqID = int(req.Amount), req.ReqID
+		serve = func(bv uint64) error {
+			return p2p.Send(p.rw, BlockHeadersMsg, &blockHeadersData{ReqID: req.ReqID, BV: bv, Headers: s.headers(req.Origin, req.Amount)})
+		}
+
+	case GetZBlocksMsg:
+		var req getZBlocksData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if len(req.Hashes) > maxZBlockFetch {
+			req.Hashes = req.Hashes[:maxZBlockFetch]
+		}
+		items, reqID = len(req.Hashes), req.ReqID
+		serve = func(bv uint64) error {
+			blocks, proof, err := s.zblocks(req.Head, req.Hashes)
+			if err != nil {
+				return err
+			}
+			return p2p.Send(p.rw, ZBlocksMsg, &zBlocksData{ReqID: req.ReqID, BV: bv, Blocks: blocks, Proof: proof})
+		}
+
+	case GetOutsMsg:
+		var req getOutsData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if len(req.Roots) > maxOutFetch {
+			req.Roots = req.Roots[:maxOutFetch]
+		}
+		items, reqID = len(req.Roots), req.ReqID
+		serve = func(bv uint64) error {
+			outs, proof, err := s.outs(req.Head, req.Roots)
+			if err != nil {
+				return err
+			}
+			return p2p.Send(p.rw, OutsMsg, &outsData{ReqID: req.ReqID, BV: bv, Outs: outs, Proof: proof})
+		}
+
+	case GetWitnessesMsg:
+		var req getOutsData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if len(req.Roots) > maxWitnessFetch {
+			req.Roots = req.Roots[:maxWitnessFetch]
+		}
+		items, reqID = len(req.Roots), req.ReqID
+		serve = func(bv uint64) error {
+			witnesses, proof, err := s.witnesses(req.Head, req.Roots)
+			if err != nil {
+				return err
+			}
+			return p2p.Send(p.rw, WitnessesMsg, &witnessesData{ReqID: req.ReqID, BV: bv, Witnesses: witnesses, Proof: proof})
+		}
+
+	case SendTxMsg:
+		var req sendTxData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if len(req.Txs) > maxTxSend {
+			return errResp(ErrRequestRejected, "%d transactions sent at once", len(req.Txs))
+		}
+		items, reqID = len(req.Txs), req.ReqID
+		serve = func(bv uint64) error {
+			errs := make([]string, len(req.Txs))
+			for i, err := range s.txPool.AddRemotes(req.Txs) {
+				if err != nil {
+					errs[i] = err.Error()
+				}
+			}
+			return p2p.Send(p.rw, TxStatusMsg, &txStatusData{ReqID: req.ReqID, BV: bv, Errors: errs})
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+
+	bv, ok := p.buf.spend(cost(msg.Code, items))
+	if !ok {
+		return errResp(ErrRequestRejected, "request %d of %d items, buffer %d", reqID, items, bv)
+	}
+	var serveErr error
+	if !s.queue.serve(requestPriorities[msg.Code], func() { serveErr = serve(bv) }) {
+		return p2p.DiscQuitting
+	}
+	return serveErr
+}
+
+// headers returns the canonical headers from origin on.
+func (s *LesServer) headers(origin, amount uint64) []*types.Header {
+	chain := s.chain
+	headers := make([]*types.Header, 0, amount)
+	for number := origin; number < origin+amount; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// stateAt returns the state of the head header, nil if the header is
+// unknown or its state is not available anymore.
+func (s *LesServer) stateAt(head common.Hash) (*types.Header, *state.StateDB) {
+	header := s.chain.GetHeaderByHash(head)
+	if header == nil {
+		return nil, nil
+	}
+	st, err := s.chain.StateAt(header.Root, header.Number.Uint64())
+	if err != nil {
+		log.Debug("Light request for unavailable state", "number", header.Number, "hash", head, "err", err)
+		return nil, nil
+	}
+	return header, st
+}
+
+// zblocks returns the zstate records of the known blocks among hashes up to
+// head, with their proofs. Blocks creating and spending no outs have no
+// record, their proof shows it.
+func (s *LesServer) zblocks(head common.Hash, hashes []common.Hash) ([]ZBlock, NodeList, error) {
+	header, st := s.stateAt(head)
+	if st == nil {
+		return nil, nil, nil
+	}
+	prover, err := newZStateProver(st, header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	zs := st.GetZState()
+	tail := s.chain.ZStateTail()
+	blocks := make([]ZBlock, 0, len(hashes))
+	for _, hash := range hashes {
+		// The zstate of the blocks below the tail is not served any more
+		block := s.chain.GetHeaderByHash(hash)
+		if block == nil || block.Number.Cmp(header.Number) > 0 || block.Number.Uint64() < tail {
+			continue
+		}
+		key := txstate.BlockKey(block.Number.Uint64())
+		value, err := zs.Tri.TryGet(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		zblock, err := decodeZBlock(hash, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := prover.proveRecord(key); err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, zblock)
+	}
+	return blocks, prover.proof, nil
+}
+
+// outs returns the known outs among roots, with their proofs.
+func (s *LesServer) outs(head common.Hash, roots []keys.Uint256) ([]ZOut, NodeList, error) {
+	header, st := s.stateAt(head)
+	if st == nil {
+		return nil, nil, nil
+	}
+	prover, err := newZStateProver(st, header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	zs := st.GetZState()
+	outs := make([]ZOut, 0, len(roots))
+	for i := range roots {
+		out, err := zs.State.GetOut(&roots[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if out == nil {
+			continue
+		}
+		if err := prover.proveRecord(txstate.OutKey(&roots[i])); err != nil {
+			return nil, nil, err
+		}
+		outs = append(outs, ZOut{Root: roots[i], Out: out.Clone()})
+	}
+	return outs, prover.proof, nil
+}
+
+// witnesses returns the witnesses of the known outs among roots, anchored at
+// the state of head, with the proofs of their anchors.
+func (s *LesServer) witnesses(head common.Hash, roots []keys.Uint256) ([]ZWitness, NodeList, error) {
+	header, st := s.stateAt(head)
+	if st == nil {
+		return nil, nil, nil
+	}
+	prover, err := newZStateProver(st, header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	zs := st.GetZState()
+	witnesses := make([]ZWitness, 0, len(roots))
+	for i := range roots {
+		out, err := zs.State.GetOut(&roots[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if out == nil {
+			continue
+		}
+		cm := *out.ToRootCM()
+		pos, paths, anchor := zs.State.MTree.GetPaths(cm)
+		treeIndex := zs.Tri.GetState(txstate.TreeIndexKey(cm).NewRef())
+		if err := prover.proveSlot(txstate.TreeIndexKey(cm)); err != nil {
+			return nil, nil, err
+		}
+		if err := prover.proveSlot(txstate.TreeRootKey(keys.Uint256_To_Uint64(&treeIndex))); err != nil {
+			return nil, nil, err
+		}
+		witnesses = append(witnesses, ZWitness{Root: roots[i], Pos: pos, Paths: paths[:], Anchor: anchor})
+	}
+	return witnesses, prover.proof, nil
+}
@@ -0,0 +1,78 @@
+// Package prometheus exposes the metrics of a go-metrics registry in the
+// Prometheus text exposition format.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sero-cash/go-sero/metrics"
+)
+
+// quantiles are the quantiles reported of timers and histograms.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+
+// Handler returns the handler writing the metrics of r for a scrape.
+// Resetting timers are left out, their snapshots reset them for the other
+// reporters of the registry.
+func Handler(r metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var names []string
+		metricsByName := make(map[string]interface{})
+		r.Each(func(name string, i interface{}) {
+			names = append(names, name)
+			metricsByName[name] = i
+		})
+		sort.Strings(names)
+
+		buf := new(bytes.Buffer)
+		for _, name := range names {
+			write(buf, mangle(name), metricsByName[name])
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	})
+}
+
+// mangle turns a metric name such as "chain/inserts" into a valid Prometheus
+// name such as "sero_chain_inserts".
+func mangle(name string) string {
+	mangled := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	return "sero_" + mangled
+}
+
+func write(buf *bytes.Buffer, name string, i interface{}) {
+	switch m := i.(type) {
+	case metrics.Counter:
+		fmt.Fprintf(buf, "# TYPE %s counter\n%s %d\n", name, name, m.Count())
+	case metrics.Gauge:
+		fmt.Fprintf(buf, "# TYPE %s gauge\n%s %d\n", name, name, m.Value())
+	case metrics.GaugeFloat64:
+		fmt.Fprintf(buf, "# TYPE %s gauge\n%s %g\n", name, name, m.Value())
+	case metrics.Meter:
+		fmt.Fprintf(buf, "# TYPE %s_total counter\n%s_total %d\n", name, name, m.Snapshot().Count())
+	case metrics.Timer:
+		t := m.Snapshot()
+		writeSummary(buf, name, t.Percentiles(quantiles), t.Count(), t.Sum())
+	case metrics.Histogram:
+		h := m.Snapshot()
+		writeSummary(buf, name, h.Percentiles(quantiles), h.Count(), h.Sum())
+	}
+}
+
+func writeSummary(buf *bytes.Buffer, name string, values []float64, count int64, sum int64) {
+	fmt.Fprintf(buf, "# TYPE %s summary\n", name)
+	for i, q := range quantiles {
+		fmt.Fprintf(buf, "%s{quantile=\"%g\"} %g\n", name, q, values[i])
+	}
+	fmt.Fprintf(buf, "%s_sum %d\n%s_count %d\n", name, sum, name, count)
+}
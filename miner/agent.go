@@ -20,9 +20,16 @@ import (
 	"sync"
 
 	"sync/atomic"
+	"time"
 
 	"github.com/sero-cash/go-sero/consensus"
 	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/metrics"
+)
+
+var (
+	sealTimer   = metrics.NewRegisteredTimer("miner/seal", nil)
+	sealedMeter = metrics.NewRegisteredMeter("miner/sealed", nil)
 )
 
 type CpuAgent struct {
@@ -100,7 +107,10 @@ out:
 }
 
 func (self *CpuAgent) mine(work *Work, stop <-chan struct{}) {
+	start := time.Now()
 	if result, err := self.engine.Seal(self.chain, work.Block, stop); result != nil {
+		sealTimer.UpdateSince(start)
+		sealedMeter.Mark(1)
 		log.Info("Successfully sealed new block", "number", result.Number(), "hash", result.Hash())
 		self.returnCh <- &Result{work, result}
 	} else {
@@ -200,3 +200,30 @@ func (self *Miner) SetSerobase(addr common.AccountAddress) {
 	self.coinbase = addr
 	self.worker.setSerobase(addr)
 }
+
+// Payout is a share of the rewards of the mined blocks paid to an account
+// other than the serobase, at a fresh PKr for every block.
+type Payout struct {
+	Address common.AccountAddress
+	Percent uint64
+}
+
+// SetPayouts splits the rewards of the blocks mined from the CoinbaseSplit
+// fork on among the payouts, the serobase keeping what they leave.
+func (self *Miner) SetPayouts(payouts []Payout) error {
+	if uint64(len(payouts)) > params.MaxCoinbasePayouts {
+		return fmt.Errorf("Payouts exceed max count. %d > %v", len(payouts), params.MaxCoinbasePayouts)
+	}
+	total := uint64(0)
+	for _, payout := range payouts {
+		if payout.Percent == 0 || payout.Percent > 100 {
+			return fmt.Errorf("Invalid payout percent %d", payout.Percent)
+		}
+		total += payout.Percent
+	}
+	if total > 100 {
+		return fmt.Errorf("Payouts exceed the reward. %d%%", total)
+	}
+	self.worker.setPayouts(payouts)
+	return nil
+}
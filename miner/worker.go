@@ -112,6 +112,7 @@ type worker struct {
 	chainDb serodb.Database
 
 	coinbase common.AccountAddress
+	payouts  []Payout
 	extra    []byte
 
 	currentMu sync.Mutex
@@ -164,6 +165,12 @@ func (self *worker) setSerobase(addr common.AccountAddress) {
 	self.coinbase = addr
 }
 
+func (self *worker) setPayouts(payouts []Payout) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.payouts = append([]Payout(nil), payouts...)
+}
+
 func (self *worker) setExtra(extra []byte) {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -411,6 +418,16 @@ func (self *worker) commitNewWork() {
 		addr.SetBytes(pkr[:])
 		header.Coinbase = addr
 		header.Licr = licr
+
+		if self.config.IsCoinbaseSplit(header.Number) {
+			for _, payout := range self.payouts {
+				pkr := keys.Addr2PKr(payout.Address.ToUint512(), keys.RandUint256().NewRef())
+				header.Payouts = append(header.Payouts, types.Payout{
+					PKr:     common.BytesToAddress(pkr[:]),
+					Percent: payout.Percent,
+				})
+			}
+		}
 	}
 
 	if err := self.engine.Prepare(self.chain, header); err != nil {
@@ -0,0 +1,312 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+const (
+	// auditLogFiles is the number of rotated audit logs kept next to the
+	// current one.
+	auditLogFiles = 10
+
+	// defaultAuditMaxSize is the size in megabytes the audit log is rotated at
+	// by default.
+	defaultAuditMaxSize = 100
+
+	redacted = "<redacted>"
+)
+
+// auditedNamespaces are the namespaces all methods of which are audited, the
+// ones not served publicly.
+var auditedNamespaces = map[string]bool{
+	"admin":     true,
+	"miner":     true,
+	"personal":  true,
+	"session":   true,
+	"policy":    true,
+	"scheduler": true,
+	"lstate":    true,
+	"memoindex": true,
+}
+
+// auditedMethods are the methods of the public namespaces that sign
+// transactions or change the state of the node. Methods added to them must
+// be listed here.
+var auditedMethods = map[string]bool{
+	"sero_sendTransaction":           true,
+	"sero_sendTransactionIdempotent": true,
+	"sero_sendTransactions":          true,
+	"sero_reSendTransaction":         true,
+	"sero_sendFromTemplate":          true,
+	"sero_createSendTemplate":        true,
+	"sero_deleteSendTemplate":        true,
+	"sero_merge":                     true,
+	"sero_cancelTransaction":         true,
+	"sero_createPkg":                 true,
+	"sero_closePkg":                  true,
+	"sero_transferPkg":               true,
+	"sero_refundPkg":                 true,
+	"sero_createHashlockPkg":         true,
+	"sero_closeWithPreimage":         true,
+	"sero_createPaymentRequest":      true,
+	"sero_payRequest":                true,
+	"sero_encryptTransaction":        true,
+	"sero_signTxWithSk":              true,
+	"sero_forwardRawTransaction":     true,
+	"sero_lockOuts":                  true,
+	"sero_unlockOuts":                true,
+}
+
+// secretParams are the positions of the parameters carrying secrets.
+var secretParams = map[string][]int{
+	"personal_newAccount":             {0},
+	"personal_importRawKey":           {0, 1},
+	"personal_deriveSeroAccount":      {0, 2},
+	"personal_exportDerivedKey":       {0, 2},
+	"personal_openWallet":             {1},
+	"personal_unlockAccount":          {1},
+	"personal_sendTransaction":        {1},
+	"personal_signAndSendTransaction": {1},
+	"personal_exportBundle":           {1, 2},
+	"personal_importBundle":           {0, 1, 2},
+	"sero_signTxWithSk":               {1},
+}
+
+// publicParams are the positions of the string parameters of the personal
+// namespace logged as is, all others are redacted as they are likely secrets.
+var publicParams = map[string][]int{
+	"personal_openWallet":        {0},
+	"personal_deriveAccount":     {0, 1},
+	"personal_deriveSeroAccount": {1},
+	"personal_exportDerivedKey":  {1},
+	"personal_exportBundle":      {0},
+	"personal_unlockAccount":     {0},
+	"personal_lockAccount":       {0},
+}
+
+// secretFields are the names of the fields of structured parameters carrying
+// secrets, such as the key of a pkg.
+var secretFields = map[string]bool{
+	"key":        true,
+	"password":   true,
+	"passphrase": true,
+	"passwd":     true,
+	"privkey":    true,
+	"seed":       true,
+	"sk":         true,
+	"preimage":   true,
+}
+
+// auditRecord is the line written to the audit log for a call.
+type auditRecord struct {
+	Time      time.Time     `json:"time"`
+	Transport string        `json:"transport"`
+	Caller    string        `json:"caller,omitempty"`
+	Method    string        `json:"method"`
+	Params    []interface{} `json:"params"`
+	Result    string        `json:"result,omitempty"` // keccak256 of the JSON encoded result
+	Error     string        `json:"error,omitempty"`
+}
+
+// auditLog appends the records of the state changing RPC calls to a file,
+// rotating it once it exceeds the maximum size.
+type auditLog struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func openAuditLog(path string, maxSize int) (*auditLog, error) {
+	if maxSize <= 0 {
+		maxSize = defaultAuditMaxSize
+	}
+	l := &auditLog{path: path, maxSize: int64(maxSize) * 1024 * 1024}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *auditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the logs by one, dropping the oldest, and reopens the
+// current one empty.
+func (l *auditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", l.path, auditLogFiles))
+	for i := auditLogFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+func (l *auditLog) write(record *auditRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Error("Failed to encode RPC audit record", "method", record.Method, "err", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			log.Error("Failed to rotate RPC audit log", "path", l.path, "err", err)
+			return
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		log.Error("Failed to write RPC audit log", "path", l.path, "err", err)
+	}
+}
+
+func (l *auditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// auditor returns the rpc.Auditor of the endpoints of a transport.
+func (l *auditLog) auditor(transport string) rpc.Auditor {
+	if l == nil {
+		return nil
+	}
+	return &transportAuditor{l, transport}
+}
+
+type transportAuditor struct {
+	log       *auditLog
+	transport string
+}
+
+func (a *transportAuditor) Audits(method string) bool {
+	if auditedMethods[method] {
+		return true
+	}
+	namespace := strings.SplitN(method, "_", 2)[0]
+	return auditedNamespaces[namespace]
+}
+
+func (a *transportAuditor) Audit(entry *rpc.AuditEntry) {
+	record := &auditRecord{
+		Time:      entry.Time.UTC(),
+		Transport: a.transport,
+		Caller:    entry.Caller,
+		Method:    entry.Method,
+		Params:    redactParams(entry.Method, entry.Params),
+	}
+	if entry.Error != nil {
+		record.Error = entry.Error.Error()
+	} else if entry.Result != nil {
+		if result, err := json.Marshal(entry.Result); err == nil {
+			record.Result = crypto.Keccak256Hash(result).Hex()
+		}
+	}
+	a.log.write(record)
+}
+
+// redactParams returns the JSON form of the parameters with the secrets
+// replaced.
+func redactParams(method string, params []interface{}) []interface{} {
+	secret := make(map[int]bool)
+	for _, i := range secretParams[method] {
+		secret[i] = true
+	}
+	public := make(map[int]bool)
+	for _, i := range publicParams[method] {
+		public[i] = true
+	}
+	personal := strings.HasPrefix(method, "personal_")
+	ret := make([]interface{}, len(params))
+	for i, param := range params {
+		if secret[i] {
+			ret[i] = redacted
+			continue
+		}
+		var value interface{}
+		if raw, err := json.Marshal(param); err != nil {
+			value = redacted
+		} else if err := json.Unmarshal(raw, &value); err != nil {
+			value = redacted
+		}
+		if _, ok := value.(string); ok && personal && !public[i] {
+			value = redacted
+		}
+		ret[i] = redactFields(value)
+	}
+	return ret
+}
+
+func redactFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, fv := range v {
+			if secretFields[strings.ToLower(field)] {
+				v[field] = redacted
+			} else {
+				v[field] = redactFields(fv)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactFields(v[i])
+		}
+	}
+	return value
+}
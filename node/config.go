@@ -92,6 +92,9 @@ type Config struct {
 	// relative), then that specific path is enforced. An empty path disables IPC.
 	IPCPath string `toml:",omitempty"`
 
+	// IPCAccess restricts the methods served via the IPC interface.
+	IPCAccess rpc.Access
+
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
 	// field is empty, no HTTP API endpoint will be started.
 	HTTPHost string `toml:",omitempty"`
@@ -124,6 +127,22 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPAccess restricts the methods of the exposed modules served via the
+	// HTTP RPC interface and requires auth tokens for the sensitive ones.
+	HTTPAccess rpc.Access
+
+	// RPCLimits caps the batch size and execution time of requests served via
+	// the HTTP and websocket RPC interfaces. IPC is not limited.
+	RPCLimits rpc.Limits
+
+	// RPCAudit is the file the state changing RPC calls are recorded to, with
+	// their secrets redacted. Relative paths are resolved in the instance
+	// directory, empty disables auditing.
+	RPCAudit string `toml:",omitempty"`
+
+	// RPCAuditMaxSize is the size in megabytes the audit log is rotated at.
+	RPCAuditMaxSize int `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -150,6 +169,10 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSAccess restricts the methods of the exposed modules served via the
+	// websocket RPC interface and requires auth tokens for the sensitive ones.
+	WSAccess rpc.Access
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
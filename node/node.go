@@ -67,6 +67,8 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	auditLog *auditLog // Record of the state changing RPC calls (nil = auditing disabled)
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -255,23 +257,30 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
+	if err := n.startAudit(); err != nil {
+		return err
+	}
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
+		n.stopAudit()
 		return err
 	}
 	if err := n.startIPC(apis); err != nil {
 		n.stopInProc()
+		n.stopAudit()
 		return err
 	}
 	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts); err != nil {
 		n.stopIPC()
 		n.stopInProc()
+		n.stopAudit()
 		return err
 	}
 	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
+		n.stopAudit()
 		return err
 	}
 	// All API endpoints started successfully
@@ -279,10 +288,37 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	return nil
 }
 
+// startAudit opens the RPC audit log, if configured.
+func (n *Node) startAudit() error {
+	if n.config.RPCAudit == "" {
+		return nil // Auditing disabled.
+	}
+	path := n.config.ResolvePath(n.config.RPCAudit)
+	if path == "" {
+		return errors.New("relative RPC audit log path without a data directory")
+	}
+	auditLog, err := openAuditLog(path, n.config.RPCAuditMaxSize)
+	if err != nil {
+		return err
+	}
+	n.auditLog = auditLog
+	n.log.Info("RPC audit log opened", "path", path)
+	return nil
+}
+
+// stopAudit closes the RPC audit log.
+func (n *Node) stopAudit() {
+	if n.auditLog != nil {
+		n.auditLog.Close()
+		n.auditLog = nil
+	}
+}
+
 // startInProc initializes an in-process RPC endpoint.
 func (n *Node) startInProc(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetAuditor(n.auditLog.auditor("inproc"))
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -306,7 +342,7 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	if n.ipcEndpoint == "" {
 		return nil // IPC disabled.
 	}
-	listener, handler, err := rpc.StartIPCEndpoint(n.ipcEndpoint, apis)
+	listener, handler, err := rpc.StartIPCEndpoint(n.ipcEndpoint, apis, n.config.IPCAccess, n.auditLog.auditor("ipc"))
 	if err != nil {
 		return err
 	}
@@ -336,7 +372,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts, n.config.RPCLimits, n.config.HTTPAccess, n.auditLog.auditor("http"))
 	if err != nil {
 		return err
 	}
@@ -369,7 +405,7 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, n.config.RPCLimits, n.config.WSAccess, n.auditLog.auditor("ws"))
 	if err != nil {
 		return err
 	}
@@ -411,6 +447,7 @@ func (n *Node) Stop() error {
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
+	n.stopAudit()
 	n.rpcAPIs = nil
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
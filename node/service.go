@@ -57,6 +57,16 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.ResolvePath(path)
 }
 
+// NodeName returns the client name of the node, including its version.
+func (ctx *ServiceContext) NodeName() string {
+	return ctx.config.NodeName()
+}
+
+// Version returns the version of the node software.
+func (ctx *ServiceContext) Version() string {
+	return ctx.config.Version
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
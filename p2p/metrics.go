@@ -29,6 +29,7 @@ var (
 	ingressTrafficMeter = metrics.NewRegisteredMeter("p2p/InboundTraffic", nil)
 	egressConnectMeter  = metrics.NewRegisteredMeter("p2p/OutboundConnects", nil)
 	egressTrafficMeter  = metrics.NewRegisteredMeter("p2p/OutboundTraffic", nil)
+	peersGauge          = metrics.NewRegisteredGauge("p2p/peers", nil)
 )
 
 // meteredConn is a wrapper around a net.Conn that meters both the
@@ -704,6 +704,7 @@ running:
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				go srv.runPeer(p)
 				peers[c.id] = p
+				peersGauge.Update(int64(len(peers)))
 				if p.Inbound() {
 					inboundCount++
 				}
@@ -721,6 +722,7 @@ running:
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			pd.log.Debug("Removing p2p peer", "duration", d, "peers", len(peers)-1, "req", pd.requested, "err", pd.err)
 			delete(peers, pd.ID())
+			peersGauge.Update(int64(len(peers)))
 			if pd.Inbound() {
 				inboundCount--
 			}
@@ -23,6 +23,10 @@ import (
 	"github.com/sero-cash/go-sero/common"
 )
 
+// DefaultCoinbaseMaturity is the coinbase maturity of the chains not
+// configuring one.
+const DefaultCoinbaseMaturity = 12
+
 // Genesis hashes to enforce below configs on.
 var (
 	MainnetGenesisHash  = common.HexToHash("0x113d483242270ab0cd4ba353cc04b64a062713a5f05bbe97b8a0548e73218e70")
@@ -48,6 +52,11 @@ var (
 	DevnetChainConfig = &ChainConfig{
 		ChainID:             big.NewInt(1024),
 		AutumnTwilightBlock: big.NewInt(0),
+		TokenInfoBlock:      big.NewInt(0),
+		TicketMetaBlock:     big.NewInt(0),
+		CoinbaseSplitBlock:  big.NewInt(0),
+		ExpiryBlock:         big.NewInt(0),
+		PkgTermsBlock:       big.NewInt(0),
 		Ethash:              new(EthashConfig),
 	}
 
@@ -56,18 +65,23 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), new(EthashConfig)}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, new(EthashConfig)}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, nil}
 
 	TestChainConfig = &ChainConfig{
 		ChainID:             big.NewInt(1),
 		AutumnTwilightBlock: big.NewInt(0),
+		TokenInfoBlock:      big.NewInt(0),
+		TicketMetaBlock:     big.NewInt(0),
+		CoinbaseSplitBlock:  big.NewInt(0),
+		ExpiryBlock:         big.NewInt(0),
+		PkgTermsBlock:       big.NewInt(0),
 		//ConstantinopleBlock: nil,
 		Ethash: new(EthashConfig),
 	}
@@ -82,6 +96,13 @@ type ChainConfig struct {
 	ChainID *big.Int `json:"chainId"` // chainId identifies the current chain and is used for replay protection
 
 	AutumnTwilightBlock *big.Int `json:"AutumnTwilightBlock,omitempty"` // AutumnTwilightBlock switch block (nil = no fork, 0 = already on AutumnTwilightBlock)
+	TokenInfoBlock      *big.Int `json:"TokenInfoBlock,omitempty"`      // TokenInfoBlock switch block enabling the token metadata registry (nil = no fork)
+	TicketMetaBlock     *big.Int `json:"TicketMetaBlock,omitempty"`     // TicketMetaBlock switch block enabling the ticket metadata registry (nil = no fork)
+	CoinbaseSplitBlock  *big.Int `json:"CoinbaseSplitBlock,omitempty"`  // CoinbaseSplitBlock switch block enabling the split of block rewards among several PKrs (nil = no fork)
+	ExpiryBlock         *big.Int `json:"ExpiryBlock,omitempty"`         // ExpiryBlock switch block enabling the valid until block of transactions (nil = no fork)
+	PkgTermsBlock       *big.Int `json:"PkgTermsBlock,omitempty"`       // PkgTermsBlock switch block enabling the expiry and hashlock terms of packages (nil = no fork)
+
+	CoinbaseMaturity uint64 `json:"coinbaseMaturity,omitempty"` // Depth below which block rewards are not spent by the wallet (0 = default)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -115,9 +136,15 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v AutumnTwilight: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v AutumnTwilight: %v TokenInfo: %v TicketMeta: %v CoinbaseSplit: %v Expiry: %v PkgTerms: %v CoinbaseMaturity: %v Engine: %v}",
 		c.ChainID,
 		c.AutumnTwilightBlock,
+		c.TokenInfoBlock,
+		c.TicketMetaBlock,
+		c.CoinbaseSplitBlock,
+		c.ExpiryBlock,
+		c.PkgTermsBlock,
+		c.GetCoinbaseMaturity(),
 		engine,
 	)
 }
@@ -127,6 +154,40 @@ func (c *ChainConfig) IsAutumnTwilight(num *big.Int) bool {
 	return isForked(c.AutumnTwilightBlock, num)
 }
 
+// IsTokenInfo returns whether num is either equal to the TokenInfo fork block or greater.
+func (c *ChainConfig) IsTokenInfo(num *big.Int) bool {
+	return isForked(c.TokenInfoBlock, num)
+}
+
+// IsTicketMeta returns whether num is either equal to the TicketMeta fork block or greater.
+func (c *ChainConfig) IsTicketMeta(num *big.Int) bool {
+	return isForked(c.TicketMetaBlock, num)
+}
+
+// IsCoinbaseSplit returns whether num is either equal to the CoinbaseSplit fork block or greater.
+func (c *ChainConfig) IsCoinbaseSplit(num *big.Int) bool {
+	return isForked(c.CoinbaseSplitBlock, num)
+}
+
+// IsExpiry returns whether num is either equal to the Expiry fork block or greater.
+func (c *ChainConfig) IsExpiry(num *big.Int) bool {
+	return isForked(c.ExpiryBlock, num)
+}
+
+// IsPkgTerms returns whether num is either equal to the PkgTerms fork block or greater.
+func (c *ChainConfig) IsPkgTerms(num *big.Int) bool {
+	return isForked(c.PkgTermsBlock, num)
+}
+
+// GetCoinbaseMaturity returns the number of confirmations a block reward needs
+// before the wallet spends it.
+func (c *ChainConfig) GetCoinbaseMaturity() uint64 {
+	if c.CoinbaseMaturity == 0 {
+		return DefaultCoinbaseMaturity
+	}
+	return c.CoinbaseMaturity
+}
+
 //
 //// IsConstantinople returns whether num is either equal to the Constantinople fork block or greater.
 //func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
@@ -162,6 +223,21 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.AutumnTwilightBlock, newcfg.AutumnTwilightBlock, head) {
 		return newCompatError("AutumnTwilight fork block", c.AutumnTwilightBlock, newcfg.AutumnTwilightBlock)
 	}
+	if isForkIncompatible(c.TokenInfoBlock, newcfg.TokenInfoBlock, head) {
+		return newCompatError("TokenInfo fork block", c.TokenInfoBlock, newcfg.TokenInfoBlock)
+	}
+	if isForkIncompatible(c.TicketMetaBlock, newcfg.TicketMetaBlock, head) {
+		return newCompatError("TicketMeta fork block", c.TicketMetaBlock, newcfg.TicketMetaBlock)
+	}
+	if isForkIncompatible(c.CoinbaseSplitBlock, newcfg.CoinbaseSplitBlock, head) {
+		return newCompatError("CoinbaseSplit fork block", c.CoinbaseSplitBlock, newcfg.CoinbaseSplitBlock)
+	}
+	if isForkIncompatible(c.ExpiryBlock, newcfg.ExpiryBlock, head) {
+		return newCompatError("Expiry fork block", c.ExpiryBlock, newcfg.ExpiryBlock)
+	}
+	if isForkIncompatible(c.PkgTermsBlock, newcfg.PkgTermsBlock, head) {
+		return newCompatError("PkgTerms fork block", c.PkgTermsBlock, newcfg.PkgTermsBlock)
+	}
 	return nil
 }
 
@@ -228,6 +304,8 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainID          *big.Int
 	IsAutumnTwilight bool
+	IsTokenInfo      bool
+	IsTicketMeta     bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -236,5 +314,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainID == nil {
 		chainID = new(big.Int)
 	}
-	return Rules{ChainID: new(big.Int).Set(chainID), IsAutumnTwilight: c.IsAutumnTwilight(num)}
+	return Rules{ChainID: new(big.Int).Set(chainID), IsAutumnTwilight: c.IsAutumnTwilight(num), IsTokenInfo: c.IsTokenInfo(num), IsTicketMeta: c.IsTicketMeta(num)}
 }
@@ -28,6 +28,7 @@ const (
 	GenesisGasLimit      uint64 = 79999998 // Gas limit of the Genesis block.
 
 	MaximumExtraDataSize  uint64 = 32    // Maximum size extra data may be after Genesis.
+	MaxCoinbasePayouts    uint64 = 8     // Maximum number of PKrs a block reward is split among besides the coinbase.
 	ExpByteGas            uint64 = 10    // Times ceil(log256(exponent)) for the EXP instruction.
 	SloadGas              uint64 = 50    // Multiplied by the number of 32-byte words that are copied (round up) for any *COPY operation and added.
 	CallValueTransferGas  uint64 = 9000  // Paid for CALL when the value transfer is non-zero.
@@ -0,0 +1,99 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+)
+
+// Access restricts the methods a server serves and the callers of sensitive
+// modules. The zero value serves all registered methods to everyone.
+type Access struct {
+	// Methods lists the methods served, as module_method or as module_* for
+	// all methods of a module. Empty serves all registered methods. The
+	// rpc_modules discovery method is always served.
+	Methods []string `toml:",omitempty"`
+
+	// AuthModules lists the modules served only to callers sending one of
+	// AuthTokens in an "Authorization: Bearer" header. IPC requests carry no
+	// header, so these modules aren't served over IPC.
+	AuthModules []string `toml:",omitempty"`
+	AuthTokens  []string `toml:",omitempty"`
+}
+
+// accessRules is the lookup form of an Access.
+type accessRules struct {
+	methods     map[string]bool // nil serves all methods
+	authModules map[string]bool
+	authTokens  []string
+}
+
+// SetAccess configures the methods served and the modules requiring an auth
+// token for all requests served afterwards. It must be called before the
+// server starts serving.
+func (s *Server) SetAccess(access Access) {
+	if len(access.Methods) == 0 && len(access.AuthModules) == 0 {
+		s.access = nil
+		return
+	}
+	rules := &accessRules{authModules: make(map[string]bool), authTokens: access.AuthTokens}
+	if len(access.Methods) > 0 {
+		rules.methods = make(map[string]bool)
+		for _, method := range access.Methods {
+			rules.methods[method] = true
+		}
+	}
+	for _, module := range access.AuthModules {
+		rules.authModules[module] = true
+	}
+	s.access = rules
+}
+
+// check returns an error if the method of the given module may not be called
+// in the context of the request.
+func (r *accessRules) check(ctx context.Context, module string, method string) Error {
+	if module == MetadataApi {
+		return nil
+	}
+	if r.methods != nil && !r.methods[module+serviceMethodSeparator+method] && !r.methods[module+serviceMethodSeparator+"*"] {
+		return &methodNotFoundError{module, method}
+	}
+	if r.authModules[module] && !r.authorized(ctx) {
+		return &unauthorizedError{module}
+	}
+	return nil
+}
+
+// authorized reports whether the request carries one of the auth tokens.
+func (r *accessRules) authorized(ctx context.Context) bool {
+	header, _ := ctx.Value("authorization").(string)
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := []byte(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	if len(token) == 0 {
+		return false
+	}
+	for _, allowed := range r.authTokens {
+		if subtle.ConstantTimeCompare(token, []byte(allowed)) == 1 {
+			return true
+		}
+	}
+	return false
+}
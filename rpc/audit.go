@@ -0,0 +1,68 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// AuditEntry is the record of an audited method call.
+type AuditEntry struct {
+	Time   time.Time
+	Caller string // remote address of the client, empty for local transports
+	Method string
+	Params []interface{}
+	Result interface{}
+	Error  error
+}
+
+// Auditor records the calls of the methods it audits. Audit is called once
+// the method returned, from the goroutine serving the request.
+type Auditor interface {
+	Audits(method string) bool
+	Audit(entry *AuditEntry)
+}
+
+// SetAuditor configures the auditor of the calls served afterwards. It must
+// be called before the server starts serving.
+func (s *Server) SetAuditor(auditor Auditor) {
+	s.auditor = auditor
+}
+
+// audit passes the call of req returning reply to the auditor, if it audits
+// the method.
+func (s *Server) audit(ctx context.Context, req *serverRequest, start time.Time, reply []reflect.Value) {
+	method := req.svcname + serviceMethodSeparator + formatName(req.callb.method.Name)
+	if !s.auditor.Audits(method) {
+		return
+	}
+	entry := &AuditEntry{Time: start, Method: method}
+	if remote, ok := ctx.Value("remote").(string); ok {
+		entry.Caller = remote
+	}
+	for _, arg := range req.args {
+		entry.Params = append(entry.Params, arg.Interface())
+	}
+	if req.callb.errPos >= 0 && !reply[req.callb.errPos].IsNil() {
+		entry.Error = reply[req.callb.errPos].Interface().(error)
+	} else if len(reply) > 0 && req.callb.errPos != 0 {
+		entry.Result = reply[0].Interface()
+	}
+	s.auditor.Audit(entry)
+}
@@ -58,6 +58,23 @@ func (e *callbackError) ErrorCode() int { return -32000 }
 
 func (e *callbackError) Error() string { return e.message }
 
+// issued when a request exceeds one of the configured server limits.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// issued when a method of a module requiring an auth token is called without
+// a valid one.
+type unauthorizedError struct{ module string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32006 }
+
+func (e *unauthorizedError) Error() string {
+	return "the methods of " + e.module + " require a valid auth token"
+}
+
 // issued when a request is received after the server is issued to stop.
 type shutdownError struct{}
 
@@ -83,6 +83,9 @@ type jsonCodec struct {
 	encMu  sync.Mutex                // guards the encoder
 	encode func(v interface{}) error // encoder to allow multiple transports
 	rw     io.ReadWriteCloser        // connection
+	stream bool                      // batch responses may be written to rw item by item
+	batch  bool                      // a streamed batch is open on rw
+	queued []interface{}             // messages written while the batch is open
 }
 
 func (err *jsonError) Error() string {
@@ -118,6 +121,7 @@ func NewJSONCodec(rwc io.ReadWriteCloser) ServerCodec {
 		encode: enc.Encode,
 		decode: dec.Decode,
 		rw:     rwc,
+		stream: true,
 	}
 }
 
@@ -355,9 +359,70 @@ func (c *jsonCodec) Write(res interface{}) error {
 	c.encMu.Lock()
 	defer c.encMu.Unlock()
 
+	// Messages can't go into the array of an open batch, they follow it
+	if c.batch {
+		c.queued = append(c.queued, res)
+		return nil
+	}
 	return c.encode(res)
 }
 
+func (c *jsonCodec) streaming() bool {
+	return c.stream
+}
+
+// writeBatchStart opens the array of the responses of a batch. The messages
+// written by Write until writeBatchEnd closes it are queued behind it. It
+// reports false if another batch is open already, the responses of which
+// are then to be written by Write at once.
+func (c *jsonCodec) writeBatchStart() (bool, error) {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	if c.batch {
+		return false, nil
+	}
+	if _, err := c.rw.Write([]byte{'['}); err != nil {
+		return false, err
+	}
+	c.batch = true
+	return true, nil
+}
+
+// writeBatchItem writes the next response of a batch.
+func (c *jsonCodec) writeBatchItem(first bool, item json.RawMessage) error {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	if !first {
+		if _, err := c.rw.Write([]byte{','}); err != nil {
+			return err
+		}
+	}
+	_, err := c.rw.Write(item)
+	return err
+}
+
+// writeBatchEnd closes the array of the responses of a batch and writes the
+// messages queued meanwhile.
+func (c *jsonCodec) writeBatchEnd() error {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	c.batch = false
+	queued := c.queued
+	c.queued = nil
+	if _, err := c.rw.Write([]byte("]\n")); err != nil {
+		return err
+	}
+	for _, res := range queued {
+		if err := c.encode(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close the underlying connection
 func (c *jsonCodec) Close() {
 	c.closer.Do(func() {
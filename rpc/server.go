@@ -18,12 +18,14 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/sero-cash/go-sero/log"
@@ -58,6 +60,69 @@ func NewServer() *Server {
 	return server
 }
 
+// Limits caps the work clients can request from a server. Zero values disable
+// the respective limit.
+type Limits struct {
+	// BatchItems is the maximum number of requests in a single batch.
+	BatchItems int `toml:",omitempty"`
+
+	// BatchResponseSize is the maximum size in bytes of the responses of a
+	// single batch. The requests following the response exceeding it are
+	// answered with an error without being executed.
+	BatchResponseSize int `toml:",omitempty"`
+
+	// ExecutionTime is the maximum total time a single request, or all the
+	// requests of a single batch, may execute for.
+	ExecutionTime time.Duration `toml:",omitempty"`
+
+	// RequestRate is the number of requests per second a client, identified
+	// by its remote host, may send on average, RequestBurst the number it
+	// may send at once. Every request of a batch counts.
+	RequestRate  float64 `toml:",omitempty"`
+	RequestBurst int     `toml:",omitempty"`
+
+	// ConcurrentCalls is the maximum number of calls executing at once on the
+	// server, calls above it are rejected.
+	ConcurrentCalls int `toml:",omitempty"`
+
+	// ConnectionCalls is the maximum number of requests of a single websocket
+	// connection executing at once, further ones are read once one finishes.
+	ConnectionCalls int `toml:",omitempty"`
+
+	// MethodTimes caps the time a single call of the given methods, such as
+	// sero_call or sero_estimateGas, may execute for.
+	MethodTimes map[string]time.Duration `toml:",omitempty"`
+}
+
+// SetLimits configures the limits enforced for all connections served
+// afterwards. It must be called before the server starts serving.
+func (s *Server) SetLimits(limits Limits) {
+	s.limits = limits
+	s.rate, s.calls = nil, nil
+	if limits.RequestRate > 0 {
+		s.rate = newRateLimiter(limits.RequestRate, limits.RequestBurst)
+	}
+	if limits.ConcurrentCalls > 0 {
+		s.calls = make(chan struct{}, limits.ConcurrentCalls)
+	}
+}
+
+// execBudget tracks the execution time a request or a batch has left.
+type execBudget struct {
+	limit time.Duration
+	used  int64 // nanoseconds, accessed atomically
+}
+
+func (b *execBudget) remaining() time.Duration {
+	return b.limit - time.Duration(atomic.LoadInt64(&b.used))
+}
+
+func (b *execBudget) charge(d time.Duration) {
+	atomic.AddInt64(&b.used, int64(d))
+}
+
+type execBudgetKey struct{}
+
 // RPCService gives meta information about the server.
 // e.g. gives information about the loaded modules.
 type RPCService struct {
@@ -155,6 +220,12 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	s.codecs.Add(codec)
 	s.codecsMu.Unlock()
 
+	// bound the requests of the connection executing at once
+	var conncalls chan struct{}
+	if s.limits.ConnectionCalls > 0 && !singleShot {
+		conncalls = make(chan struct{}, s.limits.ConnectionCalls)
+	}
+
 	// test if the server is ordered to stop
 	for atomic.LoadInt32(&s.run) == 1 {
 		reqs, batch, err := s.readRequest(codec)
@@ -184,26 +255,61 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 			}
 			return nil
 		}
+		// Reject batches exceeding the configured limit as a whole
+		if batch && s.limits.BatchItems > 0 && len(reqs) > s.limits.BatchItems {
+			err := &limitExceededError{fmt.Sprintf("batch of %d requests exceeds the limit of %d", len(reqs), s.limits.BatchItems)}
+			codec.Write(codec.CreateErrorResponse(nil, err))
+			if singleShot {
+				return nil
+			}
+			continue
+		}
+		// Reject requests of clients exceeding their request rate
+		if s.rate != nil {
+			if client := clientOf(ctx); client != "" && !s.rate.allow(client, len(reqs)) {
+				err := &limitExceededError{fmt.Sprintf("request rate of %v per second exceeded", s.limits.RequestRate)}
+				if batch {
+					codec.Write(codec.CreateErrorResponse(nil, err))
+				} else {
+					codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
+				}
+				if singleShot {
+					return nil
+				}
+				continue
+			}
+		}
+		// Every request or batch gets its own execution budget
+		reqctx := ctx
+		if s.limits.ExecutionTime > 0 {
+			reqctx = context.WithValue(ctx, execBudgetKey{}, &execBudget{limit: s.limits.ExecutionTime})
+		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
 			if batch {
-				s.execBatch(ctx, codec, reqs)
+				s.execBatch(reqctx, codec, reqs)
 			} else {
-				s.exec(ctx, codec, reqs[0])
+				s.exec(reqctx, codec, reqs[0])
 			}
 			return nil
 		}
 		// For multi-shot connections, start a goroutine to serve and loop back
+		if conncalls != nil {
+			conncalls <- struct{}{}
+		}
 		pend.Add(1)
 
-		go func(reqs []*serverRequest, batch bool) {
+		go func(ctx context.Context, reqs []*serverRequest, batch bool) {
 			defer pend.Done()
+			if conncalls != nil {
+				defer func() { <-conncalls }()
+			}
 			if batch {
 				s.execBatch(ctx, codec, reqs)
 			} else {
 				s.exec(ctx, codec, reqs[0])
 			}
-		}(reqs, batch)
+		}(reqctx, reqs, batch)
 	}
 	return nil
 }
@@ -251,11 +357,45 @@ func (s *Server) createSubscription(ctx context.Context, c ServerCodec, req *ser
 	return reply[0].Interface().(*Subscription).ID, nil
 }
 
-// handle executes a request and returns the response from the callback.
+// handle executes a request and returns the response from the callback. Plain
+// method calls are charged to the execution budget of the request or of its
+// batch, if any.
 func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverRequest) (interface{}, func()) {
 	if req.err != nil {
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
+	if s.access != nil && req.callb != nil {
+		if err := s.access.check(ctx, req.svcname, formatName(req.callb.method.Name)); err != nil {
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+	}
+	if budget, ok := ctx.Value(execBudgetKey{}).(*execBudget); ok && !req.isUnsubscribe && !req.callb.isSubscribe {
+		remaining := budget.remaining()
+		if remaining <= 0 {
+			err := &limitExceededError{fmt.Sprintf("execution time limit of %v exceeded", budget.limit)}
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, remaining)
+		defer cancel()
+		defer func(start time.Time) { budget.charge(time.Since(start)) }(time.Now())
+	}
+	if !req.isUnsubscribe && !req.callb.isSubscribe {
+		if s.calls != nil {
+			select {
+			case s.calls <- struct{}{}:
+				defer func() { <-s.calls }()
+			default:
+				err := &limitExceededError{fmt.Sprintf("limit of %d concurrent calls reached", s.limits.ConcurrentCalls)}
+				return codec.CreateErrorResponse(&req.id, err), nil
+			}
+		}
+		if limit := s.limits.MethodTimes[req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name)]; limit > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, limit)
+			defer cancel()
+		}
+	}
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
@@ -306,7 +446,13 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
+	start := time.Now()
 	reply := req.callb.method.Func.Call(arguments)
+	failed := req.callb.errPos >= 0 && !reply[req.callb.errPos].IsNil()
+	recordCall(req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name), time.Since(start), failed)
+	if s.auditor != nil {
+		s.audit(ctx, req, start, reply)
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -341,23 +487,71 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 	}
 }
 
+// batchStreamer is implemented by codecs able to write the responses of a
+// batch one at a time, so they needn't be held until the last one is ready.
+type batchStreamer interface {
+	streaming() bool
+	writeBatchStart() (bool, error)
+	writeBatchItem(first bool, item json.RawMessage) error
+	writeBatchEnd() error
+}
+
 // execBatch executes the given requests and writes the result back using the codec.
-// It will only write the response back when the last request is processed.
+// Streaming codecs get every response written as soon as it is ready, the others
+// only once the last request is processed.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
-	responses := make([]interface{}, len(requests))
-	var callbacks []func()
+	streamer, stream := codec.(batchStreamer)
+	stream = stream && streamer.streaming()
+	if stream {
+		started, err := streamer.writeBatchStart()
+		if err != nil {
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+			return
+		}
+		stream = started
+	}
+	var (
+		responses []json.RawMessage
+		callbacks []func()
+		size      int
+	)
 	for i, req := range requests {
+		var response interface{}
 		if req.err != nil {
-			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
+			response = codec.CreateErrorResponse(&req.id, req.err)
+		} else if limit := s.limits.BatchResponseSize; limit > 0 && size > limit {
+			err := &limitExceededError{fmt.Sprintf("batch response exceeds the limit of %d bytes", limit)}
+			response = codec.CreateErrorResponse(&req.id, err)
 		} else {
 			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
+			if response, callback = s.handle(ctx, codec, req); callback != nil {
 				callbacks = append(callbacks, callback)
 			}
 		}
+		item, err := json.Marshal(response)
+		if err != nil {
+			item, _ = json.Marshal(codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}))
+		}
+		size += len(item)
+
+		if !stream {
+			responses = append(responses, item)
+		} else if err := streamer.writeBatchItem(i == 0, item); err != nil {
+			streamer.writeBatchEnd()
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+			return
+		}
 	}
 
-	if err := codec.Write(responses); err != nil {
+	var err error
+	if stream {
+		err = streamer.writeBatchEnd()
+	} else {
+		err = codec.Write(responses)
+	}
+	if err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
 		codec.Close()
 	}
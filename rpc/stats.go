@@ -0,0 +1,124 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsWindow is the number of the latest calls of a method its latency
+// percentiles are computed over.
+const statsWindow = 1024
+
+// MethodStats are the statistics of the calls of a method, served by any of
+// the servers of the process.
+type MethodStats struct {
+	Calls  uint64 `json:"calls"`
+	Errors uint64 `json:"errors"`
+
+	// Latencies of the latest calls, in milliseconds
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+type methodCalls struct {
+	calls, errors uint64
+	latencies     []time.Duration // ring of the latest calls
+	next          int
+}
+
+var callStats struct {
+	mu      sync.Mutex
+	since   time.Time
+	methods map[string]*methodCalls
+}
+
+func init() {
+	ResetCallStats()
+}
+
+// recordCall counts a call of method which took elapsed.
+func recordCall(method string, elapsed time.Duration, failed bool) {
+	callStats.mu.Lock()
+	defer callStats.mu.Unlock()
+
+	m := callStats.methods[method]
+	if m == nil {
+		m = &methodCalls{latencies: make([]time.Duration, 0, statsWindow)}
+		callStats.methods[method] = m
+	}
+	m.calls++
+	if failed {
+		m.errors++
+	}
+	if len(m.latencies) < statsWindow {
+		m.latencies = append(m.latencies, elapsed)
+	} else {
+		m.latencies[m.next] = elapsed
+		m.next = (m.next + 1) % statsWindow
+	}
+}
+
+// CallStats returns the statistics of the methods called since the process
+// started or the statistics were reset, and the time they count from.
+func CallStats() (map[string]MethodStats, time.Time) {
+	callStats.mu.Lock()
+	defer callStats.mu.Unlock()
+
+	stats := make(map[string]MethodStats, len(callStats.methods))
+	for method, m := range callStats.methods {
+		latencies := make([]time.Duration, len(m.latencies))
+		copy(latencies, m.latencies)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		stats[method] = MethodStats{
+			Calls:  m.calls,
+			Errors: m.errors,
+			P50:    percentile(latencies, 50),
+			P90:    percentile(latencies, 90),
+			P99:    percentile(latencies, 99),
+			Max:    percentile(latencies, 100),
+		}
+	}
+	return stats, callStats.since
+}
+
+// ResetCallStats drops the statistics collected so far.
+func ResetCallStats() {
+	callStats.mu.Lock()
+	defer callStats.mu.Unlock()
+
+	callStats.since = time.Now()
+	callStats.methods = make(map[string]*methodCalls)
+}
+
+// percentile returns the p-th percentile of the sorted latencies in
+// milliseconds.
+func percentile(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
@@ -70,6 +70,11 @@ type subscriptions map[string]*callback  // collection of subscription callbacks
 // Server represents a RPC server
 type Server struct {
 	services serviceRegistry
+	limits   Limits
+	auditor  Auditor
+	access   *accessRules
+	rate     *rateLimiter
+	calls    chan struct{} // calls executing, if limited
 
 	run      int32
 	codecsMu sync.Mutex
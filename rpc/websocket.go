@@ -68,7 +68,12 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+			ctx := context.WithValue(context.Background(), "remote", conn.Request().RemoteAddr)
+			ctx = context.WithValue(ctx, "apikey", conn.Request().Header.Get("X-Api-Key"))
+			ctx = context.WithValue(ctx, "authorization", conn.Request().Header.Get("Authorization"))
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -0,0 +1,234 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package analytics aggregates statistics of the chain by day, such as the
+// transactions by type and the fees by currency, so dashboards need no
+// indexer of their own for the basic health of the chain.
+package analytics
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// confirmations is the depth blocks are aggregated at, so that the statistics
+// need not be rolled back on reorgs.
+const confirmations = 12
+
+// Transaction types counted by the statistics.
+const (
+	TxTransfer    = "transfer"    // shielded payment
+	TxContract    = "contract"    // transparent payment, calling a contract
+	TxCreate      = "create"      // contract creation
+	TxPkgCreate   = "pkgCreate"   // package creation
+	TxPkgTransfer = "pkgTransfer" // package transfer
+	TxPkgClose    = "pkgClose"    // package close
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// Chain is the part of the blockchain the aggregator reads.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// NewTokensFunc returns the currencies first registered by the contracts
+// called in a block, replaying it.
+type NewTokensFunc func(block *types.Block) ([]string, error)
+
+// Aggregator adds the confirmed blocks of the chain to the daily statistics
+// stored in the database, from the genesis block on.
+type Aggregator struct {
+	chain     Chain
+	db        serodb.Database
+	newTokens NewTokensFunc
+	quit      chan chan struct{}
+}
+
+// New creates the aggregator of the daily statistics.
+func New(chain Chain, db serodb.Database, newTokens NewTokensFunc) *Aggregator {
+	return &Aggregator{
+		chain:     chain,
+		db:        db,
+		newTokens: newTokens,
+		quit:      make(chan chan struct{}),
+	}
+}
+
+// Start starts aggregating the blocks in the background.
+func (a *Aggregator) Start() {
+	go a.loop()
+}
+
+// Stop stops the aggregation.
+func (a *Aggregator) Stop() {
+	done := make(chan struct{})
+	a.quit <- done
+	<-done
+}
+
+func (a *Aggregator) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := a.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		if done := a.catchUp(); done != nil {
+			close(done)
+			return
+		}
+		select {
+		case <-heads:
+		case <-sub.Err():
+			done := <-a.quit
+			close(done)
+			return
+		case done := <-a.quit:
+			close(done)
+			return
+		}
+	}
+}
+
+// catchUp aggregates the blocks confirmed since the last run. It returns the
+// channel of a stop request received meanwhile.
+func (a *Aggregator) catchUp() chan struct{} {
+	head := a.chain.CurrentBlock().NumberU64()
+	for number := rawdb.ReadDailyStatsHead(a.db); number+confirmations <= head; number++ {
+		select {
+		case done := <-a.quit:
+			return done
+		default:
+		}
+		block := a.chain.GetBlockByNumber(number)
+		if block == nil {
+			log.Warn("Daily stats block missing", "number", number)
+			return nil
+		}
+		a.aggregate(block)
+	}
+	return nil
+}
+
+// aggregate adds a block to the statistics of its day.
+func (a *Aggregator) aggregate(block *types.Block) {
+	number := block.NumberU64()
+	day := block.Time().Uint64() / secondsPerDay
+	stats := rawdb.ReadDailyStats(a.db, day)
+	if stats == nil {
+		stats = &rawdb.DailyStats{Day: day, FirstBlock: number}
+	}
+	stats.LastBlock = number
+	stats.Blocks++
+
+	batch := a.db.NewBatch()
+	active := make(map[common.Hash]bool)
+	markActive := func(pkr keys.PKr) {
+		if pkr == (keys.PKr{}) {
+			return
+		}
+		hash := crypto.Keccak256Hash(pkr[:])
+		if !active[hash] && !rawdb.HasDailyPKr(a.db, day, hash) {
+			rawdb.WriteDailyPKr(batch, day, hash)
+			stats.ActivePKrs++
+		}
+		active[hash] = true
+	}
+
+	receipts := a.chain.GetReceiptsByHash(block.Hash())
+	calls := false
+	for i, tx := range block.Transactions() {
+		stxt := tx.Stxt()
+		txType := TxTransfer
+		switch {
+		case stxt.Desc_Pkg.Create != nil:
+			txType = TxPkgCreate
+		case stxt.Desc_Pkg.Transfer != nil:
+			txType = TxPkgTransfer
+		case stxt.Desc_Pkg.Close != nil:
+			txType = TxPkgClose
+		case i < len(receipts) && receipts[i].ContractAddress != (common.Address{}):
+			txType = TxCreate
+		case tx.To() != nil:
+			txType = TxContract
+			calls = true
+		}
+		stats.Txs = addCount(stats.Txs, txType)
+
+		fee := stxt.Fee
+		currency := strings.Trim(string(fee.Currency[:]), string([]byte{0}))
+		stats.Fees = addAmount(stats.Fees, currency, fee.Value.ToIntRef())
+
+		markActive(stxt.From)
+		for _, out := range stxt.Desc_O.Outs {
+			markActive(out.Addr)
+		}
+		for _, out := range stxt.Desc_Z.Outs {
+			markActive(out.PKr)
+		}
+	}
+	if calls {
+		if tokens, err := a.newTokens(block); err != nil {
+			log.Debug("Failed to replay block for new tokens", "number", number, "err", err)
+			stats.Incomplete++
+		} else {
+			stats.NewTokens = append(stats.NewTokens, tokens...)
+		}
+	}
+	rawdb.WriteDailyStats(batch, stats)
+	rawdb.WriteDailyStatsHead(batch, number+1)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to store daily stats", "err", err)
+	}
+}
+
+func addCount(counts []rawdb.TxTypeCount, txType string) []rawdb.TxTypeCount {
+	for i := range counts {
+		if counts[i].Type == txType {
+			counts[i].Count++
+			return counts
+		}
+	}
+	counts = append(counts, rawdb.TxTypeCount{Type: txType, Count: 1})
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Type < counts[j].Type })
+	return counts
+}
+
+func addAmount(amounts []rawdb.CurrencyAmount, currency string, amount *big.Int) []rawdb.CurrencyAmount {
+	for i := range amounts {
+		if amounts[i].Currency == currency {
+			amounts[i].Amount = new(big.Int).Add(amounts[i].Amount, amount)
+			return amounts
+		}
+	}
+	amounts = append(amounts, rawdb.CurrencyAmount{Currency: currency, Amount: new(big.Int).Set(amount)})
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i].Currency < amounts[j].Currency })
+	return amounts
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+)
+
+// maxStatsDays is the number of days sero_getDailyStats returns at most.
+const maxStatsDays = 366
+
+const dateLayout = "2006-01-02"
+
+// DailyStats are the statistics of the confirmed blocks of a day, in UTC.
+type DailyStats struct {
+	Date         string                    `json:"date"`
+	FirstBlock   hexutil.Uint64            `json:"firstBlock"`
+	LastBlock    hexutil.Uint64            `json:"lastBlock"`
+	Blocks       hexutil.Uint64            `json:"blocks"`
+	Transactions map[string]hexutil.Uint64 `json:"transactions"` // by type
+	ActivePKrs   hexutil.Uint64            `json:"activePKrs"`   // distinct PKrs sending or receiving
+	NewTokens    []string                  `json:"newTokens"`
+	Fees         map[string]*hexutil.Big   `json:"fees"` // by currency
+
+	// Incomplete is the number of blocks whose contract calls could not be
+	// replayed, their new tokens are missing
+	Incomplete hexutil.Uint64 `json:"incomplete"`
+}
+
+// PublicAnalyticsAPI serves the daily statistics of the chain.
+type PublicAnalyticsAPI struct {
+	a *Aggregator
+}
+
+// NewPublicAnalyticsAPI creates the API of the daily statistics.
+func NewPublicAnalyticsAPI(a *Aggregator) *PublicAnalyticsAPI {
+	return &PublicAnalyticsAPI{a}
+}
+
+// GetDailyStats returns the statistics of the days from from to to, both
+// included and given as YYYY-MM-DD in UTC. Days without aggregated blocks are
+// left out, the blocks of the last few minutes are not aggregated yet.
+func (api *PublicAnalyticsAPI) GetDailyStats(from string, to string) ([]*DailyStats, error) {
+	first, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date: %v", err)
+	}
+	last, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date: %v", err)
+	}
+	firstDay, lastDay := first.Unix()/secondsPerDay, last.Unix()/secondsPerDay
+	if firstDay < 0 || lastDay < firstDay {
+		return nil, fmt.Errorf("invalid date range %s to %s", from, to)
+	}
+	if lastDay-firstDay >= maxStatsDays {
+		return nil, fmt.Errorf("at most %d days are returned", maxStatsDays)
+	}
+	days := []*DailyStats{}
+	for day := firstDay; day <= lastDay; day++ {
+		if stats := rawdb.ReadDailyStats(api.a.db, uint64(day)); stats != nil {
+			days = append(days, newDailyStats(stats))
+		}
+	}
+	return days, nil
+}
+
+func newDailyStats(stats *rawdb.DailyStats) *DailyStats {
+	result := &DailyStats{
+		Date:         time.Unix(int64(stats.Day)*secondsPerDay, 0).UTC().Format(dateLayout),
+		FirstBlock:   hexutil.Uint64(stats.FirstBlock),
+		LastBlock:    hexutil.Uint64(stats.LastBlock),
+		Blocks:       hexutil.Uint64(stats.Blocks),
+		Transactions: make(map[string]hexutil.Uint64),
+		ActivePKrs:   hexutil.Uint64(stats.ActivePKrs),
+		NewTokens:    stats.NewTokens,
+		Fees:         make(map[string]*hexutil.Big),
+		Incomplete:   hexutil.Uint64(stats.Incomplete),
+	}
+	if result.NewTokens == nil {
+		result.NewTokens = []string{}
+	}
+	for _, count := range stats.Txs {
+		result.Transactions[count.Type] = hexutil.Uint64(count.Count)
+	}
+	for _, fee := range stats.Fees {
+		result.Fees[fee.Currency] = (*hexutil.Big)(fee.Amount)
+	}
+	return result
+}
@@ -24,7 +24,10 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"runtime"
+	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/sero-cash/go-sero/rpc"
 
@@ -40,6 +43,8 @@ import (
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/trie"
+	"github.com/sero-cash/go-sero/zero/txs/generate"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
 )
 
 // PublicSeroAPI provides an API to access Sero full node-related
@@ -76,6 +81,88 @@ func (api *PublicSeroAPI) StopHashrate() {
 	api.e.Miner().StropHashRate()
 }
 
+// NodeFeatures lists the optional functionality enabled on the node.
+type NodeFeatures struct {
+	Pruning          bool            `json:"pruning"`          // state of old blocks is garbage collected
+	BodyPruning      bool            `json:"bodyPruning"`      // bodies of blocks unrelated to local accounts are dropped
+	ReceiptRetention uint64          `json:"receiptRetention"` // number of recent blocks receipts are kept for, 0 if all
+	ZStateRetention  uint64          `json:"zstateRetention"`  // number of recent blocks zstate records are kept for, 0 if all
+	ArchiveTail      *hexutil.Uint64 `json:"archiveTail"`      // oldest block the state is retained for, nil unless in archive mode
+	Snapshots        bool            `json:"snapshots"`        // state snapshots, not supported yet
+	FastSync         bool            `json:"fastSync"`
+	MineMode         bool            `json:"mineMode"` // local outs are not scanned
+	Mining           bool            `json:"mining"`
+}
+
+// NodeCapabilities describes the software and configuration of the node.
+type NodeCapabilities struct {
+	Name        string                `json:"name"`
+	Version     string                `json:"version"`
+	Go          string                `json:"go"`
+	Czero       string                `json:"czero"`
+	NetworkId   uint64                `json:"networkId"`
+	Genesis     common.Hash           `json:"genesis"`
+	SyncMode    string                `json:"syncMode"`
+	Features    NodeFeatures          `json:"features"`
+	Prover      generate.ProverStatus `json:"prover"`
+	ChainConfig *params.ChainConfig   `json:"chainConfig"`
+}
+
+// czeroVersion returns the version of the linked czero library as recorded in
+// the build information, which is only available for module builds.
+func czeroVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/sero-cash/go-czero-import" {
+				if dep.Replace != nil {
+					dep = dep.Replace
+				}
+				if dep.Version != "" {
+					return dep.Version
+				}
+			}
+		}
+	}
+	return "unknown"
+}
+
+// NodeInfo returns the build version, enabled features and chain configuration
+// of the node.
+func (api *PublicSeroAPI) NodeInfo() *NodeCapabilities {
+	config := api.e.config
+	var archiveTail *hexutil.Uint64
+	if tail, ok := rawdb.ReadArchiveTail(api.e.chainDb); ok {
+		archiveTail = (*hexutil.Uint64)(&tail)
+	}
+	return &NodeCapabilities{
+		Name:      api.e.nodeName,
+		Version:   api.e.version,
+		Go:        runtime.Version(),
+		Czero:     czeroVersion(),
+		NetworkId: api.e.NetVersion(),
+		Genesis:   api.e.BlockChain().Genesis().Hash(),
+		SyncMode:  config.SyncMode.String(),
+		Features: NodeFeatures{
+			Pruning:          !config.NoPruning,
+			BodyPruning:      config.BodyPruning,
+			ReceiptRetention: config.ReceiptRetention,
+			ZStateRetention:  config.ZStateRetention,
+			ArchiveTail:      archiveTail,
+			FastSync:         config.SyncMode.SyncsState(),
+			MineMode:         config.MineMode,
+			Mining:           api.e.IsMining(),
+		},
+		Prover:      generate.GetProverStatus(),
+		ChainConfig: api.e.chainConfig,
+	}
+}
+
+// ProvingKeyInfo returns the hashes and the version of the zero-knowledge
+// parameters in use, and whether they match their known-good hashes.
+func (api *PublicSeroAPI) ProvingKeyInfo() generate.ProvingKeyInfo {
+	return generate.GetProvingKeyInfo()
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -303,6 +390,71 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// DumpTxPool writes the pending and queued transactions of the pool into a
+// local file, for LoadTxPool to restore them after a restart or an upgrade.
+// It returns the number of the transactions written.
+func (api *PrivateAdminAPI) DumpTxPool(file string) (int, error) {
+	// Write into a temporary file so a failed dump leaves the previous one
+	out, err := os.OpenFile(file+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file + ".tmp")
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+	}
+	count, err := api.eth.TxPool().Dump(writer)
+	if err == nil {
+		if gz, ok := writer.(*gzip.Writer); ok {
+			err = gz.Close()
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return count, os.Rename(file+".tmp", file)
+}
+
+// TxPoolRestore is the result of a restore of the transaction pool.
+type TxPoolRestore struct {
+	Loaded  int `json:"loaded"`
+	Dropped int `json:"dropped"` // invalid by now, such as included in blocks
+}
+
+// LoadTxPool adds the transactions of a file written by DumpTxPool to the
+// pool.
+func (api *PrivateAdminAPI) LoadTxPool(file string) (*TxPoolRestore, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	loaded, dropped, err := api.eth.TxPool().Restore(reader)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %d: failed to parse: %v", loaded+dropped, err)
+	}
+	return &TxPoolRestore{Loaded: loaded, Dropped: dropped}, nil
+}
+
+// ReloadProvingKeys verifies the zero-knowledge parameter files, after they
+// were replaced, and loads them without restarting the node. The parameters
+// in use are kept if the files do not match their known-good hashes.
+func (api *PrivateAdminAPI) ReloadProvingKeys() (generate.ProvingKeyInfo, error) {
+	return generate.ReloadParams()
+}
+
 // PublicDebugAPI is the collection of Sero full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -361,23 +513,101 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return nil, errors.New("unknown preimage")
 }
 
+// TrieStatsResult is the result of a debug_trieStats call.
+type TrieStatsResult struct {
+	Number      uint64                  `json:"number"`
+	Root        common.Hash             `json:"root"`
+	Accounts    *trie.Stats             `json:"accounts"`
+	ZeroState   *trie.Stats             `json:"zeroState"`
+	Commitments txstate.MerkleTreeStats `json:"commitments"`
+}
+
+// TrieStats walks the account trie and the storage trie holding the zero state
+// at the given block and reports their node counts, depth distribution and
+// size, together with the fill level of the zero commitment tree.
+func (api *PrivateDebugAPI) TrieStats(blockNr rpc.BlockNumber) (*TrieStatsResult, error) {
+	var block *types.Block
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		return nil, errors.New("trie statistics are not available for the pending block")
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	statedb, err := api.eth.BlockChain().StateAt(block.Root(), block.NumberU64())
+	if err != nil {
+		return nil, err
+	}
+	triedb := statedb.Database().TrieDB()
+
+	result := &TrieStatsResult{
+		Number:      block.NumberU64(),
+		Root:        block.Root(),
+		Commitments: statedb.GetZState().State.MTree.Stats(),
+	}
+	accounts, err := trie.New(block.Root(), triedb)
+	if err != nil {
+		return nil, err
+	}
+	if result.Accounts, err = accounts.Stats(); err != nil {
+		return nil, err
+	}
+	if storage := statedb.StorageTrie(state.EmptyAddress); storage != nil {
+		zero, err := trie.New(storage.Hash(), triedb)
+		if err != nil {
+			return nil, err
+		}
+		if result.ZeroState, err = zero.Stats(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash  common.Hash            `json:"hash"`
-	Block map[string]interface{} `json:"block"`
-	RLP   string                 `json:"rlp"`
+	Hash        common.Hash            `json:"hash"`
+	Error       string                 `json:"error"`
+	Time        time.Time              `json:"time"`
+	Transaction *BadTxArgs             `json:"transaction"` // nil unless a transaction was rejected
+	Block       map[string]interface{} `json:"block"`
+	RLP         string                 `json:"rlp"`
+}
+
+// BadTxArgs is the transaction a bad block was rejected for, with the error of
+// the verification of its proofs and balance or of its execution.
+type BadTxArgs struct {
+	Index hexutil.Uint `json:"index"`
+	Hash  common.Hash  `json:"hash"`
+	Error string       `json:"error"`
 }
 
 // GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
 // and returns them as a JSON list of block-hashes
 func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error) {
-	blocks := api.eth.BlockChain().BadBlocks()
-	results := make([]*BadBlockArgs, len(blocks))
+	reports := api.eth.BlockChain().BadBlockReports()
+	results := make([]*BadBlockArgs, len(reports))
 
 	var err error
-	for i, block := range blocks {
+	for i, report := range reports {
+		block := report.Block
 		results[i] = &BadBlockArgs{
 			Hash: block.Hash(),
+			Time: report.Time,
+		}
+		if report.Err != nil {
+			results[i].Error = report.Err.Error()
+		}
+		if txErr, ok := report.Err.(*core.InvalidTxError); ok {
+			results[i].Transaction = &BadTxArgs{
+				Index: hexutil.Uint(txErr.Index),
+				Hash:  txErr.Hash,
+				Error: txErr.Err.Error(),
+			}
 		}
 		if rlpBytes, err := rlp.EncodeToBytes(block); err != nil {
 			results[i].RLP = err.Error() // Hacky, but hey, it works
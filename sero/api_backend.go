@@ -18,6 +18,7 @@ package sero
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 
 	"github.com/sero-cash/go-sero/consensus"
@@ -32,6 +33,7 @@ import (
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/core/vm"
 	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/internal/ethapi"
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rpc"
 	"github.com/sero-cash/go-sero/sero/downloader"
@@ -109,7 +111,21 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 		return nil, nil, err
 	}
 	stateDb, err := b.sero.BlockChain().StateAt(header.Root, header.Number.Uint64())
-	return stateDb, header, err
+	if err != nil {
+		return nil, nil, b.statePruned(header.Number.Uint64(), err)
+	}
+	return stateDb, header, nil
+}
+
+// statePruned explains the missing state of a block.
+func (b *EthAPIBackend) statePruned(number uint64, err error) error {
+	if tail, ok := rawdb.ReadArchiveTail(b.sero.chainDb); ok {
+		if number < tail {
+			return fmt.Errorf("state of block %d has been pruned, the archive retains the state from block %d", number, tail)
+		}
+		return fmt.Errorf("state of block %d missing from the archive: %v", number, err)
+	}
+	return fmt.Errorf("state of block %d has been pruned, historic state is only retained in archive mode (--gcmode archive)", number)
 }
 
 func (b *EthAPIBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
@@ -199,6 +215,10 @@ func (b *EthAPIBackend) TxPoolContent() (types.Transactions, types.Transactions)
 	return b.sero.TxPool().Content()
 }
 
+func (b *EthAPIBackend) PriceBump() uint64 {
+	return b.sero.TxPool().PriceBump()
+}
+
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.sero.TxPool().SubscribeNewTxsEvent(ch)
 }
@@ -215,6 +235,10 @@ func (b *EthAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthAPIBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*ethapi.FeeHistory, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *EthAPIBackend) ChainDb() serodb.Database {
 	return b.sero.ChainDb()
 }
@@ -227,6 +251,50 @@ func (b *EthAPIBackend) AccountManager() *accounts.Manager {
 	return b.sero.AccountManager()
 }
 
+func (b *EthAPIBackend) RPCGasCap() uint64 {
+	return b.sero.config.RPCGasCap
+}
+
+func (b *EthAPIBackend) RPCEstimateGasCap() uint64 {
+	return b.sero.config.RPCEstimateGasCap
+}
+
+func (b *EthAPIBackend) TxDefaults() ethapi.TxDefaults {
+	return b.sero.config.TxDefaults
+}
+
+func (b *EthAPIBackend) RPCCallCache() int {
+	return b.sero.config.RPCCallCache
+}
+
+func (b *EthAPIBackend) SpendPolicy() ethapi.SpendPolicy {
+	return b.sero.config.SpendPolicy
+}
+
+func (b *EthAPIBackend) ProofQueue() ethapi.ProofQueue {
+	return b.sero.config.ProofQueue
+}
+
+func (b *EthAPIBackend) Tenants() ethapi.Tenants {
+	return b.sero.config.Tenants
+}
+
+func (b *EthAPIBackend) Merge() ethapi.MergeConfig {
+	return b.sero.config.Merge
+}
+
+func (b *EthAPIBackend) RelayTargets() []string {
+	return b.sero.config.RelayTargets
+}
+
+func (b *EthAPIBackend) GenesisSupply() *big.Int {
+	return new(big.Int).Set(b.sero.genesisSupply)
+}
+
+func (b *EthAPIBackend) SupplyIndex() *ethapi.SupplyIndex {
+	return b.sero.supplyIndex
+}
+
 func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.sero.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
@@ -0,0 +1,238 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sero
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/trie"
+)
+
+// maxHeaderProofs is the number of headers GetHeaderProofs returns at most.
+const maxHeaderProofs = 192
+
+// PublicBridgeAPI serves the primitives cross-chain bridges verify the events
+// of the chain with: the headers, the proofs of the receipts included in
+// them and events attested by the node key.
+type PublicBridgeAPI struct {
+	e *Sero
+}
+
+// NewPublicBridgeAPI creates the bridge API of a full node.
+func NewPublicBridgeAPI(e *Sero) *PublicBridgeAPI {
+	return &PublicBridgeAPI{e}
+}
+
+// HeaderProof is a canonical header in its consensus encoding, which hashes
+// to Hash and links to its parent by ParentHash.
+type HeaderProof struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Header     hexutil.Bytes  `json:"header"`
+}
+
+// GetHeaderProofs returns count consecutive canonical headers starting at
+// from, for a relay to extend the chain it trusts on the other side. Counts
+// over 192 are truncated, the list stops at the head.
+func (api *PublicBridgeAPI) GetHeaderProofs(from hexutil.Uint64, count hexutil.Uint64) ([]*HeaderProof, error) {
+	if count == 0 || count > maxHeaderProofs {
+		count = maxHeaderProofs
+	}
+	proofs := []*HeaderProof{}
+	for number := uint64(from); number < uint64(from)+uint64(count); number++ {
+		header := api.e.blockchain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		enc, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, &HeaderProof{
+			Number:     hexutil.Uint64(number),
+			Hash:       header.Hash(),
+			ParentHash: header.ParentHash,
+			Header:     enc,
+		})
+	}
+	return proofs, nil
+}
+
+// ReceiptProof proves the inclusion of a receipt in the receipt trie of a
+// block, whose root is the ReceiptHash of its header.
+type ReceiptProof struct {
+	BlockHash    common.Hash     `json:"blockHash"`
+	BlockNumber  hexutil.Uint64  `json:"blockNumber"`
+	ReceiptsRoot common.Hash     `json:"receiptsRoot"`
+	Index        hexutil.Uint64  `json:"index"`
+	Key          hexutil.Bytes   `json:"key"`     // RLP encoding of the index
+	Receipt      hexutil.Bytes   `json:"receipt"` // consensus encoding of the receipt
+	Proof        []hexutil.Bytes `json:"proof"`   // trie nodes from the root to the receipt
+}
+
+// proofList collects the nodes of a trie proof in order.
+type proofList []hexutil.Bytes
+
+func (l *proofList) Put(key []byte, value []byte) error {
+	*l = append(*l, common.CopyBytes(value))
+	return nil
+}
+
+// lookupReceipt returns the block, the receipts and the index of a mined
+// transaction of the canonical chain.
+func (api *PublicBridgeAPI) lookupReceipt(hash common.Hash) (*types.Header, types.Receipts, uint64, error) {
+	blockHash, number, index := rawdb.ReadTxLookupEntry(api.e.chainDb, hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil, 0, fmt.Errorf("transaction %x not found", hash)
+	}
+	header := api.e.blockchain.GetHeaderByNumber(number)
+	if header == nil || header.Hash() != blockHash {
+		return nil, nil, 0, fmt.Errorf("transaction %x is not canonical", hash)
+	}
+	receipts := api.e.blockchain.GetReceiptsByHash(blockHash)
+	if uint64(len(receipts)) <= index {
+		return nil, nil, 0, fmt.Errorf("receipt of transaction %x not found", hash)
+	}
+	return header, receipts, index, nil
+}
+
+// GetReceiptProof returns the proof of inclusion of the receipt of a
+// transaction in its canonical block.
+func (api *PublicBridgeAPI) GetReceiptProof(hash common.Hash) (*ReceiptProof, error) {
+	header, receipts, index, err := api.lookupReceipt(hash)
+	if err != nil {
+		return nil, err
+	}
+	tr := new(trie.Trie)
+	for i := 0; i < receipts.Len(); i++ {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		tr.Update(key, receipts.GetRlp(i))
+	}
+	if tr.Hash() != header.ReceiptHash {
+		return nil, errors.New("stored receipts do not match the receipt root")
+	}
+	key, _ := rlp.EncodeToBytes(uint(index))
+	var proof proofList
+	if err := tr.Prove(key, 0, &proof); err != nil {
+		return nil, err
+	}
+	return &ReceiptProof{
+		BlockHash:    header.Hash(),
+		BlockNumber:  hexutil.Uint64(header.Number.Uint64()),
+		ReceiptsRoot: header.ReceiptHash,
+		Index:        hexutil.Uint64(index),
+		Key:          key,
+		Receipt:      receipts.GetRlp(int(index)),
+		Proof:        proof,
+	}, nil
+}
+
+// attestedEvent is the signed content of an event attestation.
+type attestedEvent struct {
+	ChainId     *big.Int
+	BlockHash   common.Hash
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint64
+	Address     common.Address
+	Topics      []common.Hash
+	Data        []byte
+}
+
+// EventAttestation is an event of the canonical chain signed by the node key.
+// Digest is the Keccak-256 hash of the RLP list of the chain id, the block
+// hash and number, the transaction hash, the log index, the address, the
+// topics and the data.
+type EventAttestation struct {
+	ChainId       *hexutil.Big   `json:"chainId"`
+	BlockHash     common.Hash    `json:"blockHash"`
+	BlockNumber   hexutil.Uint64 `json:"blockNumber"`
+	TxHash        common.Hash    `json:"transactionHash"`
+	LogIndex      hexutil.Uint64 `json:"logIndex"` // index of the log in the receipt
+	Address       common.Address `json:"address"`
+	Topics        []common.Hash  `json:"topics"`
+	Data          hexutil.Bytes  `json:"data"`
+	Confirmations hexutil.Uint64 `json:"confirmations"` // not signed
+	Digest        common.Hash    `json:"digest"`
+	Signature     hexutil.Bytes  `json:"signature"` // [R || S || V] over Digest
+	Signer        hexutil.Bytes  `json:"signer"`    // uncompressed public node key
+}
+
+// AttestEvent signs a log of a transaction of the canonical chain with the
+// node key, so bridges can require the attestations of several independent
+// nodes instead of trusting a single RPC response.
+func (api *PublicBridgeAPI) AttestEvent(hash common.Hash, logIndex hexutil.Uint64) (*EventAttestation, error) {
+	key := api.e.nodeKey
+	if key == nil {
+		return nil, errors.New("node key not available")
+	}
+	header, receipts, index, err := api.lookupReceipt(hash)
+	if err != nil {
+		return nil, err
+	}
+	logs := receipts[index].Logs
+	if uint64(logIndex) >= uint64(len(logs)) {
+		return nil, fmt.Errorf("transaction %x has %d logs", hash, len(logs))
+	}
+	event := logs[logIndex]
+	content := attestedEvent{
+		ChainId:     api.e.chainConfig.ChainID,
+		BlockHash:   header.Hash(),
+		BlockNumber: header.Number.Uint64(),
+		TxHash:      hash,
+		LogIndex:    uint64(logIndex),
+		Address:     event.Address,
+		Topics:      event.Topics,
+		Data:        event.Data,
+	}
+	enc, err := rlp.EncodeToBytes(&content)
+	if err != nil {
+		return nil, err
+	}
+	digest := crypto.Keccak256Hash(enc)
+	sig, err := crypto.Sign(digest[:], key)
+	if err != nil {
+		return nil, err
+	}
+	topics := event.Topics
+	if topics == nil {
+		topics = []common.Hash{}
+	}
+	return &EventAttestation{
+		ChainId:       (*hexutil.Big)(content.ChainId),
+		BlockHash:     content.BlockHash,
+		BlockNumber:   hexutil.Uint64(content.BlockNumber),
+		TxHash:        hash,
+		LogIndex:      logIndex,
+		Address:       event.Address,
+		Topics:        topics,
+		Data:          event.Data,
+		Confirmations: hexutil.Uint64(api.e.blockchain.CurrentHeader().Number.Uint64() - content.BlockNumber),
+		Digest:        digest,
+		Signature:     sig,
+		Signer:        crypto.FromECDSAPub(&key.PublicKey),
+	}, nil
+}
@@ -651,3 +651,47 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 	}
 	return nil, vm.Context{}, nil, fmt.Errorf("tx index %d out of range for block %x", txIndex, blockHash)
 }
+
+// newTokens replays a block, returning the currencies first registered by the
+// contracts it calls.
+func (api *PrivateDebugAPI) newTokens(block *types.Block) ([]string, error) {
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	statedb, err := api.computeStateDB(parent, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	before := statedb.Copy()
+
+	var issued []string
+	for i, tx := range block.Transactions() {
+		msg, _ := tx.AsMessage()
+		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+		vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{})
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+			return nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
+		}
+		for _, transfer := range statedb.GetTokenTransfers(tx.Hash()) {
+			if transfer.To == nil {
+				issued = append(issued, transfer.Currency)
+			}
+		}
+		statedb.Finalise(true)
+	}
+	// Issuances of currencies registered before leave no new registration
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, currency := range issued {
+		if seen[currency] {
+			continue
+		}
+		seen[currency] = true
+		if before.GetContrctAddressByToken(currency) == (common.Address{}) && statedb.GetContrctAddressByToken(currency) != (common.Address{}) {
+			tokens = append(tokens, currency)
+		}
+	}
+	return tokens, nil
+}
@@ -19,9 +19,11 @@ package sero
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -29,12 +31,14 @@ import (
 	"github.com/sero-cash/go-czero-import/keys"
 
 	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/accounts/keystore"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/hexutil"
 	"github.com/sero-cash/go-sero/consensus"
 	"github.com/sero-cash/go-sero/consensus/ethash"
 	"github.com/sero-cash/go-sero/core"
 	"github.com/sero-cash/go-sero/core/bloombits"
+	"github.com/sero-cash/go-sero/core/hooks"
 	"github.com/sero-cash/go-sero/core/rawdb"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/core/vm"
@@ -47,10 +51,14 @@ import (
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/sero/analytics"
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/filters"
 	"github.com/sero-cash/go-sero/sero/gasprice"
+	"github.com/sero-cash/go-sero/sero/replica"
+	"github.com/sero-cash/go-sero/sero/stratum"
 	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
 )
 
 type LesServer interface {
@@ -65,6 +73,11 @@ type Sero struct {
 	config      *Config
 	chainConfig *params.ChainConfig
 
+	genesisSupply *big.Int // SERO in existence after the genesis block
+
+	nodeName string // Client name of the node, reported by sero_nodeInfo
+	version  string // Version of the node software, reported by sero_nodeInfo
+
 	// Channel for shutting down the service
 	shutdownChan chan bool // Channel for shutting down the Sero
 
@@ -73,6 +86,17 @@ type Sero struct {
 	blockchain      *core.BlockChain
 	protocolManager *ProtocolManager
 	lesServer       LesServer
+	replica         *replica.Replica
+	txPoolAPI       *ethapi.PublicTransactionPoolAPI // shared by the RPC clients and the node's senders
+	scheduler       *ethapi.Scheduler
+	merger          *ethapi.Merger
+	pkrWatcher      *ethapi.PKrWatcher
+	supplyIndex     *ethapi.SupplyIndex
+	analytics       *analytics.Aggregator // nil unless daily stats are enabled
+	memoIndex       *ethapi.MemoIndex     // nil unless the memo index is enabled
+	stratum         *stratum.Server       // nil unless the stratum server is enabled
+	coldMigrator    *coldMigrator         // nil unless a cold chain database is used
+	eraImporter     *eraImporter          // nil unless an era archive directory is set
 
 	// DB interfaces
 	chainDb serodb.Database // Block chain database
@@ -92,6 +116,7 @@ type Sero struct {
 
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
+	nodeKey       *ecdsa.PrivateKey // signs the event attestations, set on start
 
 	lock sync.RWMutex // Protects the variadic fields (s.g. gas price and serobase)
 }
@@ -105,7 +130,7 @@ func (s *Sero) AddLesServer(ls LesServer) {
 // initialisation of the common Sero object)
 func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 	if config.SyncMode == downloader.LightSync {
-		return nil, errors.New("can't run sero.Sero in light sync mode, use les.LightEthereum")
+		return nil, errors.New("can't run sero.Sero in light sync mode, use les.LightSero")
 	}
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
@@ -133,10 +158,27 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 		serobase:       config.Serobase,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+		nodeName:       ctx.NodeName(),
+		version:        ctx.Version(),
 	}
 
 	log.Info("Initialising Sero protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
+	if err := registerTenants(sero.accountManager, config.Tenants); err != nil {
+		return nil, err
+	}
+
+	genesis := config.Genesis
+	if genesis == nil {
+		genesis = core.GenesisByHash(genesisHash)
+	}
+	if genesis != nil {
+		sero.genesisSupply = genesis.Supply()
+	} else {
+		log.Warn("Unknown genesis allocations, supply reports exclude them", "genesis", genesisHash)
+		sero.genesisSupply = new(big.Int).Set(core.GenesisRewardPool)
+	}
+
 	if !config.SkipBcVersionCheck {
 		bcVersion := rawdb.ReadDatabaseVersion(chainDb)
 		if bcVersion != core.BlockChainVersion && bcVersion != 0 {
@@ -146,8 +188,15 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, ReceiptLimit: config.ReceiptRetention, BodyPruning: config.BodyPruning, ZStateLimit: config.ZStateRetention}
 	)
+	lstate.SetScanWorkers(config.LstateScanWorkers)
+	lstate.SetPruneSpent(config.ZStateRetention > 0 && !config.NoPruning)
+	for _, path := range config.BlockHooks {
+		if err := hooks.Load(path); err != nil {
+			return nil, err
+		}
+	}
 	sero.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, sero.chainConfig, sero.engine, vmConfig, sero.accountManager, config.MineMode)
 
 	if err != nil {
@@ -159,6 +208,25 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 		sero.blockchain.SetHead(compat.RewindTo, core.DelFn)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	// Track the oldest block whose state the archive mode retains
+	if config.NoPruning {
+		if _, ok := rawdb.ReadArchiveTail(chainDb); !ok {
+			tail := sero.blockchain.CurrentBlock().NumberU64()
+			if tail > 0 {
+				log.Warn("Archive mode enabled on a pruned database, older state is missing", "tail", tail)
+			}
+			rawdb.WriteArchiveTail(chainDb, tail)
+		}
+	} else if _, ok := rawdb.ReadArchiveTail(chainDb); ok {
+		log.Warn("Archive mode disabled, historic state is pruned from now on")
+		rawdb.DeleteArchiveTail(chainDb)
+	}
+	if db, ok := chainDb.(*serodb.SplitDatabase); ok {
+		sero.coldMigrator = newColdMigrator(db, sero.blockchain, config.HotBlocks)
+	}
+	if config.EraDir != "" {
+		sero.eraImporter = newEraImporter(config.EraDir, sero.blockchain, chainDb)
+	}
 	sero.bloomIndexer.Start(sero.blockchain)
 
 	//if config.TxPool.Journal != "" {
@@ -171,6 +239,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 	}
 	sero.miner = miner.New(sero, sero.chainConfig, sero.EventMux(), sero.engine)
 	sero.miner.SetExtra(makeExtraData(config.ExtraData))
+	if err := sero.miner.SetPayouts(config.MinerPayouts); err != nil {
+		return nil, err
+	}
 
 	sero.APIBackend = &EthAPIBackend{sero, nil}
 	gpoParams := config.GPO
@@ -178,10 +249,55 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 		gpoParams.Default = config.GasPrice
 	}
 	sero.APIBackend.gpo = gasprice.NewOracle(sero.APIBackend, gpoParams)
+	sero.txPoolAPI = ethapi.NewSharedTransactionPoolAPI(sero.APIBackend)
+	sero.scheduler = ethapi.NewScheduler(sero.APIBackend, sero.txPoolAPI)
+	sero.merger = ethapi.NewMerger(sero.APIBackend, sero.txPoolAPI)
+	sero.pkrWatcher = ethapi.NewPKrWatcher(sero.APIBackend, chainDb)
+	sero.supplyIndex = ethapi.NewSupplyIndex(sero.APIBackend, chainDb)
+	if config.DailyStats {
+		sero.analytics = analytics.New(sero.blockchain, chainDb, NewPrivateDebugAPI(sero.chainConfig, sero).newTokens)
+	}
+	if config.MemoIndex {
+		sero.memoIndex = ethapi.NewMemoIndex(sero.APIBackend, chainDb)
+	}
+	if config.Stratum.Enabled() {
+		engine, ok := sero.engine.(*ethash.Ethash)
+		if !ok {
+			return nil, fmt.Errorf("stratum server requires ethash, not %T", sero.engine)
+		}
+		sero.stratum = stratum.New(config.Stratum, sero, engine)
+	}
 
+	if config.Replica.Enabled() && !config.MineMode {
+		if sero.replica, err = replica.New(config.Replica, sero.accountManager); err != nil {
+			return nil, err
+		}
+	}
 	return sero, nil
 }
 
+// registerTenants adds the key stores of the tenants to the account manager,
+// so their accounts are scanned and usable like the ones of the node.
+func registerTenants(am *accounts.Manager, tenants ethapi.Tenants) error {
+	if err := tenants.Validate(); err != nil {
+		return err
+	}
+	backends := am.Backends(keystore.KeyStoreType)
+	if len(tenants) == 0 || len(backends) == 0 {
+		return nil
+	}
+	ks := backends[0].(*keystore.KeyStore)
+	for name := range tenants {
+		scope, err := ks.Scope(name)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %v", name, err)
+		}
+		am.AddBackend(scope)
+		log.Info("Registered tenant key store", "tenant", name, "accounts", len(scope.Accounts()))
+	}
+	return nil
+}
+
 func makeExtraData(extra []byte) []byte {
 	if len(extra) == 0 {
 		// create default extradata
@@ -205,7 +321,18 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (serodb.Dat
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*serodb.LDBDatabase); ok {
+	if hot, ok := db.(*serodb.LDBDatabase); ok && config.ColdDataDir != "" {
+		path := ctx.ResolvePath(filepath.Join(config.ColdDataDir, name))
+		if db, err = OpenColdDatabase(hot, path, config.DatabaseCache, config.DatabaseHandles, config.ColdTables); err != nil {
+			hot.Close()
+			return nil, err
+		}
+		log.Info("Opened cold chain database", "path", path, "tables", config.ColdTables, "hot", config.HotBlocks)
+	}
+	switch db := db.(type) {
+	case *serodb.LDBDatabase:
+		db.Meter("sero/db/chaindata/")
+	case *serodb.SplitDatabase:
 		db.Meter("sero/db/chaindata/")
 	}
 	return db, nil
@@ -241,11 +368,40 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *ethash.Config, chai
 // APIs return the collection of RPC services the ethereum package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *Sero) APIs() []rpc.API {
-	apis := ethapi.GetAPIs(s.APIBackend)
+	apis := ethapi.GetAPIs(s.APIBackend, s.txPoolAPI, s.scheduler)
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	if s.analytics != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "sero",
+			Version:   "1.0",
+			Service:   analytics.NewPublicAnalyticsAPI(s.analytics),
+			Public:    true,
+		})
+	}
+	apis = append(apis, rpc.API{
+		Namespace: "sero",
+		Version:   "1.0",
+		Service:   ethapi.NewPublicPKrWatchAPI(s.pkrWatcher),
+		Public:    true,
+	})
+	if s.memoIndex != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "memoindex",
+			Version:   "1.0",
+			Service:   ethapi.NewPrivateMemoIndexAPI(s.APIBackend, s.memoIndex),
+		})
+	}
+	if s.stratum != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "miner",
+			Version:   "1.0",
+			Service:   stratum.NewPrivateStratumAPI(s.stratum),
+		})
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -253,6 +409,11 @@ func (s *Sero) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicSeroAPI(s),
 			Public:    true,
+		}, {
+			Namespace: "sero",
+			Version:   "1.0",
+			Service:   NewPublicBridgeAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "sero",
 			Version:   "1.0",
@@ -376,6 +537,10 @@ func (s *Sero) EthVersion() int                    { return int(s.protocolManage
 func (s *Sero) NetVersion() uint64                 { return s.networkID }
 func (s *Sero) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// TxPoolAPI returns the transaction pool API shared by the RPC clients and the
+// node's senders, for gateways sending on their behalf.
+func (s *Sero) TxPoolAPI() *ethapi.PublicTransactionPoolAPI { return s.txPoolAPI }
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Sero) Protocols() []p2p.Protocol {
@@ -393,6 +558,7 @@ func (s *Sero) Start(srvr *p2p.Server) error {
 
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
+	s.nodeKey = srvr.PrivateKey
 
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
@@ -407,12 +573,58 @@ func (s *Sero) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.replica != nil {
+		if err := s.replica.Start(); err != nil {
+			return err
+		}
+	}
+	s.scheduler.Start()
+	s.merger.Start()
+	s.supplyIndex.Start()
+	if s.analytics != nil {
+		s.analytics.Start()
+	}
+	if s.memoIndex != nil {
+		s.memoIndex.Start()
+	}
+	if s.coldMigrator != nil {
+		s.coldMigrator.Start()
+	}
+	if s.eraImporter != nil {
+		s.eraImporter.Start()
+	}
+	if s.stratum != nil {
+		if err := s.stratum.Open(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Sero protocol.
 func (s *Sero) Stop() error {
+	s.scheduler.Stop()
+	s.merger.Stop()
+	s.supplyIndex.Stop()
+	if s.analytics != nil {
+		s.analytics.Stop()
+	}
+	if s.memoIndex != nil {
+		s.memoIndex.Stop()
+	}
+	if s.coldMigrator != nil {
+		s.coldMigrator.Stop()
+	}
+	if s.eraImporter != nil {
+		s.eraImporter.Stop()
+	}
+	if s.stratum != nil {
+		s.stratum.Close()
+	}
+	if s.replica != nil {
+		s.replica.Stop()
+	}
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
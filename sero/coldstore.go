@@ -0,0 +1,136 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sero
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+const (
+	// coldMigrateInterval is the interval of the moves of the blocks falling
+	// out of the hot window to the cold database.
+	coldMigrateInterval = time.Minute
+
+	// coldMigrateBatch is the number of keys moved between the databases at
+	// once.
+	coldMigrateBatch = 1024
+)
+
+// OpenColdDatabase opens the cold chain database at path, returning the
+// database splitting the chain data between it and hot. The cold database is
+// given a quarter of the cache and handles.
+func OpenColdDatabase(hot *serodb.LDBDatabase, path string, cache int, handles int, tables []string) (*serodb.SplitDatabase, error) {
+	cold, err := serodb.NewLDBDatabase(path, cache/4, handles/4)
+	if err != nil {
+		return nil, err
+	}
+	db, err := serodb.NewSplitDatabase(hot, cold, rawdb.ColdTables, tables)
+	if err != nil {
+		cold.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// coldMigrator moves the tables of the blocks falling out of the hot window
+// to the cold database, and the tables no longer kept cold back, while the
+// node runs.
+type coldMigrator struct {
+	db    *serodb.SplitDatabase
+	chain *core.BlockChain
+	hot   uint64 // number of recent blocks kept in the hot database
+	quit  chan chan struct{}
+}
+
+func newColdMigrator(db *serodb.SplitDatabase, chain *core.BlockChain, hot uint64) *coldMigrator {
+	return &coldMigrator{
+		db:    db,
+		chain: chain,
+		hot:   hot,
+		quit:  make(chan chan struct{}),
+	}
+}
+
+// Start starts the migration.
+func (m *coldMigrator) Start() {
+	go m.loop()
+}
+
+// Stop stops the migration, leaving the keys moved so far in place.
+func (m *coldMigrator) Stop() {
+	done := make(chan struct{})
+	m.quit <- done
+	<-done
+}
+
+func (m *coldMigrator) loop() {
+	ticker := time.NewTicker(coldMigrateInterval)
+	defer ticker.Stop()
+
+	for {
+		if done := m.migrate(); done != nil {
+			close(done)
+			return
+		}
+		select {
+		case <-ticker.C:
+		case done := <-m.quit:
+			close(done)
+			return
+		}
+	}
+}
+
+// migrate moves the keys of the blocks out of the hot window. It returns the
+// channel of a stop request received meanwhile.
+func (m *coldMigrator) migrate() chan struct{} {
+	var limit uint64
+	if head := m.chain.CurrentBlock().NumberU64(); head > m.hot {
+		limit = head - m.hot
+	}
+	var (
+		start = time.Now()
+		total int
+	)
+	defer func() {
+		if total > 0 {
+			log.Info("Migrated cold chain data", "limit", limit, "keys", total, "elapsed", common.PrettyDuration(time.Since(start)))
+		}
+	}()
+	for {
+		select {
+		case done := <-m.quit:
+			return done
+		default:
+		}
+		moved, err := m.db.Migrate(limit, coldMigrateBatch)
+		if err != nil {
+			log.Error("Failed to migrate cold chain data", "err", err)
+			return nil
+		}
+		if moved == 0 {
+			return nil
+		}
+		total += moved
+	}
+}
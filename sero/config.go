@@ -28,9 +28,14 @@ import (
 	"github.com/sero-cash/go-sero/common/hexutil"
 	"github.com/sero-cash/go-sero/consensus/ethash"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/miner"
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/gasprice"
+	"github.com/sero-cash/go-sero/sero/replica"
+	"github.com/sero-cash/go-sero/sero/stratum"
 )
 
 // DefaultConfig contains default settings for use on the Sero main net.
@@ -48,6 +53,8 @@ var DefaultConfig = Config{
 	DatabaseCache: 768,
 	TrieCache:     256,
 	TrieTimeout:   60 * time.Minute,
+	ColdTables:    rawdb.DefaultColdTables,
+	HotBlocks:     90000,
 	GasPrice:      big.NewInt(params.Gta),
 
 	TxPool: core.DefaultTxPoolConfig,
@@ -55,6 +62,12 @@ var DefaultConfig = Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
+	RPCCallCache: 1024,
+	TxDefaults:   ethapi.DefaultTxDefaults,
+	ProofQueue:   ethapi.DefaultProofQueue,
+	Merge:        ethapi.DefaultMergeConfig,
+	Replica:      replica.DefaultConfig,
+	Stratum:      stratum.DefaultConfig,
 }
 
 func init() {
@@ -83,6 +96,23 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// Number of recent blocks to retain receipts and logs for on non-archive
+	// nodes (0 = keep all)
+	ReceiptRetention uint64 `toml:",omitempty"`
+
+	// Number of recent blocks to retain the zstate block records for on
+	// non-archive nodes (0 = keep all). Records creating outs still owned by
+	// local accounts are always kept.
+	ZStateRetention uint64 `toml:",omitempty"`
+
+	// Drop the bodies and receipts of scanned blocks that carry no data of
+	// local accounts, keeping only headers and state (wallet node)
+	BodyPruning bool `toml:",omitempty"`
+
+	// LstateScanWorkers is the number of workers matching the outs of the
+	// blocks against the local accounts, zero for one per CPU
+	LstateScanWorkers int `toml:",omitempty"`
+
 	MineMode bool
 
 	// Light client options
@@ -96,9 +126,21 @@ type Config struct {
 	TrieCache          int
 	TrieTimeout        time.Duration
 
+	// Directory of the cold chain database, keeping the ColdTables of the
+	// blocks older than the last HotBlocks on a separate volume (empty = keep
+	// all chain data in the data directory)
+	ColdDataDir string   `toml:",omitempty"`
+	ColdTables  []string `toml:",omitempty"`
+	HotBlocks   uint64   `toml:",omitempty"`
+
+	// Directory of era history archives imported while syncing, restoring
+	// the bodies and receipts missing below a state snapshot
+	EraDir string `toml:",omitempty"`
+
 	// Mining-related options
 	Serobase     common.AccountAddress `toml:",omitempty"`
 	MinerThreads int                   `toml:",omitempty"`
+	MinerPayouts []miner.Payout        `toml:",omitempty"` // Shares of the block rewards paid to other accounts than the serobase
 	ExtraData    []byte                `toml:",omitempty"`
 	GasPrice     *big.Int
 
@@ -114,6 +156,53 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// RPCGasCap is the global gas cap for sero_call and sero_estimateGas
+	RPCGasCap uint64 `toml:",omitempty"`
+
+	// RPCEstimateGasCap is the lower gas cap of sero_estimateGas, 0 if the
+	// global gas cap applies
+	RPCEstimateGasCap uint64 `toml:",omitempty"`
+
+	// RPCCallCache is the number of sero_call results cached until the next block
+	RPCCallCache int `toml:",omitempty"`
+
+	// TxDefaults fill in the unspecified fields of transactions sent via RPC
+	TxDefaults ethapi.TxDefaults
+
+	// SpendPolicy limits the amounts local accounts may send via RPC
+	SpendPolicy ethapi.SpendPolicy
+
+	// ProofQueue orders the sends waiting for proof generation by caller
+	ProofQueue ethapi.ProofQueue
+
+	// Tenants are the account groups isolated from each other at the API
+	Tenants ethapi.Tenants `toml:",omitempty"`
+
+	// Merge consolidates the small outs of the local accounts
+	Merge ethapi.MergeConfig
+
+	// DailyStats aggregates the statistics of the chain by day
+	DailyStats bool `toml:",omitempty"`
+
+	// MemoIndex keeps an encrypted index of the memos received by the local
+	// accounts, searched by memoindex_search
+	MemoIndex bool `toml:",omitempty"`
+
+	// RelayTargets are the RPC endpoints local transactions are forwarded to
+	// for broadcast, such as gateways of nodes without peers
+	RelayTargets []string `toml:",omitempty"`
+
+	// BlockHooks are the Go plugins called for every change of the canonical
+	// chain, such as custom indexers
+	BlockHooks []string `toml:",omitempty"`
+
+	// Replica keeps the lstate of a hot standby in sync with its primary
+	Replica replica.Config
+
+	// Stratum serves the mining work to pool software, disabled without an
+	// address
+	Stratum stratum.Config
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
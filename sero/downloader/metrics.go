@@ -40,4 +40,7 @@ var (
 
 	stateInMeter   = metrics.NewRegisteredMeter("sero/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("sero/downloader/states/drop", nil)
+
+	zblockInMeter   = metrics.NewRegisteredMeter("sero/downloader/zblocks/in", nil)
+	zblockDropMeter = metrics.NewRegisteredMeter("sero/downloader/zblocks/drop", nil)
 )
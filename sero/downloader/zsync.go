@@ -0,0 +1,176 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+)
+
+// MaxZBlockFetch is the number of zstate block records to allow fetching per
+// request.
+const MaxZBlockFetch = 256
+
+var errNoZBlockPeer = errors.New("no peer serving zstate block records")
+
+// zblockPeer is a peer serving the zstate block records, from sero/64 on.
+type zblockPeer interface {
+	RequestZBlocks([]common.Hash) error
+}
+
+// zblockPack is a batch of zstate block records returned by a peer.
+type zblockPack struct {
+	peerID  string
+	records [][]byte
+}
+
+func (p *zblockPack) PeerId() string { return p.peerID }
+func (p *zblockPack) Items() int     { return len(p.records) }
+func (p *zblockPack) Stats() string  { return fmt.Sprintf("%d", len(p.records)) }
+
+// DeliverZBlocks injects a batch of zstate block records received from a
+// remote node.
+func (d *Downloader) DeliverZBlocks(id string, records [][]byte) (err error) {
+	return d.deliver(id, d.zblockCh, &zblockPack{id, records}, zblockInMeter, zblockDropMeter)
+}
+
+// syncZBlocks downloads the zstate block records of the blocks up to the pivot
+// of a snap sync. The records are kept outside of the state trie, so the state
+// sync does not bring them, yet the wallets need them to scan the blocks below
+// the pivot.
+//
+// A record lists the outs created by its block, so the records are verified
+// against the pivot state: every out has to be in the state, and the indexes
+// of the outs have to follow each other from the genesis block up to the last
+// out of the pivot. The spends and packages a record lists are taken as given.
+func (d *Downloader) syncZBlocks(pivot *types.Header) error {
+	statedb, err := state.New(pivot.Root, state.NewDatabase(d.stateDB), pivot.Number.Uint64())
+	if err != nil {
+		return err
+	}
+	zs := statedb.GetZState()
+
+	next := uint64(0)
+	if genesis := zs.GetBlock(0, rawdb.ReadCanonicalHash(d.stateDB, 0).HashToUint256()); genesis != nil {
+		next = uint64(len(genesis.Roots))
+	}
+	start := time.Now()
+	for from := uint64(1); from <= pivot.Number.Uint64(); from += MaxZBlockFetch {
+		hashes := make([]common.Hash, 0, MaxZBlockFetch)
+		for number := from; number <= pivot.Number.Uint64() && len(hashes) < MaxZBlockFetch; number++ {
+			hash := rawdb.ReadCanonicalHash(d.stateDB, number)
+			if hash == (common.Hash{}) {
+				return fmt.Errorf("missing canonical header #%d", number)
+			}
+			hashes = append(hashes, hash)
+		}
+		records, err := d.fetchZBlocks(hashes)
+		if err != nil {
+			return err
+		}
+		batch := d.stateDB.NewBatch()
+		for i, record := range records {
+			number := from + uint64(i)
+			if next, err = verifyZBlock(zs, number, record, next); err != nil {
+				return err
+			}
+			if err := batch.Put(zstate.BlockKey(number, hashes[i].HashToUint256()), record); err != nil {
+				return err
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		log.Debug("Imported zstate block records", "from", from, "count", len(records))
+	}
+	if last := uint64(zs.State.Cur.Index + 1); next != last {
+		return fmt.Errorf("zstate block records end at out %d, pivot state at %d", next, last)
+	}
+	log.Info("Imported zstate block records", "pivot", pivot.Number, "outs", next, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// verifyZBlock checks that the outs of a record are in the state, the first
+// one having the index next. It returns the index following the last out.
+func verifyZBlock(zs *zstate.ZState, number uint64, record []byte, next uint64) (uint64, error) {
+	get := zstate.BlockGet{}
+	if err := get.Unserial(record); err != nil {
+		return next, fmt.Errorf("zstate record of block #%d: %v", number, err)
+	}
+	if get.Out == nil {
+		return next, fmt.Errorf("zstate record of block #%d: empty", number)
+	}
+	for i := range get.Out.Roots {
+		out, err := zs.State.GetOut(&get.Out.Roots[i])
+		if err != nil {
+			return next, err
+		}
+		if out == nil {
+			return next, fmt.Errorf("zstate record of block #%d: out %x missing from state", number, get.Out.Roots[i][:])
+		}
+		if out.Index != next {
+			return next, fmt.Errorf("zstate record of block #%d: out index %d, want %d", number, out.Index, next)
+		}
+		next++
+	}
+	return next, nil
+}
+
+// fetchZBlocks requests the records of a batch of blocks from the peers in
+// turn until one delivers all of them.
+func (d *Downloader) fetchZBlocks(hashes []common.Hash) ([][]byte, error) {
+	for _, p := range d.peers.AllPeers() {
+		zp, ok := p.peer.(zblockPeer)
+		if !ok {
+			continue
+		}
+		if err := zp.RequestZBlocks(hashes); err != nil {
+			continue
+		}
+		timeout := time.NewTimer(d.requestTTL())
+		for waiting := true; waiting; {
+			select {
+			case <-d.cancelCh:
+				timeout.Stop()
+				return nil, errCancelContentProcessing
+			case <-timeout.C:
+				p.log.Debug("zstate block records timed out", "count", len(hashes))
+				waiting = false
+			case packet := <-d.zblockCh:
+				if packet.PeerId() != p.id {
+					continue
+				}
+				timeout.Stop()
+				records := packet.(*zblockPack).records
+				if len(records) == len(hashes) {
+					return records, nil
+				}
+				p.log.Debug("Partial zstate block records", "have", len(records), "want", len(hashes))
+				waiting = false
+			}
+		}
+	}
+	return nil, errNoZBlockPeer
+}
@@ -0,0 +1,73 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package sero
+
+import (
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/era"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// eraImporter imports the history archives of a directory in the background
+// while the node syncs the rest of the chain from its peers.
+type eraImporter struct {
+	dir   string
+	chain *core.BlockChain
+	db    serodb.Database
+	quit  chan struct{}
+	done  chan struct{}
+}
+
+func newEraImporter(dir string, chain *core.BlockChain, db serodb.Database) *eraImporter {
+	return &eraImporter{
+		dir:   dir,
+		chain: chain,
+		db:    db,
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start starts importing the archives in epoch order.
+func (i *eraImporter) Start() {
+	go i.run()
+}
+
+// Stop aborts the import, waiting for the block in progress.
+func (i *eraImporter) Stop() {
+	close(i.quit)
+	<-i.done
+}
+
+func (i *eraImporter) run() {
+	defer close(i.done)
+
+	files, err := era.Files(i.dir)
+	if err != nil {
+		log.Error("Failed to list era archives", "dir", i.dir, "err", err)
+		return
+	}
+	for _, fn := range files {
+		if err := era.Import(i.chain, i.db, fn, i.quit); err != nil {
+			if err != era.ErrAborted {
+				log.Error("Failed to import era archive", "file", fn, "err", err)
+			}
+			return
+		}
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/rpc"
 	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
 )
 
 var (
@@ -233,6 +234,55 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// BlockRelevance is the notification of sero_subscribe("blockRelevance").
+type BlockRelevance struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Relevant bool           `json:"relevant"`
+	Outs     bool           `json:"outs"`   // outs received by local accounts
+	Spends   bool           `json:"spends"` // outs of local accounts spent
+	Pkgs     bool           `json:"pkgs"`   // pkgs of local accounts created, closed or transferred
+}
+
+// BlockRelevance sends a notification each time a block is scanned for the outs
+// of the local accounts, telling whether it concerns them. Wallets can refresh
+// on relevant blocks only, the details are not part of the notification.
+func (api *PublicFilterAPI) BlockRelevance(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blocks := make(chan lstate.BlockRelevance)
+		blocksSub := lstate.SubscribeBlockRelevance(blocks)
+
+		for {
+			select {
+			case b := <-blocks:
+				notifier.Notify(rpcSub.ID, &BlockRelevance{
+					Number:   hexutil.Uint64(b.Num),
+					Hash:     b.Hash,
+					Relevant: b.Any(),
+					Outs:     b.Outs,
+					Spends:   b.Spends,
+					Pkgs:     b.Pkgs,
+				})
+			case <-rpcSub.Err():
+				blocksSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				blocksSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -560,6 +610,9 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 }
 
 func decodeAddress(s string) (common.Address, error) {
+	if !common.IsBase58Address(s) {
+		return common.Address{}, fmt.Errorf("%s is not a base58 encoded contract address", s)
+	}
 	return common.Base58ToAddress(s), nil
 }
 
@@ -19,11 +19,13 @@ package filters
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core"
 	"github.com/sero-cash/go-sero/core/bloombits"
+	"github.com/sero-cash/go-sero/core/rawdb"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/rpc"
@@ -126,6 +128,9 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		if header == nil {
 			return nil, errors.New("unknown block")
 		}
+		if tail := rawdb.ReadReceiptTail(f.db); header.Number.Uint64() < tail {
+			return nil, fmt.Errorf("logs of block %d have been pruned, logs are only retained from block %d", header.Number.Uint64(), tail)
+		}
 		return f.blockLogs(ctx, header)
 	}
 	// Figure out the limits of the filter range
@@ -135,13 +140,16 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
-	if f.begin == -1 {
+	if f.begin < 0 {
 		f.begin = int64(head)
 	}
 	end := uint64(f.end)
 	if f.end == -1 {
 		end = head
 	}
+	if tail := rawdb.ReadReceiptTail(f.db); f.begin < int64(tail) && f.begin <= int64(end) {
+		return nil, fmt.Errorf("logs of block %d have been pruned, logs are only retained from block %d", f.begin, tail)
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
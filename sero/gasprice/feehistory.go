@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// maxFeeHistory is the number of blocks a fee history covers at most.
+const maxFeeHistory = 1024
+
+// txGasAndPrice is the gas used by a transaction and its gas price.
+type txGasAndPrice struct {
+	gasUsed  uint64
+	gasPrice *big.Int
+}
+
+type txsByGasPrice []txGasAndPrice
+
+func (t txsByGasPrice) Len() int           { return len(t) }
+func (t txsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t txsByGasPrice) Less(i, j int) bool { return t[i].gasPrice.Cmp(t[j].gasPrice) < 0 }
+
+// FeeHistory returns the gas price statistics of up to blocks blocks ending
+// with lastBlock: the gas prices at the given percentiles of the gas used by
+// the transactions of each block, the fees per gas at the percentiles in each
+// currency paying them, and the ratio of the gas used to the gas limit of each
+// block. Percentiles are given in ascending order between 0 and 100.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*ethapi.FeeHistory, error) {
+	if blocks < 1 {
+		return nil, errors.New("block count must be positive")
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %f", p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return nil, fmt.Errorf("percentiles not ascending: %f after %f", p, percentiles[i-1])
+		}
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("block %d not found", lastBlock)
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	history := &ethapi.FeeHistory{
+		OldestBlock:  new(big.Int).SetUint64(oldest),
+		GasUsedRatio: make([]float64, blocks),
+	}
+	if len(percentiles) > 0 {
+		history.GasPrice = make([][]*big.Int, blocks)
+		history.Fees = make([]map[string][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		number := oldest + uint64(i)
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		if block.GasLimit() > 0 {
+			history.GasUsedRatio[i] = float64(block.GasUsed()) / float64(block.GasLimit())
+		}
+		if len(percentiles) == 0 {
+			continue
+		}
+		history.Fees[i] = make(map[string][]*big.Int)
+		txs := block.Transactions()
+		if len(txs) == 0 {
+			history.GasPrice[i] = weightedPrices(nil, 0, percentiles)
+			continue
+		}
+		receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if len(receipts) != len(txs) {
+			return nil, fmt.Errorf("receipts of block %d not found", number)
+		}
+		var (
+			all         = make([]txGasAndPrice, len(txs))
+			byCurrency  = make(map[string][]txGasAndPrice)
+			currencyGas = make(map[string]uint64)
+		)
+		for j, tx := range txs {
+			gasUsed := receipts[j].GasUsed
+			all[j] = txGasAndPrice{gasUsed: gasUsed, gasPrice: tx.GasPrice()}
+			if tx.Gas() == 0 {
+				continue
+			}
+			fee := tx.Stxt().Fee
+			currency := strings.Trim(string(fee.Currency[:]), string([]byte{0}))
+			perGas := new(big.Int).Div(fee.Value.ToIntRef(), new(big.Int).SetUint64(tx.Gas()))
+			byCurrency[currency] = append(byCurrency[currency], txGasAndPrice{gasUsed: gasUsed, gasPrice: perGas})
+			currencyGas[currency] += gasUsed
+		}
+		history.GasPrice[i] = weightedPrices(all, block.GasUsed(), percentiles)
+		for currency, fees := range byCurrency {
+			history.Fees[i][currency] = weightedPrices(fees, currencyGas[currency], percentiles)
+		}
+	}
+	return history, nil
+}
+
+// weightedPrices returns the prices at the given percentiles of the gas used
+// by the transactions, zero if there are none.
+func weightedPrices(txs []txGasAndPrice, gasUsed uint64, percentiles []float64) []*big.Int {
+	prices := make([]*big.Int, len(percentiles))
+	if len(txs) == 0 {
+		for i := range prices {
+			prices[i] = new(big.Int)
+		}
+		return prices
+	}
+	sort.Sort(txsByGasPrice(txs))
+
+	var index int
+	sumGasUsed := txs[0].gasUsed
+	for i, p := range percentiles {
+		threshold := uint64(float64(gasUsed) * p / 100)
+		for sumGasUsed < threshold && index < len(txs)-1 {
+			index++
+			sumGasUsed += txs[index].gasUsed
+		}
+		prices[i] = new(big.Int).Set(txs[index].gasPrice)
+	}
+	return prices
+}
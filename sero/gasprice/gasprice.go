@@ -29,12 +29,14 @@ import (
 	"github.com/sero-cash/go-sero/rpc"
 )
 
-var maxPrice = big.NewInt(500 * params.Gta)
+// DefaultMaxPrice caps the suggested gas price unless configured otherwise.
+var DefaultMaxPrice = big.NewInt(500 * params.Gta)
 
 type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
+	MaxPrice   *big.Int `toml:",omitempty"` // cap of the suggested gas price
 }
 
 // Oracle recommends gas prices based on the content of recent
@@ -48,6 +50,7 @@ type Oracle struct {
 
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+	maxPrice                         *big.Int
 }
 
 // NewOracle returns a new oracle.
@@ -63,6 +66,10 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
+	maxPrice := params.MaxPrice
+	if maxPrice == nil || maxPrice.Sign() <= 0 {
+		maxPrice = DefaultMaxPrice
+	}
 	return &Oracle{
 		backend:     backend,
 		lastPrice:   params.Default,
@@ -70,6 +77,7 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 		maxEmpty:    blocks / 2,
 		maxBlocks:   blocks * 5,
 		percentile:  percent,
+		maxPrice:    maxPrice,
 	}
 }
 
@@ -136,8 +144,8 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		sort.Sort(bigIntArray(blockPrices))
 		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
 	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+	if price.Cmp(gpo.maxPrice) > 0 {
+		price = new(big.Int).Set(gpo.maxPrice)
 	}
 
 	gpo.cacheLock.Lock()
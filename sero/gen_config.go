@@ -10,8 +10,12 @@ import (
 	"github.com/sero-cash/go-sero/common/hexutil"
 	"github.com/sero-cash/go-sero/consensus/ethash"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/miner"
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/gasprice"
+	"github.com/sero-cash/go-sero/sero/replica"
+	"github.com/sero-cash/go-sero/sero/stratum"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -23,6 +27,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
+		ReceiptRetention        uint64 `toml:",omitempty"`
+		ZStateRetention         uint64 `toml:",omitempty"`
+		BodyPruning             bool   `toml:",omitempty"`
+		LstateScanWorkers       int    `toml:",omitempty"`
 		MineMode                bool
 		LightServ               int  `toml:",omitempty"`
 		LightPeers              int  `toml:",omitempty"`
@@ -31,14 +39,33 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DatabaseCache           int
 		TrieCache               int
 		TrieTimeout             time.Duration
+		ColdDataDir             string                `toml:",omitempty"`
+		ColdTables              []string              `toml:",omitempty"`
+		HotBlocks               uint64                `toml:",omitempty"`
+		EraDir                  string                `toml:",omitempty"`
 		Serobase                common.AccountAddress `toml:",omitempty"`
 		MinerThreads            int                   `toml:",omitempty"`
+		MinerPayouts            []miner.Payout        `toml:",omitempty"`
 		ExtraData               hexutil.Bytes         `toml:",omitempty"`
 		GasPrice                *big.Int
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		RPCGasCap               uint64 `toml:",omitempty"`
+		RPCEstimateGasCap       uint64 `toml:",omitempty"`
+		RPCCallCache            int    `toml:",omitempty"`
+		TxDefaults              ethapi.TxDefaults
+		SpendPolicy             ethapi.SpendPolicy
+		ProofQueue              ethapi.ProofQueue
+		Tenants                 ethapi.Tenants `toml:",omitempty"`
+		Merge                   ethapi.MergeConfig
+		DailyStats              bool     `toml:",omitempty"`
+		MemoIndex               bool     `toml:",omitempty"`
+		RelayTargets            []string `toml:",omitempty"`
+		BlockHooks              []string `toml:",omitempty"`
+		Replica                 replica.Config
+		Stratum                 stratum.Config
 		DocRoot                 string `toml:"-"`
 	}
 	var enc Config
@@ -46,6 +73,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.ReceiptRetention = c.ReceiptRetention
+	enc.ZStateRetention = c.ZStateRetention
+	enc.BodyPruning = c.BodyPruning
+	enc.LstateScanWorkers = c.LstateScanWorkers
 	enc.MineMode = c.MineMode
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
@@ -54,14 +85,33 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseCache = c.DatabaseCache
 	enc.TrieCache = c.TrieCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.ColdDataDir = c.ColdDataDir
+	enc.ColdTables = c.ColdTables
+	enc.HotBlocks = c.HotBlocks
+	enc.EraDir = c.EraDir
 	enc.Serobase = c.Serobase
 	enc.MinerThreads = c.MinerThreads
+	enc.MinerPayouts = c.MinerPayouts
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCEstimateGasCap = c.RPCEstimateGasCap
+	enc.RPCCallCache = c.RPCCallCache
+	enc.TxDefaults = c.TxDefaults
+	enc.SpendPolicy = c.SpendPolicy
+	enc.ProofQueue = c.ProofQueue
+	enc.Tenants = c.Tenants
+	enc.Merge = c.Merge
+	enc.DailyStats = c.DailyStats
+	enc.MemoIndex = c.MemoIndex
+	enc.RelayTargets = c.RelayTargets
+	enc.BlockHooks = c.BlockHooks
+	enc.Replica = c.Replica
+	enc.Stratum = c.Stratum
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -73,6 +123,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
+		ReceiptRetention        *uint64 `toml:",omitempty"`
+		ZStateRetention         *uint64 `toml:",omitempty"`
+		BodyPruning             *bool   `toml:",omitempty"`
+		LstateScanWorkers       *int    `toml:",omitempty"`
 		MineMode                *bool
 		LightServ               *int  `toml:",omitempty"`
 		LightPeers              *int  `toml:",omitempty"`
@@ -81,14 +135,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseCache           *int
 		TrieCache               *int
 		TrieTimeout             *time.Duration
+		ColdDataDir             *string                `toml:",omitempty"`
+		ColdTables              []string               `toml:",omitempty"`
+		HotBlocks               *uint64                `toml:",omitempty"`
+		EraDir                  *string                `toml:",omitempty"`
 		Serobase                *common.AccountAddress `toml:",omitempty"`
 		MinerThreads            *int                   `toml:",omitempty"`
+		MinerPayouts            []miner.Payout         `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes         `toml:",omitempty"`
 		GasPrice                *big.Int
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		RPCGasCap               *uint64 `toml:",omitempty"`
+		RPCEstimateGasCap       *uint64 `toml:",omitempty"`
+		RPCCallCache            *int    `toml:",omitempty"`
+		TxDefaults              *ethapi.TxDefaults
+		SpendPolicy             *ethapi.SpendPolicy
+		ProofQueue              *ethapi.ProofQueue
+		Tenants                 ethapi.Tenants `toml:",omitempty"`
+		Merge                   *ethapi.MergeConfig
+		DailyStats              *bool    `toml:",omitempty"`
+		MemoIndex               *bool    `toml:",omitempty"`
+		RelayTargets            []string `toml:",omitempty"`
+		BlockHooks              []string `toml:",omitempty"`
+		Replica                 *replica.Config
+		Stratum                 *stratum.Config
 		DocRoot                 *string `toml:"-"`
 	}
 	var dec Config
@@ -107,6 +180,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.ReceiptRetention != nil {
+		c.ReceiptRetention = *dec.ReceiptRetention
+	}
+	if dec.ZStateRetention != nil {
+		c.ZStateRetention = *dec.ZStateRetention
+	}
+	if dec.BodyPruning != nil {
+		c.BodyPruning = *dec.BodyPruning
+	}
+	if dec.LstateScanWorkers != nil {
+		c.LstateScanWorkers = *dec.LstateScanWorkers
+	}
 	if dec.MineMode != nil {
 		c.MineMode = *dec.MineMode
 	}
@@ -131,12 +216,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.ColdDataDir != nil {
+		c.ColdDataDir = *dec.ColdDataDir
+	}
+	if dec.ColdTables != nil {
+		c.ColdTables = dec.ColdTables
+	}
+	if dec.HotBlocks != nil {
+		c.HotBlocks = *dec.HotBlocks
+	}
+	if dec.EraDir != nil {
+		c.EraDir = *dec.EraDir
+	}
 	if dec.Serobase != nil {
 		c.Serobase = *dec.Serobase
 	}
 	if dec.MinerThreads != nil {
 		c.MinerThreads = *dec.MinerThreads
 	}
+	if dec.MinerPayouts != nil {
+		c.MinerPayouts = dec.MinerPayouts
+	}
 	if dec.ExtraData != nil {
 		c.ExtraData = *dec.ExtraData
 	}
@@ -155,6 +255,48 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
+	if dec.RPCEstimateGasCap != nil {
+		c.RPCEstimateGasCap = *dec.RPCEstimateGasCap
+	}
+	if dec.RPCCallCache != nil {
+		c.RPCCallCache = *dec.RPCCallCache
+	}
+	if dec.TxDefaults != nil {
+		c.TxDefaults = *dec.TxDefaults
+	}
+	if dec.SpendPolicy != nil {
+		c.SpendPolicy = *dec.SpendPolicy
+	}
+	if dec.ProofQueue != nil {
+		c.ProofQueue = *dec.ProofQueue
+	}
+	if dec.Tenants != nil {
+		c.Tenants = dec.Tenants
+	}
+	if dec.Merge != nil {
+		c.Merge = *dec.Merge
+	}
+	if dec.DailyStats != nil {
+		c.DailyStats = *dec.DailyStats
+	}
+	if dec.MemoIndex != nil {
+		c.MemoIndex = *dec.MemoIndex
+	}
+	if dec.RelayTargets != nil {
+		c.RelayTargets = dec.RelayTargets
+	}
+	if dec.BlockHooks != nil {
+		c.BlockHooks = dec.BlockHooks
+	}
+	if dec.Replica != nil {
+		c.Replica = *dec.Replica
+	}
+	if dec.Stratum != nil {
+		c.Stratum = *dec.Stratum
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -0,0 +1,148 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build grpc
+// +build grpc
+
+package grpcapi
+
+import "github.com/golang/protobuf/proto"
+
+// The messages of wallet.proto. They are encoded from their struct tags, so
+// they need no generated descriptors.
+
+type BalanceRequest struct {
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Block   int64  `protobuf:"varint,2,opt,name=block,proto3" json:"block,omitempty"`
+	MinConf uint64 `protobuf:"varint,3,opt,name=min_conf,json=minConf,proto3" json:"min_conf,omitempty"`
+}
+
+func (m *BalanceRequest) Reset()         { *m = BalanceRequest{} }
+func (m *BalanceRequest) String() string { return proto.CompactTextString(m) }
+func (*BalanceRequest) ProtoMessage()    {}
+
+type Token struct {
+	Currency  string `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	Value     string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Spendable string `protobuf:"bytes,3,opt,name=spendable,proto3" json:"spendable,omitempty"`
+}
+
+func (m *Token) Reset()         { *m = Token{} }
+func (m *Token) String() string { return proto.CompactTextString(m) }
+func (*Token) ProtoMessage()    {}
+
+type Ticket struct {
+	Category string   `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Values   [][]byte `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (m *Ticket) Reset()         { *m = Ticket{} }
+func (m *Ticket) String() string { return proto.CompactTextString(m) }
+func (*Ticket) ProtoMessage()    {}
+
+type BalanceReply struct {
+	Tokens  []*Token  `protobuf:"bytes,1,rep,name=tokens" json:"tokens,omitempty"`
+	Tickets []*Ticket `protobuf:"bytes,2,rep,name=tickets" json:"tickets,omitempty"`
+}
+
+func (m *BalanceReply) Reset()         { *m = BalanceReply{} }
+func (m *BalanceReply) String() string { return proto.CompactTextString(m) }
+func (*BalanceReply) ProtoMessage()    {}
+
+type SendTransactionRequest struct {
+	From            string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To              string `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Currency        string `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	Value           string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	Gas             uint64 `protobuf:"varint,5,opt,name=gas,proto3" json:"gas,omitempty"`
+	GasPrice        string `protobuf:"bytes,6,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	Memo            string `protobuf:"bytes,7,opt,name=memo,proto3" json:"memo,omitempty"`
+	Data            []byte `protobuf:"bytes,8,opt,name=data,proto3" json:"data,omitempty"`
+	ClientRequestId string `protobuf:"bytes,9,opt,name=client_request_id,json=clientRequestId,proto3" json:"client_request_id,omitempty"`
+}
+
+func (m *SendTransactionRequest) Reset()         { *m = SendTransactionRequest{} }
+func (m *SendTransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*SendTransactionRequest) ProtoMessage()    {}
+
+type SendTransactionReply struct {
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *SendTransactionReply) Reset()         { *m = SendTransactionReply{} }
+func (m *SendTransactionReply) String() string { return proto.CompactTextString(m) }
+func (*SendTransactionReply) ProtoMessage()    {}
+
+type TransactionDetailRequest struct {
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *TransactionDetailRequest) Reset()         { *m = TransactionDetailRequest{} }
+func (m *TransactionDetailRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionDetailRequest) ProtoMessage()    {}
+
+type DecryptedOut struct {
+	Index    uint32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Z        bool   `protobuf:"varint,2,opt,name=z,proto3" json:"z,omitempty"`
+	To       []byte `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Account  string `protobuf:"bytes,4,opt,name=account,proto3" json:"account,omitempty"`
+	Currency string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	Value    string `protobuf:"bytes,6,opt,name=value,proto3" json:"value,omitempty"`
+	Category string `protobuf:"bytes,7,opt,name=category,proto3" json:"category,omitempty"`
+	Ticket   []byte `protobuf:"bytes,8,opt,name=ticket,proto3" json:"ticket,omitempty"`
+	Memo     []byte `protobuf:"bytes,9,opt,name=memo,proto3" json:"memo,omitempty"`
+	Text     string `protobuf:"bytes,10,opt,name=text,proto3" json:"text,omitempty"`
+	Error    string `protobuf:"bytes,11,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *DecryptedOut) Reset()         { *m = DecryptedOut{} }
+func (m *DecryptedOut) String() string { return proto.CompactTextString(m) }
+func (*DecryptedOut) ProtoMessage()    {}
+
+type TransactionDetailReply struct {
+	Hash        []byte          `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	BlockHash   []byte          `protobuf:"bytes,2,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockNumber uint64          `protobuf:"varint,3,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	From        []byte          `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	FromAccount string          `protobuf:"bytes,5,opt,name=from_account,json=fromAccount,proto3" json:"from_account,omitempty"`
+	FeeCurrency string          `protobuf:"bytes,6,opt,name=fee_currency,json=feeCurrency,proto3" json:"fee_currency,omitempty"`
+	Fee         string          `protobuf:"bytes,7,opt,name=fee,proto3" json:"fee,omitempty"`
+	Outs        []*DecryptedOut `protobuf:"bytes,8,rep,name=outs" json:"outs,omitempty"`
+}
+
+func (m *TransactionDetailReply) Reset()         { *m = TransactionDetailReply{} }
+func (m *TransactionDetailReply) String() string { return proto.CompactTextString(m) }
+func (*TransactionDetailReply) ProtoMessage()    {}
+
+type SubscribeBlocksRequest struct {
+}
+
+func (m *SubscribeBlocksRequest) Reset()         { *m = SubscribeBlocksRequest{} }
+func (m *SubscribeBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeBlocksRequest) ProtoMessage()    {}
+
+type Block struct {
+	Number       uint64   `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Hash         []byte   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	ParentHash   []byte   `protobuf:"bytes,3,opt,name=parent_hash,json=parentHash,proto3" json:"parent_hash,omitempty"`
+	Time         uint64   `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	GasUsed      uint64   `protobuf:"varint,5,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	Transactions [][]byte `protobuf:"bytes,6,rep,name=transactions,proto3" json:"transactions,omitempty"`
+}
+
+func (m *Block) Reset()         { *m = Block{} }
+func (m *Block) String() string { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()    {}
@@ -0,0 +1,334 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build grpc
+// +build grpc
+
+// Package grpcapi serves the main wallet RPCs over gRPC, sparing exchange
+// backends the JSON encoding of the RPC API and streaming the new blocks with
+// typed messages. The service is defined in wallet.proto.
+//
+// gRPC is not vendored, so the package is only built with the grpc build tag,
+// keeping it out of the node unless the gateway is asked for.
+package grpcapi
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"sort"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WalletServer is the server side of the Wallet service.
+type WalletServer interface {
+	GetBalance(context.Context, *BalanceRequest) (*BalanceReply, error)
+	SendTransaction(context.Context, *SendTransactionRequest) (*SendTransactionReply, error)
+	GetTransactionDetail(context.Context, *TransactionDetailRequest) (*TransactionDetailReply, error)
+	SubscribeBlocks(*SubscribeBlocksRequest, grpc.ServerStream) error
+}
+
+// Server is the gRPC gateway of a node, serving the Wallet service.
+type Server struct {
+	addr  string
+	b     ethapi.Backend
+	chain *ethapi.PublicBlockChainAPI
+	pool  *ethapi.PublicTransactionPoolAPI
+	srv   *grpc.Server
+}
+
+// New creates the gateway listening on addr, sending through the transaction
+// pool API served to the RPC clients.
+func New(addr string, b ethapi.Backend, pool *ethapi.PublicTransactionPoolAPI) *Server {
+	return &Server{
+		addr:  addr,
+		b:     b,
+		chain: ethapi.NewPublicBlockChainAPI(b),
+		pool:  pool,
+	}
+}
+
+// Start opens the gRPC endpoint.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.srv = grpc.NewServer()
+	s.srv.RegisterService(&walletServiceDesc, s)
+	go s.srv.Serve(listener)
+	log.Info("gRPC endpoint opened", "addr", listener.Addr())
+	return nil
+}
+
+// Stop closes the gRPC endpoint, cancelling the block subscriptions.
+func (s *Server) Stop() {
+	if s.srv != nil {
+		s.srv.Stop()
+		log.Info("gRPC endpoint closed", "addr", s.addr)
+	}
+}
+
+// GetBalance returns the balance of an address.
+func (s *Server) GetBalance(ctx context.Context, req *BalanceRequest) (*BalanceReply, error) {
+	var address common.AccountAddress
+	if err := address.UnmarshalText([]byte(req.Address)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address: %v", err)
+	}
+	block := rpc.LatestBlockNumber
+	if req.Block > 0 {
+		block = rpc.BlockNumber(req.Block)
+	}
+	var minConf *hexutil.Uint64
+	if req.MinConf > 0 {
+		minConf = (*hexutil.Uint64)(&req.MinConf)
+	}
+	balance, err := s.chain.GetBalance(ctx, address, block, minConf)
+	if err != nil {
+		return nil, err
+	}
+	reply := &BalanceReply{}
+	for _, currency := range sortedKeys(balance.Tkn) {
+		token := &Token{Currency: currency, Value: balance.Tkn[currency].ToInt().String()}
+		if spendable := balance.Spendable[currency]; spendable != nil {
+			token.Spendable = spendable.ToInt().String()
+		}
+		reply.Tokens = append(reply.Tokens, token)
+	}
+	for category, values := range balance.Tkt {
+		ticket := &Ticket{Category: category}
+		for _, value := range values {
+			ticket.Values = append(ticket.Values, value.Bytes())
+		}
+		reply.Tickets = append(reply.Tickets, ticket)
+	}
+	sort.Slice(reply.Tickets, func(i, j int) bool {
+		return reply.Tickets[i].Category < reply.Tickets[j].Category
+	})
+	return reply, nil
+}
+
+// SendTransaction sends a payment to a single recipient.
+func (s *Server) SendTransaction(ctx context.Context, req *SendTransactionRequest) (*SendTransactionReply, error) {
+	args := ethapi.SendTxArgs{Currency: ethapi.Smbol(req.Currency), Memo: req.Memo}
+	if err := args.From.UnmarshalText([]byte(req.From)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+	}
+	if req.To != "" {
+		args.To = new(common.AccountAddress)
+		if err := args.To.UnmarshalText([]byte(req.To)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+		}
+	}
+	var err error
+	if args.Value, err = parseAmount("value", req.Value); err != nil {
+		return nil, err
+	}
+	if args.GasPrice, err = parseAmount("gas price", req.GasPrice); err != nil {
+		return nil, err
+	}
+	if req.Gas > 0 {
+		args.Gas = (*hexutil.Uint64)(&req.Gas)
+	}
+	if len(req.Data) > 0 {
+		args.Data = (*hexutil.Bytes)(&req.Data)
+	}
+	var hash common.Hash
+	if req.ClientRequestId != "" {
+		hash, err = s.pool.SendTransactionIdempotent(ctx, args, req.ClientRequestId)
+	} else {
+		hash, err = s.pool.SendTransaction(ctx, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SendTransactionReply{Hash: hash.Bytes()}, nil
+}
+
+// GetTransactionDetail returns a transaction with the outs received by the
+// local accounts decrypted.
+func (s *Server) GetTransactionDetail(ctx context.Context, req *TransactionDetailRequest) (*TransactionDetailReply, error) {
+	if len(req.Hash) != common.HashLength {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid hash length %d", len(req.Hash))
+	}
+	detail, err := s.pool.GetTransactionDetail(ctx, common.BytesToHash(req.Hash))
+	if err != nil {
+		return nil, err
+	}
+	if detail == nil {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+	reply := &TransactionDetailReply{
+		Hash:        detail.Hash.Bytes(),
+		From:        detail.From[:],
+		FeeCurrency: detail.FeeCurrency,
+		Fee:         detail.Fee.ToInt().String(),
+	}
+	if detail.BlockHash != nil {
+		reply.BlockHash = detail.BlockHash.Bytes()
+		reply.BlockNumber = uint64(*detail.BlockNumber)
+	}
+	if detail.FromAccount != nil {
+		reply.FromAccount = detail.FromAccount.String()
+	}
+	for _, out := range detail.Outs {
+		dout := &DecryptedOut{
+			Index:    uint32(out.Index),
+			Z:        out.Z,
+			To:       out.To[:],
+			Account:  out.Account.String(),
+			Currency: out.Currency,
+			Category: out.Category,
+			Memo:     out.Memo,
+			Text:     out.Text,
+			Error:    out.Error,
+		}
+		if out.Value != nil {
+			dout.Value = out.Value.ToInt().String()
+		}
+		if out.Ticket != nil {
+			dout.Ticket = out.Ticket.Bytes()
+		}
+		reply.Outs = append(reply.Outs, dout)
+	}
+	return reply, nil
+}
+
+// SubscribeBlocks streams the head blocks imported from now on, until the
+// client cancels the call.
+func (s *Server) SubscribeBlocks(req *SubscribeBlocksRequest, stream grpc.ServerStream) error {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.b.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-heads:
+			block := &Block{
+				Number:     head.Block.NumberU64(),
+				Hash:       head.Block.Hash().Bytes(),
+				ParentHash: head.Block.ParentHash().Bytes(),
+				Time:       head.Block.Time().Uint64(),
+				GasUsed:    head.Block.GasUsed(),
+			}
+			for _, tx := range head.Block.Transactions() {
+				block.Transactions = append(block.Transactions, tx.Hash().Bytes())
+			}
+			if err := stream.SendMsg(block); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			return err
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// parseAmount parses a decimal amount, nil if empty.
+func parseAmount(name string, s string) (*hexutil.Big, error) {
+	if s == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s %q", name, s)
+	}
+	return (*hexutil.Big)(amount), nil
+}
+
+func sortedKeys(m map[string]*hexutil.Big) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// walletServiceDesc describes the Wallet service of wallet.proto.
+var walletServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sero.Wallet",
+	HandlerType: (*WalletServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetBalance", Handler: getBalanceHandler},
+		{MethodName: "SendTransaction", Handler: sendTransactionHandler},
+		{MethodName: "GetTransactionDetail", Handler: getTransactionDetailHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "SubscribeBlocks", Handler: subscribeBlocksHandler, ServerStreams: true},
+	},
+	Metadata: "wallet.proto",
+}
+
+func getBalanceHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(BalanceRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).GetBalance(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/sero.Wallet/GetBalance"}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).GetBalance(ctx, req.(*BalanceRequest))
+	})
+}
+
+func sendTransactionHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(SendTransactionRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).SendTransaction(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/sero.Wallet/SendTransaction"}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).SendTransaction(ctx, req.(*SendTransactionRequest))
+	})
+}
+
+func getTransactionDetailHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(TransactionDetailRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).GetTransactionDetail(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/sero.Wallet/GetTransactionDetail"}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).GetTransactionDetail(ctx, req.(*TransactionDetailRequest))
+	})
+}
+
+func subscribeBlocksHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(SubscribeBlocksRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(WalletServer).SubscribeBlocks(req, stream)
+}
@@ -61,8 +61,9 @@ func errResp(code errCode, format string, v ...interface{}) error {
 type ProtocolManager struct {
 	networkID uint64
 
-	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
-	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	fastSync  uint32              // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	pivotMode downloader.SyncMode // Mode of the sync while fast sync is enabled, fast or snap
+	acceptTxs uint32              // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
 	blockchain  *core.BlockChain
@@ -108,18 +109,19 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		quitSync:    make(chan struct{}),
 	}
 	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
-		log.Warn("Blockchain not empty, fast sync disabled")
+	if mode.SyncsState() && blockchain.CurrentBlock().NumberU64() > 0 {
+		log.Warn("Blockchain not empty, fast sync disabled", "mode", mode)
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	if mode.SyncsState() {
 		manager.fastSync = uint32(1)
+		manager.pivotMode = mode
 	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < sero63 {
+		if mode.SyncsState() && version < sero63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -435,6 +437,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested block body, stopping if enough was found
+			if pm.blockchain.BodyPruned(hash) {
+				continue
+			}
 			if data := pm.blockchain.GetBodyRLP(hash); len(data) != 0 {
 				bodies = append(bodies, data)
 				bytes += len(data)
@@ -504,6 +509,60 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
 
+	case p.version >= sero64 && msg.Code == GetZBlocksMsg:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		statedb, err := pm.blockchain.State()
+		if err != nil {
+			return err
+		}
+		zs := statedb.GetZState()
+
+		// Gather records until the fetch or network limits is reached, stopping
+		// at the first block unknown, as records are verified in order
+		var (
+			hash    common.Hash
+			bytes   int
+			records [][]byte
+		)
+		for bytes < softResponseLimit && len(records) < downloader.MaxZBlockFetch {
+			// Retrieve the hash of the next block
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			header := pm.blockchain.GetHeaderByHash(hash)
+			if header == nil {
+				break
+			}
+			block := zs.GetBlock(header.Number.Uint64(), hash.HashToUint256())
+			if block == nil {
+				break
+			}
+			record, err := block.Serial()
+			if err != nil {
+				break
+			}
+			records = append(records, record)
+			bytes += len(record)
+		}
+		return p.SendZBlocks(records)
+
+	case p.version >= sero64 && msg.Code == ZBlocksMsg:
+		// A batch of zstate block records arrived to one of our previous requests
+		var records [][]byte
+		if err := msg.Decode(&records); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Deliver all to the downloader
+		if err := pm.downloader.DeliverZBlocks(p.id, records); err != nil {
+			log.Debug("Failed to deliver zstate block records", "err", err)
+		}
+
 	case p.version >= sero63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
@@ -524,6 +583,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested block's receipts, skipping if unknown to us
+			if pm.blockchain.BodyPruned(hash) {
+				continue
+			}
 			results := pm.blockchain.GetReceiptsByHash(hash)
 			if results == nil {
 				if header := pm.blockchain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
@@ -279,6 +279,12 @@ func (p *peer) SendNodeData(data [][]byte) error {
 	return p2p.Send(p.rw, NodeDataMsg, data)
 }
 
+// SendZBlocks sends a batch of zstate block records, corresponding to the
+// hashes requested.
+func (p *peer) SendZBlocks(records [][]byte) error {
+	return p2p.Send(p.rw, ZBlocksMsg, records)
+}
+
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
@@ -320,6 +326,12 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
+// RequestZBlocks fetches a batch of zstate block records from a remote node.
+func (p *peer) RequestZBlocks(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of zstate block records", "count", len(hashes))
+	return p2p.Send(p.rw, GetZBlocksMsg, hashes)
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
@@ -32,16 +32,17 @@ import (
 const (
 	sero62 = 62
 	sero63 = 63
+	sero64 = 64
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "sero"
 
 // ProtocolVersions are the upported versions of the sero protocol (first is primary).
-var ProtocolVersions = []uint{sero63, sero62}
+var ProtocolVersions = []uint{sero64, sero63, sero62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -62,6 +63,10 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to sero/64
+	GetZBlocksMsg = 0x11
+	ZBlocksMsg    = 0x12
 )
 
 type errCode int
@@ -0,0 +1,146 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts/keystore"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+// client keeps the state of a standby in sync with its primary.
+type client struct {
+	url      string
+	http     *http.Client
+	ks       *keystore.KeyStore
+	accounts bool
+	imported string // name of the state file imported last
+	quit     chan struct{}
+}
+
+func newClient(primary string, config *tls.Config, ks *keystore.KeyStore, addAccounts bool, interval time.Duration) *client {
+	c := &client{
+		url: "https://" + primary,
+		http: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: config},
+			Timeout:   10 * time.Minute,
+		},
+		ks:       ks,
+		accounts: addAccounts,
+		quit:     make(chan struct{}),
+	}
+	go c.loop(interval)
+	log.Info("Replica syncing lstate", "primary", primary, "accounts", addAccounts)
+	return c
+}
+
+func (c *client) close() {
+	close(c.quit)
+}
+
+func (c *client) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.sync(); err != nil {
+			log.Warn("Replica sync failed", "primary", c.url, "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// sync imports the newest state of the primary once the standby scans for
+// the same accounts and has the block of the state on its canonical chain.
+func (c *client) sync() error {
+	status, err := c.status()
+	if err != nil {
+		return err
+	}
+	if c.accounts {
+		for _, account := range status.Accounts {
+			if c.ks.HasAddress(account.Address) {
+				continue
+			}
+			if _, err := c.ks.AddWatchOnly(account.Address, account.Tk); err != nil {
+				return err
+			}
+			log.Info("Replica added watch-only account", "address", account.Address)
+		}
+	}
+	tks, err := lstate.TksDigest()
+	if err != nil {
+		return err
+	}
+	if tks != status.Tks {
+		return fmt.Errorf("accounts differ from the primary")
+	}
+	if status.File == c.imported {
+		return nil
+	}
+	name, data, err := c.state()
+	if err != nil {
+		return err
+	}
+	if err := lstate.ImportStateFile(name, data); err != nil {
+		return err
+	}
+	c.imported = name
+	return nil
+}
+
+func (c *client) status() (*Status, error) {
+	resp, err := c.http.Get(c.url + statusPath)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+	status := new(Status)
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (c *client) state() (string, []byte, error) {
+	resp, err := c.http.Get(c.url + statePath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("state: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.Header.Get(stateFileHeader), data, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package replica keeps the local out database (lstate) of a hot standby in
+// sync with its primary wallet node, so a failover needs no rescan of the
+// chain. Nodes talk over TLS authenticating each other with certificates
+// issued by a shared CA.
+package replica
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/accounts/keystore"
+	"github.com/sero-cash/go-sero/common"
+)
+
+// Config are the replication settings of a node. A primary serves its state
+// on Listen, a standby pulls the state of the primary at Primary.
+type Config struct {
+	Listen   string        `toml:",omitempty"` // Address the primary serves its state on
+	Primary  string        `toml:",omitempty"` // Address of the primary a standby syncs from
+	Cert     string        `toml:",omitempty"` // Certificate of this node
+	Key      string        `toml:",omitempty"` // Private key of the certificate
+	CA       string        `toml:",omitempty"` // CA issuing the certificates of both nodes
+	Accounts bool          `toml:",omitempty"` // Share the accounts as watch-only keys
+	Interval time.Duration `toml:",omitempty"` // Time between two syncs of a standby
+}
+
+// DefaultConfig contains the default replication settings.
+var DefaultConfig = Config{
+	Interval: 30 * time.Second,
+}
+
+// Enabled reports whether the node replicates its state in either role.
+func (c *Config) Enabled() bool {
+	return c.Listen != "" || c.Primary != ""
+}
+
+// Account is an account shared as a watch-only key.
+type Account struct {
+	Address common.AccountAddress `json:"address"`
+	Tk      common.AccountAddress `json:"tk"`
+}
+
+// Status is what the primary tells a standby about its state.
+type Status struct {
+	Tks      common.Hash `json:"tks"`      // digest of the accounts the state is scanned for
+	File     string      `json:"file"`     // newest state file on the canonical chain
+	Number   uint64      `json:"number"`   // block number of the state file
+	Accounts []Account   `json:"accounts"` // accounts of the primary, if shared
+}
+
+// Replica runs the replication of a node as a primary, a standby or both.
+type Replica struct {
+	config Config
+	tls    *tls.Config
+	am     *accounts.Manager
+
+	server *server
+	client *client
+}
+
+// New creates the replication of a node from its configuration.
+func New(config Config, am *accounts.Manager) (*Replica, error) {
+	if config.Cert == "" || config.Key == "" || config.CA == "" {
+		return nil, errors.New("replication needs a certificate, its key and the CA")
+	}
+	cert, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("replica certificate: %v", err)
+	}
+	ca, err := ioutil.ReadFile(config.CA)
+	if err != nil {
+		return nil, fmt.Errorf("replica CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("replica CA: no certificates found")
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultConfig.Interval
+	}
+	return &Replica{
+		config: config,
+		tls: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      pool,
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		},
+		am: am,
+	}, nil
+}
+
+// Start starts serving the state to standbys and syncing from the primary,
+// as configured.
+func (r *Replica) Start() error {
+	if r.config.Listen != "" {
+		srv, err := newServer(r.config.Listen, r.tls, r.am, r.config.Accounts)
+		if err != nil {
+			return err
+		}
+		r.server = srv
+	}
+	if r.config.Primary != "" {
+		r.client = newClient(r.config.Primary, r.tls, fetchKeystore(r.am), r.config.Accounts, r.config.Interval)
+	}
+	return nil
+}
+
+// Stop terminates the replication.
+func (r *Replica) Stop() {
+	if r.server != nil {
+		r.server.close()
+		r.server = nil
+	}
+	if r.client != nil {
+		r.client.close()
+		r.client = nil
+	}
+}
+
+func fetchKeystore(am *accounts.Manager) *keystore.KeyStore {
+	return am.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+)
+
+const (
+	statusPath = "/status"
+	statePath  = "/state"
+
+	// stateFileHeader carries the name of the state file served on statePath.
+	stateFileHeader = "X-Lstate-File"
+)
+
+// server serves the state of a primary to the standbys presenting a
+// certificate of the CA.
+type server struct {
+	listener net.Listener
+	am       *accounts.Manager
+	accounts bool
+}
+
+func newServer(addr string, config *tls.Config, am *accounts.Manager, shareAccounts bool) (*server, error) {
+	listener, err := tls.Listen("tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+	srv := &server{listener: listener, am: am, accounts: shareAccounts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(statusPath, srv.serveStatus)
+	mux.HandleFunc(statePath, srv.serveState)
+	go http.Serve(listener, mux)
+
+	log.Info("Replica serving lstate", "addr", listener.Addr(), "accounts", shareAccounts)
+	return srv, nil
+}
+
+func (srv *server) close() {
+	srv.listener.Close()
+}
+
+func (srv *server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	tks, err := lstate.TksDigest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	name, num, _, err := lstate.NewestStateFile()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	status := Status{Tks: tks, File: name, Number: num}
+	if srv.accounts {
+		for _, wallet := range srv.am.Wallets() {
+			for _, account := range wallet.Accounts() {
+				status.Accounts = append(status.Accounts, Account{Address: account.Address, Tk: account.Tk})
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&status)
+}
+
+func (srv *server) serveState(w http.ResponseWriter, r *http.Request) {
+	name, _, data, err := lstate.NewestStateFile()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	log.Debug("Replica serving lstate file", "file", name, "peer", r.RemoteAddr)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(stateFileHeader, name)
+	w.Write(data)
+}
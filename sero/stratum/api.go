@@ -0,0 +1,57 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stratum
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-sero/common/hexutil"
+)
+
+// WorkerStats are the statistics of a worker connected to the stratum server.
+type WorkerStats struct {
+	Login      string       `json:"login"`
+	Worker     string       `json:"worker"`
+	Remote     string       `json:"remote"`
+	Connected  time.Time    `json:"connected"`
+	Difficulty *hexutil.Big `json:"difficulty"` // current share difficulty
+
+	Accepted hexutil.Uint64 `json:"accepted"`
+	Rejected hexutil.Uint64 `json:"rejected"` // invalid or duplicate
+	Stale    hexutil.Uint64 `json:"stale"`
+	Blocks   hexutil.Uint64 `json:"blocks"` // sealed by an accepted share
+
+	Hashrate         hexutil.Uint64 `json:"hashrate"`         // estimated from the shares of the last retarget
+	ReportedHashrate hexutil.Uint64 `json:"reportedHashrate"` // submitted by the worker
+	LastShare        *time.Time     `json:"lastShare"`        // nil until a share is accepted
+}
+
+// PrivateStratumAPI serves the statistics of the stratum workers.
+type PrivateStratumAPI struct {
+	s *Server
+}
+
+// NewPrivateStratumAPI creates the API of the stratum server.
+func NewPrivateStratumAPI(s *Server) *PrivateStratumAPI {
+	return &PrivateStratumAPI{s}
+}
+
+// StratumWorkers returns the statistics of the workers connected to the
+// stratum server.
+func (api *PrivateStratumAPI) StratumWorkers() []*WorkerStats {
+	return api.s.Workers()
+}
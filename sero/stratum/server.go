@@ -0,0 +1,573 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stratum serves the work of the miner to ASIC and GPU miners over
+// the stratum protocol of the pool software, in its EthProxy dialect: newline
+// delimited JSON-RPC over TCP with eth_submitLogin, eth_getWork,
+// eth_submitWork and eth_submitHashrate, new jobs being pushed to the logged
+// in workers. The shares of every worker are checked against a difficulty
+// retargeted to the configured share time.
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/miner"
+)
+
+const (
+	maxLineSize    = 4096                   // Maximum size of a request of a worker
+	idleTimeout    = 5 * time.Minute        // Time after which a silent worker is disconnected
+	jobLifetime    = 7 * (12 * time.Second) // Time a job is kept for late shares, as by the remote agent
+	retargetShares = 8                      // Shares a retarget aims the window at
+)
+
+// Config are the settings of the stratum server.
+type Config struct {
+	Addr          string        `toml:",omitempty"` // Listening address, empty if disabled
+	Difficulty    uint64        `toml:",omitempty"` // Share difficulty the workers start with
+	MinDifficulty uint64        `toml:",omitempty"` // Lowest share difficulty of a retarget
+	ShareTime     time.Duration `toml:",omitempty"` // Time between two shares of a worker the retargets aim at
+}
+
+// DefaultConfig contains the default stratum settings.
+var DefaultConfig = Config{
+	Difficulty:    4000000000,
+	MinDifficulty: 100000000,
+	ShareTime:     10 * time.Second,
+}
+
+// Enabled reports whether the node serves its work over stratum.
+func (c *Config) Enabled() bool {
+	return c.Addr != ""
+}
+
+// Backend is the node mining the blocks of the served work.
+type Backend interface {
+	BlockChain() *core.BlockChain
+	Miner() *miner.Miner
+	IsMining() bool
+	StartMining(local bool) error
+}
+
+// headReader is the chain the shares are checked against, a job being stale
+// once a block of its number is imported.
+type headReader interface {
+	CurrentHeader() *types.Header
+}
+
+// shareVerifier is the engine checking the shares of the workers.
+type shareVerifier interface {
+	VerifyShare(header *types.Header, difficulty *big.Int) (bool, error)
+}
+
+// job is a work package of the miner handed out to the workers.
+type job struct {
+	work    *miner.Work
+	created time.Time
+	shares  map[types.BlockNonce]struct{} // submitted, to reject duplicates
+}
+
+// Server is the stratum endpoint of a node. It is registered with the miner
+// as an agent, receiving the work to mine and returning the sealed blocks.
+type Server struct {
+	config Config
+	b      Backend
+	chain  headReader
+	engine shareVerifier
+
+	listener net.Listener
+	sessions map[*session]struct{}
+	current  *job
+	jobs     map[common.Hash]*job
+	mu       sync.Mutex
+
+	workCh   chan *miner.Work
+	returnCh chan<- *miner.Result
+	quitCh   chan struct{}
+	running  int32
+}
+
+// New creates the stratum server of a node.
+func New(config Config, b Backend, engine *ethash.Ethash) *Server {
+	if config.Difficulty == 0 {
+		config.Difficulty = DefaultConfig.Difficulty
+	}
+	if config.MinDifficulty == 0 {
+		config.MinDifficulty = DefaultConfig.MinDifficulty
+	}
+	if config.ShareTime <= 0 {
+		config.ShareTime = DefaultConfig.ShareTime
+	}
+	return &Server{
+		config:   config,
+		b:        b,
+		chain:    b.BlockChain(),
+		engine:   engine,
+		sessions: make(map[*session]struct{}),
+		jobs:     make(map[common.Hash]*job),
+	}
+}
+
+// Open opens the stratum endpoint and registers the server with the miner.
+func (s *Server) Open() error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.b.Miner().Register(s)
+	go s.accept(listener)
+	log.Info("Stratum endpoint opened", "addr", listener.Addr())
+	return nil
+}
+
+// Close closes the stratum endpoint, disconnecting the workers.
+func (s *Server) Close() {
+	if s.listener == nil {
+		return
+	}
+	s.b.Miner().Unregister(s)
+	s.listener.Close()
+
+	s.mu.Lock()
+	for sess := range s.sessions {
+		sess.conn.Close()
+	}
+	s.mu.Unlock()
+	log.Info("Stratum endpoint closed", "addr", s.config.Addr)
+}
+
+// Work implements miner.Agent, returning the channel the work is sent to.
+func (s *Server) Work() chan<- *miner.Work {
+	return s.workCh
+}
+
+// SetReturnCh implements miner.Agent.
+func (s *Server) SetReturnCh(returnCh chan<- *miner.Result) {
+	s.returnCh = returnCh
+}
+
+// Start implements miner.Agent, serving the work of the miner.
+func (s *Server) Start() {
+	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		return
+	}
+	s.quitCh = make(chan struct{})
+	s.workCh = make(chan *miner.Work, 1)
+	go s.loop(s.workCh, s.quitCh)
+}
+
+// Stop implements miner.Agent.
+func (s *Server) Stop() {
+	if !atomic.CompareAndSwapInt32(&s.running, 1, 0) {
+		return
+	}
+	close(s.quitCh)
+	close(s.workCh)
+}
+
+// GetHashRate implements miner.Agent, returning the hashrate of the workers
+// estimated from their shares.
+func (s *Server) GetHashRate() (tot int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sess := range s.sessions {
+		tot += int64(sess.hashrate)
+	}
+	return
+}
+
+// loop hands the new work out to the workers and retargets their share
+// difficulty until the agent is stopped.
+func (s *Server) loop(workCh chan *miner.Work, quitCh chan struct{}) {
+	ticker := time.NewTicker(s.config.ShareTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quitCh:
+			return
+		case work, ok := <-workCh:
+			if !ok {
+				return
+			}
+			s.mu.Lock()
+			s.current = &job{work: work, created: time.Now(), shares: make(map[types.BlockNonce]struct{})}
+			s.jobs[work.Block.HashNoNonce()] = s.current
+			for sess := range s.sessions {
+				if sess.login != "" {
+					s.notify(sess)
+				}
+			}
+			s.mu.Unlock()
+		case <-ticker.C:
+			s.mu.Lock()
+			for hash, job := range s.jobs {
+				if time.Since(job.created) > jobLifetime {
+					delete(s.jobs, hash)
+				}
+			}
+			for sess := range s.sessions {
+				if s.retarget(sess) && sess.login != "" {
+					s.notify(sess)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// accept serves the workers connecting to the endpoint.
+func (s *Server) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		sess := &session{
+			conn:       conn,
+			enc:        json.NewEncoder(conn),
+			difficulty: new(big.Int).SetUint64(s.config.Difficulty),
+			connected:  time.Now(),
+			window:     time.Now(),
+			work:       new(big.Int),
+		}
+		s.mu.Lock()
+		s.sessions[sess] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serve(sess)
+	}
+}
+
+// serve answers the requests of a worker until it disconnects.
+func (s *Server) serve(sess *session) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.sessions, sess)
+		s.mu.Unlock()
+		sess.conn.Close()
+		log.Debug("Stratum worker disconnected", "worker", sess.name(), "remote", sess.conn.RemoteAddr())
+	}()
+
+	scanner := bufio.NewScanner(sess.conn)
+	scanner.Buffer(make([]byte, maxLineSize), maxLineSize)
+	for {
+		sess.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		if !scanner.Scan() {
+			return
+		}
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			sess.send(&response{Version: "2.0", Error: &rpcError{Code: -32700, Message: "parse error"}})
+			return
+		}
+		result, err := s.handle(sess, &req)
+		resp := &response{Id: req.Id, Version: "2.0", Result: result}
+		if err != nil {
+			resp.Result, resp.Error = nil, err
+		}
+		if sess.send(resp) != nil {
+			return
+		}
+	}
+}
+
+// handle executes a request of a worker.
+func (s *Server) handle(sess *session, req *request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "eth_submitLogin":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 || params[0] == "" {
+			return nil, &rpcError{Code: -32602, Message: "invalid login"}
+		}
+		if !s.b.IsMining() {
+			if err := s.b.StartMining(false); err != nil {
+				return nil, &rpcError{Code: -1, Message: err.Error()}
+			}
+		}
+		s.mu.Lock()
+		sess.login, sess.worker = params[0], req.Worker
+		s.mu.Unlock()
+		log.Info("Stratum worker logged in", "worker", sess.name(), "remote", sess.conn.RemoteAddr())
+		return true, nil
+
+	case "eth_getWork":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if sess.login == "" {
+			return nil, &rpcError{Code: -1, Message: "not logged in"}
+		}
+		if s.current == nil {
+			return nil, &rpcError{Code: 0, Message: "no work available yet"}
+		}
+		return s.workOf(sess), nil
+
+	case "eth_submitWork":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 3 {
+			return nil, &rpcError{Code: -32602, Message: "invalid share"}
+		}
+		var (
+			nonce types.BlockNonce
+			hash  common.Hash
+			mix   common.Hash
+		)
+		if nonce.UnmarshalText([]byte(params[0])) != nil || hash.UnmarshalText([]byte(params[1])) != nil || mix.UnmarshalText([]byte(params[2])) != nil {
+			return nil, &rpcError{Code: -32602, Message: "invalid share"}
+		}
+		return s.submit(sess, nonce, hash, mix), nil
+
+	case "eth_submitHashrate":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+			return nil, &rpcError{Code: -32602, Message: "invalid hashrate"}
+		}
+		rate, err := hexutil.DecodeUint64(params[0])
+		if err != nil {
+			return nil, &rpcError{Code: -32602, Message: "invalid hashrate"}
+		}
+		s.mu.Lock()
+		sess.reported = rate
+		s.mu.Unlock()
+		return true, nil
+	}
+	return nil, &rpcError{Code: -32601, Message: fmt.Sprintf("the method %s does not exist", req.Method)}
+}
+
+// submit checks a share of a worker, returning the block to the miner if the
+// share seals it. The share is verified without holding the server lock.
+func (s *Server) submit(sess *session, nonce types.BlockNonce, hash common.Hash, mix common.Hash) bool {
+	s.mu.Lock()
+	if sess.login == "" {
+		s.mu.Unlock()
+		return false
+	}
+	job := s.jobs[hash]
+	if job == nil || job.work.Block.NumberU64() <= s.chain.CurrentHeader().Number.Uint64() {
+		sess.stale++
+		s.mu.Unlock()
+		log.Debug("Stale stratum share", "worker", sess.name(), "hash", hash)
+		return false
+	}
+	if _, ok := job.shares[nonce]; ok {
+		sess.rejected++
+		s.mu.Unlock()
+		log.Debug("Duplicate stratum share", "worker", sess.name(), "hash", hash, "nonce", nonce)
+		return false
+	}
+	difficulty := sess.difficulty
+	s.mu.Unlock()
+
+	header := job.work.Block.Header()
+	header.Nonce = nonce
+	header.MixDigest = mix
+	sealed, err := s.engine.VerifyShare(header, difficulty)
+
+	s.mu.Lock()
+	if err != nil {
+		sess.rejected++
+		s.mu.Unlock()
+		log.Debug("Invalid stratum share", "worker", sess.name(), "hash", hash, "err", err)
+		return false
+	}
+	// The same share may have been submitted while it was verified
+	if _, ok := job.shares[nonce]; ok {
+		sess.rejected++
+		s.mu.Unlock()
+		log.Debug("Duplicate stratum share", "worker", sess.name(), "hash", hash, "nonce", nonce)
+		return false
+	}
+	job.shares[nonce] = struct{}{}
+	sess.accepted++
+	sess.shares++
+	sess.lastShare = time.Now()
+	sess.work.Add(sess.work, difficulty)
+
+	var result *miner.Result
+	if sealed && s.jobs[hash] == job {
+		sess.blocks++
+		result = &miner.Result{Work: job.work, Block: job.work.Block.WithSeal(header)}
+		delete(s.jobs, hash)
+	}
+	s.mu.Unlock()
+
+	if result != nil {
+		log.Info("Stratum worker sealed a block", "worker", sess.name(), "number", result.Block.Number(), "hash", result.Block.Hash())
+		s.returnCh <- result
+	}
+	return true
+}
+
+// workOf returns the current job with the share target of a worker, in the
+// format of sero_getWork. The server lock is held.
+func (s *Server) workOf(sess *session) [4]string {
+	block := s.current.work.Block
+
+	var res [4]string
+	res[0] = block.HashNoNonce().Hex()
+	res[1] = common.BytesToHash(ethash.SeedHash(block.NumberU64())).Hex()
+	n := big.NewInt(1)
+	n.Lsh(n, 255)
+	n.Div(n, sess.difficulty)
+	n.Lsh(n, 1)
+	res[2] = common.BytesToHash(n.Bytes()).Hex()
+	res[3] = block.Number().String()
+	return res
+}
+
+// notify pushes the current job to a worker. The server lock is held.
+func (s *Server) notify(sess *session) {
+	if s.current == nil {
+		return
+	}
+	go sess.send(&response{Id: json.RawMessage("0"), Version: "2.0", Result: s.workOf(sess)})
+}
+
+// retarget adjusts the share difficulty of a worker once its window spans the
+// time of retargetShares shares, so that it submits a share every ShareTime.
+// The difficulty changes by four times at most, staying between the minimum
+// and the difficulty of the block. The server lock is held.
+func (s *Server) retarget(sess *session) bool {
+	elapsed := time.Since(sess.window)
+	if elapsed < s.config.ShareTime*retargetShares {
+		return false
+	}
+	sess.hashrate = new(big.Int).Div(sess.work, big.NewInt(int64(elapsed/time.Second))).Uint64()
+
+	old := sess.difficulty
+	next := new(big.Int).Mul(old, big.NewInt(int64(sess.shares)*int64(s.config.ShareTime)))
+	next.Div(next, big.NewInt(int64(elapsed)))
+	if low := new(big.Int).Div(old, big.NewInt(4)); next.Cmp(low) < 0 {
+		next = low
+	}
+	if high := new(big.Int).Mul(old, big.NewInt(4)); next.Cmp(high) > 0 {
+		next = high
+	}
+	if min := new(big.Int).SetUint64(s.config.MinDifficulty); next.Cmp(min) < 0 {
+		next = min
+	}
+	if s.current != nil {
+		if max := s.current.work.Block.Header().ActualDifficulty(); next.Cmp(max) > 0 {
+			next = max
+		}
+	}
+	sess.difficulty, sess.window, sess.shares, sess.work = next, time.Now(), 0, new(big.Int)
+	return next.Cmp(old) != 0
+}
+
+// Workers returns the statistics of the connected workers.
+func (s *Server) Workers() []*WorkerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workers := make([]*WorkerStats, 0, len(s.sessions))
+	for sess := range s.sessions {
+		stats := &WorkerStats{
+			Login:            sess.login,
+			Worker:           sess.worker,
+			Remote:           sess.conn.RemoteAddr().String(),
+			Connected:        sess.connected,
+			Difficulty:       (*hexutil.Big)(new(big.Int).Set(sess.difficulty)),
+			Accepted:         hexutil.Uint64(sess.accepted),
+			Rejected:         hexutil.Uint64(sess.rejected),
+			Stale:            hexutil.Uint64(sess.stale),
+			Blocks:           hexutil.Uint64(sess.blocks),
+			Hashrate:         hexutil.Uint64(sess.hashrate),
+			ReportedHashrate: hexutil.Uint64(sess.reported),
+		}
+		if !sess.lastShare.IsZero() {
+			last := sess.lastShare
+			stats.LastShare = &last
+		}
+		workers = append(workers, stats)
+	}
+	return workers
+}
+
+// session is the connection of a worker.
+type session struct {
+	conn  net.Conn
+	enc   *json.Encoder
+	encMu sync.Mutex
+
+	// Guarded by the server lock
+	login      string
+	worker     string
+	connected  time.Time
+	difficulty *big.Int // share difficulty
+	window     time.Time
+	shares     uint64   // accepted in the window
+	work       *big.Int // difficulty of the shares accepted in the window
+	hashrate   uint64   // estimated over the last window
+	reported   uint64   // submitted by the worker
+	accepted   uint64
+	rejected   uint64
+	stale      uint64
+	blocks     uint64
+	lastShare  time.Time
+}
+
+// name returns the name of a worker in the logs.
+func (sess *session) name() string {
+	if sess.worker != "" {
+		return sess.login + "." + sess.worker
+	}
+	return sess.login
+}
+
+// send writes a message to a worker.
+func (sess *session) send(msg *response) error {
+	sess.encMu.Lock()
+	defer sess.encMu.Unlock()
+
+	sess.conn.SetWriteDeadline(time.Now().Add(idleTimeout))
+	return sess.enc.Encode(msg)
+}
+
+type request struct {
+	Id     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Worker string          `json:"worker"`
+}
+
+type response struct {
+	Id      json.RawMessage `json:"id"`
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stratum
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/miner"
+)
+
+// testChain is a chain whose head is at number.
+type testChain struct {
+	number uint64
+}
+
+func (c *testChain) CurrentHeader() *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(c.number)}
+}
+
+// testVerifier accepts the shares of the nonces it is given, sealing the block
+// with the ones in sealing.
+type testVerifier struct {
+	valid   map[types.BlockNonce]bool
+	sealing map[types.BlockNonce]bool
+}
+
+func (v *testVerifier) VerifyShare(header *types.Header, difficulty *big.Int) (bool, error) {
+	if !v.valid[header.Nonce] {
+		return false, errors.New("invalid share")
+	}
+	return v.sealing[header.Nonce], nil
+}
+
+// newTestServer creates a server serving a job of block number on a chain
+// whose head is its parent, returning the hash of the job.
+func newTestServer(number uint64, verifier *testVerifier) (*Server, common.Hash, chan *miner.Result) {
+	results := make(chan *miner.Result, 1)
+	s := &Server{
+		config:   Config{Difficulty: 1000, MinDifficulty: 100, ShareTime: time.Second},
+		chain:    &testChain{number: number - 1},
+		engine:   verifier,
+		sessions: make(map[*session]struct{}),
+		jobs:     make(map[common.Hash]*job),
+		returnCh: results,
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number), Difficulty: big.NewInt(1000000)})
+	s.current = &job{work: &miner.Work{Block: block}, created: time.Now(), shares: make(map[types.BlockNonce]struct{})}
+	s.jobs[block.HashNoNonce()] = s.current
+	return s, block.HashNoNonce(), results
+}
+
+// newTestSession creates a logged in worker of difficulty.
+func newTestSession(difficulty int64) *session {
+	return &session{
+		login:      "miner",
+		difficulty: big.NewInt(difficulty),
+		connected:  time.Now(),
+		window:     time.Now(),
+		work:       new(big.Int),
+	}
+}
+
+func TestSubmitShares(t *testing.T) {
+	verifier := &testVerifier{
+		valid:   map[types.BlockNonce]bool{{1}: true, {2}: true},
+		sealing: map[types.BlockNonce]bool{{2}: true},
+	}
+	s, hash, results := newTestServer(10, verifier)
+	sess := newTestSession(1000)
+
+	if !s.submit(sess, types.BlockNonce{1}, hash, common.Hash{}) {
+		t.Fatalf("valid share rejected")
+	}
+	// A share submitted twice only counts once
+	if s.submit(sess, types.BlockNonce{1}, hash, common.Hash{}) {
+		t.Errorf("duplicate share accepted")
+	}
+	// as does an invalid one
+	if s.submit(sess, types.BlockNonce{3}, hash, common.Hash{}) {
+		t.Errorf("invalid share accepted")
+	}
+	if sess.accepted != 1 || sess.rejected != 2 || sess.work.Int64() != 1000 {
+		t.Errorf("share stats mismatch: have %d accepted, %d rejected, %v work, want 1, 2, 1000", sess.accepted, sess.rejected, sess.work)
+	}
+	// A share sealing the block returns it to the miner and retires the job
+	if !s.submit(sess, types.BlockNonce{2}, hash, common.Hash{}) {
+		t.Fatalf("sealing share rejected")
+	}
+	select {
+	case result := <-results:
+		if result.Block.Nonce() != (types.BlockNonce{2}).Uint64() {
+			t.Errorf("sealed block nonce mismatch: have %d, want %d", result.Block.Nonce(), (types.BlockNonce{2}).Uint64())
+		}
+	default:
+		t.Fatalf("sealed block not returned")
+	}
+	if sess.blocks != 1 || s.jobs[hash] != nil {
+		t.Errorf("sealed job not retired")
+	}
+}
+
+func TestSubmitStaleShares(t *testing.T) {
+	verifier := &testVerifier{valid: map[types.BlockNonce]bool{{1}: true}}
+	s, hash, _ := newTestServer(10, verifier)
+	sess := newTestSession(1000)
+
+	// Shares of unknown jobs are stale
+	if s.submit(sess, types.BlockNonce{1}, common.Hash{1}, common.Hash{}) {
+		t.Errorf("share of unknown job accepted")
+	}
+	// as are those of the jobs whose block was imported meanwhile
+	s.chain.(*testChain).number = 10
+	if s.submit(sess, types.BlockNonce{1}, hash, common.Hash{}) {
+		t.Errorf("share of imported block accepted")
+	}
+	if sess.stale != 2 || sess.accepted != 0 {
+		t.Errorf("share stats mismatch: have %d stale, %d accepted, want 2, 0", sess.stale, sess.accepted)
+	}
+	// Shares of workers not logged in are dropped
+	s.chain.(*testChain).number = 9
+	sess.login = ""
+	if s.submit(sess, types.BlockNonce{1}, hash, common.Hash{}) {
+		t.Errorf("share of anonymous worker accepted")
+	}
+}
+
+func TestRetarget(t *testing.T) {
+	s, _, _ := newTestServer(10, &testVerifier{})
+	window := s.config.ShareTime * retargetShares
+
+	tests := []struct {
+		shares uint64
+		want   int64
+	}{
+		{retargetShares, 1000},     // on target
+		{retargetShares * 2, 2000}, // twice too many shares
+		{retargetShares * 9, 4000}, // raised four times at most
+		{retargetShares / 2, 500},  // twice too few shares
+		{0, 250},                   // lowered four times at most
+	}
+	for i, tt := range tests {
+		sess := newTestSession(1000)
+		sess.window, sess.shares = time.Now().Add(-window), tt.shares
+		s.retarget(sess)
+		// The elapsed time slightly exceeds the window, rounding the
+		// difficulty down
+		if have := sess.difficulty.Int64(); have > tt.want || have < tt.want*99/100 {
+			t.Errorf("test %d: difficulty mismatch: have %d, want %d", i, have, tt.want)
+		}
+		if sess.shares != 0 || sess.work.Sign() != 0 {
+			t.Errorf("test %d: window not reset", i)
+		}
+	}
+	// The difficulty stays above the minimum
+	sess := newTestSession(200)
+	sess.window = time.Now().Add(-window)
+	if !s.retarget(sess) || sess.difficulty.Int64() != 100 {
+		t.Errorf("difficulty mismatch: have %v, want 100", sess.difficulty)
+	}
+	// A window not yet elapsed keeps the difficulty
+	sess = newTestSession(1000)
+	if s.retarget(sess) || sess.difficulty.Int64() != 1000 {
+		t.Errorf("difficulty retargeted before the window elapsed")
+	}
+}
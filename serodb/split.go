@@ -0,0 +1,293 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package serodb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+// ColdTable is a table of block data a SplitDatabase can keep in the cold
+// database. Its keys are the prefix followed by the big endian block number.
+type ColdTable struct {
+	Name       string
+	Prefix     []byte
+	KeyLengths []int // lengths of the keys of the table, telling them from other keys with the prefix
+}
+
+// match reports whether the key belongs to the table.
+func (t *ColdTable) match(key []byte) bool {
+	if !bytes.HasPrefix(key, t.Prefix) {
+		return false
+	}
+	for _, length := range t.KeyLengths {
+		if len(key) == length {
+			return true
+		}
+	}
+	return false
+}
+
+// number returns the block number of a key of the table.
+func (t *ColdTable) number(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(t.Prefix) : len(t.Prefix)+8])
+}
+
+// SplitDatabase keeps the recent chain data in a hot database and moves the
+// tables of old blocks to a cold database, typically on a separate cheaper
+// volume. Writes always go to the hot database; Migrate moves the keys between
+// the two, so the tables kept cold can be changed on a running database.
+type SplitDatabase struct {
+	hot, cold *LDBDatabase
+	tables    []*ColdTable
+	moved     map[string]bool // names of the tables kept in the cold database
+
+	lock sync.RWMutex // held for writing while keys are moved between the databases
+}
+
+// NewSplitDatabase returns a database keeping the named tables of blocks in
+// the cold database. Keys of all the tables are looked up in both databases,
+// so the data of tables no longer kept cold is found until migrated back.
+func NewSplitDatabase(hot, cold *LDBDatabase, tables []ColdTable, moved []string) (*SplitDatabase, error) {
+	db := &SplitDatabase{hot: hot, cold: cold, moved: make(map[string]bool)}
+	for i := range tables {
+		db.tables = append(db.tables, &tables[i])
+	}
+	for _, name := range moved {
+		if db.tableNamed(name) == nil {
+			return nil, fmt.Errorf("unknown cold table %q", name)
+		}
+		db.moved[name] = true
+	}
+	return db, nil
+}
+
+func (db *SplitDatabase) tableNamed(name string) *ColdTable {
+	for _, table := range db.tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// table returns the table of a key, nil if it is never kept cold.
+func (db *SplitDatabase) table(key []byte) *ColdTable {
+	for _, table := range db.tables {
+		if table.match(key) {
+			return table
+		}
+	}
+	return nil
+}
+
+// Hot returns the database of the recent data.
+func (db *SplitDatabase) Hot() *LDBDatabase {
+	return db.hot
+}
+
+// Cold returns the database of the old blocks.
+func (db *SplitDatabase) Cold() *LDBDatabase {
+	return db.cold
+}
+
+// Path returns the path to the hot database directory.
+func (db *SplitDatabase) Path() string {
+	return db.hot.Path()
+}
+
+// LDB returns the hot LevelDB instance, holding all but the cold tables.
+func (db *SplitDatabase) LDB() *leveldb.DB {
+	return db.hot.LDB()
+}
+
+// Meter configures the metrics collectors of both databases.
+func (db *SplitDatabase) Meter(prefix string) {
+	db.hot.Meter(prefix)
+	db.cold.Meter(prefix + "cold/")
+}
+
+func (db *SplitDatabase) Put(key []byte, value []byte) error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	return db.hot.Put(key, value)
+}
+
+func (db *SplitDatabase) Has(key []byte) (bool, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if ok, err := db.hot.Has(key); ok || err != nil || db.table(key) == nil {
+		return ok, err
+	}
+	return db.cold.Has(key)
+}
+
+func (db *SplitDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	dat, err := db.hot.Get(key)
+	if err == errors.ErrNotFound && db.table(key) != nil {
+		return db.cold.Get(key)
+	}
+	return dat, err
+}
+
+func (db *SplitDatabase) Delete(key []byte) error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if err := db.hot.Delete(key); err != nil {
+		return err
+	}
+	if db.table(key) != nil {
+		return db.cold.Delete(key)
+	}
+	return nil
+}
+
+func (db *SplitDatabase) Close() {
+	db.hot.Close()
+	db.cold.Close()
+}
+
+func (db *SplitDatabase) NewBatch() Batch {
+	return &splitBatch{db: db, hot: db.hot.NewBatch(), cold: db.cold.NewBatch()}
+}
+
+// Migrate moves at most max keys of the blocks below limit of the tables kept
+// cold to the cold database, and the keys of the other tables back to the hot
+// one. It returns the number of keys moved, zero once the databases are in
+// place.
+func (db *SplitDatabase) Migrate(limit uint64, max int) (int, error) {
+	moved := 0
+	for _, table := range db.tables {
+		if moved >= max {
+			break
+		}
+		var (
+			n   int
+			err error
+		)
+		if db.moved[table.Name] {
+			n, err = db.move(db.hot, db.cold, table, limit, max-moved)
+		} else {
+			n, err = db.move(db.cold, db.hot, table, ^uint64(0), max-moved)
+		}
+		if err != nil {
+			return moved, err
+		}
+		moved += n
+	}
+	return moved, nil
+}
+
+// move moves at most max keys of the table below limit from src to dst.
+func (db *SplitDatabase) move(src, dst *LDBDatabase, table *ColdTable, limit uint64, max int) (int, error) {
+	var keys [][]byte
+
+	it := src.NewIteratorWithPrefix(table.Prefix)
+	for it.Next() && len(keys) < max {
+		key := it.Key()
+		if !table.match(key) {
+			continue
+		}
+		if table.number(key) >= limit {
+			break
+		}
+		keys = append(keys, append([]byte(nil), key...))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	// Keys deleted since the iteration must not be moved back into existence
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	put, del, n := dst.NewBatch(), src.NewBatch(), 0
+	for _, key := range keys {
+		value, err := src.Get(key)
+		if err == errors.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		put.Put(key, value)
+		del.Delete(key)
+		n++
+	}
+	if err := put.Write(); err != nil {
+		return 0, err
+	}
+	if err := del.Write(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// splitBatch writes to the hot database, deleting the keys of the cold tables
+// from both.
+type splitBatch struct {
+	db        *SplitDatabase
+	hot, cold Batch
+}
+
+func (b *splitBatch) Put(key, value []byte) error {
+	return b.hot.Put(key, value)
+}
+
+func (b *splitBatch) Delete(key []byte) error {
+	if err := b.hot.Delete(key); err != nil {
+		return err
+	}
+	if b.db.table(key) != nil {
+		return b.cold.Delete(key)
+	}
+	return nil
+}
+
+func (b *splitBatch) Write() error {
+	b.db.lock.RLock()
+	defer b.db.lock.RUnlock()
+
+	if b.cold.ValueSize() > 0 {
+		if err := b.cold.Write(); err != nil {
+			return err
+		}
+	}
+	return b.hot.Write()
+}
+
+func (b *splitBatch) ValueSize() int {
+	return b.hot.ValueSize() + b.cold.ValueSize()
+}
+
+func (b *splitBatch) Reset() {
+	b.hot.Reset()
+	b.cold.Reset()
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+// Stats summarizes the shape of a trie.
+type Stats struct {
+	Nodes  uint64             `json:"nodes"`  // number of short and full nodes, embedded ones included
+	Stored uint64             `json:"stored"` // number of nodes stored by their hash
+	Leaves uint64             `json:"leaves"` // number of values
+	Size   common.StorageSize `json:"size"`   // encoded size of the stored nodes
+	Depths []uint64           `json:"depths"` // number of values per depth, the root being at depth zero
+}
+
+// Stats walks the whole trie, resolving every node from the database, and
+// returns its statistics.
+func (t *Trie) Stats() (*Stats, error) {
+	stats := new(Stats)
+	if err := t.stats(t.root, nil, 0, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (t *Trie) stats(n node, prefix []byte, depth int, stats *Stats) error {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case valueNode:
+		stats.Leaves++
+		for len(stats.Depths) <= depth {
+			stats.Depths = append(stats.Depths, 0)
+		}
+		stats.Depths[depth]++
+		return nil
+	case *shortNode:
+		stats.Nodes++
+		return t.stats(n.Val, append(prefix, n.Key...), depth+1, stats)
+	case *fullNode:
+		stats.Nodes++
+		for i, child := range &n.Children {
+			if err := t.stats(child, append(prefix, byte(i)), depth+1, stats); err != nil {
+				return err
+			}
+		}
+		return nil
+	case hashNode:
+		blob, err := t.db.Node(common.BytesToHash(n))
+		if err != nil {
+			return &MissingNodeError{NodeHash: common.BytesToHash(n), Path: prefix}
+		}
+		stats.Stored++
+		stats.Size += common.StorageSize(len(blob))
+
+		child, err := t.resolveHash(n, prefix)
+		if err != nil {
+			return err
+		}
+		return t.stats(child, prefix, depth, stats)
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
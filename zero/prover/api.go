@@ -0,0 +1,100 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package prover runs the proof generation of transactions in a process of
+// its own, separate from the node. The node sends the descriptions of the
+// transactions it generates to the prover over the RPC transports of the node
+// (IPC, HTTP or WebSocket) and receives them with their proofs filled in, so
+// that the memory of the proving parameters is held by the prover only and
+// provers can be scaled apart from nodes.
+//
+// The descriptions carry the spending seeds of the accounts whose outs they
+// spend and create: the host of the prover, and anyone able to read the
+// connection to it or to call it, can spend the outs of the accounts of the
+// node. The endpoints are therefore limited to IPC and loopback addresses, both
+// by the node and by the prover, unless the operator explicitly allows remote
+// ones, for a prover on a trusted host reached over a trusted network.
+package prover
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/zero/txs/generate"
+)
+
+// Namespace is the RPC namespace of the prover API.
+const Namespace = "prover"
+
+// API is the RPC API of a prover process, generating the proofs with its
+// linked czero library.
+type API struct {
+	slots chan struct{} // proofs generated at the same time
+}
+
+// NewAPI creates the API generating at most threads proofs at the same time,
+// further requests wait for a slot.
+func NewAPI(threads int) *API {
+	if threads < 1 {
+		threads = 1
+	}
+	return &API{slots: make(chan struct{}, threads)}
+}
+
+// Status returns whether the linked czero library generates proofs the
+// network accepts. Nodes check it when they connect.
+func (api *API) Status() generate.ProverStatus {
+	return generate.GetProverStatus()
+}
+
+// ProvePkg generates the proof of the description of a package creation.
+func (api *API) ProvePkg(ctx context.Context, desc cpt.PkgDesc) (*cpt.PkgDesc, error) {
+	if err := api.prove(ctx, func() error { return cpt.GenPkgProof(&desc) }); err != nil {
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// ProveInput generates the proof of the description of a shielded input.
+func (api *API) ProveInput(ctx context.Context, desc cpt.InputDesc) (*cpt.InputDesc, error) {
+	if err := api.prove(ctx, func() error { return cpt.GenInputProof(&desc) }); err != nil {
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// ProveOutput generates the proof of the description of a shielded output.
+func (api *API) ProveOutput(ctx context.Context, desc cpt.OutputDesc) (*cpt.OutputDesc, error) {
+	if err := api.prove(ctx, func() error { return cpt.GenOutputProof(&desc) }); err != nil {
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// prove runs gen once a slot is free, unless the request is given up before.
+func (api *API) prove(ctx context.Context, gen func() error) error {
+	if status := generate.GetProverStatus(); !status.Compatible {
+		return errors.New("prover disabled: " + status.Reason)
+	}
+	select {
+	case api.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-api.slots }()
+	return gen()
+}
@@ -0,0 +1,156 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package prover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/generate"
+)
+
+// Timeout is the time a proof may take the prover, including the wait for
+// one of its slots.
+const Timeout = 2 * time.Minute
+
+// Client is a generate.Prover sending the descriptions to a prover process.
+// It connects on the first proof and again after the connection failed.
+type Client struct {
+	endpoint string
+
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+// NewClient creates the client of the prover at endpoint, an IPC path or an
+// HTTP or WebSocket URL. Endpoints not on this host are refused unless remote
+// is set, as the descriptions sent carry the spending seeds of the accounts.
+func NewClient(endpoint string, remote bool) (*Client, error) {
+	if !remote && !IsLocal(endpoint) {
+		return nil, fmt.Errorf("prover %s is not on this host, it would see the spending seeds of the accounts", endpoint)
+	}
+	return &Client{endpoint: endpoint}, nil
+}
+
+// IsLocal reports whether an endpoint of the prover is on this host: an IPC
+// path, or an HTTP or WebSocket URL of a loopback address.
+func IsLocal(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "":
+		return true
+	case "http", "https", "ws", "wss":
+		return IsLoopback(u.Hostname())
+	}
+	return false
+}
+
+// IsLoopback reports whether host, a name or an IP address, is a loopback
+// address. Empty hosts, meaning all the interfaces, are not.
+func IsLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Status returns the status of the prover.
+func (c *Client) Status(ctx context.Context) (*generate.ProverStatus, error) {
+	status := new(generate.ProverStatus)
+	if err := c.call(ctx, status, "status"); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GenPkgProof implements generate.Prover.
+func (c *Client) GenPkgProof(desc *cpt.PkgDesc) error {
+	return c.prove(desc, "provePkg", *desc)
+}
+
+// GenInputProof implements generate.Prover.
+func (c *Client) GenInputProof(desc *cpt.InputDesc) error {
+	return c.prove(desc, "proveInput", *desc)
+}
+
+// GenOutputProof implements generate.Prover.
+func (c *Client) GenOutputProof(desc *cpt.OutputDesc) error {
+	return c.prove(desc, "proveOutput", *desc)
+}
+
+// prove sends desc and decodes the description returned into result.
+func (c *Client) prove(result interface{}, method string, desc interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.call(ctx, result, method, desc); err != nil {
+		return err
+	}
+	log.Debug("Proof generated by prover", "endpoint", c.endpoint, "method", method, "elapsed", time.Since(start))
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	client, err := c.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("prover %s: %v", c.endpoint, err)
+	}
+	if err := client.CallContext(ctx, result, Namespace+"_"+method, args...); err != nil {
+		if _, ok := err.(rpc.Error); !ok {
+			// Not an error of the prover, connect again next time
+			c.drop(client)
+		}
+		return fmt.Errorf("prover %s: %v", c.endpoint, err)
+	}
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) (*rpc.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		client, err := rpc.DialContext(ctx, c.endpoint)
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	return c.client, nil
+}
+
+func (c *Client) drop(client *rpc.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == client {
+		c.client.Close()
+		c.client = nil
+	}
+}
@@ -0,0 +1,174 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/tx"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/pkgstate"
+)
+
+// genState is the view of the state the generation of a transaction reads
+// its inputs, their witnesses and the packages from.
+type genState interface {
+	getOut(root *keys.Uint256) (*lstate.OutState, error)
+	getPkg(id *keys.Uint256) *pkgstate.ZPkg
+	getPaths(rootCM keys.Uint256) (pos uint64, paths []keys.Uint256, anchor keys.Uint256)
+	num() uint64
+}
+
+// localState reads from the local out database of the node.
+type localState struct {
+	st *lstate.State
+}
+
+func (self localState) getOut(root *keys.Uint256) (*lstate.OutState, error) {
+	return self.st.GetOut(root)
+}
+
+func (self localState) getPkg(id *keys.Uint256) *pkgstate.ZPkg {
+	return self.st.State.Pkgs.GetPkg(id)
+}
+
+func (self localState) getPaths(rootCM keys.Uint256) (uint64, []keys.Uint256, keys.Uint256) {
+	pos, paths, anchor := self.st.State.State.MTree.GetPaths(rootCM)
+	return pos, paths[:], anchor
+}
+
+func (self localState) num() uint64 {
+	return self.st.State.Num()
+}
+
+// ParamIn is an input of a transaction with the witness of its commitment.
+type ParamIn struct {
+	Out    lstate.OutState
+	Pos    uint64
+	Paths  []keys.Uint256
+	Anchor keys.Uint256
+}
+
+// TxParam is everything needed to generate a transaction without the chain:
+// the transaction with its inputs selected, the inputs with their witnesses
+// and the packages it closes or transfers. It is produced by a node tracking
+// the account and signed where the seed is kept.
+type TxParam struct {
+	Tx   tx.T
+	Num  uint64
+	Ins  []ParamIn
+	Pkgs []pkgstate.ZPkg
+}
+
+func (self *TxParam) getOut(root *keys.Uint256) (*lstate.OutState, error) {
+	for i := range self.Ins {
+		if self.Ins[i].Out.Root == *root {
+			return &self.Ins[i].Out, nil
+		}
+	}
+	return nil, fmt.Errorf("input %x not in the transaction param", root[:])
+}
+
+func (self *TxParam) getPkg(id *keys.Uint256) *pkgstate.ZPkg {
+	for i := range self.Pkgs {
+		if self.Pkgs[i].Pack.Id == *id {
+			return &self.Pkgs[i]
+		}
+	}
+	return nil
+}
+
+func (self *TxParam) getPaths(rootCM keys.Uint256) (uint64, []keys.Uint256, keys.Uint256) {
+	for _, in := range self.Ins {
+		if in.Out.RootCM == rootCM {
+			return in.Pos, in.Paths, in.Anchor
+		}
+	}
+	return 0, nil, keys.Uint256{}
+}
+
+func (self *TxParam) num() uint64 {
+	return self.Num
+}
+
+// Serial encodes the param for the transfer to the signing side.
+func (self *TxParam) Serial() ([]byte, error) {
+	return rlp.EncodeToBytes(self)
+}
+
+// UnserialTxParam decodes a param encoded by Serial.
+func UnserialTxParam(data []byte) (*TxParam, error) {
+	param := new(TxParam)
+	if err := rlp.DecodeBytes(data, param); err != nil {
+		return nil, err
+	}
+	return param, nil
+}
+
+// GenTxParam collects the inputs of t, their witnesses and the packages it
+// uses from the local out database, the inputs of t must be selected.
+func GenTxParam(t *tx.T) (*TxParam, error) {
+	st := lstate.CurrentState1()
+	if st == nil {
+		return nil, errors.New("lstate is not ready")
+	}
+	local := localState{st}
+	param := &TxParam{Tx: *t, Num: local.num()}
+	for _, in := range t.Ins {
+		out, err := local.getOut(&in.Root)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			return nil, fmt.Errorf("input %x not found", in.Root[:])
+		}
+		pin := ParamIn{Out: *out}
+		pin.Pos, pin.Paths, pin.Anchor = local.getPaths(out.RootCM)
+		param.Ins = append(param.Ins, pin)
+	}
+	for _, id := range pkgIds(t) {
+		zpkg := local.getPkg(id)
+		if zpkg == nil {
+			return nil, fmt.Errorf("package %x not found", id[:])
+		}
+		param.Pkgs = append(param.Pkgs, *zpkg)
+	}
+	return param, nil
+}
+
+func pkgIds(t *tx.T) (ids []*keys.Uint256) {
+	if t.PkgClose != nil {
+		ids = append(ids, &t.PkgClose.Id)
+	}
+	if t.PkgTransfer != nil {
+		ids = append(ids, &t.PkgTransfer.Id)
+	}
+	return
+}
+
+// SignTxParam generates the transaction of param with the seed of the
+// sender, it needs neither the chain nor the keystore.
+func SignTxParam(param *TxParam, seed *keys.Uint256) (s stx.T, e error) {
+	params.mu.RLock()
+	defer params.mu.RUnlock()
+	if e = generationError(); e != nil {
+		return
+	}
+	t := param.Tx
+	if ctx, err := prepareCtx(param, seed, nil, &t); err != nil {
+		e = err
+		return
+	} else {
+		ctx.setData()
+		if e = ctx.proveTx(); e != nil {
+			return
+		}
+		if e = ctx.signTx(); e != nil {
+			return
+		}
+		s = ctx.s
+		return
+	}
+}
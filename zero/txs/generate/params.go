@@ -0,0 +1,196 @@
+package generate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/log"
+)
+
+// ParamsManifest is the file of the parameter directory listing the known-good
+// SHA-256 hashes of the parameter files, in the format of sha256sum. A
+// "# version <v>" line names the version of the parameters.
+const ParamsManifest = "SHA256SUMS"
+
+// ParamFile is a proving or verifying parameter file of the prover.
+type ParamFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// ProvingKeyInfo describes the zero-knowledge parameters in use.
+type ProvingKeyInfo struct {
+	Dir      string      `json:"dir"`
+	Version  string      `json:"version,omitempty"`
+	Files    []ParamFile `json:"files"`
+	Verified bool        `json:"verified"` // the files match the known-good hashes
+	LoadedAt int64       `json:"loadedAt"` // unix time of the last (re)load
+	Error    string      `json:"error,omitempty"`
+}
+
+var params struct {
+	mu   sync.RWMutex // held for writing while the parameters are reloaded
+	dir  string
+	load func() error
+	info ProvingKeyInfo
+}
+
+// SetParams registers the directory of the parameters and the function
+// (re)loading them into the library, and verifies them. Parameters are not
+// verified if dir is empty.
+func SetParams(dir string, load func() error) error {
+	params.mu.Lock()
+	defer params.mu.Unlock()
+
+	params.dir, params.load = dir, load
+	info, err := verifyParams(dir)
+	params.info = *info
+	params.info.LoadedAt = time.Now().Unix()
+	if err != nil {
+		params.info.Error = err.Error()
+		disableProver(fmt.Errorf("zero-knowledge parameters: %v", err))
+	}
+	return err
+}
+
+// GetProvingKeyInfo returns the description of the parameters in use.
+func GetProvingKeyInfo() ProvingKeyInfo {
+	params.mu.RLock()
+	defer params.mu.RUnlock()
+	return params.info
+}
+
+// ReloadParams verifies the parameter files, replaced in their directory, and
+// loads them into the library. The parameters in use are kept if they do not
+// match the known-good hashes. The generation of transactions waits for the
+// reload, and is enabled again if the library passes its self test.
+func ReloadParams() (ProvingKeyInfo, error) {
+	params.mu.Lock()
+	defer params.mu.Unlock()
+
+	if params.dir == "" || params.load == nil {
+		return params.info, errors.New("no zero-knowledge parameter directory configured")
+	}
+	info, err := verifyParams(params.dir)
+	if err != nil {
+		return *info, err
+	}
+	if err := params.load(); err != nil {
+		info.Error = err.Error()
+		params.info = *info
+		disableProver(fmt.Errorf("zero-knowledge parameter reload failed: %v", err))
+		return *info, err
+	}
+	info.LoadedAt = time.Now().Unix()
+	params.info = *info
+	if err := selfTest(); err != nil {
+		params.info.Error = err.Error()
+		disableProver(err)
+		return params.info, err
+	}
+	enableProver()
+	log.Info("Reloaded zero-knowledge parameters", "dir", info.Dir, "version", info.Version)
+	return params.info, nil
+}
+
+// verifyParams hashes the parameter files listed by the manifest of dir and
+// compares them with their known-good hashes.
+func verifyParams(dir string) (*ProvingKeyInfo, error) {
+	info := &ProvingKeyInfo{Dir: dir, Files: []ParamFile{}}
+	if dir == "" {
+		return info, nil
+	}
+	version, known, err := readManifest(filepath.Join(dir, ParamsManifest))
+	if err != nil {
+		return info, err
+	}
+	info.Version = version
+
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var mismatched []string
+	for _, name := range names {
+		file, err := hashParamFile(filepath.Join(dir, name))
+		if err != nil {
+			return info, err
+		}
+		file.Name = name
+		info.Files = append(info.Files, *file)
+		if file.Sha256 != known[name] {
+			mismatched = append(mismatched, name)
+		}
+	}
+	if len(mismatched) > 0 {
+		err := fmt.Errorf("parameter files %s do not match their known-good hashes", strings.Join(mismatched, ", "))
+		info.Error = err.Error()
+		return info, err
+	}
+	info.Verified = true
+	return info, nil
+}
+
+// readManifest parses a manifest into the version and the hashes by file name.
+func readManifest(path string) (string, map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	var version string
+	known := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "#"):
+			if fields := strings.Fields(strings.TrimPrefix(text, "#")); len(fields) == 2 && fields[0] == "version" {
+				version = fields[1]
+			}
+		default:
+			fields := strings.Fields(text)
+			if len(fields) != 2 || len(fields[0]) != 2*sha256.Size {
+				return "", nil, fmt.Errorf("%s:%d: invalid manifest line", path, line)
+			}
+			known[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	if len(known) == 0 {
+		return "", nil, fmt.Errorf("%s lists no parameter files", path)
+	}
+	return version, known, nil
+}
+
+func hashParamFile(path string) (*ParamFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &ParamFile{Size: size, Sha256: hex.EncodeToString(h.Sum(nil))}, nil
+}
@@ -40,7 +40,8 @@ type prePkgCreate struct {
 }
 
 type prePkgClose struct {
-	opkg pkgstate.OPkg
+	opkg   pkgstate.OPkg
+	refund bool
 }
 
 type prePkgTransfer struct {
@@ -61,7 +62,7 @@ type preTx struct {
 	desc_pkg prePkgDesc
 }
 
-func preGen(ts *tx.T, state1 *lstate.State) (p preTx, e error) {
+func preGen(ts *tx.T, state1 genState) (p preTx, e error) {
 	ck_state := NewCKState(&ts.Fee)
 
 	force_O := false
@@ -70,7 +71,7 @@ func preGen(ts *tx.T, state1 *lstate.State) (p preTx, e error) {
 	}
 
 	for _, in := range ts.Ins {
-		if src, err := state1.GetOut(&in.Root); err == nil {
+		if src, err := state1.getOut(&in.Root); err == nil {
 			if added, err := ck_state.AddIn(&src.Out_O.Asset); err != nil {
 				e = err
 				return
@@ -95,7 +96,7 @@ func preGen(ts *tx.T, state1 *lstate.State) (p preTx, e error) {
 	}
 
 	if ts.PkgClose != nil {
-		if zpkg := state1.State.Pkgs.GetPkg(&ts.PkgClose.Id); zpkg == nil {
+		if zpkg := state1.getPkg(&ts.PkgClose.Id); zpkg == nil {
 			e = fmt.Errorf("Get Pkg error %v", hex.EncodeToString(ts.PkgClose.Id[:]))
 			return
 		} else {
@@ -112,6 +113,7 @@ func preGen(ts *tx.T, state1 *lstate.State) (p preTx, e error) {
 						p.desc_pkg.close = &prePkgClose{}
 						p.desc_pkg.close.opkg.O = opkg
 						p.desc_pkg.close.opkg.Z = *zpkg
+						p.desc_pkg.close.refund = ts.PkgClose.Refund
 					}
 				}
 			}
@@ -144,7 +146,7 @@ func preGen(ts *tx.T, state1 *lstate.State) (p preTx, e error) {
 	}
 
 	if ts.PkgTransfer != nil {
-		if zpkg := state1.State.Pkgs.GetPkg(&ts.PkgTransfer.Id); zpkg == nil {
+		if zpkg := state1.getPkg(&ts.PkgTransfer.Id); zpkg == nil {
 			e = fmt.Errorf("Get Pkg error %v", hex.EncodeToString(ts.PkgTransfer.Id[:]))
 			return
 		} else {
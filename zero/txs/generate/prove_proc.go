@@ -37,7 +37,7 @@ type gen_pkg_desc struct {
 }
 
 func (self *gen_pkg_desc) Run() bool {
-	if err := cpt.GenPkgProof(&self.desc); err != nil {
+	if err := currentProver().GenPkgProof(&self.desc); err != nil {
 		self.e = err
 		return false
 	} else {
@@ -54,7 +54,7 @@ type gen_input_desc struct {
 }
 
 func (self *gen_input_desc) Run() bool {
-	if err := cpt.GenInputProof(&self.desc); err != nil {
+	if err := currentProver().GenInputProof(&self.desc); err != nil {
 		self.e = err
 		return false
 	} else {
@@ -71,7 +71,7 @@ type gen_output_desc struct {
 }
 
 func (self *gen_output_desc) Run() bool {
-	if err := cpt.GenOutputProof(&self.desc); err != nil {
+	if err := currentProver().GenOutputProof(&self.desc); err != nil {
 		self.e = err
 		return false
 	} else {
@@ -79,7 +79,7 @@ func (self *gen_output_desc) Run() bool {
 	}
 }
 
-func genDesc_Zs(state *lstate.State, seed *keys.Uint256, ptx *preTx, balance_desc *cpt.BalanceDesc, tx *stx.T) (e error) {
+func genDesc_Zs(state genState, seed *keys.Uint256, inSeed func(*lstate.OutState) *keys.Uint256, ptx *preTx, balance_desc *cpt.BalanceDesc, tx *stx.T) (e error) {
 	var gen_pkg_procs = gen_pkg_procs_pool.GetProcs()
 	defer gen_pkg_procs_pool.PutProcs(gen_pkg_procs)
 	if ptx.desc_pkg.create != nil {
@@ -102,12 +102,12 @@ func genDesc_Zs(state *lstate.State, seed *keys.Uint256, ptx *preTx, balance_des
 
 	for i, in := range ptx.desc_z.ins {
 		g := gen_input_desc{}
-		g.desc.Seed = *seed
+		g.desc.Seed = *inSeed(&in)
 		g.desc.Pkr = in.Out_Z.PKr
 		g.desc.RPK = in.Out_Z.RPK
 		g.desc.Einfo = in.Out_Z.EInfo
 		g.desc.Index = in.OutIndex
-		pos, paths, anchor := state.State.State.MTree.GetPaths(in.RootCM)
+		pos, paths, anchor := state.getPaths(in.RootCM)
 		g.desc.Position = uint32(pos)
 		g.desc.Anchor = anchor
 		for i, path := range paths {
@@ -132,7 +132,7 @@ func genDesc_Zs(state *lstate.State, seed *keys.Uint256, ptx *preTx, balance_des
 		g.desc.Tkt_value = asset.Tkt.Value
 		g.desc.Memo = out.Memo
 		g.desc.Pkr = out.Addr
-		g.desc.Height = state.State.Num()
+		g.desc.Height = state.num()
 		g.index = i
 
 		gen_output_procs.StartProc(&g)
@@ -0,0 +1,151 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// ProverStatus tells whether the linked czero library is able to generate
+// transactions the network accepts. Reason explains why it is not.
+type ProverStatus struct {
+	Compatible bool   `json:"compatible"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// Prover generates the zero-knowledge proofs of the descriptions of a
+// transaction, filling in their results.
+type Prover interface {
+	GenPkgProof(desc *cpt.PkgDesc) error
+	GenInputProof(desc *cpt.InputDesc) error
+	GenOutputProof(desc *cpt.OutputDesc) error
+}
+
+// LocalProver generates the proofs with the linked czero library.
+type LocalProver struct{}
+
+func (LocalProver) GenPkgProof(desc *cpt.PkgDesc) error       { return cpt.GenPkgProof(desc) }
+func (LocalProver) GenInputProof(desc *cpt.InputDesc) error   { return cpt.GenInputProof(desc) }
+func (LocalProver) GenOutputProof(desc *cpt.OutputDesc) error { return cpt.GenOutputProof(desc) }
+
+var prover struct {
+	mu   sync.RWMutex
+	err  error
+	impl Prover
+}
+
+// SetProver replaces the prover generating the proofs of transactions, such
+// as with one running in a separate process. Nil restores the local prover.
+func SetProver(p Prover) {
+	prover.mu.Lock()
+	defer prover.mu.Unlock()
+	prover.impl = p
+}
+
+func currentProver() Prover {
+	prover.mu.RLock()
+	defer prover.mu.RUnlock()
+	if prover.impl == nil {
+		return LocalProver{}
+	}
+	return prover.impl
+}
+
+// CheckProver checks the linked czero library after its initialization,
+// which returned initErr, and disables the generation of transactions if it
+// does not behave as the node expects.
+func CheckProver(initErr error) error {
+	err := initErr
+	if err != nil {
+		err = fmt.Errorf("czero initialization failed: %v", err)
+	} else {
+		err = selfTest()
+	}
+	if err != nil {
+		disableProver(err)
+	}
+	return err
+}
+
+// GetProverStatus returns the status of the transaction generation.
+func GetProverStatus() ProverStatus {
+	if err := proverError(); err != nil {
+		return ProverStatus{Reason: err.Error()}
+	}
+	return ProverStatus{Compatible: true}
+}
+
+func proverError() error {
+	prover.mu.RLock()
+	defer prover.mu.RUnlock()
+	return prover.err
+}
+
+func disableProver(err error) {
+	prover.mu.Lock()
+	defer prover.mu.Unlock()
+	if prover.err == nil {
+		log.Error("Incompatible czero library, transaction generation disabled", "err", err)
+		prover.err = err
+	}
+}
+
+// enableProver enables the generation again after the library was reloaded.
+func enableProver() {
+	prover.mu.Lock()
+	defer prover.mu.Unlock()
+	prover.err = nil
+}
+
+// selfTest recomputes values of the library the node relies on and checks
+// them for consistency.
+func selfTest() (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = fmt.Errorf("czero self test panicked: %v", r)
+		}
+	}()
+	roots := cpt.EmptyRoots()
+	if len(roots) != cpt.DEPTH+1 {
+		return fmt.Errorf("czero merkle depth is %d, want %d", len(roots)-1, cpt.DEPTH)
+	}
+	for i := 1; i < len(roots); i++ {
+		if cpt.Combine(&roots[i-1], &roots[i-1]) != roots[i] {
+			return fmt.Errorf("czero empty merkle root mismatch at depth %d", i)
+		}
+	}
+
+	desc := cpt.ConfirmOutputDesc{}
+	desc.Tkn_currency = utils.StringToUint256("SERO")
+	desc.Tkn_value = utils.NewU256(1).ToRef().ToUint256()
+	desc.Rsk = keys.RandUint256()
+	seed := keys.RandUint256()
+	desc.Pkr = keys.Addr2PKr(keys.Seed2Addr(&seed).NewRef(), keys.RandUint256().NewRef())
+	desc.Out_cm = cpt.GenOutCM(&desc.Tkn_currency, &desc.Tkn_value, &desc.Tkt_category, &desc.Tkt_value, &desc.Memo, &desc.Pkr, &desc.Rsk)
+	if err := cpt.ConfirmOutput(&desc); err != nil {
+		return fmt.Errorf("czero output commitment mismatch: %v", err)
+	}
+	return nil
+}
+
+// recheckProver runs the self test again after the library failed to prove
+// or sign a transaction, disabling the generation if it fails now.
+func recheckProver() {
+	if err := selfTest(); err != nil {
+		disableProver(err)
+	}
+}
+
+// generationError returns the error generating a transaction fails with while
+// the prover is disabled.
+func generationError() error {
+	if err := proverError(); err != nil {
+		return errors.New("transaction generation disabled, incompatible czero library: " + err.Error())
+	}
+	return nil
+}
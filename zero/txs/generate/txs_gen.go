@@ -2,31 +2,50 @@ package generate
 
 import (
 	"errors"
+	"time"
 
 	"github.com/sero-cash/go-czero-import/cpt"
 	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/metrics"
 	"github.com/sero-cash/go-sero/zero/txs/lstate"
 	"github.com/sero-cash/go-sero/zero/txs/stx"
 	"github.com/sero-cash/go-sero/zero/txs/tx"
 )
 
+// proveTimer measures the proof generation of transactions.
+var proveTimer = metrics.NewRegisteredTimer("zero/prove", nil)
+
 type gen_ctx struct {
-	st           *lstate.State
+	st           genState
 	seed         *keys.Uint256
+	seeds        map[keys.Uint512]*keys.Uint256
 	t            *tx.T
 	p            preTx
 	balance_desc cpt.BalanceDesc
 	s            stx.T
 }
 
-func prepareCtx(st *lstate.State, seed *keys.Uint256, t *tx.T) (ret gen_ctx, e error) {
+func prepareCtx(st genState, seed *keys.Uint256, others []*keys.Uint256, t *tx.T) (ret gen_ctx, e error) {
 	ret.st = st
 	ret.seed = seed
+	ret.seeds = make(map[keys.Uint512]*keys.Uint256)
+	for _, other := range others {
+		ret.seeds[keys.Seed2Tk(other)] = other
+	}
 	ret.t = t
 	ret.p, e = preGen(t, ret.st)
 	return
 }
 
+// inSeed returns the seed spending out, which is the one of the sender
+// unless the out belongs to one of the other accounts.
+func (self *gen_ctx) inSeed(out *lstate.OutState) *keys.Uint256 {
+	if seed, ok := self.seeds[out.Tk]; ok {
+		return seed
+	}
+	return self.seed
+}
+
 func (self *gen_ctx) setData() {
 	{
 		self.s.Ehash = self.t.Ehash
@@ -84,6 +103,10 @@ func (self *gen_ctx) setData() {
 			self.s.Desc_Pkg.Create = &stx.PkgCreate{}
 			self.s.Desc_Pkg.Create.PKr = create.pkg.PKr
 			self.s.Desc_Pkg.Create.Id = create.pkg.Id
+			terms := stx.PkgTerms{Expiry: create.pkg.Expiry, Hashlock: create.pkg.Hashlock}
+			if terms != (stx.PkgTerms{}) {
+				self.s.Desc_Pkg.Create.Terms = []stx.PkgTerms{terms}
+			}
 		}
 		if self.p.desc_pkg.transfer != nil {
 			change := self.p.desc_pkg.transfer
@@ -95,6 +118,9 @@ func (self *gen_ctx) setData() {
 			open := self.p.desc_pkg.close
 			self.s.Desc_Pkg.Close = &stx.PkgClose{}
 			self.s.Desc_Pkg.Close.Id = open.opkg.Z.Pack.Id
+			if preimage := self.t.PkgClose.Preimage; preimage != nil {
+				self.s.Desc_Pkg.Close.Preimage = []keys.Uint256{*preimage}
+			}
 			self.balance_desc.Zin_acms = append(self.balance_desc.Zin_acms, open.opkg.Z.Pack.Pkg.AssetCM[:]...)
 			self.balance_desc.Zin_ars = append(self.balance_desc.Zin_ars, open.opkg.O.Ar[:]...)
 		}
@@ -123,7 +149,8 @@ func (self *gen_ctx) setData() {
 }
 
 func (self *gen_ctx) proveTx() (e error) {
-	if err := genDesc_Zs(self.st, self.seed, &self.p, &self.balance_desc, &self.s); err != nil {
+	defer proveTimer.UpdateSince(time.Now())
+	if err := genDesc_Zs(self.st, self.seed, self.inSeed, &self.p, &self.balance_desc, &self.s); err != nil {
 		e = err
 		return
 	} else {
@@ -145,7 +172,7 @@ func (self *gen_ctx) signTx() (e error) {
 	for i, s_in_o := range self.p.desc_o.ins {
 		g := cpt.InputSDesc{}
 		g.Ehash = hash_z
-		g.Seed = *self.seed
+		g.Seed = *self.inSeed(&s_in_o)
 		g.Pkr = s_in_o.Out_Z.PKr
 		g.RootCM = s_in_o.RootCM
 		if err := cpt.GenInputSProof(&g); err != nil {
@@ -170,7 +197,11 @@ func (self *gen_ctx) signTx() (e error) {
 	}
 
 	if self.p.desc_pkg.close != nil {
-		if sign, err := keys.SignPKr(self.seed, &hash_z, &self.p.desc_pkg.close.opkg.Z.Pack.PKr); err != nil {
+		signer := &self.p.desc_pkg.close.opkg.Z.Pack.PKr
+		if self.p.desc_pkg.close.refund {
+			signer = &self.p.desc_pkg.close.opkg.Z.From
+		}
+		if sign, err := keys.SignPKr(self.seed, &hash_z, signer); err != nil {
 			e = err
 			return
 		} else {
@@ -196,16 +227,37 @@ func Gen(seed *keys.Uint256, t *tx.T) (s stx.T, e error) {
 	return Gen_lstate(st, seed, t)
 }
 
+// GenWithSponsor generates a transaction whose inputs may also belong to the
+// sponsor account, typically to pay the fee. A nil sponsor is the same as Gen.
+func GenWithSponsor(seed *keys.Uint256, sponsor *keys.Uint256, t *tx.T) (s stx.T, e error) {
+	st := lstate.CurrentState1()
+	if sponsor == nil {
+		return gen(st, seed, nil, t)
+	}
+	return gen(st, seed, []*keys.Uint256{sponsor}, t)
+}
+
 func Gen_lstate(st *lstate.State, seed *keys.Uint256, t *tx.T) (s stx.T, e error) {
-	if ctx, err := prepareCtx(st, seed, t); err != nil {
+	return gen(st, seed, nil, t)
+}
+
+func gen(st *lstate.State, seed *keys.Uint256, others []*keys.Uint256, t *tx.T) (s stx.T, e error) {
+	params.mu.RLock()
+	defer params.mu.RUnlock()
+	if e = generationError(); e != nil {
+		return
+	}
+	if ctx, err := prepareCtx(localState{st}, seed, others, t); err != nil {
 		e = err
 		return
 	} else {
 		ctx.setData()
 		if e = ctx.proveTx(); e != nil {
+			recheckProver()
 			return
 		}
 		if e = ctx.signTx(); e != nil {
+			recheckProver()
 			return
 		}
 		for _, used_out := range ctx.p.uouts {
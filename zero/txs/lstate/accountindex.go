@@ -0,0 +1,537 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/tri"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// AccountIndex summarizes the activity of a local account. Being kept in the
+// state files, it follows the reorganisations of the chain. The activity
+// before the index was introduced is not part of it, the balance history
+// starts at Since from the outs held then.
+//
+// The changes of the balance and the ticket events are not kept in the state
+// files but in the database, keyed by the account, the generation Gen of the
+// index and the block. A rescan or an import of the account restarts the index
+// with a new generation, the records of the blocks of other branches are told
+// apart by the hash of their block.
+type AccountIndex struct {
+	Tk           keys.Uint512
+	FirstNum     uint64
+	LastNum      uint64
+	Received     uint64 // number of outs received
+	Spent        uint64 // number of outs spent
+	ReceivedTkns []assets.Token
+	SpentTkns    []assets.Token
+	Since        uint64
+	BaseTkns     []assets.Token
+	BaseTkts     []assets.Ticket
+	Gen          uint64
+}
+
+// BalanceChange is the tokens received and spent by an account in a block.
+type BalanceChange struct {
+	Num      uint64
+	Hash     keys.Uint256
+	Received []assets.Token
+	Spent    []assets.Token
+	Events   []OutEvent `rlp:"tail"`
+}
+
+func (self *BalanceChange) Serial() (ret []byte, e error) {
+	return rlp.EncodeToBytes(self)
+}
+
+type balanceChangeGet struct {
+	out *BalanceChange
+}
+
+func (self *balanceChangeGet) Unserial(v []byte) (e error) {
+	if len(v) == 0 {
+		self.out = nil
+		return
+	}
+	self.out = &BalanceChange{}
+	return rlp.DecodeBytes(v, self.out)
+}
+
+// OutEvent records an out received or spent by an account. The keys identify
+// the out in the transaction of the event: the hash of the out_o or the
+// commitment of the out_z for a received out, the root and the trace of the
+// out for a spent one.
+type OutEvent struct {
+	Keys  []keys.Uint256
+	Spent bool
+	Tkn   *assets.Token  `rlp:"nil"`
+	Tkt   *assets.Ticket `rlp:"nil"`
+}
+
+// TktEvent records a ticket received or spent by a local account.
+type TktEvent struct {
+	Category keys.Uint256
+	Value    keys.Uint256
+	Tk       keys.Uint512
+	Root     keys.Uint256
+	Num      uint64
+	Spent    bool
+	Hash     keys.Uint256
+	Gen      uint64
+}
+
+// changeRef is the block of a change of an account.
+type changeRef struct {
+	Num  uint64
+	Hash keys.Uint256
+}
+
+// changeRefs lists the changes of an account in a bucket of blocks.
+type changeRefs struct {
+	refs []changeRef
+}
+
+func (self *changeRefs) Serial() (ret []byte, e error) {
+	return rlp.EncodeToBytes(self.refs)
+}
+
+func (self *changeRefs) Unserial(v []byte) (e error) {
+	self.refs = nil
+	if len(v) == 0 {
+		return
+	}
+	return rlp.DecodeBytes(v, &self.refs)
+}
+
+// tktEvents lists the events of a ticket on all the branches.
+type tktEvents struct {
+	events []*TktEvent
+}
+
+func (self *tktEvents) Serial() (ret []byte, e error) {
+	return rlp.EncodeToBytes(self.events)
+}
+
+func (self *tktEvents) Unserial(v []byte) (e error) {
+	self.events = nil
+	if len(v) == 0 {
+		return
+	}
+	return rlp.DecodeBytes(v, &self.events)
+}
+
+// change_bucket is the number of blocks the changes of an account are listed
+// together in the database.
+const change_bucket = 1024
+
+func encodeNum(num uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], num)
+	return b[:]
+}
+
+func changeName(tk *keys.Uint512, gen uint64, num uint64, hash *keys.Uint256) (ret []byte) {
+	ret = []byte("LSTATE_ACCOUNT_CHANGE_")
+	ret = append(ret, tk[:]...)
+	ret = append(ret, encodeNum(gen)...)
+	ret = append(ret, encodeNum(num)...)
+	ret = append(ret, hash[:]...)
+	return
+}
+
+func changeBucketName(tk *keys.Uint512, gen uint64, bucket uint64) (ret []byte) {
+	ret = []byte("LSTATE_ACCOUNT_CHANGES_")
+	ret = append(ret, tk[:]...)
+	ret = append(ret, encodeNum(gen)...)
+	ret = append(ret, encodeNum(bucket)...)
+	return
+}
+
+func tktEventsName(category *keys.Uint256, value *keys.Uint256) (ret []byte) {
+	ret = []byte("LSTATE_TKT_EVENTS_")
+	ret = append(ret, category[:]...)
+	ret = append(ret, value[:]...)
+	return
+}
+
+// newGen returns the generation of a restarted account index.
+func newGen() uint64 {
+	return uint64(time.Now().UnixNano())
+}
+
+// canonical tells whether the block num of hash is on the current chain.
+func canonical(num uint64, hash *keys.Uint256) bool {
+	bc := current_bc
+	if bc == nil {
+		return true
+	}
+	header := bc.GetHeaderByNumber(num)
+	return header != nil && *header.Hash().HashToUint256() == *hash
+}
+
+func addToken(tkns []assets.Token, tkn *assets.Token) []assets.Token {
+	for i := range tkns {
+		if tkns[i].Currency == tkn.Currency {
+			tkns[i].Value.AddU(&tkn.Value)
+			return tkns
+		}
+	}
+	return append(tkns, cloneToken(tkn))
+}
+
+// cloneToken copies a token, the value of which is updated in place by
+// addToken.
+func cloneToken(tkn *assets.Token) assets.Token {
+	return assets.Token{Currency: tkn.Currency, Value: utils.U256(*new(big.Int).Set(tkn.Value.ToIntRef()))}
+}
+
+func cloneTokens(tkns []assets.Token) (ret []assets.Token) {
+	for i := range tkns {
+		ret = append(ret, cloneToken(&tkns[i]))
+	}
+	return
+}
+
+func (self *State) index_out(out *OutState, num uint64, spent bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	index, ok := self.G2accounts[out.Tk]
+	if !ok {
+		index = &AccountIndex{Tk: out.Tk}
+		self.G2accounts[out.Tk] = index
+	}
+	if index.Received+index.Spent == 0 {
+		index.FirstNum = num
+	}
+	index.LastNum = num
+
+	change, ok := self.changes[out.Tk]
+	if !ok {
+		change = &BalanceChange{Num: num}
+		self.changes[out.Tk] = change
+	}
+	event := OutEvent{Spent: spent}
+	if spent {
+		event.Keys = []keys.Uint256{out.Root, out.Trace}
+	} else if out.Z {
+		event.Keys = []keys.Uint256{out.Out_Z.OutCM}
+	} else {
+		event.Keys = []keys.Uint256{out.Out_O.ToHash()}
+	}
+	if tkn := out.Out_O.Asset.Tkn; tkn != nil {
+		clone := cloneToken(tkn)
+		event.Tkn = &clone
+	}
+	if tkt := out.Out_O.Asset.Tkt; tkt != nil && tkt.Value != keys.Empty_Uint256 {
+		clone := *tkt
+		event.Tkt = &clone
+	}
+	change.Events = append(change.Events, event)
+
+	tkn := out.Out_O.Asset.Tkn
+	if spent {
+		self.relevance.Spends = true
+		index.Spent++
+		if tkn != nil {
+			index.SpentTkns = addToken(index.SpentTkns, tkn)
+			change.Spent = addToken(change.Spent, tkn)
+		}
+	} else {
+		self.relevance.Outs = true
+		index.Received++
+		if tkn != nil {
+			index.ReceivedTkns = addToken(index.ReceivedTkns, tkn)
+			change.Received = addToken(change.Received, tkn)
+		}
+	}
+
+	tkt := out.Out_O.Asset.Tkt
+	if tkt != nil && tkt.Value != keys.Empty_Uint256 {
+		self.tktEvents = append(self.tktEvents, &TktEvent{
+			Category: tkt.Category,
+			Value:    tkt.Value,
+			Tk:       out.Tk,
+			Root:     out.Root,
+			Num:      num,
+			Spent:    spent,
+			Gen:      index.Gen,
+		})
+	}
+}
+
+// commitChanges writes the changes and the ticket events of the block of hash
+// to the database, once all its outs are indexed. It reports whether the block
+// changed any account.
+func (self *State) commitChanges(hash *keys.Uint256) (changed bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	changed = len(self.changes) > 0
+	for tk, change := range self.changes {
+		change.Hash = *hash
+		self.putChange(self.G2accounts[tk], change)
+	}
+	for _, event := range self.tktEvents {
+		event.Hash = *hash
+		self.putTktEvent(event)
+	}
+	self.changes = make(map[keys.Uint512]*BalanceChange)
+	self.tktEvents = nil
+	return
+}
+
+// putChange writes the change of a block to the records of an account, the
+// caller holds the write lock.
+func (self *State) putChange(index *AccountIndex, change *BalanceChange) {
+	t := self.State.State.Tri()
+	tri.UpdateGlobalObj(t, changeName(&index.Tk, index.Gen, change.Num, &change.Hash), change)
+
+	name := changeBucketName(&index.Tk, index.Gen, change.Num/change_bucket)
+	bucket := changeRefs{}
+	tri.GetGlobalObj(t, name, &bucket)
+	ref := changeRef{Num: change.Num, Hash: change.Hash}
+	for _, r := range bucket.refs {
+		if r == ref {
+			return
+		}
+	}
+	bucket.refs = append(bucket.refs, ref)
+	tri.UpdateGlobalObj(t, name, &bucket)
+}
+
+// putTktEvent adds an event to the records of a ticket, the caller holds the
+// write lock.
+func (self *State) putTktEvent(event *TktEvent) {
+	t := self.State.State.Tri()
+	name := tktEventsName(&event.Category, &event.Value)
+	list := tktEvents{}
+	tri.GetGlobalObj(t, name, &list)
+	for _, e := range list.events {
+		if *e == *event {
+			return
+		}
+	}
+	list.events = append(list.events, event)
+	tri.UpdateGlobalObj(t, name, &list)
+}
+
+// walkChanges calls f with the changes of an account in the blocks from from
+// to to of the current chain, in block order. The caller holds the lock.
+func (self *State) walkChanges(index *AccountIndex, from uint64, to uint64, f func(change *BalanceChange)) {
+	if index.Received+index.Spent == 0 {
+		return
+	}
+	if from < index.Since {
+		from = index.Since
+	}
+	if to > index.LastNum {
+		to = index.LastNum
+	}
+	t := self.State.State.Tri()
+	for b := from / change_bucket; from <= to && b <= to/change_bucket; b++ {
+		bucket := changeRefs{}
+		tri.GetGlobalObj(t, changeBucketName(&index.Tk, index.Gen, b), &bucket)
+		sort.Slice(bucket.refs, func(i, j int) bool {
+			return bucket.refs[i].Num < bucket.refs[j].Num
+		})
+		for _, ref := range bucket.refs {
+			if ref.Num < from || ref.Num > to || !canonical(ref.Num, &ref.Hash) {
+				continue
+			}
+			get := balanceChangeGet{}
+			tri.GetGlobalObj(t, changeName(&index.Tk, index.Gen, ref.Num, &ref.Hash), &get)
+			if get.out != nil {
+				f(get.out)
+			}
+		}
+	}
+}
+
+func (self *State) accountsToData() {
+	accounts := []*AccountIndex{}
+	for _, index := range self.G2accounts {
+		accounts = append(accounts, index)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].Tk[:], accounts[j].Tk[:]) < 0
+	})
+	self.data.Accounts = accounts
+}
+
+// indexHeldOuts starts the index of the accounts holding outs in a state file
+// written before the index was introduced, from the outs they hold.
+func (self *State) indexHeldOuts() {
+	for _, root := range self.G2wouts {
+		out := self.G2outs[root]
+		index, ok := self.G2accounts[out.Tk]
+		if !ok {
+			index = &AccountIndex{Tk: out.Tk, Since: self.State.Num()}
+			self.G2accounts[out.Tk] = index
+		}
+		if tkn := out.Out_O.Asset.Tkn; tkn != nil {
+			index.BaseTkns = addToken(index.BaseTkns, tkn)
+		}
+		if tkt := out.Out_O.Asset.Tkt; tkt != nil && tkt.Value != keys.Empty_Uint256 {
+			index.BaseTkts = append(index.BaseTkts, *tkt)
+		}
+	}
+}
+
+// GetBalanceAt returns the tokens and tickets held by an account at the end
+// of block num, which must not precede the start of its index.
+func (self *State) GetBalanceAt(tk *keys.Uint512, num uint64) (tkns map[keys.Uint256]*big.Int, tkts []assets.Ticket, e error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	tkns = make(map[keys.Uint256]*big.Int)
+	index, ok := self.G2accounts[*tk]
+	if !ok {
+		return
+	}
+	if num < index.Since {
+		e = fmt.Errorf("balance before block %d is not indexed", index.Since)
+		return
+	}
+	add := func(tkn *assets.Token, sign int) {
+		value, ok := tkns[tkn.Currency]
+		if !ok {
+			value = new(big.Int)
+			tkns[tkn.Currency] = value
+		}
+		if sign < 0 {
+			value.Sub(value, tkn.Value.ToIntRef())
+		} else {
+			value.Add(value, tkn.Value.ToIntRef())
+		}
+	}
+	for i := range index.BaseTkns {
+		add(&index.BaseTkns[i], 1)
+	}
+	held := make(map[assets.Ticket]bool)
+	for _, tkt := range index.BaseTkts {
+		held[tkt] = true
+	}
+	self.walkChanges(index, index.Since, num, func(change *BalanceChange) {
+		for i := range change.Received {
+			add(&change.Received[i], 1)
+		}
+		for i := range change.Spent {
+			add(&change.Spent[i], -1)
+		}
+		// Spending precedes receiving within a block
+		for _, event := range change.Events {
+			if event.Tkt != nil && event.Spent {
+				delete(held, *event.Tkt)
+			}
+		}
+		for _, event := range change.Events {
+			if event.Tkt != nil && !event.Spent {
+				held[*event.Tkt] = true
+			}
+		}
+	})
+	for cy, value := range tkns {
+		if value.Sign() == 0 {
+			delete(tkns, cy)
+		}
+	}
+
+	for tkt := range held {
+		tkts = append(tkts, tkt)
+	}
+	sort.Slice(tkts, func(i, j int) bool {
+		if tkts[i].Category != tkts[j].Category {
+			return bytes.Compare(tkts[i].Category[:], tkts[j].Category[:]) < 0
+		}
+		return bytes.Compare(tkts[i].Value[:], tkts[j].Value[:]) < 0
+	})
+	return
+}
+
+// GetAccountIndex returns a copy of the activity index of an account, nil if
+// the account has no recorded activity.
+func (self *State) GetAccountIndex(tk *keys.Uint512) *AccountIndex {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	index, ok := self.G2accounts[*tk]
+	if !ok {
+		return nil
+	}
+	ret := *index
+	ret.ReceivedTkns = cloneTokens(index.ReceivedTkns)
+	ret.SpentTkns = cloneTokens(index.SpentTkns)
+	ret.BaseTkns = cloneTokens(index.BaseTkns)
+	ret.BaseTkts = append([]assets.Ticket{}, index.BaseTkts...)
+	return &ret
+}
+
+// GetHistory returns the changes of an account in the blocks from from to to,
+// in block order.
+func (self *State) GetHistory(tk *keys.Uint512, from uint64, to uint64) (ret []*BalanceChange) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	index, ok := self.G2accounts[*tk]
+	if !ok {
+		return
+	}
+	self.walkChanges(index, from, to, func(change *BalanceChange) {
+		ret = append(ret, change)
+	})
+	return
+}
+
+// GetTktEvents returns the history of a ticket in block order.
+func (self *State) GetTktEvents(category *keys.Uint256, value *keys.Uint256) (ret []TktEvent) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	for _, event := range self.tkt_events(category, value) {
+		ret = append(ret, *event)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Num != ret[j].Num {
+			return ret[i].Num < ret[j].Num
+		}
+		// Spending precedes receiving within a block
+		return ret[i].Spent && !ret[j].Spent
+	})
+	return
+}
+
+// tkt_events returns the events of a ticket of the local accounts on the
+// current chain. The caller holds the lock.
+func (self *State) tkt_events(category *keys.Uint256, value *keys.Uint256) (ret []*TktEvent) {
+	list := tktEvents{}
+	tri.GetGlobalObj(self.State.State.Tri(), tktEventsName(category, value), &list)
+	for _, event := range list.events {
+		index, ok := self.G2accounts[event.Tk]
+		if !ok || event.Gen != index.Gen || event.Num < index.Since || event.Num > index.LastNum {
+			continue
+		}
+		if canonical(event.Num, &event.Hash) {
+			ret = append(ret, event)
+		}
+	}
+	return
+}
@@ -0,0 +1,203 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+)
+
+// AccountBundle is the scanned part of the local out state of an account,
+// exported to move the account to another node without rescanning the chain.
+type AccountBundle struct {
+	Num   uint64         // last block scanned for the outs
+	Hash  keys.Uint256   // hash of the last block scanned
+	Outs  []*OutState    // unspent outs
+	Nils  []keys.Uint256 // nullifiers of the outs spent
+	Index *AccountIndex  `rlp:"nil"`
+	// Changes and TktEvents are the history of the account, the blocks of
+	// which are checked to be canonical on import.
+	Changes   []*BalanceChange
+	TktEvents []*TktEvent
+}
+
+// ExportAccount returns the serialized bundle of the unspent outs, the
+// nullifiers of the spent outs and the index of an account.
+func ExportAccount(tk keys.Uint512) ([]byte, error) {
+	bc := current_bc
+	if bc == nil {
+		return nil, errNotRunning
+	}
+	process_lock.Lock()
+	defer process_lock.Unlock()
+
+	st := current_state1
+	num, ok := ScannedNumber()
+	if st == nil || !ok {
+		return nil, errNotRunning
+	}
+	header := bc.GetHeaderByNumber(num)
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", num)
+	}
+	return st.export_account(&tk, num, header.Hash().HashToUint256())
+}
+
+// ImportAccount adds the outs and the index of an account exported by
+// ExportAccount on another node, then scans the blocks following the export
+// for the outs of the account in the background, reporting the progress by
+// RescanStatus. The exported block must be canonical and scanned here.
+func ImportAccount(tk keys.Uint512, data []byte) error {
+	bc := current_bc
+	if bc == nil {
+		return errNotRunning
+	}
+	bundle := AccountBundle{}
+	if err := rlp.DecodeBytes(data, &bundle); err != nil {
+		return err
+	}
+	if bundle.Index != nil && bundle.Index.Tk != tk {
+		return errors.New("bundle is of another account")
+	}
+	for _, out := range bundle.Outs {
+		if out.Tk != tk {
+			return errors.New("bundle is of another account")
+		}
+	}
+	if header := bc.GetHeaderByNumber(bundle.Num); header == nil || *header.Hash().HashToUint256() != bundle.Hash {
+		return fmt.Errorf("block %d of the bundle is not canonical", bundle.Num)
+	}
+	if scanned, ok := ScannedNumber(); !ok || scanned < bundle.Num {
+		return fmt.Errorf("block %d of the bundle is not scanned yet", bundle.Num)
+	}
+	return start_rescan(bc, tk, bundle.Num+1, func(st *State) (int, error) {
+		if scanned, ok := ScannedNumber(); !ok || scanned < bundle.Num {
+			return 0, fmt.Errorf("block %d of the bundle is not scanned any more", bundle.Num)
+		}
+		return st.import_account(&tk, &bundle)
+	})
+}
+
+func (self *State) export_account(tk *keys.Uint512, num uint64, hash *keys.Uint256) ([]byte, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	bundle := AccountBundle{Num: num, Hash: *hash, Index: self.G2accounts[*tk]}
+	for _, root := range self.G2touts[*tk] {
+		bundle.Outs = append(bundle.Outs, self.G2outs[root])
+	}
+	if bundle.Index != nil {
+		self.walkChanges(bundle.Index, bundle.Index.Since, num, func(change *BalanceChange) {
+			bundle.Changes = append(bundle.Changes, change)
+			for _, event := range change.Events {
+				if !event.Spent {
+					continue
+				}
+				for i := range event.Keys {
+					if self.State.State.HasIn(&event.Keys[i]) {
+						bundle.Nils = append(bundle.Nils, event.Keys[i])
+					}
+				}
+			}
+		})
+		tkts := make(map[assets.Ticket]bool)
+		for _, tkt := range bundle.Index.BaseTkts {
+			tkts[tkt] = true
+		}
+		for _, change := range bundle.Changes {
+			for _, event := range change.Events {
+				if event.Tkt != nil {
+					tkts[*event.Tkt] = true
+				}
+			}
+		}
+		for tkt := range tkts {
+			for _, event := range self.tkt_events(&tkt.Category, &tkt.Value) {
+				if event.Tk == *tk {
+					bundle.TktEvents = append(bundle.TktEvents, event)
+				}
+			}
+		}
+	}
+	return rlp.EncodeToBytes(&bundle)
+}
+
+// import_account adds the outs and the index of a bundle to the state, which
+// must not know the account yet. It returns the number of outs added.
+func (self *State) import_account(tk *keys.Uint512, bundle *AccountBundle) (int, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if len(self.G2touts[*tk]) > 0 {
+		return 0, errors.New("outs of the account are scanned already, rescan the account instead")
+	}
+	if index, ok := self.G2accounts[*tk]; ok && index.Received+index.Spent > 0 {
+		return 0, errors.New("outs of the account are scanned already, rescan the account instead")
+	}
+	for i := range bundle.Nils {
+		if !self.State.State.HasIn(&bundle.Nils[i]) {
+			return 0, errors.New("bundle spends outs not spent on this chain")
+		}
+	}
+	for _, out := range bundle.Outs {
+		if os, err := self.State.State.GetOut(&out.Root); err != nil {
+			return 0, err
+		} else if os == nil {
+			return 0, errors.New("bundle holds outs not created on this chain")
+		}
+	}
+
+	index := bundle.Index
+	if index == nil {
+		index = &AccountIndex{Tk: *tk, Since: bundle.Num + 1}
+	}
+	index.Gen = newGen()
+	delete(self.changes, *tk)
+	for _, change := range bundle.Changes {
+		if change.Num <= bundle.Num && canonical(change.Num, &change.Hash) {
+			self.putChange(index, change)
+			self.putRelevant(change.Num, &change.Hash)
+		}
+	}
+	for _, event := range bundle.TktEvents {
+		if event.Tk == *tk && event.Num <= bundle.Num && canonical(event.Num, &event.Hash) {
+			event.Gen = index.Gen
+			self.putTktEvent(event)
+		}
+	}
+	for _, out := range bundle.Outs {
+		self.G2wouts = append(self.G2wouts, out.Root)
+		self.G2touts[*tk] = append(self.G2touts[*tk], out.Root)
+		self.G2outs[out.Root] = out
+		self.G2outs[out.Trace] = out
+		self.addBalance(out, 1)
+		if bundle.Index == nil {
+			if tkn := out.Out_O.Asset.Tkn; tkn != nil {
+				index.BaseTkns = addToken(index.BaseTkns, tkn)
+			}
+			if tkt := out.Out_O.Asset.Tkt; tkt != nil && tkt.Value != keys.Empty_Uint256 {
+				index.BaseTkts = append(index.BaseTkts, *tkt)
+			}
+		}
+	}
+	self.G2accounts[*tk] = index
+	return len(bundle.Outs), nil
+}
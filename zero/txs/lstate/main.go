@@ -10,6 +10,7 @@ import (
 	"github.com/sero-cash/go-sero/zero/utils"
 
 	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/metrics"
 
 	"time"
 
@@ -26,8 +27,12 @@ import (
 type BlockChain interface {
 	GetCurrenHeader() *types.Header
 	GetHeader(hash *common.Hash) *types.Header
+	GetHeaderByNumber(num uint64) *types.Header
 	NewState(hash *common.Hash) *zstate.ZState
 	GetTks() []keys.Uint512
+	// ZStateTail returns the oldest block the zstate records of which were
+	// not pruned, the blocks below it can not be scanned again.
+	ZStateTail() uint64
 
 	CashChose() *atomic.Value
 }
@@ -43,7 +48,35 @@ func CurrentState1() *State {
 	return current_state1
 }
 
+// scanned_num is the number of the last block the outs were scanned for,
+// offset by one so zero means nothing was scanned yet.
+var scanned_num uint64
+
+// scanLagGauge is the number of blocks the scan of the outs is behind the
+// head of the chain.
+var scanLagGauge = metrics.NewRegisteredGauge("zero/lstate/lag", nil)
+
+// setScanned records num as the last block the outs were scanned for.
+func setScanned(bc BlockChain, num uint64) {
+	atomic.StoreUint64(&scanned_num, num+1)
+	if head := bc.GetCurrenHeader(); head != nil && head.Number.Uint64() > num {
+		scanLagGauge.Update(int64(head.Number.Uint64() - num))
+	} else {
+		scanLagGauge.Update(0)
+	}
+}
+
+// ScannedNumber returns the number of the last block the outs were scanned for.
+func ScannedNumber() (uint64, bool) {
+	num := atomic.LoadUint64(&scanned_num)
+	if num == 0 {
+		return 0, false
+	}
+	return num - 1, true
+}
+
 func Run(bc BlockChain) {
+	current_bc = bc
 	go run(bc)
 	for current_state1 != nil {
 		time.Sleep(time.Second * 1)
@@ -144,6 +177,10 @@ func parse_block_chain(bc BlockChain, last_cmd_count int) (current_cm_count int,
 	var st1 *State
 	parse_count := 0
 	for i := len(need_load) - 1; i >= 0; i-- {
+		if rewind_pending() {
+			// The branch is being dropped, the next pass rescans from the rewind
+			return
+		}
 
 		header := need_load[i]
 		current_num := header.Number.Uint64()
@@ -188,8 +225,10 @@ func parse_block_chain(bc BlockChain, last_cmd_count int) (current_cm_count int,
 
 		commitment_len := len(st1.State.State.Block.Roots)
 		t.Renter(fmt.Sprintf("PARSE_BLOCK_CHAIN----UpdateWiteness(count=%d)", commitment_len))
-		st1.UpdateWitness(tks, current_num, block)
+		relevance := st1.UpdateWitness(tks, current_num, current_hash.HashToUint256(), block)
 		current_state1 = st1
+		setScanned(bc, current_num)
+		relevance_feed.Send(BlockRelevance{current_num, current_hash, relevance})
 
 		t.Renter("PARSE_BLOCK_CHAIN----Finalize")
 		if parse_count%2000 == 0 {
@@ -215,6 +254,8 @@ func parse_block_chain(bc BlockChain, last_cmd_count int) (current_cm_count int,
 		current_state1 = &st1
 	}
 
+	setScanned(bc, chose)
+
 	cashChose := bc.CashChose()
 	cashChose.Store(chose)
 	return current_cm_count, nil
@@ -224,11 +265,14 @@ func parse_block_chain(bc BlockChain, last_cmd_count int) (current_cm_count int,
 func run(bc BlockChain) {
 	cmd_count := 2
 	for {
+		process_lock.Lock()
+		apply_rewind(bc)
 		cmd_count, _ := parse_block_chain(bc, cmd_count)
+		process_lock.Unlock()
 		if cmd_count <= 1 {
-			time.Sleep(1000 * 1000 * 1000 * 8)
+			wait_scan(time.Second * 8)
 		} else {
-			time.Sleep(1000 * 1000 * 10)
+			wait_scan(time.Millisecond * 10)
 		}
 	}
 }
@@ -0,0 +1,397 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+const (
+	state1_tmp_suffix = ".tmp"
+
+	// state1_keep_window is the number of blocks below the head whose state
+	// files compaction keeps, the reorganisations the chain keeps the tries
+	// in memory for reload them instead of rescanning.
+	state1_keep_window = 128
+
+	// state1_checkpoint_interval spans the blocks of which compaction keeps
+	// the newest canonical state file as a checkpoint, deeper rewinds and
+	// rescans restart from it.
+	state1_checkpoint_interval = 10000
+)
+
+var (
+	errNotRunning    = errors.New("lstate is not running")
+	errRepairRunning = errors.New("lstate repair is already running")
+)
+
+// process_lock serializes the out scanning loop with the maintenance routines,
+// so files are never removed while a block is being parsed.
+var process_lock sync.Mutex
+
+// current_bc is the chain the out scanning loop was started with.
+var current_bc BlockChain
+
+// Stats describes the on-disk state of the local out database.
+type Stats struct {
+	Dir         string         `json:"dir"`
+	Files       int            `json:"files"`
+	Size        int64          `json:"size"`
+	Oldest      uint64         `json:"oldest"`
+	Newest      uint64         `json:"newest"`
+	Scanned     uint64         `json:"scanned"`
+	Outs        int            `json:"outs"`
+	Accounts    int            `json:"accounts"`
+	PkgsFrom    int            `json:"pkgsFrom"`
+	PkgsTo      int            `json:"pkgsTo"`
+	ScanWorkers int            `json:"scanWorkers"`
+	Repairing   bool           `json:"repairing"`
+	Repair      RepairProgress `json:"repair"`
+}
+
+// RepairProgress reports the progress of the last repair run.
+type RepairProgress struct {
+	Total    int    `json:"total"`
+	Checked  int    `json:"checked"`
+	Corrupt  int    `json:"corrupt"`
+	Orphaned int    `json:"orphaned"`
+	From     uint64 `json:"from"`
+	Target   uint64 `json:"target"`
+	Error    string `json:"error,omitempty"`
+}
+
+var (
+	repair_mu       sync.Mutex
+	repair_running  bool
+	repair_progress RepairProgress
+)
+
+type state1File struct {
+	name string
+	num  uint64
+	size int64
+}
+
+func list_state1_files() (files []state1File, tmps []string, e error) {
+	infos, err := ioutil.ReadDir(zconfig.State1_dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, state1_tmp_suffix) {
+			tmps = append(tmps, name)
+			continue
+		}
+		var num uint64
+		if _, err := fmt.Sscanf(name, "%d.", &num); err != nil {
+			continue
+		}
+		files = append(files, state1File{name, num, info.Size()})
+	}
+	return
+}
+
+// check_pruned fails if the zstate records of the blocks from from on were
+// pruned, the outs of the blocks below the zstate tail can not be scanned
+// again.
+func check_pruned(bc BlockChain, from uint64) error {
+	if tail := bc.ZStateTail(); from < tail {
+		return fmt.Errorf("cannot scan from block %d, zstate records pruned below %d", from, tail)
+	}
+	return nil
+}
+
+// is_canonical reports whether the state file belongs to the canonical chain
+// at or below the current head.
+func is_canonical(bc BlockChain, file *state1File) bool {
+	header := bc.GetHeaderByNumber(file.num)
+	if header == nil {
+		return false
+	}
+	hash := header.Hash()
+	return state1_file_name(file.num, &hash) == file.name
+}
+
+// check_state1_file decodes the state file, reporting whether it is intact.
+func check_state1_file(name string) error {
+	bytes, err := ioutil.ReadFile(zconfig.State1_file(name))
+	if err != nil {
+		return err
+	}
+	if len(bytes) == 0 {
+		return errors.New("empty state file")
+	}
+	data := StateData{}
+	return rlp.DecodeBytes(bytes, &data)
+}
+
+// GetStats returns the statistics of the local out database.
+func GetStats() (stats Stats, e error) {
+	files, _, err := list_state1_files()
+	if err != nil {
+		e = err
+		return
+	}
+	stats.Dir = zconfig.State1_dir()
+	stats.Files = len(files)
+	for i, file := range files {
+		stats.Size += file.size
+		if i == 0 || file.num < stats.Oldest {
+			stats.Oldest = file.num
+		}
+		if file.num > stats.Newest {
+			stats.Newest = file.num
+		}
+	}
+	if st := CurrentState1(); st != nil {
+		st.mu.RLock()
+		stats.Outs = len(st.G2wouts)
+		stats.Accounts = len(st.G2touts)
+		stats.PkgsFrom = len(st.G2pkgs_from)
+		stats.PkgsTo = len(st.G2pkgs_to)
+		st.mu.RUnlock()
+	}
+	stats.Scanned, _ = ScannedNumber()
+	stats.ScanWorkers = ScanWorkers()
+	repair_mu.Lock()
+	stats.Repairing = repair_running
+	stats.Repair = repair_progress
+	repair_mu.Unlock()
+	return
+}
+
+// Compact removes the state files of the blocks no longer on the canonical
+// chain, and those of the canonical ones except the files of the last
+// state1_keep_window blocks and one checkpoint per state1_checkpoint_interval
+// blocks. The out scanning loop continues from the newest one.
+func Compact() (removed int, e error) {
+	bc := current_bc
+	if bc == nil {
+		e = errNotRunning
+		return
+	}
+	process_lock.Lock()
+	defer process_lock.Unlock()
+
+	files, tmps, err := list_state1_files()
+	if err != nil {
+		e = err
+		return
+	}
+	head := bc.GetCurrenHeader().Number.Uint64()
+	checkpoints := make(map[uint64]int)
+	keep := make(map[int]bool)
+	for i := range files {
+		file := &files[i]
+		if !is_canonical(bc, file) {
+			continue
+		}
+		if file.num+state1_keep_window > head {
+			keep[i] = true
+		}
+		span := file.num / state1_checkpoint_interval
+		if last, ok := checkpoints[span]; !ok || file.num > files[last].num {
+			checkpoints[span] = i
+		}
+	}
+	for _, i := range checkpoints {
+		keep[i] = true
+	}
+	// Without a canonical state the scan starts over from the genesis
+	if len(keep) == 0 && len(files) > 0 {
+		if e = check_pruned(bc, 0); e != nil {
+			return
+		}
+	}
+	for i, file := range files {
+		if keep[i] {
+			continue
+		}
+		if err := os.Remove(zconfig.State1_file(file.name)); err != nil {
+			e = err
+			return
+		}
+		removed++
+	}
+	for _, tmp := range tmps {
+		os.Remove(zconfig.State1_file(tmp))
+	}
+	log.Info("Compacted lstate", "removed", removed, "kept", len(keep))
+	return
+}
+
+// Repair checks every state file in the background. Corrupt files and files of
+// blocks no longer on the canonical chain are removed, the out scanning loop
+// re-derives the missing states from the chain database afterwards.
+func Repair() error {
+	bc := current_bc
+	if bc == nil {
+		return errNotRunning
+	}
+	repair_mu.Lock()
+	defer repair_mu.Unlock()
+	if repair_running {
+		return errRepairRunning
+	}
+	repair_running = true
+	repair_progress = RepairProgress{}
+	go repair(bc)
+	return nil
+}
+
+func repair(bc BlockChain) {
+	process_lock.Lock()
+	defer process_lock.Unlock()
+
+	done := func(err error) {
+		repair_mu.Lock()
+		if err != nil {
+			repair_progress.Error = err.Error()
+		}
+		repair_running = false
+		repair_mu.Unlock()
+	}
+
+	files, tmps, err := list_state1_files()
+	if err != nil {
+		done(err)
+		return
+	}
+	for _, tmp := range tmps {
+		os.Remove(zconfig.State1_file(tmp))
+	}
+	repair_mu.Lock()
+	repair_progress.Total = len(files)
+	repair_mu.Unlock()
+
+	newest, corrupts, orphans := uint64(0), 0, 0
+	for i := range files {
+		file := &files[i]
+		corrupt, orphaned := false, false
+		if err := check_state1_file(file.name); err != nil {
+			log.Warn("Removing corrupt lstate file", "file", file.name, "err", err)
+			corrupt = true
+		} else if !is_canonical(bc, file) {
+			log.Debug("Removing orphaned lstate file", "file", file.name)
+			orphaned = true
+		}
+		if corrupt || orphaned {
+			if err := os.Remove(zconfig.State1_file(file.name)); err != nil {
+				done(err)
+				return
+			}
+		} else if file.num > newest {
+			newest = file.num
+		}
+		if corrupt {
+			corrupts++
+		}
+		if orphaned {
+			orphans++
+		}
+		repair_mu.Lock()
+		repair_progress.Checked++
+		repair_progress.Corrupt = corrupts
+		repair_progress.Orphaned = orphans
+		repair_mu.Unlock()
+	}
+	repair_mu.Lock()
+	repair_progress.From = newest
+	repair_progress.Target = bc.GetCurrenHeader().Number.Uint64()
+	repair_mu.Unlock()
+
+	log.Info("Repaired lstate", "checked", len(files), "corrupt", corrupts, "orphaned", orphans, "from", newest)
+	done(nil)
+}
+
+func write_state1_file(name string, data []byte) error {
+	file := zconfig.State1_file(name)
+	tmp := file + state1_tmp_suffix
+	if err := ioutil.WriteFile(tmp, data, os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// RescanFrom removes the state files of all blocks at or above num together
+// with the orphaned ones below it, so the out scanning loop replays the chain
+// from the newest remaining canonical state.
+func RescanFrom(num uint64) (removed int, e error) {
+	bc := current_bc
+	if bc == nil {
+		e = errNotRunning
+		return
+	}
+	process_lock.Lock()
+	defer process_lock.Unlock()
+
+	if head := bc.GetCurrenHeader().Number.Uint64(); num > head {
+		e = fmt.Errorf("rescan block %d is above the current head %d", num, head)
+		return
+	}
+	files, tmps, err := list_state1_files()
+	if err != nil {
+		e = err
+		return
+	}
+	base, found := uint64(0), false
+	for i := range files {
+		file := &files[i]
+		if file.num < num && is_canonical(bc, file) && file.num >= base {
+			base, found = file.num, true
+		}
+	}
+	from := uint64(0)
+	if found {
+		from = base + 1
+	}
+	if e = check_pruned(bc, from); e != nil {
+		return
+	}
+	for i := range files {
+		file := &files[i]
+		if file.num < num && is_canonical(bc, file) {
+			continue
+		}
+		if err := os.Remove(zconfig.State1_file(file.name)); err != nil {
+			e = err
+			return
+		}
+		removed++
+	}
+	for _, tmp := range tmps {
+		os.Remove(zconfig.State1_file(tmp))
+	}
+	log.Info("Rescanning lstate", "from", num, "base", base, "removed", removed)
+	return
+}
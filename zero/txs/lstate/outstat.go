@@ -18,11 +18,13 @@ package lstate
 
 import (
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
 
 	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/log"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/zero/txs/zstate/tri"
 	"github.com/sero-cash/go-sero/zero/utils"
@@ -98,6 +100,29 @@ func outStatName(root *keys.Uint256) (ret []byte) {
 	return
 }
 
+// prune_spent is non zero if the statistics of the spent outs are dropped.
+var prune_spent int32
+
+// SetPruneSpent sets whether the selection statistics of the outs of the local
+// accounts are dropped once the outs are spent.
+func SetPruneSpent(prune bool) {
+	if prune {
+		atomic.StoreInt32(&prune_spent, 1)
+	} else {
+		atomic.StoreInt32(&prune_spent, 0)
+	}
+}
+
+// clearOutStat drops the selection statistics of a spent out.
+func clearOutStat(st *txstate.State, root *keys.Uint256) {
+	if atomic.LoadInt32(&prune_spent) == 0 {
+		return
+	}
+	if err := st.Tri().TryGlobalPut(outStatName(root), nil); err != nil {
+		log.Warn("Failed to drop the statistics of a spent out", "root", root, "err", err)
+	}
+}
+
 func UpdateOutStat(st *txstate.State, out *OutState) {
 	os := OutStat{}
 	os.Z = out.Z
@@ -0,0 +1,82 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+)
+
+// Relevance tells what a block changed for the local accounts.
+type Relevance struct {
+	Outs   bool // outs received
+	Spends bool // outs spent
+	Pkgs   bool // pkgs created, closed or transferred
+}
+
+// Any reports whether the block changed anything for the local accounts.
+func (self Relevance) Any() bool {
+	return self.Outs || self.Spends || self.Pkgs
+}
+
+// BlockRelevance is the relevance of a scanned block.
+type BlockRelevance struct {
+	Num  uint64
+	Hash common.Hash
+	Relevance
+}
+
+var relevance_feed event.Feed
+
+// SubscribeBlockRelevance notifies the relevance of the blocks as the outs
+// are scanned. Blocks scanned again after a reorganisation are notified again.
+func SubscribeBlockRelevance(ch chan<- BlockRelevance) event.Subscription {
+	return relevance_feed.Subscribe(ch)
+}
+
+func relevantName(num uint64, hash *keys.Uint256) (ret []byte) {
+	ret = []byte("LSTATE_RELEVANT_")
+	ret = append(ret, encodeNum(num)...)
+	ret = append(ret, hash[:]...)
+	return
+}
+
+// markRelevant records that the block of num and hash received, spent or
+// changed the packages of outs of the local accounts.
+func (self *State) markRelevant(num uint64, hash *keys.Uint256) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.putRelevant(num, hash)
+}
+
+// putRelevant records a relevant block, the caller holds the write lock.
+func (self *State) putRelevant(num uint64, hash *keys.Uint256) {
+	if err := self.State.State.Tri().TryGlobalPut(relevantName(num, hash), []byte{1}); err != nil {
+		log.Warn("Failed to record a relevant block", "num", num, "err", err)
+	}
+}
+
+// Relevant reports whether the scanned block of num and hash received, spent
+// or changed the packages of outs of the local accounts.
+func (self *State) Relevant(num uint64, hash *keys.Uint256) bool {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	value, err := self.State.State.Tri().TryGlobalGet(relevantName(num, hash))
+	return err != nil || len(value) > 0
+}
@@ -0,0 +1,123 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// TksDigest identifies the set of accounts the outs are scanned for. State
+// files can only be shared between nodes scanning for the same accounts.
+func TksDigest() (common.Hash, error) {
+	bc := current_bc
+	if bc == nil {
+		return common.Hash{}, errNotRunning
+	}
+	tks := bc.GetTks()
+	sort.Slice(tks, func(i, j int) bool { return bytes.Compare(tks[i][:], tks[j][:]) < 0 })
+	data := make([]byte, 0, len(tks)*64)
+	for _, tk := range tks {
+		data = append(data, tk[:]...)
+	}
+	return crypto.Keccak256Hash(data), nil
+}
+
+// NewestStateFile returns the name, block number and content of the newest
+// state file on the canonical chain.
+func NewestStateFile() (name string, num uint64, data []byte, e error) {
+	bc := current_bc
+	if bc == nil {
+		e = errNotRunning
+		return
+	}
+	files, _, err := list_state1_files()
+	if err != nil {
+		e = err
+		return
+	}
+	newest := -1
+	for i := range files {
+		if newest >= 0 && files[i].num <= files[newest].num {
+			continue
+		}
+		if is_canonical(bc, &files[i]) {
+			newest = i
+		}
+	}
+	if newest < 0 {
+		e = errors.New("no canonical lstate file")
+		return
+	}
+	name, num = files[newest].name, files[newest].num
+	data, e = ioutil.ReadFile(zconfig.State1_file(name))
+	return
+}
+
+// ImportStateFile stores the state file of a canonical block received from
+// another node and removes the local state files above it, so the out scanning
+// loop continues from the imported state.
+func ImportStateFile(name string, data []byte) error {
+	bc := current_bc
+	if bc == nil {
+		return errNotRunning
+	}
+	var num uint64
+	if _, err := fmt.Sscanf(name, "%d.", &num); err != nil {
+		return fmt.Errorf("invalid lstate file name %q", name)
+	}
+	file := state1File{name, num, int64(len(data))}
+	if !is_canonical(bc, &file) {
+		return fmt.Errorf("lstate file %s is not on the local canonical chain", name)
+	}
+	if err := rlp.DecodeBytes(data, &StateData{}); err != nil {
+		return fmt.Errorf("corrupt lstate file %s: %v", name, err)
+	}
+	process_lock.Lock()
+	defer process_lock.Unlock()
+
+	files, tmps, err := list_state1_files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.num < num || f.name == name {
+			continue
+		}
+		if err := os.Remove(zconfig.State1_file(f.name)); err != nil {
+			return err
+		}
+	}
+	for _, tmp := range tmps {
+		os.Remove(zconfig.State1_file(tmp))
+	}
+	if err := write_state1_file(name, data); err != nil {
+		return err
+	}
+	log.Info("Imported lstate file", "file", name)
+	return nil
+}
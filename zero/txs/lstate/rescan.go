@@ -0,0 +1,303 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+// rescan_batch is the number of blocks an account rescan scans at once,
+// between the passes of the out scanning loop.
+const rescan_batch = 256
+
+var errRescanRunning = errors.New("an account rescan is already running")
+
+// RescanProgress reports the progress of the last account rescan.
+type RescanProgress struct {
+	Running bool   `json:"running"`
+	From    uint64 `json:"from"`
+	Current uint64 `json:"current"` // next block to scan
+	Target  uint64 `json:"target"`  // last block to scan, the following ones were scanned for the account already
+	Kept    int    `json:"kept"`    // outs received before From and still unspent
+	Found   int    `json:"found"`   // outs received from From on
+	Error   string `json:"error,omitempty"`
+}
+
+var (
+	rescan_mu       sync.Mutex
+	rescan_running  bool
+	rescan_progress RescanProgress
+)
+
+// RescanAccount rebuilds the outs of an account from block from on in the
+// background, e.g. after its key was imported or its outs look corrupt. The
+// unspent outs received before from are kept, the later ones are dropped and
+// found again by scanning the blocks, and the history of the account restarts
+// at from. Progress is reported by RescanStatus.
+func RescanAccount(tk keys.Uint512, from uint64) error {
+	bc := current_bc
+	if bc == nil {
+		return errNotRunning
+	}
+	if head := bc.GetCurrenHeader().Number.Uint64(); from > head {
+		return fmt.Errorf("rescan block %d is above the current head %d", from, head)
+	}
+	return start_rescan(bc, tk, from, func(st *State) (int, error) {
+		return st.reset_account(&tk, from), nil
+	})
+}
+
+// start_rescan runs prepare on the current state, then scans the blocks from
+// from on for the outs of an account in the background. prepare returns the
+// number of outs the account keeps.
+func start_rescan(bc BlockChain, tk keys.Uint512, from uint64, prepare func(st *State) (int, error)) error {
+	if err := check_pruned(bc, from); err != nil {
+		return err
+	}
+	rescan_mu.Lock()
+	defer rescan_mu.Unlock()
+	if rescan_running {
+		return errRescanRunning
+	}
+	rescan_running = true
+	rescan_progress = RescanProgress{Running: true, From: from, Current: from}
+	go rescan_account(bc, tk, from, prepare)
+	return nil
+}
+
+// RescanStatus returns the progress of the last account rescan.
+func RescanStatus() RescanProgress {
+	rescan_mu.Lock()
+	defer rescan_mu.Unlock()
+	return rescan_progress
+}
+
+func rescan_account(bc BlockChain, tk keys.Uint512, from uint64, prepare func(st *State) (int, error)) {
+	done := func(err error) {
+		rescan_mu.Lock()
+		if err != nil {
+			rescan_progress.Error = err.Error()
+			log.Error("Failed to rescan account outs", "from", from, "err", err)
+		} else {
+			log.Info("Rescanned account outs", "from", from, "kept", rescan_progress.Kept, "found", rescan_progress.Found)
+		}
+		rescan_progress.Running = false
+		rescan_running = false
+		rescan_mu.Unlock()
+	}
+
+	// Blocks scanned from now on by the loop include the account already
+	process_lock.Lock()
+	st := current_state1
+	target, ok := ScannedNumber()
+	if st == nil || !ok {
+		process_lock.Unlock()
+		done(errNotRunning)
+		return
+	}
+	kept, err := prepare(st)
+	if err == nil {
+		err = save_state(bc, st)
+	}
+	process_lock.Unlock()
+	if err != nil {
+		done(err)
+		return
+	}
+
+	rescan_mu.Lock()
+	rescan_progress.Target = target
+	rescan_progress.Kept = kept
+	rescan_mu.Unlock()
+
+	for next := from; next <= target; next += rescan_batch {
+		last := next + rescan_batch - 1
+		if last > target {
+			last = target
+		}
+		process_lock.Lock()
+		found, err := rescan_blocks(bc, &tk, next, last)
+		process_lock.Unlock()
+		if err != nil {
+			done(err)
+			return
+		}
+		rescan_mu.Lock()
+		rescan_progress.Current = last + 1
+		rescan_progress.Found += found
+		rescan_mu.Unlock()
+	}
+	done(nil)
+}
+
+// rescan_blocks scans the blocks from from to to for the outs of an account
+// and saves the current state. It must be called with the process lock held.
+func rescan_blocks(bc BlockChain, tk *keys.Uint512, from uint64, to uint64) (found int, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = fmt.Errorf("rescan blocks %d to %d: %v", from, to, r)
+		}
+	}()
+	st := current_state1
+	if st == nil {
+		return 0, errNotRunning
+	}
+	for num := from; num <= to; num++ {
+		block, hash, err := block_record(bc, st.State, num)
+		if err != nil {
+			return found, err
+		}
+		found += st.rescan_block(tk, num, hash, block)
+	}
+	return found, save_state(bc, st)
+}
+
+// save_state writes the state file of the last scanned block, so the out
+// scanning loop continues from the changes made to the current state. It
+// must be called with the process lock held.
+func save_state(bc BlockChain, st *State) (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = fmt.Errorf("save lstate: %v", r)
+		}
+	}()
+	scanned, ok := ScannedNumber()
+	if !ok {
+		return errNotRunning
+	}
+	header := bc.GetHeaderByNumber(scanned)
+	if header == nil {
+		return fmt.Errorf("block %d not found", scanned)
+	}
+	hash := header.Hash()
+	st.Finalize(state1_file_name(scanned, &hash), scanned)
+	return nil
+}
+
+// block_record returns the zstate record of the outs created and spent by a
+// canonical block and the hash of the block, from the record or else the
+// state of the block.
+func block_record(bc BlockChain, stz *zstate.ZState, num uint64) (*zstate.Block, *keys.Uint256, error) {
+	header := bc.GetHeaderByNumber(num)
+	if header == nil {
+		return nil, nil, fmt.Errorf("block %d not found", num)
+	}
+	hash := header.Hash()
+	if block := stz.GetBlock(num, hash.HashToUint256()); block != nil {
+		return block, hash.HashToUint256(), nil
+	}
+	var st *zstate.ZState
+	func() {
+		defer func() { recover() }()
+		st = bc.NewState(&hash)
+	}()
+	if st == nil {
+		return nil, nil, fmt.Errorf("zstate record and state of block %d are pruned", num)
+	}
+	return &zstate.Block{
+		Pkgs:  st.Pkgs.Block.Pkgs,
+		Dels:  st.State.Block.Dels,
+		Roots: st.State.Block.Roots,
+	}, hash.HashToUint256(), nil
+}
+
+// reset_account drops the outs of an account received from block from on or
+// spent already, and restarts its index at from with the outs it keeps. It
+// returns the number of outs kept.
+func (self *State) reset_account(tk *keys.Uint512, from uint64) int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	index := &AccountIndex{Tk: *tk, Since: from, Gen: newGen()}
+	delete(self.changes, *tk)
+	kept := []keys.Uint256{}
+	for _, root := range self.G2touts[*tk] {
+		out := self.G2outs[root]
+		if out.Num < from && !self.State.State.HasIn(&out.Trace) && !self.State.State.HasIn(&out.Root) {
+			kept = append(kept, root)
+			if tkn := out.Out_O.Asset.Tkn; tkn != nil {
+				index.BaseTkns = addToken(index.BaseTkns, tkn)
+			}
+			if tkt := out.Out_O.Asset.Tkt; tkt != nil && tkt.Value != keys.Empty_Uint256 {
+				index.BaseTkts = append(index.BaseTkts, *tkt)
+			}
+			continue
+		}
+		for i, wout := range self.G2wouts {
+			if wout == root {
+				self.del_wout_dirty(uint(i))
+				break
+			}
+		}
+		self.addBalance(out, -1)
+		clearOutStat(&self.State.State, &root)
+		delete(self.G2outs, root)
+		delete(self.G2outs, out.Trace)
+	}
+	if len(kept) == 0 {
+		delete(self.G2touts, *tk)
+	} else {
+		self.G2touts[*tk] = kept
+	}
+	self.G2accounts[*tk] = index
+	return len(kept)
+}
+
+// rescan_block adds the outs of a block received by an account and drops the
+// ones the block spent, like UpdateWitness does for all local accounts. The
+// packages of the block are left alone. It returns the number of outs found.
+func (self *State) rescan_block(tk *keys.Uint512, num uint64, hash *keys.Uint256, block *zstate.Block) (found int) {
+	for _, del := range block.Dels {
+		self.del(&del, num)
+	}
+	tks := []keys.Uint512{*tk}
+	oss := make([]*txstate.OutState, len(block.Roots))
+	for i := range block.Roots {
+		os, err := self.State.State.GetOut(&block.Roots[i])
+		if err != nil {
+			panic(err)
+		}
+		if os == nil {
+			panic(fmt.Sprintf("out of block %d not found", num))
+		}
+		oss[i] = os
+	}
+	wouts := make([]*OutState, len(oss))
+	scan(len(oss), func(i int) {
+		wouts[i] = matchOut(tks, oss[i], &block.Roots[i], num)
+	})
+	for i, wos := range wouts {
+		if wos != nil {
+			if out, _ := self.GetOut(&block.Roots[i]); out != nil {
+				continue
+			}
+			self.addWout(&block.Roots[i], wos)
+			found++
+		}
+	}
+	if self.commitChanges(hash) {
+		self.markRelevant(num, hash)
+	}
+	return
+}
@@ -0,0 +1,133 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// rewind_to is the block the local outs are rolled back to by the next pass of
+// the out scanning loop, offset by one so zero means no rewind is pending.
+var rewind_to uint64
+
+// rewind_ch wakes the out scanning loop up for a pending rewind.
+var rewind_ch = make(chan struct{}, 1)
+
+// Rewind rolls the local outs back to block num after the canonical chain
+// dropped the blocks above it, in a reorg or a SetHead. The out scanning loop
+// reloads the newest canonical state at or below num and rescans the new
+// branch at once; until then ScannedNumber reports num at most.
+func Rewind(num uint64) {
+	for {
+		pending := atomic.LoadUint64(&rewind_to)
+		if pending != 0 && pending <= num+1 {
+			break
+		}
+		if atomic.CompareAndSwapUint64(&rewind_to, pending, num+1) {
+			break
+		}
+	}
+	for {
+		scanned := atomic.LoadUint64(&scanned_num)
+		if scanned <= num+1 || atomic.CompareAndSwapUint64(&scanned_num, scanned, num+1) {
+			break
+		}
+	}
+	select {
+	case rewind_ch <- struct{}{}:
+	default:
+	}
+}
+
+// rewind_pending reports whether a rewind waits for the out scanning loop.
+func rewind_pending() bool {
+	return atomic.LoadUint64(&rewind_to) != 0
+}
+
+// wait_scan sleeps until the next pass of the out scanning loop, returning
+// early for a rewind.
+func wait_scan(d time.Duration) {
+	select {
+	case <-rewind_ch:
+	case <-time.After(d):
+	}
+}
+
+// apply_rewind makes the newest canonical state at or below the pending
+// rewind block current, removing the state files of the dropped blocks.
+// It must be called with the process lock held.
+func apply_rewind(bc BlockChain) (e error) {
+	target := atomic.SwapUint64(&rewind_to, 0)
+	if target == 0 {
+		return
+	}
+	num := target - 1
+	defer func() {
+		if r := recover(); r != nil {
+			e = fmt.Errorf("rewind lstate to %d: %v", num, r)
+			log.Error("Failed to rewind lstate", "number", num, "err", e)
+		}
+	}()
+
+	files, _, err := list_state1_files()
+	if err != nil {
+		return err
+	}
+	var base *state1File
+	removed := 0
+	for i := range files {
+		file := &files[i]
+		if is_canonical(bc, file) {
+			if file.num <= num && (base == nil || file.num > base.num) {
+				base = file
+			}
+			continue
+		}
+		if file.num > num {
+			if err := os.Remove(zconfig.State1_file(file.name)); err != nil {
+				return err
+			}
+			removed++
+		}
+	}
+	if base == nil {
+		// Nothing to reload, the scan starts over from the genesis
+		if err := check_pruned(bc, 0); err != nil {
+			log.Error("Failed to rewind lstate", "number", num, "removed", removed, "err", err)
+			return err
+		}
+		atomic.StoreUint64(&scanned_num, 0)
+		bc.CashChose().Store(uint64(0))
+		log.Warn("Rewound lstate without a canonical state", "number", num, "removed", removed)
+		return
+	}
+	header := bc.GetHeaderByNumber(base.num)
+	hash := header.Hash()
+	st := LoadState(bc.NewState(&hash), base.name)
+	current_state1 = &st
+	setScanned(bc, base.num)
+	bc.CashChose().Store(base.num)
+
+	log.Info("Rewound lstate", "number", num, "base", base.num, "removed", removed)
+	return
+}
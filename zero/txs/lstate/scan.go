@@ -0,0 +1,188 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package lstate
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sero-cash/go-czero-import/keys"
+)
+
+// scan_workers is the number of goroutines matching the outs of a block
+// against the local accounts, zero for one per CPU.
+var scan_workers int32
+
+// SetScanWorkers sets the size of the out scanning worker pool, zero or less
+// to use one worker per CPU.
+func SetScanWorkers(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt32(&scan_workers, int32(n))
+}
+
+// ScanWorkers returns the size of the out scanning worker pool.
+func ScanWorkers() int {
+	if n := atomic.LoadInt32(&scan_workers); n > 0 {
+		return int(n)
+	}
+	return runtime.NumCPU()
+}
+
+// scan runs f for every index below count on the worker pool and returns
+// once all of them are done. A panic of a worker is raised again in the
+// caller, where the scanning loop recovers from it.
+func scan(count int, f func(i int)) {
+	workers := ScanWorkers()
+	if workers > count {
+		workers = count
+	}
+	if workers <= 1 {
+		for i := 0; i < count; i++ {
+			f(i)
+		}
+		return
+	}
+	next := int64(-1)
+	var failure atomic.Value
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					failure.Store(fmt.Sprintf("%v", r))
+					atomic.StoreInt64(&next, int64(count))
+				}
+			}()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= count {
+					return
+				}
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+	if r := failure.Load(); r != nil {
+		panic(r)
+	}
+}
+
+// addWout records an out received by a local account, the caller holds no
+// lock.
+func (self *State) addWout(root *keys.Uint256, wos *OutState) {
+	self.mu.Lock()
+	self.G2wouts = append(self.G2wouts, *root)
+	self.G2touts[wos.Tk] = append(self.G2touts[wos.Tk], *root)
+	self.G2outs[*root] = wos
+	self.G2outs[wos.Trace] = wos
+	self.addBalance(wos, 1)
+	self.mu.Unlock()
+	self.index_out(wos, wos.Num, false)
+}
+
+// delWout forgets an out spent by a local account, the caller holds no lock.
+func (self *State) delWout(src *OutState, num uint64) {
+	self.mu.Lock()
+	found := false
+	for i, wout := range self.G2wouts {
+		if wout == src.Root {
+			self.del_wout_dirty(uint(i))
+			found = true
+			break
+		}
+	}
+	if found {
+		touts := self.G2touts[src.Tk]
+		for i, wout := range touts {
+			if wout == src.Root {
+				touts = append(touts[:i], touts[i+1:]...)
+				break
+			}
+		}
+		if len(touts) == 0 {
+			delete(self.G2touts, src.Tk)
+		} else {
+			self.G2touts[src.Tk] = touts
+		}
+		self.addBalance(src, -1)
+		clearOutStat(&self.State.State, &src.Root)
+	}
+	self.mu.Unlock()
+	if found {
+		self.index_out(src, num, true)
+	}
+}
+
+// addBalance adds the token of an out to the cached balance of its account,
+// or subtracts it for a negative sign. The caller holds the write lock.
+func (self *State) addBalance(out *OutState, sign int) {
+	tkn := out.Out_O.Asset.Tkn
+	if tkn == nil {
+		return
+	}
+	balance, ok := self.balances[out.Tk]
+	if !ok {
+		balance = make(map[keys.Uint256]*big.Int)
+		self.balances[out.Tk] = balance
+	}
+	value, ok := balance[tkn.Currency]
+	if !ok {
+		value = new(big.Int)
+		balance[tkn.Currency] = value
+	}
+	if sign < 0 {
+		value.Sub(value, tkn.Value.ToIntRef())
+	} else {
+		value.Add(value, tkn.Value.ToIntRef())
+	}
+	if value.Sign() == 0 {
+		delete(balance, tkn.Currency)
+	}
+	if len(balance) == 0 {
+		delete(self.balances, out.Tk)
+	}
+}
+
+// indexWouts builds the per-account out lists and the cached balances from
+// the outs held after a load.
+func (self *State) indexWouts() {
+	for _, root := range self.G2wouts {
+		out := self.G2outs[root]
+		self.G2touts[out.Tk] = append(self.G2touts[out.Tk], root)
+		self.addBalance(out, 1)
+	}
+}
+
+// GetBalance returns the tokens held by an account, as cached by the scanning
+// of the blocks.
+func (self *State) GetBalance(tk *keys.Uint512) (tkns map[keys.Uint256]*big.Int) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	tkns = make(map[keys.Uint256]*big.Int)
+	for cy, value := range self.balances[*tk] {
+		tkns[cy] = new(big.Int).Set(value)
+	}
+	return
+}
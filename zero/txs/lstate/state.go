@@ -20,14 +20,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"runtime/debug"
 	"sync"
 
 	"github.com/sero-cash/go-sero/log"
 
-	"github.com/sero-cash/go-sero/zero/txs/assets"
-
 	"github.com/sero-cash/go-sero/common/hexutil"
 
 	"github.com/sero-cash/go-sero/zero/txs/pkg"
@@ -54,8 +53,16 @@ type State struct {
 	G2wouts     []keys.Uint256
 	G2pkgs_from map[keys.Uint256]*Pkg
 	G2pkgs_to   map[keys.Uint256]*Pkg
+	G2accounts  map[keys.Uint512]*AccountIndex
+	G2touts     map[keys.Uint512][]keys.Uint256
+
+	balances map[keys.Uint512]map[keys.Uint256]*big.Int
+
+	changes   map[keys.Uint512]*BalanceChange // changes of the block being indexed
+	tktEvents []*TktEvent                     // ticket events of the block being indexed
 
-	data StateData
+	relevance Relevance
+	data      StateData
 }
 
 func LoadState(zstate *zstate.ZState, loadName string) (state State) {
@@ -64,20 +71,10 @@ func LoadState(zstate *zstate.ZState, loadName string) (state State) {
 	return
 }
 
-func (self *State) add_out_dirty(k *keys.Uint256, state *OutState) {
-	self.mu.Lock()
-	defer self.mu.Unlock()
-	self.G2outs[*k] = state
-}
-
 func (self *State) del_wout_dirty(i uint) {
 	self.G2wouts = append(self.G2wouts[:i], self.G2wouts[i+1:]...)
 }
 
-func (self *State) append_wout_dirty(k *keys.Uint256) {
-	self.G2wouts = append(self.G2wouts, *k)
-}
-
 func (state *State) clear_dirty() {
 }
 
@@ -95,6 +92,10 @@ func (self *State) load(loadName string) {
 	self.G2wouts = []keys.Uint256{}
 	self.G2pkgs_from = make(map[keys.Uint256]*Pkg)
 	self.G2pkgs_to = make(map[keys.Uint256]*Pkg)
+	self.G2accounts = make(map[keys.Uint512]*AccountIndex)
+	self.G2touts = make(map[keys.Uint512][]keys.Uint256)
+	self.balances = make(map[keys.Uint512]map[keys.Uint256]*big.Int)
+	self.changes = make(map[keys.Uint512]*BalanceChange)
 	self.clear_dirty()
 
 	if loadName != "" {
@@ -135,6 +136,8 @@ func (self *State) toData() {
 	}
 	self.data.Pkgs_to = pkgs_to
 
+	self.accountsToData()
+
 	self.data.Outs = outs
 }
 
@@ -145,6 +148,7 @@ func (self *State) dataTo() {
 		self.G2outs[root] = out
 		self.G2outs[out.Trace] = out
 	}
+	self.indexWouts()
 
 	for _, pkg := range self.data.Pkgs_from {
 		self.G2pkgs_from[pkg.Pkg.Z.Pack.Id] = pkg
@@ -153,14 +157,20 @@ func (self *State) dataTo() {
 	for _, pkg := range self.data.Pkgs_to {
 		self.G2pkgs_to[pkg.Pkg.Z.Pack.Id] = pkg
 	}
+
+	for _, index := range self.data.Accounts {
+		self.G2accounts[index.Tk] = index
+	}
+	if len(self.data.Accounts) == 0 {
+		self.indexHeldOuts()
+	}
 }
 
 func (self *State) Finalize(saveName string, num uint64) {
 	self.toData()
 	self.clear_dirty()
-	current_file := zconfig.State1_file(saveName)
 	serial := self.data.Serial()
-	if err := ioutil.WriteFile(current_file, serial, os.ModePerm); err != nil {
+	if err := write_state1_file(saveName, serial); err != nil {
 		panic(err)
 	} else {
 	}
@@ -182,22 +192,15 @@ func (state *State) GetOut(root *keys.Uint256) (src *OutState, e error) {
 	}
 }
 
-func (self *State) addOut(tks []keys.Uint512, os *txstate.OutState, root *keys.Uint256, num uint64) {
-
-	t := utils.TR_enter(fmt.Sprintf("ADD_OUT num=%v", num))
-
-	self.addWouts(tks, os, root, num)
-
-	t.Leave()
-	return
-}
-
-func (state *State) addWouts(tks []keys.Uint512, os *txstate.OutState, root *keys.Uint256, num uint64) {
+// matchOut returns the out of a block owned by one of the local accounts, nil
+// if none of them owns it. It only reads the out and is run by the workers of
+// the scanning pool.
+func matchOut(tks []keys.Uint512, os *txstate.OutState, root *keys.Uint256, num uint64) *OutState {
 	for _, tk := range tks {
 		if os.IsO() {
 			out_o := os.Out_O
 			if out_o.Asset.Tkn == nil && out_o.Asset.Tkt == nil {
-				break
+				return nil
 			}
 			no_tkn_value := false
 			if out_o.Asset.Tkn != nil {
@@ -217,16 +220,16 @@ func (state *State) addWouts(tks []keys.Uint512, os *txstate.OutState, root *key
 			}
 
 			if no_tkt_value && no_tkn_value {
-				break
+				return nil
 			}
 
 			if out_o.Addr == (keys.PKr{}) {
-				break
+				return nil
 			}
 
-			t := utils.TR_enter("addWouts---IsMyPKr")
+			t := utils.TR_enter("matchOut---IsMyPKr")
 			if succ := keys.IsMyPKr(&tk, &out_o.Addr); succ {
-				t.Renter("addWouts---EncOutput")
+				t.Renter("matchOut---EncOutput")
 				out_z := &stx.Out_Z{}
 				{
 					desc_info := cpt.EncOutputInfo{}
@@ -244,7 +247,6 @@ func (state *State) addWouts(tks []keys.Uint512, os *txstate.OutState, root *key
 					out_z.EInfo = desc_info.Einfo
 					out_z.OutCM = *os.ToOutCM()
 				}
-				state.append_wout_dirty(root)
 				wos := OutState{}
 				wos.Root = *root
 				wos.RootCM = *os.ToRootCM()
@@ -255,39 +257,16 @@ func (state *State) addWouts(tks []keys.Uint512, os *txstate.OutState, root *key
 				wos.Z = false
 				wos.Trace = cpt.GenTil(&tk, os.ToRootCM())
 				wos.Num = num
-				state.add_out_dirty(root, &wos)
-				state.add_out_dirty(&wos.Trace, &wos)
 				t.Leave()
-				break
+				return &wos
 			} else {
 				t.Leave()
 			}
 		} else {
 			if succ := keys.IsMyPKr(&tk, &os.Out_Z.PKr); succ {
-				key, flag := keys.FetchKey(&tk, &os.Out_Z.RPK)
-
-				info_desc := cpt.InfoDesc{}
-				info_desc.Key = key
-				info_desc.Flag = flag
-				info_desc.Einfo = os.Out_Z.EInfo
-
-				cpt.DecOutput(&info_desc)
-
-				if e := stx.ConfirmOut_Z(&info_desc, os.Out_Z); e == nil {
-					state.append_wout_dirty(root)
+				if out_o, e := stx.DecOut_Z(&tk, os.Out_Z); e == nil {
 					wos := OutState{}
-					wos.Out_O.Addr = os.Out_Z.PKr
-					wos.Out_O.Asset = assets.NewAsset(
-						&assets.Token{
-							info_desc.Tkn_currency,
-							utils.NewU256_ByKey(&info_desc.Tkn_value),
-						},
-						&assets.Ticket{
-							info_desc.Tkt_category,
-							info_desc.Tkt_value,
-						},
-					)
-					wos.Out_O.Memo = info_desc.Memo
+					wos.Out_O = out_o
 					wos.Out_Z = os.Out_Z.Clone().ToRef()
 					wos.Root = *root
 					wos.RootCM = *os.ToRootCM()
@@ -296,18 +275,17 @@ func (state *State) addWouts(tks []keys.Uint512, os *txstate.OutState, root *key
 					wos.Z = true
 					wos.Trace = cpt.GenTil(&tk, os.ToRootCM())
 					wos.Num = num
-					state.add_out_dirty(root, &wos)
-					state.add_out_dirty(&wos.Trace, &wos)
-					break
+					return &wos
 				} else {
 					log.Error("My out_z confirm error", "root", hexutil.Encode(os.ToRootCM()[:]))
 				}
 			}
 		}
 	}
+	return nil
 }
 
-func (state *State) del(del *keys.Uint256) (e error) {
+func (state *State) del(del *keys.Uint256, num uint64) (e error) {
 	if src, err := state.GetOut(del); err != nil {
 		e = err
 		return
@@ -316,13 +294,7 @@ func (state *State) del(del *keys.Uint256) (e error) {
 			i := 0
 			i++
 		} else {
-			for i, wout := range state.G2wouts {
-				if wout == src.Root {
-					state.del_wout_dirty(uint(i))
-					break
-				} else {
-				}
-			}
+			state.delWout(src, num)
 		}
 	}
 	return
@@ -334,9 +306,11 @@ func (state *State) addPkg(tks []keys.Uint512, id *keys.Uint256, pg *pkgstate.ZP
 
 	if _, ok := state.G2pkgs_from[*id]; ok {
 		delete(state.G2pkgs_from, *id)
+		state.relevance.Pkgs = true
 	}
 	if _, ok := state.G2pkgs_to[*id]; ok {
 		delete(state.G2pkgs_to, *id)
+		state.relevance.Pkgs = true
 	}
 
 	if pg != nil {
@@ -372,6 +346,9 @@ func (state *State) addPkg(tks []keys.Uint512, id *keys.Uint256, pg *pkgstate.ZP
 				break
 			}
 		}
+		if insert_from || insert_to {
+			state.relevance.Pkgs = true
+		}
 	}
 }
 
@@ -403,31 +380,43 @@ func (state *State) GetPkgs(tk *keys.Uint512, is_from bool) (ret []*Pkg) {
 	return
 }
 
-func (state *State) UpdateWitness(tks []keys.Uint512, num uint64, block *zstate.Block) {
+func (state *State) UpdateWitness(tks []keys.Uint512, num uint64, hash *keys.Uint256, block *zstate.Block) Relevance {
+	state.relevance = Relevance{}
 	for _, del := range block.Dels {
-		state.del(&del)
+		state.del(&del, num)
 	}
-	for _, root := range block.Roots {
-		t := utils.TR_enter("UpdateWitness---RootKey")
-
-		if os, err := state.State.State.GetOut(&root); err != nil {
+	oss := make([]*txstate.OutState, len(block.Roots))
+	for i := range block.Roots {
+		if os, err := state.State.State.GetOut(&block.Roots[i]); err != nil {
 			panic(err)
 		} else {
 			if os == nil {
 				panic("gen witness out from B2outs can not find in G2outs")
 			} else {
 			}
-			t.Renter("UpdateWitness---ToRootCM")
-			t.Renter("UpdateWitness---addOut")
-			state.addOut(tks, os, &root, num)
+			oss[i] = os
 		}
-		t.Leave()
 	}
+	t := utils.TR_enter(fmt.Sprintf("ADD_OUT num=%v count=%v", num, len(oss)))
+	wouts := make([]*OutState, len(oss))
+	scan(len(oss), func(i int) {
+		wouts[i] = matchOut(tks, oss[i], &block.Roots[i], num)
+	})
+	for i, wos := range wouts {
+		if wos != nil {
+			state.addWout(&block.Roots[i], wos)
+		}
+	}
+	t.Leave()
 	for _, id := range block.Pkgs {
 		pg := state.State.Pkgs.GetPkg(&id)
 		state.addPkg(tks, &id, pg)
 	}
-	return
+	state.commitChanges(hash)
+	if state.relevance.Any() {
+		state.markRelevant(num, hash)
+	}
+	return state.relevance
 }
 
 func (self *State) GetOuts(tk *keys.Uint512) (outs []*OutState, e error) {
@@ -438,7 +427,10 @@ func (self *State) GetOuts(tk *keys.Uint512) (outs []*OutState, e error) {
 			e = fmt.Errorf("%v", r)
 		}
 	}()
-	for _, root := range self.G2wouts {
+	self.mu.RLock()
+	roots := append([]keys.Uint256{}, self.G2touts[*tk]...)
+	self.mu.RUnlock()
+	for _, root := range roots {
 		if src, err := self.GetOut(&root); err != nil {
 			e = err
 			return
@@ -25,6 +25,7 @@ type StateData struct {
 	Pkgs_from []*Pkg
 	Pkgs_to   []*Pkg
 	MaxNum    uint64
+	Accounts  []*AccountIndex `rlp:"tail"`
 }
 
 func (self *StateData) Serial() (ret []byte) {
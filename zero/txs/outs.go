@@ -2,6 +2,7 @@ package txs
 
 import (
 	"errors"
+	"math/big"
 
 	"github.com/sero-cash/go-sero/zero/txs/pkg"
 
@@ -31,15 +32,32 @@ func GetOuts(tk *keys.Uint512) (outs []*lstate.OutState, e error) {
 	return st1.GetOuts(tk)
 }
 
-func GetRoots(tk *keys.Uint512, costTkns map[keys.Uint256]utils.U256, costTkts map[keys.Uint256][]keys.Uint256) (roots []keys.Uint256, tknMap map[keys.Uint256]utils.U256, tktMap map[keys.Uint256][]keys.Uint256, e error) {
+// GetBalance returns the tokens held by a local account, kept up to date by
+// the scanning of the blocks.
+func GetBalance(tk *keys.Uint512) (tkns map[keys.Uint256]*big.Int, e error) {
+	st1 := lstate.CurrentState1()
+	if st1 == nil {
+		e = errors.New("Get balance but lstate is nil")
+		return
+	}
+	return st1.GetBalance(tk), nil
+}
+
+func GetRoots(tk *keys.Uint512, costTkns map[keys.Uint256]utils.U256, costTkts map[keys.Uint256][]keys.Uint256, maxNum uint64, excluded []keys.Uint256, selection string) (roots []keys.Uint256, tknMap map[keys.Uint256]utils.U256, tktMap map[keys.Uint256][]keys.Uint256, e error) {
 	tknMap = make(map[keys.Uint256]utils.U256)
 	tktMap = make(map[keys.Uint256][]keys.Uint256)
 	if outs, err := GetOuts(tk); err != nil {
 		e = err
 		return
 	} else {
+		if maxNum > 0 {
+			outs = confirmedOuts(outs, maxNum)
+		}
+		if len(excluded) > 0 {
+			outs = excludeOuts(outs, excluded)
+		}
 		for cy, value := range costTkns {
-			tknRoots, amount, tkts, err := GetTknRoots(outs, &value, &cy)
+			tknRoots, amount, tkts, err := selectTknRoots(outs, &value, &cy, selection)
 			if err != nil {
 				e = err
 				return
@@ -198,3 +216,27 @@ func uint256Remove(slice []keys.Uint256, elem keys.Uint256) []keys.Uint256 {
 	}
 	return slice
 }
+
+// excludeOuts returns the outs the roots of which are not excluded.
+func excludeOuts(outs []*lstate.OutState, excluded []keys.Uint256) (ret []*lstate.OutState) {
+	skip := make(map[keys.Uint256]bool)
+	for _, root := range excluded {
+		skip[root] = true
+	}
+	for _, out := range outs {
+		if !skip[out.Root] {
+			ret = append(ret, out)
+		}
+	}
+	return
+}
+
+// confirmedOuts returns the outs created at or before block maxNum.
+func confirmedOuts(outs []*lstate.OutState, maxNum uint64) (ret []*lstate.OutState) {
+	for _, out := range outs {
+		if out.Num <= maxNum {
+			ret = append(ret, out)
+		}
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package txs
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/zero/txs/lstate"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// Coin selection strategies choosing the outs spent by a transaction.
+const (
+	SelectIndex       = ""            // order of the local out index
+	SelectLargest     = "largest"     // largest outs first, fewest inputs
+	SelectSmallest    = "smallest"    // smallest outs first, spending the dust
+	SelectExact       = "exact"       // outs matching the cost without change if found, largest first otherwise
+	SelectConsolidate = "consolidate" // smallest outs first, merging more of them into the change
+)
+
+const (
+	// selectMaxIns bounds the inputs the exact match and the consolidation
+	// select, above it the inputs of a transaction are no longer shielded.
+	selectMaxIns = 10
+	// exactMaxTries bounds the branches of the exact match search.
+	exactMaxTries = 100000
+)
+
+// ValidSelection reports whether s names a coin selection strategy.
+func ValidSelection(s string) bool {
+	switch s {
+	case SelectIndex, SelectLargest, SelectSmallest, SelectExact, SelectConsolidate:
+		return true
+	}
+	return false
+}
+
+// selectTknRoots selects the outs paying value of a currency with the given
+// strategy.
+func selectTknRoots(outs []*lstate.OutState, v *utils.U256, currency *keys.Uint256, selection string) (roots []keys.Uint256, amount utils.U256, tkts map[keys.Uint256][]keys.Uint256, e error) {
+	switch selection {
+	case SelectIndex:
+		return GetTknRoots(outs, v, currency)
+	case SelectLargest:
+		return GetTknRoots(sortedTknOuts(outs, currency, true), v, currency)
+	case SelectSmallest:
+		return GetTknRoots(sortedTknOuts(outs, currency, false), v, currency)
+	case SelectExact:
+		candidates := sortedTknOuts(outs, currency, true)
+		if exact := exactOuts(candidates, v.ToIntRef()); exact != nil {
+			candidates = exact
+		}
+		return GetTknRoots(candidates, v, currency)
+	case SelectConsolidate:
+		candidates := sortedTknOuts(outs, currency, false)
+		if roots, amount, tkts, e = GetTknRoots(candidates, v, currency); e != nil {
+			return
+		}
+		for _, out := range candidates[len(roots):] {
+			if len(roots) >= selectMaxIns {
+				break
+			}
+			roots = append(roots, out.Root)
+			amount.AddU(&out.Out_O.Asset.Tkn.Value)
+			if tkt := out.Out_O.Asset.Tkt; tkt != nil {
+				tkts[tkt.Category] = append(tkts[tkt.Category], tkt.Value)
+			}
+		}
+		return
+	default:
+		e = errors.New("unknown coin selection " + selection)
+		return
+	}
+}
+
+// sortedTknOuts returns the outs holding the currency, by value.
+func sortedTknOuts(outs []*lstate.OutState, currency *keys.Uint256, descending bool) (ret []*lstate.OutState) {
+	for _, out := range outs {
+		if out.Out_O.Asset.Tkn != nil && out.Out_O.Asset.Tkn.Currency == *currency {
+			ret = append(ret, out)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		c := ret[i].Out_O.Asset.Tkn.Value.Cmp(&ret[j].Out_O.Asset.Tkn.Value)
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+	return
+}
+
+// exactOuts searches, branch and bound, for at most selectMaxIns of the outs
+// sorted by decreasing value summing to target. It returns nil if there are
+// none or the search gives up.
+func exactOuts(outs []*lstate.OutState, target *big.Int) []*lstate.OutState {
+	values := make([]*big.Int, len(outs))
+	// rest[i] is the sum of the values from i on, bounding the branches
+	rest := make([]*big.Int, len(outs)+1)
+	rest[len(outs)] = new(big.Int)
+	for i := len(outs) - 1; i >= 0; i-- {
+		values[i] = outs[i].Out_O.Asset.Tkn.Value.ToIntRef()
+		rest[i] = new(big.Int).Add(rest[i+1], values[i])
+	}
+
+	tries := 0
+	picked := []int{}
+	var search func(i int, need *big.Int) bool
+	search = func(i int, need *big.Int) bool {
+		if need.Sign() == 0 {
+			return true
+		}
+		tries++
+		if i == len(outs) || len(picked) == selectMaxIns || tries > exactMaxTries || rest[i].Cmp(need) < 0 {
+			return false
+		}
+		if values[i].Cmp(need) <= 0 {
+			picked = append(picked, i)
+			if search(i+1, new(big.Int).Sub(need, values[i])) {
+				return true
+			}
+			picked = picked[:len(picked)-1]
+		}
+		return search(i+1, need)
+	}
+	if !search(0, target) {
+		return nil
+	}
+	ret := make([]*lstate.OutState, len(picked))
+	for i, j := range picked {
+		ret[i] = outs[j]
+	}
+	return ret
+}
@@ -1,6 +1,10 @@
 package stx
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
 	"github.com/sero-cash/go-czero-import/cpt"
 	"github.com/sero-cash/go-czero-import/keys"
 	"github.com/sero-cash/go-sero/crypto/sha3"
@@ -11,6 +15,16 @@ import (
 type PkgClose struct {
 	Id   keys.Uint256
 	Sign keys.Uint512
+
+	// Preimage holds the preimage of the hashlock of the package, if it has
+	// one.
+	Preimage []keys.Uint256 `rlp:"tail"`
+}
+
+func (self *PkgClose) writePreimage(d io.Writer) {
+	if len(self.Preimage) > 0 {
+		d.Write(self.Preimage[0][:])
+	}
 }
 
 func (this PkgClose) ToRef() (ret *PkgClose) {
@@ -22,6 +36,7 @@ func (self *PkgClose) ToHash() (ret keys.Uint256) {
 	d := sha3.NewKeccak256()
 	d.Write(self.Id[:])
 	d.Write(self.Sign[:])
+	self.writePreimage(d)
 	copy(ret[:], d.Sum(nil))
 	return ret
 }
@@ -36,6 +51,7 @@ func (self *PkgClose) ToHash_for_gen() (ret keys.Uint256) {
 func (self *PkgClose) ToHash_for_sign() (ret keys.Uint256) {
 	d := sha3.NewKeccak256()
 	d.Write(self.Id[:])
+	self.writePreimage(d)
 	copy(ret[:], d.Sum(nil))
 	return ret
 }
@@ -91,6 +107,49 @@ type PkgCreate struct {
 	PKr   keys.PKr
 	Pkg   pkg.Pkg_Z
 	Proof cpt.Proof
+
+	// Terms holds the conditions of closing the package, packages without
+	// them are closed by their owner at any time.
+	Terms []PkgTerms `rlp:"tail"`
+}
+
+// PkgTerms are the optional conditions of closing a package.
+type PkgTerms struct {
+	// Expiry is the number of blocks after which the creator may refund the
+	// package if it is not closed, 0 if it never expires.
+	Expiry uint64
+	// Hashlock is the SHA-256 hash of the preimage the owner reveals to close
+	// the package, empty for none.
+	Hashlock keys.Uint256
+}
+
+// GetTerms returns the conditions of closing the package.
+func (self *PkgCreate) GetTerms() PkgTerms {
+	if len(self.Terms) == 0 {
+		return PkgTerms{}
+	}
+	return self.Terms[0]
+}
+
+// ExpiryBlocks returns the number of blocks after which the package may be
+// refunded, 0 if it does not expire.
+func (self *PkgCreate) ExpiryBlocks() uint64 {
+	return self.GetTerms().Expiry
+}
+
+func (self *PkgCreate) writeTerms(d io.Writer) {
+	if len(self.Terms) > 0 {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], self.Terms[0].Expiry)
+		d.Write(b[:])
+		d.Write(self.Terms[0].Hashlock[:])
+	}
+}
+
+// Unlocks returns whether preimage opens the hashlock.
+func (self *PkgTerms) Unlocks(preimage *keys.Uint256) bool {
+	hash := sha256.Sum256(preimage[:])
+	return keys.Uint256(hash) == self.Hashlock
 }
 
 func (this PkgCreate) ToRef() (ret *PkgCreate) {
@@ -103,6 +162,7 @@ func (self *PkgCreate) ToHash() (ret keys.Uint256) {
 	d.Write(self.Id[:])
 	d.Write(self.PKr[:])
 	d.Write(self.Pkg.ToHash().NewRef()[:])
+	self.writeTerms(d)
 	copy(ret[:], d.Sum(nil))
 	return ret
 }
@@ -120,6 +180,7 @@ func (self *PkgCreate) ToHash_for_sign() (ret keys.Uint256) {
 	d.Write(self.Id[:])
 	d.Write(self.PKr[:])
 	d.Write(self.Pkg.ToHash().NewRef()[:])
+	self.writeTerms(d)
 	copy(ret[:], d.Sum(nil))
 	return ret
 }
@@ -135,6 +196,13 @@ type PkgDesc_Z struct {
 	Close    *PkgClose    `rlp:"nil"`
 }
 
+// HasTerms returns whether the created package carries closing terms or the
+// close reveals a preimage, which are only accepted from the PkgTerms fork on.
+func (self *PkgDesc_Z) HasTerms() bool {
+	return (self.Create != nil && len(self.Create.Terms) > 0) ||
+		(self.Close != nil && len(self.Close.Preimage) > 0)
+}
+
 func (this PkgDesc_Z) ToRef() (ret *PkgDesc_Z) {
 	ret = &this
 	return
@@ -77,6 +77,36 @@ func ConfirmOut_Z(deInfo *cpt.InfoDesc, out_z *Out_Z) (e error) {
 	return
 }
 
+// DecOut_Z decrypts an out_z sent to a PKr of tk and confirms the decrypted
+// content against its commitment.
+func DecOut_Z(tk *keys.Uint512, out_z *Out_Z) (out_o Out_O, e error) {
+	key, flag := keys.FetchKey(tk, &out_z.RPK)
+
+	info_desc := cpt.InfoDesc{}
+	info_desc.Key = key
+	info_desc.Flag = flag
+	info_desc.Einfo = out_z.EInfo
+
+	cpt.DecOutput(&info_desc)
+
+	if e = ConfirmOut_Z(&info_desc, out_z); e != nil {
+		return
+	}
+	out_o.Addr = out_z.PKr
+	out_o.Asset = assets.NewAsset(
+		&assets.Token{
+			Currency: info_desc.Tkn_currency,
+			Value:    utils.NewU256_ByKey(&info_desc.Tkn_value),
+		},
+		&assets.Ticket{
+			Category: info_desc.Tkt_category,
+			Value:    info_desc.Tkt_value,
+		},
+	)
+	out_o.Memo = info_desc.Memo
+	return
+}
+
 func (self *Out_Z) Clone() (ret Out_Z) {
 	utils.DeepCopy(&ret, self)
 	return
@@ -205,3 +235,18 @@ func (self *T) ToHash_for_sign() (ret keys.Uint256) {
 	copy(ret[:], d.Sum(nil))
 	return
 }
+
+// ProofCount returns the number of zero knowledge proofs in the transaction.
+func (self *T) ProofCount() (count int) {
+	count = len(self.Desc_Z.Ins) + len(self.Desc_Z.Outs)
+	if self.Desc_Pkg.Create != nil {
+		count++
+	}
+	return
+}
+
+// ProofSize returns the size of the zero knowledge proofs in the transaction
+// in bytes.
+func (self *T) ProofSize() int {
+	return self.ProofCount() * len(cpt.Proof{})
+}
@@ -0,0 +1,167 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/keys"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/tx"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/pkgstate"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func sero(v int) assets.Token {
+	return assets.Token{Currency: utils.StringToUint256("SERO"), Value: utils.NewU256(uint64(v))}
+}
+
+// skipBlocks moves the zstate n blocks ahead without any transaction.
+func skipBlocks(n uint64) {
+	g_blocks.st = zstate.NewState(g_blocks.st.Tri, g_blocks.st.Num()+n)
+	g_blocks.st0 = &g_blocks.st.State
+	g_blocks.st1.State = g_blocks.st
+}
+
+// apply generates and verifies t, and adds it to a new block if it verifies.
+func (self *user) apply(t *tx.T) error {
+	s, e := self.Gen(&self.seed, t)
+	if e != nil {
+		return e
+	}
+	if e := self.Verify(&s); e != nil {
+		return e
+	}
+	g_blocks.st.AddStx(&s)
+	g_blocks.st.Update()
+	EndBlock()
+	return nil
+}
+
+func (self *user) createPkg(v int, fee int, u user, terms tx.PkgCreate) (id keys.Uint256, e error) {
+	outs := self.GetOuts()
+	in := tx.In{}
+	in.Root = outs[0].Root
+
+	terms.Id = cpt.Random()
+	terms.PKr = u.getAR()
+	terms.Pkg.Asset = assets.Asset{Tkn: sero(v).ToRef()}
+
+	out := tx.Out{}
+	out.Addr = self.getAR()
+	out.Asset = outs[0].Out_O.Asset.Clone()
+	out.Asset.Tkn.Value.SubU(utils.NewU256(uint64(v)).ToRef())
+	out.Asset.Tkn.Value.SubU(utils.NewU256(uint64(fee)).ToRef())
+	out.IsZ = true
+
+	t := tx.T{}
+	t.Fee = sero(fee)
+	t.Ins = append(t.Ins, in)
+	t.Outs = append(t.Outs, out)
+	t.PkgCreate = &terms
+	return terms.Id, self.apply(&t)
+}
+
+func (self *user) closePkg(id *keys.Uint256, key *keys.Uint256, v int, refund bool, preimage *keys.Uint256) error {
+	t := tx.T{}
+	t.Fee = sero(0)
+	t.PkgClose = &tx.PkgClose{Id: *id, Key: *key, Refund: refund, Preimage: preimage}
+
+	out := tx.Out{}
+	out.Asset = assets.Asset{Tkn: sero(v).ToRef()}
+	out.IsZ = true
+	out.Addr = self.getAR()
+	t.Outs = append(t.Outs, out)
+	return self.apply(&t)
+}
+
+func (self *user) pkgKey(id *keys.Uint256) (key keys.Uint256) {
+	for _, p := range self.GetPkgs(true) {
+		if p.Pkg.Z.Pack.Id == *id {
+			return p.Key
+		}
+	}
+	return
+}
+
+func TestPkgRefundable(t *testing.T) {
+	pg := pkgstate.ZPkg{High: 10}
+	if pg.Refundable(100) {
+		t.Fatalf("package without terms is refundable")
+	}
+	pg.Pack.Terms = []stx.PkgTerms{{Expiry: 5}}
+	if pg.Refundable(14) {
+		t.Fatalf("package is refundable before its expiry")
+	}
+	if !pg.Refundable(15) {
+		t.Fatalf("package is not refundable at its expiry")
+	}
+}
+
+func TestPkgRefund(t *testing.T) {
+	user_m := newUser(1)
+	user_a := newUser(2)
+	user_m.addOut(100)
+
+	id, err := user_m.createPkg(50, 10, user_a, tx.PkgCreate{Expiry: 3})
+	if err != nil {
+		t.Fatalf("create pkg: %v", err)
+	}
+	key := user_m.pkgKey(&id)
+
+	if err := user_m.closePkg(&id, &key, 50, true, nil); err == nil {
+		t.Fatalf("refund before the expiry verified")
+	}
+	skipBlocks(3)
+	if err := user_m.closePkg(&id, &key, 50, true, nil); err != nil {
+		t.Fatalf("refund after the expiry: %v", err)
+	}
+	if user_m.Logout() != 90 {
+		t.Fatalf("refund was not returned to the creator")
+	}
+}
+
+func TestPkgHashlock(t *testing.T) {
+	user_m := newUser(1)
+	user_a := newUser(2)
+	user_m.addOut(100)
+
+	preimage := cpt.Random()
+	id, err := user_m.createPkg(50, 10, user_a, tx.PkgCreate{Hashlock: keys.Uint256(sha256.Sum256(preimage[:]))})
+	if err != nil {
+		t.Fatalf("create pkg: %v", err)
+	}
+	key := user_m.pkgKey(&id)
+
+	if err := user_a.closePkg(&id, &key, 50, false, nil); err == nil {
+		t.Fatalf("close without the preimage verified")
+	}
+	wrong := cpt.Random()
+	if err := user_a.closePkg(&id, &key, 50, false, &wrong); err == nil {
+		t.Fatalf("close with a wrong preimage verified")
+	}
+	if err := user_a.closePkg(&id, &key, 50, false, &preimage); err != nil {
+		t.Fatalf("close with the preimage: %v", err)
+	}
+	if user_a.Logout() != 50 {
+		t.Fatalf("pkg was not closed to the owner")
+	}
+}
@@ -74,7 +74,12 @@ func EndBlock() {
 	} else {
 		g_blocks.st1.State = g_blocks.st
 	}
-	g_blocks.st1.UpdateWitness(keys.Seeds2Tks(seeds))
+	block := zstate.Block{
+		Roots: g_blocks.st.State.Block.Roots,
+		Dels:  g_blocks.st.State.Block.Dels,
+		Pkgs:  g_blocks.st.Pkgs.Block.Pkgs,
+	}
+	g_blocks.st1.UpdateWitness(keys.Seeds2Tks(seeds), g_blocks.st.Num(), &keys.Uint256{}, &block)
 	NewBlock()
 }
 
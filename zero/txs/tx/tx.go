@@ -39,11 +39,21 @@ type PkgCreate struct {
 	Id  keys.Uint256
 	PKr keys.PKr
 	Pkg pkg.Pkg_O
+	// Expiry is the number of blocks after which the creator may refund the
+	// package, zero if it does not expire.
+	Expiry uint64
+	// Hashlock is the SHA-256 hash of the preimage the owner reveals to close
+	// the package, empty for none.
+	Hashlock keys.Uint256
 }
 
 type PkgClose struct {
 	Id  keys.Uint256
 	Key keys.Uint256
+	// Refund closes an expired package as its creator instead of its owner.
+	Refund bool
+	// Preimage opens the hashlock of the package.
+	Preimage *keys.Uint256 `rlp:"nil"`
 }
 
 type PkgTransfer struct {
@@ -52,14 +62,37 @@ type PkgTransfer struct {
 }
 
 type T struct {
-	FromRnd     *keys.Uint256
+	FromRnd     *keys.Uint256 `rlp:"nil"`
 	Ehash       keys.Uint256
 	Fee         assets.Token
 	Ins         []In
 	Outs        []Out
-	PkgCreate   *PkgCreate
-	PkgTransfer *PkgTransfer
-	PkgClose    *PkgClose
+	PkgCreate   *PkgCreate   `rlp:"nil"`
+	PkgTransfer *PkgTransfer `rlp:"nil"`
+	PkgClose    *PkgClose    `rlp:"nil"`
+
+	// MaxInNum limits the outs selected as inputs to the ones created at or
+	// before this block, zero for no limit.
+	MaxInNum uint64
+	// Excluded are the roots of the outs not to be selected as inputs, such
+	// as the immature block rewards.
+	Excluded []keys.Uint256
+	// Selection is the coin selection strategy of the inputs, one of the
+	// txs.Select constants.
+	Selection string
+	// Recipients are the account addresses the PKrs of the outs and of the
+	// package are derived from, for the wallet to check them against the
+	// scope of the unlock of the sender.
+	Recipients map[keys.PKr]keys.Uint512 `rlp:"-"`
+}
+
+// AddRecipient records the account address a PKr of the transaction is
+// derived from.
+func (self *T) AddRecipient(pkr keys.PKr, addr *keys.Uint512) {
+	if self.Recipients == nil {
+		self.Recipients = make(map[keys.PKr]keys.Uint512)
+	}
+	self.Recipients[pkr] = *addr
 }
 
 func (self *T) TokenCost() (ret map[keys.Uint256]utils.U256) {
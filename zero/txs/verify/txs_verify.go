@@ -28,6 +28,7 @@ import (
 
 	"github.com/sero-cash/go-czero-import/keys"
 	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/pkgstate"
 	"github.com/sero-cash/go-sero/zero/utils"
 )
 
@@ -142,6 +143,12 @@ func Verify_state1(s *stx.T, state *zstate.ZState) (e error) {
 	}
 
 	t.Renter("Miner-Verify-----pkgs")
+	if s.Desc_Pkg.Create != nil {
+		if terms := s.Desc_Pkg.Create.Terms; len(terms) > 1 || (len(terms) == 1 && terms[0] == stx.PkgTerms{}) {
+			e = fmt.Errorf("Invalid terms of the pkg %v", hexutil.Encode(s.Desc_Pkg.Create.Id[:]))
+			return
+		}
+	}
 	if s.Desc_Pkg.Transfer != nil {
 		if pg := state.Pkgs.GetPkg(&s.Desc_Pkg.Transfer.Id); pg == nil {
 			e = fmt.Errorf("Can not find pkg of the id %v", hexutil.Encode(s.Desc_Pkg.Transfer.Id[:]))
@@ -160,7 +167,15 @@ func Verify_state1(s *stx.T, state *zstate.ZState) (e error) {
 			e = fmt.Errorf("Can not find pkg of the id %v", hexutil.Encode(s.Desc_Pkg.Close.Id[:]))
 			return
 		} else {
-			if keys.VerifyPKr(&hash_z, &s.Desc_Pkg.Close.Sign, &pg.Pack.PKr) {
+			if err := verifyPkgClose(s.Desc_Pkg.Close, pg); err != nil {
+				e = err
+				return
+			}
+			// the owner closes a hashlocked pkg revealing the preimage, the
+			// creator refunds an expired pkg without
+			locked := pg.Pack.GetTerms().Hashlock != keys.Empty_Uint256 && len(s.Desc_Pkg.Close.Preimage) == 0
+			if (!locked && keys.VerifyPKr(&hash_z, &s.Desc_Pkg.Close.Sign, &pg.Pack.PKr)) ||
+				(pg.Refundable(state.Num()) && keys.VerifyPKr(&hash_z, &s.Desc_Pkg.Close.Sign, &pg.From)) {
 				balance_desc.Zin_acms = append(balance_desc.Zin_acms, pg.Pack.Pkg.AssetCM[:]...)
 			} else {
 				e = fmt.Errorf("Can not verify pkg sign of the id %v", hexutil.Encode(s.Desc_Pkg.Close.Id[:]))
@@ -205,3 +220,19 @@ func Verify_state1(s *stx.T, state *zstate.ZState) (e error) {
 		return
 	}
 }
+
+// verifyPkgClose checks the preimage of a close against the hashlock of the
+// package. Refunds reveal no preimage, their sign is checked by the caller.
+func verifyPkgClose(pc *stx.PkgClose, pg *pkgstate.ZPkg) error {
+	if len(pc.Preimage) > 1 {
+		return fmt.Errorf("Invalid preimage of the pkg %v", hexutil.Encode(pc.Id[:]))
+	}
+	if len(pc.Preimage) == 0 {
+		return nil
+	}
+	terms := pg.Pack.GetTerms()
+	if terms.Hashlock == keys.Empty_Uint256 || !terms.Unlocks(&pc.Preimage[0]) {
+		return fmt.Errorf("Preimage does not open the hashlock of the pkg %v", hexutil.Encode(pc.Id[:]))
+	}
+	return nil
+}
@@ -25,6 +25,13 @@ type ZPkg struct {
 	Pack stx.PkgCreate
 }
 
+// Refundable returns whether the creator may refund the package in the block
+// of the given number, which is the case once it expired.
+func (self *ZPkg) Refundable(num uint64) bool {
+	expiry := self.Pack.ExpiryBlocks()
+	return expiry != 0 && num >= self.High+expiry
+}
+
 func (self *ZPkg) Serial() (ret []byte, e error) {
 	return rlp.EncodeToBytes(self)
 }
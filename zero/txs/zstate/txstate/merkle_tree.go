@@ -131,6 +131,31 @@ func (self *MerkleTree) GetPaths(value keys.Uint256) (pos uint64, paths [DEPTH]k
 	return
 }
 
+type MerkleTreeStats struct {
+	Depth  int    `json:"depth"`
+	Trees  uint64 `json:"trees"`
+	Leaves uint64 `json:"leaves"`
+	Nodes  uint64 `json:"nodes"`
+	Free   uint64 `json:"free"`
+}
+
+func (self *MerkleTree) Stats() (ret MerkleTreeStats) {
+	treeIndex := self.geCurrentTreeIndex()
+	current := self.getCurrentLeafIndex() - startIndex
+
+	ret.Depth = DEPTH
+	ret.Trees = treeIndex + 1
+	ret.Leaves = treeIndex*startIndex + current
+	ret.Nodes = treeIndex * (cap - 1)
+	if current > 0 {
+		for depth, count := 0, current; depth <= DEPTH; depth, count = depth+1, (count+1)/2 {
+			ret.Nodes += count
+		}
+	}
+	ret.Free = startIndex - current
+	return
+}
+
 func (self *MerkleTree) nextLeafIndex() uint64 {
 	leafIndex := self.getCurrentLeafIndex()
 	if leafIndex == cap {
@@ -170,6 +195,18 @@ func brother(index uint64) uint64 {
 	}
 }
 
+// TreeIndexKey returns the storage key of the index of the tree holding the
+// commitment value.
+func TreeIndexKey(value keys.Uint256) keys.Uint256 {
+	return treeKey(value)
+}
+
+// TreeRootKey returns the storage key of the root of the tree of the given
+// index, the anchor of the witnesses of its commitments.
+func TreeRootKey(treeIndex uint64) keys.Uint256 {
+	return indexPathKey(1, treeIndex)
+}
+
 func leafKey(value keys.Uint256) keys.Uint256 {
 	return keys.Uint256(crypto.Keccak256Hash(append([]byte("LEAF_"), value[:]...)))
 }
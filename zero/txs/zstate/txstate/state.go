@@ -93,6 +93,17 @@ func (self *State) Name2BKey(name string, num uint64) (ret []byte) {
 	return
 }
 
+// BlockKey returns the key of the StateBlock of the block of the given number
+// in the state trie, so that light clients can request proofs of it.
+func BlockKey(num uint64) []byte {
+	return []byte(fmt.Sprintf("%s_%d", BLOCK_NAME, num))
+}
+
+// OutKey returns the key of the OutState of root in the state trie.
+func OutKey(root *keys.Uint256) []byte {
+	return outName0(root)
+}
+
 func (self *State) load() {
 	get := CurrentGet{}
 	tri.GetObj(
@@ -17,6 +17,7 @@
 package zstate
 
 import (
+	"bytes"
 	"math/big"
 
 	"github.com/sero-cash/go-sero/rlp"
@@ -70,6 +71,28 @@ func (self *BlockGet) Unserial(v []byte) (e error) {
 	}
 }
 
+// IsTrieRecord reports whether a leaf of the account trie is one of the records
+// the zstate keeps there instead of an account: the mark of a spent out, the
+// head of the out tree, an out, a package or the changes of a block.
+func IsTrieRecord(leaf []byte) bool {
+	if bytes.Equal(leaf, []byte{1}) {
+		return true
+	}
+	records := []interface{}{
+		new(txstate.Current),
+		new(txstate.StateBlock),
+		new(txstate.OutState),
+		new(pkgstate.ZPkg),
+		new(pkgstate.Block),
+	}
+	for _, record := range records {
+		if rlp.DecodeBytes(leaf, record) == nil {
+			return true
+		}
+	}
+	return false
+}
+
 type ZState struct {
 	Tri   tri.Tri
 	num   uint64